@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,20 +14,30 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/docker/docker/client"
 	h "github.com/gorilla/handlers"
 	"github.com/pressly/goose/v3"
 	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/blobstore"
 	"github.com/stanstork/stratum-api/internal/config"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/execsync"
 	"github.com/stanstork/stratum-api/internal/handlers"
 	"github.com/stanstork/stratum-api/internal/middleware"
 	"github.com/stanstork/stratum-api/internal/migration"
+	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/passwords"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/revalidation"
 	"github.com/stanstork/stratum-api/internal/routes"
+	"github.com/stanstork/stratum-api/internal/scanning"
+	"github.com/stanstork/stratum-api/internal/scheduler"
+	"github.com/stanstork/stratum-api/internal/storage"
 	"github.com/stanstork/stratum-api/internal/temporal"
 	"github.com/stanstork/stratum-api/internal/temporal/activities"
 	"github.com/stanstork/stratum-api/internal/temporal/workflows"
+	"github.com/stanstork/stratum-api/internal/version"
+	"github.com/stanstork/stratum-api/internal/webhook"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 	tc "go.temporal.io/sdk/client"
@@ -37,6 +50,12 @@ type application struct {
 	temporalClient tc.Client
 	logger         zerolog.Logger
 	notifications  notification.Service
+	imageWarmer    *engine.ImageWarmer
+	dockerHosts    *engine.HostPool
+	kubernetes     *engine.KubernetesRunner
+	registryAuth   *engine.RegistryAuth
+	credentials    *temporal.CredentialVault
+	scanner        scanning.Scanner
 }
 
 func main() {
@@ -57,7 +76,7 @@ func main() {
 	cfg := config.Load()
 
 	// Initialize database connection.
-	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	db, err := sql.Open(repository.TenantGuardDriverName, cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to connect to the database")
 	}
@@ -71,12 +90,13 @@ func main() {
 
 	// Initialize notification service.
 	notificationRepo := repository.NewNotificationRepository(db)
-	emailNotifier, emailErr := notification.NewEmailNotifier(cfg.Email, logger)
+	emailNotifier, emailErr := notification.NewEmailNotifier(cfg.Email, repository.NewTenantRepository(db), logger)
 	if emailErr != nil {
 		logger.Error().Err(emailErr).Msg("failed to configure email notifier")
 	}
 	firebaseNotifier := notification.NewFirebaseNotifier(cfg.Firebase, logger)
-	notificationService := notification.NewService(notificationRepo, logger, emailNotifier, firebaseNotifier)
+	executionWebhooks := webhook.NewExecutionNotifier(repository.NewWebhookRepository(db), repository.NewJobRepository(db), logger)
+	notificationService := notification.NewService(notificationRepo, logger, emailNotifier, firebaseNotifier, executionWebhooks)
 
 	// Initialize Temporal client.
 	temporalClient, err := tc.Dial(tc.Options{
@@ -87,6 +107,27 @@ func main() {
 	}
 	defer temporalClient.Close()
 
+	// Docker daemons that run the engine.
+	dockerHosts, err := engine.NewHostPool(cfg.Docker, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to configure Docker hosts")
+	}
+
+	// Kubernetes Jobs run the engine instead when the runner is "kubernetes".
+	var kubernetes *engine.KubernetesRunner
+	if cfg.Runner.Kind == config.RunnerKubernetes {
+		if kubernetes, err = engine.NewKubernetesRunner(cfg.Runner.Kubernetes); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to configure the Kubernetes runner")
+		}
+		logger.Info().Str("namespace", kubernetes.Namespace).Msg("Engine runs are launched as Kubernetes Jobs")
+	}
+
+	// Malware scanner for engine artifacts.
+	scanner, err := scanning.New(cfg.Scanning)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to configure artifact scanning")
+	}
+
 	// Create the application instance.
 	app := &application{
 		config:         cfg,
@@ -94,23 +135,39 @@ func main() {
 		temporalClient: temporalClient,
 		logger:         logger,
 		notifications:  notificationService,
+		dockerHosts:    dockerHosts,
+		kubernetes:     kubernetes,
+		registryAuth:   engine.NewRegistryAuth(repository.NewRegistryRepository(db), cfg.Docker.RegistryTokenRefresh),
+		credentials:    temporal.NewCredentialVault(temporal.DefaultCredentialTTL),
+		scanner:        scanner,
 	}
 
-	// Start the Temporal worker in a separate goroutine.
-	temporalWorker := app.startTemporalWorker(logger)
+	// Start the Temporal workers in separate goroutines.
+	temporalWorkers := app.startTemporalWorkers(logger)
+
+	// Start cluster-wide periodic tasks.
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	sched := app.startScheduler(schedCtx, logger)
+
+	// Seed the engine compatibility matrix from the configured engine.
+	go app.discoverEngineCapabilities(logger)
 
 	// Initialize the HTTP router and middleware.
 	router := app.initRouter(logger)
 	loggedRouter := middleware.LoggingMiddleware(app.logger)(router)
 	corsHandler := h.CORS(
 		h.AllowedOrigins([]string{"http://localhost:3000"}),
-		h.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		h.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		h.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		h.AllowedHeaders([]string{"Content-Type", "Authorization", "If-None-Match", "X-API-Key", "X-API-Version", "Idempotency-Key"}),
+		h.ExposedHeaders([]string{"ETag", "X-API-Version", "Deprecation", "Sunset", "Link", handlers.DataRegionHeader}),
 		h.AllowCredentials(),
 	)(loggedRouter)
 
 	// Start the HTTP server and handle graceful shutdown.
-	app.startServer(corsHandler, temporalWorker, logger)
+	app.startServer(corsHandler, temporalWorkers, logger)
+
+	stopScheduler()
+	sched.Wait()
 
 	logger.Info().Msg("Application terminated.")
 }
@@ -123,62 +180,348 @@ func (app *application) initRouter(logger zerolog.Logger) http.Handler {
 	userRepo := repository.NewUserRepository(app.db)
 	tenantRepo := repository.NewTenantRepository(app.db)
 	inviteRepo := repository.NewInviteRepository(app.db)
-
-	// Mailer for invites
+	apiKeyRepo := repository.NewAPIKeyRepository(app.db)
+	domainRepo := repository.NewEmailDomainRepository(app.db)
+	auditRepo := repository.NewAuditRepository(app.db)
+	templateRepo := repository.NewTemplateRepository(app.db)
+	webhookRepo := repository.NewWebhookRepository(app.db)
+	announcementRepo := repository.NewAnnouncementRepository(app.db)
+	engineRepo := repository.NewEngineRepository(app.db)
+	residency := storage.NewResidency(app.config.Storage, tenantRepo)
+
+	// Mailer for invites and email verification
 	inviteMailer, err := notification.NewSMTPInviteMailer(app.config.Email)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to configure invite mailer")
 	}
 
 	// Handlers
-	authHandler := handlers.NewAuthHandler(app.db, app.config, logger)
-	jobHandler := handlers.NewJobHandler(jobRepo, app.temporalClient, app.notifications, logger)
-	connHandler := handlers.NewConnectionHandler(connRepo, app.config.Worker.EngineImage, logger)
-	metaHandler := handlers.NewMetadataHandler(connRepo, app.config.Worker.EngineImage, logger)
-	reportHandler := handlers.NewReportHandler(connRepo, jobRepo, app.config.Worker.EngineImage, logger)
-	tenantHandler := handlers.NewTenantHandler(tenantRepo, userRepo, logger)
-	inviteHandler := handlers.NewInviteHandler(inviteRepo, tenantRepo, userRepo, inviteMailer, app.config.Email.InviteURLTemplate, logger)
+	passwordPolicy := passwords.NewPolicy(app.config.Users.PasswordPolicy, logger)
+	authHandler := handlers.NewAuthHandler(app.db, app.config, inviteMailer, passwordPolicy, logger)
+	jobHandler := handlers.NewJobHandler(jobRepo, connRepo, tenantRepo, templateRepo, auditRepo, engineRepo, app.temporalClient, app.notifications, residency, app.dockerHosts, app.credentials, app.scanner, app.config.Worker.EngineImage, logger)
+	connHandler := handlers.NewConnectionHandler(connRepo, jobRepo, app.temporalClient, app.dockerHosts, app.config.Worker.EngineImage, logger)
+	metaHandler := handlers.NewMetadataHandler(connRepo, app.dockerHosts, app.config.Worker.EngineImage, logger)
+	reportHandler := handlers.NewReportHandler(connRepo, jobRepo, app.dockerHosts, app.config.Worker.EngineImage, logger)
+	tenantHandler := handlers.NewTenantHandler(tenantRepo, userRepo, auditRepo, webhookRepo, residency, app.temporalClient, app.config.Tenants.DeletionGracePeriod, passwordPolicy, logger)
+	inviteHandler := handlers.NewInviteHandler(inviteRepo, tenantRepo, userRepo, auditRepo, webhookRepo, app.inviteDelivery(inviteMailer, logger), passwordPolicy, logger)
 	notificationHandler := handlers.NewNotificationHandler(app.notifications, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo, auditRepo, logger)
+	grafanaHandler := handlers.NewGrafanaHandler(jobRepo, logger)
+	domainHandler := handlers.NewDomainHandler(domainRepo, userRepo, auditRepo, logger)
+	templateHandler := handlers.NewTemplateHandler(templateRepo, connRepo, logger)
+	viewHandler := handlers.NewViewHandler(repository.NewViewRepository(app.db), logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, auditRepo, logger)
+	announcementHandler := handlers.NewAnnouncementHandler(announcementRepo, logger)
+	sensorHandler := handlers.NewSensorHandler(repository.NewSensorRepository(app.db), connRepo, logger)
+	complianceHandler := handlers.NewComplianceHandler(auditRepo, app.complianceSigningKey(logger), logger)
+	setupHandler := handlers.NewSetupHandler(repository.NewInstanceRepository(app.db), auditRepo, inviteMailer, app.dockerHosts, app.temporalClient, passwordPolicy, logger)
+	metricsHandler := handlers.NewMetricsHandler(tenantRepo, app.config.Metrics, logger)
+	scimHandler := handlers.NewSCIMHandler(repository.NewSCIMTokenRepository(app.db), userRepo, auditRepo, webhookRepo, passwordPolicy, logger)
+	latency := app.newLatencyTracker(logger)
+	adminHandler := handlers.NewAdminHandler(context.Background(), app.imageWarmer, app.dockerHosts, repository.NewRegistryRepository(app.db), engineRepo, app.registryAuth, app.config.Worker.EngineImage, latency, logger)
+
+	accessLog := middleware.NewAccessLogger(auditRepo, logger)
+	go accessLog.Run(context.Background())
+
+	router := routes.NewRouter(authHandler, jobHandler, connHandler, metaHandler, reportHandler, tenantHandler, inviteHandler, notificationHandler, apiKeyHandler, grafanaHandler, adminHandler, domainHandler, complianceHandler, templateHandler, webhookHandler, announcementHandler, sensorHandler, viewHandler, setupHandler, metricsHandler, scimHandler)
+	router.Use(latency.Middleware)
+	router.Use(accessLog.Middleware)
+	return router
+}
 
-	return routes.NewRouter(authHandler, jobHandler, connHandler, metaHandler, reportHandler, tenantHandler, inviteHandler, notificationHandler)
+// complianceSigningKey decodes the evidence export signing key. Without one,
+// exports are not signed.
+func (app *application) complianceSigningKey(logger zerolog.Logger) ed25519.PrivateKey {
+	encoded := app.config.Compliance.SigningKey
+	if encoded == "" {
+		return nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		logger.Fatal().Msg("compliance signing_key must be a base64-encoded 32-byte Ed25519 seed")
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// newLatencyTracker builds the per-route latency budget tracker and starts its
+// evaluation loop. Sustained budget breaches are published as system notifications.
+func (app *application) newLatencyTracker(logger zerolog.Logger) *middleware.LatencyTracker {
+	cfg := app.config.Latency
+	budgets := make([]middleware.LatencyBudget, 0, len(cfg.Budgets))
+	for _, b := range cfg.Budgets {
+		budgets = append(budgets, middleware.LatencyBudget{Route: b.Route, P99: b.P99})
+	}
+
+	onAlert := func(alert middleware.LatencyAlert) {
+		evt := notification.Event{
+			Event:    models.NotificationEventLatencyBudget,
+			Severity: models.NotificationSeverityWarning,
+			Title:    fmt.Sprintf("Latency budget exceeded: %s", alert.Route),
+			Message: fmt.Sprintf("P99 latency of %s is %s, above its %s budget since %s.",
+				alert.Route, alert.P99.Round(time.Millisecond), alert.Budget, alert.BreachedSince.Format(time.RFC3339)),
+			Metadata: map[string]interface{}{
+				"route":     alert.Route,
+				"p99_ms":    alert.P99.Milliseconds(),
+				"budget_ms": alert.Budget.Milliseconds(),
+				"resolved":  alert.Resolved,
+			},
+		}
+		if alert.Resolved {
+			evt.Severity = models.NotificationSeverityInfo
+			evt.Title = fmt.Sprintf("Latency budget recovered: %s", alert.Route)
+			evt.Message = fmt.Sprintf("P99 latency of %s is back within its %s budget.", alert.Route, alert.Budget)
+		}
+		if _, err := app.notifications.Publish(context.Background(), evt); err != nil {
+			logger.Warn().Err(err).Str("route", alert.Route).Msg("failed to publish latency alert")
+		}
+	}
+
+	prefixes := []string{"/api/" + version.CurrentContract, "/api"}
+	tracker := middleware.NewLatencyTracker(cfg.Window, cfg.AlertAfter, cfg.DefaultP99, budgets, prefixes, onAlert, logger)
+	go tracker.Run(context.Background(), 30*time.Second)
+	return tracker
 }
 
-func (app *application) startTemporalWorker(logger zerolog.Logger) worker.Worker {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// discoverEngineCapabilities records the configured engine's version and the format
+// pairs it migrates, which definitions are checked against. A failure leaves the
+// matrix as it was; admins can rerun discovery.
+func (app *application) discoverEngineCapabilities(logger zerolog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	host, err := app.dockerHosts.Pick(ctx, "")
 	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Docker client")
+		logger.Warn().Err(err).Msg("No docker host for engine capability discovery")
+		return
+	}
+	caps, err := host.Engine(app.config.Worker.EngineImage).Capabilities(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Engine capability discovery failed")
+		return
+	}
+	if err := repository.NewEngineRepository(app.db).RecordEngineCapabilities(caps, true); err != nil {
+		logger.Warn().Err(err).Msg("Failed to record engine capabilities")
+		return
+	}
+	logger.Info().Str("version", caps.Version).Int("pairs", len(caps.Pairs)).Msg("Engine capabilities discovered")
+}
+
+// startTemporalWorkers starts the worker of the execution task queue and, unless no
+// priority slots are configured, one limited to that many concurrent activities on
+// the priority task queue.
+func (app *application) startTemporalWorkers(logger zerolog.Logger) []worker.Worker {
+	// Warm engine images so the first execution after a deploy skips the pull.
+	app.imageWarmer = engine.NewImageWarmer(app.dockerHosts, app.registryAuth, logger)
+	warmed := map[string]bool{}
+	for _, ref := range append([]string{app.config.Worker.EngineImage}, app.config.Worker.PrepullImages...) {
+		if ref != "" && !warmed[ref] {
+			warmed[ref] = true
+			app.imageWarmer.Warm(context.Background(), ref)
+		}
 	}
 
 	activityImpl := &activities.Activities{
 		JobRepo:           repository.NewJobRepository(app.db),
 		ConnRepo:          repository.NewConnectionRepository(app.db),
-		DockerClient:      dockerClient,
+		TenantRepo:        repository.NewTenantRepository(app.db),
+		SensorRepo:        repository.NewSensorRepository(app.db),
+		Hosts:             app.dockerHosts,
+		Kubernetes:        app.kubernetes,
+		Registries:        app.registryAuth,
+		Credentials:       app.credentials,
 		EngineImage:       app.config.Worker.EngineImage,
 		JWTSigningKey:     []byte(app.config.JWTSecret),
-		TempDir:           app.config.Worker.TempDir,
+		Blobs:             blobstore.NewPostgresStore(app.db),
 		ContainerCPULimit: app.config.Worker.ContainerCPULimit,
 		ContainerMemLimit: app.config.Worker.ContainerMemoryLimit,
 		Notifier:          app.notifications,
+		Residency:         storage.NewResidency(app.config.Storage, repository.NewTenantRepository(app.db)),
+		Scanner:           app.scanner,
+		Regression: activities.RegressionPolicy{
+			Threshold:    app.config.Regression.Threshold,
+			BaselineRuns: app.config.Regression.BaselineRuns,
+			MinRuns:      app.config.Regression.MinRuns,
+		},
 	}
 
 	w := worker.New(app.temporalClient, temporal.TaskQueueName, worker.Options{})
-
 	w.RegisterWorkflow(workflows.ExecutionWorkflow)
+	w.RegisterWorkflow(workflows.TenantPurgeWorkflow)
+	w.RegisterWorkflow(workflows.RunGroupWorkflow)
+	w.RegisterWorkflow(workflows.RollbackWorkflow)
 	w.RegisterActivity(activityImpl)
+	workers := []worker.Worker{w}
+
+	if slots := app.config.Worker.PrioritySlots; slots > 0 {
+		pw := worker.New(app.temporalClient, temporal.PriorityTaskQueueName, worker.Options{
+			MaxConcurrentActivityExecutionSize: slots,
+		})
+		pw.RegisterWorkflow(workflows.ExecutionWorkflow)
+		pw.RegisterActivity(activityImpl)
+		workers = append(workers, pw)
+	} else {
+		logger.Warn().Msg("No priority worker slots configured; high-priority runs wait for a worker on another instance")
+	}
 
-	// Start the worker in a goroutine so it doesn't block.
-	go func() {
-		logger.Info().Msg("Starting Temporal worker...")
-		if err := w.Run(worker.InterruptCh()); err != nil {
-			logger.Fatal().Err(err).Msg("Unable to start worker")
-		}
-	}()
+	// Start the workers in goroutines so they don't block.
+	for _, w := range workers {
+		go func(w worker.Worker) {
+			logger.Info().Msg("Starting Temporal worker...")
+			if err := w.Run(worker.InterruptCh()); err != nil {
+				logger.Fatal().Err(err).Msg("Unable to start worker")
+			}
+		}(w)
+	}
 
-	return w
+	return workers
+}
+
+// inviteDelivery sends invite emails through mailer and retries the failed ones.
+func (app *application) inviteDelivery(mailer notification.InviteMailer, logger zerolog.Logger) *notification.InviteDelivery {
+	return notification.NewInviteDelivery(
+		repository.NewInviteRepository(app.db),
+		repository.NewTenantRepository(app.db),
+		mailer,
+		app.config.Email.InviteURLTemplate,
+		app.config.Email.InviteMaxAttempts,
+		logger,
+	)
+}
+
+// startScheduler registers periodic maintenance tasks and starts them. Each task runs
+// at most once per interval across all replicas.
+func (app *application) startScheduler(ctx context.Context, logger zerolog.Logger) *scheduler.Scheduler {
+	userRepo := repository.NewUserRepository(app.db)
+
+	sched := scheduler.New(app.db, scheduler.NewPostgresLocker(app.db), logger)
+	sched.Register(scheduler.Task{
+		Name:     "prune-email-verifications",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			pruned, err := userRepo.PruneEmailVerifications(time.Now().Add(-7 * 24 * time.Hour))
+			if err != nil {
+				return err
+			}
+			if pruned > 0 {
+				logger.Info().Int64("count", pruned).Msg("pruned stale email verification tokens")
+			}
+			return nil
+		},
+	})
+	if retention := app.config.Users.EraseDeletedAfter; retention > 0 {
+		sched.Register(scheduler.Task{
+			Name:     "erase-deleted-users",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				ids, err := userRepo.ListErasableUsers(time.Now().Add(-retention), 100)
+				if err != nil {
+					return err
+				}
+				for _, id := range ids {
+					if err := userRepo.EraseUser("", id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+						return err
+					}
+				}
+				if len(ids) > 0 {
+					logger.Info().Int("count", len(ids)).Msg("erased deleted users past retention")
+				}
+				return nil
+			},
+		})
+	}
+	revalidator := revalidation.New(
+		repository.NewJobRepository(app.db),
+		repository.NewConnectionRepository(app.db),
+		app.dockerHosts,
+		app.config.Worker.EngineImage,
+		app.notifications,
+		app.config.Revalidation.DemoteOnFailure,
+		app.config.Revalidation.BatchSize,
+		logger,
+	)
+	sched.Register(scheduler.Task{
+		Name:     "revalidate-definitions",
+		Interval: app.config.Revalidation.Interval,
+		Timeout:  10 * time.Minute,
+		Run:      revalidator.RunOnce,
+	})
+	freshness := revalidation.NewFreshnessChecker(
+		repository.NewJobRepository(app.db),
+		repository.NewConnectionRepository(app.db),
+		repository.NewTenantRepository(app.db),
+		app.dockerHosts,
+		app.config.Worker.EngineImage,
+		app.notifications,
+		app.config.Revalidation.FreshnessInterval,
+		app.config.Revalidation.BatchSize,
+		logger,
+	)
+	sched.Register(scheduler.Task{
+		Name:     "check-validation-freshness",
+		Interval: 15 * time.Minute,
+		Timeout:  10 * time.Minute,
+		Run:      freshness.RunOnce,
+	})
+	dispatcher := webhook.NewDispatcher(
+		repository.NewWebhookRepository(app.db),
+		app.config.Webhooks.Timeout,
+		app.config.Webhooks.MaxAttempts,
+		app.config.Webhooks.BatchSize,
+		logger,
+	)
+	sched.Register(scheduler.Task{
+		Name:     "deliver-webhooks",
+		Interval: app.config.Webhooks.Interval,
+		Timeout:  15 * time.Minute,
+		Run:      dispatcher.RunOnce,
+	})
+	if mailer, err := notification.NewSMTPInviteMailer(app.config.Email); err == nil {
+		sched.Register(scheduler.Task{
+			Name:     "retry-invite-emails",
+			Interval: app.config.Email.InviteRetryInterval,
+			Timeout:  10 * time.Minute,
+			Run:      app.inviteDelivery(mailer, logger).RunOnce,
+		})
+	}
+	jobRepo := repository.NewJobRepository(app.db)
+	sched.Register(scheduler.Task{
+		Name:     "rollup-execution-stats",
+		Interval: time.Hour,
+		Timeout:  15 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := jobRepo.RefreshMonthlyRollups()
+			return err
+		},
+	})
+	reconciler := execsync.New(jobRepo, app.temporalClient, app.notifications, 15*time.Minute, 100, logger)
+	sched.Register(scheduler.Task{
+		Name:     "reconcile-executions",
+		Interval: 5 * time.Minute,
+		Timeout:  5 * time.Minute,
+		Run:      reconciler.RunOnce,
+	})
+	blobs := blobstore.NewPostgresStore(app.db)
+	sched.Register(scheduler.Task{
+		Name:     "sweep-blobs",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run: func(ctx context.Context) error {
+			swept, err := blobs.DeleteUnreferenced(ctx, time.Now().Add(-7*24*time.Hour))
+			if err != nil {
+				return err
+			}
+			if swept > 0 {
+				logger.Info().Int64("count", swept).Msg("swept unreferenced blobs")
+			}
+			return nil
+		},
+	})
+	sched.Start(ctx)
+
+	return sched
 }
 
 // startServer launches the HTTP server and handles graceful shutdown.
-func (app *application) startServer(handler http.Handler, temporalWorker worker.Worker, logger zerolog.Logger) {
+func (app *application) startServer(handler http.Handler, temporalWorkers []worker.Worker, logger zerolog.Logger) {
 	server := &http.Server{
 		Addr:    ":" + app.config.ServerPort,
 		Handler: handler,
@@ -213,8 +556,10 @@ func (app *application) startServer(handler http.Handler, temporalWorker worker.
 		logger.Info().Msg("HTTP server shutdown complete.")
 	}
 
-	// Stop the Temporal worker.
-	logger.Info().Msg("Stopping Temporal worker...")
-	temporalWorker.Stop()
-	logger.Info().Msg("Temporal worker stopped.")
+	// Stop the Temporal workers.
+	logger.Info().Msg("Stopping Temporal workers...")
+	for _, w := range temporalWorkers {
+		w.Stop()
+	}
+	logger.Info().Msg("Temporal workers stopped.")
 }
@@ -13,8 +13,24 @@ const (
 	tenantIDKey  contextKey = "tenant_id"
 	userIDKey    contextKey = "user_id"
 	userRolesKey contextKey = "user_roles"
+	identityKey  contextKey = "identity_record"
+	apiKeyIDKey  contextKey = "api_key_id"
 )
 
+// IdentityRecord receives the identity authenticated further down the handler chain,
+// for middleware that runs before authentication such as access logging.
+type IdentityRecord struct {
+	TenantID string
+	UserID   string
+}
+
+// WithIdentityRecord returns a context whose later WithIdentity calls are also
+// copied into the returned record.
+func WithIdentityRecord(ctx context.Context) (context.Context, *IdentityRecord) {
+	rec := &IdentityRecord{}
+	return context.WithValue(ctx, identityKey, rec), rec
+}
+
 // WithIdentity stores tenant, user, and role information on the context.
 func WithIdentity(ctx context.Context, tenantID, userID string, roles []models.UserRole) context.Context {
 	if tenantID != "" {
@@ -23,11 +39,29 @@ func WithIdentity(ctx context.Context, tenantID, userID string, roles []models.U
 	if userID != "" {
 		ctx = context.WithValue(ctx, userIDKey, userID)
 	}
+	if rec, ok := ctx.Value(identityKey).(*IdentityRecord); ok {
+		rec.TenantID = tenantID
+		rec.UserID = userID
+	}
 	normalized := models.EnsureDefaultRole(models.NormalizeRoles(roles))
 	ctx = context.WithValue(ctx, userRolesKey, normalized)
 	return ctx
 }
 
+// WithAPIKey records that the request was authenticated with the API key keyID.
+func WithAPIKey(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey, keyID)
+}
+
+// APIKeyIDFromRequest returns the API key the request was authenticated with.
+func APIKeyIDFromRequest(r *http.Request) (string, bool) {
+	id, ok := r.Context().Value(apiKeyIDKey).(string)
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
 func TenantIDFromRequest(r *http.Request) (string, bool) {
 	tid, ok := r.Context().Value(tenantIDKey).(string)
 	if !ok || tid == "" {
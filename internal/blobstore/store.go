@@ -0,0 +1,210 @@
+// Package blobstore keeps large immutable payloads, such as definition ASTs,
+// progress snapshots and execution reports, once per distinct content, addressed
+// by the SHA-256 of their bytes.
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for hashes the store does not hold.
+var ErrNotFound = errors.New("blob not found")
+
+// DefaultChunkSize is how much of a blob is held in memory, and stored per row,
+// while it is written or read.
+const DefaultChunkSize = 256 << 10
+
+// Ref identifies a stored blob.
+type Ref struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// Store is a content-addressable blob store with streaming reads and writes.
+type Store interface {
+	// Put stores the reader's content and returns its reference. Content the store
+	// already holds is not stored again.
+	Put(ctx context.Context, r io.Reader) (Ref, error)
+	// Open streams a blob's content. It returns ErrNotFound for unknown hashes.
+	Open(ctx context.Context, hash string) (io.ReadCloser, error)
+	// Delete removes a blob. Blobs are shared by everything with the same content,
+	// so only blobs whose content is known to be unique may be deleted this way.
+	Delete(ctx context.Context, hash string) error
+}
+
+// PutBytes stores b.
+func PutBytes(ctx context.Context, s Store, b []byte) (Ref, error) {
+	return s.Put(ctx, bytes.NewReader(b))
+}
+
+// ReadAll returns a blob's content.
+func ReadAll(ctx context.Context, s Store, hash string) ([]byte, error) {
+	rc, err := s.Open(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// PostgresStore keeps blobs in tenant.blobs, split into tenant.blob_chunks rows of
+// at most chunkSize bytes, so neither side of a transfer buffers a whole blob.
+type PostgresStore struct {
+	db        *sql.DB
+	chunkSize int
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db, chunkSize: DefaultChunkSize}
+}
+
+// Put streams the content into chunks staged under a temporary key while hashing
+// it, then either files them under the hash or, when the content is already
+// stored, discards them and refreshes the existing blob so it is not swept.
+func (s *PostgresStore) Put(ctx context.Context, r io.Reader) (Ref, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Ref{}, err
+	}
+	defer tx.Rollback()
+
+	staging := "staging:" + uuid.NewString()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tenant.blobs (hash) VALUES ($1)`, staging); err != nil {
+		return Ref{}, err
+	}
+
+	var (
+		h    = sha256.New()
+		buf  = make([]byte, s.chunkSize)
+		ref  Ref
+		seq  int
+		done bool
+	)
+	for !done {
+		n, err := io.ReadFull(r, buf)
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			done = true
+		case err != nil:
+			return Ref{}, err
+		}
+		if n == 0 {
+			continue
+		}
+		h.Write(buf[:n])
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tenant.blob_chunks (hash, seq, data) VALUES ($1, $2, $3)`,
+			staging, seq, buf[:n]); err != nil {
+			return Ref{}, err
+		}
+		ref.Size += int64(n)
+		seq++
+	}
+	ref.Hash = hex.EncodeToString(h.Sum(nil))
+
+	if s.touch(ctx, ref.Hash) {
+		return ref, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tenant.blobs SET hash = $1, size = $2 WHERE hash = $3`,
+		ref.Hash, ref.Size, staging); err != nil {
+		// A concurrent Put of the same content got there first.
+		if strings.Contains(err.Error(), "duplicate") {
+			return ref, nil
+		}
+		return Ref{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Ref{}, err
+	}
+	return ref, nil
+}
+
+// touch marks an existing blob as freshly written and reports whether it exists.
+func (s *PostgresStore) touch(ctx context.Context, hash string) bool {
+	res, err := s.db.ExecContext(ctx, `UPDATE tenant.blobs SET touched_at = now() WHERE hash = $1`, hash)
+	if err != nil {
+		return false
+	}
+	n, err := res.RowsAffected()
+	return err == nil && n > 0
+}
+
+func (s *PostgresStore) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tenant.blobs WHERE hash = $1)`, hash).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &chunkReader{ctx: ctx, db: s.db, hash: hash}, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM tenant.blobs WHERE hash = $1`, hash)
+	return err
+}
+
+// DeleteUnreferenced removes blobs no definition, snapshot or artifact refers to
+// and that have not been written since olderThan, and returns how many it removed.
+// The grace period protects blobs whose reference is about to be stored.
+func (s *PostgresStore) DeleteUnreferenced(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		/* cross-tenant: blobs are shared by every tenant */
+		DELETE FROM tenant.blobs b
+		WHERE b.touched_at < $1
+		  AND b.hash NOT LIKE 'staging:%'
+		  AND NOT EXISTS (SELECT 1 FROM tenant.job_definitions WHERE ast_hash = b.hash OR progress_snapshot_hash = b.hash)
+		  AND NOT EXISTS (SELECT 1 FROM tenant.job_definition_snapshots WHERE snapshot_hash = b.hash)
+		  AND NOT EXISTS (SELECT 1 FROM tenant.execution_snapshots WHERE ast_hash = b.hash)
+		  AND NOT EXISTS (SELECT 1 FROM tenant.execution_artifacts WHERE content_hash = b.hash)`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// chunkReader reads a blob one chunk row at a time.
+type chunkReader struct {
+	ctx  context.Context
+	db   *sql.DB
+	hash string
+	seq  int
+	buf  []byte
+	eof  bool
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.eof {
+			return 0, io.EOF
+		}
+		err := c.db.QueryRowContext(c.ctx, `SELECT data FROM tenant.blob_chunks WHERE hash = $1 AND seq = $2`,
+			c.hash, c.seq).Scan(&c.buf)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.eof = true
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		c.seq++
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *chunkReader) Close() error {
+	c.buf, c.eof = nil, true
+	return nil
+}
@@ -11,18 +11,34 @@ type WorkerConfig struct {
 	PollInterval         time.Duration `mapstructure:"poll_interval"`
 	EngineImage          string        `mapstructure:"engine_image"`
 	EngineContainer      string        `mapstructure:"engine_container"`
-	TempDir              string        `mapstructure:"temp_dir"`
 	ContainerCPULimit    int64         `mapstructure:"container_cpu_limit"`
 	ContainerMemoryLimit int64         `mapstructure:"container_memory_limit"`
+	// PrepullImages are extra engine images warmed at startup, e.g. the next release.
+	PrepullImages []string `mapstructure:"prepull_images"`
+	// PrioritySlots is how many executions the worker reserves for high-priority
+	// runs; they never pick up normal-priority work.
+	PrioritySlots int `mapstructure:"priority_slots"`
 }
 
 type Config struct {
-	DatabaseURL string         `mapstructure:"database_url"`
-	ServerPort  string         `mapstructure:"server_port"`
-	JWTSecret   string         `mapstructure:"jwt_secret"`
-	Worker      WorkerConfig   `mapstructure:"worker"`
-	Email       EmailConfig    `mapstructure:"email"`
-	Firebase    FirebaseConfig `mapstructure:"firebase"`
+	DatabaseURL  string             `mapstructure:"database_url"`
+	ServerPort   string             `mapstructure:"server_port"`
+	JWTSecret    string             `mapstructure:"jwt_secret"`
+	Worker       WorkerConfig       `mapstructure:"worker"`
+	Email        EmailConfig        `mapstructure:"email"`
+	Firebase     FirebaseConfig     `mapstructure:"firebase"`
+	Revalidation RevalidationConfig `mapstructure:"revalidation"`
+	Storage      StorageConfig      `mapstructure:"storage"`
+	Latency      LatencyConfig      `mapstructure:"latency"`
+	Docker       DockerConfig       `mapstructure:"docker"`
+	Runner       RunnerConfig       `mapstructure:"runner"`
+	Tenants      TenantsConfig      `mapstructure:"tenants"`
+	Users        UsersConfig        `mapstructure:"users"`
+	Regression   RegressionConfig   `mapstructure:"regression"`
+	Compliance   ComplianceConfig   `mapstructure:"compliance"`
+	Webhooks     WebhooksConfig     `mapstructure:"webhooks"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
+	Scanning     ScanningConfig     `mapstructure:"scanning"`
 }
 
 type EmailConfig struct {
@@ -32,7 +48,12 @@ type EmailConfig struct {
 	Username          string   `mapstructure:"username"`
 	Password          string   `mapstructure:"password"`
 	InviteURLTemplate string   `mapstructure:"invite_url_template"`
+	VerifyURLTemplate string   `mapstructure:"verify_url_template"`
 	AlertRecipients   []string `mapstructure:"alert_recipients"`
+	// Invite emails that fail to send are retried every InviteRetryInterval, with
+	// exponential backoff per invite, until InviteMaxAttempts sends have failed.
+	InviteRetryInterval time.Duration `mapstructure:"invite_retry_interval"`
+	InviteMaxAttempts   int           `mapstructure:"invite_max_attempts"`
 }
 
 type FirebaseConfig struct {
@@ -41,6 +62,192 @@ type FirebaseConfig struct {
 	Topic     string `mapstructure:"topic"`
 }
 
+// RevalidationConfig controls the background re-check of READY definitions after
+// one of their connections is edited, and how often the source schema of READY
+// definitions is compared with the validated one for tenants that ask for it.
+type RevalidationConfig struct {
+	Interval          time.Duration `mapstructure:"interval"`
+	BatchSize         int           `mapstructure:"batch_size"`
+	DemoteOnFailure   bool          `mapstructure:"demote_on_failure"`
+	FreshnessInterval time.Duration `mapstructure:"freshness_interval"`
+}
+
+// StorageConfig maps data residency regions to the buckets artifacts and logs are
+// kept in. ServingRegion is the region this deployment runs in; when set, tenant data
+// stored in another region is neither written nor served by this deployment.
+type StorageConfig struct {
+	ServingRegion string                         `mapstructure:"serving_region"`
+	DefaultRegion string                         `mapstructure:"default_region"`
+	Regions       map[string]StorageRegionConfig `mapstructure:"regions"`
+}
+
+type StorageRegionConfig struct {
+	Bucket   string `mapstructure:"bucket"`
+	Endpoint string `mapstructure:"endpoint"`
+	// URL is the public base URL of the API deployment serving this region, used to
+	// point clients at the right deployment.
+	URL string `mapstructure:"url"`
+}
+
+// LatencyConfig sets per-route P99 latency budgets. A route whose rolling P99 stays
+// above its budget for AlertAfter raises a warning notification.
+type LatencyConfig struct {
+	Window     time.Duration         `mapstructure:"window"`
+	AlertAfter time.Duration         `mapstructure:"alert_after"`
+	DefaultP99 time.Duration         `mapstructure:"default_p99"`
+	Budgets    []LatencyBudgetConfig `mapstructure:"budgets"`
+}
+
+type LatencyBudgetConfig struct {
+	// Route is "METHOD /path" relative to the API prefix, e.g. "GET /jobs/{jobID}".
+	Route string        `mapstructure:"route"`
+	P99   time.Duration `mapstructure:"p99"`
+}
+
+// DockerConfig lists the Docker daemons engine work runs on. With no hosts the local
+// daemon from the environment (DOCKER_HOST and friends) is used.
+type DockerConfig struct {
+	// Strategy picks a host for unpinned tenants: "round_robin" or "least_loaded".
+	Strategy string             `mapstructure:"strategy"`
+	Hosts    []DockerHostConfig `mapstructure:"hosts"`
+	// TenantPins maps tenant IDs to the name of the host their work must run on.
+	TenantPins map[string]string `mapstructure:"tenant_pins"`
+	// MaxConcurrentExecs caps the engine execs (tests, metadata pulls, dry runs) this
+	// process runs on one host at a time. Zero means no limit.
+	MaxConcurrentExecs int `mapstructure:"max_concurrent_execs"`
+	// RegistryTokenRefresh is how long registry credentials issued by a credential
+	// helper, such as ECR tokens, are reused before the helper is run again.
+	RegistryTokenRefresh time.Duration `mapstructure:"registry_token_refresh"`
+}
+
+type DockerHostConfig struct {
+	Name string `mapstructure:"name"`
+	// Host is the daemon address, e.g. "tcp://engine-1.internal:2376".
+	Host      string `mapstructure:"host"`
+	TLSCACert string `mapstructure:"tls_ca_cert"`
+	TLSCert   string `mapstructure:"tls_cert"`
+	TLSKey    string `mapstructure:"tls_key"`
+}
+
+// Runner kinds: where engine migration runs are launched.
+const (
+	RunnerDocker     = "docker"
+	RunnerKubernetes = "kubernetes"
+)
+
+// RunnerConfig selects where the engine runs migrations. "docker" starts a
+// container on one of the Docker hosts; "kubernetes" starts a Kubernetes Job in the
+// cluster the API runs in, so the Docker socket need not be mounted for runs.
+// Engine commands that exec into the long-running engine container (connection
+// tests, metadata pulls, dry runs) still go to the Docker hosts.
+type RunnerConfig struct {
+	Kind       string           `mapstructure:"kind"`
+	Kubernetes KubernetesConfig `mapstructure:"kubernetes"`
+}
+
+// KubernetesConfig configures the Jobs engine runs are launched as. The API
+// server address and credentials come from the pod's service account.
+type KubernetesConfig struct {
+	// Namespace the Jobs are created in; empty means the namespace the API runs in.
+	Namespace string `mapstructure:"namespace"`
+	// ServiceAccount the engine pods run as; empty means the namespace default.
+	ServiceAccount   string   `mapstructure:"service_account"`
+	ImagePullSecrets []string `mapstructure:"image_pull_secrets"`
+	// JobTTL is how long finished Jobs are kept before Kubernetes deletes them, for
+	// Jobs the worker could not clean up itself.
+	JobTTL time.Duration `mapstructure:"job_ttl"`
+}
+
+// TenantsConfig controls tenant lifecycle. Deleted tenants stay restorable for
+// DeletionGracePeriod before their data is purged.
+type TenantsConfig struct {
+	DeletionGracePeriod time.Duration `mapstructure:"deletion_grace_period"`
+}
+
+// UsersConfig controls user data retention. Deleted users are erased automatically
+// EraseDeletedAfter after their deletion; zero disables automatic erasure.
+type UsersConfig struct {
+	EraseDeletedAfter time.Duration        `mapstructure:"erase_deleted_after"`
+	PasswordPolicy    PasswordPolicyConfig `mapstructure:"password_policy"`
+}
+
+// PasswordPolicyConfig is enforced whenever a password is chosen: on signup, invite
+// acceptance, admin-created users and password changes. With BreachCheck, passwords
+// are also looked up in a breach corpus through the k-anonymity range API at
+// BreachCheckURL.
+type PasswordPolicyConfig struct {
+	MinLength          int           `mapstructure:"min_length"`
+	RequireUppercase   bool          `mapstructure:"require_uppercase"`
+	RequireLowercase   bool          `mapstructure:"require_lowercase"`
+	RequireDigit       bool          `mapstructure:"require_digit"`
+	RequireSymbol      bool          `mapstructure:"require_symbol"`
+	BreachCheck        bool          `mapstructure:"breach_check"`
+	BreachCheckURL     string        `mapstructure:"breach_check_url"`
+	BreachCheckTimeout time.Duration `mapstructure:"breach_check_timeout"`
+}
+
+// RegressionConfig controls how succeeded executions are compared against their
+// definition's baseline, the medians of its last BaselineRuns succeeded runs. A run
+// whose duration grows, or throughput drops, by more than Threshold (0.5 = 50%) is
+// flagged once at least MinRuns earlier runs exist. A negative threshold disables
+// detection.
+type RegressionConfig struct {
+	Threshold    float64 `mapstructure:"threshold"`
+	BaselineRuns int     `mapstructure:"baseline_runs"`
+	MinRuns      int     `mapstructure:"min_runs"`
+}
+
+// ComplianceConfig controls audit evidence exports. SigningKey is a base64-encoded
+// 32-byte Ed25519 seed; when set, export manifests are signed with it.
+type ComplianceConfig struct {
+	SigningKey string `mapstructure:"signing_key"`
+}
+
+// WebhooksConfig controls delivery of the webhook outbox. Failed deliveries are
+// retried with exponential backoff until MaxAttempts attempts have been made.
+type WebhooksConfig struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BatchSize   int           `mapstructure:"batch_size"`
+}
+
+// MetricsConfig controls the per-tenant usage scrape endpoint. The endpoint is
+// disabled until ScrapeToken is set; scrapers send it as a bearer token. Quotas
+// are monthly limits used to report the quota remaining, with per-tenant
+// overrides in TenantQuotas keyed by tenant ID. A zero limit means unlimited.
+type MetricsConfig struct {
+	ScrapeToken  string                 `mapstructure:"scrape_token"`
+	Quotas       QuotaConfig            `mapstructure:"quotas"`
+	TenantQuotas map[string]QuotaConfig `mapstructure:"tenant_quotas"`
+}
+
+type QuotaConfig struct {
+	MonthlyExecutions int64 `mapstructure:"monthly_executions"`
+	MonthlyBytes      int64 `mapstructure:"monthly_bytes"`
+}
+
+// QuotaFor returns the quotas of a tenant: its override when it has one, the
+// defaults otherwise.
+func (c MetricsConfig) QuotaFor(tenantID string) QuotaConfig {
+	if q, ok := c.TenantQuotas[tenantID]; ok {
+		return q
+	}
+	return c.Quotas
+}
+
+// ScanningConfig selects the malware scanner engine artifacts are checked with
+// before they are stored: "clamav" streams them to the clamd daemon at Address,
+// "http" posts them to the scanning service at URL. With no backend artifacts are
+// stored unscanned, unless their tenant requires scanning.
+type ScanningConfig struct {
+	Backend string        `mapstructure:"backend"`
+	Address string        `mapstructure:"address"`
+	URL     string        `mapstructure:"url"`
+	Token   string        `mapstructure:"token"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
 // Load reads the configuration from a YAML file and returns a Config instance.
 func Load() *Config {
 	v := viper.New()
@@ -51,6 +258,9 @@ func Load() *Config {
 	v.AddConfigPath("./config")
 	v.SetConfigType("yaml")
 
+	v.SetDefault("revalidation.demote_on_failure", true)
+	v.SetDefault("worker.priority_slots", 2)
+
 	if err := v.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file: %v", err)
 	}
@@ -75,6 +285,100 @@ func Load() *Config {
 	if config.Email.InviteURLTemplate == "" {
 		config.Email.InviteURLTemplate = "https://app.stratum.dev/invite/accept?token=%s"
 	}
+	if config.Email.InviteRetryInterval <= 0 {
+		config.Email.InviteRetryInterval = 30 * time.Second
+	}
+	if config.Email.InviteMaxAttempts <= 0 {
+		config.Email.InviteMaxAttempts = 6
+	}
+	if config.Email.VerifyURLTemplate == "" {
+		config.Email.VerifyURLTemplate = "https://app.stratum.dev/verify-email?token=%s"
+	}
+
+	if config.Revalidation.Interval <= 0 {
+		config.Revalidation.Interval = 30 * time.Second
+	}
+	if config.Revalidation.BatchSize <= 0 {
+		config.Revalidation.BatchSize = 20
+	}
+	if config.Revalidation.FreshnessInterval <= 0 {
+		config.Revalidation.FreshnessInterval = 24 * time.Hour
+	}
+
+	if config.Latency.Window <= 0 {
+		config.Latency.Window = 5 * time.Minute
+	}
+	if config.Latency.AlertAfter <= 0 {
+		config.Latency.AlertAfter = 5 * time.Minute
+	}
+
+	if region := config.Storage.DefaultRegion; region != "" {
+		if _, ok := config.Storage.Regions[region]; !ok {
+			log.Fatalf("storage default_region %q has no entry under storage.regions", region)
+		}
+	}
+
+	if config.Tenants.DeletionGracePeriod <= 0 {
+		config.Tenants.DeletionGracePeriod = 30 * 24 * time.Hour
+	}
+
+	if config.Users.PasswordPolicy.MinLength <= 0 {
+		config.Users.PasswordPolicy.MinLength = 8
+	}
+
+	if config.Regression.Threshold == 0 {
+		config.Regression.Threshold = 0.5
+	}
+	if config.Regression.BaselineRuns <= 0 {
+		config.Regression.BaselineRuns = 20
+	}
+	if config.Regression.MinRuns <= 0 {
+		config.Regression.MinRuns = 5
+	}
+	if config.Regression.MinRuns > config.Regression.BaselineRuns {
+		config.Regression.MinRuns = config.Regression.BaselineRuns
+	}
+
+	if config.Webhooks.Interval <= 0 {
+		config.Webhooks.Interval = 15 * time.Second
+	}
+	if config.Webhooks.Timeout <= 0 {
+		config.Webhooks.Timeout = 10 * time.Second
+	}
+	if config.Webhooks.MaxAttempts <= 0 {
+		config.Webhooks.MaxAttempts = 8
+	}
+	if config.Webhooks.BatchSize <= 0 {
+		config.Webhooks.BatchSize = 50
+	}
+
+	if config.Docker.Strategy == "" {
+		config.Docker.Strategy = "round_robin"
+	}
+	if config.Docker.RegistryTokenRefresh <= 0 {
+		config.Docker.RegistryTokenRefresh = 6 * time.Hour
+	}
+	hostNames := make(map[string]bool, len(config.Docker.Hosts))
+	for _, h := range config.Docker.Hosts {
+		if h.Name == "" || h.Host == "" {
+			log.Fatal("every docker host needs a name and a host address")
+		}
+		if hostNames[h.Name] {
+			log.Fatalf("docker host %q is defined more than once", h.Name)
+		}
+		hostNames[h.Name] = true
+	}
+
+	switch config.Runner.Kind {
+	case "":
+		config.Runner.Kind = RunnerDocker
+	case RunnerDocker, RunnerKubernetes:
+	default:
+		log.Fatalf("unknown runner kind %q; use %q or %q", config.Runner.Kind, RunnerDocker, RunnerKubernetes)
+	}
+	if config.Runner.Kubernetes.JobTTL <= 0 {
+		config.Runner.Kubernetes.JobTTL = time.Hour
+	}
 
 	return &config
 }
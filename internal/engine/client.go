@@ -2,13 +2,20 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stanstork/stratum-api/internal/models"
 )
 
+// scratchRoot holds the per-call scratch directories inside the engine container.
+const scratchRoot = "/tmp/stratum"
+
 type Client struct {
 	Runner        Runner
 	ContainerName string
@@ -16,6 +23,18 @@ type Client struct {
 	WorkDir       string // optional default workdir in container
 }
 
+// ExitError reports an engine command that ran but exited non-zero, as opposed to
+// a failure to reach the engine container at all.
+type ExitError struct {
+	Op       string
+	ExitCode int
+	Output   string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s failed (%d): %s", e.Op, e.ExitCode, e.Output)
+}
+
 func NewClient(r Runner, containerName string) *Client {
 	return &Client{
 		Runner:        r,
@@ -37,17 +56,41 @@ func (c *Client) TestConnection(ctx context.Context, driver, dsn string) (string
 	return logs, nil
 }
 
+// Benchmark measures round-trip latency and sequential read throughput to the
+// database behind dsn. The result is the engine's JSON report.
+func (c *Client) Benchmark(ctx context.Context, driver, dsn string) ([]byte, error) {
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	reportPath := path.Join(dir, "benchmark_report.json")
+
+	cmd := []string{c.Bin, "benchmark", "--format", driver, "--conn-str", dsn, "--output", reportPath}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithWorkDir(c.WorkDir), WithTimeout(2*time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "benchmark", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
+}
+
 func (c *Client) SaveSourceMetadata(ctx context.Context, conn models.Connection) ([]byte, error) {
-	outPath := "/tmp/source_metadata.json"
 	connStr, err := conn.GenerateConnString()
 	if err != nil {
 		return nil, fmt.Errorf("conn string: %w", err)
 	}
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	outPath := path.Join(dir, "source_metadata.json")
 
-	script := fmt.Sprintf("mkdir -p $(dirname %s) && %s source info --conn-str '%s' --format %s --output %s",
-		outPath, c.Bin, connStr, conn.DataFormat, outPath)
-
-	res, err := c.Runner.Sh(ctx, c.ContainerName, script, WithWorkDir(c.WorkDir), WithTimeout(120*time.Second))
+	cmd := []string{c.Bin, "source", "info", "--conn-str", connStr, "--format", conn.DataFormat, "--output", outPath}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithWorkDir(c.WorkDir), WithTimeout(120*time.Second))
 	if err != nil {
 		return nil, err
 	}
@@ -58,28 +101,222 @@ func (c *Client) SaveSourceMetadata(ctx context.Context, conn models.Connection)
 }
 
 func (c *Client) DryRun(ctx context.Context, configJSON []byte) ([]byte, error) {
-	const tmpDir = "/tmp/stratum"
 	const cfgName = "config.json"
-	const reportPath = "/tmp/dry_run_report.json"
 
-	if _, err := c.Runner.Sh(ctx, c.ContainerName, "mkdir -p "+tmpDir, WithTimeout(10*time.Second)); err != nil {
-		return nil, fmt.Errorf("mkdir tmp: %w", err)
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if err := c.Runner.CopyTo(ctx, c.ContainerName, tmpDir, configJSON, cfgName); err != nil {
+	defer c.removeScratch(dir)
+	if err := c.Runner.CopyTo(ctx, c.ContainerName, dir, configJSON, cfgName); err != nil {
 		return nil, fmt.Errorf("upload config: %w", err)
 	}
-	if _, err := c.Runner.Sh(ctx, c.ContainerName, fmt.Sprintf("mkdir -p $(dirname %s)", reportPath), WithTimeout(10*time.Second)); err != nil {
-		return nil, fmt.Errorf("mkdir output parent: %w", err)
+	reportPath := path.Join(dir, "dry_run_report.json")
+
+	cmd := []string{c.Bin, "validate", "--config", path.Join(dir, cfgName), "--output", reportPath, "--from-ast"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithTimeout(5*time.Minute))
+	if err != nil {
+		return nil, err
 	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "dry-run report", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
+}
 
-	script := fmt.Sprintf("%s validate --config %s --output %s --from-ast",
-		c.Bin, path.Join(tmpDir, cfgName), reportPath)
-	res, err := c.Runner.Sh(ctx, c.ContainerName, script, WithTimeout(5*time.Minute))
+// DestinationState asks the engine for the row counts of every destination table the
+// given AST writes to. The result is the engine's JSON report.
+func (c *Client) DestinationState(ctx context.Context, configJSON []byte) ([]byte, error) {
+	const cfgName = "dest_state_config.json"
+
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	if err := c.Runner.CopyTo(ctx, c.ContainerName, dir, configJSON, cfgName); err != nil {
+		return nil, fmt.Errorf("upload config: %w", err)
+	}
+	reportPath := path.Join(dir, "dest_state_report.json")
+
+	cmd := []string{c.Bin, "dest", "state", "--config", path.Join(dir, cfgName), "--output", reportPath, "--from-ast"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithTimeout(2*time.Minute))
 	if err != nil {
 		return nil, err
 	}
 	if res.ExitCode != 0 {
-		return nil, fmt.Errorf("dry-run report failed (%d): %s", res.ExitCode, res.Stdout+res.Stderr)
+		return nil, fmt.Errorf("dest state failed (%d): %s", res.ExitCode, res.Stdout+res.Stderr)
 	}
 	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
 }
+
+// Verify asks the engine to compare the row counts, and where it can the
+// checksums, of every table the given AST migrated between source and
+// destination. The result is the engine's JSON report.
+func (c *Client) Verify(ctx context.Context, configJSON []byte) ([]byte, error) {
+	const cfgName = "verify_config.json"
+
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	if err := c.Runner.CopyTo(ctx, c.ContainerName, dir, configJSON, cfgName); err != nil {
+		return nil, fmt.Errorf("upload config: %w", err)
+	}
+	reportPath := path.Join(dir, "verify_report.json")
+
+	cmd := []string{c.Bin, "verify", "--config", path.Join(dir, cfgName), "--output", reportPath, "--from-ast", "--checksum"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithTimeout(VerifyTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "verify", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
+}
+
+// VerifyTimeout bounds a verification; checksums read every row of both sides.
+const VerifyTimeout = 30 * time.Minute
+
+// Rollback asks the engine to undo what the execution executionID wrote to the
+// destination of the given AST: objects the run created are dropped and objects it
+// replaced are restored. The result is the engine's JSON report.
+func (c *Client) Rollback(ctx context.Context, configJSON []byte, executionID string) ([]byte, error) {
+	const cfgName = "rollback_config.json"
+
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	if err := c.Runner.CopyTo(ctx, c.ContainerName, dir, configJSON, cfgName); err != nil {
+		return nil, fmt.Errorf("upload config: %w", err)
+	}
+	reportPath := path.Join(dir, "rollback_report.json")
+
+	cmd := []string{c.Bin, "rollback", "--config", path.Join(dir, cfgName), "--execution-id", executionID, "--output", reportPath, "--from-ast"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithTimeout(RollbackTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "rollback", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
+}
+
+// RollbackTimeout bounds a rollback; restoring replaced tables copies their rows
+// back.
+const RollbackTimeout = 30 * time.Minute
+
+// SampleRows asks the engine for up to rows randomly chosen source rows of each
+// table (all migrated tables when tables is empty) together with the destination
+// rows that share their keys. The result is the engine's JSON report.
+func (c *Client) SampleRows(ctx context.Context, configJSON []byte, tables []string, rows int) ([]byte, error) {
+	const cfgName = "sample_config.json"
+
+	dir, err := c.scratchDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeScratch(dir)
+	if err := c.Runner.CopyTo(ctx, c.ContainerName, dir, configJSON, cfgName); err != nil {
+		return nil, fmt.Errorf("upload config: %w", err)
+	}
+	reportPath := path.Join(dir, "sample_report.json")
+
+	cmd := []string{c.Bin, "sample", "--config", path.Join(dir, cfgName), "--rows", strconv.Itoa(rows), "--output", reportPath, "--from-ast"}
+	if len(tables) > 0 {
+		cmd = append(cmd, "--tables", strings.Join(tables, ","))
+	}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithTimeout(5*time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "sample", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	return c.Runner.CopyFrom(ctx, c.ContainerName, reportPath)
+}
+
+// RowCount asks the engine for the number of rows in a table of the database behind
+// dsn.
+func (c *Client) RowCount(ctx context.Context, driver, dsn, table string) (int64, error) {
+	cmd := []string{c.Bin, "source", "count", "--format", driver, "--conn-str", dsn, "--table", table}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithWorkDir(c.WorkDir), WithTimeout(2*time.Minute))
+	if err != nil {
+		return 0, err
+	}
+	if res.ExitCode != 0 {
+		return 0, &ExitError{Op: "row count", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(res.Stdout), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected row count output %q", strings.TrimSpace(res.Stdout))
+	}
+	return count, nil
+}
+
+// Capabilities asks the engine for its version and the source/destination data
+// format pairs it can migrate.
+func (c *Client) Capabilities(ctx context.Context) (models.EngineCapabilities, error) {
+	var caps models.EngineCapabilities
+	cmd := []string{c.Bin, "capabilities", "--output-format", "json"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithWorkDir(c.WorkDir), WithTimeout(time.Minute))
+	if err != nil {
+		return caps, err
+	}
+	if res.ExitCode != 0 {
+		return caps, &ExitError{Op: "capabilities", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(res.Stdout)), &caps); err != nil {
+		return caps, fmt.Errorf("unexpected capabilities output %q", strings.TrimSpace(res.Stdout))
+	}
+	caps.Normalize()
+	if caps.Version == "" {
+		return caps, fmt.Errorf("engine reported no version")
+	}
+	return caps, nil
+}
+
+// WritePrivileges asks the engine which write privileges the user behind dsn holds
+// on its database, e.g. "INSERT ON public.orders". None means the user cannot write.
+func (c *Client) WritePrivileges(ctx context.Context, driver, dsn string) ([]string, error) {
+	cmd := []string{c.Bin, "source", "privileges", "--format", driver, "--conn-str", dsn, "--writes"}
+	res, err := c.Runner.Exec(ctx, c.ContainerName, cmd, WithWorkDir(c.WorkDir), WithTimeout(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Op: "privilege check", ExitCode: res.ExitCode, Output: res.Stdout + res.Stderr}
+	}
+	var privileges []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(res.Stdout)), &privileges); err != nil {
+		return nil, fmt.Errorf("unexpected privilege check output %q", strings.TrimSpace(res.Stdout))
+	}
+	return privileges, nil
+}
+
+// scratchDir creates a directory in the container that only this call uses, so
+// concurrent execs never read or overwrite each other's configs and reports.
+func (c *Client) scratchDir(ctx context.Context) (string, error) {
+	dir := path.Join(scratchRoot, uuid.NewString())
+	res, err := c.Runner.Exec(ctx, c.ContainerName, []string{"mkdir", "-p", dir}, WithTimeout(10*time.Second))
+	if err != nil {
+		return "", fmt.Errorf("mkdir scratch: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("mkdir scratch failed (%d): %s", res.ExitCode, res.Stdout+res.Stderr)
+	}
+	return dir, nil
+}
+
+// removeScratch deletes a scratch directory. It runs even when the request context
+// is already done, and failures only leave a stray directory behind.
+func (c *Client) removeScratch(dir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.Runner.Exec(ctx, c.ContainerName, []string{"rm", "-rf", dir}, WithTimeout(10*time.Second))
+}
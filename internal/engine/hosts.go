@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/config"
+)
+
+// Host selection strategies for tenants that are not pinned to a host.
+const (
+	StrategyRoundRobin  = "round_robin"
+	StrategyLeastLoaded = "least_loaded"
+)
+
+// LocalHostName names the daemon taken from the environment when no hosts are configured.
+const LocalHostName = "local"
+
+const hostProbeTimeout = 3 * time.Second
+
+// ErrUnknownHost is returned when a host name does not match any configured host.
+var ErrUnknownHost = errors.New("unknown docker host")
+
+// ErrHostBusy is returned by Acquire when the chosen host already runs as many
+// engine execs from this process as it is allowed to.
+var ErrHostBusy = errors.New("docker host is at its concurrent exec limit")
+
+// DockerHost is one Docker daemon engine work can be sent to.
+type DockerHost struct {
+	Name     string
+	Address  string
+	Client   *client.Client
+	inFlight int64
+}
+
+// Engine returns an engine client that execs into containerName on this host.
+func (h *DockerHost) Engine(containerName string) *Client {
+	return NewClient(NewDockerRunner(h.Client), containerName)
+}
+
+// Hold counts an operation against the host's load until release is called.
+func (h *DockerHost) Hold() (release func()) {
+	atomic.AddInt64(&h.inFlight, 1)
+	return h.releaser()
+}
+
+// tryHold is Hold bounded by limit; it reports false once limit operations are in
+// flight. A limit of zero means unbounded.
+func (h *DockerHost) tryHold(limit int64) (func(), bool) {
+	for {
+		cur := atomic.LoadInt64(&h.inFlight)
+		if limit > 0 && cur >= limit {
+			return nil, false
+		}
+		if atomic.CompareAndSwapInt64(&h.inFlight, cur, cur+1) {
+			return h.releaser(), true
+		}
+	}
+}
+
+func (h *DockerHost) releaser() func() {
+	var once sync.Once
+	return func() { once.Do(func() { atomic.AddInt64(&h.inFlight, -1) }) }
+}
+
+// HostStatus is a point-in-time view of a host for operators.
+type HostStatus struct {
+	Name              string   `json:"name"`
+	Address           string   `json:"address"`
+	Reachable         bool     `json:"reachable"`
+	ContainersRunning int      `json:"containers_running"`
+	InFlight          int64    `json:"in_flight"`
+	PinnedTenants     []string `json:"pinned_tenants,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+// HostPool selects the Docker daemon that engine work for a tenant runs on. Pinned
+// tenants always go to their host; everyone else is spread by the configured strategy.
+type HostPool struct {
+	hosts    []*DockerHost
+	byName   map[string]*DockerHost
+	pins     map[string]string
+	strategy string
+	maxExecs int64
+	next     uint64
+	logger   zerolog.Logger
+}
+
+func NewHostPool(cfg config.DockerConfig, logger zerolog.Logger) (*HostPool, error) {
+	p := &HostPool{
+		byName:   make(map[string]*DockerHost),
+		pins:     cfg.TenantPins,
+		strategy: cfg.Strategy,
+		maxExecs: int64(cfg.MaxConcurrentExecs),
+		logger:   logger.With().Str("component", "docker_hosts").Logger(),
+	}
+	switch p.strategy {
+	case "":
+		p.strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyLeastLoaded:
+	default:
+		return nil, fmt.Errorf("unknown docker host strategy %q", cfg.Strategy)
+	}
+
+	if len(cfg.Hosts) == 0 {
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, fmt.Errorf("docker client: %w", err)
+		}
+		p.add(&DockerHost{Name: LocalHostName, Address: cli.DaemonHost(), Client: cli})
+		return p, nil
+	}
+
+	for _, hc := range cfg.Hosts {
+		opts := []client.Opt{client.WithHost(hc.Host), client.WithAPIVersionNegotiation()}
+		if hc.TLSCACert != "" || hc.TLSCert != "" || hc.TLSKey != "" {
+			opts = append(opts, client.WithTLSClientConfig(hc.TLSCACert, hc.TLSCert, hc.TLSKey))
+		}
+		cli, err := client.NewClientWithOpts(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("docker host %s: %w", hc.Name, err)
+		}
+		p.add(&DockerHost{Name: hc.Name, Address: hc.Host, Client: cli})
+	}
+	for tenantID, name := range p.pins {
+		if _, ok := p.byName[name]; !ok {
+			return nil, fmt.Errorf("tenant %s pinned to %w %q", tenantID, ErrUnknownHost, name)
+		}
+	}
+	return p, nil
+}
+
+func (p *HostPool) add(h *DockerHost) {
+	p.hosts = append(p.hosts, h)
+	p.byName[h.Name] = h
+}
+
+// Hosts returns every host in configuration order.
+func (p *HostPool) Hosts() []*DockerHost {
+	return p.hosts
+}
+
+// Host returns the named host. An empty name means the first configured host, which
+// keeps work recorded before hosts were named on the daemon it started on.
+func (p *HostPool) Host(name string) (*DockerHost, error) {
+	if name == "" {
+		return p.hosts[0], nil
+	}
+	h, ok := p.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownHost, name)
+	}
+	return h, nil
+}
+
+// Pick chooses the host for a tenant's next piece of engine work.
+func (p *HostPool) Pick(ctx context.Context, tenantID string) (*DockerHost, error) {
+	if name, ok := p.pins[tenantID]; ok {
+		return p.Host(name)
+	}
+	if len(p.hosts) == 1 {
+		return p.hosts[0], nil
+	}
+	if p.strategy == StrategyLeastLoaded {
+		return p.leastLoaded(ctx)
+	}
+	n := atomic.AddUint64(&p.next, 1)
+	return p.hosts[(n-1)%uint64(len(p.hosts))], nil
+}
+
+// Acquire picks a host for tenantID and holds it until release is called. It fails
+// with ErrHostBusy when the host is at the configured concurrent exec limit.
+func (p *HostPool) Acquire(ctx context.Context, tenantID string) (*DockerHost, func(), error) {
+	h, err := p.Pick(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	release, ok := h.tryHold(p.maxExecs)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w (%s)", ErrHostBusy, h.Name)
+	}
+	return h, release, nil
+}
+
+// leastLoaded returns the reachable host with the fewest running containers plus
+// in-flight operations from this process. Unreachable hosts are skipped.
+func (p *HostPool) leastLoaded(ctx context.Context) (*DockerHost, error) {
+	statuses := p.probe(ctx)
+	var best *DockerHost
+	bestLoad := int64(-1)
+	for i, st := range statuses {
+		if !st.Reachable {
+			continue
+		}
+		load := int64(st.ContainersRunning) + st.InFlight
+		if best == nil || load < bestLoad {
+			best, bestLoad = p.hosts[i], load
+		}
+	}
+	if best == nil {
+		return nil, errors.New("no docker host is reachable")
+	}
+	return best, nil
+}
+
+// Statuses probes every host and reports its load and the tenants pinned to it.
+func (p *HostPool) Statuses(ctx context.Context) []HostStatus {
+	statuses := p.probe(ctx)
+	for tenantID, name := range p.pins {
+		for i := range statuses {
+			if statuses[i].Name == name {
+				statuses[i].PinnedTenants = append(statuses[i].PinnedTenants, tenantID)
+			}
+		}
+	}
+	for i := range statuses {
+		sort.Strings(statuses[i].PinnedTenants)
+	}
+	return statuses
+}
+
+func (p *HostPool) probe(ctx context.Context) []HostStatus {
+	statuses := make([]HostStatus, len(p.hosts))
+	var wg sync.WaitGroup
+	for i, h := range p.hosts {
+		wg.Add(1)
+		go func(i int, h *DockerHost) {
+			defer wg.Done()
+			st := HostStatus{Name: h.Name, Address: h.Address, InFlight: atomic.LoadInt64(&h.inFlight)}
+			probeCtx, cancel := context.WithTimeout(ctx, hostProbeTimeout)
+			defer cancel()
+			info, err := h.Client.Info(probeCtx)
+			if err != nil {
+				p.logger.Warn().Err(err).Str("host", h.Name).Msg("docker host unreachable")
+				st.Error = err.Error()
+			} else {
+				st.Reachable = true
+				st.ContainersRunning = info.ContainersRunning
+			}
+			statuses[i] = st
+		}(i, h)
+	}
+	wg.Wait()
+	return statuses
+}
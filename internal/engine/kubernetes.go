@@ -0,0 +1,445 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/config"
+)
+
+// serviceAccountDir is where Kubernetes mounts a pod's service account token, the
+// cluster CA and the pod's namespace.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+const (
+	kubePollInterval = 2 * time.Second
+	// kubeRunLabel selects the pods of one engine Job.
+	kubeRunLabel = "stratum.run"
+	// kubeEngineContainer names the engine container in the Job's pod.
+	kubeEngineContainer = "engine"
+	kubeEnvKeyPrefix    = "env."
+)
+
+// Waiting reasons of a container that will not start without someone fixing the
+// Job's image, pull secrets or secret.
+var kubeStuckReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// KubernetesRunner launches engine runs as Kubernetes Jobs in the cluster the API
+// runs in. It talks to the API server with the pod's service account, which needs
+// create, get, list and delete on jobs, pods, pods/log and secrets in Namespace.
+type KubernetesRunner struct {
+	Namespace      string
+	apiServer      string
+	tokenFile      string
+	http           *http.Client
+	serviceAccount string
+	pullSecrets    []string
+	jobTTL         time.Duration
+}
+
+// KubernetesJob is one engine run launched as a Job. Env and Files reach the pod
+// through a secret owned by the Job, so credentials in them do not appear in the
+// Job spec and are deleted with it.
+type KubernetesJob struct {
+	// Name is the Job's name and must be a valid DNS label.
+	Name  string
+	Image string
+	Args  []string
+	// Env holds "KEY=value" entries, as for Docker.
+	Env []string
+	// Files are mounted read-only as MountPath/<name>, beside the image's own files.
+	Files       map[string][]byte
+	MountPath   string
+	Labels      map[string]string
+	CPUMillis   int64
+	MemoryBytes int64
+}
+
+// KubernetesPod is the pod a Job's engine container runs in.
+type KubernetesPod struct {
+	Name string
+	// ImageID is the digest of the engine image the pod pulled.
+	ImageID string
+}
+
+// KubernetesAPIError is a request the API server refused.
+type KubernetesAPIError struct {
+	Code    int
+	Message string
+}
+
+func (e *KubernetesAPIError) Error() string {
+	return fmt.Sprintf("kubernetes api: %d %s", e.Code, e.Message)
+}
+
+// NewKubernetesRunner configures a runner from the service account of the pod the
+// API runs in.
+func NewKubernetesRunner(cfg config.KubernetesConfig) (*KubernetesRunner, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes runner: KUBERNETES_SERVICE_HOST is not set; the API must run in the cluster")
+	}
+	ca, err := os.ReadFile(path.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes runner: read cluster CA: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("kubernetes runner: cluster CA holds no certificates")
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		raw, err := os.ReadFile(path.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes runner: read pod namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(raw))
+	}
+	return &KubernetesRunner{
+		Namespace: namespace,
+		apiServer: "https://" + net.JoinHostPort(host, port),
+		tokenFile: path.Join(serviceAccountDir, "token"),
+		http: &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+		}},
+		serviceAccount: cfg.ServiceAccount,
+		pullSecrets:    cfg.ImagePullSecrets,
+		jobTTL:         cfg.JobTTL,
+	}, nil
+}
+
+// Launch creates the Job and the secret carrying its env and files. The pod waits
+// for the secret, so it is created second, owned by the Job.
+func (k *KubernetesRunner) Launch(ctx context.Context, job KubernetesJob) error {
+	secret := map[string][]byte{}
+	var env []map[string]interface{}
+	for _, kv := range job.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		secret[kubeEnvKeyPrefix+name] = []byte(value)
+		env = append(env, map[string]interface{}{
+			"name": name,
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"name": job.Name, "key": kubeEnvKeyPrefix + name},
+			},
+		})
+	}
+	var mounts []map[string]interface{}
+	for name, content := range job.Files {
+		secret[name] = content
+		mounts = append(mounts, map[string]interface{}{
+			"name":      "run-files",
+			"mountPath": path.Join(job.MountPath, name),
+			"subPath":   name,
+			"readOnly":  true,
+		})
+	}
+
+	resources := map[string]interface{}{}
+	quantities := map[string]string{}
+	if job.CPUMillis > 0 {
+		quantities["cpu"] = fmt.Sprintf("%dm", job.CPUMillis)
+	}
+	if job.MemoryBytes > 0 {
+		quantities["memory"] = fmt.Sprintf("%d", job.MemoryBytes)
+	}
+	if len(quantities) > 0 {
+		resources["requests"], resources["limits"] = quantities, quantities
+	}
+
+	podLabels := map[string]string{kubeRunLabel: job.Name}
+	for key, value := range job.Labels {
+		podLabels[key] = value
+	}
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers": []map[string]interface{}{{
+			"name":         kubeEngineContainer,
+			"image":        job.Image,
+			"args":         job.Args,
+			"env":          env,
+			"volumeMounts": mounts,
+			"resources":    resources,
+		}},
+		"volumes": []map[string]interface{}{{
+			"name":   "run-files",
+			"secret": map[string]interface{}{"secretName": job.Name},
+		}},
+	}
+	if k.serviceAccount != "" {
+		podSpec["serviceAccountName"] = k.serviceAccount
+	}
+	if len(k.pullSecrets) > 0 {
+		refs := make([]map[string]string, 0, len(k.pullSecrets))
+		for _, name := range k.pullSecrets {
+			refs = append(refs, map[string]string{"name": name})
+		}
+		podSpec["imagePullSecrets"] = refs
+	}
+	spec := map[string]interface{}{
+		// The activity running the Job owns retries; a failed pod is not replaced.
+		"backoffLimit": 0,
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": podLabels},
+			"spec":     podSpec,
+		},
+	}
+	if k.jobTTL > 0 {
+		spec["ttlSecondsAfterFinished"] = int64(k.jobTTL / time.Second)
+	}
+
+	var created struct {
+		Metadata struct {
+			UID string `json:"uid"`
+		} `json:"metadata"`
+	}
+	err := k.do(ctx, http.MethodPost, k.jobsPath(""), map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"name": job.Name, "labels": job.Labels},
+		"spec":       spec,
+	}, &created)
+	if err != nil {
+		return fmt.Errorf("create job %s: %w", job.Name, err)
+	}
+
+	err = k.do(ctx, http.MethodPost, k.corePath("secrets", ""), map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":   job.Name,
+			"labels": job.Labels,
+			"ownerReferences": []map[string]interface{}{{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"name":       job.Name,
+				"uid":        created.Metadata.UID,
+			}},
+		},
+		"type": "Opaque",
+		"data": secret,
+	}, nil)
+	if err != nil {
+		k.Delete(context.Background(), job.Name)
+		return fmt.Errorf("create secret for job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// WaitForPod returns the Job's pod once its engine container has started. A
+// container that cannot start, such as one whose image cannot be pulled, fails
+// the wait.
+func (k *KubernetesRunner) WaitForPod(ctx context.Context, jobName string) (KubernetesPod, error) {
+	query := url.Values{"labelSelector": {kubeRunLabel + "=" + jobName}}
+	for {
+		var pods struct {
+			Items []kubePod `json:"items"`
+		}
+		if err := k.do(ctx, http.MethodGet, k.corePath("pods", "")+"?"+query.Encode(), nil, &pods); err != nil {
+			return KubernetesPod{}, fmt.Errorf("list pods of job %s: %w", jobName, err)
+		}
+		for _, pod := range pods.Items {
+			status, ok := pod.engineStatus()
+			if pod.Status.Phase == "Failed" && !ok {
+				return KubernetesPod{}, fmt.Errorf("pod %s failed: %s %s", pod.Metadata.Name, pod.Status.Reason, pod.Status.Message)
+			}
+			if !ok {
+				continue
+			}
+			if w := status.State.Waiting; w != nil && kubeStuckReasons[w.Reason] {
+				return KubernetesPod{}, fmt.Errorf("pod %s cannot start: %s: %s", pod.Metadata.Name, w.Reason, w.Message)
+			}
+			if status.State.Running != nil || status.State.Terminated != nil {
+				return KubernetesPod{Name: pod.Metadata.Name, ImageID: imageDigest(status.ImageID)}, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return KubernetesPod{}, ctx.Err()
+		case <-time.After(kubePollInterval):
+		}
+	}
+}
+
+// Logs follows the engine container's output until it exits.
+func (k *KubernetesRunner) Logs(ctx context.Context, podName string) (io.ReadCloser, error) {
+	query := url.Values{"container": {kubeEngineContainer}, "follow": {"true"}}
+	resp, err := k.open(ctx, http.MethodGet, k.corePath("pods", podName)+"/log?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("logs of pod %s: %w", podName, err)
+	}
+	return resp.Body, nil
+}
+
+// Wait returns the engine container's exit code once it has terminated.
+func (k *KubernetesRunner) Wait(ctx context.Context, podName string) (int64, error) {
+	for {
+		var pod kubePod
+		if err := k.do(ctx, http.MethodGet, k.corePath("pods", podName), nil, &pod); err != nil {
+			return 0, fmt.Errorf("get pod %s: %w", podName, err)
+		}
+		status, ok := pod.engineStatus()
+		if ok && status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode, nil
+		}
+		if pod.Status.Phase == "Failed" {
+			return 0, fmt.Errorf("pod %s failed: %s %s", podName, pod.Status.Reason, pod.Status.Message)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(kubePollInterval):
+		}
+	}
+}
+
+// Delete removes the Job. Kubernetes then stops its pod and removes the secret it
+// owns. A Job that is already gone is not an error.
+func (k *KubernetesRunner) Delete(ctx context.Context, jobName string) error {
+	query := url.Values{"propagationPolicy": {"Background"}}
+	err := k.do(ctx, http.MethodDelete, k.jobsPath(jobName)+"?"+query.Encode(), nil, nil)
+	var apiErr *KubernetesAPIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+type kubeContainerStatus struct {
+	Name    string `json:"name"`
+	ImageID string `json:"imageID"`
+	State   struct {
+		Waiting *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"waiting"`
+		Running    *struct{} `json:"running"`
+		Terminated *struct {
+			ExitCode int64 `json:"exitCode"`
+		} `json:"terminated"`
+	} `json:"state"`
+}
+
+type kubePod struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Phase             string                `json:"phase"`
+		Reason            string                `json:"reason"`
+		Message           string                `json:"message"`
+		ContainerStatuses []kubeContainerStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+func (p kubePod) engineStatus() (kubeContainerStatus, bool) {
+	for _, status := range p.Status.ContainerStatuses {
+		if status.Name == kubeEngineContainer {
+			return status, true
+		}
+	}
+	return kubeContainerStatus{}, false
+}
+
+// imageDigest drops the scheme some container runtimes prefix to a pod's image ID,
+// as in "docker-pullable://repo@sha256:...", leaving the repo digest Docker reports.
+func imageDigest(imageID string) string {
+	if _, rest, ok := strings.Cut(imageID, "://"); ok {
+		return rest
+	}
+	return imageID
+}
+
+func (k *KubernetesRunner) jobsPath(name string) string {
+	p := "/apis/batch/v1/namespaces/" + url.PathEscape(k.Namespace) + "/jobs"
+	if name != "" {
+		p += "/" + url.PathEscape(name)
+	}
+	return p
+}
+
+func (k *KubernetesRunner) corePath(resource, name string) string {
+	p := "/api/v1/namespaces/" + url.PathEscape(k.Namespace) + "/" + resource
+	if name != "" {
+		p += "/" + url.PathEscape(name)
+	}
+	return p
+}
+
+// do sends a JSON request and decodes the response into out when it is non-nil.
+func (k *KubernetesRunner) do(ctx context.Context, method, p string, body, out interface{}) error {
+	resp, err := k.open(ctx, method, p, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// open sends a request and returns the response of a successful one; the caller
+// closes its body.
+func (k *KubernetesRunner) open(ctx context.Context, method, p string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, k.apiServer+p, reader)
+	if err != nil {
+		return nil, err
+	}
+	// Projected service account tokens are rotated, so the file is read every time.
+	token, err := os.ReadFile(k.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("read service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var status struct {
+			Message string `json:"message"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(raw, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(raw))
+		}
+		return nil, &KubernetesAPIError{Code: resp.StatusCode, Message: status.Message}
+	}
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type kubeRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+// fakeAPIServer records the requests it gets and answers each with handle.
+func fakeAPIServer(t *testing.T, handle func(w http.ResponseWriter, r kubeRequest)) (*KubernetesRunner, func() []kubeRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []kubeRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := kubeRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Auth: r.Header.Get("Authorization")}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&req.Body)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		handle(w, req)
+	}))
+	t.Cleanup(srv.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runner := &KubernetesRunner{
+		Namespace:   "stratum",
+		apiServer:   srv.URL,
+		tokenFile:   tokenFile,
+		http:        srv.Client(),
+		pullSecrets: []string{"registry"},
+	}
+	return runner, func() []kubeRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]kubeRequest(nil), requests...)
+	}
+}
+
+func TestKubernetesLaunch(t *testing.T) {
+	runner, requests := fakeAPIServer(t, func(w http.ResponseWriter, r kubeRequest) {
+		if r.Path == "/apis/batch/v1/namespaces/stratum/jobs" {
+			w.Write([]byte(`{"metadata":{"uid":"job-uid"}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	err := runner.Launch(context.Background(), KubernetesJob{
+		Name:        "stratum-run-1",
+		Image:       "stratum/engine:1.2",
+		Args:        []string{"migrate"},
+		Env:         []string{"DB_PASSWORD=s3cret"},
+		Files:       map[string][]byte{"config.json": []byte(`{"a":1}`)},
+		MountPath:   "/app",
+		Labels:      map[string]string{"stratum.execution_id": "1"},
+		CPUMillis:   500,
+		MemoryBytes: 1 << 30,
+	})
+	if err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want the job and its secret", len(reqs))
+	}
+	job, secret := reqs[0], reqs[1]
+	for _, r := range reqs {
+		if r.Method != http.MethodPost || r.Auth != "Bearer sa-token" {
+			t.Fatalf("got %s with auth %q, want an authenticated POST", r.Method, r.Auth)
+		}
+	}
+
+	raw, _ := json.Marshal(job.Body)
+	if strings.Contains(string(raw), "s3cret") {
+		t.Fatalf("job spec carries the env value: %s", raw)
+	}
+	spec := job.Body["spec"].(map[string]interface{})
+	if spec["backoffLimit"] != float64(0) {
+		t.Fatalf("backoffLimit = %v, want 0", spec["backoffLimit"])
+	}
+	template := spec["template"].(map[string]interface{})
+	labels := template["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+	if labels[kubeRunLabel] != "stratum-run-1" || labels["stratum.execution_id"] != "1" {
+		t.Fatalf("pod labels = %v", labels)
+	}
+	podSpec := template["spec"].(map[string]interface{})
+	container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	if container["image"] != "stratum/engine:1.2" {
+		t.Fatalf("image = %v", container["image"])
+	}
+	limits := container["resources"].(map[string]interface{})["limits"].(map[string]interface{})
+	if limits["cpu"] != "500m" || limits["memory"] != "1073741824" {
+		t.Fatalf("limits = %v", limits)
+	}
+	envRef := container["env"].([]interface{})[0].(map[string]interface{})
+	if envRef["name"] != "DB_PASSWORD" || envRef["valueFrom"] == nil {
+		t.Fatalf("env = %v, want a secret reference", envRef)
+	}
+	mount := container["volumeMounts"].([]interface{})[0].(map[string]interface{})
+	if mount["mountPath"] != "/app/config.json" || mount["readOnly"] != true {
+		t.Fatalf("mount = %v", mount)
+	}
+	if podSpec["imagePullSecrets"] == nil {
+		t.Fatal("pod spec has no image pull secrets")
+	}
+
+	if secret.Path != "/api/v1/namespaces/stratum/secrets" {
+		t.Fatalf("secret posted to %s", secret.Path)
+	}
+	owner := secret.Body["metadata"].(map[string]interface{})["ownerReferences"].([]interface{})[0].(map[string]interface{})
+	if owner["uid"] != "job-uid" || owner["name"] != "stratum-run-1" {
+		t.Fatalf("secret owner = %v, want the job", owner)
+	}
+	data := secret.Body["data"].(map[string]interface{})
+	if got, _ := base64.StdEncoding.DecodeString(data[kubeEnvKeyPrefix+"DB_PASSWORD"].(string)); string(got) != "s3cret" {
+		t.Fatalf("secret env = %q", got)
+	}
+	if got, _ := base64.StdEncoding.DecodeString(data["config.json"].(string)); string(got) != `{"a":1}` {
+		t.Fatalf("secret file = %q", got)
+	}
+}
+
+func TestKubernetesLaunchDeletesJobWhenSecretFails(t *testing.T) {
+	runner, requests := fakeAPIServer(t, func(w http.ResponseWriter, r kubeRequest) {
+		if strings.HasSuffix(r.Path, "/secrets") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"secrets is forbidden"}`))
+			return
+		}
+		w.Write([]byte(`{"metadata":{"uid":"job-uid"}}`))
+	})
+
+	err := runner.Launch(context.Background(), KubernetesJob{Name: "stratum-run-2", Image: "stratum/engine"})
+	var apiErr *KubernetesAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden || apiErr.Message != "secrets is forbidden" {
+		t.Fatalf("err = %v, want the API server's refusal", err)
+	}
+	reqs := requests()
+	last := reqs[len(reqs)-1]
+	if last.Method != http.MethodDelete || last.Path != "/apis/batch/v1/namespaces/stratum/jobs/stratum-run-2" {
+		t.Fatalf("last request = %s %s, want the job deleted", last.Method, last.Path)
+	}
+}
+
+func TestKubernetesWaitForPod(t *testing.T) {
+	pods := func(body string) func(http.ResponseWriter, kubeRequest) {
+		return func(w http.ResponseWriter, r kubeRequest) { w.Write([]byte(body)) }
+	}
+
+	t.Run("running", func(t *testing.T) {
+		runner, requests := fakeAPIServer(t, pods(`{"items":[
+			{"metadata":{"name":"pending"},"status":{"phase":"Pending"}},
+			{"metadata":{"name":"stratum-run-3-abc"},"status":{"phase":"Running","containerStatuses":[
+				{"name":"sidecar","imageID":"sidecar@sha256:111","state":{"running":{}}},
+				{"name":"engine","imageID":"docker-pullable://stratum/engine@sha256:222","state":{"running":{}}}
+			]}}
+		]}`))
+		pod, err := runner.WaitForPod(context.Background(), "stratum-run-3")
+		if err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+		if pod.Name != "stratum-run-3-abc" || pod.ImageID != "stratum/engine@sha256:222" {
+			t.Fatalf("pod = %+v", pod)
+		}
+		req := requests()[0]
+		if req.Path != "/api/v1/namespaces/stratum/pods" || req.Query != "labelSelector=stratum.run%3Dstratum-run-3" {
+			t.Fatalf("listed %s?%s", req.Path, req.Query)
+		}
+	})
+
+	t.Run("image cannot be pulled", func(t *testing.T) {
+		runner, _ := fakeAPIServer(t, pods(`{"items":[
+			{"metadata":{"name":"stratum-run-4-abc"},"status":{"phase":"Pending","containerStatuses":[
+				{"name":"engine","state":{"waiting":{"reason":"ImagePullBackOff","message":"not found"}}}
+			]}}
+		]}`))
+		_, err := runner.WaitForPod(context.Background(), "stratum-run-4")
+		if err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+			t.Fatalf("err = %v, want the pull failure", err)
+		}
+	})
+
+	t.Run("pod failed", func(t *testing.T) {
+		runner, _ := fakeAPIServer(t, pods(`{"items":[
+			{"metadata":{"name":"stratum-run-5-abc"},"status":{"phase":"Failed","reason":"Evicted","message":"low memory"}}
+		]}`))
+		_, err := runner.WaitForPod(context.Background(), "stratum-run-5")
+		if err == nil || !strings.Contains(err.Error(), "Evicted") {
+			t.Fatalf("err = %v, want the pod failure", err)
+		}
+	})
+
+	t.Run("cancelled while pending", func(t *testing.T) {
+		runner, _ := fakeAPIServer(t, pods(`{"items":[]}`))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := runner.WaitForPod(ctx, "stratum-run-6"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want the context's", err)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/network"
+)
+
+// ErrNetworkNotFound is returned when a connection's Docker network does not exist
+// on the host the engine runs on.
+var ErrNetworkNotFound = errors.New("docker network not found")
+
+// CheckNetworks returns an error wrapping ErrNetworkNotFound for the first of
+// networks that does not exist on this host.
+func (h *DockerHost) CheckNetworks(ctx context.Context, networks ...string) error {
+	for _, name := range networks {
+		if _, err := h.Client.NetworkInspect(ctx, name, network.InspectOptions{}); err != nil {
+			if cerrdefs.IsNotFound(err) {
+				return fmt.Errorf("%w: %q does not exist on docker host %s", ErrNetworkNotFound, name, h.Name)
+			}
+			return fmt.Errorf("inspect docker network %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// JoinNetworks connects a running container to those of networks it is not on
+// yet. The engine container stays connected, so later engine commands against
+// connections on the same networks resolve their hosts too.
+func (h *DockerHost) JoinNetworks(ctx context.Context, containerName string, networks ...string) error {
+	if len(networks) == 0 {
+		return nil
+	}
+	if err := h.CheckNetworks(ctx, networks...); err != nil {
+		return err
+	}
+	info, err := h.Client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return fmt.Errorf("inspect container %s: %w", containerName, err)
+	}
+	for _, name := range networks {
+		if info.NetworkSettings != nil {
+			if _, joined := info.NetworkSettings.Networks[name]; joined {
+				continue
+			}
+		}
+		if err := h.Client.NetworkConnect(ctx, name, containerName, nil); err != nil {
+			return fmt.Errorf("connect container %s to docker network %q: %w", containerName, name, err)
+		}
+	}
+	return nil
+}
+
+// EndpointsConfig is the networking config of a container created on networks.
+func EndpointsConfig(networks []string) *network.NetworkingConfig {
+	if len(networks) == 0 {
+		return nil
+	}
+	endpoints := make(map[string]*network.EndpointSettings, len(networks))
+	for _, name := range networks {
+		endpoints[name] = &network.EndpointSettings{}
+	}
+	return &network.NetworkingConfig{EndpointsConfig: endpoints}
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/rs/zerolog"
+)
+
+// Image pull states reported by ImageWarmer.
+const (
+	PullStatePulling = "pulling"
+	PullStateReady   = "ready"
+	PullStateFailed  = "failed"
+)
+
+const (
+	pullMaxAttempts    = 6
+	pullInitialBackoff = 2 * time.Second
+	pullMaxBackoff     = time.Minute
+	pullAttemptTimeout = 10 * time.Minute
+)
+
+// PullStatus is the last known pull state of one image on one Docker host.
+type PullStatus struct {
+	Host        string     `json:"host"`
+	Image       string     `json:"image"`
+	State       string     `json:"state"`
+	Digest      string     `json:"digest,omitempty"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ImageWarmer pulls engine images onto every Docker host ahead of executions so the
+// first run after a deploy or an image change does not pay the pull cost.
+type ImageWarmer struct {
+	hosts  *HostPool
+	auth   *RegistryAuth
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	statuses map[string]*PullStatus
+}
+
+func NewImageWarmer(hosts *HostPool, auth *RegistryAuth, logger zerolog.Logger) *ImageWarmer {
+	return &ImageWarmer{
+		hosts:    hosts,
+		auth:     auth,
+		logger:   logger.With().Str("component", "image_warmer").Logger(),
+		statuses: make(map[string]*PullStatus),
+	}
+}
+
+// Warm starts a background pull of ref on every host, retrying with exponential
+// backoff. Hosts already pulling the image are left alone. The returned statuses are
+// snapshots taken when the pulls were scheduled.
+func (w *ImageWarmer) Warm(ctx context.Context, ref string) []PullStatus {
+	out := make([]PullStatus, 0, len(w.hosts.Hosts()))
+	for _, h := range w.hosts.Hosts() {
+		out = append(out, w.warmHost(ctx, h, ref))
+	}
+	return out
+}
+
+func (w *ImageWarmer) warmHost(ctx context.Context, h *DockerHost, ref string) PullStatus {
+	key := statusKey(h.Name, ref)
+	w.mu.Lock()
+	if st, ok := w.statuses[key]; ok && st.State == PullStatePulling {
+		snapshot := *st
+		w.mu.Unlock()
+		return snapshot
+	}
+	st := &PullStatus{Host: h.Name, Image: ref, State: PullStatePulling, StartedAt: time.Now().UTC()}
+	w.statuses[key] = st
+	snapshot := *st
+	w.mu.Unlock()
+
+	go w.pullWithRetry(ctx, h, ref)
+	return snapshot
+}
+
+// Statuses returns the pull status of every image this worker has warmed.
+func (w *ImageWarmer) Statuses() []PullStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]PullStatus, 0, len(w.statuses))
+	for _, st := range w.statuses {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Image != out[j].Image {
+			return out[i].Image < out[j].Image
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+func (w *ImageWarmer) pullWithRetry(ctx context.Context, h *DockerHost, ref string) {
+	key := statusKey(h.Name, ref)
+	backoff := pullInitialBackoff
+	for attempt := 1; attempt <= pullMaxAttempts; attempt++ {
+		digest, err := w.pull(ctx, h.Client, ref)
+		w.update(key, func(st *PullStatus) {
+			st.Attempts = attempt
+			if err != nil {
+				st.LastError = err.Error()
+				return
+			}
+			now := time.Now().UTC()
+			st.State, st.Digest, st.LastError, st.CompletedAt = PullStateReady, digest, "", &now
+		})
+		if err == nil {
+			w.logger.Info().Str("host", h.Name).Str("image", ref).Str("digest", digest).Int("attempts", attempt).Msg("engine image ready")
+			return
+		}
+		w.logger.Warn().Err(err).Str("host", h.Name).Str("image", ref).Int("attempt", attempt).Msg("engine image pull failed")
+
+		if attempt == pullMaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			w.markFailed(key)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, pullMaxBackoff)
+	}
+	w.markFailed(key)
+}
+
+func (w *ImageWarmer) markFailed(key string) {
+	w.update(key, func(st *PullStatus) {
+		now := time.Now().UTC()
+		st.State, st.CompletedAt = PullStateFailed, &now
+	})
+}
+
+func (w *ImageWarmer) pull(ctx context.Context, docker *client.Client, ref string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pullAttemptTimeout)
+	defer cancel()
+
+	opts, err := w.auth.PullOptions(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("pull %s: %w", ref, err)
+	}
+	reader, err := docker.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return "", fmt.Errorf("pull %s: %w", ref, err)
+	}
+	_, copyErr := io.Copy(io.Discard, reader)
+	reader.Close()
+	if copyErr != nil {
+		return "", fmt.Errorf("pull %s: %w", ref, copyErr)
+	}
+
+	inspect, err := docker.ImageInspect(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("inspect %s: %w", ref, err)
+	}
+	if len(inspect.RepoDigests) > 0 {
+		return inspect.RepoDigests[0], nil
+	}
+	return inspect.ID, nil
+}
+
+func (w *ImageWarmer) update(key string, fn func(*PullStatus)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if st, ok := w.statuses[key]; ok {
+		fn(st)
+	}
+}
+
+func statusKey(host, ref string) string {
+	return host + "|" + ref
+}
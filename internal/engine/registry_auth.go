@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// DefaultRegistryTokenRefresh is how long credentials issued by a credential
+// helper are reused. ECR tokens are valid for twelve hours.
+const DefaultRegistryTokenRefresh = 6 * time.Hour
+
+const credentialHelperTimeout = 30 * time.Second
+
+// dockerHubServerAddress is the server address Docker Hub credentials are kept under.
+const dockerHubServerAddress = "https://index.docker.io/v1/"
+
+// RegistryCredentialSource looks up the credential configured for a registry
+// host. It returns sql.ErrNoRows for registries without one.
+type RegistryCredentialSource interface {
+	GetRegistryCredential(registry string) (models.RegistryCredential, string, error)
+}
+
+// helperCredential is what a Docker credential helper prints for "get".
+type helperCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+type cachedAuth struct {
+	encoded   string
+	expiresAt time.Time
+}
+
+// RegistryAuth supplies the X-Registry-Auth header of image pulls from private
+// registries. Credentials from helpers are cached for the refresh period and
+// fetched again when the registry rejects them.
+type RegistryAuth struct {
+	source  RegistryCredentialSource
+	refresh time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedAuth
+}
+
+func NewRegistryAuth(source RegistryCredentialSource, refresh time.Duration) *RegistryAuth {
+	if refresh <= 0 {
+		refresh = DefaultRegistryTokenRefresh
+	}
+	return &RegistryAuth{
+		source:  source,
+		refresh: refresh,
+		cache:   make(map[string]cachedAuth),
+	}
+}
+
+// PullOptions returns the options pulling ref with the credentials of its
+// registry. Refs from registries without credentials are pulled anonymously. A
+// nil RegistryAuth always pulls anonymously.
+func (a *RegistryAuth) PullOptions(ctx context.Context, ref string) (image.PullOptions, error) {
+	if a == nil {
+		return image.PullOptions{}, nil
+	}
+	host := models.ImageRegistry(ref)
+	encoded, err := a.encodedAuth(ctx, host)
+	if err != nil {
+		return image.PullOptions{}, err
+	}
+	if encoded == "" {
+		return image.PullOptions{}, nil
+	}
+	return image.PullOptions{
+		RegistryAuth: encoded,
+		// The daemon rejected the credentials: drop cached helper tokens and retry
+		// once with fresh ones.
+		PrivilegeFunc: func(ctx context.Context) (string, error) {
+			a.Invalidate(host)
+			return a.encodedAuth(ctx, host)
+		},
+	}, nil
+}
+
+// Invalidate drops the cached credentials of a registry host, e.g. after its
+// credential was changed.
+func (a *RegistryAuth) Invalidate(host string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.cache, host)
+}
+
+func (a *RegistryAuth) encodedAuth(ctx context.Context, host string) (string, error) {
+	a.mu.Lock()
+	if cached, ok := a.cache[host]; ok && time.Now().Before(cached.expiresAt) {
+		a.mu.Unlock()
+		return cached.encoded, nil
+	}
+	a.mu.Unlock()
+
+	cred, password, err := a.source.GetRegistryCredential(host)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load credentials of registry %s: %w", host, err)
+	}
+
+	serverAddress := host
+	if host == models.DockerHubRegistry {
+		serverAddress = dockerHubServerAddress
+	}
+	auth := registry.AuthConfig{ServerAddress: serverAddress}
+	ttl := a.refresh
+	switch cred.AuthType {
+	case models.RegistryAuthBasic:
+		if cred.Username != nil {
+			auth.Username = *cred.Username
+		}
+		auth.Password = password
+		// Stored credentials only change through the API, which invalidates them.
+		ttl = time.Hour
+	case models.RegistryAuthHelper:
+		if cred.Helper == nil {
+			return "", fmt.Errorf("registry %s has no credential helper", host)
+		}
+		out, err := runCredentialHelper(ctx, *cred.Helper, serverAddress)
+		if err != nil {
+			return "", fmt.Errorf("credential helper %s for registry %s: %w", *cred.Helper, host, err)
+		}
+		// Helpers return "<token>" as the username of identity tokens.
+		if out.Username == "<token>" {
+			auth.IdentityToken = out.Secret
+		} else {
+			auth.Username, auth.Password = out.Username, out.Secret
+		}
+	default:
+		return "", fmt.Errorf("registry %s has unknown auth type %q", host, cred.AuthType)
+	}
+
+	encoded, err := registry.EncodeAuthConfig(auth)
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	a.cache[host] = cachedAuth{encoded: encoded, expiresAt: time.Now().Add(ttl)}
+	a.mu.Unlock()
+	return encoded, nil
+}
+
+// runCredentialHelper runs "docker-credential-<helper> get" the way the Docker CLI
+// does, passing the server URL on stdin.
+func runCredentialHelper(ctx context.Context, helper, serverURL string) (helperCredential, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return helperCredential{}, fmt.Errorf("%w: %s", err, msg)
+	}
+	var out helperCredential
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return helperCredential{}, fmt.Errorf("decode helper output: %w", err)
+	}
+	if out.Secret == "" {
+		return helperCredential{}, errors.New("helper returned no secret")
+	}
+	return out, nil
+}
@@ -0,0 +1,122 @@
+package execsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	tc "go.temporal.io/sdk/client"
+)
+
+// Reconciler fails executions that are still pending, running or paused although their
+// workflow has closed. The workflow settles its execution itself on errors and
+// cancellation; this covers the endings it never sees, such as a workflow timeout
+// or termination.
+type Reconciler struct {
+	jobs      repository.JobRepository
+	temporal  tc.Client
+	notifier  notification.Service
+	grace     time.Duration
+	batchSize int
+	logger    zerolog.Logger
+}
+
+func New(jobs repository.JobRepository, temporalClient tc.Client, notifier notification.Service, grace time.Duration, batchSize int, logger zerolog.Logger) *Reconciler {
+	if grace <= 0 {
+		grace = 15 * time.Minute
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Reconciler{
+		jobs:      jobs,
+		temporal:  temporalClient,
+		notifier:  notifier,
+		grace:     grace,
+		batchSize: batchSize,
+		logger:    logger.With().Str("component", "execution_reconciler").Logger(),
+	}
+}
+
+// RunOnce checks one batch of executions that have not changed for the grace
+// period.
+func (r *Reconciler) RunOnce(ctx context.Context) error {
+	execs, err := r.jobs.ListActiveExecutions(time.Now().Add(-r.grace), r.batchSize)
+	if err != nil {
+		return fmt.Errorf("list active executions: %w", err)
+	}
+	for _, exec := range execs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reason, closed, err := r.workflowOutcome(ctx, temporal.ExecutionWorkflowID(exec.ID, exec.WorkflowID))
+		if err != nil {
+			r.logger.Warn().Err(err).Str("execution_id", exec.ID).Msg("failed to describe execution workflow")
+			continue
+		}
+		if !closed {
+			continue
+		}
+		failed, err := r.jobs.FailActiveExecution(exec.TenantID, exec.ID, reason)
+		if err != nil {
+			r.logger.Error().Err(err).Str("execution_id", exec.ID).Msg("failed to fail orphaned execution")
+			continue
+		}
+		if failed {
+			r.logger.Warn().Str("execution_id", exec.ID).Str("reason", reason).Msg("marked orphaned execution failed")
+			r.notify(ctx, exec, reason)
+		}
+	}
+	return nil
+}
+
+// workflowOutcome reports whether the execution's workflow has closed and, if so,
+// why the execution failed.
+func (r *Reconciler) workflowOutcome(ctx context.Context, workflowID string) (string, bool, error) {
+	resp, err := r.temporal.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return "Execution workflow no longer exists.", true, nil
+		}
+		return "", false, err
+	}
+	switch status := resp.GetWorkflowExecutionInfo().GetStatus(); status {
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		return "", false, nil
+	case enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
+		return "Execution workflow timed out.", true, nil
+	case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
+		return "Execution workflow was terminated.", true, nil
+	case enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
+		return "Execution was cancelled.", true, nil
+	case enums.WORKFLOW_EXECUTION_STATUS_FAILED:
+		return "Execution workflow failed.", true, nil
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		return "Execution workflow completed without recording a result.", true, nil
+	default:
+		return fmt.Sprintf("Execution workflow ended as %s.", status), true, nil
+	}
+}
+
+func (r *Reconciler) notify(ctx context.Context, exec models.JobExecution, reason string) {
+	if r.notifier == nil {
+		return
+	}
+	def, err := r.jobs.GetJobDefinitionByID(exec.TenantID, exec.JobDefinitionID)
+	if err != nil {
+		r.logger.Warn().Err(err).Str("execution_id", exec.ID).Msg("unable to load definition for failure notification")
+		return
+	}
+	if err := r.notifier.NotifyExecutionFailed(ctx, exec.TenantID, exec.JobDefinitionID, exec.ID, def.Name, reason, exec.ActivityAttempts); err != nil {
+		r.logger.Warn().Err(err).Str("execution_id", exec.ID).Msg("failed to publish execution failed notification")
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/middleware"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+type AdminHandler struct {
+	warmer      *engine.ImageWarmer
+	hosts       *engine.HostPool
+	registries  repository.RegistryRepository
+	engines     repository.EngineRepository
+	auth        *engine.RegistryAuth
+	engineImage string
+	latency     *middleware.LatencyTracker
+	// warmCtx outlives individual requests so pulls continue after the response.
+	warmCtx context.Context
+	logger  zerolog.Logger
+}
+
+type pullImagePayload struct {
+	Image string `json:"image"`
+}
+
+type workerStatusResponse struct {
+	TaskQueue         string              `json:"task_queue"`
+	PriorityTaskQueue string              `json:"priority_task_queue"`
+	EngineImage       string              `json:"engine_image"`
+	Images            []engine.PullStatus `json:"images"`
+}
+
+func NewAdminHandler(warmCtx context.Context, warmer *engine.ImageWarmer, hosts *engine.HostPool, registries repository.RegistryRepository, engines repository.EngineRepository, auth *engine.RegistryAuth, engineImage string, latency *middleware.LatencyTracker, logger zerolog.Logger) *AdminHandler {
+	return &AdminHandler{warmer: warmer, hosts: hosts, registries: registries, engines: engines, auth: auth, engineImage: engineImage, latency: latency, warmCtx: warmCtx, logger: logger}
+}
+
+// PullEngineImage warms an engine image on every Docker host ahead of a rollout. Without a
+// body it re-pulls the configured image, picking up a moved tag.
+func (h *AdminHandler) PullEngineImage(w http.ResponseWriter, r *http.Request) {
+	var payload pullImagePayload
+	if err := decodeAllowEmpty(r, &payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	ref := strings.TrimSpace(payload.Image)
+	if ref == "" {
+		ref = h.engineImage
+	}
+
+	statuses := h.warmer.Warm(h.warmCtx, ref)
+	h.logger.Info().Str("image", ref).Msg("Engine image pull requested")
+	writeJSON(w, http.StatusAccepted, statuses)
+}
+
+// WorkerStatus reports the worker's engine image and the pull state of warmed images.
+func (h *AdminHandler) WorkerStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, workerStatusResponse{
+		TaskQueue:         temporal.TaskQueueName,
+		PriorityTaskQueue: temporal.PriorityTaskQueueName,
+		EngineImage:       h.engineImage,
+		Images:            h.warmer.Statuses(),
+	})
+}
+
+// DockerHosts reports reachability and load of every Docker host engine work runs on.
+func (h *AdminHandler) DockerHosts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"hosts": h.hosts.Statuses(r.Context()),
+	})
+}
+
+// Latency returns rolling per-route latency percentiles and budget state for this
+// API instance.
+func (h *AdminHandler) Latency(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"routes": h.latency.Snapshot(),
+	})
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const (
+	defaultMaintenanceMessage  = "Stratum is undergoing scheduled maintenance. Changes are disabled until it completes."
+	maintenanceCompleteMessage = "Maintenance is complete. Thank you for your patience."
+	// maintenanceRefresh bounds how stale the guard's view of maintenance mode may
+	// be on replicas other than the one it was toggled on.
+	maintenanceRefresh = 10 * time.Second
+)
+
+// AnnouncementHandler serves UI banner announcements and the platform maintenance
+// switch.
+type AnnouncementHandler struct {
+	repo   repository.AnnouncementRepository
+	logger zerolog.Logger
+
+	mu          sync.Mutex
+	maintenance models.MaintenanceMode
+	checkedAt   time.Time
+}
+
+type announcementRequest struct {
+	TenantID *string    `json:"tenant_id"`
+	Message  string     `json:"message"`
+	Severity string     `json:"severity"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+func NewAnnouncementHandler(repo repository.AnnouncementRepository, logger zerolog.Logger) *AnnouncementHandler {
+	return &AnnouncementHandler{repo: repo, logger: logger.With().Str("handler", "announcement").Logger()}
+}
+
+// Active returns the banners the caller's UI should show: global announcements and
+// those of the caller's tenant, most severe first.
+func (h *AnnouncementHandler) Active(w http.ResponseWriter, r *http.Request) {
+	tid, _ := authz.TenantIDFromRequest(r)
+	announcements, err := h.repo.ActiveAnnouncements(tid)
+	if err != nil {
+		http.Error(w, "Failed to list announcements: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, announcements)
+}
+
+// List returns every announcement, including scheduled and ended ones. ?tenant_id=
+// limits the list to one tenant's announcements.
+func (h *AnnouncementHandler) List(w http.ResponseWriter, r *http.Request) {
+	announcements, err := h.repo.ListAnnouncements(strings.TrimSpace(r.URL.Query().Get("tenant_id")))
+	if err != nil {
+		http.Error(w, "Failed to list announcements: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, announcements)
+}
+
+func (h *AnnouncementHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req announcementRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	a := models.Announcement{Source: models.AnnouncementSourceManual}
+	if req.TenantID != nil && strings.TrimSpace(*req.TenantID) != "" {
+		tenantID := strings.TrimSpace(*req.TenantID)
+		a.TenantID = &tenantID
+	}
+	if !applyAnnouncementRequest(w, &a, req) {
+		return
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		a.CreatedBy = &uid
+	}
+	created, err := h.repo.CreateAnnouncement(a)
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			http.Error(w, "Tenant not found", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to create announcement: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// Update changes an announcement's message, severity and window; omitted fields
+// keep their values, except ends_at, whose omission leaves the banner up
+// indefinitely. Its audience, the tenant or every tenant, is fixed at creation.
+func (h *AnnouncementHandler) Update(w http.ResponseWriter, r *http.Request) {
+	current, err := h.repo.GetAnnouncement(mux.Vars(r)["announcementID"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load announcement: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req announcementRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Message == "" {
+		req.Message = current.Message
+	}
+	if req.Severity == "" {
+		req.Severity = current.Severity
+	}
+	if req.StartsAt == nil {
+		req.StartsAt = &current.StartsAt
+	}
+	if !applyAnnouncementRequest(w, &current, req) {
+		return
+	}
+	updated, err := h.repo.UpdateAnnouncement(current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update announcement: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *AnnouncementHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.DeleteAnnouncement(mux.Vars(r)["announcementID"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete announcement: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AnnouncementHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	mode, err := h.repo.GetMaintenanceMode()
+	if err != nil {
+		http.Error(w, "Failed to load maintenance mode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, mode)
+}
+
+// SetMaintenance toggles maintenance mode. Enabling it posts a critical global
+// banner with the message; disabling it ends that banner and briefly announces
+// that maintenance is over.
+func (h *AnnouncementHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if !req.Enabled {
+		message = ""
+	} else if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	var updatedBy *string
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		updatedBy = &uid
+	}
+	mode, err := h.repo.SetMaintenanceMode(req.Enabled, message, maintenanceCompleteMessage, updatedBy)
+	if err != nil {
+		http.Error(w, "Failed to update maintenance mode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.mu.Lock()
+	h.maintenance, h.checkedAt = mode, time.Now()
+	h.mu.Unlock()
+	h.logger.Info().Bool("enabled", mode.Enabled).Msg("maintenance mode updated")
+	writeJSON(w, http.StatusOK, mode)
+}
+
+// MaintenanceGuard rejects changes while maintenance mode is on. Reads stay
+// available, and super admins may still change anything, including switching
+// maintenance mode off. It must run after authentication.
+func (h *AnnouncementHandler) MaintenanceGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		mode := h.currentMaintenance()
+		if mode.Enabled {
+			roles, _ := authz.RolesFromRequest(r)
+			if !models.HasAtLeast(roles, models.RoleSuperAdmin) {
+				w.Header().Set("Retry-After", "300")
+				http.Error(w, "Service under maintenance: "+mode.Message, http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentMaintenance returns the maintenance mode, reloading it at most every
+// maintenanceRefresh. When it cannot be loaded the last known state is kept.
+func (h *AnnouncementHandler) currentMaintenance() models.MaintenanceMode {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Since(h.checkedAt) < maintenanceRefresh {
+		return h.maintenance
+	}
+	mode, err := h.repo.GetMaintenanceMode()
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("failed to load maintenance mode")
+	} else {
+		h.maintenance = mode
+	}
+	h.checkedAt = time.Now()
+	return h.maintenance
+}
+
+// applyAnnouncementRequest validates the request and copies it onto a.
+func applyAnnouncementRequest(w http.ResponseWriter, a *models.Announcement, req announcementRequest) bool {
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		http.Error(w, "message is required", http.StatusBadRequest)
+		return false
+	}
+	severity := strings.ToLower(strings.TrimSpace(req.Severity))
+	if severity == "" {
+		severity = models.AnnouncementInfo
+	}
+	if !models.ValidAnnouncementSeverity(severity) {
+		http.Error(w, "severity must be info, warning or critical", http.StatusBadRequest)
+		return false
+	}
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(startsAt) {
+		http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return false
+	}
+	a.Message = message
+	a.Severity = severity
+	a.StartsAt = startsAt
+	a.EndsAt = req.EndsAt
+	return true
+}
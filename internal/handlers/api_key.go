@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const apiKeyPrefix = "sk_"
+
+type APIKeyHandler struct {
+	repo   repository.APIKeyRepository
+	audit  repository.AuditRepository
+	logger zerolog.Logger
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyRepository, audit repository.AuditRepository, logger zerolog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		repo:   repo,
+		audit:  audit,
+		logger: logger.With().Str("handler", "api_key").Logger(),
+	}
+}
+
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+	secret := apiKeyPrefix + token
+
+	var createdBy *string
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		createdBy = &uid
+	}
+
+	key, err := h.repo.CreateAPIKey(models.APIKey{
+		TenantID:  tenantID,
+		Name:      name,
+		KeyPrefix: secret[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(secret),
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		http.Error(w, "Failed to create API key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditAPIKeyCreated,
+		TargetType: "api_key",
+		TargetID:   key.ID,
+		Details:    map[string]interface{}{"name": key.Name, "key_prefix": key.KeyPrefix},
+	})
+
+	// The plaintext key is only ever returned here.
+	writeJSON(w, http.StatusCreated, struct {
+		models.APIKey
+		Key string `json:"key"`
+	}{APIKey: key, Key: secret})
+}
+
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.repo.ListAPIKeys(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to list API keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	keyID := mux.Vars(r)["keyID"]
+	if err := h.repo.RevokeAPIKey(tenantID, keyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke API key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditAPIKeyRevoked,
+		TargetType: "api_key",
+		TargetID:   keyID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Middleware authenticates machine clients by API key, accepted either as a bearer
+// token or in the X-API-Key header. Keys act with viewer permissions in their tenant.
+func (h *APIKeyHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := strings.TrimSpace(r.Header.Get("X-API-Key"))
+		if secret == "" {
+			if parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "Bearer" {
+				secret = strings.TrimSpace(parts[1])
+			}
+		}
+		if !strings.HasPrefix(secret, apiKeyPrefix) {
+			http.Error(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := h.repo.AuthenticateAPIKey(hashToken(secret))
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				h.logger.Error().Err(err).Msg("failed to authenticate API key")
+			}
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := authz.WithIdentity(r.Context(), key.TenantID, "", []models.UserRole{models.RoleViewer})
+		ctx = authz.WithAPIKey(ctx, key.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// recordAudit stores an audit event for a change made by the calling user. The change
+// has already happened, so a failure to record it is logged rather than returned.
+func recordAudit(audit repository.AuditRepository, logger zerolog.Logger, r *http.Request, evt models.AuditEvent) {
+	if audit == nil {
+		return
+	}
+	if evt.ActorID == nil {
+		if uid, ok := authz.UserIDFromRequest(r); ok {
+			evt.ActorID = &uid
+		}
+	}
+	if err := audit.RecordEvent(evt); err != nil {
+		logger.Error().Err(err).Str("action", evt.Action).Str("target_id", evt.TargetID).Msg("failed to record audit event")
+	}
+}
@@ -3,22 +3,35 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/config"
 	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/passwords"
 	"github.com/stanstork/stratum-api/internal/repository"
 )
 
+const emailVerificationTTL = 48 * time.Hour
+
 type AuthHandler struct {
-	userRepository repository.UserRepository
-	jwtSecret      string
-	logger         zerolog.Logger
+	userRepository   repository.UserRepository
+	tenantRepository repository.TenantRepository
+	domainRepository repository.EmailDomainRepository
+	tenantStatus     *tenantStatusCache
+	mailer           notification.VerificationMailer
+	verifyURLTpl     string
+	passwords        *passwords.Policy
+	jwtSecret        string
+	logger           zerolog.Logger
 }
 
 type signupRequest struct {
@@ -29,16 +42,45 @@ type signupRequest struct {
 	LastName  string `json:"last_name"`
 }
 
+type signupResponse struct {
+	models.User
+	// JoinStatus is set for domain signups: "active", "pending_verification" or
+	// "pending_approval".
+	JoinStatus string `json:"join_status,omitempty"`
+}
+
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-func NewAuthHandler(db *sql.DB, cfg *config.Config, logger zerolog.Logger) *AuthHandler {
+type changeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func NewAuthHandler(db *sql.DB, cfg *config.Config, mailer notification.VerificationMailer, passwords *passwords.Policy, logger zerolog.Logger) *AuthHandler {
+	return NewAuthHandlerWithRepositories(
+		repository.NewUserRepository(db),
+		repository.NewTenantRepository(db),
+		repository.NewEmailDomainRepository(db),
+		cfg, mailer, passwords, logger,
+	)
+}
+
+// NewAuthHandlerWithRepositories builds the handler on the given repositories, which
+// lets tests serve it on top of in-memory fakes.
+func NewAuthHandlerWithRepositories(users repository.UserRepository, tenants repository.TenantRepository, domains repository.EmailDomainRepository, cfg *config.Config, mailer notification.VerificationMailer, passwords *passwords.Policy, logger zerolog.Logger) *AuthHandler {
 	return &AuthHandler{
-		userRepository: repository.NewUserRepository(db),
-		jwtSecret:      cfg.JWTSecret,
-		logger:         logger,
+		userRepository:   users,
+		tenantRepository: tenants,
+		domainRepository: domains,
+		tenantStatus:     newTenantStatusCache(tenants),
+		mailer:           mailer,
+		verifyURLTpl:     cfg.Email.VerifyURLTemplate,
+		passwords:        passwords,
+		jwtSecret:        cfg.JWTSecret,
+		logger:           logger,
 	}
 }
 
@@ -53,16 +95,114 @@ func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	req.FirstName = strings.TrimSpace(req.FirstName)
 	req.LastName = strings.TrimSpace(req.LastName)
 
+	if !checkPassword(w, r, h.passwords, req.Password) {
+		return
+	}
+
+	// A tenant that verified the email's domain decides how its addresses join, also
+	// when the signup names the tenant itself.
+	domain, err := h.domainRepository.FindVerifiedDomain(models.EmailDomainOf(req.Email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Failed to look up email domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == nil && (req.TenantID == "" || req.TenantID == domain.TenantID) {
+		h.signUpByDomain(w, req, domain)
+		return
+	}
+	if req.TenantID == "" {
+		http.Error(w, "tenant_id is required: no tenant has verified this email domain", http.StatusBadRequest)
+		return
+	}
+
 	user, err := h.userRepository.CreateUser(req.TenantID, req.Email, req.Password, req.FirstName, req.LastName, []models.UserRole{models.RoleViewer})
 	if err != nil {
 		http.Error(w, "Failed to create user: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if err := h.sendVerification(user); err != nil {
+		h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to send verification email")
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(models.User{ID: user.ID, Email: user.Email, TenantID: user.TenantID, Roles: user.Roles})
 }
 
+// signUpByDomain joins the tenant that verified the email's domain as a viewer. The
+// account stays inactive until the address is verified and, when the domain requires
+// it, an admin approves the join.
+func (h *AuthHandler) signUpByDomain(w http.ResponseWriter, req signupRequest, domain models.EmailDomain) {
+	user, err := h.userRepository.CreateInactiveUser(domain.TenantID, req.Email, req.Password, req.FirstName, req.LastName, []models.UserRole{models.RoleViewer})
+	if err != nil {
+		http.Error(w, "Failed to create user: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := models.JoinRequestApproved
+	if domain.RequireApproval {
+		status = models.JoinRequestPending
+	}
+	if _, err := h.domainRepository.CreateJoinRequest(models.DomainJoinRequest{
+		TenantID: domain.TenantID,
+		UserID:   user.ID,
+		Domain:   domain.Domain,
+		Status:   status,
+	}); err != nil {
+		if delErr := h.userRepository.DeleteUser(user.ID); delErr != nil {
+			h.logger.Error().Err(delErr).Str("user_id", user.ID).Msg("failed to roll back domain signup")
+		}
+		http.Error(w, "Failed to join tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sendVerification(user); err != nil {
+		h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to send verification email")
+	}
+
+	joinStatus := "pending_verification"
+	if domain.RequireApproval {
+		joinStatus = "pending_approval"
+	}
+	h.logger.Info().Str("tenant_id", domain.TenantID).Str("domain", domain.Domain).Str("user_id", user.ID).Msg("user joined tenant by email domain")
+
+	writeJSON(w, http.StatusCreated, signupResponse{
+		User:       models.User{ID: user.ID, Email: user.Email, TenantID: user.TenantID, Roles: user.Roles},
+		JoinStatus: joinStatus,
+	})
+}
+
+// LookupSignupDomain tells a signup form which tenant an email address would join.
+func (h *AuthHandler) LookupSignupDomain(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.domainRepository.FindVerifiedDomain(models.EmailDomainOf(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "No tenant for this email domain", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to look up email domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tenant, err := h.tenantRepository.GetTenantByID(domain.TenantID)
+	if err != nil {
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tenant_id":        tenant.ID,
+		"tenant_name":      tenant.Name,
+		"domain":           domain.Domain,
+		"require_approval": domain.RequireApproval,
+	})
+}
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -76,6 +216,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tenant, err := h.tenantRepository.GetTenantByID(user.TenantID)
+	if err != nil {
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tenant.SuspendedAt != nil && !models.HasAtLeast(user.Roles, models.RoleSuperAdmin) {
+		http.Error(w, "Tenant is scheduled for deletion", http.StatusForbidden)
+		return
+	}
+	if user.EmailVerifiedAt == nil && tenant.RequireVerifiedEmail {
+		http.Error(w, "Email address has not been verified", http.StatusForbidden)
+		return
+	}
+
 	rolesClaim := make([]string, 0, len(user.Roles))
 	for _, role := range user.Roles {
 		rolesClaim = append(rolesClaim, string(role))
@@ -99,6 +253,137 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
 }
 
+// VerifyEmail redeems a verification token delivered by email.
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepository.ConsumeEmailVerification(hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to verify email: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Domain signups become usable once their address is verified (and approved).
+	if _, err := h.domainRepository.ActivateDomainMember(user.ID); err != nil {
+		h.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to activate domain member")
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+// ResendVerification issues a fresh verification link. It always answers 202 so the
+// endpoint cannot be used to discover which addresses have accounts.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepository.GetUserByEmail(strings.TrimSpace(req.Email))
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			if err := h.sendVerification(user); err != nil {
+				h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to resend verification email")
+			}
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		h.logger.Error().Err(err).Msg("failed to look up user for verification resend")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ChangeEmail updates the caller's email address and starts verification of the new one.
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authz.UserIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing user context", http.StatusUnauthorized)
+		return
+	}
+
+	var req changeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.userRepository.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.userRepository.AuthenticateUser(current.Email, req.Password); err != nil {
+		http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if strings.EqualFold(current.Email, email) {
+		http.Error(w, "Email is unchanged", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepository.UpdateUserEmail(userID, email)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "Email already in use", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update email: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sendVerification(user); err != nil {
+		h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to send verification email")
+	}
+
+	writeJSON(w, http.StatusOK, tenantUserResponse{
+		ID:        user.ID,
+		TenantID:  user.TenantID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		IsActive:  user.IsActive,
+		Roles:     user.Roles,
+	})
+}
+
+func (h *AuthHandler) sendVerification(user models.User) error {
+	if h.mailer == nil {
+		return errors.New("email sender not configured")
+	}
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	if err := h.userRepository.CreateEmailVerification(user.ID, user.Email, hashToken(token), time.Now().Add(emailVerificationTTL)); err != nil {
+		return err
+	}
+	return h.mailer.SendVerification(user.Email, fmt.Sprintf(h.verifyURLTpl, token))
+}
+
 func (h *AuthHandler) JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
@@ -127,8 +412,17 @@ func (h *AuthHandler) JWTMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "Token expired", http.StatusUnauthorized)
 			return
 		}
-		userRoles, ok := extractRolesFromClaims(claims)
-		if !ok {
+		// An engine container's job token carries no roles. It acts as an editor,
+		// but only on its own execution's callbacks.
+		var userRoles []models.UserRole
+		jobToken := claims.VerifyAudience(jobTokenAudience, true)
+		if jobToken {
+			if !isOwnJobCallback(r, claims) {
+				http.Error(w, "Job token cannot access this endpoint", http.StatusForbidden)
+				return
+			}
+			userRoles = []models.UserRole{models.RoleEditor}
+		} else if userRoles, ok = extractRolesFromClaims(claims); !ok {
 			http.Error(w, "Missing role claim", http.StatusUnauthorized)
 			return
 		}
@@ -138,12 +432,63 @@ func (h *AuthHandler) JWTMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "Missing token claim", http.StatusUnauthorized)
 			return
 		}
+		if !models.HasAtLeast(userRoles, models.RoleSuperAdmin) {
+			suspended, err := h.tenantStatus.suspended(tenantID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					http.Error(w, "Tenant not found", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if suspended {
+				http.Error(w, "Tenant is scheduled for deletion", http.StatusForbidden)
+				return
+			}
+		}
 		userID, _ := claims["sub"].(string)
+		if jobToken {
+			// The subject is the execution, not a user.
+			userID = ""
+		}
 		ctx := authz.WithIdentity(r.Context(), tenantID, userID, userRoles)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// jobTokenAudience marks the tokens handed to engine containers.
+const jobTokenAudience = "job-worker"
+
+// jobCallbackRoutes are the endpoints an engine container calls back on during a run.
+var jobCallbackRoutes = []string{
+	"/jobs/executions/{execID}/checkpoints",
+	"/jobs/executions/{execID}/complete",
+}
+
+// isOwnJobCallback reports whether r is a callback on the execution the job token
+// was issued for.
+func isOwnJobCallback(r *http.Request, claims jwt.MapClaims) bool {
+	execID, _ := claims["sub"].(string)
+	if execID == "" || mux.Vars(r)["execID"] != execID {
+		return false
+	}
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	for _, callback := range jobCallbackRoutes {
+		if strings.HasSuffix(tpl, callback) {
+			return true
+		}
+	}
+	return false
+}
+
 func extractRolesFromClaims(claims jwt.MapClaims) ([]models.UserRole, bool) {
 	rawRoles, ok := claims["roles"]
 	if !ok {
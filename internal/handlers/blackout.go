@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// overrideBlackoutParam lets admins start a run inside a blackout window.
+const overrideBlackoutParam = "override_blackout"
+
+// ListBlackoutWindows returns the tenant's blackout windows.
+func (h *TenantHandler) ListBlackoutWindows(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.blackoutTenant(w, r)
+	if !ok {
+		return
+	}
+	windows, err := h.tenantRepo.ListBlackoutWindows(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to list blackout windows: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, windows)
+}
+
+// CreateBlackoutWindow adds a recurring window in which the tenant's executions may
+// not start.
+func (h *TenantHandler) CreateBlackoutWindow(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.blackoutTenant(w, r)
+	if !ok {
+		return
+	}
+	var window models.BlackoutWindow
+	if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := window.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	window.TenantID = tenantID
+	window.CreatedBy = nil
+	if userID, ok := authz.UserIDFromRequest(r); ok && userID != "" {
+		window.CreatedBy = &userID
+	}
+
+	created, err := h.tenantRepo.CreateBlackoutWindow(window)
+	if err != nil {
+		http.Error(w, "Failed to create blackout window: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditBlackoutWindowCreated,
+		TargetType: "blackout_window",
+		TargetID:   created.ID,
+		Details:    map[string]interface{}{"name": created.Name},
+	})
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// DeleteBlackoutWindow removes one of the tenant's blackout windows.
+func (h *TenantHandler) DeleteBlackoutWindow(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.blackoutTenant(w, r)
+	if !ok {
+		return
+	}
+	windowID := mux.Vars(r)["windowID"]
+	if err := h.tenantRepo.DeleteBlackoutWindow(tenantID, windowID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Blackout window not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete blackout window: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditBlackoutWindowDeleted,
+		TargetType: "blackout_window",
+		TargetID:   windowID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// blackoutTenant returns the tenant of the request path, which admins may only
+// manage for their own tenant.
+func (h *TenantHandler) blackoutTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	requesterRoles, _ := authz.RolesFromRequest(r)
+	tenantID := mux.Vars(r)["tenantID"]
+	if !models.HasAtLeast(requesterRoles, models.RoleSuperAdmin) {
+		if tid, ok := authz.TenantIDFromRequest(r); !ok || tid != tenantID {
+			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			return "", false
+		}
+	}
+	if _, err := h.tenantRepo.GetTenantByID(tenantID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return "", false
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return tenantID, true
+}
+
+// enforceBlackout refuses a run with 409 while one of the tenant's blackout windows
+// is active, unless an admin passed ?override_blackout=true. It returns the name of
+// an overridden window, to be recorded with the run.
+func (h *JobHandler) enforceBlackout(w http.ResponseWriter, r *http.Request, tenantID string) (string, bool) {
+	override := r.URL.Query().Get(overrideBlackoutParam) == "true"
+	if override {
+		roles, _ := authz.RolesFromRequest(r)
+		if !models.HasAtLeast(roles, models.RoleAdmin) {
+			http.Error(w, "Only admins can override blackout windows", http.StatusForbidden)
+			return "", false
+		}
+	}
+	windows, err := h.tenants.ListBlackoutWindows(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to check blackout windows: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	active, until := models.ActiveBlackout(windows, time.Now())
+	if active == nil {
+		return "", true
+	}
+	if override {
+		h.logger.Warn().Str("tenant_id", tenantID).Str("blackout_window", active.Name).Msg("Blackout window overridden")
+		return active.Name, true
+	}
+	http.Error(w, fmt.Sprintf("Runs are blocked by blackout window %q until %s; admins can pass %s=true",
+		active.Name, until.UTC().Format(time.RFC3339), overrideBlackoutParam), http.StatusConflict)
+	return "", false
+}
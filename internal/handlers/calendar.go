@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	calendarDateLayout = "2006-01-02"
+	// maxCalendarDays bounds the span of one calendar request.
+	maxCalendarDays = 92
+	// maxCalendarEvents bounds the events of one calendar response; a response that
+	// hits it is marked truncated.
+	maxCalendarEvents = 5000
+)
+
+type calendarResponse struct {
+	From      string               `json:"from"`
+	To        string               `json:"to"`
+	Timezone  string               `json:"timezone"`
+	Days      []models.CalendarDay `json:"days"`
+	Truncated bool                 `json:"truncated"`
+}
+
+// GetCalendar returns the tenant's executions between ?from= and ?to= (inclusive
+// dates, YYYY-MM-DD) bucketed into days of ?tz= (UTC by default), together with the
+// upcoming runs of scheduled definitions. Without dates it covers the two weeks
+// either side of today. Every day of the range is listed, including days without
+// events.
+func (h *JobHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+
+	tz := strings.TrimSpace(q.Get("tz"))
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		http.Error(w, "unknown timezone "+tz, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from, to := today.AddDate(0, 0, -14), today.AddDate(0, 0, 14)
+	if raw := q.Get("from"); raw != "" {
+		if from, err = time.ParseInLocation(calendarDateLayout, raw, loc); err != nil {
+			http.Error(w, "from must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		if q.Get("to") == "" {
+			to = from.AddDate(0, 0, 28)
+		}
+	}
+	if raw := q.Get("to"); raw != "" {
+		if to, err = time.ParseInLocation(calendarDateLayout, raw, loc); err != nil {
+			http.Error(w, "to must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	if end.After(from.AddDate(0, 0, maxCalendarDays)) {
+		http.Error(w, "the calendar covers at most "+strconv.Itoa(maxCalendarDays)+" days", http.StatusBadRequest)
+		return
+	}
+
+	// One query for the whole range; events are bucketed here.
+	events, err := h.repo.ListCalendarExecutions(tid, from, end, maxCalendarEvents+1)
+	if err != nil {
+		http.Error(w, "Failed to load calendar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := calendarResponse{
+		From:     from.Format(calendarDateLayout),
+		To:       to.Format(calendarDateLayout),
+		Timezone: loc.String(),
+	}
+	if len(events) > maxCalendarEvents {
+		events, resp.Truncated = events[:maxCalendarEvents], true
+	}
+
+	// Upcoming runs of scheduled definitions fill the future part of the range.
+	schedules, err := h.repo.ListSchedules(tid)
+	if err != nil {
+		http.Error(w, "Failed to load schedules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	upcomingFrom := from
+	if upcomingFrom.Before(now) {
+		upcomingFrom = now
+	}
+	for _, schedule := range schedules {
+		remaining := maxCalendarEvents - len(events)
+		if remaining <= 0 {
+			resp.Truncated = true
+			break
+		}
+		runs := schedule.Occurrences(upcomingFrom, end, remaining+1)
+		if len(runs) > remaining {
+			runs, resp.Truncated = runs[:remaining], true
+		}
+		for _, at := range runs {
+			events = append(events, models.CalendarEvent{
+				Kind:              models.CalendarEventSchedule,
+				JobDefinitionID:   schedule.JobDefinitionID,
+				JobDefinitionName: schedule.JobDefinitionName,
+				Status:            models.CalendarStatusScheduled,
+				At:                at,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+
+	index := map[string]int{}
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(calendarDateLayout)
+		index[date] = len(resp.Days)
+		resp.Days = append(resp.Days, models.CalendarDay{
+			Date:   date,
+			Counts: map[string]int{},
+			Events: []models.CalendarEvent{},
+		})
+	}
+	for _, event := range events {
+		i, ok := index[event.At.In(loc).Format(calendarDateLayout)]
+		if !ok {
+			continue
+		}
+		day := &resp.Days[i]
+		day.Events = append(day.Events, event)
+		day.Counts[event.Status]++
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const (
+	evidenceManifestName  = "manifest.json"
+	evidenceSignatureName = "manifest.json.sig"
+)
+
+// ComplianceHandler builds audit evidence bundles.
+type ComplianceHandler struct {
+	audit      repository.AuditRepository
+	signingKey ed25519.PrivateKey
+	logger     zerolog.Logger
+}
+
+// evidenceFile describes one file of an evidence bundle.
+type evidenceFile struct {
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256"`
+	Bytes   int64  `json:"bytes"`
+	Records int    `json:"records"`
+}
+
+// evidenceManifest lists the files of a bundle with their SHA-256 digests. When a
+// signing key is configured, manifest.json.sig holds the base64 Ed25519 signature
+// of manifest.json, verifiable with PublicKey.
+type evidenceManifest struct {
+	GeneratedAt        time.Time      `json:"generated_at"`
+	GeneratedBy        string         `json:"generated_by,omitempty"`
+	TenantID           string         `json:"tenant_id,omitempty"`
+	From               time.Time      `json:"from"`
+	To                 time.Time      `json:"to"`
+	Files              []evidenceFile `json:"files"`
+	SignatureAlgorithm string         `json:"signature_algorithm,omitempty"`
+	PublicKey          string         `json:"public_key,omitempty"`
+}
+
+// NewComplianceHandler builds the handler. signingKey may be nil, in which case
+// bundles are not signed.
+func NewComplianceHandler(audit repository.AuditRepository, signingKey ed25519.PrivateKey, logger zerolog.Logger) *ComplianceHandler {
+	return &ComplianceHandler{audit: audit, signingKey: signingKey, logger: logger}
+}
+
+// Export streams a zip archive of the audit evidence for [from, to): audit events,
+// user and role changes, execution snapshots and API access logs, one JSON object per
+// line, plus a manifest of their SHA-256 digests. ?tenant_id= limits the bundle to
+// one tenant.
+func (h *ComplianceHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		http.Error(w, "from must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	to := time.Now().UTC()
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "to must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	tenantID := q.Get("tenant_id")
+
+	manifest := evidenceManifest{
+		GeneratedAt: time.Now().UTC(),
+		TenantID:    tenantID,
+		From:        from.UTC(),
+		To:          to.UTC(),
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		manifest.GeneratedBy = uid
+	}
+	sections := []struct {
+		name string
+		each func(fn func(json.RawMessage) error) error
+	}{
+		{"audit_events.jsonl", func(fn func(json.RawMessage) error) error {
+			return h.audit.EachAuditEvent(tenantID, from, to, nil, fn)
+		}},
+		{"user_role_changes.jsonl", func(fn func(json.RawMessage) error) error {
+			return h.audit.EachAuditEvent(tenantID, from, to, models.MembershipAuditActions, fn)
+		}},
+		{"execution_snapshots.jsonl", func(fn func(json.RawMessage) error) error {
+			return h.audit.EachExecutionSnapshot(tenantID, from, to, fn)
+		}},
+		{"access_logs.jsonl", func(fn func(json.RawMessage) error) error {
+			return h.audit.EachAccessLog(tenantID, from, to, fn)
+		}},
+	}
+
+	filename := fmt.Sprintf("evidence-%s-%s.zip", from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	zw := zip.NewWriter(w)
+
+	// Once streaming has started the status can no longer change; on failure the
+	// archive is left without a manifest, which verification reports as incomplete.
+	for _, section := range sections {
+		file, err := writeEvidenceFile(zw, section.name, section.each)
+		if err != nil {
+			h.logger.Error().Err(err).Str("file", section.name).Msg("compliance export failed")
+			return
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if h.signingKey != nil {
+		manifest.SignatureAlgorithm = "ed25519"
+		manifest.PublicKey = base64.StdEncoding.EncodeToString(h.signingKey.Public().(ed25519.PublicKey))
+	}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		h.logger.Error().Err(err).Msg("compliance export failed to encode manifest")
+		return
+	}
+	if err := writeZipEntry(zw, evidenceManifestName, raw); err != nil {
+		h.logger.Error().Err(err).Msg("compliance export failed to write manifest")
+		return
+	}
+	if h.signingKey != nil {
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(h.signingKey, raw)) + "\n"
+		if err := writeZipEntry(zw, evidenceSignatureName, []byte(sig)); err != nil {
+			h.logger.Error().Err(err).Msg("compliance export failed to write signature")
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		h.logger.Error().Err(err).Msg("compliance export failed to finish archive")
+		return
+	}
+
+	digest := sha256.Sum256(raw)
+	evt := models.AuditEvent{
+		Action:     models.AuditComplianceExported,
+		TargetType: "evidence_bundle",
+		TargetID:   hex.EncodeToString(digest[:]),
+		Details:    map[string]interface{}{"from": manifest.From, "to": manifest.To, "signed": h.signingKey != nil},
+	}
+	if tenantID != "" {
+		evt.TenantID = &tenantID
+	}
+	recordAudit(h.audit, h.logger, r, evt)
+}
+
+// writeEvidenceFile writes one JSON Lines file into the archive and returns its
+// manifest entry.
+func writeEvidenceFile(zw *zip.Writer, name string, each func(fn func(json.RawMessage) error) error) (evidenceFile, error) {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return evidenceFile{}, err
+	}
+	digest := sha256.New()
+	out := &countingWriter{w: io.MultiWriter(fw, digest)}
+
+	file := evidenceFile{Name: name}
+	err = each(func(row json.RawMessage) error {
+		file.Records++
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+		_, err := out.Write([]byte("\n"))
+		return err
+	})
+	if err != nil {
+		return evidenceFile{}, err
+	}
+	file.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	file.Bytes = out.n
+	return file, nil
+}
+
+func writeZipEntry(zw *zip.Writer, name string, content []byte) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(content)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
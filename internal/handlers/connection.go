@@ -7,69 +7,174 @@ import (
 	"net/http"
 	"regexp"
 
-	"github.com/docker/docker/client"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/middleware"
 	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/version"
+	tc "go.temporal.io/sdk/client"
 )
 
 var ansi = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
 
+// testConnRequest describes the database to test. The raw DSN form is deprecated
+// in favour of the structured fields.
 type testConnRequest struct {
-	Format string `json:"format"`
-	DSN    string `json:"dsn"`
+	Format   string                   `json:"format"`
+	Host     string                   `json:"host"`
+	Port     int                      `json:"port"`
+	Username string                   `json:"username"`
+	Password string                   `json:"password"`
+	DBName   string                   `json:"db_name"`
+	Options  models.ConnectionOptions `json:"options"`
+	DSN      string                   `json:"dsn"`
+	// DockerNetwork and NetworkAlias reach a database on a Docker network, as
+	// they do for a saved connection.
+	DockerNetwork string `json:"docker_network"`
+	NetworkAlias  string `json:"network_alias"`
 }
 
-type ConnectionHandler struct {
-	repo          repository.ConnectionRepository
-	engineClient  *engine.Client
-	containerName string
-	logger        zerolog.Logger
+// testConnByIDRequest carries the password for testing a prompt-mode connection.
+type testConnByIDRequest struct {
+	Password string `json:"password"`
 }
 
-func NewConnectionHandler(repo repository.ConnectionRepository, containerName string, logger zerolog.Logger) *ConnectionHandler {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Docker client")
-	}
+// updateConnectionRequest tells an omitted environment, options or Docker network
+// settings, which keep the current ones, apart from empty ones, which remove them.
+// PauseSchedules pauses the schedules using the connection when the update changes
+// how it connects; they resume once the connection tests successfully.
+type updateConnectionRequest struct {
+	models.Connection
+	Environment    *string                   `json:"environment"`
+	Options        *models.ConnectionOptions `json:"options"`
+	ReadOnly       *bool                     `json:"read_only"`
+	DockerNetwork  *string                   `json:"docker_network"`
+	NetworkAlias   *string                   `json:"network_alias"`
+	PauseSchedules bool                      `json:"pause_schedules"`
+}
+
+// updateConnectionResponse is the updated connection with the definitions whose
+// schedules the update paused.
+type updateConnectionResponse struct {
+	*models.Connection
+	PausedSchedules []string `json:"paused_schedules,omitempty"`
+}
 
-	dr := engine.NewDockerRunner(dockerClient)
-	cli := engine.NewClient(dr, containerName)
-	return &ConnectionHandler{engineClient: cli, containerName: containerName, repo: repo, logger: logger}
+type ConnectionHandler struct {
+	repo           repository.ConnectionRepository
+	jobRepo        repository.JobRepository
+	temporalClient tc.Client
+	hosts          *engine.HostPool
+	containerName  string
+	logger         zerolog.Logger
+}
+
+func NewConnectionHandler(repo repository.ConnectionRepository, jobRepo repository.JobRepository, temporalClient tc.Client, hosts *engine.HostPool, containerName string, logger zerolog.Logger) *ConnectionHandler {
+	return &ConnectionHandler{hosts: hosts, containerName: containerName, repo: repo, jobRepo: jobRepo, temporalClient: temporalClient, logger: logger}
 }
 
 func (h *ConnectionHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
 	var req testConnRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	if req.Format == "" || req.DSN == "" {
-		h.logger.Warn().Msg("Format and DSN are required for testing connection")
-		http.Error(w, "Format and DSN are required", http.StatusBadRequest)
+	if req.Format == "" {
+		h.logger.Warn().Msg("Format is required for testing connection")
+		http.Error(w, "Format is required", http.StatusBadRequest)
 		return
 	}
 
-	logs, err := h.engineClient.TestConnection(r.Context(), req.Format, req.DSN)
-	resp := map[string]string{"logs": ansi.ReplaceAllString(logs, "")}
+	dsn := req.DSN
+	var networks []string
+	if dsn != "" {
+		middleware.SetDeprecationHeaders(w, middleware.Deprecation{
+			Since:  version.RawDSNTestDeprecatedAt,
+			Sunset: version.RawDSNTestSunset,
+		})
+		h.logger.Warn().Str("tenant_id", tid).Str("dsn", models.RedactDSN(dsn)).Msg("Connection tested with deprecated raw DSN")
+	} else {
+		if req.Host == "" || req.Port <= 0 {
+			http.Error(w, "Host and port are required", http.StatusBadRequest)
+			return
+		}
+		conn := models.Connection{
+			DataFormat:    req.Format,
+			Host:          req.Host,
+			Port:          req.Port,
+			Username:      req.Username,
+			Password:      req.Password,
+			DBName:        req.DBName,
+			Options:       req.Options,
+			DockerNetwork: req.DockerNetwork,
+			NetworkAlias:  req.NetworkAlias,
+		}
+		if err := conn.Options.Normalize(conn.DataFormat); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := conn.NormalizeNetwork(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		networks = models.DockerNetworks(&conn)
+		var err error
+		dsn, err = conn.GenerateConnString()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Debug().Str("tenant_id", tid).Str("format", req.Format).Str("host", req.Host).Int("port", req.Port).
+			Str("username", req.Username).Str("password", "****").Msg("Testing connection")
+	}
+
+	engineClient, release, ok := acquireEngine(w, r, h.hosts, tid, h.containerName, networks...)
+	if !ok {
+		return
+	}
+	defer release()
+
+	logs, err := engineClient.TestConnection(r.Context(), req.Format, dsn)
+	resp := map[string]interface{}{"logs": models.ScrubDSN(ansi.ReplaceAllString(logs, ""), dsn)}
 
 	if err != nil {
 		// return both the error and logs
 		w.WriteHeader(http.StatusBadRequest)
-		resp["error"] = ansi.ReplaceAllString(err.Error(), "")
+		resp["error"] = models.ScrubDSN(ansi.ReplaceAllString(err.Error(), ""), dsn)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		resp["status"] = "ok"
+		addServerDetails(resp, req.Format, req.Options, models.DetectServerVersion(logs), logs)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// addServerDetails adds the server version a successful test-conn reported to its
+// response, along with warnings about deprecated MySQL settings for that version.
+func addServerDetails(resp map[string]interface{}, format string, opts models.ConnectionOptions, serverVersion, logs string) {
+	if serverVersion == "" {
+		return
+	}
+	resp["server_version"] = serverVersion
+	if format != "mysql" {
+		return
+	}
+	if warnings := models.MySQLWarnings(opts, serverVersion, logs); len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+}
+
 func (h *ConnectionHandler) TestConnectionByID(w http.ResponseWriter, r *http.Request) {
 	tid, ok := authz.TenantIDFromRequest(r)
 	if !ok {
@@ -77,19 +182,8 @@ func (h *ConnectionHandler) TestConnectionByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 	id := mux.Vars(r)["id"]
-	conn, err := h.repo.Get(tid, id)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Connection not found", http.StatusNotFound)
-			return
-		}
-		h.logger.Error().Err(err).Msgf("Failed to get connection with ID %s", id)
-		http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if conn == nil {
-		h.logger.Warn().Msgf("Connection with ID %s not found", id)
-		http.Error(w, "Connection not found", http.StatusNotFound)
+	conn, ok := h.probeConnection(w, r, tid, id)
+	if !ok {
 		return
 	}
 
@@ -99,23 +193,20 @@ func (h *ConnectionHandler) TestConnectionByID(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Failed to generate connection string: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logs, err := h.engineClient.TestConnection(r.Context(), conn.DataFormat, conn_str)
-	resp := map[string]string{"logs": ansi.ReplaceAllString(logs, "")}
-
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		resp["error"] = ansi.ReplaceAllString(err.Error(), "")
-	} else {
-		w.WriteHeader(http.StatusOK)
-		resp["status"] = "ok"
+	engineClient, release, ok := acquireEngine(w, r, h.hosts, tid, h.containerName, models.DockerNetworks(conn)...)
+	if !ok {
+		return
 	}
+	logs, testErr := engineClient.TestConnection(r.Context(), conn.DataFormat, conn_str)
+	release()
+	resp := map[string]interface{}{"logs": models.ScrubDSN(ansi.ReplaceAllString(logs, ""), conn_str)}
 
 	h.logger.Info().Msgf("Tested connection %s: %s", id, resp["logs"])
 
-	if resp["status"] == "ok" {
-		conn.Status = "valid"
+	if testErr != nil {
+		conn.Status = models.ConnectionStatusInvalid
 	} else {
-		conn.Status = "invalid"
+		conn.Status = models.ConnectionStatusValid
 	}
 	_, err = h.repo.Update(conn)
 	if err != nil {
@@ -124,8 +215,59 @@ func (h *ConnectionHandler) TestConnectionByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	status := http.StatusOK
+	if testErr != nil {
+		status = http.StatusBadRequest
+		resp["error"] = models.ScrubDSN(ansi.ReplaceAllString(testErr.Error(), ""), conn_str)
+	} else {
+		resp["status"] = "ok"
+		// Later connection strings take their driver defaults from this version.
+		if version := models.DetectServerVersion(logs); version != "" && version != conn.ServerVersion {
+			if err := h.repo.SaveServerVersion(tid, id, version); err != nil {
+				h.logger.Warn().Err(err).Str("connection_id", id).Msg("failed to store server version")
+			}
+			conn.ServerVersion = version
+		}
+		addServerDetails(resp, conn.DataFormat, conn.Options, conn.ServerVersion, logs)
+		if resumed := h.resumeRotatedSchedules(r.Context(), conn); len(resumed) > 0 {
+			resp["resumed_schedules"] = resumed
+		}
+	}
+
+	writeJSON(w, status, resp)
+}
+
+// probeConnection loads a saved connection for an engine probe. Prompt-mode
+// connections take their password from the request body for this call only.
+func (h *ConnectionHandler) probeConnection(w http.ResponseWriter, r *http.Request, tid, id string) (*models.Connection, bool) {
+	conn, err := h.repo.Get(tid, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Connection not found", http.StatusNotFound)
+			return nil, false
+		}
+		h.logger.Error().Err(err).Msgf("Failed to get connection with ID %s", id)
+		http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if conn == nil || conn.Ephemeral {
+		h.logger.Warn().Msgf("Connection with ID %s not found", id)
+		http.Error(w, "Connection not found", http.StatusNotFound)
+		return nil, false
+	}
+	if conn.PromptsForCredentials() {
+		var req testConnByIDRequest
+		if err := decodeAllowEmpty(r, &req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return nil, false
+		}
+		if req.Password == "" {
+			http.Error(w, "Connection prompts for credentials; password is required", http.StatusBadRequest)
+			return nil, false
+		}
+		conn.Password = req.Password
+	}
+	return conn, true
 }
 
 func (h *ConnectionHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -134,18 +276,52 @@ func (h *ConnectionHandler) List(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
-	connections, err := h.repo.List(tid)
+	redact := redactsConnections(r)
+	if version, err := h.repo.ListVersion(tid); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to compute connections version")
+	} else {
+		// Viewers and editors get different representations of the same version.
+		if redact {
+			version += "|redacted"
+		}
+		if notModified(w, r, version) {
+			return
+		}
+	}
+	query := r.URL.Query()
+	filter := repository.ConnectionFilter{Environment: query.Get("environment")}
+	if !models.ValidEnvironment(filter.Environment) {
+		http.Error(w, "environment must be prod, staging or dev", http.StatusBadRequest)
+		return
+	}
+	tags, err := models.NormalizeTags(query["tag"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Tags = tags
+
+	connections, err := h.repo.List(tid, filter)
 	if err != nil {
 		http.Error(w, "Failed to list connections: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	for i := range connections {
-		connections[i].Password = "" // Omit password in response for security
+	var payload interface{} = connections
+	if redact {
+		summaries := make([]connectionSummary, 0, len(connections))
+		for _, conn := range connections {
+			summaries = append(summaries, summarizeConnection(*conn))
+		}
+		payload = summaries
+	} else {
+		for i := range connections {
+			connections[i].Password = "" // Omit password in response for security
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(connections); err != nil {
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -166,10 +342,18 @@ func (h *ConnectionHandler) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if conn.Ephemeral {
+		http.Error(w, "Connection not found", http.StatusNotFound)
+		return
+	}
 	conn.Password = "" // Omit password in response for security
 
+	var payload interface{} = conn
+	if redactsConnections(r) {
+		payload = summarizeConnection(*conn)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(conn); err != nil {
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -186,9 +370,26 @@ func (h *ConnectionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	conn.TenantID = tid
+	conn.Ephemeral = false
+	conn.ServerVersion = ""
+	if conn.CredentialMode != "" && !models.ValidCredentialMode(conn.CredentialMode) {
+		http.Error(w, "credential_mode must be stored or prompt", http.StatusBadRequest)
+		return
+	}
+	if !validateLabels(w, &conn) {
+		return
+	}
+	if err := conn.Options.Normalize(conn.DataFormat); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := conn.NormalizeNetwork(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if conn.Status == "" {
-		conn.Status = "untested" // Default status if not provided
+		conn.Status = models.ConnectionStatusUntested // Default status if not provided
 	}
 
 	createdConn, err := h.repo.Create(&conn)
@@ -212,22 +413,102 @@ func (h *ConnectionHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := mux.Vars(r)["id"]
-	var conn models.Connection
-	if err := json.NewDecoder(r.Body).Decode(&conn); err != nil {
+	var req updateConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	conn := req.Connection
+	if req.Environment != nil {
+		conn.Environment = *req.Environment
+	}
+	if req.Options != nil {
+		conn.Options = *req.Options
+	}
+	if req.ReadOnly != nil {
+		conn.ReadOnly = *req.ReadOnly
+	}
+	if req.DockerNetwork != nil {
+		conn.DockerNetwork = *req.DockerNetwork
+	}
+	if req.NetworkAlias != nil {
+		conn.NetworkAlias = *req.NetworkAlias
+	}
 	conn.ID = id // Ensure the ID is set from the URL
 	conn.TenantID = tid
 
+	if conn.CredentialMode != "" && !models.ValidCredentialMode(conn.CredentialMode) {
+		http.Error(w, "credential_mode must be stored or prompt", http.StatusBadRequest)
+		return
+	}
+
+	previous, err := h.repo.Get(tid, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Connection not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if conn.CredentialMode == "" {
+		conn.CredentialMode = previous.CredentialMode
+	}
+	if req.Environment == nil {
+		conn.Environment = previous.Environment
+	}
+	if conn.Tags == nil {
+		conn.Tags = previous.Tags
+	}
+	if req.Options == nil {
+		conn.Options = previous.Options
+	}
+	if req.ReadOnly == nil {
+		conn.ReadOnly = previous.ReadOnly
+	}
+	if req.DockerNetwork == nil {
+		conn.DockerNetwork = previous.DockerNetwork
+	}
+	if req.NetworkAlias == nil {
+		conn.NetworkAlias = previous.NetworkAlias
+	}
+	if !validateLabels(w, &conn) {
+		return
+	}
+	if err := conn.Options.Normalize(conn.DataFormat); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := conn.NormalizeNetwork(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if conn.ReadOnly && !previous.ReadOnly && !h.rejectReadOnlyInUse(w, &conn) {
+		return
+	}
+
 	updatedConn, err := h.repo.Update(&conn)
 	if err != nil {
 		http.Error(w, "Failed to update connection: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	resp := updateConnectionResponse{Connection: updatedConn}
+	// READY definitions were validated against the old endpoint; re-check them.
+	if previous != nil && endpointChanged(previous, updatedConn) {
+		queued, err := h.jobRepo.EnqueueDefinitionRevalidations(tid, id)
+		if err != nil {
+			h.logger.Warn().Err(err).Str("connection_id", id).Msg("failed to enqueue definition revalidation")
+		} else if queued > 0 {
+			h.logger.Info().Int64("count", queued).Str("connection_id", id).Msg("queued definitions for revalidation")
+		}
+		if req.PauseSchedules {
+			resp.PausedSchedules = h.pauseSchedulesForRotation(r.Context(), updatedConn)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedConn); err != nil {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -250,3 +531,34 @@ func (h *ConnectionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
+
+// endpointChanged reports whether an update touches anything that affects where or
+// how the connection reaches its database.
+func endpointChanged(before, after *models.Connection) bool {
+	return before.DataFormat != after.DataFormat ||
+		before.Host != after.Host ||
+		before.Port != after.Port ||
+		before.Username != after.Username ||
+		before.Password != after.Password ||
+		before.CredentialMode != after.CredentialMode ||
+		before.DBName != after.DBName ||
+		before.ReadOnly != after.ReadOnly ||
+		before.DockerNetwork != after.DockerNetwork ||
+		before.NetworkAlias != after.NetworkAlias ||
+		!before.Options.Equal(after.Options)
+}
+
+// validateLabels checks the connection's environment and normalizes its tags.
+func validateLabels(w http.ResponseWriter, conn *models.Connection) bool {
+	if !models.ValidEnvironment(conn.Environment) {
+		http.Error(w, "environment must be prod, staging or dev", http.StatusBadRequest)
+		return false
+	}
+	tags, err := models.NormalizeTags(conn.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	conn.Tags = tags
+	return true
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const benchmarkTimeout = 3 * time.Minute
+
+// Benchmark measures round-trip latency and sequential read throughput to the
+// connection's database and stores the result on the connection, where dry-run
+// reports pick it up for their transfer estimate.
+func (h *ConnectionHandler) Benchmark(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	conn, ok := h.probeConnection(w, r, tid, id)
+	if !ok {
+		return
+	}
+	connStr, err := conn.GenerateConnString()
+	if err != nil {
+		http.Error(w, "Failed to generate connection string: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	engineClient, release, ok := acquireEngine(w, r, h.hosts, tid, h.containerName)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(r.Context(), benchmarkTimeout)
+	defer cancel()
+	raw, err := engineClient.Benchmark(ctx, conn.DataFormat, connStr)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "benchmark timed out", http.StatusGatewayTimeout)
+			return
+		}
+		var exitErr *engine.ExitError
+		if errors.As(err, &exitErr) {
+			http.Error(w, "benchmark failed: "+models.ScrubDSN(ansi.ReplaceAllString(exitErr.Output, ""), connStr), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "benchmark failed: "+models.ScrubDSN(ansi.ReplaceAllString(err.Error(), ""), connStr), http.StatusBadGateway)
+		return
+	}
+
+	var result models.ConnectionBenchmark
+	if err := json.Unmarshal(raw, &result); err != nil {
+		http.Error(w, "Failed to parse engine benchmark: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if result.MeasuredAt.IsZero() {
+		result.MeasuredAt = time.Now().UTC()
+	}
+	if err := h.repo.SaveBenchmark(tid, id, result); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Connection not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to store benchmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("connection_id", id).Float64("latency_ms", result.LatencyMs).
+		Float64("throughput_bytes_per_sec", result.ThroughputBytesPerSec).Msg("connection benchmarked")
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	tc "go.temporal.io/sdk/client"
+)
+
+// Impact lists what depends on a connection before it is deleted or its
+// credentials rotate: the READY definitions using it, the runs their schedules
+// start within the next ?days (7 by default), schedules paused for a rotation and
+// the executions in flight.
+func (h *ConnectionHandler) Impact(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	days := models.DefaultImpactDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > models.MaxImpactDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", models.MaxImpactDays), http.StatusBadRequest)
+			return
+		}
+		days = v
+	}
+
+	conn, err := h.repo.Get(tid, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Connection not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if conn.Ephemeral {
+		http.Error(w, "Connection not found", http.StatusNotFound)
+		return
+	}
+
+	impact, err := h.connectionImpact(tid, id, days)
+	if err != nil {
+		http.Error(w, "Failed to analyze connection impact: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, impact)
+}
+
+func (h *ConnectionHandler) connectionImpact(tenantID, connectionID string, days int) (models.ConnectionImpact, error) {
+	impact := models.ConnectionImpact{
+		ConnectionID:      connectionID,
+		Days:              days,
+		ReadyDefinitions:  []models.ImpactedDefinition{},
+		ScheduledRuns:     []models.ScheduledRun{},
+		PausedSchedules:   []models.JobSchedule{},
+		RunningExecutions: []models.JobExecution{},
+	}
+
+	definitions, err := h.jobRepo.ListDefinitions(tenantID)
+	if err != nil {
+		return impact, err
+	}
+	for _, def := range definitions {
+		if role := def.ConnectionRole(connectionID); role != "" && def.Status == models.DefinitionStatusReady {
+			impact.ReadyDefinitions = append(impact.ReadyDefinitions, models.ImpactedDefinition{ID: def.ID, Name: def.Name, Role: role})
+		}
+	}
+
+	schedules, err := h.schedulesUsing(tenantID, connectionID, definitions)
+	if err != nil {
+		return impact, err
+	}
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+	for _, schedule := range schedules {
+		if schedule.PausedAt != nil {
+			impact.PausedSchedules = append(impact.PausedSchedules, schedule)
+			continue
+		}
+		for _, at := range schedule.Occurrences(now, until, models.MaxImpactRunsPerSchedule) {
+			impact.ScheduledRuns = append(impact.ScheduledRuns, models.ScheduledRun{
+				JobDefinitionID:   schedule.JobDefinitionID,
+				JobDefinitionName: schedule.JobDefinitionName,
+				ScheduleID:        schedule.ScheduleID,
+				RunAt:             at,
+			})
+		}
+	}
+	sort.SliceStable(impact.ScheduledRuns, func(i, j int) bool {
+		return impact.ScheduledRuns[i].RunAt.Before(impact.ScheduledRuns[j].RunAt)
+	})
+
+	executions, err := h.jobRepo.ListConnectionActiveExecutions(tenantID, connectionID)
+	if err != nil {
+		return impact, err
+	}
+	if executions != nil {
+		impact.RunningExecutions = executions
+	}
+	return impact, nil
+}
+
+// connectionSchedules returns the schedules of definitions that use the connection.
+func (h *ConnectionHandler) connectionSchedules(tenantID, connectionID string) ([]models.JobSchedule, error) {
+	definitions, err := h.jobRepo.ListDefinitions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return h.schedulesUsing(tenantID, connectionID, definitions)
+}
+
+// schedulesUsing returns the schedules of those of the tenant's definitions that use
+// the connection.
+func (h *ConnectionHandler) schedulesUsing(tenantID, connectionID string, definitions []models.JobDefinition) ([]models.JobSchedule, error) {
+	using := make(map[string]bool)
+	for _, def := range definitions {
+		if def.ConnectionRole(connectionID) != "" {
+			using[def.ID] = true
+		}
+	}
+	schedules, err := h.jobRepo.ListSchedules(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var matched []models.JobSchedule
+	for _, schedule := range schedules {
+		if using[schedule.JobDefinitionID] {
+			matched = append(matched, schedule)
+		}
+	}
+	return matched, nil
+}
+
+// pauseSchedulesForRotation pauses the running schedules of definitions that use the
+// connection while its credentials rotate, and returns the IDs of the definitions
+// whose schedules it paused. A schedule that fails to pause is logged and skipped.
+func (h *ConnectionHandler) pauseSchedulesForRotation(ctx context.Context, conn *models.Connection) []string {
+	schedules, err := h.connectionSchedules(conn.TenantID, conn.ID)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("connection_id", conn.ID).Msg("failed to list schedules to pause")
+		return nil
+	}
+	paused := []string{}
+	note := fmt.Sprintf("Paused while the credentials of connection %s rotate", conn.Name)
+	for _, schedule := range schedules {
+		if schedule.PausedAt != nil {
+			continue
+		}
+		handle := h.temporalClient.ScheduleClient().GetHandle(ctx, schedule.ScheduleID)
+		if err := handle.Pause(ctx, tc.SchedulePauseOptions{Note: note}); err != nil {
+			h.logger.Warn().Err(err).Str("schedule_id", schedule.ScheduleID).Msg("failed to pause schedule for credential rotation")
+			continue
+		}
+		connID := conn.ID
+		if err := h.jobRepo.SetSchedulePaused(conn.TenantID, schedule.JobDefinitionID, &connID); err != nil {
+			h.logger.Warn().Err(err).Str("schedule_id", schedule.ScheduleID).Msg("failed to record paused schedule")
+			continue
+		}
+		paused = append(paused, schedule.JobDefinitionID)
+	}
+	return paused
+}
+
+// resumeRotatedSchedules resumes the schedules the connection paused for its
+// credential rotation, once the new credentials tested successfully, and returns
+// the IDs of the definitions whose schedules it resumed.
+func (h *ConnectionHandler) resumeRotatedSchedules(ctx context.Context, conn *models.Connection) []string {
+	schedules, err := h.connectionSchedules(conn.TenantID, conn.ID)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("connection_id", conn.ID).Msg("failed to list schedules to resume")
+		return nil
+	}
+	var resumed []string
+	for _, schedule := range schedules {
+		if schedule.PausedForConnectionID == nil || *schedule.PausedForConnectionID != conn.ID {
+			continue
+		}
+		handle := h.temporalClient.ScheduleClient().GetHandle(ctx, schedule.ScheduleID)
+		if err := handle.Unpause(ctx, tc.ScheduleUnpauseOptions{Note: "Connection " + conn.Name + " tested successfully"}); err != nil {
+			h.logger.Warn().Err(err).Str("schedule_id", schedule.ScheduleID).Msg("failed to resume schedule after credential rotation")
+			continue
+		}
+		if err := h.jobRepo.SetSchedulePaused(conn.TenantID, schedule.JobDefinitionID, nil); err != nil {
+			h.logger.Warn().Err(err).Str("schedule_id", schedule.ScheduleID).Msg("failed to record resumed schedule")
+			continue
+		}
+		resumed = append(resumed, schedule.JobDefinitionID)
+	}
+	return resumed
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	maxImportBodyBytes     = 5 << 20
+	maxImportRows          = 500
+	defaultImportTestLimit = 4
+	maxImportTestLimit     = 16
+	importTestTimeout      = 30 * time.Second
+)
+
+var importDataFormats = map[string]struct{}{
+	"pg":         {},
+	"postgres":   {},
+	"postgresql": {},
+	"mysql":      {},
+}
+
+// importRow is one connection in an import file. Port is kept as text so CSV and
+// JSON input share validation.
+type importRow struct {
+	Name       string `json:"name"`
+	DataFormat string `json:"data_format"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	DBName     string `json:"db_name"`
+}
+
+type importRowResult struct {
+	Row          int                     `json:"row"`
+	Name         string                  `json:"name"`
+	ID           string                  `json:"id,omitempty"`
+	Status       string                  `json:"status"` // created, invalid, failed
+	Errors       []string                `json:"errors,omitempty"`
+	Connectivity models.ConnectionStatus `json:"connectivity,omitempty"` // valid, invalid when tested
+	TestError    string                  `json:"test_error,omitempty"`
+}
+
+type importReport struct {
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []importRowResult `json:"results"`
+}
+
+// Import creates connections in bulk from a CSV file (header row required) or a JSON
+// array. With ?test=true each valid row is connectivity-tested before it is stored,
+// at most ?concurrency rows at a time; the outcome is stored as the connection status.
+func (h *ConnectionHandler) Import(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	testConnectivity, _ := strconv.ParseBool(r.URL.Query().Get("test"))
+	concurrency := defaultImportTestLimit
+	if raw := r.URL.Query().Get("concurrency"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			http.Error(w, "concurrency must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		concurrency = min(v, maxImportTestLimit)
+	}
+
+	rows, err := parseImportRows(r)
+	if err != nil {
+		http.Error(w, "Invalid import file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		http.Error(w, "Import file contains no connections", http.StatusBadRequest)
+		return
+	}
+	if len(rows) > maxImportRows {
+		http.Error(w, fmt.Sprintf("Import is limited to %d connections", maxImportRows), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]importRowResult, len(rows))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, row := range rows {
+		conn, errs := row.toConnection()
+		results[i] = importRowResult{Row: i + 1, Name: conn.Name, Errors: errs}
+		if len(errs) > 0 {
+			results[i].Status = "invalid"
+			continue
+		}
+		conn.TenantID = tid
+
+		wg.Add(1)
+		go func(res *importRowResult, conn models.Connection) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			h.importConnection(r.Context(), res, conn, testConnectivity)
+		}(&results[i], conn)
+	}
+	wg.Wait()
+
+	report := importReport{Total: len(results), Results: results}
+	for _, res := range results {
+		if res.Status == "created" {
+			report.Created++
+		} else {
+			report.Failed++
+		}
+	}
+	h.logger.Info().Str("tenant_id", tid).Int("created", report.Created).Int("failed", report.Failed).Msg("Imported connections")
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *ConnectionHandler) importConnection(ctx context.Context, res *importRowResult, conn models.Connection, test bool) {
+	conn.Status = models.ConnectionStatusUntested
+	if test {
+		dsn, err := conn.GenerateConnString()
+		if err == nil {
+			var host *engine.DockerHost
+			var release func()
+			host, release, err = h.hosts.Acquire(ctx, conn.TenantID)
+			if err == nil {
+				testCtx, cancel := context.WithTimeout(ctx, importTestTimeout)
+				_, err = host.Engine(h.containerName).TestConnection(testCtx, conn.DataFormat, dsn)
+				cancel()
+				release()
+			}
+		}
+		if errors.Is(err, engine.ErrHostBusy) {
+			// Not a verdict on the connection; leave it untested.
+			res.TestError = err.Error()
+		} else if err != nil {
+			conn.Status = models.ConnectionStatusInvalid
+			res.TestError = models.ScrubDSN(ansi.ReplaceAllString(err.Error(), ""), dsn)
+		} else {
+			conn.Status = models.ConnectionStatusValid
+		}
+		res.Connectivity = conn.Status
+	}
+
+	created, err := h.repo.Create(&conn)
+	if err != nil {
+		res.Status = "failed"
+		res.Errors = append(res.Errors, "failed to create connection: "+err.Error())
+		return
+	}
+	res.Status = "created"
+	res.ID = created.ID
+}
+
+func parseImportRows(r *http.Request) ([]importRow, error) {
+	body := http.MaxBytesReader(nil, r.Body, maxImportBodyBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return parseImportCSV(body)
+	case "application/json", "":
+		var rows []importRow
+		if err := json.NewDecoder(body).Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+}
+
+func parseImportCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "data_format", "host", "db_name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, importRow{
+			Name:       field(record, "name"),
+			DataFormat: field(record, "data_format"),
+			Host:       field(record, "host"),
+			Port:       field(record, "port"),
+			Username:   field(record, "username"),
+			Password:   field(record, "password"),
+			DBName:     field(record, "db_name"),
+		})
+	}
+	return rows, nil
+}
+
+// UnmarshalJSON accepts the port as either a number or a string.
+func (row *importRow) UnmarshalJSON(data []byte) error {
+	type alias importRow
+	var raw struct {
+		alias
+		Port json.RawMessage `json:"port"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*row = importRow(raw.alias)
+	row.Port = strings.Trim(string(raw.Port), `"`)
+	return nil
+}
+
+func (row importRow) toConnection() (models.Connection, []string) {
+	conn := models.Connection{
+		Name:       strings.TrimSpace(row.Name),
+		DataFormat: strings.ToLower(strings.TrimSpace(row.DataFormat)),
+		Host:       strings.TrimSpace(row.Host),
+		Username:   strings.TrimSpace(row.Username),
+		Password:   row.Password,
+		DBName:     strings.TrimSpace(row.DBName),
+	}
+
+	var errs []string
+	if conn.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if _, ok := importDataFormats[conn.DataFormat]; !ok {
+		errs = append(errs, fmt.Sprintf("unsupported data_format %q", row.DataFormat))
+	}
+	if conn.Host == "" {
+		errs = append(errs, "host is required")
+	}
+	if conn.DBName == "" {
+		errs = append(errs, "db_name is required")
+	}
+	if port := strings.TrimSpace(row.Port); port == "" || port == "null" {
+		conn.Port = defaultImportPort(conn.DataFormat)
+	} else if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		errs = append(errs, fmt.Sprintf("invalid port %q", row.Port))
+	} else {
+		conn.Port = p
+	}
+	return conn, errs
+}
+
+func defaultImportPort(format string) int {
+	if format == "mysql" {
+		return 3306
+	}
+	return 5432
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// rejectReadOnlyDestination refuses a definition whose destination is a read-only
+// connection. Missing connections are left to the definition validation to report.
+func (h *JobHandler) rejectReadOnlyDestination(w http.ResponseWriter, tenantID, destinationID string) bool {
+	destinationID = strings.TrimSpace(destinationID)
+	if destinationID == "" {
+		return true
+	}
+	dst, err := h.connRepo.Get(tenantID, destinationID)
+	if err != nil {
+		if isNotFound(err) {
+			return true
+		}
+		http.Error(w, "Failed to get destination connection: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if msg := models.ReadOnlyDestination(*dst); msg != "" {
+		http.Error(w, "Invalid destination: "+msg, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// rejectReadOnlyInUse refuses to make a connection read-only while definitions
+// write to it.
+func (h *ConnectionHandler) rejectReadOnlyInUse(w http.ResponseWriter, conn *models.Connection) bool {
+	definitions, err := h.jobRepo.ListDefinitions(conn.TenantID)
+	if err != nil {
+		http.Error(w, "Failed to list job definitions: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	var names []string
+	for _, def := range definitions {
+		if role := def.ConnectionRole(conn.ID); role == "destination" || role == "both" {
+			names = append(names, def.Name)
+		}
+	}
+	if len(names) > 0 {
+		http.Error(w, fmt.Sprintf("Connection is the destination of %d job definition(s) and cannot be read-only: %s",
+			len(names), strings.Join(names, ", ")), http.StatusConflict)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,357 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+func TestConnectionCRUD(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	var created models.Connection
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "warehouse",
+		"data_format": "pg",
+		"db_name":     "analytics",
+		"username":    "etl",
+		"password":    "s3cret",
+		"environment": "staging",
+		"tags":        []string{"Finance"},
+	}, token), http.StatusCreated, &created)
+	if created.ID == "" || created.Status != "untested" || created.CredentialMode != models.CredentialModeStored {
+		t.Fatalf("created = %+v", created)
+	}
+	path := "/api/v1/connections/" + created.ID
+
+	var fetched models.Connection
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &fetched)
+	if fetched.Password != "" {
+		t.Fatal("get returned the connection password")
+	}
+
+	var updated models.Connection
+	h.Decode(h.Do(http.MethodPut, path, map[string]interface{}{
+		"name":        "warehouse-eu",
+		"data_format": "pg",
+		"db_name":     "analytics",
+		"username":    "etl",
+	}, token), http.StatusOK, &updated)
+	if updated.Name != "warehouse-eu" || updated.Environment != "staging" {
+		t.Fatalf("updated = %+v, want the new name and the kept environment", updated)
+	}
+
+	var listed []models.Connection
+	h.Decode(h.Do(http.MethodGet, "/api/v1/connections?environment=staging", nil, token), http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("listed = %+v", listed)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/connections?environment=prod", nil, token), http.StatusOK, &listed)
+	if len(listed) != 0 {
+		t.Fatalf("prod filter listed %d connections", len(listed))
+	}
+
+	h.Decode(h.Do(http.MethodDelete, path, nil, token), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusNotFound, nil)
+	h.Decode(h.Do(http.MethodDelete, path, nil, token), http.StatusNotFound, nil)
+}
+
+func TestConnectionRejectsUnknownEnvironment(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "warehouse",
+		"data_format": "pg",
+		"environment": "qa",
+	}, token), http.StatusBadRequest, nil)
+}
+
+func TestConnectionMySQLOptions(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	var created models.Connection
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "legacy",
+		"data_format": "mysql",
+		"host":        "db.internal",
+		"port":        3306,
+		"db_name":     "shop",
+		"options":     map[string]interface{}{"charset": "Latin1", "tls": "skip-verify", "allow_native_passwords": true},
+	}, token), http.StatusCreated, &created)
+	if created.Options.Charset != "latin1" || created.Options.TLS != "skip-verify" {
+		t.Fatalf("options = %+v", created.Options)
+	}
+
+	// Omitted options are kept on update.
+	var updated models.Connection
+	h.Decode(h.Do(http.MethodPut, "/api/v1/connections/"+created.ID, map[string]interface{}{
+		"name":        "legacy-shop",
+		"data_format": "mysql",
+		"host":        "db.internal",
+		"port":        3306,
+		"db_name":     "shop",
+	}, token), http.StatusOK, &updated)
+	if !updated.Options.Equal(created.Options) {
+		t.Fatalf("update dropped the options: %+v", updated.Options)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "bad-tls",
+		"data_format": "mysql",
+		"options":     map[string]interface{}{"tls": "maybe"},
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "pg-with-options",
+		"data_format": "pg",
+		"options":     map[string]interface{}{"charset": "utf8mb4"},
+	}, token), http.StatusBadRequest, nil)
+}
+
+func TestConnectionDockerNetwork(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":          "no-network",
+		"data_format":   "pg",
+		"network_alias": "db",
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":           "bad-network",
+		"data_format":    "pg",
+		"docker_network": "my network",
+	}, token), http.StatusBadRequest, nil)
+
+	var created models.Connection
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":           "compose-db",
+		"data_format":    "pg",
+		"host":           "localhost",
+		"port":           5432,
+		"username":       "etl",
+		"db_name":        "shop",
+		"docker_network": " shop_default ",
+		"network_alias":  "db",
+	}, token), http.StatusCreated, &created)
+	if created.DockerNetwork != "shop_default" || created.NetworkAlias != "db" {
+		t.Fatalf("created = %+v", created)
+	}
+	dsn, err := created.GenerateConnString()
+	if err != nil || dsn != "postgres://etl:@db:5432/shop" {
+		t.Fatalf("engine DSN = %q, %v; want the network alias as host", dsn, err)
+	}
+	path := "/api/v1/connections/" + created.ID
+
+	// Omitted network settings are kept on update; clearing the network alone
+	// would strand the alias.
+	var updated models.Connection
+	h.Decode(h.Do(http.MethodPut, path, map[string]interface{}{
+		"name":        "compose-shop",
+		"data_format": "pg",
+		"host":        "localhost",
+		"port":        5432,
+		"db_name":     "shop",
+	}, token), http.StatusOK, &updated)
+	if updated.DockerNetwork != "shop_default" || updated.NetworkAlias != "db" {
+		t.Fatalf("update dropped the network settings: %+v", updated)
+	}
+	h.Decode(h.Do(http.MethodPut, path, map[string]interface{}{
+		"name":           "compose-shop",
+		"data_format":    "pg",
+		"docker_network": "",
+	}, token), http.StatusBadRequest, nil)
+	var cleared models.Connection
+	h.Decode(h.Do(http.MethodPut, path, map[string]interface{}{
+		"name":           "compose-shop",
+		"data_format":    "pg",
+		"host":           "localhost",
+		"docker_network": "",
+		"network_alias":  "",
+	}, token), http.StatusOK, &cleared)
+	if cleared.DockerNetwork != "" || cleared.NetworkAlias != "" || cleared.EngineHost() != "localhost" {
+		t.Fatalf("updated = %+v, want the network settings cleared", cleared)
+	}
+}
+
+func TestConnectionImpactAndRotationPause(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	var conns [2]*models.Connection
+	for i, name := range []string{"source", "destination"} {
+		conn, err := h.Store.Connections().Create(&models.Connection{TenantID: tenant.ID, Name: name, DataFormat: "pg", Password: "old"})
+		if err != nil {
+			t.Fatalf("create %s connection: %v", name, err)
+		}
+		conns[i] = conn
+	}
+	def, err := h.Store.Jobs().CrateDefinition(models.JobDefinition{
+		TenantID:                tenant.ID,
+		Name:                    "Hourly copy",
+		AST:                     []byte(`{}`),
+		SourceConnectionID:      conns[0].ID,
+		DestinationConnectionID: conns[1].ID,
+		Status:                  "READY",
+	})
+	if err != nil {
+		t.Fatalf("create definition: %v", err)
+	}
+	var schedule models.JobSchedule
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/"+def.ID+"/schedule", map[string]string{"cron_expression": "0 * * * *"}, token), http.StatusOK, &schedule)
+
+	path := "/api/v1/connections/" + conns[0].ID
+	var impact models.ConnectionImpact
+	h.Decode(h.Do(http.MethodGet, path+"/impact?days=1", nil, token), http.StatusOK, &impact)
+	if len(impact.ReadyDefinitions) != 1 || impact.ReadyDefinitions[0].Role != "source" {
+		t.Fatalf("ready definitions = %+v", impact.ReadyDefinitions)
+	}
+	if n := len(impact.ScheduledRuns); n < 23 || n > 24 {
+		t.Fatalf("listed %d scheduled runs in a day of an hourly schedule", n)
+	}
+	h.Decode(h.Do(http.MethodGet, path+"/impact?days=365", nil, token), http.StatusBadRequest, nil)
+
+	// Rotating the password with pause_schedules pauses the schedule.
+	var rotated struct {
+		models.Connection
+		PausedSchedules []string `json:"paused_schedules"`
+	}
+	h.Decode(h.Do(http.MethodPut, path, map[string]interface{}{
+		"name":            "source",
+		"data_format":     "pg",
+		"password":        "new",
+		"pause_schedules": true,
+	}, token), http.StatusOK, &rotated)
+	if len(rotated.PausedSchedules) != 1 || rotated.PausedSchedules[0] != def.ID {
+		t.Fatalf("paused schedules = %v, want [%s]", rotated.PausedSchedules, def.ID)
+	}
+	if paused, _ := h.Temporal.SchedulePaused(schedule.ScheduleID); !paused {
+		t.Fatal("Temporal schedule was not paused")
+	}
+	h.Decode(h.Do(http.MethodGet, path+"/impact", nil, token), http.StatusOK, &impact)
+	if len(impact.PausedSchedules) != 1 || len(impact.ScheduledRuns) != 0 {
+		t.Fatalf("impact after pause = %d paused, %d runs", len(impact.PausedSchedules), len(impact.ScheduledRuns))
+	}
+}
+
+func TestReadOnlyConnectionsCannotBeDestinations(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	var source, target models.Connection
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name": "orders", "data_format": "pg", "db_name": "orders", "username": "reader", "read_only": true,
+	}, token), http.StatusCreated, &source)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name": "warehouse", "data_format": "pg", "db_name": "analytics", "username": "etl",
+	}, token), http.StatusCreated, &target)
+	if !source.ReadOnly || target.ReadOnly {
+		t.Fatalf("read_only = %v/%v, want true/false", source.ReadOnly, target.ReadOnly)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/draft", map[string]interface{}{
+		"name": "Backwards", "source_connection_id": target.ID, "destination_connection_id": source.ID,
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/draft", map[string]interface{}{
+		"name": "Inline", "destination_dsn": "postgres://etl@db.acme.test/analytics?options=-c%20default_transaction_read_only%3Don",
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/draft", map[string]interface{}{
+		"name": "Nightly copy", "source_connection_id": source.ID, "destination_connection_id": target.ID,
+	}, token), http.StatusCreated, nil)
+
+	// A connection written to by a definition cannot be made read-only.
+	h.Decode(h.Do(http.MethodPut, "/api/v1/connections/"+target.ID, map[string]interface{}{
+		"name": "warehouse", "data_format": "pg", "db_name": "analytics", "username": "etl", "read_only": true,
+	}, token), http.StatusConflict, nil)
+
+	// Updates that leave read_only out keep it.
+	var updated models.Connection
+	h.Decode(h.Do(http.MethodPut, "/api/v1/connections/"+source.ID, map[string]interface{}{
+		"name": "orders-replica", "data_format": "pg", "db_name": "orders", "username": "reader",
+	}, token), http.StatusOK, &updated)
+	if !updated.ReadOnly {
+		t.Fatal("update without read_only cleared it")
+	}
+	dsn, err := updated.GenerateConnString()
+	if err != nil || dsn != "postgres://reader:@:0/orders?options=-c%20default_transaction_read_only%3Don" {
+		t.Fatalf("dsn = %q, %v", dsn, err)
+	}
+}
+
+func TestConnectionPasswordWithURLCharacters(t *testing.T) {
+	const password = "p@ss/w#rd%"
+	for _, format := range []string{"pg", "mysql"} {
+		conn := models.Connection{
+			DataFormat: format,
+			Host:       "db.internal",
+			Port:       5432,
+			DBName:     "shop",
+			Username:   "etl",
+			Password:   password,
+		}
+		dsn, err := conn.GenerateConnString()
+		if err != nil {
+			t.Fatalf("%s: generate DSN: %v", format, err)
+		}
+		parsed, err := models.ParseDSN(dsn)
+		if err != nil || parsed.Password != password || parsed.Host != "db.internal" || parsed.DBName != "shop" {
+			t.Fatalf("%s: DSN %q parsed to %+v, %v", format, dsn, parsed, err)
+		}
+
+		text := "connect " + dsn + ": password authentication failed (password " + password + ")"
+		if scrubbed := models.ScrubDSN(text, dsn); strings.Contains(scrubbed, "p@ss") || strings.Contains(scrubbed, "p%40ss") {
+			t.Fatalf("%s: scrubbed text still has the password: %q", format, scrubbed)
+		}
+	}
+}
+
+func TestViewersSeeConnectionSummaries(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, user, editor := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	viewer := h.Token(tenant.ID, user.ID, models.RoleViewer)
+
+	var created models.Connection
+	h.Decode(h.Do(http.MethodPost, "/api/v1/connections", map[string]interface{}{
+		"name":        "warehouse",
+		"data_format": "pg",
+		"host":        "db.internal",
+		"port":        5432,
+		"db_name":     "analytics",
+		"username":    "etl",
+		"password":    "s3cret",
+		"environment": "prod",
+	}, editor), http.StatusCreated, &created)
+
+	var fetched map[string]interface{}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/connections/"+created.ID, nil, viewer), http.StatusOK, &fetched)
+	var listed []map[string]interface{}
+	listRec := h.Do(http.MethodGet, "/api/v1/connections", nil, viewer)
+	h.Decode(listRec, http.StatusOK, &listed)
+	if len(listed) != 1 {
+		t.Fatalf("viewer listed %d connections", len(listed))
+	}
+	for _, conn := range []map[string]interface{}{fetched, listed[0]} {
+		if conn["id"] != created.ID || conn["name"] != "warehouse" || conn["environment"] != "prod" {
+			t.Fatalf("viewer got %v, want the connection summary", conn)
+		}
+		for _, key := range []string{"host", "port", "username", "db_name"} {
+			if _, ok := conn[key]; ok {
+				t.Fatalf("viewer got %s in %v", key, conn)
+			}
+		}
+	}
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/connections/"+created.ID, nil, editor), http.StatusOK, &fetched)
+	if fetched["host"] != "db.internal" || fetched["username"] != "etl" {
+		t.Fatalf("editor got %v, want the connection details", fetched)
+	}
+	editorRec := h.Do(http.MethodGet, "/api/v1/connections", nil, editor)
+	if editorRec.Header().Get("ETag") == listRec.Header().Get("ETag") {
+		t.Fatal("viewer and editor lists share an ETag")
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// ArchiveJob archives a definition that is no longer used. Archived definitions
+// keep their executions and stats for audits, but are left out of definition lists
+// unless ?include_archived=true and cannot be edited or run. A scheduled
+// definition, or one with an execution in progress or awaiting approval, cannot be
+// archived.
+func (h *JobHandler) ArchiveJob(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+
+	def, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if def.Status == models.DefinitionStatusArchived {
+		http.Error(w, "Job definition is already archived", http.StatusConflict)
+		return
+	}
+	if _, err := h.repo.GetSchedule(tid, jobDefID); err == nil {
+		http.Error(w, "Job definition is scheduled; delete its schedule before archiving it", http.StatusConflict)
+		return
+	} else if !isNotFound(err) {
+		http.Error(w, "Failed to load schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if active, err := h.repo.GetActiveDefinitionExecution(tid, jobDefID); err == nil {
+		http.Error(w, "Job definition has an active execution "+active.ID+"; archive it once the execution finishes", http.StatusConflict)
+		return
+	} else if !isNotFound(err) {
+		http.Error(w, "Failed to check active executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if held, err := h.repo.GetAwaitingApprovalExecution(tid, jobDefID); err == nil {
+		http.Error(w, "Job definition has execution "+held.ID+" awaiting approval; approve or decline it before archiving", http.StatusConflict)
+		return
+	} else if !isNotFound(err) {
+		http.Error(w, "Failed to check executions awaiting approval: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	archived, err := h.repo.ArchiveDefinition(tid, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to archive job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !archived {
+		http.Error(w, "Job definition is already archived", http.StatusConflict)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditDefinitionArchived,
+		TargetType: "job_definition",
+		TargetID:   jobDefID,
+		Details:    map[string]interface{}{"previous_status": string(def.Status)},
+	})
+	h.writeDefinition(w, tid, jobDefID)
+}
+
+// UnarchiveJob returns an archived definition to the status it had when it was
+// archived.
+func (h *JobHandler) UnarchiveJob(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+
+	if _, err := h.repo.GetJobDefinitionByID(tid, jobDefID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unarchived, err := h.repo.UnarchiveDefinition(tid, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to unarchive job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !unarchived {
+		http.Error(w, "Job definition is not archived", http.StatusConflict)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditDefinitionUnarchived,
+		TargetType: "job_definition",
+		TargetID:   jobDefID,
+	})
+	h.writeDefinition(w, tid, jobDefID)
+}
+
+// writeDefinition responds with the definition as it is stored now.
+func (h *JobHandler) writeDefinition(w http.ResponseWriter, tenantID, jobDefID string) {
+	def, err := h.repo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+// rejectArchived writes a conflict and returns false when def is archived, for
+// requests that would edit or run it.
+func rejectArchived(w http.ResponseWriter, def models.JobDefinition) bool {
+	if def.Status == models.DefinitionStatusArchived {
+		http.Error(w, "Job definition is archived; unarchive it first", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// rejectArchivedDefinition loads a definition for a run of one of its executions
+// and applies rejectArchived to it.
+func (h *JobHandler) rejectArchivedDefinition(w http.ResponseWriter, tenantID, jobDefID string) bool {
+	def, err := h.repo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return false
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return rejectArchived(w, def)
+}
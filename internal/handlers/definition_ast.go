@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// astSection selects part of a definition's AST for GET /jobs/{jobID}: the subtree
+// at ?ast_path=, or the entries of the AST's table list named by ?tables=. The
+// section is extracted in the database, so large ASTs are not re-parsed per request.
+type astSection struct {
+	path   []string
+	tables []string
+}
+
+func parseASTSection(r *http.Request) (astSection, error) {
+	var section astSection
+	q := r.URL.Query()
+	rawPath, rawTables := q.Get("ast_path"), strings.TrimSpace(q.Get("tables"))
+	if q.Has("ast_path") && rawTables != "" {
+		return section, errors.New("pass either ast_path or tables, not both")
+	}
+	if q.Has("ast_path") {
+		path, err := models.ParseASTPath(rawPath)
+		if err != nil {
+			return section, err
+		}
+		section.path = path
+	}
+	if rawTables != "" {
+		for _, table := range strings.Split(rawTables, ",") {
+			table = strings.TrimSpace(table)
+			if table == "" {
+				return section, errors.New("tables must be a comma-separated list of table names")
+			}
+			section.tables = append(section.tables, table)
+		}
+	}
+	return section, nil
+}
+
+func (s astSection) selected() bool {
+	return s.path != nil || s.tables != nil
+}
+
+// applyASTSection replaces the AST of a shaped definition with the selected
+// section. A path that leads nowhere selects null.
+func (h *JobHandler) applyASTSection(tid, jobDefID string, section astSection, shaped map[string]json.RawMessage) error {
+	if _, ok := shaped["ast"]; !ok || !section.selected() {
+		return nil
+	}
+	var (
+		ast json.RawMessage
+		err error
+	)
+	if section.path != nil {
+		ast, err = h.repo.GetDefinitionASTPath(tid, jobDefID, section.path)
+	} else {
+		ast, err = h.repo.GetDefinitionASTTables(tid, jobDefID, section.tables)
+	}
+	if err != nil {
+		return err
+	}
+	if ast == nil {
+		ast = json.RawMessage("null")
+	}
+	shaped["ast"] = ast
+	return nil
+}
+
+// ListASTTables returns the names of the tables a definition's AST lists, without
+// loading the AST itself.
+func (h *JobHandler) ListASTTables(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	tables, err := h.repo.ListDefinitionASTTables(tid, mux.Vars(r)["jobID"])
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to list AST tables: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tables": tables})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// definitionLock returns the lock an in-progress execution holds on the
+// definition, or nil when it has none.
+func (h *JobHandler) definitionLock(tenantID, jobDefID string) (*models.DefinitionLock, error) {
+	active, err := h.repo.GetActiveDefinitionExecution(tenantID, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &models.DefinitionLock{ExecutionID: active.ID, Status: active.Status, Since: active.CreatedAt}, nil
+}
+
+// checkDefinitionLock rejects edits of a locked definition's AST or connections
+// with 409. Admins may pass ?override_lock=true to edit it anyway; the in-progress
+// run keeps what it started with.
+func (h *JobHandler) checkDefinitionLock(w http.ResponseWriter, r *http.Request, tenantID, jobDefID string) bool {
+	lock, err := h.definitionLock(tenantID, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to check definition lock: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if lock == nil {
+		return true
+	}
+	if r.URL.Query().Get("override_lock") != "true" {
+		http.Error(w, "Job definition is locked while execution "+lock.ExecutionID+" is "+string(lock.Status)+
+			"; an admin may pass override_lock=true to edit it anyway", http.StatusConflict)
+		return false
+	}
+	roles, _ := authz.RolesFromRequest(r)
+	if !models.HasAtLeast(roles, models.RoleAdmin) {
+		http.Error(w, "Only admins can override a definition lock", http.StatusForbidden)
+		return false
+	}
+	h.logger.Warn().Str("tenant_id", tenantID).Str("job_definition_id", jobDefID).
+		Str("execution_id", lock.ExecutionID).Msg("definition lock overridden")
+	return true
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// connectionSummary is what viewers see of a connection, on its own or in a
+// definition: enough to recognise it, nothing about where they point or who they log in as.
+type connectionSummary struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	DataFormat string                  `json:"data_format"`
+	Status     models.ConnectionStatus `json:"status"`
+	// Environment stays visible so viewers notice prod/dev mix-ups too.
+	Environment string `json:"environment,omitempty"`
+}
+
+// definitionView shapes job definition responses for the caller: viewers get
+// connection summaries instead of connection details, ?fields= trims the response
+// to the listed top-level fields, and lists leave out archived definitions unless
+// ?include_archived=true.
+type definitionView struct {
+	redactConnections bool
+	fields            map[string]bool
+	includeArchived   bool
+}
+
+// redactsConnections reports whether the caller sees connection summaries rather
+// than connection details: everyone below editor does.
+func redactsConnections(r *http.Request) bool {
+	roles, _ := authz.RolesFromRequest(r)
+	return !models.HasAtLeast(roles, models.RoleEditor)
+}
+
+func summarizeConnection(c models.Connection) connectionSummary {
+	return connectionSummary{ID: c.ID, Name: c.Name, DataFormat: c.DataFormat, Status: c.Status, Environment: c.Environment}
+}
+
+func newDefinitionView(r *http.Request) (definitionView, error) {
+	view := definitionView{
+		redactConnections: redactsConnections(r),
+		includeArchived:   r.URL.Query().Get("include_archived") == "true",
+	}
+
+	raw := strings.TrimSpace(r.URL.Query().Get("fields"))
+	if raw == "" {
+		return view, nil
+	}
+	view.fields = map[string]bool{}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			return view, errors.New("fields must be a comma-separated list of field names")
+		}
+		view.fields[f] = true
+	}
+	return view, nil
+}
+
+// versionTag distinguishes the cached representations of the same list version.
+func (v definitionView) versionTag(version string) string {
+	if v.redactConnections {
+		return version + "|redacted"
+	}
+	return version
+}
+
+// lists reports whether a list includes the definition.
+func (v definitionView) lists(def models.JobDefinition) bool {
+	return v.includeArchived || def.Status != models.DefinitionStatusArchived
+}
+
+// render shapes a definition, or a value embedding one such as a stats row.
+func (v definitionView) render(payload interface{}) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+
+	if v.redactConnections {
+		for _, key := range []string{"source_connection", "destination_connection"} {
+			conn, ok := out[key]
+			if !ok {
+				continue
+			}
+			var c models.Connection
+			if err := json.Unmarshal(conn, &c); err != nil {
+				return nil, err
+			}
+			if out[key], err = json.Marshal(summarizeConnection(c)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v.fields != nil {
+		for key := range out {
+			if !v.fields[key] {
+				delete(out, key)
+			}
+		}
+	}
+	return out, nil
+}
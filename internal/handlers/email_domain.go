@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const dnsLookupTimeout = 10 * time.Second
+
+// DomainHandler manages the email domains a tenant auto-joins signups from, and the
+// join requests those signups create.
+type DomainHandler struct {
+	domains   repository.EmailDomainRepository
+	users     repository.UserRepository
+	audit     repository.AuditRepository
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+	logger    zerolog.Logger
+}
+
+type createDomainRequest struct {
+	Domain          string `json:"domain"`
+	RequireApproval bool   `json:"require_approval"`
+}
+
+type updateDomainRequest struct {
+	RequireApproval *bool `json:"require_approval"`
+}
+
+// emailDomainResponse includes the DNS record an admin has to publish.
+type emailDomainResponse struct {
+	models.EmailDomain
+	TXTRecordName  string `json:"txt_record_name"`
+	TXTRecordValue string `json:"txt_record_value"`
+}
+
+func NewDomainHandler(domains repository.EmailDomainRepository, users repository.UserRepository, audit repository.AuditRepository, logger zerolog.Logger) *DomainHandler {
+	return &DomainHandler{
+		domains:   domains,
+		users:     users,
+		audit:     audit,
+		lookupTXT: net.DefaultResolver.LookupTXT,
+		logger:    logger,
+	}
+}
+
+func toDomainResponse(d models.EmailDomain) emailDomainResponse {
+	return emailDomainResponse{EmailDomain: d, TXTRecordName: d.TXTRecordName(), TXTRecordValue: d.TXTRecordValue()}
+}
+
+func (h *DomainHandler) List(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	domains, err := h.domains.ListDomains(tid)
+	if err != nil {
+		http.Error(w, "Failed to list domains: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := make([]emailDomainResponse, 0, len(domains))
+	for _, d := range domains {
+		resp = append(resp, toDomainResponse(d))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Create claims a domain for the tenant. It does nothing for signups until verified.
+func (h *DomainHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req createDomainRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	domain, err := models.NormalizeEmailDomain(req.Domain)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := generateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate verification token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	claim := models.EmailDomain{
+		TenantID:          tid,
+		Domain:            domain,
+		VerificationToken: token,
+		RequireApproval:   req.RequireApproval,
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		claim.CreatedBy = &uid
+	}
+	created, err := h.domains.CreateDomain(claim)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "Domain already added", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to add domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toDomainResponse(created))
+}
+
+func (h *DomainHandler) Update(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req updateDomainRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.RequireApproval == nil {
+		http.Error(w, "require_approval is required", http.StatusBadRequest)
+		return
+	}
+	updated, err := h.domains.SetDomainApproval(tid, mux.Vars(r)["domainID"], *req.RequireApproval)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toDomainResponse(updated))
+}
+
+func (h *DomainHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	if err := h.domains.DeleteDomain(tid, mux.Vars(r)["domainID"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify looks up the domain's verification TXT record and marks the domain verified
+// when it carries the expected token.
+func (h *DomainHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	domainID := mux.Vars(r)["domainID"]
+	domain, err := h.domains.GetDomain(tid, domainID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if domain.VerifiedAt != nil {
+		writeJSON(w, http.StatusOK, toDomainResponse(domain))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dnsLookupTimeout)
+	defer cancel()
+	records, err := h.lookupTXT(ctx, domain.TXTRecordName())
+	if err != nil {
+		h.logger.Info().Err(err).Str("domain", domain.Domain).Msg("domain verification lookup failed")
+		http.Error(w, "TXT record "+domain.TXTRecordName()+" not found", http.StatusUnprocessableEntity)
+		return
+	}
+	found := false
+	for _, rec := range records {
+		if strings.TrimSpace(rec) == domain.TXTRecordValue() {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "TXT record "+domain.TXTRecordName()+" does not contain the verification token", http.StatusUnprocessableEntity)
+		return
+	}
+
+	verified, err := h.domains.MarkDomainVerified(tid, domainID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "Domain is already verified by another tenant", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to verify domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("tenant_id", tid).Str("domain", verified.Domain).Msg("email domain verified")
+	writeJSON(w, http.StatusOK, toDomainResponse(verified))
+}
+
+func (h *DomainHandler) ListJoinRequests(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.JoinRequestPending, models.JoinRequestApproved, models.JoinRequestRejected:
+	default:
+		http.Error(w, "status must be pending, approved or rejected", http.StatusBadRequest)
+		return
+	}
+	requests, err := h.domains.ListJoinRequests(tid, status)
+	if err != nil {
+		http.Error(w, "Failed to list join requests: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// ApproveJoinRequest admits a pending domain signup. The user can log in as soon as
+// their email address is also verified.
+func (h *DomainHandler) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decide(w, r, models.JoinRequestApproved)
+	if !ok {
+		return
+	}
+	if _, err := h.domains.ActivateDomainMember(req.UserID); err != nil {
+		http.Error(w, "Failed to activate user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+// RejectJoinRequest refuses a pending domain signup and removes the account.
+func (h *DomainHandler) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decide(w, r, models.JoinRequestRejected)
+	if !ok {
+		return
+	}
+	if err := h.users.DeleteUser(req.UserID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Failed to remove user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (h *DomainHandler) decide(w http.ResponseWriter, r *http.Request, status string) (models.DomainJoinRequest, bool) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return models.DomainJoinRequest{}, false
+	}
+	uid, ok := authz.UserIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing user context", http.StatusUnauthorized)
+		return models.DomainJoinRequest{}, false
+	}
+	req, err := h.domains.DecideJoinRequest(tid, mux.Vars(r)["requestID"], status, uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Pending join request not found", http.StatusNotFound)
+			return models.DomainJoinRequest{}, false
+		}
+		http.Error(w, "Failed to update join request: "+err.Error(), http.StatusInternalServerError)
+		return models.DomainJoinRequest{}, false
+	}
+	action := models.AuditJoinRequestApproved
+	if status == models.JoinRequestRejected {
+		action = models.AuditJoinRequestRejected
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   req.UserID,
+		Details:    map[string]interface{}{"join_request_id": req.ID, "email": req.Email, "domain": req.Domain},
+	})
+	return req, true
+}
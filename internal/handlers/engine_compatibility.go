@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// EngineCompatibility returns the format pairs every known engine version
+// migrates, and the version of the running engine.
+func (h *AdminHandler) EngineCompatibility(w http.ResponseWriter, r *http.Request) {
+	matrix, err := h.engines.GetCompatibilityMatrix()
+	if err != nil {
+		http.Error(w, "Failed to load engine compatibility: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, matrix)
+}
+
+// RecordEngineCompatibility adds an engine version's format pairs to the matrix.
+// Without a body it discovers them from the configured engine, which becomes the
+// current version; with one it records the given version's pairs, such as those of
+// a release not rolled out yet, leaving the current version alone.
+func (h *AdminHandler) RecordEngineCompatibility(w http.ResponseWriter, r *http.Request) {
+	var caps models.EngineCapabilities
+	if err := decodeAllowEmpty(r, &caps); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	caps.Normalize()
+	current := caps.Version == "" && len(caps.Pairs) == 0
+	if current {
+		engineClient, release, ok := acquireEngine(w, r, h.hosts, "", h.engineImage)
+		if !ok {
+			return
+		}
+		discovered, err := engineClient.Capabilities(r.Context())
+		release()
+		if err != nil {
+			http.Error(w, "Engine capability discovery failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		caps = discovered
+	} else if caps.Version == "" {
+		http.Error(w, "version is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.engines.RecordEngineCapabilities(caps, current); err != nil {
+		http.Error(w, "Failed to record engine capabilities: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("version", caps.Version).Bool("current", current).Int("pairs", len(caps.Pairs)).Msg("Engine capabilities recorded")
+	h.EngineCompatibility(w, r)
+}
+
+// engineCompatibilityError checks a definition's connection formats against the
+// engine compatibility matrix. It returns the reason the running engine cannot
+// migrate between them, or "" when it can, when either connection is not set yet,
+// or when the definition does not run on the engine.
+func (h *JobHandler) engineCompatibilityError(tenantID, jobType, sourceID, destinationID string) (string, error) {
+	sourceID, destinationID = strings.TrimSpace(sourceID), strings.TrimSpace(destinationID)
+	if jobType == models.JobTypeSQLScript || sourceID == "" || destinationID == "" {
+		return "", nil
+	}
+	matrix, err := h.engines.GetCompatibilityMatrix()
+	if err != nil || matrix.CurrentVersion == "" {
+		return "", err
+	}
+	src, err := h.connRepo.Get(tenantID, sourceID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	dst, err := h.connRepo.Get(tenantID, destinationID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return matrix.Check(src.DataFormat, dst.DataFormat), nil
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/engine"
+)
+
+// acquireEngine picks the Docker host for the tenant's engine work and returns an
+// engine client bound to it. It writes a 429 when the host is at its exec limit, a
+// 503 when no host is available, and returns ok=false in both cases. Callers must
+// call release once the engine call has finished. The engine container joins the
+// given Docker networks first; one missing on the host is a 400.
+func acquireEngine(w http.ResponseWriter, r *http.Request, hosts *engine.HostPool, tenantID, containerName string, networks ...string) (*engine.Client, func(), bool) {
+	host, release, err := hosts.Acquire(r.Context(), tenantID)
+	if errors.Is(err, engine.ErrHostBusy) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Engine is busy, try again shortly", http.StatusTooManyRequests)
+		return nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, "No engine host available: "+err.Error(), http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+	if err := host.JoinNetworks(r.Context(), containerName, networks...); err != nil {
+		release()
+		if errors.Is(err, engine.ErrNetworkNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		http.Error(w, "Failed to join docker network: "+err.Error(), http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+	return host.Engine(containerName), release, true
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// enforceEnvironmentPolicy refuses a source/destination pair labeled with different
+// environments when the tenant blocks cross-environment jobs. Missing connections
+// are left to the definition validation to report.
+func (h *JobHandler) enforceEnvironmentPolicy(w http.ResponseWriter, tenantID, sourceID, destinationID string) bool {
+	blocked, ok := h.blocksCrossEnvironment(w, tenantID)
+	if !ok || !blocked {
+		return ok
+	}
+	src, err := h.connRepo.Get(tenantID, strings.TrimSpace(sourceID))
+	if err != nil {
+		if isNotFound(err) {
+			return true
+		}
+		http.Error(w, "Failed to get source connection: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	dst, err := h.connRepo.Get(tenantID, strings.TrimSpace(destinationID))
+	if err != nil {
+		if isNotFound(err) {
+			return true
+		}
+		http.Error(w, "Failed to get destination connection: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return rejectEnvironmentMismatch(w, *src, *dst)
+}
+
+// enforceDefinitionEnvironmentPolicy applies the cross-environment policy to a
+// stored definition before it runs.
+func (h *JobHandler) enforceDefinitionEnvironmentPolicy(w http.ResponseWriter, tenantID, jobDefID string) bool {
+	blocked, ok := h.blocksCrossEnvironment(w, tenantID)
+	if !ok || !blocked {
+		return ok
+	}
+	def, err := h.repo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return false
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return rejectEnvironmentMismatch(w, def.SourceConnection, def.DestinationConnection)
+}
+
+func (h *JobHandler) blocksCrossEnvironment(w http.ResponseWriter, tenantID string) (bool, bool) {
+	tenant, err := h.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return false, false
+	}
+	return tenant.BlockCrossEnvironmentJobs, true
+}
+
+func rejectEnvironmentMismatch(w http.ResponseWriter, src, dst models.Connection) bool {
+	if msg := models.EnvironmentMismatch(src, dst); msg != "" {
+		http.Error(w, "Cross-environment jobs are blocked for this tenant: "+msg, http.StatusConflict)
+		return false
+	}
+	return true
+}
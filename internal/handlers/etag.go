@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// listETag derives a weak ETag from a repository version string and the request's
+// query, so that differently paginated or filtered lists never share a tag.
+func listETag(r *http.Request, version string) string {
+	sum := sha256.Sum256([]byte(version + "?" + r.URL.RawQuery))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// notModified stamps the response with the list ETag and reports whether the client
+// already holds the current version, in which case a 304 has been written.
+func notModified(w http.ResponseWriter, r *http.Request, version string) bool {
+	etag := listETag(r, version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag || "W/"+candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
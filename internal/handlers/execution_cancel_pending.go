@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/api/serviceerror"
+)
+
+// cancelPendingMessage is recorded on every execution cancelled in bulk.
+const cancelPendingMessage = "Cancelled while pending."
+
+// cancelPendingRequest optionally limits a bulk cancel to one definition.
+type cancelPendingRequest struct {
+	JobDefinitionID string `json:"job_definition_id"`
+}
+
+type cancelPendingResponse struct {
+	Cancelled    int      `json:"cancelled"`
+	ExecutionIDs []string `json:"execution_ids"`
+	// TerminateFailed lists cancelled executions whose workflow could not be
+	// terminated; it may still move them to running.
+	TerminateFailed []string `json:"terminate_failed,omitempty"`
+}
+
+// CancelPendingExecutions cancels every pending execution of the tenant, or of one
+// definition with job_definition_id, and terminates their workflows. Pending runs
+// have not started the engine, so nothing needs to be cleaned up. Executions that
+// leave pending meanwhile are left alone.
+func (h *JobHandler) CancelPendingExecutions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req cancelPendingRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.JobDefinitionID != "" {
+		if _, err := h.repo.GetJobDefinitionByID(tid, req.JobDefinitionID); err != nil {
+			if isNotFound(err) {
+				http.Error(w, "Job definition not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	active, err := h.repo.ListTenantActiveExecutions(tid)
+	if err != nil {
+		http.Error(w, "Failed to list active executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := cancelPendingResponse{ExecutionIDs: []string{}}
+	for _, exec := range active {
+		if exec.Status != models.ExecutionStatusPending {
+			continue
+		}
+		if req.JobDefinitionID != "" && exec.JobDefinitionID != req.JobDefinitionID {
+			continue
+		}
+		// Mark the run first so a workflow that moved on to running is not terminated.
+		cancelled, err := h.repo.CancelPendingExecution(tid, exec.ID, cancelPendingMessage)
+		if err != nil {
+			http.Error(w, "Failed to cancel execution: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !cancelled {
+			continue
+		}
+		resp.Cancelled++
+		resp.ExecutionIDs = append(resp.ExecutionIDs, exec.ID)
+
+		workflowID := temporal.ExecutionWorkflowID(exec.ID, exec.WorkflowID)
+		if err := h.temporalClient.TerminateWorkflow(r.Context(), workflowID, "", cancelPendingMessage); err != nil {
+			var notFound *serviceerror.NotFound
+			if !errors.As(err, &notFound) {
+				h.logger.Error().Err(err).Str("execution_id", exec.ID).Msg("failed to terminate pending execution workflow")
+				resp.TerminateFailed = append(resp.TerminateFailed, exec.ID)
+			}
+		}
+		recordAudit(h.audit, h.logger, r, models.AuditEvent{
+			TenantID:   &tid,
+			Action:     models.AuditExecutionCancelled,
+			TargetType: "execution",
+			TargetID:   exec.ID,
+			Details:    map[string]interface{}{"job_definition_id": exec.JobDefinitionID, "bulk": true},
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
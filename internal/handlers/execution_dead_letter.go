@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+	// maxBulkRequeue caps the executions of one requeue request.
+	maxBulkRequeue = 100
+)
+
+// Per-execution outcomes of a bulk requeue.
+const (
+	requeueRequeued = "requeued"
+	requeueSkipped  = "skipped"
+	requeueFailed   = "failed"
+)
+
+// ListDeadLetterExecutions lists the tenant's failed executions that were not
+// requeued yet, newest first. A failed execution has used up its definition's
+// retry policy, or failed in a way retrying cannot fix.
+func (h *JobHandler) ListDeadLetterExecutions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	limit := defaultDeadLetterLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxDeadLetterLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxDeadLetterLimit), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+	executions, err := h.repo.ListDeadLetterExecutions(tid, limit)
+	if err != nil {
+		http.Error(w, "Failed to list dead-letter executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+// bulkRequeueRequest names the executions to requeue, or asks for the whole
+// dead-letter list with All.
+type bulkRequeueRequest struct {
+	ExecutionIDs []string `json:"execution_ids"`
+	All          bool     `json:"all"`
+}
+
+// bulkRequeueResult reports what happened to one execution. RequeuedAs is the run
+// that replaced it.
+type bulkRequeueResult struct {
+	ExecutionID     string `json:"execution_id"`
+	JobDefinitionID string `json:"job_definition_id,omitempty"`
+	Status          string `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+	RequeuedAs      string `json:"requeued_as_execution_id,omitempty"`
+}
+
+type bulkRequeueResponse struct {
+	Total    int                 `json:"total"`
+	Requeued int                 `json:"requeued"`
+	Skipped  int                 `json:"skipped"`
+	Failed   int                 `json:"failed"`
+	Results  []bulkRequeueResult `json:"results"`
+}
+
+// RequeueExecutions starts a fresh run of the definition of each listed failed
+// execution, with the overrides the failed run had, and links the two. Several
+// failed executions of one definition are replaced by a single run. Executions
+// that are not failed, were already requeued, belong to a definition that prompts
+// for credentials or is running, or break the tenant's environment policy are
+// skipped. Nothing is requeued inside a blackout window that is not overridden or
+// while the tenant is at its execution start limit.
+func (h *JobHandler) RequeueExecutions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req bulkRequeueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	ids := req.ExecutionIDs
+	switch {
+	case req.All && len(ids) > 0:
+		http.Error(w, "Pass either execution_ids or all, not both", http.StatusBadRequest)
+		return
+	case req.All:
+		dead, err := h.repo.ListDeadLetterExecutions(tid, maxBulkRequeue)
+		if err != nil {
+			http.Error(w, "Failed to list dead-letter executions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, exec := range dead {
+			ids = append(ids, exec.ID)
+		}
+	case len(ids) == 0:
+		http.Error(w, "execution_ids are required", http.StatusBadRequest)
+		return
+	case len(ids) > maxBulkRequeue:
+		http.Error(w, fmt.Sprintf("at most %d executions can be requeued at once", maxBulkRequeue), http.StatusBadRequest)
+		return
+	}
+	blocked, ok := h.blocksCrossEnvironment(w, tid)
+	if !ok {
+		return
+	}
+	freshness, ok := h.validationFreshness(w, tid)
+	if !ok {
+		return
+	}
+	overridden, ok := h.enforceBlackout(w, r, tid)
+	if !ok {
+		return
+	}
+	if !h.enforceStartLimit(w, tid) {
+		return
+	}
+
+	_, baseContext := requestTrigger(r)
+	if overridden != "" {
+		baseContext["blackout_override"] = overridden
+	}
+	// started maps each definition requeued by this request to its new run.
+	started := make(map[string]string)
+	seen := make(map[string]bool, len(ids))
+	results := make([]bulkRequeueResult, 0, len(ids))
+	for _, id := range ids {
+		res := bulkRequeueResult{ExecutionID: id}
+		if seen[id] {
+			res.Status, res.Reason = requeueSkipped, "listed more than once"
+			results = append(results, res)
+			continue
+		}
+		seen[id] = true
+		res.JobDefinitionID, res.RequeuedAs, res.Status, res.Reason = h.requeueExecution(r, tid, id, blocked, freshness, baseContext, started)
+		results = append(results, res)
+	}
+
+	resp := bulkRequeueResponse{Total: len(results), Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case requeueRequeued:
+			resp.Requeued++
+		case requeueSkipped:
+			resp.Skipped++
+		case requeueFailed:
+			resp.Failed++
+		}
+	}
+	status := http.StatusAccepted
+	if resp.Requeued == 0 {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, resp)
+}
+
+// requeueExecution requeues one failed execution and returns its definition, the
+// run replacing it, and the outcome with its reason.
+func (h *JobHandler) requeueExecution(r *http.Request, tid, execID string, blockCrossEnv bool, freshness models.ValidationFreshness, baseContext map[string]string, started map[string]string) (defID, requeuedAs, status, reason string) {
+	exec, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", "", requeueSkipped, "execution not found"
+		}
+		return "", "", requeueFailed, "failed to get execution: " + err.Error()
+	}
+	defID = exec.JobDefinitionID
+	if exec.Status != models.ExecutionStatusFailed {
+		return defID, "", requeueSkipped, "execution is " + string(exec.Status) + ", not failed"
+	}
+	if exec.RequeuedAsExecutionID != nil {
+		return defID, "", requeueSkipped, "already requeued as " + *exec.RequeuedAsExecutionID
+	}
+
+	// A second failed run of a definition requeued above is replaced by the same run.
+	if newID, ok := started[defID]; ok {
+		marked, err := h.repo.MarkExecutionRequeued(tid, execID, newID)
+		if err != nil {
+			return defID, "", requeueFailed, "failed to record requeue: " + err.Error()
+		}
+		if !marked {
+			return defID, "", requeueSkipped, "execution was requeued concurrently"
+		}
+		return defID, newID, requeueRequeued, ""
+	}
+
+	def, err := h.repo.GetJobDefinitionByID(tid, defID)
+	if err != nil {
+		if isNotFound(err) {
+			return defID, "", requeueSkipped, "job definition not found"
+		}
+		return defID, "", requeueFailed, "failed to load job definition: " + err.Error()
+	}
+	if def.Status == models.DefinitionStatusArchived {
+		return defID, "", requeueSkipped, "job definition is archived"
+	}
+	if blockCrossEnv {
+		if msg := models.EnvironmentMismatch(def.SourceConnection, def.DestinationConnection); msg != "" {
+			return defID, "", requeueSkipped, "cross-environment jobs are blocked for this tenant: " + msg
+		}
+	}
+	if def.StaleValidation && freshness == models.ValidationFreshnessBlock {
+		return defID, "", requeueSkipped, staleValidationBlocked
+	}
+	for _, connID := range []string{def.SourceConnectionID, def.DestinationConnectionID} {
+		if connID == "" {
+			continue
+		}
+		conn, err := h.connRepo.Get(tid, connID)
+		if err != nil {
+			if isNotFound(err) {
+				continue // the prepare activity reports missing connections
+			}
+			return defID, "", requeueFailed, "failed to get connection: " + err.Error()
+		}
+		if conn.PromptsForCredentials() {
+			return defID, "", requeueSkipped, "connection " + conn.Name + " prompts for credentials; run the job instead"
+		}
+	}
+	if active, err := h.repo.GetActiveDefinitionExecution(tid, defID); err == nil {
+		return defID, "", requeueSkipped, "job definition already has an active execution " + active.ID
+	} else if !isNotFound(err) {
+		return defID, "", requeueFailed, "failed to check active executions: " + err.Error()
+	}
+	overrides, err := h.executionOverrides(tid, execID)
+	if err != nil {
+		return defID, "", requeueFailed, "failed to load execution overrides: " + err.Error()
+	}
+
+	// Claim the execution before starting its run, so a concurrent requeue of the
+	// same execution does not start a second one.
+	newID := uuid.New().String()
+	marked, err := h.repo.MarkExecutionRequeued(tid, execID, newID)
+	if err != nil {
+		return defID, "", requeueFailed, "failed to record requeue: " + err.Error()
+	}
+	if !marked {
+		return defID, "", requeueSkipped, "execution was requeued concurrently"
+	}
+	triggerContext := map[string]string{"requeued_from_execution_id": execID}
+	for k, v := range baseContext {
+		triggerContext[k] = v
+	}
+	params := temporal.ExecutionParams{
+		TenantID:        tid,
+		ExecutionID:     newID,
+		JobDefinitionID: defID,
+		JobType:         def.JobType,
+		Priority:        models.PriorityNormal,
+		Overrides:       overrides,
+		TriggeredBy:     models.TriggerRetry,
+		TriggerContext:  triggerContext,
+	}
+	if _, err := h.launchExecution(r, params, nil); err != nil {
+		if clearErr := h.repo.ClearExecutionRequeued(tid, execID, newID); clearErr != nil {
+			h.logger.Error().Err(clearErr).Str("execution_id", execID).Msg("failed to release requeue claim")
+		}
+		return defID, "", requeueFailed, "failed to start job execution workflow: " + err.Error()
+	}
+	started[defID] = newID
+	return defID, newID, requeueRequeued, ""
+}
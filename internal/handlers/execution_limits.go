@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// GetExecutionLimits returns the tenant's execution start limits with the starts
+// counted against them and, once they are reached, when the next start is allowed.
+func (h *TenantHandler) GetExecutionLimits(w http.ResponseWriter, r *http.Request) {
+	requesterRoles, _ := authz.RolesFromRequest(r)
+	tenantID := mux.Vars(r)["tenantID"]
+	if !models.HasAtLeast(requesterRoles, models.RoleSuperAdmin) {
+		if tid, ok := authz.TenantIDFromRequest(r); !ok || tid != tenantID {
+			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			return
+		}
+	}
+	if _, err := h.tenantRepo.GetTenantByID(tenantID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	usage, err := executionStartUsage(h.tenantRepo, tenantID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to load execution limits: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// UpdateExecutionLimits replaces the tenant's plan and start limit overrides.
+func (h *TenantHandler) UpdateExecutionLimits(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantID"]
+	if _, err := h.tenantRepo.GetTenantByID(tenantID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var payload struct {
+		Plan          *string `json:"plan"`
+		StartsPerHour *int    `json:"starts_per_hour"`
+		Burst         *int    `json:"burst"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	limits := models.TenantExecutionLimits{
+		TenantID:      tenantID,
+		Plan:          payload.Plan,
+		StartsPerHour: payload.StartsPerHour,
+		Burst:         payload.Burst,
+	}
+	if err := limits.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if userID, ok := authz.UserIDFromRequest(r); ok && userID != "" {
+		limits.UpdatedBy = &userID
+	}
+
+	if _, err := h.tenantRepo.SaveExecutionLimits(limits); err != nil {
+		http.Error(w, "Failed to save execution limits: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditExecutionLimitsUpdated,
+		TargetType: "tenant",
+		TargetID:   tenantID,
+	})
+
+	usage, err := executionStartUsage(h.tenantRepo, tenantID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to load execution limits: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// executionStartUsage reports the tenant's start limits and their use at now.
+func executionStartUsage(tenants repository.TenantRepository, tenantID string, now time.Time) (models.ExecutionStartUsage, error) {
+	limits, err := tenants.GetExecutionLimits(tenantID)
+	if err != nil {
+		return models.ExecutionStartUsage{}, err
+	}
+	starts, err := tenants.ListExecutionStarts(tenantID, now.Add(-models.ExecutionStartRateWindow))
+	if err != nil {
+		return models.ExecutionStartUsage{}, err
+	}
+	usage := models.ExecutionStartUsage{
+		TenantExecutionLimits: limits,
+		Effective:             limits.Effective(),
+		StartsLastHour:        len(starts),
+	}
+	for _, at := range starts {
+		if at.After(now.Add(-models.ExecutionStartBurstWindow)) {
+			usage.StartsLastMinute++
+		}
+	}
+	if allowed, resetAt := usage.Effective.Check(starts, now); !allowed {
+		usage.ResetAt = &resetAt
+	}
+	return usage, nil
+}
+
+// enforceStartLimit writes a 429 response with the reset time and returns false
+// when the tenant reached its execution start limit.
+func (h *JobHandler) enforceStartLimit(w http.ResponseWriter, tenantID string) bool {
+	usage, err := executionStartUsage(h.tenants, tenantID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to check execution limits: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if usage.ResetAt == nil {
+		return true
+	}
+	retryAfter := int(time.Until(*usage.ResetAt).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(usage.ResetAt.Unix(), 10))
+	http.Error(w, "Execution start limit reached; next start allowed at "+usage.ResetAt.UTC().Format(time.RFC3339), http.StatusTooManyRequests)
+	return false
+}
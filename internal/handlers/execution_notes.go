@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	maxNoteLength         = 20000
+	defaultNoteSearchSize = 20
+	maxNoteSearchSize     = 100
+)
+
+type createNotePayload struct {
+	Body string `json:"body"`
+}
+
+// CreateExecutionNote attaches a markdown note to an execution, authored by the
+// calling user.
+func (h *JobHandler) CreateExecutionNote(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	var payload createNotePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		http.Error(w, "Note body is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(body) > maxNoteLength {
+		http.Error(w, "Note body exceeds "+strconv.Itoa(maxNoteLength)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	note := models.ExecutionNote{ExecutionID: execID, TenantID: tid, Body: body}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		note.AuthorID = &uid
+	}
+	created, err := h.repo.CreateExecutionNote(note)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to create note: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *JobHandler) ListExecutionNotes(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	if _, err := h.repo.GetExecution(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	notes, err := h.repo.ListExecutionNotes(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list notes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, notes)
+}
+
+// DeleteExecutionNote removes a note. Authors may delete their own notes; admins may
+// delete any note.
+func (h *JobHandler) DeleteExecutionNote(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	execID, noteID := vars["execID"], vars["noteID"]
+
+	notes, err := h.repo.ListExecutionNotes(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to load notes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var note *models.ExecutionNote
+	for i := range notes {
+		if notes[i].ID == noteID {
+			note = &notes[i]
+			break
+		}
+	}
+	if note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	roles, _ := authz.RolesFromRequest(r)
+	uid, _ := authz.UserIDFromRequest(r)
+	isAuthor := note.AuthorID != nil && uid != "" && *note.AuthorID == uid
+	if !isAuthor && !models.HasAtLeast(roles, models.RoleAdmin) {
+		http.Error(w, "only the author or an admin can delete this note", http.StatusForbidden)
+		return
+	}
+
+	if err := h.repo.DeleteExecutionNote(tid, execID, noteID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete note: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchExecutionNotes full-text searches the tenant's execution notes (?q=, ?limit=).
+func (h *JobHandler) SearchExecutionNotes(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit := defaultNoteSearchSize
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, maxNoteSearchSize)
+		}
+	}
+
+	notes, err := h.repo.SearchExecutionNotes(tid, q, limit)
+	if err != nil {
+		http.Error(w, "Failed to search notes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, notes)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/api/serviceerror"
+)
+
+// progressQueryTimeout bounds how long a progress request waits for the workflow
+// to answer before falling back to the recorded status.
+const progressQueryTimeout = 5 * time.Second
+
+// GetExecutionProgress reports the step an execution is at and how far it has come.
+// Active executions ask their workflow; finished ones, and runs whose workflow
+// cannot answer, are derived from the recorded status. During the run step the
+// percent complete follows the tables the engine reported as completed.
+func (h *JobHandler) GetExecutionProgress(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	progress := models.ProgressFromStatus(execution)
+	if execution.Status.Active() {
+		if live, ok := h.queryProgress(r.Context(), execution); ok {
+			progress = live
+		}
+	}
+	progress.ExecutionID, progress.Status = execution.ID, execution.Status
+
+	tables, err := h.repo.ListDefinitionASTTables(tid, execution.JobDefinitionID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, "Failed to list AST tables: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	checkpoints, err := h.repo.ListExecutionCheckpoints(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution checkpoints: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	progress.ApplyCheckpoints(tables, checkpoints)
+	writeJSON(w, http.StatusOK, progress)
+}
+
+// queryProgress asks the execution's workflow for its progress. It reports false
+// when the workflow is gone or does not answer in time.
+func (h *JobHandler) queryProgress(ctx context.Context, execution models.JobExecution) (models.ExecutionProgress, bool) {
+	ctx, cancel := context.WithTimeout(ctx, progressQueryTimeout)
+	defer cancel()
+
+	var progress models.ExecutionProgress
+	workflowID := temporal.ExecutionWorkflowID(execution.ID, execution.WorkflowID)
+	value, err := h.temporalClient.QueryWorkflow(ctx, workflowID, "", temporal.ProgressQueryName)
+	if err == nil {
+		err = value.Get(&progress)
+	}
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if !errors.As(err, &notFound) {
+			h.logger.Warn().Err(err).Str("execution_id", execution.ID).Msg("failed to query execution progress")
+		}
+		return progress, false
+	}
+	progress.Live = true
+	return progress, true
+}
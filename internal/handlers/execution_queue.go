@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// GetExecutionQueue reports how many of the tenant's executions are ahead of an
+// active execution and when it is predicted to start and finish, from the average
+// durations of past runs.
+func (h *JobHandler) GetExecutionQueue(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !execution.Status.Active() {
+		http.Error(w, "Execution has already finished", http.StatusConflict)
+		return
+	}
+
+	active, err := h.repo.ListTenantActiveExecutions(tid)
+	if err != nil {
+		http.Error(w, "Failed to list active executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	averages, err := h.repo.AverageDefinitionDurations(tid)
+	if err != nil {
+		http.Error(w, "Failed to load execution durations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.EstimateQueue(execution, active, averages, time.Now().UTC()))
+}
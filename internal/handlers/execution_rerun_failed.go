@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+// RerunFailedTables starts a new run of a finished execution that migrates only
+// the tables the engine reported as failed. The run uses the AST and overrides
+// the execution snapshotted, with the table list cut down to the failed tables,
+// so the tables that already succeeded are not copied again.
+func (h *JobHandler) RerunFailedTables(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch execution.Status {
+	case models.ExecutionStatusAwaitingApproval, models.ExecutionStatusPending, models.ExecutionStatusRunning, models.ExecutionStatusPaused:
+		http.Error(w, "Only finished executions can be re-run", http.StatusConflict)
+		return
+	}
+	if !h.authorizeRun(w, r, tid, execution.JobDefinitionID) {
+		return
+	}
+	def, err := h.repo.GetJobDefinitionByID(tid, execution.JobDefinitionID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !rejectArchived(w, def) {
+		return
+	}
+	if def.JobType == models.JobTypeSQLScript {
+		http.Error(w, "SQL script executions have no tables to re-run", http.StatusConflict)
+		return
+	}
+
+	tables, err := h.repo.ListExecutionTables(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution tables: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var failed []string
+	for _, table := range tables {
+		if table.Status == models.ExecutionTableFailed {
+			failed = append(failed, table.Table)
+		}
+	}
+	if len(failed) == 0 {
+		http.Error(w, "Execution has no failed tables to re-run", http.StatusConflict)
+		return
+	}
+	snapshot, err := h.repo.GetExecutionSnapshot(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Execution has no AST snapshot to re-run", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to load execution snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, kept, err := models.FilterASTTables(snapshot.AST, failed); err != nil || len(kept) == 0 {
+		http.Error(w, "None of the failed tables are in the execution's AST", http.StatusConflict)
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, execution.JobDefinitionID) {
+		return
+	}
+
+	creds, ok := h.runCredentials(w, r, tid, execution.JobDefinitionID)
+	if !ok {
+		return
+	}
+	overrides, err := h.executionOverrides(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to load execution overrides: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggeredBy, triggerContext := requestTrigger(r)
+	triggerContext["replay_of_execution_id"] = execID
+	triggerContext["rerun_tables"] = strings.Join(failed, ",")
+	params := temporal.ExecutionParams{
+		TenantID:            tid,
+		ExecutionID:         uuid.New().String(),
+		JobDefinitionID:     execution.JobDefinitionID,
+		JobType:             def.JobType,
+		ReplayOfExecutionID: execID,
+		OnlyTables:          failed,
+		Overrides:           overrides,
+		SkipSensors:         true,
+		AllowConcurrent:     r.URL.Query().Get("force") == "true",
+		TriggeredBy:         triggeredBy,
+		TriggerContext:      triggerContext,
+	}
+	h.startExecution(w, r, params, creds, "Job execution re-run for its failed tables.")
+}
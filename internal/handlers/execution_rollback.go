@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+// RollbackExecution starts a rollback of a finished execution: the engine drops
+// the destination objects the execution created and restores the ones it replaced,
+// working from the AST the execution snapshotted. The rollback is tracked as an
+// execution of its own, whose trigger context names the execution it rolls back.
+func (h *JobHandler) RollbackExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch execution.Status {
+	case models.ExecutionStatusFailed, models.ExecutionStatusCancelled, models.ExecutionStatusSucceeded:
+	case models.ExecutionStatusSkipped:
+		http.Error(w, "Skipped executions wrote nothing to roll back", http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Only finished executions can be rolled back", http.StatusConflict)
+		return
+	}
+	if execution.TriggerContext["rollback_of_execution_id"] != "" {
+		http.Error(w, "Rollback executions cannot be rolled back", http.StatusConflict)
+		return
+	}
+	def, err := h.repo.GetJobDefinitionByID(tid, execution.JobDefinitionID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !rejectArchived(w, def) {
+		return
+	}
+	if def.JobType == models.JobTypeSQLScript {
+		http.Error(w, "SQL script executions cannot be rolled back", http.StatusConflict)
+		return
+	}
+	if _, err := h.repo.GetExecutionSnapshot(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Execution has no AST snapshot to roll back", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to load execution snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, execution.JobDefinitionID) {
+		return
+	}
+
+	creds, ok := h.runCredentials(w, r, tid, execution.JobDefinitionID)
+	if !ok {
+		return
+	}
+
+	triggeredBy, triggerContext := requestTrigger(r)
+	triggerContext["rollback_of_execution_id"] = execID
+	params := temporal.ExecutionParams{
+		TenantID:              tid,
+		ExecutionID:           uuid.New().String(),
+		JobDefinitionID:       execution.JobDefinitionID,
+		JobType:               def.JobType,
+		ReplayOfExecutionID:   execID,
+		RollbackOfExecutionID: execID,
+		SkipSensors:           true,
+		AllowConcurrent:       r.URL.Query().Get("force") == "true",
+		TriggeredBy:           triggeredBy,
+		TriggerContext:        triggerContext,
+	}
+	h.startExecution(w, r, params, creds, "Job execution rollback started.")
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// StartupLatency averages how long executions spent in each startup stage, from
+// the workflow starting to the engine's first log line, over the last ?days (7 by
+// default), across all tenants or the one given by ?tenant_id.
+func (h *JobHandler) StartupLatency(w http.ResponseWriter, r *http.Request) {
+	days := models.DefaultStartupLatencyDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > models.MaxStartupLatencyDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", models.MaxStartupLatencyDays), http.StatusBadRequest)
+			return
+		}
+		days = v
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	timings, err := h.repo.ListStartupTimings(r.URL.Query().Get("tenant_id"), since)
+	if err != nil {
+		http.Error(w, "Failed to load startup timings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.AverageStartup(days, timings))
+}
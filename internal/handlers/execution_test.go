@@ -0,0 +1,1284 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+// startExecution seeds a READY definition with a running execution, the way the
+// execution workflow leaves it once the engine container is up.
+func startExecution(t *testing.T, h *testutil.Harness, tenantID string) models.JobExecution {
+	t.Helper()
+	jobs := h.Store.Jobs()
+	def, err := jobs.CrateDefinition(models.JobDefinition{
+		TenantID: tenantID,
+		Name:     "Nightly copy",
+		JobType:  models.JobTypeEngine,
+		AST:      []byte(`{"migrate":{}}`),
+		Status:   "READY",
+	})
+	if err != nil {
+		t.Fatalf("create definition: %v", err)
+	}
+	execID := uuid.NewString()
+	exec, err := jobs.CreateExecution(tenantID, def.ID, execID, temporal.ExecWorkflowIDPrefix+execID, models.TriggerUser, nil, false)
+	if err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if _, err := jobs.UpdateExecution(tenantID, exec.ID, "running", "", ""); err != nil {
+		t.Fatalf("start execution: %v", err)
+	}
+	return exec
+}
+
+func TestPauseAndResumeSignalTheWorkflow(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/pause", nil, token), http.StatusAccepted, nil)
+	// The workflow records the pause once the engine has checkpointed.
+	if _, err := h.Store.Jobs().SetExecutionPaused(tenant.ID, exec.ID, true); err != nil {
+		t.Fatalf("record pause: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/pause", nil, token), http.StatusConflict, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/resume", nil, token), http.StatusAccepted, nil)
+
+	signals := h.Temporal.Signals()
+	if len(signals) != 2 {
+		t.Fatalf("sent %d signals, want 2", len(signals))
+	}
+	want := temporal.ExecWorkflowIDPrefix + exec.ID
+	if signals[0].Name != temporal.PauseSignalName || signals[1].Name != temporal.ResumeSignalName ||
+		signals[0].WorkflowID != want || signals[1].WorkflowID != want {
+		t.Fatalf("signals = %+v", signals)
+	}
+}
+
+func TestPauseRequiresEditor(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, viewer, _ := h.SeedTenant("Acme", "viewer@acme.test", models.RoleViewer)
+	exec := startExecution(t, h, tenant.ID)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/pause", nil, h.Token(tenant.ID, viewer.ID, models.RoleViewer)), http.StatusForbidden, nil)
+	if signals := h.Temporal.Signals(); len(signals) != 0 {
+		t.Fatalf("sent %d signals without permission", len(signals))
+	}
+}
+
+func TestRerunReplaysTheSnapshottedAST(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/rerun", nil, token), http.StatusConflict, nil)
+	if _, err := jobs.UpdateExecution(tenant.ID, exec.ID, "failed", "", "boom"); err != nil {
+		t.Fatalf("fail execution: %v", err)
+	}
+	// Without a snapshot there is nothing to replay.
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/rerun", nil, token), http.StatusConflict, nil)
+
+	if err := jobs.CreateExecutionSnapshot(models.ExecutionSnapshot{
+		ExecutionID:     exec.ID,
+		TenantID:        tenant.ID,
+		JobDefinitionID: exec.JobDefinitionID,
+		AST:             []byte(`{"migrate":{}}`),
+	}); err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+exec.ID+"/rerun", nil, token), http.StatusAccepted, nil)
+
+	started := h.Temporal.Started()
+	if len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+	params, ok := started[0].Args[0].(temporal.ExecutionParams)
+	if !ok {
+		t.Fatalf("workflow args = %#v", started[0].Args)
+	}
+	if params.ReplayOfExecutionID != exec.ID || params.ExecutionID == exec.ID || params.JobDefinitionID != exec.JobDefinitionID {
+		t.Fatalf("params = %+v", params)
+	}
+}
+
+func TestRerunFailedTables(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+	path := "/api/v1/executions/" + exec.ID + "/rerun-failed"
+
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusConflict, nil)
+	if _, err := jobs.UpdateExecution(tenant.ID, exec.ID, "failed", "", "boom"); err != nil {
+		t.Fatalf("fail execution: %v", err)
+	}
+	if err := jobs.CreateExecutionSnapshot(models.ExecutionSnapshot{
+		ExecutionID:     exec.ID,
+		TenantID:        tenant.ID,
+		JobDefinitionID: exec.JobDefinitionID,
+		AST:             []byte(`{"migrate":{"tables":["users",{"name":"orders","batch_size":500},"invoices"]}}`),
+	}); err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	// Without per-table results there is nothing known to have failed.
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusConflict, nil)
+
+	if err := jobs.SaveExecutionTables(tenant.ID, exec.ID, []models.JobExecutionTable{
+		{Table: "users", Status: models.ExecutionTableSucceeded, RowsCopied: 10},
+		{Table: "orders", Status: models.ExecutionTableFailed},
+		{Table: "invoices", Status: models.ExecutionTableSucceeded, RowsCopied: 4},
+	}); err != nil {
+		t.Fatalf("save tables: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusAccepted, nil)
+
+	started := h.Temporal.Started()
+	if len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+	params := started[0].Args[0].(temporal.ExecutionParams)
+	if params.ReplayOfExecutionID != exec.ID || len(params.OnlyTables) != 1 || params.OnlyTables[0] != "orders" {
+		t.Fatalf("params = %+v", params)
+	}
+
+	ast, kept, err := models.FilterASTTables([]byte(`{"migrate":{"tables":["users",{"name":"orders","batch_size":500}]}}`), params.OnlyTables)
+	if err != nil || len(kept) != 1 {
+		t.Fatalf("filter tables = %v, %v", kept, err)
+	}
+	if string(ast) != `{"migrate":{"tables":[{"batch_size":500,"name":"orders"}]}}` {
+		t.Fatalf("filtered AST = %s", ast)
+	}
+}
+
+func TestRollbackExecution(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, user, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+	path := "/api/v1/executions/" + exec.ID + "/rollback"
+
+	h.Decode(h.Do(http.MethodPost, path, nil, h.Token(tenant.ID, user.ID, models.RoleViewer)), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusConflict, nil)
+	if _, err := jobs.UpdateExecution(tenant.ID, exec.ID, "failed", "", "boom"); err != nil {
+		t.Fatalf("fail execution: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusConflict, nil)
+	if err := jobs.CreateExecutionSnapshot(models.ExecutionSnapshot{
+		ExecutionID:     exec.ID,
+		TenantID:        tenant.ID,
+		JobDefinitionID: exec.JobDefinitionID,
+		AST:             []byte(`{"migrate":{"tables":["users","orders"]}}`),
+	}); err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	var started map[string]string
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusAccepted, &started)
+	workflows := h.Temporal.Started()
+	if len(workflows) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(workflows))
+	}
+	params := workflows[0].Args[0].(temporal.ExecutionParams)
+	if params.ExecutionID != started["executionID"] || params.RollbackOfExecutionID != exec.ID || params.ReplayOfExecutionID != exec.ID {
+		t.Fatalf("params = %+v", params)
+	}
+	if params.TriggerContext["rollback_of_execution_id"] != exec.ID {
+		t.Fatalf("trigger context = %v", params.TriggerContext)
+	}
+
+	// The rollback is an execution of its own and carries the engine's report.
+	rollback, err := jobs.CreateExecution(tenant.ID, exec.JobDefinitionID, params.ExecutionID, workflows[0].Options.ID, params.TriggeredBy, params.TriggerContext, true)
+	if err != nil {
+		t.Fatalf("create rollback execution: %v", err)
+	}
+	if _, err := jobs.UpdateExecution(tenant.ID, rollback.ID, "succeeded", "", ""); err != nil {
+		t.Fatalf("finish rollback: %v", err)
+	}
+	report := models.RollbackReport{RolledBackExecutionID: exec.ID, Objects: []models.RolledBackObject{
+		{Object: "users", Action: models.RollbackActionDropped},
+		{Object: "orders", Action: models.RollbackActionRestored},
+	}}
+	content, _ := json.Marshal(report)
+	artifact := models.ExecutionArtifact{TenantID: tenant.ID, ExecutionID: rollback.ID, Kind: models.ArtifactKindRollback, Content: content}
+	if _, err := jobs.SaveExecutionArtifact(artifact); err != nil {
+		t.Fatalf("save artifact: %v", err)
+	}
+	var got models.JobExecution
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+rollback.ID, nil, token), http.StatusOK, &got)
+	if got.Rollback == nil || got.Rollback.RolledBackExecutionID != exec.ID || len(got.Rollback.Objects) != 2 {
+		t.Fatalf("rollback report = %+v", got.Rollback)
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/"+rollback.ID+"/rollback", nil, token), http.StatusConflict, nil)
+}
+
+func TestRunJobIdempotencyKey(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	if _, err := h.Store.Jobs().UpdateExecution(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, "", ""); err != nil {
+		t.Fatalf("finish execution: %v", err)
+	}
+	path := "/api/v1/jobs/" + exec.JobDefinitionID + "/run"
+
+	run := func() (int, map[string]string) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", "retry-1")
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, req)
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	code, first := run()
+	if code != http.StatusAccepted {
+		t.Fatalf("first run = %d, want %d", code, http.StatusAccepted)
+	}
+	// The workflow creates the execution row once it starts.
+	params := h.Temporal.Started()[0].Args[0].(temporal.ExecutionParams)
+	if params.TriggerContext["idempotency_key"] != "retry-1" {
+		t.Fatalf("trigger context = %v", params.TriggerContext)
+	}
+	if _, err := h.Store.Jobs().CreateExecution(tenant.ID, params.JobDefinitionID, params.ExecutionID, temporal.ExecWorkflowIDPrefix+params.ExecutionID, params.TriggeredBy, params.TriggerContext, params.AllowConcurrent); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+
+	code, second := run()
+	if code != http.StatusOK || second["executionID"] != first["executionID"] {
+		t.Fatalf("repeated run = %d %v, want %d with execution %s", code, second, http.StatusOK, first["executionID"])
+	}
+	if started := h.Temporal.Started(); len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+}
+
+func TestExecutionTriggerAttribution(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, user, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+	if err := jobs.SetExecutionComplete(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, 1, 1); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+
+	var run map[string]string
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/"+exec.JobDefinitionID+"/run", nil, token), http.StatusAccepted, &run)
+	params := h.Temporal.Started()[0].Args[0].(temporal.ExecutionParams)
+	if _, err := jobs.CreateExecution(tenant.ID, params.JobDefinitionID, params.ExecutionID, "", params.TriggeredBy, params.TriggerContext, params.AllowConcurrent); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	var manual map[string]interface{}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+params.ExecutionID, nil, token), http.StatusOK, &manual)
+	if manual["trigger_type"] != models.TriggerTypeManual || manual["triggered_by_user_id"] != user.ID {
+		t.Fatalf("manual run trigger = %v by %v", manual["trigger_type"], manual["triggered_by_user_id"])
+	}
+
+	// A scheduled run names the schedule's creator in its context, but nobody
+	// started it by hand.
+	scheduled, err := jobs.CreateExecution(tenant.ID, exec.JobDefinitionID, uuid.NewString(), "", models.TriggerSchedule, map[string]string{"user_id": user.ID}, true)
+	if err != nil {
+		t.Fatalf("create scheduled execution: %v", err)
+	}
+	var got map[string]interface{}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+scheduled.ID, nil, token), http.StatusOK, &got)
+	if got["trigger_type"] != models.TriggerTypeSchedule || got["triggered_by_user_id"] != nil {
+		t.Fatalf("scheduled run trigger = %v by %v", got["trigger_type"], got["triggered_by_user_id"])
+	}
+}
+
+func TestExecutionStartupBreakdown(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, user, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+
+	start := time.Now().Add(-time.Minute).UTC()
+	offsets := map[string]time.Duration{
+		models.StartupStageWorkflowStarted:  0,
+		models.StartupStagePrepared:         2 * time.Second,
+		models.StartupStageImagePulled:      32 * time.Second,
+		models.StartupStageContainerStarted: 35 * time.Second,
+		models.StartupStageFirstLog:         40 * time.Second,
+	}
+	for stage, offset := range offsets {
+		if err := h.Store.Jobs().RecordStartupStage(tenant.ID, exec.ID, stage, start.Add(offset)); err != nil {
+			t.Fatalf("record %s: %v", stage, err)
+		}
+	}
+
+	var got models.JobExecution
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+exec.ID, nil, token), http.StatusOK, &got)
+	if got.StartupBreakdown == nil || len(got.StartupBreakdown.Phases) != len(models.StartupStages) || got.StartupBreakdown.TotalSeconds != 40 {
+		t.Fatalf("startup breakdown = %+v", got.StartupBreakdown)
+	}
+	if pull := got.StartupBreakdown.Phases[2]; pull.Stage != models.StartupStageImagePulled || pull.Seconds != 30 {
+		t.Fatalf("image pull phase = %+v", pull)
+	}
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/executions/startup-latency", nil, token), http.StatusForbidden, nil)
+	var latency models.StartupLatency
+	admin := h.Token(tenant.ID, user.ID, models.RoleSuperAdmin)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/executions/startup-latency?days=1", nil, admin), http.StatusOK, &latency)
+	if latency.Executions != 1 || len(latency.Stages) != 4 || latency.AvgTotalSeconds != 40 {
+		t.Fatalf("startup latency = %+v", latency)
+	}
+}
+
+func TestRunJobRejectsConcurrentExecutions(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	path := "/api/v1/jobs/" + exec.JobDefinitionID + "/run"
+
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusConflict, nil)
+	if started := h.Temporal.Started(); len(started) != 0 {
+		t.Fatalf("started %d workflows while an execution was running", len(started))
+	}
+
+	h.Decode(h.Do(http.MethodPost, path+"?force=true", nil, token), http.StatusAccepted, nil)
+	started := h.Temporal.Started()
+	if len(started) != 1 || !started[0].Args[0].(temporal.ExecutionParams).AllowConcurrent {
+		t.Fatalf("started = %+v", started)
+	}
+
+	// The workflow refuses to record a second active execution unless forced.
+	_, err := h.Store.Jobs().CreateExecution(tenant.ID, exec.JobDefinitionID, uuid.NewString(), "", models.TriggerUser, nil, false)
+	if !errors.Is(err, repository.ErrExecutionActive) {
+		t.Fatalf("create concurrent execution: %v, want ErrExecutionActive", err)
+	}
+}
+
+func TestRunJobPriorityTaskQueue(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	path := "/api/v1/jobs/" + exec.JobDefinitionID + "/run?force=true"
+
+	h.Decode(h.Do(http.MethodPost, path+"&priority=urgent", nil, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, path, nil, token), http.StatusAccepted, nil)
+	h.Decode(h.Do(http.MethodPost, path+"&priority=high", nil, token), http.StatusAccepted, nil)
+
+	started := h.Temporal.Started()
+	if len(started) != 2 {
+		t.Fatalf("started %d workflows, want 2", len(started))
+	}
+	if started[0].Options.TaskQueue != temporal.TaskQueueName {
+		t.Fatalf("normal run task queue = %q", started[0].Options.TaskQueue)
+	}
+	params := started[1].Args[0].(temporal.ExecutionParams)
+	if started[1].Options.TaskQueue != temporal.PriorityTaskQueueName || params.Priority != models.PriorityHigh ||
+		params.TriggerContext["priority"] != models.PriorityHigh {
+		t.Fatalf("high-priority run = %+v, %+v", started[1].Options, params)
+	}
+}
+
+func TestDefinitionLockedWhileExecutionInProgress(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, user, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	path := "/api/v1/jobs/" + exec.JobDefinitionID
+	edit := map[string]interface{}{"ast": map[string]interface{}{"migrate": map[string]interface{}{"batch": 10}}}
+
+	var def models.JobDefinition
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &def)
+	if def.Lock == nil || def.Lock.ExecutionID != exec.ID || def.Lock.Status != models.ExecutionStatusRunning {
+		t.Fatalf("lock = %+v, want one held by execution %s", def.Lock, exec.ID)
+	}
+
+	h.Decode(h.Do(http.MethodPatch, path, edit, token), http.StatusConflict, nil)
+	// Edits that don't change what runs are allowed.
+	h.Decode(h.Do(http.MethodPatch, path, map[string]interface{}{"description": "Copies orders"}, token), http.StatusOK, nil)
+	h.Decode(h.Do(http.MethodPatch, path+"?override_lock=true", edit, token), http.StatusForbidden, nil)
+	admin := h.Token(tenant.ID, user.ID, models.RoleAdmin)
+	h.Decode(h.Do(http.MethodPatch, path+"?override_lock=true", edit, admin), http.StatusOK, nil)
+
+	if err := h.Store.Jobs().SetExecutionComplete(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, 0, 0); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+	var unlocked models.JobDefinition
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &unlocked)
+	if unlocked.Lock != nil {
+		t.Fatalf("lock = %+v after the execution finished", unlocked.Lock)
+	}
+	h.Decode(h.Do(http.MethodPatch, path, edit, token), http.StatusOK, nil)
+}
+
+func TestGetExecutionLongPoll(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	path := "/api/v1/jobs/executions/" + exec.ID
+
+	h.Decode(h.Do(http.MethodGet, path+"?wait=10m", nil, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodGet, path+"?wait=1s&after_status=bogus", nil, token), http.StatusBadRequest, nil)
+
+	// A status other than after_status returns at once.
+	var got models.JobExecution
+	h.Decode(h.Do(http.MethodGet, path+"?wait=30s&after_status=pending", nil, token), http.StatusOK, &got)
+	if got.Status != models.ExecutionStatusRunning {
+		t.Fatalf("status = %q, want running", got.Status)
+	}
+
+	// Without a change the request is held for the wait, then returns as is.
+	start := time.Now()
+	h.Decode(h.Do(http.MethodGet, path+"?wait=300ms&after_status=running", nil, token), http.StatusOK, &got)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || got.Status != models.ExecutionStatusRunning {
+		t.Fatalf("returned %q after %s, want running after the wait", got.Status, elapsed)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		h.Store.Jobs().SetExecutionComplete(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, 1, 1)
+	}()
+	h.Decode(h.Do(http.MethodGet, path+"?wait=30s", nil, token), http.StatusOK, &got)
+	if got.Status != models.ExecutionStatusSucceeded {
+		t.Fatalf("status = %q, want succeeded once the run completed", got.Status)
+	}
+}
+
+func TestRunJobTimeoutOverride(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	path := "/api/v1/jobs/" + exec.JobDefinitionID + "/run?force=true"
+
+	h.Decode(h.Do(http.MethodPost, path+"&timeout_seconds=5", nil, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, path+"&timeout_seconds=forever", nil, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, path+"&timeout_seconds=7200", nil, token), http.StatusAccepted, nil)
+
+	started := h.Temporal.Started()
+	if len(started) != 1 || started[0].Args[0].(temporal.ExecutionParams).TimeoutSeconds != 7200 {
+		t.Fatalf("started = %+v, want one run with a 7200s timeout", started)
+	}
+}
+
+func TestQuarantinedArtifactsAreNotServed(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	exec := startExecution(t, h, tenant.ID)
+
+	var updated models.Tenant
+	h.Decode(h.Do(http.MethodPatch, "/api/v1/tenants/"+tenant.ID, map[string]bool{"require_artifact_scan": true}, token), http.StatusOK, &updated)
+	if !updated.RequireArtifactScan {
+		t.Fatal("require_artifact_scan was not saved")
+	}
+
+	jobs := h.Store.Jobs()
+	for _, artifact := range []models.ExecutionArtifact{
+		{Kind: models.ArtifactKindPartialState, ScanStatus: models.ArtifactScanClean},
+		{Kind: models.ArtifactKindSampleDiff, ScanStatus: models.ArtifactScanQuarantined, ScanDetail: "infected: Eicar-Test-Signature"},
+	} {
+		artifact.TenantID, artifact.ExecutionID = tenant.ID, exec.ID
+		artifact.Content = json.RawMessage(`{"tables":[]}`)
+		if _, err := jobs.SaveExecutionArtifact(artifact); err != nil {
+			t.Fatalf("save artifact: %v", err)
+		}
+	}
+
+	var artifacts []models.ExecutionArtifact
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+exec.ID+"/artifacts", nil, token), http.StatusOK, &artifacts)
+	if len(artifacts) != 2 {
+		t.Fatalf("got %d artifacts, want 2", len(artifacts))
+	}
+	for _, artifact := range artifacts {
+		served := len(artifact.Content) > 0 && string(artifact.Content) != "null"
+		if served == artifact.Quarantined() {
+			t.Fatalf("%s artifact (%s) served content = %v", artifact.Kind, artifact.ScanStatus, served)
+		}
+	}
+}
+
+func TestRunJobWithOverrides(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	var conns [2]*models.Connection
+	for i, name := range []string{"source", "destination"} {
+		conn, err := h.Store.Connections().Create(&models.Connection{TenantID: tenant.ID, Name: name, DataFormat: "pg"})
+		if err != nil {
+			t.Fatalf("create %s connection: %v", name, err)
+		}
+		conns[i] = conn
+	}
+	def, err := h.Store.Jobs().CrateDefinition(models.JobDefinition{
+		TenantID:                tenant.ID,
+		Name:                    "Nightly copy",
+		JobType:                 models.JobTypeEngine,
+		AST:                     []byte(`{"migrate":{"tables":["orders"]}}`),
+		SourceConnectionID:      conns[0].ID,
+		DestinationConnectionID: conns[1].ID,
+		Status:                  "READY",
+	})
+	if err != nil {
+		t.Fatalf("create definition: %v", err)
+	}
+	path := "/api/v1/jobs/" + def.ID + "/run?force=true"
+
+	for _, overrides := range []string{
+		`[1, 2]`,
+		`{"connections": {}}`,
+		`{"filter": {"$secret": "token", "value": "hunter2"}}`,
+		`{"write_modes": {"orders": "upsert"}}`,
+		// The merged AST is validated like the definition's own.
+		`{"filter": {"$secret": "token"}}`,
+	} {
+		body := map[string]json.RawMessage{"overrides": json.RawMessage(overrides)}
+		h.Decode(h.Do(http.MethodPost, path, body, token), http.StatusBadRequest, nil)
+	}
+
+	overrides := json.RawMessage(`{"batch_size":500,"target_schema":"scratch"}`)
+	h.Decode(h.Do(http.MethodPost, path, map[string]json.RawMessage{"overrides": overrides}, token), http.StatusAccepted, nil)
+	started := h.Temporal.Started()
+	if len(started) != 1 || string(started[0].Args[0].(temporal.ExecutionParams).Overrides) != string(overrides) {
+		t.Fatalf("started = %+v, want one run with the overrides", started)
+	}
+
+	// A viewer with a run grant may vary the run's parameters, not what it migrates.
+	operator, err := h.Store.Users().CreateUser(tenant.ID, "ops@acme.test", "Password123!", "Ops", "User", []models.UserRole{models.RoleViewer})
+	if err != nil {
+		t.Fatalf("create viewer: %v", err)
+	}
+	if _, err := h.Store.Jobs().SetRunGrants(tenant.ID, def.ID, models.RunGrants{Users: []string{operator.ID}}, ""); err != nil {
+		t.Fatalf("grant run: %v", err)
+	}
+	viewerToken := h.Token(tenant.ID, operator.ID, operator.Roles...)
+	for _, overrides := range []string{
+		`{"migrate":{"tables":["users"]}}`,
+		`{"migrate":{"batch_size":100},"query":"SELECT * FROM users"}`,
+	} {
+		body := map[string]json.RawMessage{"overrides": json.RawMessage(overrides)}
+		h.Decode(h.Do(http.MethodPost, path, body, viewerToken), http.StatusForbidden, nil)
+	}
+	overrides = json.RawMessage(`{"migrate":{"batch_size":100,"filters":{"orders":"id > 10"}}}`)
+	h.Decode(h.Do(http.MethodPost, path, map[string]json.RawMessage{"overrides": overrides}, viewerToken), http.StatusAccepted, nil)
+	if started := h.Temporal.Started(); len(started) != 2 {
+		t.Fatalf("started %d runs, want the viewer's parameter run too", len(started))
+	}
+
+	merged, err := models.ApplyRunOverrides(json.RawMessage(`{"migrate":{"batch_size":100,"tables":["orders"]},"filter":{"x":1}}`),
+		json.RawMessage(`{"migrate":{"batch_size":500},"filter":null}`))
+	if err != nil {
+		t.Fatalf("apply overrides: %v", err)
+	}
+	if string(merged) != `{"migrate":{"batch_size":500,"tables":["orders"]}}` {
+		t.Fatalf("merged AST = %s", merged)
+	}
+}
+
+func TestGetExecutionQueue(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	first := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+
+	create := func() models.JobExecution {
+		execID := uuid.NewString()
+		exec, err := jobs.CreateExecution(tenant.ID, first.JobDefinitionID, execID, temporal.ExecWorkflowIDPrefix+execID, models.TriggerUser, nil, true)
+		if err != nil {
+			t.Fatalf("create execution: %v", err)
+		}
+		return exec
+	}
+	queuePath := func(exec models.JobExecution) string { return "/api/v1/executions/" + exec.ID + "/queue" }
+
+	// Without a finished run there is no duration to predict from.
+	waiting := create()
+	var queue models.ExecutionQueue
+	h.Decode(h.Do(http.MethodGet, queuePath(waiting), nil, token), http.StatusOK, &queue)
+	if queue.Ahead != 0 || queue.Running != 1 || queue.EstimatedStartAt != nil {
+		t.Fatalf("queue = %+v, want nothing ahead, one running and no estimate", queue)
+	}
+
+	if err := jobs.SetExecutionComplete(tenant.ID, first.ID, models.ExecutionStatusSucceeded, 1, 1); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+	h.Decode(h.Do(http.MethodGet, queuePath(first), nil, token), http.StatusConflict, nil)
+
+	last := create()
+	var placed models.ExecutionQueue
+	h.Decode(h.Do(http.MethodGet, queuePath(last), nil, token), http.StatusOK, &placed)
+	if placed.Ahead != 1 || placed.Running != 0 || placed.EstimatedStartAt == nil || placed.EstimatedFinishAt == nil {
+		t.Fatalf("queue = %+v, want one ahead with estimates", placed)
+	}
+	if placed.EstimatedFinishAt.Before(*placed.EstimatedStartAt) {
+		t.Fatalf("estimated finish %s is before start %s", placed.EstimatedFinishAt, placed.EstimatedStartAt)
+	}
+}
+
+func TestExecutionWebhooksRenderPayloadTemplates(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	exec := startExecution(t, h, tenant.ID)
+
+	// Templates are rendered against a sample event when they are saved.
+	h.Decode(h.Do(http.MethodPost, "/api/v1/webhooks/execution", map[string]interface{}{
+		"url":              "https://hooks.acme.test/stratum",
+		"payload_template": map[string]interface{}{"type": "go_template", "template": `{"status": {{.data.status}}}`},
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/webhooks/execution", map[string]interface{}{
+		"url":              "https://hooks.acme.test/stratum",
+		"payload_template": map[string]interface{}{"type": "jsonpath", "fields": map[string]string{"id": "data.execution_id"}},
+	}, token), http.StatusBadRequest, nil)
+
+	var sub models.WebhookSubscription
+	h.Decode(h.Do(http.MethodPost, "/api/v1/webhooks/execution", map[string]interface{}{
+		"url":    "https://hooks.acme.test/stratum",
+		"events": []string{models.WebhookEventExecutionSucceeded},
+		"payload_template": map[string]interface{}{"type": "jsonpath", "fields": map[string]string{
+			"id":     "$.data.execution_id",
+			"state":  "$.data['status']",
+			"source": "$.data.missing",
+		}},
+	}, token), http.StatusCreated, &sub)
+
+	var test models.WebhookDelivery
+	h.Decode(h.Do(http.MethodPost, "/api/v1/webhooks/execution/"+sub.ID+"/test", nil, token), http.StatusAccepted, &test)
+	var sample map[string]interface{}
+	if err := json.Unmarshal(test.Payload, &sample); err != nil {
+		t.Fatalf("decode test payload: %v", err)
+	}
+	if sample["state"] != "succeeded" || sample["source"] != nil || len(sample) != 3 {
+		t.Fatalf("test payload = %v", sample)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+exec.ID+"/complete", map[string]interface{}{
+		"status":            models.ExecutionStatusSucceeded,
+		"records_processed": 10,
+	}, token), http.StatusNoContent, nil)
+
+	var deliveries []models.WebhookDelivery
+	h.Decode(h.Do(http.MethodGet, "/api/v1/webhooks/execution/"+sub.ID+"/deliveries", nil, token), http.StatusOK, &deliveries)
+	if len(deliveries) != 2 {
+		t.Fatalf("deliveries = %d, want the test delivery and the result", len(deliveries))
+	}
+	var payload map[string]interface{}
+	for _, d := range deliveries {
+		if d.EventType == models.WebhookEventExecutionSucceeded && d.ID != test.ID {
+			if err := json.Unmarshal(d.Payload, &payload); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+		}
+	}
+	if payload["id"] != exec.ID || payload["state"] != "succeeded" {
+		t.Fatalf("delivered payload = %v", payload)
+	}
+}
+
+func TestRequeueDeadLetterExecutions(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	jobs := h.Store.Jobs()
+	first := startExecution(t, h, tenant.ID)
+	if err := jobs.SetExecutionComplete(tenant.ID, first.ID, models.ExecutionStatusFailed, 0, 0); err != nil {
+		t.Fatalf("fail execution: %v", err)
+	}
+	secondID := uuid.NewString()
+	second, err := jobs.CreateExecution(tenant.ID, first.JobDefinitionID, secondID, temporal.ExecWorkflowIDPrefix+secondID, models.TriggerSchedule, nil, false)
+	if err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if err := jobs.SetExecutionComplete(tenant.ID, second.ID, models.ExecutionStatusFailed, 0, 0); err != nil {
+		t.Fatalf("fail execution: %v", err)
+	}
+	succeeded := startExecution(t, h, tenant.ID)
+	if err := jobs.SetExecutionComplete(tenant.ID, succeeded.ID, models.ExecutionStatusSucceeded, 10, 0); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+
+	var dead []models.JobExecution
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/dead-letter", nil, token), http.StatusOK, &dead)
+	if len(dead) != 2 {
+		t.Fatalf("dead-letter executions = %d, want 2", len(dead))
+	}
+
+	var resp struct {
+		Requeued int `json:"requeued"`
+		Skipped  int `json:"skipped"`
+		Results  []struct {
+			ExecutionID string `json:"execution_id"`
+			Status      string `json:"status"`
+			RequeuedAs  string `json:"requeued_as_execution_id"`
+		} `json:"results"`
+	}
+	body := map[string]interface{}{"execution_ids": []string{first.ID, second.ID, succeeded.ID}}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/requeue", body, token), http.StatusAccepted, &resp)
+	if resp.Requeued != 2 || resp.Skipped != 1 {
+		t.Fatalf("requeued %d, skipped %d; want 2 and 1", resp.Requeued, resp.Skipped)
+	}
+	// Both failed runs of the definition are replaced by one new run.
+	started := h.Temporal.Started()
+	if len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+	params := started[0].Args[0].(temporal.ExecutionParams)
+	if params.TriggeredBy != models.TriggerRetry || params.TriggerContext["requeued_from_execution_id"] != first.ID {
+		t.Fatalf("trigger = %s %v", params.TriggeredBy, params.TriggerContext)
+	}
+	if resp.Results[0].RequeuedAs != params.ExecutionID || resp.Results[1].RequeuedAs != params.ExecutionID {
+		t.Fatalf("results = %+v, want both requeued as %s", resp.Results, params.ExecutionID)
+	}
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/dead-letter", nil, token), http.StatusOK, &dead)
+	if len(dead) != 0 {
+		t.Fatalf("dead-letter executions after requeue = %d, want 0", len(dead))
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/executions/requeue", map[string]interface{}{"execution_ids": []string{first.ID}}, token), http.StatusOK, &resp)
+	if resp.Requeued != 0 || resp.Skipped != 1 {
+		t.Fatalf("second requeue: requeued %d, skipped %d", resp.Requeued, resp.Skipped)
+	}
+}
+
+func TestRunApprovalGate(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, requester, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	h.Decode(h.Do(http.MethodPatch, "/api/v1/tenants/"+tenant.ID, map[string]bool{"require_run_approval": true}, token), http.StatusOK, nil)
+	exec := startExecution(t, h, tenant.ID)
+	if err := h.Store.Jobs().SetExecutionComplete(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, 10, 0); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+
+	var held struct {
+		ExecutionID string `json:"executionID"`
+		Status      string `json:"status"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/"+exec.JobDefinitionID+"/run", nil, token), http.StatusAccepted, &held)
+	if held.Status != string(models.ExecutionStatusAwaitingApproval) {
+		t.Fatalf("run status = %q, want awaiting_approval", held.Status)
+	}
+	if started := h.Temporal.Started(); len(started) != 0 {
+		t.Fatalf("started %d workflows before approval", len(started))
+	}
+
+	approve := "/api/v1/executions/" + held.ExecutionID + "/approve"
+	h.Decode(h.Do(http.MethodPost, approve, nil, token), http.StatusForbidden, nil)
+	editor, err := h.Store.Users().CreateUser(tenant.ID, "editor@acme.test", "Password123!", "Test", "User", []models.UserRole{models.RoleEditor})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, approve, nil, h.Token(tenant.ID, editor.ID, models.RoleEditor)), http.StatusForbidden, nil)
+
+	approver, err := h.Store.Users().CreateUser(tenant.ID, "approver@acme.test", "Password123!", "Test", "User", []models.UserRole{models.RoleAdmin})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	approverToken := h.Token(tenant.ID, approver.ID, models.RoleAdmin)
+	h.Decode(h.Do(http.MethodPost, approve, nil, approverToken), http.StatusAccepted, nil)
+	started := h.Temporal.Started()
+	if len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+	params := started[0].Args[0].(temporal.ExecutionParams)
+	if params.ExecutionID != held.ExecutionID || params.TriggerContext["user_id"] != requester.ID {
+		t.Fatalf("approved run = %+v", params)
+	}
+	h.Decode(h.Do(http.MethodPost, approve, nil, approverToken), http.StatusConflict, nil)
+}
+
+func TestStaleValidationPreflight(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	exec := startExecution(t, h, tenant.ID)
+	if err := h.Store.Jobs().SetExecutionComplete(tenant.ID, exec.ID, models.ExecutionStatusSucceeded, 10, 0); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+	jobs := h.Store.Jobs()
+	tenantPath := "/api/v1/tenants/" + tenant.ID
+	defPath := "/api/v1/jobs/" + exec.JobDefinitionID
+
+	due, err := jobs.ListSchemaCheckDefinitions(time.Now(), 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("due with freshness off = %d, %v", len(due), err)
+	}
+	h.Decode(h.Do(http.MethodPatch, tenantPath, map[string]string{"validation_freshness": "sometimes"}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPatch, tenantPath, map[string]string{"validation_freshness": "warn"}, token), http.StatusOK, nil)
+	due, err = jobs.ListSchemaCheckDefinitions(time.Now(), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due with freshness warn = %d, %v", len(due), err)
+	}
+
+	def := due[0]
+	for i, hash := range []string{"a", "a", "b", "c"} {
+		flagged, err := jobs.RecordDefinitionSchemaCheck(tenant.ID, def.ID, def.UpdatedAt, hash)
+		if err != nil {
+			t.Fatalf("record check %d: %v", i, err)
+		}
+		// Only the first mismatch with the baseline flags the definition.
+		if flagged != (i == 2) {
+			t.Fatalf("check %d flagged = %v", i, flagged)
+		}
+	}
+
+	var stale models.JobDefinition
+	h.Decode(h.Do(http.MethodGet, defPath, nil, token), http.StatusOK, &stale)
+	if !stale.StaleValidation || stale.StaleSince == nil || len(stale.Warnings) == 0 || stale.Warnings[len(stale.Warnings)-1] != models.StaleValidationMessage {
+		t.Fatalf("definition = stale %v since %v, warnings %v", stale.StaleValidation, stale.StaleSince, stale.Warnings)
+	}
+
+	var run map[string]interface{}
+	h.Decode(h.Do(http.MethodPost, defPath+"/run", nil, token), http.StatusAccepted, &run)
+	if run["warning"] != models.StaleValidationMessage {
+		t.Fatalf("run response = %v, want the stale validation warning", run)
+	}
+	if started := h.Temporal.Started(); len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+
+	h.Decode(h.Do(http.MethodPatch, tenantPath, map[string]string{"validation_freshness": "block"}, token), http.StatusOK, nil)
+	h.Decode(h.Do(http.MethodPost, defPath+"/run", nil, token), http.StatusConflict, nil)
+
+	// Revalidating the definition starts over with a new baseline.
+	if err := jobs.ResetDefinitionSchemaCheck(tenant.ID, def.ID); err != nil {
+		t.Fatalf("reset check: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPost, defPath+"/run", nil, token), http.StatusAccepted, nil)
+}
+
+func TestListJobsETagTracksStaleValidation(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+	def, err := jobs.GetJobDefinitionByID(tenant.ID, exec.JobDefinitionID)
+	if err != nil {
+		t.Fatalf("load definition: %v", err)
+	}
+	if _, err := jobs.RecordDefinitionSchemaCheck(tenant.ID, def.ID, def.UpdatedAt, "a"); err != nil {
+		t.Fatalf("record baseline check: %v", err)
+	}
+
+	list := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, req)
+		return rec
+	}
+	first := list("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("list = %d with ETag %q", first.Code, etag)
+	}
+	if rec := list(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged list = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	// Drift is recorded without touching the definition itself.
+	if flagged, err := jobs.RecordDefinitionSchemaCheck(tenant.ID, def.ID, def.UpdatedAt, "b"); err != nil || !flagged {
+		t.Fatalf("record drift = %v, %v", flagged, err)
+	}
+	rec := list(etag)
+	var defs []models.JobDefinition
+	h.Decode(rec, http.StatusOK, &defs)
+	if len(defs) != 1 || !defs[0].StaleValidation {
+		t.Fatalf("definitions after drift = %+v", defs)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatalf("ETag unchanged after drift")
+	}
+}
+
+func TestExecutionStartLimits(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, admin, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	limitsPath := "/api/v1/tenants/" + tenant.ID + "/limits"
+
+	h.Decode(h.Do(http.MethodPut, limitsPath, map[string]interface{}{"plan": "free"}, token), http.StatusForbidden, nil)
+	superToken := h.Token(tenant.ID, admin.ID, models.RoleSuperAdmin)
+	h.Decode(h.Do(http.MethodPut, limitsPath, map[string]interface{}{"plan": "gold"}, superToken), http.StatusBadRequest, nil)
+	var usage models.ExecutionStartUsage
+	h.Decode(h.Do(http.MethodPut, limitsPath, map[string]interface{}{"plan": "free", "burst": 5}, superToken), http.StatusOK, &usage)
+	if usage.Effective.StartsPerHour != 10 || usage.Effective.Burst != 5 || usage.ResetAt != nil {
+		t.Fatalf("limits = %+v", usage)
+	}
+
+	var exec models.JobExecution
+	for i := 0; i < 5; i++ {
+		exec = startExecution(t, h, tenant.ID)
+	}
+	h.Decode(h.Do(http.MethodGet, limitsPath, nil, token), http.StatusOK, &usage)
+	if usage.StartsLastMinute != 5 || usage.ResetAt == nil {
+		t.Fatalf("usage after burst = %+v", usage)
+	}
+
+	rec := h.Do(http.MethodPost, "/api/v1/jobs/"+exec.JobDefinitionID+"/run?force=true", nil, token)
+	h.Decode(rec, http.StatusTooManyRequests, nil)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("429 without Retry-After")
+	}
+	if started := h.Temporal.Started(); len(started) != 0 {
+		t.Fatalf("started %d workflows beyond the start limit", len(started))
+	}
+
+	h.Decode(h.Do(http.MethodPut, limitsPath, map[string]interface{}{"plan": "enterprise"}, superToken), http.StatusOK, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/"+exec.JobDefinitionID+"/run?force=true", nil, token), http.StatusAccepted, nil)
+}
+
+func TestBlackoutWindows(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, editor, editorToken := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	adminToken := h.Token(tenant.ID, editor.ID, models.RoleAdmin)
+	windowsPath := "/api/v1/tenants/" + tenant.ID + "/blackout-windows"
+
+	now := time.Now().UTC()
+	window := map[string]interface{}{
+		"name":       "business hours",
+		"start_time": now.Add(-time.Hour).Format("15:04"),
+		"end_time":   now.Add(time.Hour).Format("15:04"),
+	}
+	h.Decode(h.Do(http.MethodPost, windowsPath, window, editorToken), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodPost, windowsPath, map[string]interface{}{"name": "x", "start_time": "8am", "end_time": "18:00"}, adminToken), http.StatusBadRequest, nil)
+	var created models.BlackoutWindow
+	h.Decode(h.Do(http.MethodPost, windowsPath, window, adminToken), http.StatusCreated, &created)
+	if created.Timezone != "UTC" || len(created.Days) != 0 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	exec := startExecution(t, h, tenant.ID)
+	run := "/api/v1/jobs/" + exec.JobDefinitionID + "/run?force=true"
+	h.Decode(h.Do(http.MethodPost, run, nil, editorToken), http.StatusConflict, nil)
+	h.Decode(h.Do(http.MethodPost, run+"&override_blackout=true", nil, editorToken), http.StatusForbidden, nil)
+	if started := h.Temporal.Started(); len(started) != 0 {
+		t.Fatalf("started %d workflows inside a blackout window", len(started))
+	}
+
+	h.Decode(h.Do(http.MethodPost, run+"&override_blackout=true", nil, adminToken), http.StatusAccepted, nil)
+	started := h.Temporal.Started()
+	if len(started) != 1 {
+		t.Fatalf("started %d workflows, want 1", len(started))
+	}
+	if params := started[0].Args[0].(temporal.ExecutionParams); params.TriggerContext["blackout_override"] != "business hours" {
+		t.Fatalf("trigger context = %v", params.TriggerContext)
+	}
+
+	h.Decode(h.Do(http.MethodDelete, windowsPath+"/"+created.ID, nil, adminToken), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodDelete, windowsPath+"/"+created.ID, nil, adminToken), http.StatusNotFound, nil)
+	h.Decode(h.Do(http.MethodPost, run, nil, editorToken), http.StatusAccepted, nil)
+}
+
+func TestRunGroup(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	jobs := h.Store.Jobs()
+	var defIDs []string
+	for _, name := range []string{"customers", "orders"} {
+		def, err := jobs.CrateDefinition(models.JobDefinition{
+			TenantID: tenant.ID,
+			Name:     name,
+			JobType:  models.JobTypeEngine,
+			AST:      []byte(`{"migrate":{}}`),
+			Status:   "READY",
+		})
+		if err != nil {
+			t.Fatalf("create definition: %v", err)
+		}
+		defIDs = append(defIDs, def.ID)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/run-group", map[string]interface{}{
+		"job_definition_ids": []string{defIDs[0], defIDs[0]},
+	}, token), http.StatusBadRequest, nil)
+
+	var group models.RunGroup
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/run-group", map[string]interface{}{
+		"name":               "month end",
+		"job_definition_ids": defIDs,
+		"parallelism":        5,
+		"stop_on_failure":    true,
+	}, token), http.StatusAccepted, &group)
+	if group.Parallelism != 2 || group.Status != models.RunGroupStatusRunning || group.Progress.Pending != 2 {
+		t.Fatalf("group = %+v", group)
+	}
+	started := h.Temporal.Started()
+	if len(started) != 1 || started[0].Options.ID != temporal.RunGroupWorkflowIDPrefix+group.ID {
+		t.Fatalf("started = %+v", started)
+	}
+	params := started[0].Args[0].(temporal.RunGroupParams)
+	if len(params.Members) != 2 || !params.StopOnFailure {
+		t.Fatalf("params = %+v", params)
+	}
+	first := params.Members[0]
+	if first.TriggeredBy != models.TriggerPipeline || first.TriggerContext["run_group_id"] != group.ID {
+		t.Fatalf("member params = %+v", first)
+	}
+
+	// The workflow records the first member's execution under its reserved ID.
+	if _, err := jobs.CreateExecution(tenant.ID, first.JobDefinitionID, first.ExecutionID, temporal.ExecWorkflowIDPrefix+first.ExecutionID, first.TriggeredBy, first.TriggerContext, false); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if err := jobs.SetExecutionComplete(tenant.ID, first.ExecutionID, models.ExecutionStatusSucceeded, 120, 4096); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+	groupPath := "/api/v1/jobs/run-groups/" + group.ID
+	h.Decode(h.Do(http.MethodGet, groupPath, nil, token), http.StatusOK, &group)
+	if group.Progress.Succeeded != 1 || group.Progress.Pending != 1 || group.Members[0].JobDefinitionName != "customers" {
+		t.Fatalf("progress = %+v", group)
+	}
+	h.Decode(h.Do(http.MethodGet, groupPath+"/report", nil, token), http.StatusConflict, nil)
+
+	if err := jobs.SkipRunGroupMembers(tenant.ID, group.ID, []string{params.Members[1].ExecutionID}); err != nil {
+		t.Fatalf("skip members: %v", err)
+	}
+	current, _ := jobs.GetRunGroup(tenant.ID, group.ID)
+	if err := jobs.CompleteRunGroup(tenant.ID, group.ID, models.NewRunGroupReport(current, time.Now())); err != nil {
+		t.Fatalf("complete group: %v", err)
+	}
+	var report models.RunGroupReport
+	h.Decode(h.Do(http.MethodGet, groupPath+"/report", nil, token), http.StatusOK, &report)
+	if report.Status != models.RunGroupStatusFailed || report.Progress.Skipped != 1 || report.RecordsProcessed != 120 {
+		t.Fatalf("report = %+v", report)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/run-groups/"+uuid.NewString(), nil, token), http.StatusNotFound, nil)
+}
+
+func TestCancelPendingExecutions(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	jobs := h.Store.Jobs()
+	running := startExecution(t, h, tenant.ID)
+	pending := func(defID string) models.JobExecution {
+		execID := uuid.NewString()
+		exec, err := jobs.CreateExecution(tenant.ID, defID, execID, temporal.ExecWorkflowIDPrefix+execID, models.TriggerUser, nil, true)
+		if err != nil {
+			t.Fatalf("create execution: %v", err)
+		}
+		return exec
+	}
+	first := pending(running.JobDefinitionID)
+	other := startExecution(t, h, tenant.ID)
+	second := pending(other.JobDefinitionID)
+
+	var resp struct {
+		Cancelled    int      `json:"cancelled"`
+		ExecutionIDs []string `json:"execution_ids"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/cancel-pending",
+		map[string]string{"job_definition_id": running.JobDefinitionID}, token), http.StatusOK, &resp)
+	if resp.Cancelled != 1 || resp.ExecutionIDs[0] != first.ID {
+		t.Fatalf("filtered cancel = %+v", resp)
+	}
+	if terminated := h.Temporal.Terminated(); len(terminated) != 1 || terminated[0] != temporal.ExecWorkflowIDPrefix+first.ID {
+		t.Fatalf("terminated = %v", terminated)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/cancel-pending", nil, token), http.StatusOK, &resp)
+	if resp.Cancelled != 1 || resp.ExecutionIDs[0] != second.ID {
+		t.Fatalf("cancel = %+v", resp)
+	}
+	for id, want := range map[string]models.ExecutionStatus{
+		first.ID:   models.ExecutionStatusCancelled,
+		second.ID:  models.ExecutionStatusCancelled,
+		running.ID: models.ExecutionStatusRunning,
+	} {
+		exec, err := jobs.GetExecution(tenant.ID, id)
+		if err != nil || exec.Status != want {
+			t.Fatalf("execution %s = %q, %v; want %q", id, exec.Status, err, want)
+		}
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/cancel-pending",
+		map[string]string{"job_definition_id": uuid.NewString()}, token), http.StatusNotFound, nil)
+}
+
+func TestGetExecutionProgress(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "viewer@acme.test", models.RoleViewer)
+	jobs := h.Store.Jobs()
+	def, err := jobs.CrateDefinition(models.JobDefinition{
+		TenantID: tenant.ID,
+		Name:     "Nightly copy",
+		JobType:  models.JobTypeEngine,
+		AST:      []byte(`{"migrate":{"tables":["users",{"name":"orders"}]}}`),
+		Status:   "READY",
+	})
+	if err != nil {
+		t.Fatalf("create definition: %v", err)
+	}
+	execID := uuid.NewString()
+	exec, err := jobs.CreateExecution(tenant.ID, def.ID, execID, temporal.ExecWorkflowIDPrefix+execID, models.TriggerUser, nil, false)
+	if err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if _, err := jobs.UpdateExecution(tenant.ID, exec.ID, "running", "", ""); err != nil {
+		t.Fatalf("start execution: %v", err)
+	}
+	if err := jobs.SaveExecutionCheckpoints(tenant.ID, exec.ID, []models.ExecutionCheckpoint{{Table: "orders", RowsProcessed: 10, Completed: true}}); err != nil {
+		t.Fatalf("save checkpoints: %v", err)
+	}
+	path := "/api/v1/executions/" + exec.ID + "/progress"
+
+	// Without an answer from the workflow, the recorded status decides the step.
+	var progress models.ExecutionProgress
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &progress)
+	if progress.Live || progress.Step != models.ProgressStepRun || progress.TablesTotal != 2 || progress.TablesCompleted != 1 || progress.Percent != 62 {
+		t.Fatalf("derived progress = %+v", progress)
+	}
+
+	h.Temporal.SetQueryResult(temporal.ExecWorkflowIDPrefix+exec.ID, temporal.ProgressQueryName,
+		models.ExecutionProgress{Step: models.ProgressStepPull, Percent: 15})
+	progress = models.ExecutionProgress{}
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &progress)
+	if !progress.Live || progress.Step != models.ProgressStepPull || progress.Percent != 15 || progress.Status != models.ExecutionStatusRunning {
+		t.Fatalf("live progress = %+v", progress)
+	}
+
+	if _, err := jobs.UpdateExecution(tenant.ID, exec.ID, "succeeded", "", ""); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+	progress = models.ExecutionProgress{}
+	h.Decode(h.Do(http.MethodGet, path, nil, token), http.StatusOK, &progress)
+	if progress.Live || progress.Step != models.ProgressStepComplete || progress.Percent != 100 {
+		t.Fatalf("finished progress = %+v", progress)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+uuid.NewString()+"/progress", nil, token), http.StatusNotFound, nil)
+}
+
+func TestExecutionTablesFromCompletionCallback(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	complete := "/api/v1/jobs/executions/" + exec.ID + "/complete"
+
+	h.Decode(h.Do(http.MethodPost, complete, map[string]interface{}{
+		"status": models.ExecutionStatusFailed,
+		"tables": []map[string]interface{}{{"table": "orders", "status": "done"}},
+	}, token), http.StatusBadRequest, nil)
+
+	h.Decode(h.Do(http.MethodPost, complete, map[string]interface{}{
+		"status":            models.ExecutionStatusFailed,
+		"records_processed": 120,
+		"tables": []map[string]interface{}{
+			{"table": "users", "status": models.ExecutionTableSucceeded, "rows_copied": 120},
+			{"table": "orders", "status": models.ExecutionTableFailed, "error": "duplicate key value"},
+		},
+	}, token), http.StatusNoContent, nil)
+
+	var tables []models.JobExecutionTable
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+exec.ID+"/tables", nil, token), http.StatusOK, &tables)
+	if len(tables) != 2 {
+		t.Fatalf("tables = %+v", tables)
+	}
+	orders, users := tables[0], tables[1]
+	if orders.Table != "orders" || orders.Status != models.ExecutionTableFailed || orders.Error == nil || *orders.Error != "duplicate key value" {
+		t.Fatalf("orders = %+v", orders)
+	}
+	if users.Table != "users" || users.Status != models.ExecutionTableSucceeded || users.RowsCopied != 120 || users.Error != nil {
+		t.Fatalf("users = %+v", users)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+uuid.NewString()+"/tables", nil, token), http.StatusNotFound, nil)
+}
+
+func TestExecutionLogsFilteredByLevel(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "viewer@acme.test", models.RoleViewer)
+	exec := startExecution(t, h, tenant.ID)
+	logs := "\x1b[2m2024-05-01T10:00:00Z\x1b[0m \x1b[34mDEBUG\x1b[0m stratum::plan: reading schema\n" +
+		"2024-05-01T10:00:01Z  INFO stratum::copy: copying users\n" +
+		"2024-05-01T10:00:02Z  \x1b[33mWARN\x1b[0m stratum::copy: orders has no primary key\n" +
+		"2024-05-01T10:00:03Z \x1b[31mERROR\x1b[0m stratum::copy: duplicate key value\n" +
+		"    at orders (id)\n"
+	if _, err := h.Store.Jobs().UpdateExecution(tenant.ID, exec.ID, models.ExecutionStatusFailed, "copy failed", logs); err != nil {
+		t.Fatalf("complete execution: %v", err)
+	}
+
+	var all models.ExecutionLogs
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+exec.ID+"/logs", nil, token), http.StatusOK, &all)
+	if len(all.Chunks) != 4 || all.Counts[models.LogLevelError] != 2 || all.Counts[models.LogLevelDebug] != 1 {
+		t.Fatalf("logs = %+v", all)
+	}
+	for _, chunk := range all.Chunks {
+		if strings.Contains(chunk.Content, "\x1b") {
+			t.Fatalf("chunk %d kept ANSI escapes: %q", chunk.Seq, chunk.Content)
+		}
+	}
+
+	var problems models.ExecutionLogs
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+exec.ID+"/logs?level=warn", nil, token), http.StatusOK, &problems)
+	if len(problems.Chunks) != 2 || problems.Chunks[0].Level != models.LogLevelWarn || problems.Chunks[1].Level != models.LogLevelError {
+		t.Fatalf("warnings and errors = %+v", problems)
+	}
+	if want := "2024-05-01T10:00:03Z ERROR stratum::copy: duplicate key value\n    at orders (id)"; problems.Chunks[1].Content != want {
+		t.Fatalf("error chunk = %q, want %q", problems.Chunks[1].Content, want)
+	}
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+exec.ID+"/logs?level=loud", nil, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+uuid.NewString()+"/logs", nil, token), http.StatusNotFound, nil)
+}
+
+func TestExecutionEventsTimeline(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	exec := startExecution(t, h, tenant.ID)
+	jobs := h.Store.Jobs()
+	for _, event := range []models.ExecutionEvent{
+		{Type: models.ExecutionEventCreated, OccurredAt: exec.CreatedAt},
+		{Type: models.ExecutionEventStarted, Details: map[string]interface{}{"container_id": "c1"}},
+	} {
+		if err := jobs.RecordExecutionEvent(tenant.ID, exec.ID, event); err != nil {
+			t.Fatalf("record %s: %v", event.Type, err)
+		}
+	}
+	if err := jobs.RecordExecutionEvent(uuid.NewString(), exec.ID, models.ExecutionEvent{Type: models.ExecutionEventQueued}); err == nil {
+		t.Fatal("recorded an event under another tenant")
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+exec.ID+"/complete", map[string]interface{}{
+		"status": models.ExecutionStatusSucceeded,
+	}, token), http.StatusNoContent, nil)
+
+	var events []models.ExecutionEvent
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+exec.ID+"/events", nil, token), http.StatusOK, &events)
+	if len(events) != 3 {
+		t.Fatalf("events = %+v", events)
+	}
+	types := []string{events[0].Type, events[1].Type, events[2].Type}
+	if types[0] != models.ExecutionEventCreated || types[1] != models.ExecutionEventStarted || types[2] != models.ExecutionEventCallbackReceived {
+		t.Fatalf("event order = %v", types)
+	}
+	if events[1].Details["container_id"] != "c1" || events[2].Details["status"] != string(models.ExecutionStatusSucceeded) {
+		t.Fatalf("event details = %+v, %+v", events[1].Details, events[2].Details)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+uuid.NewString()+"/events", nil, token), http.StatusNotFound, nil)
+}
+
+func TestJobTokenReportsCheckpoints(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "viewer@acme.test", models.RoleViewer)
+	exec := startExecution(t, h, tenant.ID)
+	other := startExecution(t, h, tenant.ID)
+	jobToken := h.JobToken(tenant.ID, exec.ID)
+	checkpoints := map[string]interface{}{
+		"checkpoints": []map[string]interface{}{{"table": "orders", "last_key": map[string]int{"id": 5000}, "rows_processed": 5000}},
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+exec.ID+"/checkpoints", checkpoints, jobToken), http.StatusNoContent, nil)
+	var saved []models.ExecutionCheckpoint
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs/executions/"+exec.ID+"/checkpoints", nil, jobToken), http.StatusOK, &saved)
+	if len(saved) != 1 || saved[0].Table != "orders" || saved[0].RowsProcessed != 5000 || string(saved[0].LastKey) != `{"id":5000}` {
+		t.Fatalf("checkpoints = %+v", saved)
+	}
+
+	// The token reaches neither another execution nor the rest of the API.
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+other.ID+"/checkpoints", checkpoints, jobToken), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/executions/"+exec.ID+"/logs", nil, jobToken), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/jobs", nil, jobToken), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+exec.ID+"/checkpoints", checkpoints, token), http.StatusForbidden, nil)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/jobs/executions/"+exec.ID+"/complete", map[string]interface{}{
+		"status": models.ExecutionStatusSucceeded,
+	}, jobToken), http.StatusNoContent, nil)
+}
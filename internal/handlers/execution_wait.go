@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	// maxExecutionWait caps how long GetExecution holds a request open.
+	maxExecutionWait = 60 * time.Second
+	// executionWaitPoll is how often a waiting request re-reads the execution.
+	executionWaitPoll = 250 * time.Millisecond
+)
+
+// parseExecutionWait reads the long-poll parameters of GetExecution: ?wait, a
+// duration such as 30s, and ?after_status, the status the caller last saw, which
+// defaults to the execution's current status. A zero wait means no long poll.
+func parseExecutionWait(w http.ResponseWriter, r *http.Request) (time.Duration, models.ExecutionStatus, bool) {
+	query := r.URL.Query()
+	after := models.ExecutionStatus(query.Get("after_status"))
+	if after != "" && !models.IsValidExecutionStatus(after) {
+		http.Error(w, fmt.Sprintf("unknown after_status %q", after), http.StatusBadRequest)
+		return 0, "", false
+	}
+	raw := query.Get("wait")
+	if raw == "" {
+		return 0, after, true
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil || wait < 0 || wait > maxExecutionWait {
+		http.Error(w, fmt.Sprintf("wait must be a duration of at most %s", maxExecutionWait), http.StatusBadRequest)
+		return 0, "", false
+	}
+	return wait, after, true
+}
+
+// awaitExecutionChange re-reads the execution until its status is no longer after
+// or the wait elapses, and returns the latest copy. Orchestrators use it instead of
+// polling GetExecution in a loop.
+func (h *JobHandler) awaitExecutionChange(r *http.Request, execution models.JobExecution, after models.ExecutionStatus, wait time.Duration) (models.JobExecution, error) {
+	if after == "" {
+		after = execution.Status
+	}
+	if wait == 0 || execution.Status != after {
+		return execution, nil
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(executionWaitPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return execution, nil
+		case <-deadline.C:
+			return execution, nil
+		case <-ticker.C:
+			latest, err := h.repo.GetExecution(execution.TenantID, execution.ID)
+			if err != nil {
+				return execution, err
+			}
+			execution = latest
+			if execution.Status != after {
+				return execution, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// grafanaPerDefinitionSuffix splits a metric into one series per job definition.
+const grafanaPerDefinitionSuffix = ".by_definition"
+
+type grafanaMetric struct {
+	value func(p models.ExecutionSeriesPoint) (float64, bool)
+	// weight is set for averages, which are combined weighted by the number of runs
+	// they cover instead of summed.
+	weight func(p models.ExecutionSeriesPoint) float64
+}
+
+var grafanaMetrics = map[string]grafanaMetric{
+	"executions.total": {value: func(p models.ExecutionSeriesPoint) (float64, bool) {
+		return float64(p.Total), true
+	}},
+	"executions.succeeded": {value: func(p models.ExecutionSeriesPoint) (float64, bool) {
+		return float64(p.Succeeded), true
+	}},
+	"executions.failed": {value: func(p models.ExecutionSeriesPoint) (float64, bool) {
+		return float64(p.Failed), true
+	}},
+	"executions.avg_duration_seconds": {
+		value: func(p models.ExecutionSeriesPoint) (float64, bool) {
+			if p.AvgDurationSeconds == nil {
+				return 0, false
+			}
+			return *p.AvgDurationSeconds, true
+		},
+		// Runs that are pending, running or failed before starting have no duration.
+		weight: func(p models.ExecutionSeriesPoint) float64 { return float64(p.TimedRuns) },
+	},
+	"executions.bytes_transferred": {value: func(p models.ExecutionSeriesPoint) (float64, bool) {
+		return float64(p.BytesTransferred), true
+	}},
+}
+
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int64 `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaHandler serves the Grafana JSON datasource protocol on top of execution stats.
+type GrafanaHandler struct {
+	repo   repository.JobRepository
+	logger zerolog.Logger
+}
+
+func NewGrafanaHandler(repo repository.JobRepository, logger zerolog.Logger) *GrafanaHandler {
+	return &GrafanaHandler{
+		repo:   repo,
+		logger: logger.With().Str("handler", "grafana").Logger(),
+	}
+}
+
+// TestDatasource answers Grafana's "Save & test" probe.
+func (h *GrafanaHandler) TestDatasource(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *GrafanaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Target string `json:"target"`
+	}
+	if err := decodeAllowEmpty(r, &payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	filter := strings.ToLower(strings.TrimSpace(payload.Target))
+
+	targets := make([]string, 0, len(grafanaMetrics)*2)
+	for name := range grafanaMetrics {
+		for _, target := range []string{name, name + grafanaPerDefinitionSuffix} {
+			if filter == "" || strings.Contains(target, filter) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	sort.Strings(targets)
+	writeJSON(w, http.StatusOK, targets)
+}
+
+func (h *GrafanaHandler) Query(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	to := req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.Range.From
+	if from.IsZero() || !from.Before(to) {
+		from = to.Add(-24 * time.Hour)
+	}
+
+	bucket := time.Duration(req.IntervalMs) * time.Millisecond
+	if req.MaxDataPoints > 0 {
+		if minBucket := to.Sub(from) / time.Duration(req.MaxDataPoints); bucket < minBucket {
+			bucket = minBucket
+		}
+	}
+	if bucket < time.Minute {
+		bucket = time.Minute
+	}
+
+	points, err := h.repo.ListExecutionSeries(tid, from, to, bucket)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to load execution series")
+		http.Error(w, "Failed to query execution stats", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]grafanaSeries, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		name := strings.TrimSuffix(target.Target, grafanaPerDefinitionSuffix)
+		metric, ok := grafanaMetrics[name]
+		if !ok {
+			http.Error(w, "Unknown target: "+target.Target, http.StatusBadRequest)
+			return
+		}
+		response = append(response, buildGrafanaSeries(target.Target, metric, name != target.Target, points)...)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func buildGrafanaSeries(target string, metric grafanaMetric, perDefinition bool, points []models.ExecutionSeriesPoint) []grafanaSeries {
+	type accumulator struct {
+		sum    float64
+		weight float64
+	}
+	grouped := make(map[string]map[int64]*accumulator)
+	var order []string
+
+	for _, point := range points {
+		value, ok := metric.value(point)
+		if !ok {
+			continue
+		}
+		series := target
+		if perDefinition {
+			series = target + " " + point.JobDefinitionName
+		}
+		buckets, exists := grouped[series]
+		if !exists {
+			buckets = make(map[int64]*accumulator)
+			grouped[series] = buckets
+			order = append(order, series)
+		}
+		ts := point.Bucket.UnixMilli()
+		acc, exists := buckets[ts]
+		if !exists {
+			acc = &accumulator{}
+			buckets[ts] = acc
+		}
+		if metric.weight != nil {
+			weight := metric.weight(point)
+			acc.sum += value * weight
+			acc.weight += weight
+		} else {
+			acc.sum += value
+		}
+	}
+
+	if len(order) == 0 {
+		return []grafanaSeries{{Target: target, Datapoints: [][2]float64{}}}
+	}
+
+	sort.Strings(order)
+	result := make([]grafanaSeries, 0, len(order))
+	for _, name := range order {
+		buckets := grouped[name]
+		timestamps := make([]int64, 0, len(buckets))
+		for ts := range buckets {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+		datapoints := make([][2]float64, 0, len(timestamps))
+		for _, ts := range timestamps {
+			acc := buckets[ts]
+			value := acc.sum
+			if metric.weight != nil && acc.weight > 0 {
+				value = acc.sum / acc.weight
+			}
+			datapoints = append(datapoints, [2]float64{value, float64(ts)})
+		}
+		result = append(result, grafanaSeries{Target: name, Datapoints: datapoints})
+	}
+	return result
+}
@@ -8,7 +8,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -18,7 +17,9 @@ import (
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/passwords"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/utils"
 )
 
 const defaultInviteTTL = 7 * 24 * time.Hour
@@ -27,9 +28,11 @@ type InviteHandler struct {
 	inviteRepo repository.InviteRepository
 	tenantRepo repository.TenantRepository
 	userRepo   repository.UserRepository
+	audit      repository.AuditRepository
+	webhooks   repository.WebhookRepository
 	tokenTTL   time.Duration
-	mailer     notification.InviteMailer
-	urlTpl     string
+	delivery   *notification.InviteDelivery
+	passwords  *passwords.Policy
 	logger     zerolog.Logger
 }
 
@@ -43,20 +46,21 @@ func NewInviteHandler(
 	inviteRepo repository.InviteRepository,
 	tenantRepo repository.TenantRepository,
 	userRepo repository.UserRepository,
-	mailer notification.InviteMailer,
-	inviteURLTemplate string,
+	audit repository.AuditRepository,
+	webhooks repository.WebhookRepository,
+	delivery *notification.InviteDelivery,
+	passwords *passwords.Policy,
 	logger zerolog.Logger,
 ) *InviteHandler {
-	if inviteURLTemplate == "" {
-		inviteURLTemplate = "https://app.stratum.dev/invite/accept?token=%s"
-	}
 	return &InviteHandler{
 		inviteRepo: inviteRepo,
 		tenantRepo: tenantRepo,
 		userRepo:   userRepo,
+		audit:      audit,
+		webhooks:   webhooks,
 		tokenTTL:   defaultInviteTTL,
-		mailer:     mailer,
-		urlTpl:     inviteURLTemplate,
+		delivery:   delivery,
+		passwords:  passwords,
 		logger:     logger,
 	}
 }
@@ -146,82 +150,118 @@ func (h *InviteHandler) processInviteCreation(w http.ResponseWriter, tenant mode
 		return
 	}
 
-	roles := make([]models.UserRole, 0, len(payload.Roles))
-	if len(payload.Roles) == 0 {
-		roles = append(roles, models.RoleViewer)
-	} else {
-		for _, roleStr := range payload.Roles {
-			role := models.UserRole(strings.ToLower(strings.TrimSpace(roleStr)))
-			roles = append(roles, role)
-		}
+	roles, err := parseInviteRoles(payload.Roles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	roles = models.NormalizeRoles(roles)
-	if !models.IsValidRoleList(roles) {
-		http.Error(w, "invalid roles", http.StatusBadRequest)
+	ttl, err := h.inviteTTL(payload.ExpiresInHours)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	ttl := h.tokenTTL
-	if payload.ExpiresInHours != nil {
-		dur := *payload.ExpiresInHours
-		if dur <= 0 || dur > 24*30 {
-			http.Error(w, "expires_in_hours must be between 1 and 720", http.StatusBadRequest)
-			return
-		}
-		ttl = time.Duration(dur) * time.Hour
+	if h.delivery == nil {
+		http.Error(w, "email sender not configured", http.StatusInternalServerError)
+		return
 	}
 
-	expiresAt := time.Now().Add(ttl)
-	token, err := generateInviteToken()
+	invite, token, err := h.newInvite(tenant.ID, email, roles, ttl, createdBy)
 	if err != nil {
-		http.Error(w, "failed to generate invite token", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	tokenHash := hashInviteToken(token)
-
-	invite, err := h.inviteRepo.CreateInvite(models.Invite{
-		TenantID:  tenant.ID,
-		Email:     email,
-		Roles:     roles,
-		TokenHash: tokenHash,
-		ExpiresAt: expiresAt,
-		CreatedBy: createdBy,
-	})
+	invite, err = h.inviteRepo.CreateInvite(invite)
 	if err != nil {
 		http.Error(w, "failed to create invite: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if h.mailer == nil {
-		http.Error(w, "email sender not configured", http.StatusInternalServerError)
-		return
+	// A failed send leaves the email queued for retries; the invite stands either way.
+	invite = h.delivery.Send(invite, tenant.Name, token)
+	writeJSON(w, http.StatusCreated, newInviteTokenResponse(invite, token))
+}
+
+// parseInviteRoles normalizes the requested roles; no roles means viewer.
+func parseInviteRoles(raw []string) ([]models.UserRole, error) {
+	roles := make([]models.UserRole, 0, len(raw))
+	if len(raw) == 0 {
+		roles = append(roles, models.RoleViewer)
+	} else {
+		for _, roleStr := range raw {
+			role := models.UserRole(strings.ToLower(strings.TrimSpace(roleStr)))
+			roles = append(roles, role)
+		}
 	}
+	roles = models.NormalizeRoles(roles)
+	if !models.IsValidRoleList(roles) {
+		return nil, errors.New("invalid roles")
+	}
+	return roles, nil
+}
 
-	inviteURL := fmt.Sprintf(h.urlTpl, token)
-	if err := h.mailer.SendInvite(invite.Email, tenant.Name, inviteURL); err != nil {
-		http.Error(w, "failed to send invite email: "+err.Error(), http.StatusInternalServerError)
-		return
+// inviteTTL returns how long a new invite stays valid.
+func (h *InviteHandler) inviteTTL(expiresInHours *int) (time.Duration, error) {
+	if expiresInHours == nil {
+		return h.tokenTTL, nil
 	}
+	dur := *expiresInHours
+	if dur <= 0 || dur > 24*30 {
+		return 0, errors.New("expires_in_hours must be between 1 and 720")
+	}
+	return time.Duration(dur) * time.Hour, nil
+}
 
-	response := struct {
-		ID        string            `json:"id"`
-		TenantID  string            `json:"tenant_id"`
-		Email     string            `json:"email"`
-		Roles     []models.UserRole `json:"roles"`
-		Token     string            `json:"token"`
-		ExpiresAt time.Time         `json:"expires_at"`
-	}{
-		ID:        invite.ID,
-		TenantID:  invite.TenantID,
-		Email:     invite.Email,
-		Roles:     invite.Roles,
-		Token:     token,
-		ExpiresAt: invite.ExpiresAt,
+// newInvite builds an unsaved invite with a fresh token and its email queued for
+// the first retry. It returns the raw token alongside.
+func (h *InviteHandler) newInvite(tenantID, email string, roles []models.UserRole, ttl time.Duration, createdBy *string) (models.Invite, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return models.Invite{}, "", errors.New("failed to generate invite token")
 	}
+	sealedToken, err := utils.EncryptSecret(token)
+	if err != nil {
+		return models.Invite{}, "", errors.New("failed to seal invite token: " + err.Error())
+	}
+	nextDeliveryAt := h.delivery.FirstRetryAt()
+	return models.Invite{
+		TenantID:       tenantID,
+		Email:          email,
+		Roles:          roles,
+		TokenHash:      hashToken(token),
+		ExpiresAt:      time.Now().Add(ttl),
+		CreatedBy:      createdBy,
+		DeliveryToken:  sealedToken,
+		NextDeliveryAt: &nextDeliveryAt,
+	}, token, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+// inviteTokenResponse is returned when an invite's token is issued, so admins can
+// share the link themselves while the email is still undelivered.
+type inviteTokenResponse struct {
+	ID             string            `json:"id"`
+	TenantID       string            `json:"tenant_id"`
+	Email          string            `json:"email"`
+	Roles          []models.UserRole `json:"roles"`
+	Token          string            `json:"token"`
+	ExpiresAt      time.Time         `json:"expires_at"`
+	DeliveryStatus string            `json:"delivery_status"`
+	DeliveryError  *string           `json:"delivery_error,omitempty"`
+	NextDeliveryAt *time.Time        `json:"next_delivery_at,omitempty"`
+}
+
+func newInviteTokenResponse(invite models.Invite, token string) inviteTokenResponse {
+	return inviteTokenResponse{
+		ID:             invite.ID,
+		TenantID:       invite.TenantID,
+		Email:          invite.Email,
+		Roles:          invite.Roles,
+		Token:          token,
+		ExpiresAt:      invite.ExpiresAt,
+		DeliveryStatus: invite.DeliveryStatus,
+		DeliveryError:  invite.DeliveryError,
+		NextDeliveryAt: invite.NextDeliveryAt,
+	}
 }
 
 func (h *InviteHandler) PreviewInvite(w http.ResponseWriter, r *http.Request) {
@@ -231,7 +271,7 @@ func (h *InviteHandler) PreviewInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	invite, err := h.inviteRepo.GetInviteByTokenHash(hashInviteToken(token))
+	invite, err := h.inviteRepo.GetInviteByTokenHash(hashToken(token))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "invite not found", http.StatusNotFound)
@@ -295,7 +335,7 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	invite, err := h.inviteRepo.GetInviteByTokenHash(hashInviteToken(token))
+	invite, err := h.inviteRepo.GetInviteByTokenHash(hashToken(token))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "invite not found", http.StatusNotFound)
@@ -314,6 +354,11 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var (
+		userID        string
+		previousRoles []models.UserRole
+		created       bool
+	)
 	existingUser, err := h.userRepo.GetUserByEmail(invite.Email)
 	switch {
 	case err == nil:
@@ -330,6 +375,8 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "failed to update user roles: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.markEmailVerified(existingUser.ID)
+		userID, previousRoles = existingUser.ID, existingUser.Roles
 	case errors.Is(err, sql.ErrNoRows):
 		password := strings.TrimSpace(payload.Password)
 		firstName := strings.TrimSpace(payload.FirstName)
@@ -338,10 +385,17 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "password is required", http.StatusBadRequest)
 			return
 		}
-		if _, err := h.userRepo.CreateUser(invite.TenantID, invite.Email, password, firstName, lastName, invite.Roles); err != nil {
+		if !checkPassword(w, r, h.passwords, password) {
+			return
+		}
+		user, err := h.userRepo.CreateUser(invite.TenantID, invite.Email, password, firstName, lastName, invite.Roles)
+		if err != nil {
 			http.Error(w, "failed to create user: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.markEmailVerified(user.ID)
+		userID = user.ID
+		created = true
 	default:
 		http.Error(w, "failed to load user: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -355,6 +409,27 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to finalize invite: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	details := map[string]interface{}{"invite_id": invite.ID, "email": invite.Email, "roles": invite.Roles}
+	if previousRoles != nil {
+		details["previous_roles"] = previousRoles
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &invite.TenantID,
+		ActorID:    &userID,
+		Action:     models.AuditInviteAccepted,
+		TargetType: "user",
+		TargetID:   userID,
+		Details:    details,
+	})
+	if created {
+		emitWebhook(h.webhooks, h.logger, invite.TenantID, models.WebhookCategoryUser, models.WebhookEventUserCreated,
+			map[string]interface{}{"user_id": userID, "email": invite.Email, "roles": invite.Roles})
+	}
+	data := map[string]interface{}{"invite_id": invite.ID, "user_id": userID, "email": invite.Email, "roles": invite.Roles}
+	if previousRoles != nil {
+		data["previous_roles"] = previousRoles
+	}
+	emitWebhook(h.webhooks, h.logger, invite.TenantID, models.WebhookCategoryUser, models.WebhookEventInviteAccepted, data)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -383,25 +458,40 @@ func (h *InviteHandler) ListCurrentInvites(w http.ResponseWriter, r *http.Reques
 	}
 
 	type inviteInfo struct {
-		ID         string            `json:"id"`
-		Email      string            `json:"email"`
-		Roles      []models.UserRole `json:"roles"`
-		ExpiresAt  time.Time         `json:"expires_at"`
-		AcceptedAt *time.Time        `json:"accepted_at,omitempty"`
-		CreatedAt  time.Time         `json:"created_at"`
-		CreatedBy  *string           `json:"created_by,omitempty"`
-	}
-
+		ID               string            `json:"id"`
+		Email            string            `json:"email"`
+		Roles            []models.UserRole `json:"roles"`
+		ExpiresAt        time.Time         `json:"expires_at"`
+		AcceptedAt       *time.Time        `json:"accepted_at,omitempty"`
+		CreatedAt        time.Time         `json:"created_at"`
+		CreatedBy        *string           `json:"created_by,omitempty"`
+		DeliveryStatus   string            `json:"delivery_status"`
+		DeliveryAttempts int               `json:"delivery_attempts"`
+		DeliveryError    *string           `json:"delivery_error,omitempty"`
+		NextDeliveryAt   *time.Time        `json:"next_delivery_at,omitempty"`
+		DeliveredAt      *time.Time        `json:"delivered_at,omitempty"`
+	}
+
+	// ?delivery_status=failed lists the invites whose email never arrived.
+	deliveryStatus := strings.TrimSpace(r.URL.Query().Get("delivery_status"))
 	response := make([]inviteInfo, 0, len(invites))
 	for _, inv := range invites {
+		if deliveryStatus != "" && inv.DeliveryStatus != deliveryStatus {
+			continue
+		}
 		response = append(response, inviteInfo{
-			ID:         inv.ID,
-			Email:      inv.Email,
-			Roles:      inv.Roles,
-			ExpiresAt:  inv.ExpiresAt,
-			AcceptedAt: inv.AcceptedAt,
-			CreatedAt:  inv.CreatedAt,
-			CreatedBy:  inv.CreatedBy,
+			ID:               inv.ID,
+			Email:            inv.Email,
+			Roles:            inv.Roles,
+			ExpiresAt:        inv.ExpiresAt,
+			AcceptedAt:       inv.AcceptedAt,
+			CreatedAt:        inv.CreatedAt,
+			CreatedBy:        inv.CreatedBy,
+			DeliveryStatus:   inv.DeliveryStatus,
+			DeliveryAttempts: inv.DeliveryAttempts,
+			DeliveryError:    inv.DeliveryError,
+			NextDeliveryAt:   inv.NextDeliveryAt,
+			DeliveredAt:      inv.DeliveredAt,
 		})
 	}
 
@@ -437,7 +527,77 @@ func (h *InviteHandler) CancelCurrentInvite(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func generateInviteToken() (string, error) {
+// ResendCurrentInvite issues a new token for an open invite and sends its email
+// again with a fresh retry budget. The old link stops working.
+func (h *InviteHandler) ResendCurrentInvite(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok || tenantID == "" {
+		http.Error(w, "tenant context missing", http.StatusForbidden)
+		return
+	}
+	if h.delivery == nil {
+		http.Error(w, "email sender not configured", http.StatusInternalServerError)
+		return
+	}
+
+	inviteID := mux.Vars(r)["inviteID"]
+	invite, err := h.inviteRepo.GetInvite(inviteID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invite not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to load invite: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if invite.IsUsed() {
+		http.Error(w, "invite already accepted", http.StatusConflict)
+		return
+	}
+	if invite.IsExpired(time.Now()) {
+		http.Error(w, "invite expired", http.StatusGone)
+		return
+	}
+
+	tenant, err := h.tenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		http.Error(w, "failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		http.Error(w, "failed to generate invite token", http.StatusInternalServerError)
+		return
+	}
+	sealedToken, err := utils.EncryptSecret(token)
+	if err != nil {
+		http.Error(w, "failed to seal invite token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	invite, err = h.inviteRepo.RequeueInviteDelivery(invite.ID, tenantID, hashToken(token), sealedToken, h.delivery.FirstRetryAt())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invite no longer valid", http.StatusGone)
+			return
+		}
+		http.Error(w, "failed to queue invite email: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	invite = h.delivery.Send(invite, tenant.Name, token)
+	writeJSON(w, http.StatusOK, newInviteTokenResponse(invite, token))
+}
+
+// markEmailVerified records email ownership for a user who redeemed an invite; the
+// invite link was delivered to that address, which is proof enough.
+func (h *InviteHandler) markEmailVerified(userID string) {
+	if err := h.userRepo.MarkEmailVerified(userID); err != nil {
+		h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to mark invited user's email as verified")
+	}
+}
+
+func generateToken() (string, error) {
 	buf := make([]byte, 32)
 	if _, err := rand.Read(buf); err != nil {
 		return "", err
@@ -445,7 +605,7 @@ func generateInviteToken() (string, error) {
 	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
-func hashInviteToken(token string) string {
+func hashToken(token string) string {
 	sum := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(sum[:])
 }
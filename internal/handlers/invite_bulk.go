@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// maxBulkInvites caps the recipients of one bulk invite request.
+const maxBulkInvites = 200
+
+// Per-recipient outcomes of a bulk invite.
+const (
+	bulkInviteCreated   = "created"
+	bulkInviteDuplicate = "duplicate"
+	bulkInviteInvalid   = "invalid"
+	bulkInviteMember    = "existing_user"
+)
+
+type bulkInviteRequest struct {
+	Emails         []string `json:"emails"`
+	Roles          []string `json:"roles"`
+	ExpiresInHours *int     `json:"expires_in_hours"`
+}
+
+// bulkInviteResult reports what happened to one recipient. Token and the delivery
+// fields are only set for created invites.
+type bulkInviteResult struct {
+	Email          string            `json:"email"`
+	Status         string            `json:"status"`
+	Reason         string            `json:"reason,omitempty"`
+	InviteID       string            `json:"invite_id,omitempty"`
+	Roles          []models.UserRole `json:"roles,omitempty"`
+	Token          string            `json:"token,omitempty"`
+	DeliveryStatus string            `json:"delivery_status,omitempty"`
+	DeliveryError  *string           `json:"delivery_error,omitempty"`
+}
+
+type bulkInviteResponse struct {
+	Total      int                `json:"total"`
+	Created    int                `json:"created"`
+	Duplicates int                `json:"duplicates"`
+	Invalid    int                `json:"invalid"`
+	Members    int                `json:"existing_users"`
+	Results    []bulkInviteResult `json:"results"`
+}
+
+// CreateBulkInvites invites every listed address to the current tenant with the
+// same roles. Invalid addresses, repeats, addresses with a pending invite and
+// existing users are reported and skipped; the rest are created together or not
+// at all, then their emails are sent one by one.
+func (h *InviteHandler) CreateBulkInvites(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok || tenantID == "" {
+		http.Error(w, "tenant context missing", http.StatusForbidden)
+		return
+	}
+
+	var payload bulkInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Emails) == 0 {
+		http.Error(w, "emails are required", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Emails) > maxBulkInvites {
+		http.Error(w, fmt.Sprintf("at most %d emails can be invited at once", maxBulkInvites), http.StatusBadRequest)
+		return
+	}
+	roles, err := parseInviteRoles(payload.Roles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl, err := h.inviteTTL(payload.ExpiresInHours)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.delivery == nil {
+		http.Error(w, "email sender not configured", http.StatusInternalServerError)
+		return
+	}
+
+	tenant, err := h.tenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pending, err := h.pendingInviteEmails(tenantID)
+	if err != nil {
+		http.Error(w, "failed to list invites: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var createdBy *string
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		createdBy = &uid
+	}
+
+	results := make([]bulkInviteResult, len(payload.Emails))
+	seen := make(map[string]bool, len(payload.Emails))
+	var (
+		invites []models.Invite
+		tokens  []string
+		slots   []int // results index of each invite
+	)
+	for i, raw := range payload.Emails {
+		email := strings.TrimSpace(strings.ToLower(raw))
+		results[i] = bulkInviteResult{Email: email}
+		res := &results[i]
+		switch {
+		case !validInviteEmail(email):
+			res.Email, res.Status, res.Reason = strings.TrimSpace(raw), bulkInviteInvalid, "not a valid email address"
+			continue
+		case seen[email]:
+			res.Status, res.Reason = bulkInviteDuplicate, "listed more than once"
+			continue
+		case pending[email]:
+			res.Status, res.Reason = bulkInviteDuplicate, "already has a pending invite"
+			seen[email] = true
+			continue
+		}
+		seen[email] = true
+
+		user, err := h.userRepo.GetUserByEmail(email)
+		switch {
+		case err == nil && user.TenantID == tenantID:
+			res.Status, res.Reason = bulkInviteMember, "already a member of this tenant"
+			continue
+		case err == nil:
+			res.Status, res.Reason = bulkInviteMember, "belongs to a different tenant"
+			continue
+		case !errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "failed to load user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		invite, token, err := h.newInvite(tenantID, email, roles, ttl, createdBy)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		invites = append(invites, invite)
+		tokens = append(tokens, token)
+		slots = append(slots, i)
+	}
+
+	if len(invites) > 0 {
+		created, err := h.inviteRepo.CreateInvites(invites)
+		if err != nil {
+			http.Error(w, "failed to create invites: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Emails go out only once every invite is stored. A failed send leaves
+		// that recipient's email queued for retries.
+		for j, invite := range created {
+			invite = h.delivery.Send(invite, tenant.Name, tokens[j])
+			res := &results[slots[j]]
+			res.Status = bulkInviteCreated
+			res.InviteID = invite.ID
+			res.Roles = invite.Roles
+			res.Token = tokens[j]
+			res.DeliveryStatus = invite.DeliveryStatus
+			res.DeliveryError = invite.DeliveryError
+		}
+	}
+
+	resp := bulkInviteResponse{Total: len(results), Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case bulkInviteCreated:
+			resp.Created++
+		case bulkInviteDuplicate:
+			resp.Duplicates++
+		case bulkInviteInvalid:
+			resp.Invalid++
+		case bulkInviteMember:
+			resp.Members++
+		}
+	}
+	h.logger.Info().Str("tenant_id", tenantID).Int("created", resp.Created).Int("skipped", resp.Total-resp.Created).Msg("bulk invites created")
+
+	status := http.StatusCreated
+	if resp.Created == 0 {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, resp)
+}
+
+// pendingInviteEmails returns the addresses with an open invite to the tenant.
+func (h *InviteHandler) pendingInviteEmails(tenantID string) (map[string]bool, error) {
+	invites, err := h.inviteRepo.ListInvitesByTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pending := make(map[string]bool, len(invites))
+	for _, inv := range invites {
+		if !inv.IsUsed() && !inv.IsExpired(now) {
+			pending[strings.ToLower(inv.Email)] = true
+		}
+	}
+	return pending, nil
+}
+
+// validInviteEmail accepts a bare address such as "ana@example.com", without a
+// display name or angle brackets.
+func validInviteEmail(email string) bool {
+	if email == "" {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@")+1:], ".")
+}
@@ -0,0 +1,301 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+type inviteToken struct {
+	ID             string `json:"id"`
+	Email          string `json:"email"`
+	Token          string `json:"token"`
+	DeliveryStatus string `json:"delivery_status"`
+}
+
+func TestInviteAcceptFlow(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+
+	var invite inviteToken
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites", map[string]interface{}{
+		"email": "New.Hire@acme.test",
+		"roles": []string{"editor"},
+	}, admin), http.StatusCreated, &invite)
+	if invite.Email != "new.hire@acme.test" || invite.Token == "" || invite.DeliveryStatus != "sent" {
+		t.Fatalf("invite = %+v", invite)
+	}
+	sent := h.Mailer.Invites()
+	if len(sent) != 1 || sent[0].Email != invite.Email || sent[0].URL != "http://localhost/invites/"+invite.Token {
+		t.Fatalf("sent invites = %+v", sent)
+	}
+
+	var preview struct {
+		Email      string            `json:"email"`
+		TenantName string            `json:"tenant_name"`
+		Roles      []models.UserRole `json:"roles"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/invites/"+invite.Token, nil, ""), http.StatusOK, &preview)
+	if preview.TenantName != tenant.Name || preview.Email != invite.Email {
+		t.Fatalf("preview = %+v", preview)
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/invites/"+invite.Token+"/accept", map[string]string{
+		"password":   "Str0ng-Passw0rd!",
+		"first_name": "New",
+		"last_name":  "Hire",
+	}, ""), http.StatusNoContent, nil)
+
+	user, err := h.Store.Users().GetUserByEmail(invite.Email)
+	if err != nil {
+		t.Fatalf("accepted user not found: %v", err)
+	}
+	if user.TenantID != tenant.ID || !models.HasAtLeast(user.Roles, models.RoleEditor) || user.EmailVerifiedAt == nil {
+		t.Fatalf("accepted user = %+v", user)
+	}
+
+	// The token is single use.
+	h.Decode(h.Do(http.MethodPost, "/api/v1/invites/"+invite.Token+"/accept", map[string]string{"password": "Str0ng-Passw0rd!"}, ""), http.StatusConflict, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/invites/"+invite.Token, nil, ""), http.StatusConflict, nil)
+
+	var accepted bool
+	for _, event := range h.Store.AuditEvents() {
+		if event.Action == models.AuditInviteAccepted && event.TargetID == user.ID {
+			accepted = true
+		}
+	}
+	if !accepted {
+		t.Fatal("invite acceptance was not audited")
+	}
+}
+
+func TestEraseUserScrubsAuditAndWebhooks(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	var sub models.WebhookSubscription
+	h.Decode(h.Do(http.MethodPost, "/api/v1/webhooks/user", map[string]interface{}{
+		"url": "https://hooks.acme.test/stratum",
+	}, admin), http.StatusCreated, &sub)
+
+	const email = "leaver@acme.test"
+	var invite inviteToken
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites", map[string]interface{}{"email": email, "roles": []string{"viewer"}}, admin), http.StatusCreated, &invite)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/invites/"+invite.Token+"/accept", map[string]string{"password": "Str0ng-Passw0rd!"}, ""), http.StatusNoContent, nil)
+	user, err := h.Store.Users().GetUserByEmail(email)
+	if err != nil {
+		t.Fatalf("accepted user not found: %v", err)
+	}
+
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/users/"+user.ID+"?erase=true", map[string]string{"confirm": email}, admin), http.StatusNoContent, nil)
+
+	for _, event := range h.Store.AuditEvents() {
+		if raw, _ := json.Marshal(event.Details); strings.Contains(string(raw), email) {
+			t.Fatalf("audit event %s still has the erased email: %s", event.Action, raw)
+		}
+	}
+	var deliveries []models.WebhookDelivery
+	h.Decode(h.Do(http.MethodGet, "/api/v1/webhooks/user/"+sub.ID+"/deliveries", nil, admin), http.StatusOK, &deliveries)
+	if len(deliveries) == 0 {
+		t.Fatal("no user webhook was delivered")
+	}
+	for _, d := range deliveries {
+		if strings.Contains(string(d.Payload), email) {
+			t.Fatalf("%s delivery still has the erased email: %s", d.EventType, d.Payload)
+		}
+	}
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, admin, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	// Accounts that predate email verification are migrated as verified.
+	if err := h.Store.Users().MarkEmailVerified(admin.ID); err != nil {
+		t.Fatalf("mark verified: %v", err)
+	}
+	newcomer, err := h.Store.Users().CreateUser(tenant.ID, "new@acme.test", "Password123!", "New", "User", []models.UserRole{models.RoleViewer})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	h.Decode(h.Do(http.MethodPatch, "/api/v1/tenants/"+tenant.ID, map[string]bool{"require_verified_email": true}, token), http.StatusOK, nil)
+
+	login := func(email string) *httptest.ResponseRecorder {
+		return h.Do(http.MethodPost, "/api/v1/login", map[string]string{"email": email, "password": "Password123!"}, "")
+	}
+	h.Decode(login(admin.Email), http.StatusOK, nil)
+	h.Decode(login(newcomer.Email), http.StatusForbidden, nil)
+}
+
+func TestSetupOnFreshlyMigratedInstance(t *testing.T) {
+	h := testutil.NewHarness(t)
+	h.SeedInitialData()
+
+	var status struct {
+		SetupRequired bool `json:"setup_required"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/setup", nil, ""), http.StatusOK, &status)
+	if !status.SetupRequired {
+		t.Fatal("setup is locked on an instance holding only the seed data")
+	}
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/setup", map[string]string{
+		"instance_name": "Acme Stratum",
+		"tenant_name":   "Acme",
+		"email":         "owner@acme.test",
+		"password":      "Password123!",
+	}, ""), http.StatusCreated, nil)
+
+	login := func(email, password string) int {
+		return h.Do(http.MethodPost, "/api/v1/login", map[string]string{"email": email, "password": password}, "").Code
+	}
+	if code := login("owner@acme.test", "Password123!"); code != http.StatusOK {
+		t.Fatalf("super-admin login = %d, want 200", code)
+	}
+	if code := login("admin@example.com", "Qwerty123!"); code == http.StatusOK {
+		t.Fatal("the seeded admin can still log in after setup")
+	}
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/setup", nil, ""), http.StatusOK, &status)
+	if status.SetupRequired {
+		t.Fatal("setup is still open after it completed")
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/setup", map[string]string{
+		"instance_name": "Again", "tenant_name": "Again", "email": "again@acme.test", "password": "Password123!",
+	}, ""), http.StatusConflict, nil)
+
+	// An instance in use stays locked even without a setup record.
+	used := testutil.NewHarness(t)
+	used.SeedInitialData()
+	used.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+	used.Decode(used.Do(http.MethodGet, "/api/v1/setup", nil, ""), http.StatusOK, &status)
+	if status.SetupRequired {
+		t.Fatal("setup is open on an instance that has tenants of its own")
+	}
+}
+
+func TestSignupWithTenantIDFollowsDomainPolicy(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, _ := h.SeedTenant("Acme", "admin@acme.com", models.RoleAdmin)
+	domains := h.Store.EmailDomains()
+	domain, err := domains.CreateDomain(models.EmailDomain{TenantID: tenant.ID, Domain: "acme.com", VerificationToken: "token"})
+	if err != nil {
+		t.Fatalf("create domain: %v", err)
+	}
+	if _, err := domains.MarkDomainVerified(tenant.ID, domain.ID); err != nil {
+		t.Fatalf("verify domain: %v", err)
+	}
+	if _, err := domains.SetDomainApproval(tenant.ID, domain.ID, true); err != nil {
+		t.Fatalf("require approval: %v", err)
+	}
+
+	// Naming the tenant does not skip the approval its verified domain requires.
+	var joined struct {
+		TenantID   string `json:"tenant_id"`
+		JoinStatus string `json:"join_status"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/signup", map[string]string{
+		"tenant_id": tenant.ID,
+		"email":     "ops@acme.com",
+		"password":  "Str0ng-Passw0rd!",
+	}, ""), http.StatusCreated, &joined)
+	if joined.TenantID != tenant.ID || joined.JoinStatus != "pending_approval" {
+		t.Fatalf("signup = %+v, want a join pending approval", joined)
+	}
+	user, err := h.Store.Users().GetUserByEmail("ops@acme.com")
+	if err != nil {
+		t.Fatalf("signed-up user not found: %v", err)
+	}
+	if user.IsActive {
+		t.Fatal("user joined without approval")
+	}
+	pending, err := domains.ListJoinRequests(tenant.ID, models.JoinRequestPending)
+	if err != nil || len(pending) != 1 || pending[0].UserID != user.ID {
+		t.Fatalf("pending join requests = %+v, %v", pending, err)
+	}
+}
+
+func TestInviteListAndCancel(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+
+	var invite inviteToken
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites", map[string]interface{}{"email": "guest@acme.test"}, admin), http.StatusCreated, &invite)
+
+	var listed []struct {
+		ID    string            `json:"id"`
+		Roles []models.UserRole `json:"roles"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/users/invites", nil, admin), http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].ID != invite.ID || len(listed[0].Roles) != 1 || listed[0].Roles[0] != models.RoleViewer {
+		t.Fatalf("listed = %+v", listed)
+	}
+
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/users/invites/"+invite.ID, nil, admin), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/users/invites", nil, admin), http.StatusOK, &listed)
+	if len(listed) != 0 {
+		t.Fatalf("cancelled invite still listed: %+v", listed)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/invites/"+invite.Token, nil, ""), http.StatusNotFound, nil)
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/users/invites/"+invite.ID, nil, admin), http.StatusNotFound, nil)
+}
+
+func TestInviteRequiresAdmin(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, editor := h.SeedTenant("Acme", "editor@acme.test", models.RoleEditor)
+
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites", map[string]interface{}{"email": "guest@acme.test"}, editor), http.StatusForbidden, nil)
+	if sent := h.Mailer.Invites(); len(sent) != 0 {
+		t.Fatalf("sent %d invites without permission", len(sent))
+	}
+}
+
+func TestBulkInvites(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites", map[string]interface{}{"email": "pending@acme.test"}, admin), http.StatusCreated, nil)
+
+	var resp struct {
+		Total      int `json:"total"`
+		Created    int `json:"created"`
+		Duplicates int `json:"duplicates"`
+		Invalid    int `json:"invalid"`
+		Members    int `json:"existing_users"`
+		Results    []struct {
+			Email  string `json:"email"`
+			Status string `json:"status"`
+			Token  string `json:"token"`
+		} `json:"results"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites/bulk", map[string]interface{}{
+		"emails": []string{"one@acme.test", "Two@acme.test", "one@acme.test", "not-an-email", "pending@acme.test", "admin@acme.test"},
+		"roles":  []string{"editor"},
+	}, admin), http.StatusCreated, &resp)
+
+	if resp.Total != 6 || resp.Created != 2 || resp.Duplicates != 2 || resp.Invalid != 1 || resp.Members != 1 {
+		t.Fatalf("summary = %+v", resp)
+	}
+	want := []string{"created", "created", "duplicate", "invalid", "duplicate", "existing_user"}
+	for i, res := range resp.Results {
+		if res.Status != want[i] {
+			t.Fatalf("result %d (%s) = %q, want %q", i, res.Email, res.Status, want[i])
+		}
+		if (res.Status == "created") != (res.Token != "") {
+			t.Fatalf("result %d token = %q", i, res.Token)
+		}
+	}
+	if sent := h.Mailer.Invites(); len(sent) != 3 {
+		t.Fatalf("sent %d invite emails, want 3", len(sent))
+	}
+
+	// Nothing left to create: the summary comes back without creating anything.
+	h.Decode(h.Do(http.MethodPost, "/api/v1/users/invites/bulk", map[string]interface{}{
+		"emails": []string{"one@acme.test"},
+	}, admin), http.StatusOK, &resp)
+	if resp.Created != 0 || resp.Duplicates != 1 {
+		t.Fatalf("repeat summary = %+v", resp)
+	}
+}
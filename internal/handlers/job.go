@@ -10,47 +10,78 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/engine"
 	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/notification"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/scanning"
+	"github.com/stanstork/stratum-api/internal/storage"
 	"github.com/stanstork/stratum-api/internal/temporal"
 	"github.com/stanstork/stratum-api/internal/temporal/workflows"
+	"github.com/stanstork/stratum-api/internal/utils"
 
 	tc "go.temporal.io/sdk/client"
 )
 
+// maxStatsMonths bounds how many months of history the stats endpoints return.
+const maxStatsMonths = 120
+
 type JobHandler struct {
 	repo           repository.JobRepository
+	connRepo       repository.ConnectionRepository
+	tenants        repository.TenantRepository
+	templates      repository.TemplateRepository
+	audit          repository.AuditRepository
+	engines        repository.EngineRepository
 	temporalClient tc.Client
 	notifier       notification.Service
+	residency      *storage.Residency
+	hosts          *engine.HostPool
+	credentials    *temporal.CredentialVault
+	scanner        scanning.Scanner
+	containerName  string
+	reachability   *reachabilityCache
 	logger         zerolog.Logger
 }
 
 type createDefinitionPayload struct {
-	Name                    string          `json:"name"`
-	Description             string          `json:"description"`
-	AST                     json.RawMessage `json:"ast"`
-	SourceConnectionID      string          `json:"source_connection_id"`
-	DestinationConnectionID string          `json:"destination_connection_id"`
-	ProgressSnapshot        json.RawMessage `json:"progress_snapshot"`
-	Status                  string          `json:"status"`
+	Name                    string              `json:"name"`
+	Description             string              `json:"description"`
+	JobType                 string              `json:"job_type"`
+	AST                     json.RawMessage     `json:"ast"`
+	SourceConnectionID      string              `json:"source_connection_id"`
+	DestinationConnectionID string              `json:"destination_connection_id"`
+	SourceDSN               string              `json:"source_dsn"`
+	DestinationDSN          string              `json:"destination_dsn"`
+	ProgressSnapshot        json.RawMessage     `json:"progress_snapshot"`
+	Status                  string              `json:"status"`
+	WriteMode               string              `json:"write_mode"`
+	RetryPolicy             *models.RetryPolicy `json:"retry_policy"`
+	Verification            string              `json:"verification"`
 }
 
 type updateDefinitionPayload struct {
-	Name                    *string          `json:"name"`
-	Description             *string          `json:"description"`
-	AST                     *json.RawMessage `json:"ast"`
-	SourceConnectionID      *string          `json:"source_connection_id"`
-	DestinationConnectionID *string          `json:"destination_connection_id"`
-	ProgressSnapshot        *json.RawMessage `json:"progress_snapshot"`
-	Status                  *string          `json:"status"`
+	Name                    *string             `json:"name"`
+	Description             *string             `json:"description"`
+	AST                     *json.RawMessage    `json:"ast"`
+	SourceConnectionID      *string             `json:"source_connection_id"`
+	DestinationConnectionID *string             `json:"destination_connection_id"`
+	ProgressSnapshot        *json.RawMessage    `json:"progress_snapshot"`
+	Status                  *string             `json:"status"`
+	WriteMode               *string             `json:"write_mode"`
+	RetryPolicy             *models.RetryPolicy `json:"retry_policy"`
+	Verification            *string             `json:"verification"`
 }
 
+// hasChanges reports whether the payload edits what the definition runs. The retry
+// policy and verification only affect how runs are checked and retried, so
+// changing them keeps a READY definition ready.
 func (p updateDefinitionPayload) hasChanges() bool {
 	return p.Name != nil ||
 		p.Description != nil ||
@@ -58,23 +89,42 @@ func (p updateDefinitionPayload) hasChanges() bool {
 		p.SourceConnectionID != nil ||
 		p.DestinationConnectionID != nil ||
 		p.ProgressSnapshot != nil ||
-		p.Status != nil
+		p.Status != nil ||
+		p.WriteMode != nil
+}
+
+// editsRunInputs reports whether the payload edits what an in-progress execution
+// runs: the AST or a connection.
+func (p updateDefinitionPayload) editsRunInputs() bool {
+	return p.AST != nil || p.SourceConnectionID != nil || p.DestinationConnectionID != nil
 }
 
 type resolvedDefinition struct {
 	Name                    string
 	Description             string
+	JobType                 string
 	AST                     json.RawMessage
 	SourceConnectionID      string
 	DestinationConnectionID string
 	ProgressSnapshot        json.RawMessage
 }
 
-func NewJobHandler(repo repository.JobRepository, temporalClient tc.Client, notifier notification.Service, logger zerolog.Logger) *JobHandler {
+func NewJobHandler(repo repository.JobRepository, connRepo repository.ConnectionRepository, tenants repository.TenantRepository, templates repository.TemplateRepository, audit repository.AuditRepository, engines repository.EngineRepository, temporalClient tc.Client, notifier notification.Service, residency *storage.Residency, hosts *engine.HostPool, credentials *temporal.CredentialVault, scanner scanning.Scanner, containerName string, logger zerolog.Logger) *JobHandler {
 	return &JobHandler{
 		repo:           repo,
+		connRepo:       connRepo,
+		tenants:        tenants,
+		templates:      templates,
+		audit:          audit,
+		engines:        engines,
 		temporalClient: temporalClient,
 		notifier:       notifier,
+		residency:      residency,
+		hosts:          hosts,
+		credentials:    credentials,
+		scanner:        scanner,
+		containerName:  containerName,
+		reachability:   newReachabilityCache(),
 		logger:         logger,
 	}
 }
@@ -100,41 +150,116 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	h.createDefinition(w, tid, payload)
+}
 
+// createDefinition stores a new definition from a create payload, READY unless the
+// payload asks for another status, and writes the response.
+func (h *JobHandler) createDefinition(w http.ResponseWriter, tid string, payload createDefinitionPayload) {
 	name := strings.TrimSpace(payload.Name)
 	if name == "" {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
-	status := strings.ToUpper(strings.TrimSpace(payload.Status))
+	discardInline, ok := h.applyInlineDSNs(w, tid, &payload)
+	if !ok {
+		return
+	}
+	stored := false
+	defer func() {
+		if !stored {
+			discardInline()
+		}
+	}()
+	if !h.rejectReadOnlyDestination(w, tid, payload.DestinationConnectionID) {
+		return
+	}
+	jobType, ok := parseJobType(w, payload.JobType)
+	if !ok {
+		return
+	}
+	writeMode, ok := parseWriteMode(w, payload.WriteMode)
+	if !ok {
+		return
+	}
+	if !validRetryPolicy(w, payload.RetryPolicy) {
+		return
+	}
+	verification, ok := parseVerification(w, payload.Verification)
+	if !ok {
+		return
+	}
+	secrets, ok := extractSecrets(w, &payload.AST, &payload.ProgressSnapshot)
+	if !ok {
+		return
+	}
+	status := models.DefinitionStatus(strings.ToUpper(strings.TrimSpace(payload.Status)))
 	if status == "" {
-		status = "READY"
+		status = models.DefinitionStatusReady
 	}
-	if status == "READY" {
+	if status == models.DefinitionStatusArchived {
+		http.Error(w, "Job definitions are archived with POST /jobs/{jobID}/archive", http.StatusBadRequest)
+		return
+	}
+	if status == models.DefinitionStatusReady {
 		if len(payload.AST) == 0 {
 			http.Error(w, "AST is required when status is READY", http.StatusBadRequest)
 			return
 		}
-		if strings.TrimSpace(payload.SourceConnectionID) == "" || strings.TrimSpace(payload.DestinationConnectionID) == "" {
+		if jobType == models.JobTypeSQLScript {
+			errs := validateSQLScript(resolvedDefinition{
+				AST:                     payload.AST,
+				SourceConnectionID:      payload.SourceConnectionID,
+				DestinationConnectionID: payload.DestinationConnectionID,
+			})
+			if len(errs) > 0 {
+				http.Error(w, "Invalid SQL script: "+strings.Join(errs, "; "), http.StatusBadRequest)
+				return
+			}
+		} else if strings.TrimSpace(payload.SourceConnectionID) == "" || strings.TrimSpace(payload.DestinationConnectionID) == "" {
 			http.Error(w, "Source and destination connections are required when status is READY", http.StatusBadRequest)
 			return
+		} else if _, err := models.TableWriteModes(payload.AST); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(payload.SourceConnectionID) != "" && !h.enforceEnvironmentPolicy(w, tid, payload.SourceConnectionID, payload.DestinationConnectionID) {
+			return
+		}
+		incompatible, err := h.engineCompatibilityError(tid, jobType, payload.SourceConnectionID, payload.DestinationConnectionID)
+		if err != nil {
+			http.Error(w, "Failed to check engine compatibility: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if incompatible != "" {
+			http.Error(w, incompatible, http.StatusBadRequest)
+			return
 		}
 	}
 	definition := models.JobDefinition{
 		TenantID:                tid,
 		Name:                    name,
 		Description:             payload.Description,
+		JobType:                 jobType,
 		AST:                     payload.AST,
 		SourceConnectionID:      strings.TrimSpace(payload.SourceConnectionID),
 		DestinationConnectionID: strings.TrimSpace(payload.DestinationConnectionID),
 		Status:                  status,
 		ProgressSnapshot:        cloneRawMessage(payload.ProgressSnapshot),
+		WriteMode:               writeMode,
+		RetryPolicy:             payload.RetryPolicy,
+		Verification:            verification,
 	}
 	createdDef, err := h.repo.CrateDefinition(definition)
 	if err != nil {
 		http.Error(w, "Failed to create job definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	stored = true
+	if err := h.repo.SetDefinitionSecrets(tid, createdDef.ID, secrets); err != nil {
+		http.Error(w, "Failed to store definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	writeJSON(w, http.StatusCreated, createdDef)
 }
 
@@ -154,21 +279,62 @@ func (h *JobHandler) CreateDraft(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
+	discardInline, ok := h.applyInlineDSNs(w, tid, &payload)
+	if !ok {
+		return
+	}
+	stored := false
+	defer func() {
+		if !stored {
+			discardInline()
+		}
+	}()
+	if !h.rejectReadOnlyDestination(w, tid, payload.DestinationConnectionID) {
+		return
+	}
+	jobType, ok := parseJobType(w, payload.JobType)
+	if !ok {
+		return
+	}
+	writeMode, ok := parseWriteMode(w, payload.WriteMode)
+	if !ok {
+		return
+	}
+	if !validRetryPolicy(w, payload.RetryPolicy) {
+		return
+	}
+	verification, ok := parseVerification(w, payload.Verification)
+	if !ok {
+		return
+	}
+	secrets, ok := extractSecrets(w, &payload.AST, &payload.ProgressSnapshot)
+	if !ok {
+		return
+	}
 	definition := models.JobDefinition{
 		TenantID:                tid,
 		Name:                    name,
 		Description:             payload.Description,
+		JobType:                 jobType,
 		AST:                     payload.AST,
 		SourceConnectionID:      strings.TrimSpace(payload.SourceConnectionID),
 		DestinationConnectionID: strings.TrimSpace(payload.DestinationConnectionID),
-		Status:                  "DRAFT",
+		Status:                  models.DefinitionStatusDraft,
 		ProgressSnapshot:        cloneRawMessage(payload.ProgressSnapshot),
+		WriteMode:               writeMode,
+		RetryPolicy:             payload.RetryPolicy,
+		Verification:            verification,
 	}
 	createdDef, err := h.repo.CrateDefinition(definition)
 	if err != nil {
 		http.Error(w, "Failed to create draft job definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	stored = true
+	if err := h.repo.SetDefinitionSecrets(tid, createdDef.ID, secrets); err != nil {
+		http.Error(w, "Failed to store definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	writeJSON(w, http.StatusCreated, createdDef)
 }
 
@@ -178,12 +344,34 @@ func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
+	view, err := newDefinitionView(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if version, err := h.repo.DefinitionsVersion(tid); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to compute job definitions version")
+	} else if notModified(w, r, view.versionTag(version)) {
+		return
+	}
 	definitions, err := h.repo.ListDefinitions(tid)
 	if err != nil {
 		http.Error(w, "Failed to list job definitions: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, definitions)
+	resp := make([]map[string]json.RawMessage, 0, len(definitions))
+	for _, def := range definitions {
+		if !view.lists(def) {
+			continue
+		}
+		shaped, err := view.render(def)
+		if err != nil {
+			http.Error(w, "Failed to encode job definitions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp = append(resp, shaped)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (h *JobHandler) AutosaveJob(w http.ResponseWriter, r *http.Request) {
@@ -199,6 +387,10 @@ func (h *JobHandler) AutosaveJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	secrets, ok := extractSecrets(w, payload.AST, payload.ProgressSnapshot)
+	if !ok {
+		return
+	}
 
 	currentDef, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
 	if err != nil {
@@ -209,6 +401,12 @@ func (h *JobHandler) AutosaveJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !rejectArchived(w, currentDef) {
+		return
+	}
+	if payload.editsRunInputs() && !h.checkDefinitionLock(w, r, tid, jobDefID) {
+		return
+	}
 
 	update := repository.DefinitionUpdate{}
 
@@ -234,18 +432,45 @@ func (h *JobHandler) AutosaveJob(w http.ResponseWriter, r *http.Request) {
 	}
 	if payload.DestinationConnectionID != nil {
 		dst := strings.TrimSpace(*payload.DestinationConnectionID)
+		if !h.rejectReadOnlyDestination(w, tid, dst) {
+			return
+		}
 		update.DestinationConnectionID = &dst
 	}
 	if payload.ProgressSnapshot != nil {
 		snapshot := cloneRawMessage(*payload.ProgressSnapshot)
 		update.ProgressSnapshot = &snapshot
 	}
+	if payload.WriteMode != nil {
+		writeMode, ok := parseWriteMode(w, *payload.WriteMode)
+		if !ok {
+			return
+		}
+		update.WriteMode = &writeMode
+	}
+	if payload.RetryPolicy != nil {
+		if !validRetryPolicy(w, payload.RetryPolicy) {
+			return
+		}
+		update.RetryPolicy = payload.RetryPolicy
+	}
+	if payload.Verification != nil {
+		verification, ok := parseVerification(w, *payload.Verification)
+		if !ok {
+			return
+		}
+		update.Verification = &verification
+	}
 
 	if payload.Status != nil {
-		status := strings.ToUpper(strings.TrimSpace(*payload.Status))
+		status := models.DefinitionStatus(strings.ToUpper(strings.TrimSpace(*payload.Status)))
+		if status == models.DefinitionStatusArchived {
+			http.Error(w, "Job definitions are archived with POST /jobs/{jobID}/archive", http.StatusBadRequest)
+			return
+		}
 		update.Status = &status
-	} else if currentDef.Status == "READY" && payload.hasChanges() {
-		status := "DRAFT"
+	} else if currentDef.Status == models.DefinitionStatusReady && payload.hasChanges() {
+		status := models.DefinitionStatusDraft
 		update.Status = &status
 	}
 
@@ -258,6 +483,23 @@ func (h *JobHandler) AutosaveJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to save definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.repo.SetDefinitionSecrets(tid, jobDefID, secrets); err != nil {
+		http.Error(w, "Failed to store definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Connections attached by this save are probed for reachability. The result is
+	// advisory and never fails the save.
+	if payload.SourceConnectionID != nil && updatedDef.SourceConnection.ID != "" {
+		if msg := h.reachability.connectionWarning("Source", updatedDef.SourceConnection); msg != "" {
+			updatedDef.Warnings = append(updatedDef.Warnings, msg)
+		}
+	}
+	if payload.DestinationConnectionID != nil && updatedDef.DestinationConnection.ID != "" {
+		if msg := h.reachability.connectionWarning("Destination", updatedDef.DestinationConnection); msg != "" {
+			updatedDef.Warnings = append(updatedDef.Warnings, msg)
+		}
+	}
 
 	writeJSON(w, http.StatusOK, updatedDef)
 }
@@ -275,6 +517,10 @@ func (h *JobHandler) ValidateJobDefinition(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	secrets, ok := extractSecrets(w, payload.AST, payload.ProgressSnapshot)
+	if !ok {
+		return
+	}
 
 	currentDef, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
 	if err != nil {
@@ -285,15 +531,35 @@ func (h *JobHandler) ValidateJobDefinition(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !rejectArchived(w, currentDef) {
+		return
+	}
 
 	resolved := resolveDefinition(payload, currentDef)
-	if errs := validateResolvedDefinition(resolved); len(errs) > 0 {
+	errs := validateResolvedDefinition(resolved)
+	missing, err := h.missingSecrets(tid, jobDefID, resolved.AST, secrets)
+	if err != nil {
+		http.Error(w, "Failed to check definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	incompatible, err := h.engineCompatibilityError(tid, resolved.JobType, resolved.SourceConnectionID, resolved.DestinationConnectionID)
+	if err != nil {
+		http.Error(w, "Failed to check engine compatibility: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if incompatible != "" {
+		errs = append(errs, incompatible)
+	}
+	if errs = append(errs, missing...); len(errs) > 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
 			"valid":  false,
 			"errors": errs,
 		})
 		return
 	}
+	if !h.rejectReadOnlyDestination(w, tid, resolved.DestinationConnectionID) {
+		return
+	}
 
 	update := repository.DefinitionUpdate{}
 	name := resolved.Name
@@ -306,7 +572,7 @@ func (h *JobHandler) ValidateJobDefinition(w http.ResponseWriter, r *http.Reques
 	update.SourceConnectionID = &src
 	dst := strings.TrimSpace(resolved.DestinationConnectionID)
 	update.DestinationConnectionID = &dst
-	status := "VALIDATING"
+	status := models.DefinitionStatusValidating
 	update.Status = &status
 	if payload.ProgressSnapshot != nil {
 		snapshot := cloneRawMessage(*payload.ProgressSnapshot)
@@ -322,6 +588,10 @@ func (h *JobHandler) ValidateJobDefinition(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Failed to validate definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.repo.SetDefinitionSecrets(tid, jobDefID, secrets); err != nil {
+		http.Error(w, "Failed to store definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"valid":      true,
@@ -342,6 +612,10 @@ func (h *JobHandler) MarkDefinitionReady(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	secrets, ok := extractSecrets(w, payload.AST, payload.ProgressSnapshot)
+	if !ok {
+		return
+	}
 
 	currentDef, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
 	if err != nil {
@@ -352,15 +626,38 @@ func (h *JobHandler) MarkDefinitionReady(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !rejectArchived(w, currentDef) {
+		return
+	}
 
 	resolved := resolveDefinition(payload, currentDef)
-	if errs := validateResolvedDefinition(resolved); len(errs) > 0 {
+	errs := validateResolvedDefinition(resolved)
+	missing, err := h.missingSecrets(tid, jobDefID, resolved.AST, secrets)
+	if err != nil {
+		http.Error(w, "Failed to check definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	incompatible, err := h.engineCompatibilityError(tid, resolved.JobType, resolved.SourceConnectionID, resolved.DestinationConnectionID)
+	if err != nil {
+		http.Error(w, "Failed to check engine compatibility: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if incompatible != "" {
+		errs = append(errs, incompatible)
+	}
+	if errs = append(errs, missing...); len(errs) > 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
 			"valid":  false,
 			"errors": errs,
 		})
 		return
 	}
+	if !h.rejectReadOnlyDestination(w, tid, resolved.DestinationConnectionID) {
+		return
+	}
+	if resolved.SourceConnectionID != "" && !h.enforceEnvironmentPolicy(w, tid, resolved.SourceConnectionID, resolved.DestinationConnectionID) {
+		return
+	}
 
 	update := repository.DefinitionUpdate{}
 	name := resolved.Name
@@ -373,7 +670,7 @@ func (h *JobHandler) MarkDefinitionReady(w http.ResponseWriter, r *http.Request)
 	update.SourceConnectionID = &src
 	dst := strings.TrimSpace(resolved.DestinationConnectionID)
 	update.DestinationConnectionID = &dst
-	status := "READY"
+	status := models.DefinitionStatusReady
 	update.Status = &status
 	if payload.ProgressSnapshot != nil {
 		snapshot := cloneRawMessage(*payload.ProgressSnapshot)
@@ -389,6 +686,10 @@ func (h *JobHandler) MarkDefinitionReady(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to mark definition ready: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.repo.SetDefinitionSecrets(tid, jobDefID, secrets); err != nil {
+		http.Error(w, "Failed to store definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	if h.notifier != nil {
 		if err := h.notifier.NotifyValidationComplete(r.Context(), tid, updatedDef.ID, updatedDef.Name); err != nil {
@@ -410,6 +711,11 @@ func (h *JobHandler) DelteJob(w http.ResponseWriter, r *http.Request) {
 	}
 	jobDefID := mux.Vars(r)["jobID"]
 
+	// The Temporal Schedule outlives the definition row, so it is removed first.
+	if err := h.removeSchedule(r.Context(), tid, jobDefID); err != nil && !isNotFound(err) {
+		http.Error(w, "Failed to delete job schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if err := h.repo.DeleteDefinition(tid, jobDefID); err != nil {
 		if isNotFound(err) {
 			http.Error(w, "Job definition not found", http.StatusNotFound)
@@ -421,6 +727,10 @@ func (h *JobHandler) DelteJob(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RunJob starts an execution of a definition. A request with an Idempotency-Key
+// header that an earlier request already used returns that request's execution
+// instead of starting another. ?priority=high runs it on the priority task queue;
+// ?timeout_seconds fails the engine run and stops its container once it takes longer.
 func (h *JobHandler) RunJob(w http.ResponseWriter, r *http.Request) {
 	tid, ok := authz.TenantIDFromRequest(r)
 	if !ok {
@@ -428,132 +738,938 @@ func (h *JobHandler) RunJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	jobDefID := mux.Vars(r)["jobID"]
-	execID := uuid.New().String()
-
-	// Set up the workflow options.
-	workflowOptions := tc.StartWorkflowOptions{
-		ID:        fmt.Sprintf("%s%s", temporal.ExecWorkflowIDPrefix, execID),
-		TaskQueue: temporal.TaskQueueName,
-	}
-
-	// Define the parameters for the workflow.
-	params := temporal.ExecutionParams{
-		TenantID:        tid,
-		ExecutionID:     execID,
-		JobDefinitionID: jobDefID,
-	}
-
-	// Execute the workflow. This call is asynchronous.
-	we, err := h.temporalClient.ExecuteWorkflow(context.Background(), workflowOptions, workflows.ExecutionWorkflow, params)
-	if err != nil {
-		http.Error(w, "Failed to start job execution workflow: "+err.Error(), http.StatusInternalServerError)
+	if !h.authorizeRun(w, r, tid, jobDefID) {
 		return
 	}
-
-	response := map[string]string{
-		"message":     "Job execution started.",
-		"executionID": execID,
-		"workflowID":  we.GetID(),
-		"runID":       we.GetRunID(),
-	}
-	writeJSON(w, http.StatusAccepted, response)
-}
-
-func (h *JobHandler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
-	tid, ok := authz.TenantIDFromRequest(r)
-	if !ok {
-		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, jobDefID) {
 		return
 	}
-	jobDefID := mux.Vars(r)["jobID"]
-	execution, err := h.repo.GetLastExecution(tid, jobDefID)
+	def, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
 	if err != nil {
 		if isNotFound(err) {
-			http.Error(w, "Job execution not found", http.StatusNotFound)
+			http.Error(w, "Job definition not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, "Failed to get job execution status: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !rejectArchived(w, def) {
 		return
 	}
-	writeJSON(w, http.StatusOK, execution)
-}
 
-func (h *JobHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
-	tid, ok := authz.TenantIDFromRequest(r)
-	if !ok {
-		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+	priority := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("priority")))
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+	if !models.ValidPriority(priority) {
+		http.Error(w, "priority must be "+models.PriorityNormal+" or "+models.PriorityHigh, http.StatusBadRequest)
 		return
 	}
-	// parse query params with defaults
-	limit := 20
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil {
-			limit = v
+	var timeoutSeconds int
+	if raw := r.URL.Query().Get("timeout_seconds"); raw != "" {
+		minSeconds, maxSeconds := int(models.MinMaxExecutionDuration.Seconds()), int(models.MaxMaxExecutionDuration.Seconds())
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < minSeconds || v > maxSeconds {
+			http.Error(w, fmt.Sprintf("timeout_seconds must be between %d and %d", minSeconds, maxSeconds), http.StatusBadRequest)
+			return
 		}
+		timeoutSeconds = v
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if v, err := strconv.Atoi(o); err == nil {
-			offset = v
+
+	executionID := uuid.New().String()
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key != "" {
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
+			return
+		}
+		executionID = idempotentExecutionID(tid, jobDefID, key)
+		existing, err := h.repo.GetExecution(tid, executionID)
+		if err == nil {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"message":     "Job execution already started for this idempotency key.",
+				"executionID": existing.ID,
+				"workflowID":  temporal.ExecWorkflowIDPrefix + existing.ID,
+				"status":      string(existing.Status),
+			})
+			return
+		}
+		if !isNotFound(err) {
+			http.Error(w, "Failed to look up idempotent execution: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
-	executions, err := h.repo.ListExecutions(tid, limit, offset)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var req runJobRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	writeJSON(w, http.StatusOK, executions)
-}
-
-func (h *JobHandler) GetExecutionStats(w http.ResponseWriter, r *http.Request) {
-	tid, ok := authz.TenantIDFromRequest(r)
+	if len(req.Overrides) > 0 && string(req.Overrides) != "null" {
+		if def.JobType == models.JobTypeSQLScript {
+			http.Error(w, "SQL script runs do not accept overrides", http.StatusBadRequest)
+			return
+		}
+		if err := models.ValidateRunOverrides(req.Overrides); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// A run grant covers varying the run's parameters, not editing what it migrates.
+		if paths := models.NonParameterOverrides(req.Overrides); len(paths) > 0 {
+			if roles, _ := authz.RolesFromRequest(r); !models.HasAtLeast(roles, models.RoleEditor) {
+				http.Error(w, "overriding "+strings.Join(paths, ", ")+" requires the editor role", http.StatusForbidden)
+				return
+			}
+		}
+		if !h.validateOverriddenDefinition(w, tid, def, req.Overrides) {
+			return
+		}
+	} else {
+		req.Overrides = nil
+	}
+	creds, ok := h.requestCredentials(w, tid, jobDefID, req.runCredentialsRequest)
 	if !ok {
-		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
-	days := 31 // default to 31 days
-	if d := r.URL.Query().Get("days"); d != "" {
-		if v, err := strconv.Atoi(d); err == nil {
-			days = v
-		}
+
+	triggeredBy, triggerContext := requestTrigger(r)
+	if key != "" {
+		triggerContext["idempotency_key"] = key
 	}
+	if priority == models.PriorityHigh {
+		triggerContext["priority"] = priority
+	}
+	params := temporal.ExecutionParams{
+		TenantID:        tid,
+		ExecutionID:     executionID,
+		JobDefinitionID: jobDefID,
+		JobType:         def.JobType,
+		SkipSensors:     r.URL.Query().Get("skip_sensors") == "true",
+		AllowConcurrent: r.URL.Query().Get("force") == "true",
+		Priority:        priority,
+		TimeoutSeconds:  timeoutSeconds,
+		Overrides:       req.Overrides,
+		TriggeredBy:     triggeredBy,
+		TriggerContext:  triggerContext,
+	}
+	h.startExecution(w, r, params, creds, "Job execution started.")
+}
 
-	stats, err := h.repo.ListExecutionStats(tid, days)
+// validateOverriddenDefinition checks the definition's AST with the run's overrides
+// merged in the way MarkDefinitionReady checks a definition, and writes a 400 with
+// the errors when it is not runnable.
+func (h *JobHandler) validateOverriddenDefinition(w http.ResponseWriter, tenantID string, def models.JobDefinition, overrides json.RawMessage) bool {
+	merged, err := models.ApplyRunOverrides(def.AST, overrides)
 	if err != nil {
-		http.Error(w, "Failed to get execution stats: "+err.Error(), http.StatusInternalServerError)
-		return
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	errs := validateResolvedDefinition(resolvedDefinition{
+		Name:                    def.Name,
+		JobType:                 def.JobType,
+		AST:                     merged,
+		SourceConnectionID:      def.SourceConnectionID,
+		DestinationConnectionID: def.DestinationConnectionID,
+	})
+	missing, err := h.missingSecrets(tenantID, def.ID, merged, nil)
+	if err != nil {
+		http.Error(w, "Failed to check definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return false
 	}
+	if errs = append(errs, missing...); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"valid":  false,
+			"errors": errs,
+		})
+		return false
+	}
+	return true
+}
 
-	writeJSON(w, http.StatusOK, stats)
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentExecutionID derives the execution ID of a run from its idempotency key,
+// so a repeated request maps to the same execution and, while the first run's
+// workflow is open, to the same workflow rather than a second one.
+func idempotentExecutionID(tenantID, jobDefID, key string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(tenantID+"/"+jobDefID+"/"+key)).String()
 }
 
-func (h *JobHandler) GetJobDefinition(w http.ResponseWriter, r *http.Request) {
-	tid, ok := authz.TenantIDFromRequest(r)
+// requestTrigger attributes a run started over the API to the API key the request
+// was authenticated with, or else to the signed-in user, whose ID the execution
+// records as triggered_by_user_id.
+func requestTrigger(r *http.Request) (string, map[string]string) {
+	if keyID, ok := authz.APIKeyIDFromRequest(r); ok {
+		return models.TriggerAPIKey, map[string]string{"api_key_id": keyID}
+	}
+	triggerContext := map[string]string{}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		triggerContext["user_id"] = uid
+	}
+	return models.TriggerUser, triggerContext
+}
+
+// startExecution launches the execution workflow and writes the 202 response.
+// Just-in-time credentials are handed to the worker through the in-memory vault,
+// never through the workflow input. A definition with an active execution is only
+// started again when params allow concurrent runs; the workflow enforces the same
+// rule when it records the execution. Tenants that require run approval get the
+// run held for approval instead. Runs inside a blackout window are refused with
+// 409 unless an admin overrides it, and runs beyond the tenant's start limits with
+// 429.
+func (h *JobHandler) startExecution(w http.ResponseWriter, r *http.Request, params temporal.ExecutionParams, creds temporal.RunCredentials, message string) {
+	warning, ok := h.enforceValidationFreshness(w, params.TenantID, params.JobDefinitionID)
 	if !ok {
-		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
-	jobDefID := mux.Vars(r)["jobID"]
-	definition, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
-	if err != nil {
-		if isNotFound(err) {
-			http.Error(w, "Job definition not found", http.StatusNotFound)
+	approval, ok := h.runApprovalRequired(w, params.TenantID)
+	if !ok {
+		return
+	}
+	if approval {
+		if err := h.holdForApproval(r, params); err != nil {
+			http.Error(w, "Failed to hold job execution for approval: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		http.Error(w, "Failed to get job definition: "+err.Error(), http.StatusInternalServerError)
+		response := map[string]string{
+			"message":     "Job execution awaits approval.",
+			"executionID": params.ExecutionID,
+			"status":      string(models.ExecutionStatusAwaitingApproval),
+		}
+		if warning != "" {
+			response["warning"] = warning
+		}
+		writeJSON(w, http.StatusAccepted, response)
 		return
 	}
-	writeJSON(w, http.StatusOK, definition)
-}
-
-func (h *JobHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
-	tid, ok := authz.TenantIDFromRequest(r)
+	overridden, ok := h.enforceBlackout(w, r, params.TenantID)
+	if !ok {
+		return
+	}
+	if overridden != "" {
+		params.TriggerContext["blackout_override"] = overridden
+	}
+	if !h.enforceStartLimit(w, params.TenantID) {
+		return
+	}
+	if !params.AllowConcurrent {
+		active, err := h.repo.GetActiveDefinitionExecution(params.TenantID, params.JobDefinitionID)
+		if err == nil {
+			http.Error(w, fmt.Sprintf("Job definition already has an active execution %s; pass force=true to start another", active.ID), http.StatusConflict)
+			return
+		}
+		if !isNotFound(err) {
+			http.Error(w, "Failed to check active executions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	we, err := h.launchExecution(r, params, creds)
+	if err != nil {
+		http.Error(w, "Failed to start job execution workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]string{
+		"message":     message,
+		"executionID": params.ExecutionID,
+		"workflowID":  we.GetID(),
+		"runID":       we.GetRunID(),
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// launchExecution hands the run's credentials to the vault, starts the execution
+// workflow and audits the trigger.
+func (h *JobHandler) launchExecution(r *http.Request, params temporal.ExecutionParams, creds temporal.RunCredentials) (tc.WorkflowRun, error) {
+	if len(creds) > 0 {
+		h.credentials.Put(params.ExecutionID, creds)
+	}
+
+	// Set up the workflow options.
+	workflowOptions := tc.StartWorkflowOptions{
+		ID:        fmt.Sprintf("%s%s", temporal.ExecWorkflowIDPrefix, params.ExecutionID),
+		TaskQueue: temporal.TaskQueueFor(params.Priority),
+	}
+
+	// Rollbacks are executions too, run by their own workflow.
+	var workflowFn interface{} = workflows.ExecutionWorkflow
+	if params.RollbackOfExecutionID != "" {
+		workflowFn = workflows.RollbackWorkflow
+	}
+
+	// Execute the workflow. This call is asynchronous.
+	we, err := h.temporalClient.ExecuteWorkflow(context.Background(), workflowOptions, workflowFn, params)
+	if err != nil {
+		h.credentials.Discard(params.ExecutionID)
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"job_definition_id": params.JobDefinitionID,
+		"triggered_by":      params.TriggeredBy,
+	}
+	for k, v := range params.TriggerContext {
+		details[k] = v
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &params.TenantID,
+		Action:     models.AuditExecutionTriggered,
+		TargetType: "execution",
+		TargetID:   params.ExecutionID,
+		Details:    details,
+	})
+	return we, nil
+}
+
+func (h *JobHandler) ResumeExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// A paused execution is resumed in place; a failed one restarts from its checkpoints.
+	if execution.Status == models.ExecutionStatusPaused {
+		h.signalPause(w, r, execution, false)
+		return
+	}
+	if execution.Status != models.ExecutionStatusFailed {
+		http.Error(w, "Only failed or paused executions can be resumed", http.StatusConflict)
+		return
+	}
+
+	checkpoints, err := h.repo.ListExecutionCheckpoints(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to load execution checkpoints: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(checkpoints) == 0 {
+		http.Error(w, "Execution has no checkpoints to resume from", http.StatusConflict)
+		return
+	}
+
+	if !h.rejectArchivedDefinition(w, tid, execution.JobDefinitionID) {
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, execution.JobDefinitionID) {
+		return
+	}
+
+	creds, ok := h.runCredentials(w, r, tid, execution.JobDefinitionID)
+	if !ok {
+		return
+	}
+
+	// The resumed run finishes what the failed one started, overrides included.
+	overrides, err := h.executionOverrides(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to load execution overrides: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, triggerContext := requestTrigger(r)
+	triggerContext["resumed_from_execution_id"] = execID
+	params := temporal.ExecutionParams{
+		TenantID:              tid,
+		ExecutionID:           uuid.New().String(),
+		JobDefinitionID:       execution.JobDefinitionID,
+		ResumeFromExecutionID: execID,
+		Overrides:             overrides,
+		SkipSensors:           true,
+		AllowConcurrent:       r.URL.Query().Get("force") == "true",
+		TriggeredBy:           models.TriggerRetry,
+		TriggerContext:        triggerContext,
+	}
+	h.startExecution(w, r, params, creds, "Job execution resumed from checkpoint.")
+}
+
+// executionOverrides returns the run overrides an execution was started with, as
+// recorded in its snapshot; nil when it had none or has no snapshot.
+func (h *JobHandler) executionOverrides(tenantID, execID string) (json.RawMessage, error) {
+	snapshot, err := h.repo.GetExecutionSnapshot(tenantID, execID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	overrides, ok := snapshot.Parameters["overrides"]
+	if !ok || overrides == nil {
+		return nil, nil
+	}
+	return json.Marshal(overrides)
+}
+
+// RerunExecution starts a new run of a finished execution's definition with the
+// exact AST that execution snapshotted, even if the definition was edited since.
+// Connections, secrets and the tenant's worker settings are the current ones.
+func (h *JobHandler) RerunExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch execution.Status {
+	case models.ExecutionStatusAwaitingApproval, models.ExecutionStatusPending, models.ExecutionStatusRunning, models.ExecutionStatusPaused:
+		http.Error(w, "Only finished executions can be re-run", http.StatusConflict)
+		return
+	}
+	if !h.authorizeRun(w, r, tid, execution.JobDefinitionID) {
+		return
+	}
+	def, err := h.repo.GetJobDefinitionByID(tid, execution.JobDefinitionID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !rejectArchived(w, def) {
+		return
+	}
+	if def.JobType == models.JobTypeSQLScript {
+		http.Error(w, "SQL script executions cannot be re-run from a snapshot", http.StatusConflict)
+		return
+	}
+	if _, err := h.repo.GetExecutionSnapshot(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Execution has no AST snapshot to re-run", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to load execution snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, execution.JobDefinitionID) {
+		return
+	}
+
+	creds, ok := h.runCredentials(w, r, tid, execution.JobDefinitionID)
+	if !ok {
+		return
+	}
+
+	triggeredBy, triggerContext := requestTrigger(r)
+	triggerContext["replay_of_execution_id"] = execID
+	params := temporal.ExecutionParams{
+		TenantID:            tid,
+		ExecutionID:         uuid.New().String(),
+		JobDefinitionID:     execution.JobDefinitionID,
+		JobType:             def.JobType,
+		ReplayOfExecutionID: execID,
+		SkipSensors:         true,
+		AllowConcurrent:     r.URL.Query().Get("force") == "true",
+		TriggeredBy:         triggeredBy,
+		TriggerContext:      triggerContext,
+	}
+	h.startExecution(w, r, params, creds, "Job execution re-run from snapshot.")
+}
+
+// PauseExecution asks a running engine execution to checkpoint and idle until it is
+// resumed. The status turns paused once the engine has been told.
+func (h *JobHandler) PauseExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if execution.Status != models.ExecutionStatusRunning {
+		http.Error(w, "Only running executions can be paused", http.StatusConflict)
+		return
+	}
+	def, err := h.repo.GetJobDefinitionByID(tid, execution.JobDefinitionID)
+	if err != nil {
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if def.JobType == models.JobTypeSQLScript {
+		http.Error(w, "SQL script executions cannot be paused", http.StatusConflict)
+		return
+	}
+	h.signalPause(w, r, execution, true)
+}
+
+// CancelExecution aborts a pending, running or paused execution. The workflow stops
+// the engine container, or stops at its next step when the container has not
+// started, and the status turns cancelled.
+func (h *JobHandler) CancelExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch execution.Status {
+	case models.ExecutionStatusAwaitingApproval:
+		// Nothing runs before approval, so declining a held run just settles it.
+		h.declineExecution(w, r, execution)
+		return
+	case models.ExecutionStatusPending, models.ExecutionStatusRunning, models.ExecutionStatusPaused:
+	default:
+		http.Error(w, "Only pending, running or paused executions can be cancelled", http.StatusConflict)
+		return
+	}
+	if execution.Status != models.ExecutionStatusPending {
+		def, err := h.repo.GetJobDefinitionByID(tid, execution.JobDefinitionID)
+		if err != nil {
+			http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if def.JobType == models.JobTypeSQLScript {
+			http.Error(w, "SQL script executions cannot be cancelled once running", http.StatusConflict)
+			return
+		}
+	}
+	h.signalExecution(w, r, execution, temporal.CancelSignalName, models.AuditExecutionCancelled, "Execution cancellation requested.")
+}
+
+// signalPause sends the execution's workflow a pause or resume signal.
+func (h *JobHandler) signalPause(w http.ResponseWriter, r *http.Request, execution models.JobExecution, pause bool) {
+	if pause {
+		h.signalExecution(w, r, execution, temporal.PauseSignalName, models.AuditExecutionPaused, "Execution pause requested.")
+		return
+	}
+	h.signalExecution(w, r, execution, temporal.ResumeSignalName, models.AuditExecutionResumed, "Execution resume requested.")
+}
+
+// signalExecution sends the execution's workflow a control signal and audits it
+// as action.
+func (h *JobHandler) signalExecution(w http.ResponseWriter, r *http.Request, execution models.JobExecution, signal, action, message string) {
+	workflowID := temporal.ExecutionWorkflowID(execution.ID, execution.WorkflowID)
+	if err := h.temporalClient.SignalWorkflow(r.Context(), workflowID, "", signal, nil); err != nil {
+		http.Error(w, "Failed to signal execution workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &execution.TenantID,
+		Action:     action,
+		TargetType: "execution",
+		TargetID:   execution.ID,
+		Details:    map[string]interface{}{"job_definition_id": execution.JobDefinitionID},
+	})
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"message":     message,
+		"executionID": execution.ID,
+		"workflowID":  workflowID,
+	})
+}
+
+// ReportCheckpoints receives periodic per-table progress from the engine.
+func (h *JobHandler) ReportCheckpoints(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	var req struct {
+		Checkpoints []models.ExecutionCheckpoint `json:"checkpoints"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Failed to decode request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Checkpoints) == 0 {
+		http.Error(w, "At least one checkpoint is required", http.StatusBadRequest)
+		return
+	}
+	for i := range req.Checkpoints {
+		req.Checkpoints[i].Table = strings.TrimSpace(req.Checkpoints[i].Table)
+		if req.Checkpoints[i].Table == "" {
+			http.Error(w, "Checkpoint table is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.repo.SaveExecutionCheckpoints(tid, execID, req.Checkpoints); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to save checkpoints: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *JobHandler) ListExecutionCheckpoints(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	if _, err := h.repo.GetExecution(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	checkpoints, err := h.repo.ListExecutionCheckpoints(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution checkpoints: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, checkpoints)
+}
+
+// ListExecutionTables returns how each table of an execution fared, as the engine
+// reported on completion.
+func (h *JobHandler) ListExecutionTables(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	if _, err := h.repo.GetExecution(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tables, err := h.repo.ListExecutionTables(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution tables: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, tables)
+}
+
+// ListExecutionEvents returns an execution's timeline, from its creation through
+// the engine's callback to completion, for finding where a stuck run stopped.
+func (h *JobHandler) ListExecutionEvents(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	if _, err := h.repo.GetExecution(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	events, err := h.repo.ListExecutionEvents(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// GetExecutionLogs returns an execution's logs as chunks classified by level. The
+// level parameter keeps only chunks of that level or more severe, so
+// ?level=warn leaves the warnings and errors of a failed run.
+func (h *JobHandler) GetExecutionLogs(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	minLevel := models.LogLevelDebug
+	if raw := r.URL.Query().Get("level"); raw != "" {
+		if minLevel, ok = models.ParseLogLevel(raw); !ok {
+			http.Error(w, "Invalid log level", http.StatusBadRequest)
+			return
+		}
+	}
+	execID := mux.Vars(r)["execID"]
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkTenantRegion(w, h.residency, tid) {
+		return
+	}
+	chunks, err := h.repo.ListExecutionLogChunks(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution logs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Logs stored before they were chunked are classified on the fly.
+	if len(chunks) == 0 && execution.Logs != nil {
+		chunks = models.ChunkLogs(models.StripANSI(*execution.Logs))
+	}
+	writeJSON(w, http.StatusOK, models.FilterLogChunks(execID, chunks, minLevel))
+}
+
+func (h *JobHandler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+	execution, err := h.repo.GetLastExecution(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, execution)
+}
+
+func (h *JobHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	// parse query params with defaults
+	limit := 20
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil {
+			limit = v
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil {
+			offset = v
+		}
+	}
+
+	triggeredBy := strings.TrimSpace(r.URL.Query().Get("triggered_by"))
+	if triggeredBy != "" && !models.ValidTrigger(triggeredBy) {
+		http.Error(w, "Invalid triggered_by: "+triggeredBy, http.StatusBadRequest)
+		return
+	}
+
+	executions, err := h.repo.ListExecutions(tid, triggeredBy, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+func (h *JobHandler) GetExecutionStats(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	days := 31 // default to 31 days
+	if d := r.URL.Query().Get("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil {
+			days = v
+		}
+	}
+
+	tz := strings.TrimSpace(r.URL.Query().Get("tz"))
+	if tz != "" {
+		if err := utils.ValidateTimezone(tz); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	months, ok := parseStatsMonths(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.repo.ListExecutionStats(tid, days, tz)
+	if err != nil {
+		http.Error(w, "Failed to get execution stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if months > 0 {
+		now := time.Now().UTC()
+		stats.PerMonth, err = h.repo.ListMonthlyStats(tid, "", now.AddDate(0, 1-months, 0), now)
+		if err != nil {
+			http.Error(w, "Failed to get monthly execution stats: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// GetDefinitionMonthlyStats returns a definition's executions per month for the last
+// ?months= months (12 by default), oldest first. Months without executions are
+// omitted.
+func (h *JobHandler) GetDefinitionMonthlyStats(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	months, ok := parseStatsMonths(w, r)
+	if !ok {
+		return
+	}
+	if months == 0 {
+		months = 12
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+	if _, err := h.repo.GetJobDefinitionByID(tid, jobDefID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	stats, err := h.repo.ListMonthlyStats(tid, jobDefID, now.AddDate(0, 1-months, 0), now)
+	if err != nil {
+		http.Error(w, "Failed to get monthly execution stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = []models.ExecutionMonthStat{}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// parseStatsMonths reads ?months=, how many months of history to return; 0 when
+// absent.
+func parseStatsMonths(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("months")
+	if raw == "" {
+		return 0, true
+	}
+	months, err := strconv.Atoi(raw)
+	if err != nil || months <= 0 || months > maxStatsMonths {
+		http.Error(w, "months must be between 1 and "+strconv.Itoa(maxStatsMonths), http.StatusBadRequest)
+		return 0, false
+	}
+	return months, true
+}
+
+func (h *JobHandler) GetJobDefinition(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	view, err := newDefinitionView(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	section, err := parseASTSection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+	definition, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	grants, err := h.repo.GetRunGrants(tid, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to load run permissions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	definition.RunGrants = &grants
+	if err := h.loadSchedule(&definition); err != nil {
+		http.Error(w, "Failed to load schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if definition.Lock, err = h.definitionLock(tid, jobDefID); err != nil {
+		http.Error(w, "Failed to load definition lock: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	shaped, err := view.render(definition)
+	if err != nil {
+		http.Error(w, "Failed to encode job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.applyASTSection(tid, jobDefID, section, shaped); err != nil {
+		http.Error(w, "Failed to load AST section: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, shaped)
+}
+
+// GetExecution returns an execution. With ?wait=30s it long-polls: the response is
+// held until the status differs from ?after_status (by default the status it had
+// when the request arrived) or the wait elapses.
+func (h *JobHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	wait, afterStatus, ok := parseExecutionWait(w, r)
 	if !ok {
-		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
-	execID := mux.Vars(r)["execID"]
 	execution, err := h.repo.GetExecution(tid, execID)
 	if err != nil {
 		if isNotFound(err) {
@@ -563,9 +1679,159 @@ func (h *JobHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// The execution carries its logs, which are subject to the tenant's data residency.
+	if !checkTenantRegion(w, h.residency, tid) {
+		return
+	}
+	if execution, err = h.awaitExecutionChange(r, execution, afterStatus, wait); err != nil {
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if execution.Status == models.ExecutionStatusFailed {
+		h.attachPartialState(&execution)
+	}
+	if execution.Status == models.ExecutionStatusSucceeded || execution.Status == models.ExecutionStatusFailed {
+		h.attachVerification(&execution)
+	}
+	if execution.TriggerContext["rollback_of_execution_id"] != "" {
+		h.attachRollback(&execution)
+	}
+	if notes, err := h.repo.ListExecutionNotes(tid, execID); err != nil {
+		h.logger.Warn().Err(err).Str("execution_id", execID).Msg("failed to load execution notes")
+	} else {
+		execution.Notes = notes
+	}
+	execution.StartupBreakdown = models.BreakdownStartup(execution.StartupTimings)
 	writeJSON(w, http.StatusOK, execution)
 }
 
+// attachPartialState embeds the partial state report of a failed execution, if one
+// was captured.
+func (h *JobHandler) attachPartialState(execution *models.JobExecution) {
+	var report models.PartialStateReport
+	if h.loadReportArtifact(*execution, models.ArtifactKindPartialState, &report) {
+		execution.PartialState = &report
+	}
+}
+
+// attachVerification embeds the post-migration verification report of a finished
+// execution, if its run was verified.
+func (h *JobHandler) attachVerification(execution *models.JobExecution) {
+	var report models.VerificationReport
+	if h.loadReportArtifact(*execution, models.ArtifactKindVerification, &report) {
+		execution.Verification = &report
+	}
+}
+
+// attachRollback embeds the report of a rollback execution, once the engine has
+// run it.
+func (h *JobHandler) attachRollback(execution *models.JobExecution) {
+	var report models.RollbackReport
+	if h.loadReportArtifact(*execution, models.ArtifactKindRollback, &report) {
+		execution.Rollback = &report
+	}
+}
+
+// loadReportArtifact decodes the execution's artifact of the given kind into report.
+// It reports false when there is none, or when it may not be served here.
+func (h *JobHandler) loadReportArtifact(execution models.JobExecution, kind string, report interface{}) bool {
+	artifact, err := h.repo.GetExecutionArtifact(execution.TenantID, execution.ID, kind)
+	if err != nil {
+		if !isNotFound(err) {
+			h.logger.Warn().Err(err).Str("execution_id", execution.ID).Str("kind", kind).Msg("failed to load execution report")
+		}
+		return false
+	}
+	if h.residency != nil {
+		if err := h.residency.CheckAccess(artifact.StorageRegion); err != nil {
+			return false
+		}
+	}
+	if artifact.Quarantined() {
+		return false
+	}
+	if err := json.Unmarshal(artifact.Content, report); err != nil {
+		h.logger.Warn().Err(err).Str("execution_id", execution.ID).Str("kind", kind).Msg("invalid execution report")
+		return false
+	}
+	return true
+}
+
+func (h *JobHandler) ListExecutionArtifacts(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	if _, err := h.repo.GetExecution(tid, execID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	artifacts, err := h.repo.ListExecutionArtifacts(tid, execID)
+	if err != nil {
+		http.Error(w, "Failed to list execution artifacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.residency != nil {
+		for _, artifact := range artifacts {
+			if err := h.residency.CheckAccess(artifact.StorageRegion); err != nil {
+				writeCrossRegion(w, err)
+				return
+			}
+		}
+	}
+	// Quarantined artifacts are listed with their scan result but not their content.
+	for i := range artifacts {
+		if artifacts[i].Quarantined() {
+			artifacts[i].Content = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, artifacts)
+}
+
+// scanArtifact malware scans an artifact before it is stored, quarantining it when
+// it fails. Scanner outages are logged; whether they quarantine the artifact
+// depends on the tenant's require_artifact_scan setting.
+func (h *JobHandler) scanArtifact(ctx context.Context, artifact *models.ExecutionArtifact) error {
+	tenant, err := h.tenants.GetTenantByID(artifact.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := scanning.Apply(ctx, h.scanner, tenant.RequireArtifactScan, artifact); err != nil {
+		h.logger.Warn().Err(err).Str("execution_id", artifact.ExecutionID).Str("kind", artifact.Kind).Msg("artifact scan failed")
+	}
+	if artifact.Quarantined() {
+		h.logger.Warn().Str("execution_id", artifact.ExecutionID).Str("kind", artifact.Kind).Str("detail", artifact.ScanDetail).Msg("artifact quarantined")
+	}
+	return nil
+}
+
+func (h *JobHandler) GetExecutionSnapshot(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+	snapshot, err := h.repo.GetExecutionSnapshot(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Execution snapshot not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get execution snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// SetExecutionComplete is the engine's completion callback. Along with the overall
+// outcome the engine may report how each table fared.
 func (h *JobHandler) SetExecutionComplete(w http.ResponseWriter, r *http.Request) {
 	tid, ok := authz.TenantIDFromRequest(r)
 	if !ok {
@@ -574,14 +1840,26 @@ func (h *JobHandler) SetExecutionComplete(w http.ResponseWriter, r *http.Request
 	}
 	execID := mux.Vars(r)["execID"]
 	var req struct {
-		Status           string `json:"status"`
-		RecordsProcessed int64  `json:"records_processed"`
-		BytesTransferred int64  `json:"bytes_transferred"`
+		Status           models.ExecutionStatus     `json:"status"`
+		RecordsProcessed int64                      `json:"records_processed"`
+		BytesTransferred int64                      `json:"bytes_transferred"`
+		Tables           []models.JobExecutionTable `json:"tables"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Failed to decode request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	for i := range req.Tables {
+		req.Tables[i].Table = strings.TrimSpace(req.Tables[i].Table)
+		if req.Tables[i].Table == "" {
+			http.Error(w, "Table name is required", http.StatusBadRequest)
+			return
+		}
+		if !models.ValidExecutionTableStatus(req.Tables[i].Status) {
+			http.Error(w, "Table status must be succeeded, failed or skipped", http.StatusBadRequest)
+			return
+		}
+	}
 	if err := h.repo.SetExecutionComplete(tid, execID, req.Status, req.RecordsProcessed, req.BytesTransferred); err != nil {
 		if isNotFound(err) {
 			http.Error(w, "Job execution not found", http.StatusNotFound)
@@ -590,6 +1868,21 @@ func (h *JobHandler) SetExecutionComplete(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Failed to set execution complete: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if len(req.Tables) > 0 {
+		if err := h.repo.SaveExecutionTables(tid, execID, req.Tables); err != nil {
+			http.Error(w, "Failed to save execution tables: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	callback := models.ExecutionEvent{Type: models.ExecutionEventCallbackReceived, Details: map[string]interface{}{
+		"status":            req.Status,
+		"records_processed": req.RecordsProcessed,
+		"bytes_transferred": req.BytesTransferred,
+		"tables":            len(req.Tables),
+	}}
+	if err := h.repo.RecordExecutionEvent(tid, execID, callback); err != nil {
+		h.logger.Warn().Err(err).Str("execution_id", execID).Msg("failed to record callback event")
+	}
 	if h.notifier != nil {
 		exec, err := h.repo.GetExecution(tid, execID)
 		if err != nil {
@@ -599,9 +1892,9 @@ func (h *JobHandler) SetExecutionComplete(w http.ResponseWriter, r *http.Request
 			if defErr != nil {
 				h.logger.Warn().Err(defErr).Str("job_definition_id", exec.JobDefinitionID).Msg("failed to load job definition for notification")
 			} else {
-				status := strings.ToLower(strings.TrimSpace(exec.Status))
+				status := models.ExecutionStatus(strings.ToLower(strings.TrimSpace(string(exec.Status))))
 				switch status {
-				case "succeeded":
+				case models.ExecutionStatusSucceeded:
 					var recordsProcessed, bytesTransferred int64
 					if exec.RecordsProcessed != nil {
 						recordsProcessed = *exec.RecordsProcessed
@@ -609,15 +1902,15 @@ func (h *JobHandler) SetExecutionComplete(w http.ResponseWriter, r *http.Request
 					if exec.BytesTransferred != nil {
 						bytesTransferred = *exec.BytesTransferred
 					}
-					if err := h.notifier.NotifyExecutionSucceeded(r.Context(), tid, exec.JobDefinitionID, execID, def.Name, recordsProcessed, bytesTransferred); err != nil {
+					if err := h.notifier.NotifyExecutionSucceeded(r.Context(), tid, exec.JobDefinitionID, execID, def.Name, recordsProcessed, bytesTransferred, exec.ActivityAttempts); err != nil {
 						h.logger.Warn().Err(err).Str("execution_id", execID).Msg("failed to publish execution success notification")
 					}
-				case "failed":
+				case models.ExecutionStatusFailed:
 					reason := ""
 					if exec.ErrorMessage != nil {
 						reason = *exec.ErrorMessage
 					}
-					if err := h.notifier.NotifyExecutionFailed(r.Context(), tid, exec.JobDefinitionID, execID, def.Name, reason); err != nil {
+					if err := h.notifier.NotifyExecutionFailed(r.Context(), tid, exec.JobDefinitionID, execID, def.Name, reason, exec.ActivityAttempts); err != nil {
 						h.logger.Warn().Err(err).Str("execution_id", execID).Msg("failed to publish execution failure notification")
 					}
 				}
@@ -633,12 +1926,97 @@ func (h *JobHandler) ListJobDefinitionsWithStats(w http.ResponseWriter, r *http.
 		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
 		return
 	}
+	view, err := newDefinitionView(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	stats, err := h.repo.ListJobDefinitionsWithStats(tid)
 	if err != nil {
 		http.Error(w, "Failed to get job definition stats: "+err.Error(), http.StatusNotFound)
 		return
 	}
-	writeJSON(w, http.StatusOK, stats)
+	resp := make([]map[string]json.RawMessage, 0, len(stats))
+	for _, stat := range stats {
+		if !view.lists(stat.JobDefinition) {
+			continue
+		}
+		shaped, err := view.render(stat)
+		if err != nil {
+			http.Error(w, "Failed to encode job definition stats: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp = append(resp, shaped)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// applyInlineDSNs validates any inline source/destination DSNs on a create payload and
+// stores them as ephemeral connections, pointing the payload at the new records. It
+// writes an error response and returns false when the payload cannot be used. The
+// caller runs discard when no definition ends up stored with the connections, which
+// nothing else would ever delete.
+func (h *JobHandler) applyInlineDSNs(w http.ResponseWriter, tenantID string, payload *createDefinitionPayload) (discard func(), ok bool) {
+	var created []string
+	discard = func() {
+		for _, id := range created {
+			if err := h.connRepo.DeleteEphemeral(tenantID, id); err != nil {
+				h.logger.Warn().Err(err).Str("connection_id", id).Msg("failed to delete unused inline connection")
+			}
+		}
+	}
+
+	inline := []struct {
+		role  string
+		dsn   string
+		id    *string
+		conn  models.Connection
+		label string
+	}{
+		{role: "source", dsn: strings.TrimSpace(payload.SourceDSN), id: &payload.SourceConnectionID, label: "Source"},
+		{role: "destination", dsn: strings.TrimSpace(payload.DestinationDSN), id: &payload.DestinationConnectionID, label: "Destination"},
+	}
+
+	for i := range inline {
+		if inline[i].dsn == "" {
+			continue
+		}
+		if strings.TrimSpace(*inline[i].id) != "" {
+			http.Error(w, inline[i].label+" connection and inline DSN are mutually exclusive", http.StatusBadRequest)
+			return nil, false
+		}
+		conn, err := models.ParseDSN(inline[i].dsn)
+		if err != nil {
+			http.Error(w, inline[i].label+" DSN: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		if inline[i].role == "destination" && conn.ReadOnly {
+			http.Error(w, "Destination DSN opens read-only sessions and cannot be a destination", http.StatusBadRequest)
+			return nil, false
+		}
+		inline[i].conn = conn
+	}
+
+	for _, entry := range inline {
+		if entry.dsn == "" {
+			continue
+		}
+		conn := entry.conn
+		conn.TenantID = tenantID
+		conn.Name = fmt.Sprintf("inline-%s-%s", entry.role, uuid.NewString()[:8])
+		conn.Status = models.ConnectionStatusUntested
+		conn.Ephemeral = true
+		stored, err := h.connRepo.Create(&conn)
+		if err != nil {
+			h.logger.Error().Err(err).Str("role", entry.role).Msg("failed to store inline connection")
+			http.Error(w, "Failed to store inline "+entry.role+" connection: "+err.Error(), http.StatusInternalServerError)
+			discard()
+			return nil, false
+		}
+		*entry.id = stored.ID
+		created = append(created, stored.ID)
+	}
+	return discard, true
 }
 
 func cloneRawMessage(msg json.RawMessage) json.RawMessage {
@@ -673,6 +2051,7 @@ func resolveDefinition(payload updateDefinitionPayload, current models.JobDefini
 	return resolvedDefinition{
 		Name:                    defaultTrimmedString(payload.Name, current.Name),
 		Description:             defaultString(payload.Description, current.Description),
+		JobType:                 current.JobType,
 		AST:                     defaultRaw(payload.AST, current.AST),
 		SourceConnectionID:      defaultTrimmedString(payload.SourceConnectionID, current.SourceConnectionID),
 		DestinationConnectionID: defaultTrimmedString(payload.DestinationConnectionID, current.DestinationConnectionID),
@@ -688,15 +2067,84 @@ func validateResolvedDefinition(def resolvedDefinition) []string {
 	if len(def.AST) == 0 {
 		errs = append(errs, "ast is required")
 	}
+	if def.JobType == models.JobTypeSQLScript {
+		return append(errs, validateSQLScript(def)...)
+	}
 	if strings.TrimSpace(def.SourceConnectionID) == "" {
 		errs = append(errs, "source_connection_id is required")
 	}
 	if strings.TrimSpace(def.DestinationConnectionID) == "" {
 		errs = append(errs, "destination_connection_id is required")
 	}
+	if _, err := models.TableWriteModes(def.AST); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+// validateSQLScript checks the script spec in a sql_script definition's AST and the
+// connections it needs: the destination always, the source only to copy a table.
+func validateSQLScript(def resolvedDefinition) []string {
+	var errs []string
+	spec, err := models.ParseSQLScript(def.AST)
+	if len(def.AST) > 0 && err != nil {
+		errs = append(errs, err.Error())
+	}
+	if spec.NeedsSource() && strings.TrimSpace(def.SourceConnectionID) == "" {
+		errs = append(errs, "source_connection_id is required to copy a table")
+	}
+	if strings.TrimSpace(def.DestinationConnectionID) == "" {
+		errs = append(errs, "destination_connection_id is required")
+	}
 	return errs
 }
 
+// parseJobType validates a create payload's job type; an empty type is an engine job.
+func parseJobType(w http.ResponseWriter, raw string) (string, bool) {
+	jobType := strings.ToLower(strings.TrimSpace(raw))
+	if jobType == "" {
+		return models.JobTypeEngine, true
+	}
+	if !models.ValidJobType(jobType) {
+		http.Error(w, "job_type must be "+models.JobTypeEngine+" or "+models.JobTypeSQLScript, http.StatusBadRequest)
+		return "", false
+	}
+	return jobType, true
+}
+
+// parseWriteMode validates a payload's write mode; an empty mode is the default.
+func parseWriteMode(w http.ResponseWriter, raw string) (string, bool) {
+	mode, err := models.NormalizeWriteMode(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return mode, true
+}
+
+// parseVerification validates a payload's verification mode; an empty mode is off.
+func parseVerification(w http.ResponseWriter, raw string) (string, bool) {
+	mode, err := models.NormalizeVerification(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return mode, true
+}
+
+// validRetryPolicy rejects a retry policy outside the allowed bounds. A nil policy
+// is valid and keeps the defaults.
+func validRetryPolicy(w http.ResponseWriter, policy *models.RetryPolicy) bool {
+	if policy == nil {
+		return true
+	}
+	if err := policy.Validate(); err != nil {
+		http.Error(w, "Invalid retry_policy: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func decodeAllowEmpty(r *http.Request, dest interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(dest); err != nil {
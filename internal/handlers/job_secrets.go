@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type setSecretPayload struct {
+	Value *string `json:"value"`
+}
+
+// ListDefinitionSecrets returns the names of a definition's secrets; values are never returned.
+func (h *JobHandler) ListDefinitionSecrets(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+
+	if _, err := h.repo.GetJobDefinitionByID(tid, jobDefID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	secrets, err := h.repo.ListDefinitionSecrets(tid, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to list secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+// SetDefinitionSecret creates or replaces a single secret value.
+func (h *JobHandler) SetDefinitionSecret(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	jobDefID, name := vars["jobID"], vars["name"]
+	if err := models.ValidateSecretName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload setSecretPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Value == nil {
+		http.Error(w, "Invalid request payload: value is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetDefinitionSecrets(tid, jobDefID, map[string]string{name: *payload.Value}); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to store secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *JobHandler) DeleteDefinitionSecret(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	if err := h.repo.DeleteDefinitionSecret(tid, vars["jobID"], vars["name"]); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Secret not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// extractSecrets moves inline secret values out of the given documents (the AST and
+// any progress snapshot embedding it) so only references are stored. It writes a
+// 400 response and returns false when a document is malformed.
+func extractSecrets(w http.ResponseWriter, docs ...*json.RawMessage) (map[string]string, bool) {
+	secrets := map[string]string{}
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		redacted, values, err := models.ExtractASTSecrets(*doc)
+		if err != nil {
+			http.Error(w, "Invalid AST secrets: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		*doc = redacted
+		for name, value := range values {
+			secrets[name] = value
+		}
+	}
+	return secrets, true
+}
+
+// missingSecrets lists secrets referenced by ast that have neither a stored value nor
+// one in pending.
+func (h *JobHandler) missingSecrets(tenantID, jobDefID string, ast json.RawMessage, pending map[string]string) ([]string, error) {
+	refs, err := models.ASTSecretRefs(ast)
+	if err != nil || len(refs) == 0 {
+		return nil, err
+	}
+	stored, err := h.repo.ListDefinitionSecrets(tenantID, jobDefID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(stored)+len(pending))
+	for _, secret := range stored {
+		known[secret.Name] = struct{}{}
+	}
+	for name := range pending {
+		known[name] = struct{}{}
+	}
+	var missing []string
+	for _, name := range refs {
+		if _, ok := known[name]; !ok {
+			missing = append(missing, fmt.Sprintf("secret %q has no value", name))
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// Enums lists the valid statuses, job types, roles and other enumerated values
+// so clients can render and validate them without hard-coding.
+func Enums(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, models.AllEnums())
+}
@@ -4,30 +4,47 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
-	"github.com/docker/docker/client"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"golang.org/x/sync/singleflight"
 )
 
+const metadataPullTimeout = 30 * time.Second
+
+// errNoEngineHost marks a pull that failed because no Docker host could take it.
+var errNoEngineHost = errors.New("no engine host available")
+
 type MetadataHandler struct {
 	repo          repository.ConnectionRepository
-	dockerClient  *client.Client
+	hosts         *engine.HostPool
 	containerName string
 	logger        zerolog.Logger
+
+	// pulls coalesces identical concurrent requests into one engine exec, and gates
+	// lets only one pull run against a connection at a time.
+	pulls singleflight.Group
+	mu    sync.Mutex
+	gates map[string]*connectionGate
 }
 
-func NewMetadataHandler(repo repository.ConnectionRepository, containerName string, logger zerolog.Logger) *MetadataHandler {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Docker client")
-	}
-	return &MetadataHandler{repo: repo, dockerClient: dockerClient, containerName: containerName, logger: logger}
+// connectionGate serializes metadata pulls for one connection. refs counts the
+// pulls holding or waiting for it so the gate can be dropped once idle.
+type connectionGate struct {
+	slot chan struct{}
+	refs int
+}
+
+func NewMetadataHandler(repo repository.ConnectionRepository, hosts *engine.HostPool, containerName string, logger zerolog.Logger) *MetadataHandler {
+	return &MetadataHandler{repo: repo, hosts: hosts, containerName: containerName, logger: logger, gates: map[string]*connectionGate{}}
 }
 
 func (h *MetadataHandler) GetSourceMetadata(w http.ResponseWriter, r *http.Request) {
@@ -51,19 +68,88 @@ func (h *MetadataHandler) GetSourceMetadata(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
+	// The flight outlives any single caller: a client that disconnects must not
+	// cancel the pull the other waiters share.
+	key := tid + "/" + conn.ID + "/" + conn.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	ch := h.pulls.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), metadataPullTimeout)
+		defer cancel()
+		return h.pull(ctx, tid, *conn)
+	})
 
-	dr := engine.NewDockerRunner(h.dockerClient)
-	cli := engine.NewClient(dr, h.containerName)
-	data, err := cli.SaveSourceMetadata(ctx, *conn)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-r.Context().Done():
+		return
+	}
+	if errors.Is(res.Err, engine.ErrHostBusy) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Engine is busy, try again shortly", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(res.Err, errNoEngineHost) {
+		http.Error(w, res.Err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if res.Err != nil {
+		http.Error(w, res.Err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// return raw JSON
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	w.Write(res.Val.([]byte))
+}
+
+// pull waits for the connection's gate and asks the engine for the source metadata.
+func (h *MetadataHandler) pull(ctx context.Context, tenantID string, conn models.Connection) ([]byte, error) {
+	unlock, err := h.lockConnection(ctx, tenantID+"/"+conn.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	host, release, err := h.hosts.Acquire(ctx, tenantID)
+	if errors.Is(err, engine.ErrHostBusy) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoEngineHost, err)
+	}
+	defer release()
+	return host.Engine(h.containerName).SaveSourceMetadata(ctx, conn)
+}
+
+// lockConnection blocks until no other pull is running for key, or ctx is done.
+func (h *MetadataHandler) lockConnection(ctx context.Context, key string) (func(), error) {
+	h.mu.Lock()
+	gate, ok := h.gates[key]
+	if !ok {
+		gate = &connectionGate{slot: make(chan struct{}, 1)}
+		h.gates[key] = gate
+	}
+	gate.refs++
+	h.mu.Unlock()
+
+	done := func() {
+		h.mu.Lock()
+		gate.refs--
+		if gate.refs == 0 {
+			delete(h.gates, key)
+		}
+		h.mu.Unlock()
+	}
+
+	select {
+	case gate.slot <- struct{}{}:
+		return func() {
+			<-gate.slot
+			done()
+		}, nil
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/config"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// prometheusContentType is the Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsHandler exports per-tenant usage and quota gauges in the Prometheus text
+// format, for billing and capacity dashboards.
+type MetricsHandler struct {
+	tenants repository.TenantRepository
+	cfg     config.MetricsConfig
+	logger  zerolog.Logger
+}
+
+func NewMetricsHandler(tenants repository.TenantRepository, cfg config.MetricsConfig, logger zerolog.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		tenants: tenants,
+		cfg:     cfg,
+		logger:  logger.With().Str("handler", "metrics").Logger(),
+	}
+}
+
+// TenantUsage serves the gauges of every live tenant. Scrapers authenticate with
+// the configured scrape token; without one the endpoint does not exist.
+func (h *MetricsHandler) TenantUsage(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.ScrapeToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.ScrapeToken)) != 1 {
+		http.Error(w, "Invalid scrape token", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	usage, err := h.tenants.ListTenantUsage(monthStart)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to load tenant usage")
+		http.Error(w, "Failed to load tenant usage: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	writeGaugeFamily(&buf, "stratum_tenant_executions_month", "Executions created this calendar month (UTC).", usage,
+		func(u models.TenantUsage, emit func(extra string, v int64)) { emit("", u.ExecutionsThisMonth) })
+	writeGaugeFamily(&buf, "stratum_tenant_bytes_transferred_month", "Bytes transferred by executions created this calendar month (UTC).", usage,
+		func(u models.TenantUsage, emit func(extra string, v int64)) { emit("", u.BytesThisMonth) })
+	writeGaugeFamily(&buf, "stratum_tenant_active_users", "Active users of the tenant.", usage,
+		func(u models.TenantUsage, emit func(extra string, v int64)) { emit("", u.ActiveUsers) })
+	writeGaugeFamily(&buf, "stratum_tenant_quota_limit", "Monthly quota of the tenant; absent when unlimited.", usage,
+		func(u models.TenantUsage, emit func(extra string, v int64)) {
+			quota := h.cfg.QuotaFor(u.TenantID)
+			if quota.MonthlyExecutions > 0 {
+				emit(`resource="executions"`, quota.MonthlyExecutions)
+			}
+			if quota.MonthlyBytes > 0 {
+				emit(`resource="bytes"`, quota.MonthlyBytes)
+			}
+		})
+	writeGaugeFamily(&buf, "stratum_tenant_quota_remaining", "Monthly quota left to the tenant, never below zero; absent when unlimited.", usage,
+		func(u models.TenantUsage, emit func(extra string, v int64)) {
+			quota := h.cfg.QuotaFor(u.TenantID)
+			if quota.MonthlyExecutions > 0 {
+				emit(`resource="executions"`, remainingQuota(quota.MonthlyExecutions, u.ExecutionsThisMonth))
+			}
+			if quota.MonthlyBytes > 0 {
+				emit(`resource="bytes"`, remainingQuota(quota.MonthlyBytes, u.BytesThisMonth))
+			}
+		})
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeGaugeFamily writes one gauge with a sample per tenant; series calls emit for
+// each sample of a tenant, with extra labels beyond the tenant's own.
+func writeGaugeFamily(buf *bytes.Buffer, name, help string, usage []models.TenantUsage, series func(u models.TenantUsage, emit func(extra string, v int64))) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	for _, u := range usage {
+		labels := fmt.Sprintf(`tenant_id="%s",tenant_name="%s"`, escapeLabelValue(u.TenantID), escapeLabelValue(u.TenantName))
+		series(u, func(extra string, v int64) {
+			if extra != "" {
+				fmt.Fprintf(buf, "%s{%s,%s} %d\n", name, labels, extra, v)
+				return
+			}
+			fmt.Fprintf(buf, "%s{%s} %d\n", name, labels, v)
+		})
+	}
+}
+
+func remainingQuota(limit, used int64) int64 {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
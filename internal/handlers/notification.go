@@ -25,6 +25,11 @@ func NewNotificationHandler(service notification.Service, logger zerolog.Logger)
 	}
 }
 
+// notificationGroupExecution collapses a listing into one entry per execution.
+const notificationGroupExecution = "execution"
+
+// List returns the tenant's recent notifications, or with ?group=execution the
+// recent groups of them, one per execution, each with its latest notification.
 func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
 	tenantID, ok := authz.TenantIDFromRequest(r)
 	if !ok {
@@ -32,6 +37,12 @@ func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	group := strings.TrimSpace(r.URL.Query().Get("group"))
+	if group != "" && group != notificationGroupExecution {
+		http.Error(w, "group must be "+notificationGroupExecution, http.StatusBadRequest)
+		return
+	}
+
 	limit := 25
 	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
 		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
@@ -39,6 +50,25 @@ func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if version, err := h.service.ListVersion(r.Context(), tenantID); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to compute notifications version")
+	} else if notModified(w, r, version) {
+		return
+	}
+
+	if group == notificationGroupExecution {
+		groups, err := h.service.ListExecutionGroups(r.Context(), tenantID, limit)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to list notification groups")
+			http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"groups": groups,
+		})
+		return
+	}
+
 	notifications, err := h.service.ListRecent(r.Context(), tenantID, limit)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to list notifications")
@@ -77,3 +107,35 @@ func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, notif)
 }
+
+// MarkGroupRead marks every notification of a group, identified by its
+// correlation key, as read.
+func (h *NotificationHandler) MarkGroupRead(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+
+	key := strings.TrimSpace(mux.Vars(r)["correlationKey"])
+	if key == "" {
+		http.Error(w, "Correlation key is required", http.StatusBadRequest)
+		return
+	}
+
+	marked, err := h.service.MarkGroupRead(r.Context(), tenantID, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Notification group not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error().Err(err).Str("correlation_key", key).Msg("failed to mark notification group as read")
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"correlation_key": key,
+		"marked":          marked,
+	})
+}
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+func TestNotificationsGroupedByExecution(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, token := h.SeedTenant("Acme", "viewer@acme.test", models.RoleViewer)
+	_, _, other := h.SeedTenant("Globex", "viewer@globex.test", models.RoleViewer)
+	svc := notification.NewService(h.Store.Notifications(), zerolog.Nop())
+	ctx := context.Background()
+
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	check(svc.NotifyExecutionStarted(ctx, tenant.ID, "def-1", "exec-a", "Nightly copy"))
+	check(svc.NotifyExecutionStarted(ctx, tenant.ID, "def-2", "exec-b", "Hourly copy"))
+	check(svc.NotifyExecutionPaused(ctx, tenant.ID, "def-1", "exec-a", "Nightly copy"))
+	check(svc.NotifyExecutionFailed(ctx, tenant.ID, "def-1", "exec-a", "Nightly copy", "boom", nil))
+	check(svc.NotifyValidationComplete(ctx, tenant.ID, "def-3", "Weekly copy"))
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/notifications?group=job", nil, token), http.StatusBadRequest, nil)
+
+	var listed struct {
+		Groups []models.NotificationGroup `json:"groups"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/notifications?group=execution", nil, token), http.StatusOK, &listed)
+	if len(listed.Groups) != 3 {
+		t.Fatalf("listed %d groups, want 3", len(listed.Groups))
+	}
+	// Groups are ordered by their latest notification; one without a correlation
+	// key stands alone.
+	validation, a, b := listed.Groups[0], listed.Groups[1], listed.Groups[2]
+	if validation.CorrelationKey != "" || validation.Count != 1 || validation.Status != "" {
+		t.Fatalf("validation group = %+v", validation)
+	}
+	if a.CorrelationKey != "exec-a" || a.Count != 3 || a.UnreadCount != 3 || a.Status != string(models.ExecutionStatusFailed) ||
+		a.Latest.EventType != models.NotificationEventExecutionFailed {
+		t.Fatalf("exec-a group = %+v", a)
+	}
+	if b.CorrelationKey != "exec-b" || b.Count != 1 || b.Status != string(models.ExecutionStatusRunning) {
+		t.Fatalf("exec-b group = %+v", b)
+	}
+
+	var marked struct {
+		Marked int64 `json:"marked"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/notifications/groups/exec-a/read", nil, other), http.StatusNotFound, nil)
+	h.Decode(h.Do(http.MethodPost, "/api/v1/notifications/groups/exec-a/read", nil, token), http.StatusOK, &marked)
+	if marked.Marked != 3 {
+		t.Fatalf("marked %d notifications, want 3", marked.Marked)
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/notifications/groups/exec-a/read", nil, token), http.StatusOK, &marked)
+	if marked.Marked != 0 {
+		t.Fatalf("marked %d notifications again, want 0", marked.Marked)
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/notifications/groups/exec-z/read", nil, token), http.StatusNotFound, nil)
+
+	h.Decode(h.Do(http.MethodGet, "/api/v1/notifications?group=execution", nil, token), http.StatusOK, &listed)
+	if listed.Groups[1].UnreadCount != 0 || listed.Groups[2].UnreadCount != 1 {
+		t.Fatalf("unread after marking exec-a = %d, %d", listed.Groups[1].UnreadCount, listed.Groups[2].UnreadCount)
+	}
+}
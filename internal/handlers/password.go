@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/passwords"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// checkPassword rejects a new password that breaks the password policy with a 400
+// listing every problem.
+func checkPassword(w http.ResponseWriter, r *http.Request, policy *passwords.Policy, password string) bool {
+	if policy == nil {
+		return true
+	}
+	if err := policy.Validate(r.Context(), password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// PasswordPolicy describes the password policy so clients can show it while a
+// password is chosen.
+func (h *AuthHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.passwords == nil {
+		writeJSON(w, http.StatusOK, passwords.Description{Rules: []string{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.passwords.Describe())
+}
+
+// ChangePassword replaces the caller's password after checking the current one.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authz.UserIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing user context", http.StatusUnauthorized)
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.NewPassword == "" {
+		http.Error(w, "New password is required", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.userRepository.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.userRepository.AuthenticateUser(current.Email, req.CurrentPassword); err != nil {
+		http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !checkPassword(w, r, h.passwords, req.NewPassword) {
+		return
+	}
+
+	if err := h.userRepository.UpdatePassword(userID, req.NewPassword); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update password: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
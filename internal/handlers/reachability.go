@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	// reachabilityTTL is how long a probe result is reused; probes of the same
+	// address inside it are debounced.
+	reachabilityTTL = time.Minute
+	// reachabilityDialTimeout bounds a single TCP probe.
+	reachabilityDialTimeout = 3 * time.Second
+	// reachabilityWait is how long an autosave waits for a fresh probe before
+	// answering without it.
+	reachabilityWait = 300 * time.Millisecond
+)
+
+// reachabilityCache probes whether connection hosts accept TCP connections. Probes
+// run in the background and are shared by every caller of the same address, so
+// frequent autosaves cost at most one dial per address and TTL.
+type reachabilityCache struct {
+	ttl     time.Duration
+	timeout time.Duration
+	wait    time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]reachabilityEntry
+	inflight map[string]chan struct{}
+}
+
+type reachabilityEntry struct {
+	err     error
+	expires time.Time
+}
+
+func newReachabilityCache() *reachabilityCache {
+	return &reachabilityCache{
+		ttl:      reachabilityTTL,
+		timeout:  reachabilityDialTimeout,
+		wait:     reachabilityWait,
+		entries:  make(map[string]reachabilityEntry),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// check returns the latest probe error of the connection's address. known is false
+// when the connection has no network address or its probe is still running.
+func (c *reachabilityCache) check(conn models.Connection) (known bool, err error) {
+	if conn.Host == "" || conn.Port == 0 {
+		return false, nil
+	}
+	addr := net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))
+	now := time.Now()
+
+	c.mu.Lock()
+	if entry, ok := c.entries[addr]; ok && now.Before(entry.expires) {
+		c.mu.Unlock()
+		return true, entry.err
+	}
+	done, running := c.inflight[addr]
+	if !running {
+		done = make(chan struct{})
+		c.inflight[addr] = done
+		go c.probe(addr, done)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(c.wait):
+		return false, nil
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[addr]
+	c.mu.Unlock()
+	return ok, entry.err
+}
+
+func (c *reachabilityCache) probe(addr string, done chan struct{}) {
+	conn, err := net.DialTimeout("tcp", addr, c.timeout)
+	if err == nil {
+		conn.Close()
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[addr] = reachabilityEntry{err: err, expires: now.Add(c.ttl)}
+	delete(c.inflight, addr)
+	c.mu.Unlock()
+	close(done)
+}
+
+// connectionWarning describes an unreachable connection for an advisory warning, or
+// returns "" when it is reachable or not yet known.
+func (c *reachabilityCache) connectionWarning(role string, conn models.Connection) string {
+	known, err := c.check(conn)
+	if !known || err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s connection %s is unreachable: %v", role, conn.Name, err)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type registryCredentialPayload struct {
+	Registry string  `json:"registry"`
+	AuthType string  `json:"auth_type"`
+	Username *string `json:"username"`
+	Password string  `json:"password"`
+	Helper   *string `json:"helper"`
+}
+
+// ListRegistryCredentials returns the credentials engine images are pulled with.
+// Passwords are never returned.
+func (h *AdminHandler) ListRegistryCredentials(w http.ResponseWriter, r *http.Request) {
+	creds, err := h.registries.ListRegistryCredentials()
+	if err != nil {
+		http.Error(w, "Failed to list registry credentials: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, creds)
+}
+
+// SaveRegistryCredential creates or replaces the credential of a registry. Basic
+// credentials keep their stored password when none is passed.
+func (h *AdminHandler) SaveRegistryCredential(w http.ResponseWriter, r *http.Request) {
+	var payload registryCredentialPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	cred := models.RegistryCredential{
+		Registry: payload.Registry,
+		AuthType: payload.AuthType,
+		Username: payload.Username,
+		Helper:   payload.Helper,
+	}
+	if err := cred.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cred.AuthType == models.RegistryAuthBasic && payload.Password == "" {
+		existing, _, err := h.registries.GetRegistryCredential(cred.Registry)
+		if err != nil && !isNotFound(err) {
+			http.Error(w, "Failed to load registry credential: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err != nil || !existing.HasPassword {
+			http.Error(w, "password is required for basic registry credentials", http.StatusBadRequest)
+			return
+		}
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok && uid != "" {
+		cred.CreatedBy = &uid
+	}
+
+	saved, err := h.registries.SaveRegistryCredential(cred, payload.Password)
+	if err != nil {
+		http.Error(w, "Failed to save registry credential: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.auth.Invalidate(saved.Registry)
+	h.logger.Info().Str("registry", saved.Registry).Str("auth_type", saved.AuthType).Msg("Registry credential saved")
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// DeleteRegistryCredential removes a registry's credential; later pulls from it
+// are anonymous.
+func (h *AdminHandler) DeleteRegistryCredential(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.registries.DeleteRegistryCredential(mux.Vars(r)["registryID"])
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Registry credential not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete registry credential: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.auth.Invalidate(deleted.Registry)
+	w.WriteHeader(http.StatusNoContent)
+}
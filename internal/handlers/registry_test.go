@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+func TestRegistryCredentials(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, admin, token := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	superToken := h.Token(tenant.ID, admin.ID, models.RoleSuperAdmin)
+	basic := map[string]interface{}{
+		"registry":  "https://Registry.Acme.Test/",
+		"auth_type": "basic",
+		"username":  "puller",
+	}
+
+	h.Decode(h.Do(http.MethodPut, "/api/v1/admin/registries", basic, token), http.StatusForbidden, nil)
+	h.Decode(h.Do(http.MethodPut, "/api/v1/admin/registries", basic, superToken), http.StatusBadRequest, nil)
+
+	basic["password"] = "s3cret"
+	var saved models.RegistryCredential
+	h.Decode(h.Do(http.MethodPut, "/api/v1/admin/registries", basic, superToken), http.StatusOK, &saved)
+	if saved.Registry != "registry.acme.test" || !saved.HasPassword {
+		t.Fatalf("saved = %+v", saved)
+	}
+	// Updating the username keeps the stored password.
+	delete(basic, "password")
+	basic["username"] = "reader"
+	h.Decode(h.Do(http.MethodPut, "/api/v1/admin/registries", basic, superToken), http.StatusOK, &saved)
+	if !saved.HasPassword || *saved.Username != "reader" {
+		t.Fatalf("updated = %+v", saved)
+	}
+
+	var helper models.RegistryCredential
+	h.Decode(h.Do(http.MethodPut, "/api/v1/admin/registries", map[string]interface{}{
+		"registry":  "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+		"auth_type": "helper",
+		"helper":    "docker-credential-ecr-login",
+	}, superToken), http.StatusOK, &helper)
+	if helper.Helper == nil || *helper.Helper != "ecr-login" || helper.HasPassword {
+		t.Fatalf("helper = %+v", helper)
+	}
+
+	var creds []models.RegistryCredential
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/registries", nil, superToken), http.StatusOK, &creds)
+	if len(creds) != 2 {
+		t.Fatalf("listed %d credentials, want 2", len(creds))
+	}
+
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/admin/registries/"+saved.ID, nil, superToken), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/admin/registries/"+saved.ID, nil, superToken), http.StatusNotFound, nil)
+}
+
+func TestImageRegistry(t *testing.T) {
+	for ref, want := range map[string]string{
+		"postgres:16":                  models.DockerHubRegistry,
+		"stratum/engine:latest":        models.DockerHubRegistry,
+		"ghcr.io/stanstork/engine:1.2": "ghcr.io",
+		"localhost:5000/engine":        "localhost:5000",
+		"localhost/engine":             "localhost",
+	} {
+		if got := models.ImageRegistry(ref); got != want {
+			t.Errorf("ImageRegistry(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
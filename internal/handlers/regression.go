@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+)
+
+const (
+	defaultRegressionPageSize = 50
+	maxRegressionPageSize     = 200
+)
+
+// ListRegressions returns the definition's performance regression history, newest
+// first: every succeeded execution whose duration or throughput fell behind the
+// definition's baseline.
+func (h *JobHandler) ListRegressions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobID := mux.Vars(r)["jobID"]
+
+	limit := defaultRegressionPageSize
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, maxRegressionPageSize)
+		}
+	}
+
+	if _, err := h.repo.GetJobDefinitionByID(tid, jobID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	regressions, err := h.repo.ListExecutionRegressions(tid, jobID, limit)
+	if err != nil {
+		http.Error(w, "Failed to list regressions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, regressions)
+}
@@ -9,11 +9,11 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/docker/docker/client"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/repository"
 )
 
@@ -25,21 +25,15 @@ var dataFormatMap = map[string]string{
 }
 
 type ReportHandler struct {
-	conn         repository.ConnectionRepository
-	job          repository.JobRepository
-	engineClient *engine.Client
-	logger       zerolog.Logger
+	conn          repository.ConnectionRepository
+	job           repository.JobRepository
+	hosts         *engine.HostPool
+	containerName string
+	logger        zerolog.Logger
 }
 
-func NewReportHandler(conn repository.ConnectionRepository, job repository.JobRepository, containerName string, logger zerolog.Logger) *ReportHandler {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Docker client")
-	}
-
-	dr := engine.NewDockerRunner(dockerClient)
-	engineClient := engine.NewClient(dr, containerName)
-	return &ReportHandler{conn: conn, job: job, engineClient: engineClient, logger: logger}
+func NewReportHandler(conn repository.ConnectionRepository, job repository.JobRepository, hosts *engine.HostPool, containerName string, logger zerolog.Logger) *ReportHandler {
+	return &ReportHandler{conn: conn, job: job, hosts: hosts, containerName: containerName, logger: logger}
 }
 
 func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +83,10 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Destination connection not found", http.StatusBadRequest)
 		return
 	}
+	if promptedConnections(srcConn, destConn) {
+		http.Error(w, "Connections that prompt for credentials cannot be used outside a job run", http.StatusBadRequest)
+		return
+	}
 
 	// Parse AST as generic map so we can inject connections
 	var ast map[string]interface{}
@@ -100,6 +98,17 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "AST is empty or invalid", http.StatusBadRequest)
 		return
 	}
+	secrets, err := h.job.GetDefinitionSecretValues(tid, defID)
+	if err != nil {
+		http.Error(w, "Failed to load definition secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resolved, err := models.ResolveASTSecrets(ast, secrets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ast = resolved.(map[string]interface{})
 
 	// Build connection strings
 	srcConnStr, err := srcConn.GenerateConnString()
@@ -125,6 +134,12 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 			"conn_str":  destConnStr,
 		},
 	}
+	ast["write_mode"] = def.EffectiveWriteMode()
+	overrides, err := models.TableWriteModes(def.AST)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	cfgBytes, err := json.Marshal(ast)
 	if err != nil {
@@ -144,7 +159,13 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), timeout)
 	defer cancel()
 
-	report, err := h.engineClient.DryRun(ctx, cfgBytes)
+	engineClient, release, ok := acquireEngine(w, r, h.hosts, tid, h.containerName)
+	if !ok {
+		return
+	}
+	defer release()
+
+	report, err := engineClient.DryRun(ctx, cfgBytes)
 	if err != nil {
 		// map timeouts to 504; other engine failures to 502
 		if errors.Is(err, context.DeadlineExceeded) {
@@ -155,6 +176,9 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	report = h.withTransferEstimate(tid, defID, srcConn, destConn, report)
+	report = h.withOverwriteWarnings(ctx, engineClient, cfgBytes, def, overrides, report)
+
 	// Return JSON bytes produced by engine
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if r.URL.Query().Get("download") == "1" {
@@ -163,3 +187,71 @@ func (h *ReportHandler) DryRunReport(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(report)
 }
+
+// withOverwriteWarnings adds write_mode_warnings to the engine's report when the
+// definition overwrites any table: one warning per overwritten destination table
+// that already holds rows. The check is advisory, so a failed row count lookup is
+// reported in the warnings instead of failing the dry run.
+func (h *ReportHandler) withOverwriteWarnings(ctx context.Context, client *engine.Client, cfg []byte, def models.JobDefinition, overrides map[string]string, report []byte) []byte {
+	overwrites := def.EffectiveWriteMode() == models.WriteModeOverwrite
+	for _, mode := range overrides {
+		overwrites = overwrites || mode == models.WriteModeOverwrite
+	}
+	if !overwrites {
+		return report
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(report, &fields); err != nil || fields == nil {
+		return report
+	}
+
+	warnings := []string{}
+	var state models.PartialStateReport
+	if raw, err := client.DestinationState(ctx, cfg); err != nil {
+		h.logger.Warn().Err(err).Str("job_definition_id", def.ID).Msg("failed to read destination state for overwrite check")
+		warnings = append(warnings, "Could not check whether overwritten destination tables hold rows")
+	} else if err := json.Unmarshal(raw, &state); err != nil {
+		warnings = append(warnings, "Could not check whether overwritten destination tables hold rows")
+	} else {
+		warnings = append(warnings, models.OverwriteWarnings(state.Tables, def.EffectiveWriteMode(), overrides)...)
+	}
+
+	encoded, err := json.Marshal(warnings)
+	if err != nil {
+		return report
+	}
+	fields["write_mode_warnings"] = encoded
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return report
+	}
+	return merged
+}
+
+// withTransferEstimate adds a transfer_estimate built from the connection benchmarks
+// and the definition's last run to the engine's report. Reports that are not a JSON
+// object are returned unchanged.
+func (h *ReportHandler) withTransferEstimate(tid, defID string, src, dst *models.Connection, report []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(report, &fields); err != nil || fields == nil {
+		return report
+	}
+
+	var lastExec *models.JobExecution
+	if exec, err := h.job.GetLastExecution(tid, defID); err == nil {
+		lastExec = &exec
+	} else if !isNotFound(err) {
+		h.logger.Warn().Err(err).Str("job_definition_id", defID).Msg("failed to load last execution for transfer estimate")
+	}
+
+	estimate, err := json.Marshal(models.NewTransferEstimate(src.Benchmark, dst.Benchmark, lastExec))
+	if err != nil {
+		return report
+	}
+	fields["transfer_estimate"] = estimate
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return report
+	}
+	return merged
+}
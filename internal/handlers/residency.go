@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/storage"
+)
+
+// DataRegionHeader names the region a tenant's data is pinned to on cross-region
+// rejections.
+const DataRegionHeader = "X-Data-Region"
+
+// writeCrossRegion answers with 421 Misdirected Request when err is a cross-region
+// access, pointing the client at the deployment that serves the data. It reports
+// whether a response was written.
+func writeCrossRegion(w http.ResponseWriter, err error) bool {
+	var crossErr *storage.CrossRegionError
+	if !errors.As(err, &crossErr) {
+		return false
+	}
+	w.Header().Set(DataRegionHeader, crossErr.Region)
+	if crossErr.URL != "" {
+		w.Header().Set("Location", crossErr.URL)
+	}
+	http.Error(w, "Data residency: "+crossErr.Error(), http.StatusMisdirectedRequest)
+	return true
+}
+
+// checkTenantRegion verifies that this deployment may serve the tenant's stored
+// artifacts and logs, writing the error response if not.
+func checkTenantRegion(w http.ResponseWriter, residency *storage.Residency, tenantID string) bool {
+	if residency == nil {
+		return true
+	}
+	if _, err := residency.TenantLocation(tenantID); err != nil {
+		if !writeCrossRegion(w, err) {
+			http.Error(w, "Failed to resolve data region: "+err.Error(), http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
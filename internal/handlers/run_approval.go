@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+// runApprovalRequired reports whether the tenant holds runs for approval. It
+// writes the error response and returns false as its second value when the
+// tenant cannot be loaded.
+func (h *JobHandler) runApprovalRequired(w http.ResponseWriter, tenantID string) (bool, bool) {
+	tenant, err := h.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return false, false
+	}
+	return tenant.RequireRunApproval, true
+}
+
+// holdForApproval records the run as awaiting approval with the workflow input it
+// starts with once approved.
+func (h *JobHandler) holdForApproval(r *http.Request, params temporal.ExecutionParams) error {
+	request, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("encode run: %w", err)
+	}
+	if _, err := h.repo.CreateAwaitingExecution(params.TenantID, params.JobDefinitionID, params.ExecutionID,
+		params.TriggeredBy, params.TriggerContext, request); err != nil {
+		return err
+	}
+	details := map[string]interface{}{
+		"job_definition_id": params.JobDefinitionID,
+		"triggered_by":      params.TriggeredBy,
+	}
+	for k, v := range params.TriggerContext {
+		details[k] = v
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &params.TenantID,
+		Action:     models.AuditExecutionApprovalRequested,
+		TargetType: "execution",
+		TargetID:   params.ExecutionID,
+		Details:    details,
+	})
+	return nil
+}
+
+// ApproveExecution releases a run held for approval to Temporal. The approver must
+// be a signed-in admin other than the user who requested the run, and supplies the
+// passwords of prompt-mode connections as for any run.
+func (h *JobHandler) ApproveExecution(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	execution, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if execution.Status != models.ExecutionStatusAwaitingApproval {
+		http.Error(w, "Only executions awaiting approval can be approved", http.StatusConflict)
+		return
+	}
+	uid, _ := authz.UserIDFromRequest(r)
+	if uid == "" {
+		http.Error(w, "Executions must be approved by a signed-in user", http.StatusForbidden)
+		return
+	}
+	if execution.TriggerContext["user_id"] == uid {
+		http.Error(w, "Executions cannot be approved by the user who requested them", http.StatusForbidden)
+		return
+	}
+	if !h.rejectArchivedDefinition(w, tid, execution.JobDefinitionID) {
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, execution.JobDefinitionID) {
+		return
+	}
+	if _, ok := h.enforceValidationFreshness(w, tid, execution.JobDefinitionID); !ok {
+		return
+	}
+	var req runCredentialsRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	creds, ok := h.collectCredentials(w, tid, execution.JobDefinitionID, req)
+	if !ok {
+		return
+	}
+
+	overridden, ok := h.enforceBlackout(w, r, tid)
+	if !ok {
+		return
+	}
+	if !h.enforceStartLimit(w, tid) {
+		return
+	}
+
+	request, err := h.repo.ClaimExecutionApproval(tid, execID, uid)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution was already approved or declined", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to approve job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var params temporal.ExecutionParams
+	if err := json.Unmarshal(request, &params); err != nil {
+		h.releaseApproval(tid, execID)
+		http.Error(w, "Failed to decode held run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if overridden != "" {
+		if params.TriggerContext == nil {
+			params.TriggerContext = map[string]string{}
+		}
+		params.TriggerContext["blackout_override"] = overridden
+	}
+	if !params.AllowConcurrent {
+		active, err := h.repo.GetActiveDefinitionExecution(tid, params.JobDefinitionID)
+		if err == nil {
+			h.releaseApproval(tid, execID)
+			http.Error(w, fmt.Sprintf("Job definition already has an active execution %s; approve once it finishes", active.ID), http.StatusConflict)
+			return
+		}
+		if !isNotFound(err) {
+			h.releaseApproval(tid, execID)
+			http.Error(w, "Failed to check active executions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	we, err := h.launchExecution(r, params, creds)
+	if err != nil {
+		h.releaseApproval(tid, execID)
+		http.Error(w, "Failed to start job execution workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditExecutionApproved,
+		TargetType: "execution",
+		TargetID:   execID,
+		Details:    map[string]interface{}{"job_definition_id": execution.JobDefinitionID},
+	})
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"message":     "Job execution approved and started.",
+		"executionID": execID,
+		"workflowID":  we.GetID(),
+		"runID":       we.GetRunID(),
+	})
+}
+
+// releaseApproval lets a claimed approval be given again after the approved run
+// could not be started.
+func (h *JobHandler) releaseApproval(tenantID, execID string) {
+	if err := h.repo.ReleaseExecutionApproval(tenantID, execID); err != nil {
+		h.logger.Error().Err(err).Str("execution_id", execID).Msg("failed to release execution approval")
+	}
+}
+
+// declineExecution cancels a run held for approval that nobody approved yet.
+func (h *JobHandler) declineExecution(w http.ResponseWriter, r *http.Request, execution models.JobExecution) {
+	if execution.ApprovedBy != nil {
+		http.Error(w, "Job execution was already approved", http.StatusConflict)
+		return
+	}
+	if _, err := h.repo.UpdateExecution(execution.TenantID, execution.ID, models.ExecutionStatusCancelled, "Declined before approval", ""); err != nil {
+		http.Error(w, "Failed to cancel job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &execution.TenantID,
+		Action:     models.AuditExecutionCancelled,
+		TargetType: "execution",
+		TargetID:   execution.ID,
+		Details:    map[string]interface{}{"job_definition_id": execution.JobDefinitionID},
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Job execution declined."})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+// runCredentialsRequest supplies passwords for prompt-mode connections when a job is
+// run. They are held in memory for the run only and never stored.
+type runCredentialsRequest struct {
+	SourcePassword      string `json:"source_password"`
+	DestinationPassword string `json:"destination_password"`
+}
+
+// runJobRequest is the optional body of RunJob: credentials as for any run, plus
+// AST overrides for this run only.
+type runJobRequest struct {
+	runCredentialsRequest
+	Overrides json.RawMessage `json:"overrides"`
+}
+
+// runCredentials collects the just-in-time passwords a run of the definition needs
+// from the request body. It writes the error response and returns false when a
+// prompt-mode connection is missing its password.
+func (h *JobHandler) runCredentials(w http.ResponseWriter, r *http.Request, tenantID, jobDefID string) (temporal.RunCredentials, bool) {
+	var req runCredentialsRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+	return h.requestCredentials(w, tenantID, jobDefID, req)
+}
+
+// requestCredentials is runCredentials for an already decoded request. Runs held
+// for approval are not given passwords until the approver supplies them, as they
+// are never stored.
+func (h *JobHandler) requestCredentials(w http.ResponseWriter, tenantID, jobDefID string, req runCredentialsRequest) (temporal.RunCredentials, bool) {
+	approval, ok := h.runApprovalRequired(w, tenantID)
+	if !ok || approval {
+		return temporal.RunCredentials{}, ok
+	}
+	return h.collectCredentials(w, tenantID, jobDefID, req)
+}
+
+// collectCredentials returns the passwords of the definition's prompt-mode
+// connections from req.
+func (h *JobHandler) collectCredentials(w http.ResponseWriter, tenantID, jobDefID string, req runCredentialsRequest) (temporal.RunCredentials, bool) {
+	def, err := h.repo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Failed to get job definition: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	creds := temporal.RunCredentials{}
+	for _, c := range []struct {
+		role     string
+		connID   string
+		password string
+	}{
+		{"source", def.SourceConnectionID, req.SourcePassword},
+		{"destination", def.DestinationConnectionID, req.DestinationPassword},
+	} {
+		if c.connID == "" {
+			continue
+		}
+		conn, err := h.connRepo.Get(tenantID, c.connID)
+		if err != nil {
+			if isNotFound(err) {
+				continue // the prepare activity reports missing connections
+			}
+			http.Error(w, "Failed to get "+c.role+" connection: "+err.Error(), http.StatusInternalServerError)
+			return nil, false
+		}
+		if !conn.PromptsForCredentials() {
+			continue
+		}
+		if c.password == "" {
+			http.Error(w, c.role+"_password is required: connection "+conn.Name+" prompts for credentials", http.StatusBadRequest)
+			return nil, false
+		}
+		creds[conn.ID] = c.password
+	}
+	return creds, true
+}
+
+// promptedConnections reports whether either connection requires run-time credentials.
+func promptedConnections(conns ...*models.Connection) bool {
+	for _, c := range conns {
+		if c != nil && c.PromptsForCredentials() {
+			return true
+		}
+	}
+	return false
+}
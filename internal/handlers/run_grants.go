@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type runGrantsRequest struct {
+	Users []string `json:"users"`
+	Roles []string `json:"roles"`
+}
+
+// SetRunPermissions replaces the users and roles allowed to run a definition
+// without being editors.
+func (h *JobHandler) SetRunPermissions(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+
+	var req runGrantsRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	grants := models.RunGrants{Users: []string{}, Roles: []models.UserRole{}}
+	seen := map[string]bool{}
+	for _, id := range req.Users {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			grants.Users = append(grants.Users, id)
+		}
+	}
+	for _, raw := range req.Roles {
+		role := models.UserRole(strings.ToLower(strings.TrimSpace(raw)))
+		if !models.IsValidRole(role) {
+			http.Error(w, "Invalid role: "+raw, http.StatusBadRequest)
+			return
+		}
+		grants.Roles = append(grants.Roles, role)
+	}
+	grants.Roles = models.NormalizeRoles(grants.Roles)
+
+	uid, _ := authz.UserIDFromRequest(r)
+	updated, err := h.repo.SetRunGrants(tid, jobDefID, grants, uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrRunGrantUserNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update run permissions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("tenant_id", tid).Str("job_definition_id", jobDefID).Int("users", len(updated.Users)).Int("roles", len(updated.Roles)).Msg("run permissions updated")
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// authorizeRun lets editors run any definition and everyone else only the
+// definitions that grant them, by user or by role. It writes a 403 when neither
+// applies.
+func (h *JobHandler) authorizeRun(w http.ResponseWriter, r *http.Request, tenantID, jobDefID string) bool {
+	roles, _ := authz.RolesFromRequest(r)
+	if models.HasAtLeast(roles, models.RoleEditor) {
+		return true
+	}
+	grants, err := h.repo.GetRunGrants(tenantID, jobDefID)
+	if err != nil {
+		http.Error(w, "Failed to load run permissions: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	uid, _ := authz.UserIDFromRequest(r)
+	if uid == "" || !grants.Allows(uid, roles) {
+		http.Error(w, "insufficient permissions to run this job", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/workflows"
+	tc "go.temporal.io/sdk/client"
+)
+
+type runGroupRequest struct {
+	Name             string   `json:"name"`
+	JobDefinitionIDs []string `json:"job_definition_ids"`
+	Parallelism      int      `json:"parallelism"`
+	StopOnFailure    bool     `json:"stop_on_failure"`
+}
+
+// RunGroup starts related definitions as one run group: at most parallelism of them
+// run at a time and, with stop_on_failure, the ones not started yet are skipped once
+// one fails. Members must be READY and may not prompt for credentials. Like single
+// runs, a group is refused while a member has an active execution unless ?force=true,
+// inside a blackout window, and beyond the tenant's start limits. Tenants that
+// require run approval cannot start groups.
+func (h *JobHandler) RunGroup(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req runGroupRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	group := models.RunGroup{
+		TenantID:      tid,
+		Name:          req.Name,
+		Parallelism:   req.Parallelism,
+		StopOnFailure: req.StopOnFailure,
+	}
+	for _, id := range req.JobDefinitionIDs {
+		group.Members = append(group.Members, models.RunGroupMember{JobDefinitionID: id})
+	}
+	if err := group.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	approval, ok := h.runApprovalRequired(w, tid)
+	if !ok {
+		return
+	}
+	if approval {
+		http.Error(w, "Run groups cannot be started while the tenant requires run approval", http.StatusConflict)
+		return
+	}
+	blockCrossEnv, ok := h.blocksCrossEnvironment(w, tid)
+	if !ok {
+		return
+	}
+	freshness, ok := h.validationFreshness(w, tid)
+	if !ok {
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+	jobTypes := make(map[string]string, len(group.Members))
+	for _, m := range group.Members {
+		jobType, ok := h.checkRunGroupMember(w, r, tid, m.JobDefinitionID, blockCrossEnv, freshness, force)
+		if !ok {
+			return
+		}
+		jobTypes[m.JobDefinitionID] = jobType
+	}
+	overridden, ok := h.enforceBlackout(w, r, tid)
+	if !ok {
+		return
+	}
+	if !h.enforceStartLimit(w, tid) {
+		return
+	}
+
+	for i := range group.Members {
+		group.Members[i].ExecutionID = uuid.New().String()
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok && uid != "" {
+		group.CreatedBy = &uid
+	}
+	group, err := h.repo.CreateRunGroup(group)
+	if err != nil {
+		http.Error(w, "Failed to create run group: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, baseContext := requestTrigger(r)
+	params := temporal.RunGroupParams{
+		TenantID:      tid,
+		GroupID:       group.ID,
+		Parallelism:   group.Parallelism,
+		StopOnFailure: group.StopOnFailure,
+	}
+	for _, m := range group.Members {
+		triggerContext := map[string]string{"run_group_id": group.ID}
+		for k, v := range baseContext {
+			triggerContext[k] = v
+		}
+		if overridden != "" {
+			triggerContext["blackout_override"] = overridden
+		}
+		params.Members = append(params.Members, temporal.ExecutionParams{
+			TenantID:        tid,
+			ExecutionID:     m.ExecutionID,
+			JobDefinitionID: m.JobDefinitionID,
+			JobType:         jobTypes[m.JobDefinitionID],
+			AllowConcurrent: force,
+			Priority:        models.PriorityNormal,
+			TriggeredBy:     models.TriggerPipeline,
+			TriggerContext:  triggerContext,
+		})
+	}
+	workflowOptions := tc.StartWorkflowOptions{
+		ID:        temporal.RunGroupWorkflowIDPrefix + group.ID,
+		TaskQueue: temporal.TaskQueueName,
+	}
+	if _, err := h.temporalClient.ExecuteWorkflow(context.Background(), workflowOptions, workflows.RunGroupWorkflow, params); err != nil {
+		// Nothing was started; close the group so it does not show as running.
+		if closeErr := h.repo.CompleteRunGroup(tid, group.ID, models.NewRunGroupReport(group, time.Now().UTC())); closeErr != nil {
+			h.logger.Error().Err(closeErr).Str("run_group_id", group.ID).Msg("failed to close run group")
+		}
+		http.Error(w, "Failed to start run group workflow: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	details := map[string]interface{}{
+		"job_definition_ids": req.JobDefinitionIDs,
+		"parallelism":        group.Parallelism,
+		"stop_on_failure":    group.StopOnFailure,
+	}
+	for k, v := range baseContext {
+		details[k] = v
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditRunGroupStarted,
+		TargetType: "run_group",
+		TargetID:   group.ID,
+		Details:    details,
+	})
+	writeJSON(w, http.StatusAccepted, group)
+}
+
+// checkRunGroupMember writes an error response and returns false when the
+// definition cannot run as part of a group. It returns the definition's job type.
+func (h *JobHandler) checkRunGroupMember(w http.ResponseWriter, r *http.Request, tid, jobDefID string, blockCrossEnv bool, freshness models.ValidationFreshness, force bool) (string, bool) {
+	if !h.authorizeRun(w, r, tid, jobDefID) {
+		return "", false
+	}
+	def, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found: "+jobDefID, http.StatusNotFound)
+			return "", false
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	if def.Status != models.DefinitionStatusReady {
+		http.Error(w, fmt.Sprintf("Job definition %s is not READY", def.Name), http.StatusConflict)
+		return "", false
+	}
+	if blockCrossEnv && !rejectEnvironmentMismatch(w, def.SourceConnection, def.DestinationConnection) {
+		return "", false
+	}
+	if _, ok := staleValidationWarning(w, freshness, def); !ok {
+		return "", false
+	}
+	for _, conn := range []models.Connection{def.SourceConnection, def.DestinationConnection} {
+		if conn.PromptsForCredentials() {
+			http.Error(w, "Connection "+conn.Name+" prompts for credentials and cannot be used by run groups", http.StatusConflict)
+			return "", false
+		}
+	}
+	if !force {
+		active, err := h.repo.GetActiveDefinitionExecution(tid, jobDefID)
+		if err == nil {
+			http.Error(w, fmt.Sprintf("Job definition %s already has an active execution %s", def.Name, active.ID), http.StatusConflict)
+			return "", false
+		}
+		if !isNotFound(err) {
+			http.Error(w, "Failed to check active executions: "+err.Error(), http.StatusInternalServerError)
+			return "", false
+		}
+	}
+	return def.JobType, true
+}
+
+// GetRunGroup returns a run group with the status of each member and the progress
+// of the group.
+func (h *JobHandler) GetRunGroup(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	group, err := h.repo.GetRunGroup(tid, mux.Vars(r)["groupID"])
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Run group not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get run group: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+// GetRunGroupReport returns the consolidated report recorded when every member of
+// the group finished.
+func (h *JobHandler) GetRunGroupReport(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	groupID := mux.Vars(r)["groupID"]
+	report, err := h.repo.GetRunGroupReport(tid, groupID)
+	if err == nil {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	if !isNotFound(err) {
+		http.Error(w, "Failed to get run group report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.repo.GetRunGroup(tid, groupID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Run group not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get run group: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Run group is still running; its report is recorded once every member finished", http.StatusConflict)
+}
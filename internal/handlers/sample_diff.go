@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+const (
+	defaultSampleRows = 100
+	maxSampleRows     = 1000
+	sampleDiffTimeout = 5 * time.Minute
+)
+
+type sampleDiffRequest struct {
+	Tables []string `json:"tables"`
+	Rows   int      `json:"rows"`
+}
+
+// SampleDiff spot-checks a finished execution: the engine fetches random source rows
+// and their destination counterparts, and the column-level mismatch statistics are
+// returned and stored as the execution's sample_diff artifact.
+func (h *JobHandler) SampleDiff(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	execID := mux.Vars(r)["execID"]
+
+	var req sampleDiffRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Rows == 0 {
+		req.Rows = defaultSampleRows
+	}
+	if req.Rows < 0 || req.Rows > maxSampleRows {
+		http.Error(w, fmt.Sprintf("rows must be between 1 and %d", maxSampleRows), http.StatusBadRequest)
+		return
+	}
+	tables := make([]string, 0, len(req.Tables))
+	for _, t := range req.Tables {
+		if t = strings.TrimSpace(t); t != "" {
+			tables = append(tables, t)
+		}
+	}
+
+	exec, err := h.repo.GetExecution(tid, execID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if exec.Status.Active() {
+		http.Error(w, "Execution has not finished", http.StatusConflict)
+		return
+	}
+	if !checkTenantRegion(w, h.residency, tid) {
+		return
+	}
+
+	cfg, err := h.executionEngineConfig(tid, exec)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Failed to build engine config: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to build engine config: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	engineClient, release, ok := acquireEngine(w, r, h.hosts, tid, h.containerName)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(r.Context(), sampleDiffTimeout)
+	defer cancel()
+	raw, err := engineClient.SampleRows(ctx, cfg, tables, req.Rows)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "sampling timed out", http.StatusGatewayTimeout)
+			return
+		}
+		http.Error(w, "sampling failed: "+ansi.ReplaceAllString(err.Error(), ""), http.StatusBadGateway)
+		return
+	}
+
+	var sample models.SampleReport
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&sample); err != nil {
+		http.Error(w, "Failed to parse engine sample: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	report := models.SampleDiffReport{CapturedAt: time.Now().UTC(), SampleSize: req.Rows, Tables: []models.TableSampleDiff{}}
+	for _, t := range sample.Tables {
+		report.Tables = append(report.Tables, models.CompareSampledTable(t))
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, "Failed to encode report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	artifact := models.ExecutionArtifact{
+		TenantID:    tid,
+		ExecutionID: execID,
+		Kind:        models.ArtifactKindSampleDiff,
+		Content:     content,
+	}
+	if h.residency != nil {
+		loc, err := h.residency.TenantLocation(tid)
+		if err != nil {
+			http.Error(w, "Failed to resolve data region: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		artifact.StorageRegion = loc.Region
+		artifact.StorageLocation = loc.URI(fmt.Sprintf("tenants/%s/executions/%s/%s.json", tid, execID, artifact.Kind))
+	}
+	if err := h.scanArtifact(r.Context(), &artifact); err != nil {
+		http.Error(w, "Failed to scan sample diff: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.repo.SaveExecutionArtifact(artifact); err != nil {
+		http.Error(w, "Failed to store sample diff: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if artifact.Quarantined() {
+		http.Error(w, "Sample diff report was quarantined: "+artifact.ScanDetail, http.StatusUnprocessableEntity)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// executionEngineConfig rebuilds the engine config an execution ran with: the AST
+// from its snapshot (or the current definition for executions recorded before
+// snapshots), resolved secrets, and the definition's current connections.
+func (h *JobHandler) executionEngineConfig(tenantID string, exec models.JobExecution) ([]byte, error) {
+	def, err := h.repo.GetJobDefinitionByID(tenantID, exec.JobDefinitionID)
+	if err != nil {
+		return nil, err
+	}
+	astJSON := []byte(def.AST)
+	if snapshot, err := h.repo.GetExecutionSnapshot(tenantID, exec.ID); err == nil {
+		astJSON = snapshot.AST
+	} else if !isNotFound(err) {
+		return nil, err
+	}
+
+	var ast map[string]interface{}
+	if err := json.Unmarshal(astJSON, &ast); err != nil || ast == nil {
+		return nil, errors.New("execution AST is empty or invalid")
+	}
+	secrets, err := h.repo.GetDefinitionSecretValues(tenantID, def.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := models.ResolveASTSecrets(ast, secrets)
+	if err != nil {
+		return nil, err
+	}
+	ast = resolved.(map[string]interface{})
+
+	src, err := h.connRepo.Get(tenantID, def.SourceConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("source connection: %w", err)
+	}
+	dst, err := h.connRepo.Get(tenantID, def.DestinationConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("destination connection: %w", err)
+	}
+	if promptedConnections(src, dst) {
+		return nil, errors.New("connections that prompt for credentials cannot be used outside a job run")
+	}
+	srcConnStr, err := src.GenerateConnString()
+	if err != nil {
+		return nil, err
+	}
+	dstConnStr, err := dst.GenerateConnString()
+	if err != nil {
+		return nil, err
+	}
+	ast["connections"] = map[string]interface{}{
+		"source": map[string]interface{}{"conn_type": "Source", "format": dataFormatMap[src.DataFormat], "conn_str": srcConnStr},
+		"dest":   map[string]interface{}{"conn_type": "Dest", "format": dataFormatMap[dst.DataFormat], "conn_str": dstConnStr},
+	}
+	return json.Marshal(ast)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/workflows"
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	tc "go.temporal.io/sdk/client"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+// scheduleNextRuns is how many upcoming runs schedule details list.
+const scheduleNextRuns = 5
+
+type schedulePayload struct {
+	CronExpression string `json:"cron_expression"`
+	Timezone       string `json:"timezone"`
+}
+
+// GetSchedule returns the definition's schedule with its next run times.
+func (h *JobHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+	schedule, err := h.repo.GetSchedule(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition is not scheduled", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	schedule.NextRuns = schedule.Occurrences(now, now.AddDate(1, 0, 0), scheduleNextRuns)
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// SetSchedule creates or replaces the Temporal Schedule that runs the definition on
+// a cron expression. Only READY definitions whose connections need no prompted
+// credentials can be scheduled, since nobody is there to supply them.
+func (h *JobHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+
+	var payload schedulePayload
+	if err := decodeAllowEmpty(r, &payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	schedule := models.JobSchedule{
+		JobDefinitionID: jobDefID,
+		TenantID:        tid,
+		CronExpression:  payload.CronExpression,
+		Timezone:        payload.Timezone,
+		ScheduleID:      temporal.JobScheduleIDPrefix + jobDefID,
+	}
+	if err := schedule.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		schedule.CreatedBy = &uid
+	}
+
+	def, err := h.repo.GetJobDefinitionByID(tid, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if def.Status != models.DefinitionStatusReady {
+		http.Error(w, "Only READY job definitions can be scheduled", http.StatusConflict)
+		return
+	}
+	if !h.enforceDefinitionEnvironmentPolicy(w, tid, jobDefID) {
+		return
+	}
+	for _, conn := range []models.Connection{def.SourceConnection, def.DestinationConnection} {
+		if conn.PromptsForCredentials() {
+			http.Error(w, "Connection "+conn.Name+" prompts for credentials and cannot be used by scheduled runs", http.StatusConflict)
+			return
+		}
+	}
+
+	triggerContext := map[string]string{"schedule_id": schedule.ScheduleID}
+	if schedule.CreatedBy != nil {
+		triggerContext["user_id"] = *schedule.CreatedBy
+	}
+	params := temporal.ExecutionParams{
+		TenantID:        tid,
+		JobDefinitionID: jobDefID,
+		JobType:         def.JobType,
+		TriggeredBy:     models.TriggerSchedule,
+		TriggerContext:  triggerContext,
+	}
+	created, err := h.upsertTemporalSchedule(r.Context(), schedule, params)
+	if err != nil {
+		http.Error(w, "Failed to save Temporal schedule: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	saved, err := h.repo.SaveSchedule(schedule)
+	if err != nil {
+		if created {
+			if delErr := h.temporalClient.ScheduleClient().GetHandle(context.Background(), schedule.ScheduleID).Delete(context.Background()); delErr != nil {
+				h.logger.Error().Err(delErr).Str("schedule_id", schedule.ScheduleID).Msg("failed to roll back Temporal schedule")
+			}
+		}
+		http.Error(w, "Failed to save schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditScheduleSet,
+		TargetType: "job_definition",
+		TargetID:   jobDefID,
+		Details:    map[string]interface{}{"cron_expression": saved.CronExpression, "timezone": saved.Timezone},
+	})
+	now := time.Now()
+	saved.NextRuns = saved.Occurrences(now, now.AddDate(1, 0, 0), scheduleNextRuns)
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// DeleteSchedule stops scheduled runs of the definition. Runs already started are
+// left alone.
+func (h *JobHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	jobDefID := mux.Vars(r)["jobID"]
+	if err := h.removeSchedule(r.Context(), tid, jobDefID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition is not scheduled", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete schedule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditScheduleDeleted,
+		TargetType: "job_definition",
+		TargetID:   jobDefID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeSchedule deletes the definition's Temporal Schedule and its record. It
+// returns sql.ErrNoRows when the definition is not scheduled.
+func (h *JobHandler) removeSchedule(ctx context.Context, tenantID, jobDefID string) error {
+	schedule, err := h.repo.GetSchedule(tenantID, jobDefID)
+	if err != nil {
+		return err
+	}
+	if err := h.temporalClient.ScheduleClient().GetHandle(ctx, schedule.ScheduleID).Delete(ctx); err != nil {
+		var notFound *serviceerror.NotFound
+		if !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	return h.repo.DeleteSchedule(tenantID, jobDefID)
+}
+
+// upsertTemporalSchedule creates the Temporal Schedule, or updates it when it already
+// exists, and reports whether it was created.
+func (h *JobHandler) upsertTemporalSchedule(ctx context.Context, schedule models.JobSchedule, params temporal.ExecutionParams) (bool, error) {
+	spec := tc.ScheduleSpec{
+		CronExpressions: []string{schedule.CronExpression},
+		TimeZoneName:    schedule.Timezone,
+	}
+	action := &tc.ScheduleWorkflowAction{
+		// Temporal appends the scheduled time to keep each run's workflow ID unique.
+		ID:        temporal.ExecWorkflowIDPrefix + "scheduled-" + schedule.JobDefinitionID,
+		Workflow:  workflows.ExecutionWorkflow,
+		Args:      []interface{}{params},
+		TaskQueue: temporal.TaskQueueName,
+	}
+	scheduleClient := h.temporalClient.ScheduleClient()
+	_, err := scheduleClient.Create(ctx, tc.ScheduleOptions{
+		ID:      schedule.ScheduleID,
+		Spec:    spec,
+		Action:  action,
+		Overlap: enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+	})
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sdktemporal.ErrScheduleAlreadyRunning) {
+		return false, err
+	}
+	err = scheduleClient.GetHandle(ctx, schedule.ScheduleID).Update(ctx, tc.ScheduleUpdateOptions{
+		DoUpdate: func(input tc.ScheduleUpdateInput) (*tc.ScheduleUpdate, error) {
+			updated := input.Description.Schedule
+			updated.Spec = &spec
+			updated.Action = action
+			return &tc.ScheduleUpdate{Schedule: &updated}, nil
+		},
+	})
+	return false, err
+}
+
+// loadSchedule attaches the definition's schedule, if any, to its details.
+func (h *JobHandler) loadSchedule(def *models.JobDefinition) error {
+	schedule, err := h.repo.GetSchedule(def.TenantID, def.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	def.Schedule = &schedule
+	return nil
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/passwords"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// SCIM schema and message URNs (RFC 7643, RFC 7644).
+const (
+	scimUserSchema     = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema    = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimConfigSchema   = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimListSchema     = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema    = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimContentType    = "application/scim+json"
+	scimTokenPrefix    = "scim_"
+	scimDefaultCount   = 100
+	scimMaxCount       = 200
+	scimBasePath       = "/scim/v2"
+	scimTokenNotActive = "SCIM provisioning is not enabled"
+)
+
+// SCIMHandler serves the SCIM 2.0 surface identity providers such as Okta and
+// Azure AD provision a tenant's users through, authenticated by the tenant's SCIM
+// token. Users map onto the tenant's users and Groups onto its roles.
+type SCIMHandler struct {
+	tokens    repository.SCIMTokenRepository
+	users     repository.UserRepository
+	audit     repository.AuditRepository
+	webhooks  repository.WebhookRepository
+	passwords *passwords.Policy
+	logger    zerolog.Logger
+}
+
+func NewSCIMHandler(tokens repository.SCIMTokenRepository, users repository.UserRepository, audit repository.AuditRepository, webhooks repository.WebhookRepository, passwords *passwords.Policy, logger zerolog.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		tokens:    tokens,
+		users:     users,
+		audit:     audit,
+		webhooks:  webhooks,
+		passwords: passwords,
+		logger:    logger.With().Str("handler", "scim").Logger(),
+	}
+}
+
+// GetToken describes the tenant's SCIM token without revealing it.
+func (h *SCIMHandler) GetToken(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	token, err := h.tokens.GetSCIMToken(tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, scimTokenNotActive, http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get SCIM token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// RotateToken issues a new SCIM token for the tenant, invalidating the previous
+// one. The plaintext token is only ever returned here.
+func (h *SCIMHandler) RotateToken(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	raw, err := generateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate SCIM token", http.StatusInternalServerError)
+		return
+	}
+	secret := scimTokenPrefix + raw
+
+	var createdBy *string
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		createdBy = &uid
+	}
+	token, err := h.tokens.SetSCIMToken(models.SCIMToken{
+		TenantID:    tenantID,
+		TokenPrefix: secret[:len(scimTokenPrefix)+6],
+		TokenHash:   hashToken(secret),
+		CreatedBy:   createdBy,
+	})
+	if err != nil {
+		http.Error(w, "Failed to store SCIM token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditSCIMTokenRotated,
+		TargetType: "scim_token",
+		TargetID:   tenantID,
+		Details:    map[string]interface{}{"token_prefix": token.TokenPrefix},
+	})
+	writeJSON(w, http.StatusCreated, struct {
+		models.SCIMToken
+		Token   string `json:"token"`
+		BaseURL string `json:"base_url"`
+	}{SCIMToken: token, Token: secret, BaseURL: scimBaseURL(r)})
+}
+
+// RevokeToken turns SCIM provisioning off for the tenant.
+func (h *SCIMHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	if err := h.tokens.DeleteSCIMToken(tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, scimTokenNotActive, http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke SCIM token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditSCIMTokenRevoked,
+		TargetType: "scim_token",
+		TargetID:   tenantID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Middleware authenticates identity providers by the tenant's SCIM bearer token.
+// Requests act in the token's tenant without a user.
+func (h *SCIMHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var secret string
+		if parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			secret = strings.TrimSpace(parts[1])
+		}
+		if !strings.HasPrefix(secret, scimTokenPrefix) {
+			scimError(w, http.StatusUnauthorized, "", "SCIM bearer token required")
+			return
+		}
+		token, err := h.tokens.AuthenticateSCIMToken(hashToken(secret))
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				h.logger.Error().Err(err).Msg("failed to authenticate SCIM token")
+			}
+			scimError(w, http.StatusUnauthorized, "", "Invalid SCIM token")
+			return
+		}
+		ctx := authz.WithIdentity(r.Context(), token.TenantID, "", nil)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ServiceProviderConfig tells identity providers which SCIM features are supported.
+func (h *SCIMHandler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]interface{} { return map[string]interface{}{"supported": ok} }
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The tenant's SCIM token, issued from the API's /scim/token endpoint",
+			"primary":     true,
+		}},
+		"meta": scimMeta{ResourceType: "ServiceProviderConfig", Location: scimBaseURL(r) + "/ServiceProviderConfig"},
+	})
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+// scimMultiValue is an entry of a multi-valued attribute such as emails or members.
+type scimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// scimPatchRequest is a SCIM PATCH body. Op is matched case-insensitively, as
+// some identity providers capitalize it.
+type scimPatchRequest struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// scimPage reads startIndex and count, 1-based as SCIM numbers results, and
+// returns the bounds of the requested page within total results.
+func scimPage(r *http.Request, total int) (startIndex, from, to int) {
+	q := r.URL.Query()
+	startIndex, count := 1, scimDefaultCount
+	if n, err := strconv.Atoi(q.Get("startIndex")); err == nil && n > 1 {
+		startIndex = n
+	}
+	if n, err := strconv.Atoi(q.Get("count")); err == nil {
+		count = max(0, min(n, scimMaxCount))
+	}
+	from = min(startIndex-1, total)
+	to = min(from+count, total)
+	return startIndex, from, to
+}
+
+// scimBaseURL is the absolute URL of the SCIM surface, which resource locations
+// and the token response point at.
+func scimBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + scimBasePath
+}
+
+func writeSCIM(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// scimError writes an error in the SCIM error format, which identity providers
+// parse instead of a plain-text body. scimType is omitted when empty.
+func scimError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+// parseSCIMBool reads a boolean sent either as JSON or, as Azure AD does in PATCH
+// operations, as a string.
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, errors.New("expected a boolean")
+	}
+	return strconv.ParseBool(strings.ToLower(strings.TrimSpace(s)))
+}
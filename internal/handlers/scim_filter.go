@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// scimFilter is a parsed SCIM filter (RFC 7644 section 3.4.2.2) in disjunctive
+// form: it matches a resource when every comparison of any one term does. Only
+// the subset identity providers send is supported: attribute comparisons joined
+// by "and" and "or", without grouping or value paths.
+type scimFilter [][]scimComparison
+
+type scimComparison struct {
+	attr  string // lowercased attribute path, e.g. "username" or "name.givenname"
+	op    string // eq, ne, co, sw, ew or pr
+	value interface{}
+}
+
+// scimAttributes returns a resource's values for a lowercased attribute path,
+// and false when the resource has no such attribute.
+type scimAttributes func(attr string) ([]interface{}, bool)
+
+// parseSCIMFilter parses expr, returning a nil filter for an empty expression.
+func parseSCIMFilter(expr string) (scimFilter, error) {
+	tokens, err := scanSCIMFilter(expr)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	var filter scimFilter
+	var term []scimComparison
+	for i := 0; i < len(tokens); {
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("incomplete comparison after %q", tokens[i].text)
+		}
+		attr, op := tokens[i], tokens[i+1]
+		if attr.quoted || op.quoted {
+			return nil, fmt.Errorf("expected an attribute and operator at %q", attr.text)
+		}
+		cmp := scimComparison{attr: scimAttrPath(attr.text), op: strings.ToLower(op.text)}
+		i += 2
+		switch cmp.op {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("missing value for %s %s", attr.text, op.text)
+			}
+			if cmp.value, err = tokens[i].value(); err != nil {
+				return nil, err
+			}
+			i++
+		case "gt", "ge", "lt", "le":
+			return nil, fmt.Errorf("operator %q is not supported", op.text)
+		default:
+			return nil, fmt.Errorf("unknown operator %q", op.text)
+		}
+		term = append(term, cmp)
+
+		if i == len(tokens) {
+			break
+		}
+		switch join := tokens[i]; {
+		case !join.quoted && strings.EqualFold(join.text, "and"):
+		case !join.quoted && strings.EqualFold(join.text, "or"):
+			filter, term = append(filter, term), nil
+		default:
+			return nil, fmt.Errorf("expected \"and\" or \"or\" at %q", join.text)
+		}
+		i++
+		if i == len(tokens) {
+			return nil, fmt.Errorf("filter ends with %q", tokens[i-1].text)
+		}
+	}
+	return append(filter, term), nil
+}
+
+// scimAttrPath lowercases an attribute path and drops its schema URN, so that
+// "urn:ietf:params:scim:schemas:core:2.0:User:userName" reads as "username".
+func scimAttrPath(path string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	if strings.HasPrefix(path, "urn:") {
+		path = path[strings.LastIndex(path, ":")+1:]
+	}
+	return path
+}
+
+// Match reports whether a resource passes the filter. A nil filter matches all.
+func (f scimFilter) Match(attrs scimAttributes) bool {
+	if f == nil {
+		return true
+	}
+	for _, term := range f {
+		matched := true
+		for _, cmp := range term {
+			if !cmp.match(attrs) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c scimComparison) match(attrs scimAttributes) bool {
+	values, ok := attrs(c.attr)
+	if !ok {
+		return false
+	}
+	if c.op == "pr" {
+		for _, v := range values {
+			if s, isString := v.(string); v != nil && (!isString || s != "") {
+				return true
+			}
+		}
+		return false
+	}
+	if c.op == "ne" {
+		return !scimComparison{attr: c.attr, op: "eq", value: c.value}.match(attrs)
+	}
+	for _, v := range values {
+		if c.compare(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare applies the operator to one value. Strings compare case-insensitively,
+// as every string attribute exposed here is case-insensitive in the SCIM schema.
+func (c scimComparison) compare(v interface{}) bool {
+	want, wantString := c.value.(string)
+	got, gotString := v.(string)
+	if !wantString || !gotString {
+		return c.op == "eq" && v == c.value
+	}
+	got, want = strings.ToLower(got), strings.ToLower(want)
+	switch c.op {
+	case "eq":
+		return got == want
+	case "co":
+		return strings.Contains(got, want)
+	case "sw":
+		return strings.HasPrefix(got, want)
+	case "ew":
+		return strings.HasSuffix(got, want)
+	}
+	return false
+}
+
+type scimFilterToken struct {
+	text   string
+	quoted bool
+}
+
+// value converts a comparison value token: a quoted string, true, false or null.
+func (t scimFilterToken) value() (interface{}, error) {
+	if t.quoted {
+		return t.text, nil
+	}
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("value %s must be a quoted string, true, false or null", t.text)
+}
+
+// scanSCIMFilter splits a filter into words and quoted strings.
+func scanSCIMFilter(expr string) ([]scimFilterToken, error) {
+	var tokens []scimFilterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			return nil, fmt.Errorf("grouping with %q is not supported", r)
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			i++
+			tokens = append(tokens, scimFilterToken{text: b.String(), quoted: true})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`"()[]`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, scimFilterToken{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// scimGroupRoles are the roles exposed as SCIM Groups. The group's id and
+// displayName are the role name. Super admins are never provisioned.
+var scimGroupRoles = []models.UserRole{models.RoleViewer, models.RoleEditor, models.RoleAdmin}
+
+func isSCIMGroup(role models.UserRole) bool {
+	return slices.Contains(scimGroupRoles, role)
+}
+
+// scimMemberFilterPath matches the value path a PATCH removes a single member with.
+var scimMemberFilterPath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]+)"\s*\]$`)
+
+type scimGroup struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id"`
+	DisplayName string           `json:"displayName"`
+	Members     []scimMultiValue `json:"members,omitempty"`
+	Meta        scimMeta         `json:"meta"`
+}
+
+// toSCIMGroup lists the users holding role as the group's members.
+func toSCIMGroup(base string, role models.UserRole, users []models.User) scimGroup {
+	group := scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          string(role),
+		DisplayName: string(role),
+		Meta:        scimMeta{ResourceType: "Group", Location: base + "/Groups/" + string(role)},
+	}
+	for _, user := range users {
+		if slices.Contains(user.Roles, role) {
+			group.Members = append(group.Members, scimMultiValue{Value: user.ID, Display: user.Email, Ref: base + "/Users/" + user.ID})
+		}
+	}
+	return group
+}
+
+// writeSCIMGroup writes a group, leaving out its members when the request
+// excludes them, as Azure AD does to keep group reads cheap.
+func writeSCIMGroup(w http.ResponseWriter, r *http.Request, group scimGroup) {
+	if excludesSCIMMembers(r) {
+		group.Members = nil
+	}
+	writeSCIM(w, http.StatusOK, group)
+}
+
+func excludesSCIMMembers(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members")
+}
+
+// attributes exposes the group to filters.
+func (g scimGroup) attributes(attr string) ([]interface{}, bool) {
+	switch attr {
+	case "id":
+		return []interface{}{g.ID}, true
+	case "displayname":
+		return []interface{}{g.DisplayName}, true
+	case "members", "members.value":
+		values := make([]interface{}, 0, len(g.Members))
+		for _, m := range g.Members {
+			values = append(values, m.Value)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// ListGroups lists the role groups matching the filter.
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := authz.TenantIDFromRequest(r)
+	filter, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		scimError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	users, err := h.users.ListUsersByTenant(tenantID)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Failed to list users: "+err.Error())
+		return
+	}
+
+	// Filters may name members, so they are matched before members are excluded.
+	base := scimBaseURL(r)
+	matched := []scimGroup{}
+	for _, role := range scimGroupRoles {
+		if group := toSCIMGroup(base, role, users); filter.Match(group.attributes) {
+			if excludesSCIMMembers(r) {
+				group.Members = nil
+			}
+			matched = append(matched, group)
+		}
+	}
+	startIndex, from, to := scimPage(r, len(matched))
+	writeSCIM(w, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: len(matched),
+		StartIndex:   startIndex,
+		ItemsPerPage: to - from,
+		Resources:    matched[from:to],
+	})
+}
+
+// GetGroup returns a role group and its members.
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	role, users, ok := h.loadGroup(w, r)
+	if !ok {
+		return
+	}
+	writeSCIMGroup(w, r, toSCIMGroup(scimBaseURL(r), role, users))
+}
+
+// ReplaceGroup sets a role group's members to exactly the ones sent.
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	role, users, ok := h.loadGroup(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		DisplayName string           `json:"displayName"`
+		Members     []scimMultiValue `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request payload")
+		return
+	}
+	if in.DisplayName != "" && in.DisplayName != string(role) {
+		scimError(w, http.StatusBadRequest, "mutability", "Groups are roles and cannot be renamed")
+		return
+	}
+	members := map[string]bool{}
+	for _, m := range in.Members {
+		members[m.Value] = true
+	}
+	h.setGroupMembers(w, r, role, users, members)
+}
+
+// PatchGroup adds and removes members of a role group, granting or revoking the
+// role. A user left without any role keeps the viewer role, so removing someone
+// from the viewer group alone does not take away their access; deactivate the
+// user for that.
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	role, users, ok := h.loadGroup(w, r)
+	if !ok {
+		return
+	}
+	var req scimPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Operations) == 0 {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "A PatchOp with at least one operation is required")
+		return
+	}
+
+	members := map[string]bool{}
+	for _, user := range users {
+		if slices.Contains(user.Roles, role) {
+			members[user.ID] = true
+		}
+	}
+	for _, op := range req.Operations {
+		path := strings.TrimSpace(op.Path)
+		value := op.Value
+		if path == "" {
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(value, &object); err != nil {
+				scimError(w, http.StatusBadRequest, "invalidValue", "value must be an object when no path is given")
+				return
+			}
+			for attr, v := range object {
+				switch scimAttrPath(attr) {
+				case "members":
+					path, value = "members", v
+				case "displayname":
+					var name string
+					if json.Unmarshal(v, &name) != nil || name != string(role) {
+						scimError(w, http.StatusBadRequest, "mutability", "Groups are roles and cannot be renamed")
+						return
+					}
+				}
+			}
+			if path == "" {
+				continue
+			}
+		}
+
+		var ids []string
+		if m := scimMemberFilterPath.FindStringSubmatch(path); m != nil {
+			ids = []string{m[1]}
+		} else if scimAttrPath(path) == "members" {
+			var err error
+			if ids, err = scimMemberIDs(value); err != nil {
+				scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+				return
+			}
+		} else if scimAttrPath(path) == "displayname" {
+			scimError(w, http.StatusBadRequest, "mutability", "Groups are roles and cannot be renamed")
+			return
+		} else {
+			scimError(w, http.StatusBadRequest, "invalidPath", "Unsupported path "+op.Path)
+			return
+		}
+
+		switch strings.ToLower(op.Op) {
+		case "add":
+			for _, id := range ids {
+				members[id] = true
+			}
+		case "remove":
+			if len(ids) == 0 {
+				clear(members)
+			}
+			for _, id := range ids {
+				delete(members, id)
+			}
+		case "replace":
+			clear(members)
+			for _, id := range ids {
+				members[id] = true
+			}
+		default:
+			scimError(w, http.StatusBadRequest, "invalidSyntax", "Unknown operation "+op.Op)
+			return
+		}
+	}
+	h.setGroupMembers(w, r, role, users, members)
+}
+
+// scimMemberIDs reads the user IDs of a members value, which may be absent.
+func scimMemberIDs(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var members []scimMultiValue
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, errors.New("members must be a list of {\"value\": userId}")
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.Value)
+	}
+	return ids, nil
+}
+
+// setGroupMembers grants role to the users in members and revokes it from the
+// others, keeping their other roles, then writes the group.
+func (h *SCIMHandler) setGroupMembers(w http.ResponseWriter, r *http.Request, role models.UserRole, users []models.User, members map[string]bool) {
+	known := make(map[string]bool, len(users))
+	for _, user := range users {
+		known[user.ID] = true
+	}
+	for id := range members {
+		if !known[id] {
+			scimError(w, http.StatusBadRequest, "invalidValue", "Unknown member "+id)
+			return
+		}
+	}
+
+	for i, user := range users {
+		if slices.Contains(user.Roles, role) == members[user.ID] {
+			continue
+		}
+		var roles []models.UserRole
+		for _, existing := range user.Roles {
+			if existing != role {
+				roles = append(roles, existing)
+			}
+		}
+		if members[user.ID] {
+			roles = append(roles, role)
+		}
+		roles = models.EnsureDefaultRole(roles)
+		if slices.Equal(roles, user.Roles) {
+			continue
+		}
+
+		updated, err := h.users.UpdateUserRoles(user.ID, roles)
+		if err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Failed to update roles: "+err.Error())
+			return
+		}
+		recordAudit(h.audit, h.logger, r, models.AuditEvent{
+			TenantID:   &updated.TenantID,
+			Action:     models.AuditUserRolesChanged,
+			TargetType: "user",
+			TargetID:   updated.ID,
+			Details:    map[string]interface{}{"previous_roles": user.Roles, "roles": updated.Roles, "source": "scim"},
+		})
+		emitWebhook(h.webhooks, h.logger, updated.TenantID, models.WebhookCategoryUser, models.WebhookEventRolesChanged,
+			map[string]interface{}{
+				"user_id":        updated.ID,
+				"email":          updated.Email,
+				"previous_roles": user.Roles,
+				"roles":          updated.Roles,
+			})
+		users[i] = updated
+	}
+	writeSCIMGroup(w, r, toSCIMGroup(scimBaseURL(r), role, users))
+}
+
+// loadGroup resolves the role group named in the path and lists the tenant's users.
+func (h *SCIMHandler) loadGroup(w http.ResponseWriter, r *http.Request) (models.UserRole, []models.User, bool) {
+	tenantID, _ := authz.TenantIDFromRequest(r)
+	role := models.UserRole(mux.Vars(r)["groupID"])
+	if !isSCIMGroup(role) {
+		scimError(w, http.StatusNotFound, "", "Group not found")
+		return "", nil, false
+	}
+	users, err := h.users.ListUsersByTenant(tenantID)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Failed to list users: "+err.Error())
+		return "", nil, false
+	}
+	return role, users, true
+}
@@ -0,0 +1,210 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+type scimUser struct {
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	Name     struct {
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Active bool `json:"active"`
+	Groups []struct {
+		Value string `json:"value"`
+	} `json:"groups"`
+}
+
+type scimUserList struct {
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+// scimToken rotates the tenant's SCIM token as its admin.
+func scimToken(t *testing.T, h *testutil.Harness, admin string) string {
+	t.Helper()
+	var rotated struct {
+		Token       string `json:"token"`
+		TokenPrefix string `json:"token_prefix"`
+	}
+	h.Decode(h.Do(http.MethodPost, "/api/v1/scim/token", nil, admin), http.StatusCreated, &rotated)
+	if rotated.Token == "" || rotated.TokenPrefix == "" {
+		t.Fatalf("rotated token = %+v", rotated)
+	}
+	return rotated.Token
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	h := testutil.NewHarness(t)
+	tenant, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	_, _, globexAdmin := h.SeedTenant("Globex", "admin@globex.test", models.RoleAdmin)
+
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users", nil, ""), http.StatusUnauthorized, nil)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/scim/token", nil, admin), http.StatusNotFound, nil)
+	token := scimToken(t, h, admin)
+	h.Decode(h.Do(http.MethodGet, "/api/v1/scim/token", nil, admin), http.StatusOK, nil)
+
+	var created scimUser
+	rec := h.Do(http.MethodPost, "/scim/v2/Users", map[string]interface{}{
+		"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"userName": "jane@acme.test",
+		"name":     map[string]string{"givenName": "Jane", "familyName": "Doe"},
+		"active":   true,
+	}, token)
+	h.Decode(rec, http.StatusCreated, &created)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/scim+json" {
+		t.Fatalf("content type = %q", ct)
+	}
+	if created.UserName != "jane@acme.test" || created.Name.GivenName != "Jane" || !created.Active ||
+		len(created.Groups) != 1 || created.Groups[0].Value != "viewer" {
+		t.Fatalf("created = %+v", created)
+	}
+	user, err := h.Store.Users().GetUserByID(created.ID)
+	if err != nil || user.TenantID != tenant.ID || user.EmailVerifiedAt == nil {
+		t.Fatalf("provisioned user = %+v, %v", user, err)
+	}
+	h.Decode(h.Do(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"userName": "jane@acme.test"}, token), http.StatusConflict, nil)
+	h.Decode(h.Do(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"userName": "bob@acme.test", "active": "False"}, token), http.StatusCreated, nil)
+
+	var list scimUserList
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq "JANE@acme.test"`), nil, token), http.StatusOK, &list)
+	if list.TotalResults != 1 || list.Resources[0].ID != created.ID {
+		t.Fatalf("filtered users = %+v", list)
+	}
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName sw "bob" or name.givenName eq "Jane"`), nil, token), http.StatusOK, &list)
+	if list.TotalResults != 2 {
+		t.Fatalf("or filter matched %d users", list.TotalResults)
+	}
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`active eq false and userName pr`), nil, token), http.StatusOK, &list)
+	if list.TotalResults != 1 || list.Resources[0].UserName != "bob@acme.test" {
+		t.Fatalf("inactive users = %+v", list)
+	}
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", nil, token), http.StatusOK, &list)
+	if list.TotalResults != 3 || list.StartIndex != 2 || list.ItemsPerPage != 1 || len(list.Resources) != 1 {
+		t.Fatalf("second page = %+v", list)
+	}
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName gt "a"`), nil, token), http.StatusBadRequest, nil)
+
+	// Another tenant's token cannot reach the user.
+	globexToken := scimToken(t, h, globexAdmin)
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users/"+created.ID, nil, globexToken), http.StatusNotFound, nil)
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users", nil, globexToken), http.StatusOK, &list)
+	if list.TotalResults != 1 {
+		t.Fatalf("globex sees %d users", list.TotalResults)
+	}
+
+	// Azure AD deactivates with a string boolean and capitalized op.
+	var patched scimUser
+	h.Decode(h.Do(http.MethodPatch, "/scim/v2/Users/"+created.ID, map[string]interface{}{
+		"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		"Operations": []map[string]interface{}{
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "value": map[string]interface{}{"name.familyName": "Smith"}},
+		},
+	}, token), http.StatusOK, &patched)
+	if patched.Active || patched.Name.FamilyName != "Smith" {
+		t.Fatalf("patched = %+v", patched)
+	}
+
+	var replaced scimUser
+	h.Decode(h.Do(http.MethodPut, "/scim/v2/Users/"+created.ID, map[string]interface{}{
+		"userName": "jane.smith@acme.test",
+		"name":     map[string]string{"givenName": "Jane", "familyName": "Smith"},
+		"active":   true,
+	}, token), http.StatusOK, &replaced)
+	if replaced.UserName != "jane.smith@acme.test" || !replaced.Active {
+		t.Fatalf("replaced = %+v", replaced)
+	}
+
+	h.Decode(h.Do(http.MethodDelete, "/scim/v2/Users/"+created.ID, nil, token), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users/"+created.ID, nil, token), http.StatusNotFound, nil)
+
+	actions := map[string]bool{}
+	for _, event := range h.Store.AuditEvents() {
+		if event.TargetID == created.ID {
+			actions[event.Action] = true
+		}
+	}
+	for _, action := range []string{models.AuditUserAdded, models.AuditUserUpdated, models.AuditUserDeleted} {
+		if !actions[action] {
+			t.Errorf("%s was not audited", action)
+		}
+	}
+
+	// Revoking the token ends provisioning.
+	h.Decode(h.Do(http.MethodDelete, "/api/v1/scim/token", nil, admin), http.StatusNoContent, nil)
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users", nil, token), http.StatusUnauthorized, nil)
+}
+
+func TestSCIMGroupsAssignRoles(t *testing.T) {
+	h := testutil.NewHarness(t)
+	_, _, admin := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	token := scimToken(t, h, admin)
+
+	var user scimUser
+	h.Decode(h.Do(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"userName": "jane@acme.test"}, token), http.StatusCreated, &user)
+
+	type groupList struct {
+		TotalResults int `json:"totalResults"`
+		Resources    []struct {
+			ID      string        `json:"id"`
+			Members []interface{} `json:"members"`
+		} `json:"Resources"`
+	}
+	var groups groupList
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Groups", nil, token), http.StatusOK, &groups)
+	if groups.TotalResults != 3 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Groups/super_admin", nil, token), http.StatusNotFound, nil)
+
+	patch := func(op string, value interface{}, path string) {
+		t.Helper()
+		h.Decode(h.Do(http.MethodPatch, "/scim/v2/Groups/editor", map[string]interface{}{
+			"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+			"Operations": []map[string]interface{}{{"op": op, "path": path, "value": value}},
+		}, token), http.StatusOK, nil)
+	}
+	patch("add", []map[string]string{{"value": user.ID}}, "members")
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Users/"+user.ID, nil, token), http.StatusOK, &user)
+	if len(user.Groups) != 2 {
+		t.Fatalf("groups after add = %+v", user.Groups)
+	}
+	var memberOf groupList
+	h.Decode(h.Do(http.MethodGet, "/scim/v2/Groups?filter="+url.QueryEscape(`members eq "`+user.ID+`"`)+"&excludedAttributes=members", nil, token), http.StatusOK, &memberOf)
+	if memberOf.TotalResults != 2 || len(memberOf.Resources[0].Members) != 0 {
+		t.Fatalf("groups of member = %+v", memberOf)
+	}
+
+	patch("remove", nil, `members[value eq "`+user.ID+`"]`)
+	stored, err := h.Store.Users().GetUserByID(user.ID)
+	if err != nil || len(stored.Roles) != 1 || stored.Roles[0] != models.RoleViewer {
+		t.Fatalf("roles after remove = %+v, %v", stored.Roles, err)
+	}
+
+	h.Decode(h.Do(http.MethodPatch, "/scim/v2/Groups/editor", map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "add", "path": "members", "value": []map[string]string{{"value": "unknown"}}}},
+	}, token), http.StatusBadRequest, nil)
+	h.Decode(h.Do(http.MethodPatch, "/scim/v2/Groups/editor", map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "displayName", "value": "owners"}},
+	}, token), http.StatusBadRequest, nil)
+
+	var changed bool
+	for _, event := range h.Store.AuditEvents() {
+		if event.Action == models.AuditUserRolesChanged && event.TargetID == user.ID {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Fatal("role change was not audited")
+	}
+}
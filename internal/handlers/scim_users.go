@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type scimName struct {
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// scimUser is a tenant user as a SCIM User. userName is the email address, and
+// groups are the user's roles.
+type scimUser struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id"`
+	UserName    string           `json:"userName"`
+	Name        scimName         `json:"name"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Emails      []scimMultiValue `json:"emails"`
+	Active      bool             `json:"active"`
+	Groups      []scimMultiValue `json:"groups"`
+	Meta        scimMeta         `json:"meta"`
+}
+
+func toSCIMUser(baseURL string, user models.User) scimUser {
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	groups := []scimMultiValue{}
+	for _, role := range user.Roles {
+		if isSCIMGroup(role) {
+			groups = append(groups, scimMultiValue{Value: string(role), Display: string(role), Ref: baseURL + "/Groups/" + string(role)})
+		}
+	}
+	return scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          user.ID,
+		UserName:    user.Email,
+		Name:        scimName{GivenName: user.FirstName, FamilyName: user.LastName, Formatted: displayName},
+		DisplayName: displayName,
+		Emails:      []scimMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      user.IsActive,
+		Groups:      groups,
+		Meta:        scimMeta{ResourceType: "User", Location: baseURL + "/Users/" + user.ID},
+	}
+}
+
+// attributes exposes the user to filters.
+func (u scimUser) attributes(attr string) ([]interface{}, bool) {
+	switch attr {
+	case "id":
+		return []interface{}{u.ID}, true
+	case "username", "emails", "emails.value":
+		return []interface{}{u.UserName}, true
+	case "name.givenname":
+		return []interface{}{u.Name.GivenName}, true
+	case "name.familyname":
+		return []interface{}{u.Name.FamilyName}, true
+	case "displayname", "name.formatted":
+		return []interface{}{u.DisplayName}, true
+	case "active":
+		return []interface{}{u.Active}, true
+	case "groups", "groups.value", "groups.display":
+		values := make([]interface{}, 0, len(u.Groups))
+		for _, g := range u.Groups {
+			values = append(values, g.Value)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// scimUserChange is the state a create, replace or patch leaves a user in.
+// Password is only set when the identity provider sends one.
+type scimUserChange struct {
+	email      string
+	givenName  string
+	familyName string
+	active     bool
+	password   string
+}
+
+func scimUserChangeFrom(user models.User) scimUserChange {
+	return scimUserChange{email: user.Email, givenName: user.FirstName, familyName: user.LastName, active: user.IsActive}
+}
+
+// scimUserInput is the User resource identity providers send on create and replace.
+type scimUserInput struct {
+	UserName string `json:"userName"`
+	Name     *struct {
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Active   json.RawMessage `json:"active"`
+	Password string          `json:"password"`
+}
+
+// apply replaces the change's attributes with the input's. Active is left as it
+// is when the input omits it.
+func (in scimUserInput) apply(change *scimUserChange) error {
+	change.email = strings.TrimSpace(in.UserName)
+	if change.email == "" {
+		for _, email := range in.Emails {
+			if change.email == "" || email.Primary {
+				change.email = strings.TrimSpace(email.Value)
+			}
+		}
+	}
+	change.givenName, change.familyName = "", ""
+	if in.Name != nil {
+		change.givenName, change.familyName = strings.TrimSpace(in.Name.GivenName), strings.TrimSpace(in.Name.FamilyName)
+	}
+	if len(in.Active) > 0 && string(in.Active) != "null" {
+		active, err := parseSCIMBool(in.Active)
+		if err != nil {
+			return errors.New("active must be a boolean")
+		}
+		change.active = active
+	}
+	change.password = in.Password
+	if change.email == "" {
+		return errors.New("userName is required")
+	}
+	return nil
+}
+
+// ListUsers lists the tenant's users matching the filter, a page at a time.
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := authz.TenantIDFromRequest(r)
+	filter, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		scimError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	users, err := h.users.ListUsersByTenant(tenantID)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Failed to list users: "+err.Error())
+		return
+	}
+
+	base := scimBaseURL(r)
+	matched := []scimUser{}
+	for _, user := range users {
+		if u := toSCIMUser(base, user); filter.Match(u.attributes) {
+			matched = append(matched, u)
+		}
+	}
+	startIndex, from, to := scimPage(r, len(matched))
+	writeSCIM(w, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: len(matched),
+		StartIndex:   startIndex,
+		ItemsPerPage: to - from,
+		Resources:    matched[from:to],
+	})
+}
+
+// GetUser returns one of the tenant's users.
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(scimBaseURL(r), user))
+}
+
+// CreateUser provisions a user. The identity provider owns the email address, so
+// it counts as verified, and a user created without a password signs in only
+// once one is set.
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := authz.TenantIDFromRequest(r)
+	var in scimUserInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request payload")
+		return
+	}
+	change := scimUserChange{active: true}
+	if err := in.apply(&change); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if _, err := h.users.GetUserByEmail(change.email); err == nil {
+		scimError(w, http.StatusConflict, "uniqueness", "A user with this userName already exists")
+		return
+	}
+
+	password := change.password
+	if password != "" {
+		if !h.checkSCIMPassword(w, r, password) {
+			return
+		}
+	} else {
+		random, err := generateToken()
+		if err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Failed to generate password")
+			return
+		}
+		password = random
+	}
+
+	create := h.users.CreateUser
+	if !change.active {
+		create = h.users.CreateInactiveUser
+	}
+	user, err := create(tenantID, change.email, password, change.givenName, change.familyName, []models.UserRole{models.RoleViewer})
+	if err != nil {
+		if isDuplicateUser(err) {
+			scimError(w, http.StatusConflict, "uniqueness", "A user with this userName already exists")
+			return
+		}
+		scimError(w, http.StatusInternalServerError, "", "Failed to create user: "+err.Error())
+		return
+	}
+	if err := h.users.MarkEmailVerified(user.ID); err != nil {
+		h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to mark provisioned email verified")
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &user.TenantID,
+		Action:     models.AuditUserAdded,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"email": user.Email, "roles": user.Roles, "source": "scim"},
+	})
+	emitWebhook(h.webhooks, h.logger, user.TenantID, models.WebhookCategoryUser, models.WebhookEventUserCreated,
+		map[string]interface{}{"user_id": user.ID, "email": user.Email, "roles": user.Roles})
+
+	writeSCIM(w, http.StatusCreated, toSCIMUser(scimBaseURL(r), user))
+}
+
+// ReplaceUser replaces a user's attributes with the ones sent.
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	var in scimUserInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request payload")
+		return
+	}
+	change := scimUserChangeFrom(user)
+	if err := in.apply(&change); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if user, ok = h.applyUserChange(w, r, user, change); !ok {
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(scimBaseURL(r), user))
+}
+
+// PatchUser applies PATCH operations to a user. Deactivating a user this way is
+// how identity providers unassign one. Attributes this API does not store are
+// ignored.
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	var req scimPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Operations) == 0 {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "A PatchOp with at least one operation is required")
+		return
+	}
+
+	change := scimUserChangeFrom(user)
+	for _, op := range req.Operations {
+		var err error
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path == "" {
+				err = patchSCIMUserObject(&change, op.Value)
+			} else {
+				err = patchSCIMUserPath(&change, scimAttrPath(op.Path), op.Value)
+			}
+		case "remove":
+			err = patchSCIMUserPath(&change, scimAttrPath(op.Path), nil)
+		default:
+			scimError(w, http.StatusBadRequest, "invalidSyntax", "Unknown operation "+op.Op)
+			return
+		}
+		if err != nil {
+			scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	if user, ok = h.applyUserChange(w, r, user, change); !ok {
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(scimBaseURL(r), user))
+}
+
+// patchSCIMUserObject applies a pathless operation, whose value maps attribute
+// paths to values.
+func patchSCIMUserObject(change *scimUserChange, raw json.RawMessage) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return errors.New("value must be an object when no path is given")
+	}
+	for attr, value := range values {
+		if scimAttrPath(attr) == "name" {
+			var name map[string]json.RawMessage
+			if err := json.Unmarshal(value, &name); err != nil {
+				return errors.New("name must be an object")
+			}
+			for sub, v := range name {
+				if err := patchSCIMUserPath(change, "name."+strings.ToLower(sub), v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := patchSCIMUserPath(change, scimAttrPath(attr), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchSCIMUserPath sets one attribute, or clears it when raw is nil.
+func patchSCIMUserPath(change *scimUserChange, path string, raw json.RawMessage) error {
+	text := func() (string, error) {
+		if raw == nil {
+			return "", nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", errors.New(path + " must be a string")
+		}
+		return strings.TrimSpace(s), nil
+	}
+	var err error
+	switch path {
+	case "active":
+		if raw == nil {
+			return errors.New("active cannot be removed")
+		}
+		if change.active, err = parseSCIMBool(raw); err != nil {
+			return errors.New("active must be a boolean")
+		}
+	case "username", `emails[type eq "work"].value`, "emails[primary eq true].value":
+		email, err := text()
+		if err != nil {
+			return err
+		}
+		if email == "" {
+			return errors.New("userName cannot be removed")
+		}
+		change.email = email
+	case "emails":
+		var emails []struct {
+			Value   string `json:"value"`
+			Primary bool   `json:"primary"`
+		}
+		if raw == nil || json.Unmarshal(raw, &emails) != nil || len(emails) == 0 {
+			return errors.New("emails must list at least one address")
+		}
+		for i, email := range emails {
+			if i == 0 || email.Primary {
+				change.email = strings.TrimSpace(email.Value)
+			}
+		}
+	case "name.givenname":
+		change.givenName, err = text()
+	case "name.familyname":
+		change.familyName, err = text()
+	case "password":
+		if change.password, err = text(); err == nil && change.password == "" {
+			return errors.New("password cannot be removed")
+		}
+	}
+	return err
+}
+
+// DeleteUser deprovisions a user. The user is deleted like one removed from the
+// tenant; identity providers that only deactivate use PATCH instead.
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.users.DeleteUser(user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			scimError(w, http.StatusNotFound, "", "User not found")
+			return
+		}
+		scimError(w, http.StatusInternalServerError, "", "Failed to delete user: "+err.Error())
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &user.TenantID,
+		Action:     models.AuditUserDeleted,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"roles": user.Roles, "source": "scim"},
+	})
+	emitWebhook(h.webhooks, h.logger, user.TenantID, models.WebhookCategoryUser, models.WebhookEventUserDeactivated,
+		map[string]interface{}{"user_id": user.ID, "email": user.Email})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUser loads the user named in the path, answering 404 for users of other
+// tenants.
+func (h *SCIMHandler) loadUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	tenantID, _ := authz.TenantIDFromRequest(r)
+	user, err := h.users.GetUserByID(mux.Vars(r)["userID"])
+	if err != nil || user.TenantID != tenantID {
+		if err == nil || isNotFound(err) {
+			scimError(w, http.StatusNotFound, "", "User not found")
+			return models.User{}, false
+		}
+		scimError(w, http.StatusInternalServerError, "", "Failed to get user: "+err.Error())
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// applyUserChange saves what changed between user and change, and returns the
+// updated user.
+func (h *SCIMHandler) applyUserChange(w http.ResponseWriter, r *http.Request, user models.User, change scimUserChange) (models.User, bool) {
+	var changed []string
+	updated := user
+	if !strings.EqualFold(change.email, user.Email) {
+		if existing, err := h.users.GetUserByEmail(change.email); err == nil && existing.ID != user.ID {
+			scimError(w, http.StatusConflict, "uniqueness", "A user with this userName already exists")
+			return user, false
+		}
+		var err error
+		if updated, err = h.users.UpdateUserEmail(user.ID, change.email); err != nil {
+			if isDuplicateUser(err) {
+				scimError(w, http.StatusConflict, "uniqueness", "A user with this userName already exists")
+				return user, false
+			}
+			scimError(w, http.StatusInternalServerError, "", "Failed to update email: "+err.Error())
+			return user, false
+		}
+		if err := h.users.MarkEmailVerified(user.ID); err != nil {
+			h.logger.Warn().Err(err).Str("user_id", user.ID).Msg("failed to mark provisioned email verified")
+		}
+		changed = append(changed, "email")
+	}
+	if change.givenName != user.FirstName || change.familyName != user.LastName || change.active != user.IsActive {
+		var err error
+		if updated, err = h.users.UpdateUserProfile(user.TenantID, user.ID, change.givenName, change.familyName, change.active); err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Failed to update user: "+err.Error())
+			return user, false
+		}
+		if change.givenName != user.FirstName || change.familyName != user.LastName {
+			changed = append(changed, "name")
+		}
+		if change.active != user.IsActive {
+			changed = append(changed, "active")
+		}
+	}
+	if change.password != "" {
+		if !h.checkSCIMPassword(w, r, change.password) {
+			return user, false
+		}
+		if err := h.users.UpdatePassword(user.ID, change.password); err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Failed to update password: "+err.Error())
+			return user, false
+		}
+		changed = append(changed, "password")
+	}
+	if len(changed) == 0 {
+		return user, true
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &user.TenantID,
+		Action:     models.AuditUserUpdated,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"changed": changed, "active": updated.IsActive, "source": "scim"},
+	})
+	if user.IsActive && !updated.IsActive {
+		emitWebhook(h.webhooks, h.logger, user.TenantID, models.WebhookCategoryUser, models.WebhookEventUserDeactivated,
+			map[string]interface{}{"user_id": user.ID, "email": updated.Email})
+	}
+	return updated, true
+}
+
+// checkSCIMPassword applies the password policy to a password the identity
+// provider sets.
+func (h *SCIMHandler) checkSCIMPassword(w http.ResponseWriter, r *http.Request, password string) bool {
+	if h.passwords == nil {
+		return true
+	}
+	if err := h.passwords.Validate(r.Context(), password); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return false
+	}
+	return true
+}
+
+// isDuplicateUser reports whether creating or renaming a user failed because the
+// email address is taken.
+func isDuplicateUser(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "duplicate") || strings.Contains(msg, "already exists")
+}
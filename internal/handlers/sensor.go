@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const (
+	defaultSensorEvaluationLimit = 100
+	maxSensorEvaluationLimit     = 1000
+)
+
+// SensorHandler manages the sensors a definition's runs wait on and their
+// evaluation history.
+type SensorHandler struct {
+	sensors repository.SensorRepository
+	conns   repository.ConnectionRepository
+	logger  zerolog.Logger
+}
+
+type sensorRequest struct {
+	Name                string          `json:"name"`
+	Kind                string          `json:"kind"`
+	Config              json.RawMessage `json:"config"`
+	PokeIntervalSeconds int             `json:"poke_interval_seconds"`
+	TimeoutSeconds      int             `json:"timeout_seconds"`
+	OnTimeout           string          `json:"on_timeout"`
+	Enabled             *bool           `json:"enabled"`
+}
+
+func NewSensorHandler(sensors repository.SensorRepository, conns repository.ConnectionRepository, logger zerolog.Logger) *SensorHandler {
+	return &SensorHandler{sensors: sensors, conns: conns, logger: logger}
+}
+
+func (h *SensorHandler) List(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	sensors, err := h.sensors.ListSensors(tid, mux.Vars(r)["jobID"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to list sensors: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, sensors)
+}
+
+func (h *SensorHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req sensorRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	sensor := models.JobSensor{
+		TenantID:        tid,
+		JobDefinitionID: mux.Vars(r)["jobID"],
+		Enabled:         true,
+	}
+	if !h.applySensorRequest(w, &sensor, req) {
+		return
+	}
+	created, err := h.sensors.CreateSensor(sensor)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A sensor with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create sensor: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// Update replaces a sensor's settings; omitted fields keep their values.
+func (h *SensorHandler) Update(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	current, err := h.sensors.GetSensor(tid, vars["jobID"], vars["sensorID"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Sensor not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load sensor: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req sensorRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !h.applySensorRequest(w, &current, req) {
+		return
+	}
+	updated, err := h.sensors.UpdateSensor(current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Sensor not found", http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A sensor with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update sensor: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *SensorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	if err := h.sensors.DeleteSensor(tid, vars["jobID"], vars["sensorID"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Sensor not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete sensor: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListEvaluations returns the definition's sensor evaluations, newest first.
+// ?sensor_id= and ?execution_id= narrow the list; ?limit= caps it.
+func (h *SensorHandler) ListEvaluations(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	limit := defaultSensorEvaluationLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if n > maxSensorEvaluationLimit {
+			n = maxSensorEvaluationLimit
+		}
+		limit = n
+	}
+	evals, err := h.sensors.ListSensorEvaluations(tid, mux.Vars(r)["jobID"], q.Get("sensor_id"), q.Get("execution_id"), limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to list sensor evaluations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, evals)
+}
+
+// applySensorRequest copies the request onto the sensor and validates the result.
+// A row_count sensor must name one of the tenant's connections.
+func (h *SensorHandler) applySensorRequest(w http.ResponseWriter, s *models.JobSensor, req sensorRequest) bool {
+	if req.Name != "" {
+		s.Name = req.Name
+	}
+	if req.Kind != "" {
+		s.Kind = req.Kind
+	}
+	if len(req.Config) > 0 {
+		s.Config = req.Config
+	}
+	if req.PokeIntervalSeconds != 0 {
+		s.PokeIntervalSeconds = req.PokeIntervalSeconds
+	}
+	if req.TimeoutSeconds != 0 {
+		s.TimeoutSeconds = req.TimeoutSeconds
+	}
+	if req.OnTimeout != "" {
+		s.OnTimeout = req.OnTimeout
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	if err := s.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if s.Kind == models.SensorKindRowCount {
+		var cfg models.RowCountSensorConfig
+		_ = json.Unmarshal(s.Config, &cfg)
+		if _, err := h.conns.Get(s.TenantID, cfg.ConnectionID); err != nil {
+			if isNotFound(err) {
+				http.Error(w, "Connection not found", http.StatusBadRequest)
+				return false
+			}
+			http.Error(w, "Failed to load connection: "+err.Error(), http.StatusInternalServerError)
+			return false
+		}
+	}
+	return true
+}
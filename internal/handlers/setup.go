@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/passwords"
+	"github.com/stanstork/stratum-api/internal/repository"
+	tc "go.temporal.io/sdk/client"
+)
+
+const setupCheckTimeout = 5 * time.Second
+
+// SetupHandler serves the first-run wizard of a self-hosted instance. Its endpoints
+// are unauthenticated and only work until setup completes.
+type SetupHandler struct {
+	instances      repository.InstanceRepository
+	audit          repository.AuditRepository
+	mailer         *notification.SMTPInviteMailer
+	hosts          *engine.HostPool
+	temporalClient tc.Client
+	passwords      *passwords.Policy
+	logger         zerolog.Logger
+}
+
+type setupRequest struct {
+	InstanceName string `json:"instance_name"`
+	TenantName   string `json:"tenant_name"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+}
+
+type setupStatusResponse struct {
+	SetupRequired bool             `json:"setup_required"`
+	Instance      *models.Instance `json:"instance,omitempty"`
+}
+
+type setupChecksResponse struct {
+	OK     bool                `json:"ok"`
+	Checks []models.SetupCheck `json:"checks"`
+}
+
+func NewSetupHandler(instances repository.InstanceRepository, audit repository.AuditRepository, mailer *notification.SMTPInviteMailer, hosts *engine.HostPool, temporalClient tc.Client, policy *passwords.Policy, logger zerolog.Logger) *SetupHandler {
+	return &SetupHandler{
+		instances:      instances,
+		audit:          audit,
+		mailer:         mailer,
+		hosts:          hosts,
+		temporalClient: temporalClient,
+		passwords:      policy,
+		logger:         logger.With().Str("component", "setup").Logger(),
+	}
+}
+
+// Status tells the wizard whether setup is still open.
+func (h *SetupHandler) Status(w http.ResponseWriter, r *http.Request) {
+	required, err := h.instances.SetupRequired()
+	if err != nil {
+		http.Error(w, "Failed to check setup status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := setupStatusResponse{SetupRequired: required}
+	if !required {
+		inst, err := h.instances.GetInstance()
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Failed to load instance: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			resp.Instance = &inst
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Checks probes SMTP, the engine hosts and Temporal so the wizard can show what
+// still needs fixing before setup is submitted.
+func (h *SetupHandler) Checks(w http.ResponseWriter, r *http.Request) {
+	if !h.requireOpen(w) {
+		return
+	}
+	checks, ok := h.runChecks(r.Context())
+	writeJSON(w, http.StatusOK, setupChecksResponse{OK: ok, Checks: checks})
+}
+
+// Setup creates the first tenant and its super-admin, stores the instance name and
+// locks setup for good. It refuses to run while a dependency check fails.
+func (h *SetupHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	if !h.requireOpen(w) {
+		return
+	}
+
+	var req setupRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.InstanceName = strings.TrimSpace(req.InstanceName)
+	req.TenantName = strings.TrimSpace(req.TenantName)
+	req.Email = strings.TrimSpace(req.Email)
+	switch {
+	case req.InstanceName == "":
+		http.Error(w, "instance_name is required", http.StatusBadRequest)
+		return
+	case req.TenantName == "":
+		http.Error(w, "tenant_name is required", http.StatusBadRequest)
+		return
+	case !strings.Contains(req.Email, "@"):
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+	if !checkPassword(w, r, h.passwords, req.Password) {
+		return
+	}
+
+	checks, ok := h.runChecks(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusFailedDependency, setupChecksResponse{OK: false, Checks: checks})
+		return
+	}
+
+	inst, tenant, user, err := h.instances.Bootstrap(repository.BootstrapInput{
+		InstanceName: req.InstanceName,
+		TenantName:   req.TenantName,
+		Email:        req.Email,
+		Password:     req.Password,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrSetupLocked) {
+			http.Error(w, "Instance setup is already complete", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to set up instance: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenant.ID,
+		ActorID:    &user.ID,
+		Action:     models.AuditInstanceSetupCompleted,
+		TargetType: "instance",
+		TargetID:   tenant.ID,
+		Details:    map[string]interface{}{"instance_name": inst.Name, "tenant_name": tenant.Name},
+	})
+	h.logger.Info().Str("tenant_id", tenant.ID).Str("user_id", user.ID).Msg("instance setup completed")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"instance": inst,
+		"tenant":   tenant,
+		"user":     models.User{ID: user.ID, Email: user.Email, TenantID: user.TenantID, Roles: user.Roles},
+		"checks":   checks,
+	})
+}
+
+// requireOpen rejects the request once setup has completed.
+func (h *SetupHandler) requireOpen(w http.ResponseWriter) bool {
+	required, err := h.instances.SetupRequired()
+	if err != nil {
+		http.Error(w, "Failed to check setup status: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !required {
+		http.Error(w, "Instance setup is already complete", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// runChecks probes each dependency and reports whether all of them passed.
+func (h *SetupHandler) runChecks(ctx context.Context) ([]models.SetupCheck, bool) {
+	checks := []models.SetupCheck{
+		h.check(ctx, "smtp", h.mailer != nil, func(ctx context.Context) error {
+			return h.mailer.Check(ctx)
+		}),
+		h.check(ctx, "engine", h.hosts != nil, func(ctx context.Context) error {
+			var errs []string
+			for _, st := range h.hosts.Statuses(ctx) {
+				if st.Reachable {
+					return nil
+				}
+				errs = append(errs, st.Name+": "+st.Error)
+			}
+			return errors.New("no docker host is reachable: " + strings.Join(errs, "; "))
+		}),
+		h.check(ctx, "temporal", h.temporalClient != nil, func(ctx context.Context) error {
+			_, err := h.temporalClient.CheckHealth(ctx, &tc.CheckHealthRequest{})
+			return err
+		}),
+	}
+	ok := true
+	for _, c := range checks {
+		if c.Status == models.SetupCheckFailed {
+			ok = false
+		}
+	}
+	return checks, ok
+}
+
+func (h *SetupHandler) check(ctx context.Context, name string, configured bool, probe func(context.Context) error) models.SetupCheck {
+	if !configured {
+		return models.SetupCheck{Name: name, Status: models.SetupCheckSkipped}
+	}
+	ctx, cancel := context.WithTimeout(ctx, setupCheckTimeout)
+	defer cancel()
+	if err := probe(ctx); err != nil {
+		h.logger.Warn().Err(err).Str("check", name).Msg("setup check failed")
+		return models.SetupCheck{Name: name, Status: models.SetupCheckFailed, Error: err.Error()}
+	}
+	return models.SetupCheck{Name: name, Status: models.SetupCheckOK}
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// TemplateHandler manages the tenant's job templates and the curated global ones.
+type TemplateHandler struct {
+	templates repository.TemplateRepository
+	conns     repository.ConnectionRepository
+	logger    zerolog.Logger
+}
+
+type templateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Kind        string          `json:"kind"`
+	AST         json.RawMessage `json:"ast"`
+	// Global creates a curated template shared by every tenant (super admins only).
+	Global bool `json:"global"`
+}
+
+// applyTemplateRequest carries the placeholder values for rendering a template or
+// creating a definition from it. The connection placeholders are filled with the
+// names of the chosen connections.
+type applyTemplateRequest struct {
+	Name                    string            `json:"name"`
+	Description             string            `json:"description"`
+	SourceConnectionID      string            `json:"source_connection_id"`
+	DestinationConnectionID string            `json:"destination_connection_id"`
+	Params                  map[string]string `json:"params"`
+	Status                  string            `json:"status"`
+}
+
+func NewTemplateHandler(templates repository.TemplateRepository, conns repository.ConnectionRepository, logger zerolog.Logger) *TemplateHandler {
+	return &TemplateHandler{templates: templates, conns: conns, logger: logger}
+}
+
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && !models.ValidTemplateKind(kind) {
+		http.Error(w, "kind must be job or fragment", http.StatusBadRequest)
+		return
+	}
+	templates, err := h.templates.ListTemplates(tid, kind)
+	if err != nil {
+		http.Error(w, "Failed to list templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tpl, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req templateRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	tpl, ok := templateFromRequest(w, req)
+	if !ok {
+		return
+	}
+	if req.Global {
+		roles, _ := authz.RolesFromRequest(r)
+		if !models.HasAtLeast(roles, models.RoleSuperAdmin) {
+			http.Error(w, "only super admins can create global templates", http.StatusForbidden)
+			return
+		}
+	} else {
+		tpl.TenantID = &tid
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		tpl.CreatedBy = &uid
+	}
+
+	created, err := h.templates.CreateTemplate(tpl)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A template with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadForChange(w, r)
+	if !ok {
+		return
+	}
+	var req templateRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	tpl, ok := templateFromRequest(w, req)
+	if !ok {
+		return
+	}
+	tpl.ID = current.ID
+	tpl.TenantID = current.TenantID
+
+	updated, err := h.templates.UpdateTemplate(tpl)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A template with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadForChange(w, r)
+	if !ok {
+		return
+	}
+	if err := h.templates.DeleteTemplate(current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Render returns the template's AST with its placeholders filled in, e.g. for
+// splicing a fragment into a definition being edited.
+func (h *TemplateHandler) Render(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	tpl, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req applyTemplateRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	ast, ok := renderTemplate(w, h.conns, tid, tpl, req)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ast": ast})
+}
+
+func (h *TemplateHandler) load(w http.ResponseWriter, r *http.Request) (models.JobTemplate, bool) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return models.JobTemplate{}, false
+	}
+	tpl, err := h.templates.GetTemplate(tid, mux.Vars(r)["templateID"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return models.JobTemplate{}, false
+		}
+		http.Error(w, "Failed to load template: "+err.Error(), http.StatusInternalServerError)
+		return models.JobTemplate{}, false
+	}
+	return tpl, true
+}
+
+// loadForChange loads a template the caller may modify: global templates are
+// curated by super admins.
+func (h *TemplateHandler) loadForChange(w http.ResponseWriter, r *http.Request) (models.JobTemplate, bool) {
+	tpl, ok := h.load(w, r)
+	if !ok {
+		return tpl, false
+	}
+	if tpl.Global {
+		roles, _ := authz.RolesFromRequest(r)
+		if !models.HasAtLeast(roles, models.RoleSuperAdmin) {
+			http.Error(w, "only super admins can change global templates", http.StatusForbidden)
+			return tpl, false
+		}
+	}
+	return tpl, true
+}
+
+// templateFromRequest validates a template payload. Templates may reference
+// definition secrets but never carry their values.
+func templateFromRequest(w http.ResponseWriter, req templateRequest) (models.JobTemplate, bool) {
+	tpl := models.JobTemplate{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Kind:        strings.ToLower(strings.TrimSpace(req.Kind)),
+		AST:         req.AST,
+	}
+	if tpl.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return tpl, false
+	}
+	if tpl.Kind == "" {
+		tpl.Kind = models.TemplateKindJob
+	}
+	if !models.ValidTemplateKind(tpl.Kind) {
+		http.Error(w, "kind must be job or fragment", http.StatusBadRequest)
+		return tpl, false
+	}
+	trimmed := bytes.TrimSpace(tpl.AST)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		http.Error(w, "ast is required", http.StatusBadRequest)
+		return tpl, false
+	}
+	if tpl.Kind == models.TemplateKindJob && trimmed[0] != '{' {
+		http.Error(w, "ast of a job template must be an object", http.StatusBadRequest)
+		return tpl, false
+	}
+	_, secrets, err := models.ExtractASTSecrets(tpl.AST)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return tpl, false
+	}
+	if len(secrets) > 0 {
+		http.Error(w, "templates cannot contain secret values; reference secrets by name only", http.StatusBadRequest)
+		return tpl, false
+	}
+	return tpl, true
+}
+
+// renderTemplate fills the template's placeholders from the request parameters and
+// the names of the chosen connections.
+func renderTemplate(w http.ResponseWriter, conns repository.ConnectionRepository, tenantID string, tpl models.JobTemplate, req applyTemplateRequest) (json.RawMessage, bool) {
+	values := make(map[string]string, len(req.Params)+2)
+	for name, value := range req.Params {
+		values[name] = value
+	}
+	connections := []struct {
+		placeholder string
+		id          string
+		role        string
+	}{
+		{models.PlaceholderSourceConnection, req.SourceConnectionID, "Source"},
+		{models.PlaceholderDestinationConnection, req.DestinationConnectionID, "Destination"},
+	}
+	for _, c := range connections {
+		id := strings.TrimSpace(c.id)
+		if id == "" {
+			continue
+		}
+		conn, err := conns.Get(tenantID, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, c.role+" connection not found", http.StatusBadRequest)
+				return nil, false
+			}
+			http.Error(w, "Failed to get connection: "+err.Error(), http.StatusInternalServerError)
+			return nil, false
+		}
+		values[c.placeholder] = conn.Name
+	}
+
+	ast, err := models.RenderTemplate(tpl.AST, values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return ast, true
+}
+
+// CreateFromTemplate creates a definition from a job template, READY unless the
+// request asks for a draft. The name defaults to the template's.
+func (h *JobHandler) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	var req applyTemplateRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	tpl, err := h.templates.GetTemplate(tid, mux.Vars(r)["templateID"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tpl.Kind != models.TemplateKindJob {
+		http.Error(w, "Only job templates can create definitions", http.StatusBadRequest)
+		return
+	}
+	ast, ok := renderTemplate(w, h.connRepo, tid, tpl, req)
+	if !ok {
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = tpl.Name
+	}
+	description := req.Description
+	if description == "" {
+		description = tpl.Description
+	}
+	h.createDefinition(w, tid, createDefinitionPayload{
+		Name:                    name,
+		Description:             description,
+		AST:                     ast,
+		SourceConnectionID:      req.SourceConnectionID,
+		DestinationConnectionID: req.DestinationConnectionID,
+		Status:                  req.Status,
+	})
+}
@@ -1,23 +1,38 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/passwords"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/storage"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/workflows"
+	"github.com/stanstork/stratum-api/internal/utils"
+
+	tc "go.temporal.io/sdk/client"
 )
 
 type TenantHandler struct {
-	tenantRepo repository.TenantRepository
-	userRepo   repository.UserRepository
-	logger     zerolog.Logger
+	tenantRepo     repository.TenantRepository
+	userRepo       repository.UserRepository
+	audit          repository.AuditRepository
+	webhooks       repository.WebhookRepository
+	residency      *storage.Residency
+	temporalClient tc.Client
+	deletionGrace  time.Duration
+	passwords      *passwords.Policy
+	logger         zerolog.Logger
 }
 
 type tenantUserResponse struct {
@@ -30,11 +45,17 @@ type tenantUserResponse struct {
 	Roles     []models.UserRole `json:"roles"`
 }
 
-func NewTenantHandler(tenantRepo repository.TenantRepository, userRepo repository.UserRepository, logger zerolog.Logger) *TenantHandler {
+func NewTenantHandler(tenantRepo repository.TenantRepository, userRepo repository.UserRepository, audit repository.AuditRepository, webhooks repository.WebhookRepository, residency *storage.Residency, temporalClient tc.Client, deletionGrace time.Duration, passwords *passwords.Policy, logger zerolog.Logger) *TenantHandler {
 	return &TenantHandler{
-		tenantRepo: tenantRepo,
-		userRepo:   userRepo,
-		logger:     logger,
+		tenantRepo:     tenantRepo,
+		userRepo:       userRepo,
+		audit:          audit,
+		webhooks:       webhooks,
+		residency:      residency,
+		temporalClient: temporalClient,
+		deletionGrace:  deletionGrace,
+		passwords:      passwords,
+		logger:         logger,
 	}
 }
 
@@ -67,6 +88,105 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tenant)
 }
 
+func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	requesterRoles, _ := authz.RolesFromRequest(r)
+	isSuperAdmin := models.HasAtLeast(requesterRoles, models.RoleSuperAdmin)
+
+	tenantID := mux.Vars(r)["tenantID"]
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isSuperAdmin {
+		if tid, ok := authz.TenantIDFromRequest(r); !ok || tid != tenantID {
+			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			return
+		}
+	}
+
+	var payload struct {
+		RequireVerifiedEmail *bool   `json:"require_verified_email"`
+		Timezone             *string `json:"timezone"`
+		Locale               *string `json:"locale"`
+		DataRegion           *string `json:"data_region"`
+		// BlockCrossEnvironmentJobs refuses definitions that mix connection environments.
+		BlockCrossEnvironmentJobs *bool `json:"block_cross_environment_jobs"`
+		// RequireArtifactScan quarantines execution artifacts that cannot be scanned.
+		RequireArtifactScan *bool `json:"require_artifact_scan"`
+		// RequireRunApproval holds runs until another admin approves them.
+		RequireRunApproval *bool `json:"require_run_approval"`
+		// ValidationFreshness is off, warn or block for definitions whose source
+		// schema drifted since they were validated.
+		ValidationFreshness *string `json:"validation_freshness"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if payload.Timezone != nil {
+		tz := strings.TrimSpace(*payload.Timezone)
+		if err := utils.ValidateTimezone(tz); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload.Timezone = &tz
+	}
+	if payload.Locale != nil {
+		locale := strings.TrimSpace(*payload.Locale)
+		if err := utils.ValidateLocale(locale); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload.Locale = &locale
+	}
+	if payload.DataRegion != nil {
+		// Moving existing data between regions is an operator task; only super admins
+		// may change the region.
+		if !isSuperAdmin {
+			http.Error(w, "only super admins can change the data region", http.StatusForbidden)
+			return
+		}
+		region := strings.TrimSpace(*payload.DataRegion)
+		if err := h.residency.ValidateRegion(region); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload.DataRegion = &region
+	}
+	var freshness *models.ValidationFreshness
+	if payload.ValidationFreshness != nil {
+		f, err := models.ParseValidationFreshness(*payload.ValidationFreshness)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		freshness = &f
+	}
+
+	tenant, err := h.tenantRepo.UpdateTenant(tenantID, repository.TenantUpdate{
+		RequireVerifiedEmail:      payload.RequireVerifiedEmail,
+		Timezone:                  payload.Timezone,
+		Locale:                    payload.Locale,
+		DataRegion:                payload.DataRegion,
+		BlockCrossEnvironmentJobs: payload.BlockCrossEnvironmentJobs,
+		RequireArtifactScan:       payload.RequireArtifactScan,
+		RequireRunApproval:        payload.RequireRunApproval,
+		ValidationFreshness:       freshness,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenant)
+}
+
 func (h *TenantHandler) AddUser(w http.ResponseWriter, r *http.Request) {
 	requesterRoles, _ := authz.RolesFromRequest(r)
 	isSuperAdmin := models.HasAtLeast(requesterRoles, models.RoleSuperAdmin)
@@ -114,6 +234,9 @@ func (h *TenantHandler) AddUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Email and password are required", http.StatusBadRequest)
 		return
 	}
+	if !checkPassword(w, r, h.passwords, payload.Password) {
+		return
+	}
 
 	var roles []models.UserRole
 	if len(payload.Roles) > 0 {
@@ -141,6 +264,15 @@ func (h *TenantHandler) AddUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &user.TenantID,
+		Action:     models.AuditUserAdded,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"email": user.Email, "roles": user.Roles},
+	})
+	emitWebhook(h.webhooks, h.logger, user.TenantID, models.WebhookCategoryUser, models.WebhookEventUserCreated,
+		map[string]interface{}{"user_id": user.ID, "email": user.Email, "roles": user.Roles})
 
 	response := struct {
 		ID        string            `json:"id"`
@@ -266,7 +398,8 @@ func (h *TenantHandler) UpdateUserRoles(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		if existingUser.TenantID != requesterTenantID {
-			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			// Answer as for an unknown ID, so other tenants' user IDs cannot be probed.
+			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
 	}
@@ -305,6 +438,20 @@ func (h *TenantHandler) UpdateUserRoles(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to update user roles: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &updatedUser.TenantID,
+		Action:     models.AuditUserRolesChanged,
+		TargetType: "user",
+		TargetID:   updatedUser.ID,
+		Details:    map[string]interface{}{"previous_roles": existingUser.Roles, "roles": updatedUser.Roles},
+	})
+	emitWebhook(h.webhooks, h.logger, updatedUser.TenantID, models.WebhookCategoryUser, models.WebhookEventRolesChanged,
+		map[string]interface{}{
+			"user_id":        updatedUser.ID,
+			"email":          updatedUser.Email,
+			"previous_roles": existingUser.Roles,
+			"roles":          updatedUser.Roles,
+		})
 
 	response := tenantUserResponse{
 		ID:        updatedUser.ID,
@@ -323,17 +470,26 @@ func (h *TenantHandler) UpdateUserRoles(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// DeleteUser soft-deletes a user. With ?erase=true the user is erased instead: their
+// personal data is anonymised for good, which requires the body to confirm the
+// user's email address.
 func (h *TenantHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := mux.Vars(r)["userID"]
 	if strings.TrimSpace(userID) == "" {
 		http.Error(w, "User ID is required", http.StatusBadRequest)
 		return
 	}
+	erase := r.URL.Query().Get("erase") == "true"
 
 	requesterRoles, _ := authz.RolesFromRequest(r)
 	isSuperAdmin := models.HasAtLeast(requesterRoles, models.RoleSuperAdmin)
 
-	existingUser, err := h.userRepo.GetUserByID(userID)
+	lookup := h.userRepo.GetUserByID
+	if erase {
+		// Users deleted earlier can still be erased.
+		lookup = h.userRepo.GetUserIncludingDeleted
+	}
+	existingUser, err := lookup(userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "User not found", http.StatusNotFound)
@@ -350,11 +506,17 @@ func (h *TenantHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if existingUser.TenantID != requesterTenantID {
-			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			// Answer as for an unknown ID, so other tenants' user IDs cannot be probed.
+			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
 	}
 
+	if erase {
+		h.eraseUser(w, r, existingUser)
+		return
+	}
+
 	if err := h.userRepo.DeleteUser(existingUser.ID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "User not found", http.StatusNotFound)
@@ -363,6 +525,143 @@ func (h *TenantHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &existingUser.TenantID,
+		Action:     models.AuditUserDeleted,
+		TargetType: "user",
+		TargetID:   existingUser.ID,
+		Details:    map[string]interface{}{"roles": existingUser.Roles},
+	})
+	emitWebhook(h.webhooks, h.logger, existingUser.TenantID, models.WebhookCategoryUser, models.WebhookEventUserDeactivated,
+		map[string]interface{}{"user_id": existingUser.ID, "email": existingUser.Email})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TenantHandler) eraseUser(w http.ResponseWriter, r *http.Request, user models.User) {
+	var payload struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := decodeAllowEmpty(r, &payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(payload.Confirm), user.Email) {
+		http.Error(w, "confirm must match the user's email address", http.StatusBadRequest)
+		return
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok && uid == user.ID {
+		http.Error(w, "You cannot erase your own account", http.StatusBadRequest)
+		return
+	}
 
+	if err := h.userRepo.EraseUser(user.TenantID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to erase user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The event carries no personal data; the user ID is all that remains of them.
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &user.TenantID,
+		Action:     models.AuditUserErased,
+		TargetType: "user",
+		TargetID:   user.ID,
+	})
+	if user.IsActive {
+		emitWebhook(h.webhooks, h.logger, user.TenantID, models.WebhookCategoryUser, models.WebhookEventUserDeactivated,
+			map[string]interface{}{"user_id": user.ID})
+	}
+	h.logger.Info().Str("user_id", user.ID).Str("tenant_id", user.TenantID).Msg("user erased")
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// DeleteTenant suspends a tenant and schedules its data for purge once the deletion
+// grace period ends. Until then a super admin can restore it.
+func (h *TenantHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	requesterRoles, _ := authz.RolesFromRequest(r)
+	tenantID := mux.Vars(r)["tenantID"]
+	if !models.HasAtLeast(requesterRoles, models.RoleSuperAdmin) {
+		if tid, ok := authz.TenantIDFromRequest(r); !ok || tid != tenantID {
+			http.Error(w, "insufficient permissions for tenant", http.StatusForbidden)
+			return
+		}
+	}
+
+	existing, err := h.tenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing.SuspendedAt != nil {
+		http.Error(w, "Tenant is already scheduled for deletion", http.StatusConflict)
+		return
+	}
+
+	tenant, err := h.tenantRepo.ScheduleTenantDeletion(tenantID, time.Now().Add(h.deletionGrace))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Tenant is already scheduled for deletion", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to delete tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	options := tc.StartWorkflowOptions{
+		ID:        temporal.TenantPurgeWorkflowIDPrefix + tenantID,
+		TaskQueue: temporal.TaskQueueName,
+	}
+	if _, err := h.temporalClient.ExecuteWorkflow(context.Background(), options, workflows.TenantPurgeWorkflow, tenantID, *tenant.PurgeAfter); err != nil {
+		// Without a purge workflow the tenant would stay suspended forever; undo.
+		if _, restoreErr := h.tenantRepo.RestoreTenant(tenantID); restoreErr != nil {
+			h.logger.Error().Err(restoreErr).Str("tenant_id", tenantID).Msg("failed to roll back tenant deletion")
+		}
+		http.Error(w, "Failed to schedule tenant purge: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditTenantDeleted,
+		TargetType: "tenant",
+		TargetID:   tenantID,
+		Details:    map[string]interface{}{"purge_after": tenant.PurgeAfter},
+	})
+	h.logger.Warn().Str("tenant_id", tenantID).Time("purge_after", *tenant.PurgeAfter).Msg("Tenant scheduled for deletion")
+	writeJSON(w, http.StatusAccepted, tenant)
+}
+
+// RestoreTenant brings back a deleted tenant whose grace period has not ended.
+func (h *TenantHandler) RestoreTenant(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantID"]
+	tenant, err := h.tenantRepo.RestoreTenant(tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Tenant not found or not restorable", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to restore tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The purge activity re-checks the tenant, so a failed cancel is only logged.
+	if err := h.temporalClient.CancelWorkflow(context.Background(), temporal.TenantPurgeWorkflowIDPrefix+tenantID, ""); err != nil {
+		h.logger.Warn().Err(err).Str("tenant_id", tenantID).Msg("failed to cancel tenant purge workflow")
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditTenantRestored,
+		TargetType: "tenant",
+		TargetID:   tenantID,
+	})
+	h.logger.Info().Str("tenant_id", tenantID).Msg("Tenant restored")
+	writeJSON(w, http.StatusOK, tenant)
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/utils"
+)
+
+// ImportTenant creates the connections, definitions and schedules of an import
+// bundle (see models.TenantImportBundle) all at once, or nothing when any item is
+// invalid. With ?dry_run=true it only validates the bundle and previews what would
+// be created.
+func (h *JobHandler) ImportTenant(w http.ResponseWriter, r *http.Request) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	var bundle models.TenantImportBundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBodyBytes)).Decode(&bundle); err != nil {
+		http.Error(w, "Invalid import bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(bundle.Connections)+len(bundle.Definitions)+len(bundle.Schedules) == 0 {
+		http.Error(w, "Import bundle is empty", http.StatusBadRequest)
+		return
+	}
+	if len(bundle.Connections) > maxImportRows || len(bundle.Definitions) > maxImportRows || len(bundle.Schedules) > maxImportRows {
+		http.Error(w, fmt.Sprintf("Import is limited to %d items of each kind", maxImportRows), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var createdBy *string
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		createdBy = &uid
+	}
+	plan, result, err := h.planTenantImport(tid, createdBy, bundle)
+	if err != nil {
+		http.Error(w, "Failed to validate import: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.DryRun = dryRun
+	if len(result.Errors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	// Temporal schedules are created first, the way SetSchedule does, and removed
+	// again when the import does not commit.
+	var created []string
+	rollback := func() {
+		for _, id := range created {
+			if err := h.temporalClient.ScheduleClient().GetHandle(context.Background(), id).Delete(context.Background()); err != nil {
+				h.logger.Error().Err(err).Str("schedule_id", id).Msg("failed to roll back Temporal schedule")
+			}
+		}
+	}
+	jobTypes := make(map[string]string, len(plan.Definitions))
+	for _, def := range plan.Definitions {
+		jobTypes[def.ID] = def.JobType
+	}
+	for _, schedule := range plan.Schedules {
+		triggerContext := map[string]string{"schedule_id": schedule.ScheduleID}
+		if schedule.CreatedBy != nil {
+			triggerContext["user_id"] = *schedule.CreatedBy
+		}
+		params := temporal.ExecutionParams{
+			TenantID:        tid,
+			JobDefinitionID: schedule.JobDefinitionID,
+			JobType:         jobTypes[schedule.JobDefinitionID],
+			TriggeredBy:     models.TriggerSchedule,
+			TriggerContext:  triggerContext,
+		}
+		isNew, err := h.upsertTemporalSchedule(r.Context(), schedule, params)
+		if isNew {
+			created = append(created, schedule.ScheduleID)
+		}
+		if err != nil {
+			rollback()
+			http.Error(w, "Failed to save Temporal schedule: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	if err := h.repo.ImportTenant(tid, plan); err != nil {
+		rollback()
+		http.Error(w, "Failed to import: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Committed = true
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditTenantImported,
+		TargetType: "tenant",
+		TargetID:   tid,
+		Details: map[string]interface{}{
+			"connections": len(plan.Connections),
+			"definitions": len(plan.Definitions),
+			"schedules":   len(plan.Schedules),
+		},
+	})
+	h.logger.Info().Str("tenant_id", tid).Int("connections", len(plan.Connections)).
+		Int("definitions", len(plan.Definitions)).Int("schedules", len(plan.Schedules)).Msg("Imported tenant bundle")
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// planTenantImport validates a bundle and assigns every item the ID it will be
+// created with. Invalid items are reported in the result's errors; the returned
+// error is for failures to validate at all.
+func (h *JobHandler) planTenantImport(tenantID string, createdBy *string, bundle models.TenantImportBundle) (repository.TenantImport, models.TenantImportResult, error) {
+	plan := repository.TenantImport{Secrets: map[string]map[string]string{}}
+	result := models.TenantImportResult{Errors: []string{}, Items: []models.TenantImportItem{}}
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	connRefs := make(map[string]models.Connection, len(bundle.Connections))
+	for i, item := range bundle.Connections {
+		label := fmt.Sprintf("connections[%d]", i)
+		ref := strings.TrimSpace(item.Ref)
+		if ref == "" {
+			fail("%s: ref is required", label)
+		} else if _, dup := connRefs[ref]; dup {
+			fail("%s: duplicate ref %q", label, ref)
+		}
+		row := importRow{
+			Name:       item.Name,
+			DataFormat: item.DataFormat,
+			Host:       item.Host,
+			Username:   item.Username,
+			Password:   item.Password,
+			DBName:     item.DBName,
+		}
+		if item.Port != 0 {
+			row.Port = strconv.Itoa(item.Port)
+		}
+		conn, errs := row.toConnection()
+		for _, msg := range errs {
+			fail("%s: %s", label, msg)
+		}
+		if len(item.PasswordEncrypted) > 0 {
+			if item.Password != "" {
+				fail("%s: set either password or password_encrypted", label)
+			} else if plain, err := utils.DecryptPassword(item.PasswordEncrypted); err != nil {
+				fail("%s: password_encrypted cannot be decrypted with this instance's key", label)
+			} else {
+				conn.Password = plain
+			}
+		}
+		conn.CredentialMode = strings.ToLower(strings.TrimSpace(item.CredentialMode))
+		if conn.CredentialMode != "" && !models.ValidCredentialMode(conn.CredentialMode) {
+			fail("%s: credential_mode must be %s or %s", label, models.CredentialModeStored, models.CredentialModePrompt)
+		}
+		conn.Environment = strings.ToLower(strings.TrimSpace(item.Environment))
+		if !models.ValidEnvironment(conn.Environment) {
+			fail("%s: unknown environment %q", label, item.Environment)
+		}
+		tags, err := models.NormalizeTags(item.Tags)
+		if err != nil {
+			fail("%s: %s", label, err.Error())
+		}
+		conn.Tags = tags
+		conn.ReadOnly = item.ReadOnly
+		conn.DockerNetwork, conn.NetworkAlias = item.DockerNetwork, item.NetworkAlias
+		if err := conn.NormalizeNetwork(); err != nil {
+			fail("%s: %s", label, err.Error())
+		}
+		conn.ID, conn.TenantID = uuid.NewString(), tenantID
+		if ref != "" {
+			connRefs[ref] = conn
+		}
+		plan.Connections = append(plan.Connections, conn)
+		result.Items = append(result.Items, models.TenantImportItem{Kind: models.ImportKindConnection, Ref: ref, Name: conn.Name, ID: conn.ID})
+	}
+
+	tenant, err := h.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		return plan, result, err
+	}
+	existing := map[string]*models.Connection{}
+	// resolve returns the connection a definition names by bundle ref or by the ID
+	// of an existing connection. ok is false when it names none.
+	resolve := func(label, field, ref, id string) (models.Connection, bool, error) {
+		ref, id = strings.TrimSpace(ref), strings.TrimSpace(id)
+		switch {
+		case ref != "" && id != "":
+			fail("%s: set either %s or %s_id", label, field, field)
+		case ref != "":
+			conn, found := connRefs[ref]
+			if !found {
+				fail("%s: %s references unknown connection %q", label, field, ref)
+			}
+			return conn, found, nil
+		case id != "":
+			conn, cached := existing[id]
+			if !cached {
+				found, err := h.connRepo.Get(tenantID, id)
+				if err != nil && !isNotFound(err) {
+					return models.Connection{}, false, err
+				}
+				if err == nil && !found.Ephemeral {
+					conn = found
+				}
+				existing[id] = conn
+			}
+			if conn == nil {
+				fail("%s: %s_id %s not found", label, field, id)
+				return models.Connection{}, false, nil
+			}
+			return *conn, true, nil
+		}
+		return models.Connection{}, false, nil
+	}
+
+	defRefs := make(map[string]models.JobDefinition, len(bundle.Definitions))
+	for i, item := range bundle.Definitions {
+		label := fmt.Sprintf("definitions[%d]", i)
+		ref := strings.TrimSpace(item.Ref)
+		if ref == "" {
+			fail("%s: ref is required", label)
+		} else if _, dup := defRefs[ref]; dup {
+			fail("%s: duplicate ref %q", label, ref)
+		}
+		def := models.JobDefinition{
+			ID:          uuid.NewString(),
+			TenantID:    tenantID,
+			Name:        strings.TrimSpace(item.Name),
+			Description: item.Description,
+			AST:         item.AST,
+			Status:      models.DefinitionStatusReady,
+			RetryPolicy: item.RetryPolicy,
+		}
+		if def.Name == "" {
+			fail("%s: name is required", label)
+		}
+		def.JobType = strings.ToLower(strings.TrimSpace(item.JobType))
+		if def.JobType == "" {
+			def.JobType = models.JobTypeEngine
+		} else if !models.ValidJobType(def.JobType) {
+			fail("%s: job_type must be %s or %s", label, models.JobTypeEngine, models.JobTypeSQLScript)
+		}
+		if mode, err := models.NormalizeWriteMode(item.WriteMode); err != nil {
+			fail("%s: %s", label, err.Error())
+		} else {
+			def.WriteMode = mode
+		}
+		if mode, err := models.NormalizeVerification(item.Verification); err != nil {
+			fail("%s: %s", label, err.Error())
+		} else {
+			def.Verification = mode
+		}
+		if item.RetryPolicy != nil {
+			if err := item.RetryPolicy.Validate(); err != nil {
+				fail("%s: invalid retry_policy: %s", label, err.Error())
+			}
+		}
+
+		src, hasSrc, err := resolve(label, "source_connection", item.SourceConnection, item.SourceConnectionID)
+		if err != nil {
+			return plan, result, err
+		}
+		dst, hasDst, err := resolve(label, "destination_connection", item.DestinationConnection, item.DestinationConnectionID)
+		if err != nil {
+			return plan, result, err
+		}
+		if hasSrc {
+			def.SourceConnectionID, def.SourceConnection = src.ID, src
+		}
+		if hasDst {
+			def.DestinationConnectionID, def.DestinationConnection = dst.ID, dst
+			if msg := models.ReadOnlyDestination(dst); msg != "" {
+				fail("%s: %s", label, msg)
+			}
+		}
+
+		if len(def.AST) == 0 {
+			fail("%s: ast is required", label)
+		} else {
+			redacted, secrets, err := models.ExtractASTSecrets(def.AST)
+			if err != nil {
+				fail("%s: invalid AST secrets: %s", label, err.Error())
+			} else {
+				def.AST = redacted
+				if len(secrets) > 0 {
+					plan.Secrets[def.ID] = secrets
+				}
+			}
+			if def.JobType == models.JobTypeSQLScript {
+				for _, msg := range validateSQLScript(resolvedDefinition{
+					AST:                     def.AST,
+					SourceConnectionID:      def.SourceConnectionID,
+					DestinationConnectionID: def.DestinationConnectionID,
+				}) {
+					fail("%s: %s", label, msg)
+				}
+			} else {
+				if def.SourceConnectionID == "" || def.DestinationConnectionID == "" {
+					fail("%s: source and destination connections are required", label)
+				}
+				if _, err := models.TableWriteModes(def.AST); err != nil {
+					fail("%s: %s", label, err.Error())
+				}
+			}
+		}
+		if tenant.BlockCrossEnvironmentJobs && hasSrc && hasDst {
+			if msg := models.EnvironmentMismatch(src, dst); msg != "" {
+				fail("%s: cross-environment jobs are blocked for this tenant: %s", label, msg)
+			}
+		}
+
+		if ref != "" {
+			defRefs[ref] = def
+		}
+		stored := def
+		stored.SourceConnection, stored.DestinationConnection = models.Connection{}, models.Connection{}
+		plan.Definitions = append(plan.Definitions, stored)
+		result.Items = append(result.Items, models.TenantImportItem{Kind: models.ImportKindDefinition, Ref: ref, Name: def.Name, ID: def.ID})
+	}
+
+	scheduled := make(map[string]bool, len(bundle.Schedules))
+	for i, item := range bundle.Schedules {
+		label := fmt.Sprintf("schedules[%d]", i)
+		ref := strings.TrimSpace(item.Definition)
+		def, found := defRefs[ref]
+		if !found {
+			fail("%s: definition references unknown definition %q", label, item.Definition)
+			continue
+		}
+		if scheduled[ref] {
+			fail("%s: definition %q is scheduled more than once", label, ref)
+			continue
+		}
+		scheduled[ref] = true
+		schedule := models.JobSchedule{
+			JobDefinitionID: def.ID,
+			TenantID:        tenantID,
+			CronExpression:  item.CronExpression,
+			Timezone:        item.Timezone,
+			ScheduleID:      temporal.JobScheduleIDPrefix + def.ID,
+			CreatedBy:       createdBy,
+		}
+		if err := schedule.Normalize(); err != nil {
+			fail("%s: %s", label, err.Error())
+		}
+		for _, conn := range []models.Connection{def.SourceConnection, def.DestinationConnection} {
+			if conn.PromptsForCredentials() {
+				fail("%s: connection %s prompts for credentials and cannot be used by scheduled runs", label, conn.Name)
+			}
+		}
+		plan.Schedules = append(plan.Schedules, schedule)
+		result.Items = append(result.Items, models.TenantImportItem{Kind: models.ImportKindSchedule, Ref: ref, Name: def.Name, ID: schedule.ScheduleID})
+	}
+	return plan, result, nil
+}
@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/testutil"
+)
+
+func TestCheckTenantPredicate(t *testing.T) {
+	cases := []struct {
+		query string
+		ok    bool
+	}{
+		{`SELECT id FROM tenant.job_definitions WHERE id = $1`, false},
+		{`SELECT id FROM tenant.job_definitions WHERE id = $1 AND tenant_id = $2`, true},
+		{`SELECT id FROM tenant.job_definitions WHERE tenant_id::text = $1`, true},
+		{`SELECT e.id FROM tenant.job_executions e JOIN tenant.job_definitions d ON d.id = e.job_definition_id AND d.tenant_id = e.tenant_id WHERE e.id = $1`, true},
+		{`UPDATE tenant.connections SET name = $2 WHERE id = $1`, false},
+		{`DELETE FROM tenant.saved_views WHERE id = $1`, false},
+		{`INSERT INTO tenant.saved_views (id, name) VALUES ($1, $2)`, false},
+		{`INSERT INTO tenant.saved_views (id, tenant_id, name) VALUES ($1, $2, $3)`, true},
+		{`SELECT id FROM tenant.users WHERE email = $1 /* cross-tenant: sign-in resolves the tenant */`, true},
+		{`SELECT id FROM tenant.tenants WHERE id = $1`, true},
+	}
+	for _, c := range cases {
+		err := repository.CheckTenantPredicate(c.query)
+		if c.ok && err != nil {
+			t.Errorf("CheckTenantPredicate(%q) = %v, want nil", c.query, err)
+		}
+		if !c.ok && !errors.Is(err, repository.ErrMissingTenantPredicate) {
+			t.Errorf("CheckTenantPredicate(%q) = %v, want ErrMissingTenantPredicate", c.query, err)
+		}
+	}
+}
+
+// seedTenantResources creates one of each tenant-owned resource the API addresses
+// by ID and returns their IDs keyed by the route variable that carries them.
+func seedTenantResources(t *testing.T, h *testutil.Harness, tenant models.Tenant, user models.User) map[string]string {
+	t.Helper()
+	exec := startExecution(t, h, tenant.ID)
+	ids := map[string]string{
+		"tenantID": tenant.ID,
+		"userID":   user.ID,
+		"jobID":    exec.JobDefinitionID,
+		"execID":   exec.ID,
+	}
+	check := func(what string, err error) {
+		if err != nil {
+			t.Fatalf("create %s: %v", what, err)
+		}
+	}
+
+	conn, err := h.Store.Connections().Create(&models.Connection{TenantID: tenant.ID, Name: "warehouse", DataFormat: "pg", DBName: "analytics"})
+	check("connection", err)
+	ids["id"] = conn.ID
+
+	note, err := h.Store.Jobs().CreateExecutionNote(models.ExecutionNote{ExecutionID: exec.ID, TenantID: tenant.ID, AuthorID: &user.ID, Body: "looks slow"})
+	check("note", err)
+	ids["noteID"] = note.ID
+
+	view, err := h.Store.Views().CreateView(models.SavedView{TenantID: tenant.ID, OwnerID: &user.ID, Shared: true, List: models.ViewListJobs, Name: "Mine", Filters: []byte(`{}`)})
+	check("view", err)
+	ids["viewID"] = view.ID
+
+	sub, err := h.Store.Webhooks().CreateSubscription(models.WebhookSubscription{TenantID: tenant.ID, Category: models.WebhookCategoryExecution, URL: "https://hooks.acme.test", Events: []string{"execution.succeeded"}, Active: true}, []byte("secret"))
+	check("webhook", err)
+	ids["webhookID"] = sub.ID
+
+	tmpl, err := h.Store.Templates().CreateTemplate(models.JobTemplate{TenantID: &tenant.ID, Name: "Copy", Kind: models.JobTypeEngine, AST: []byte(`{"migrate":{}}`)})
+	check("template", err)
+	ids["templateID"] = tmpl.ID
+
+	sensor, err := h.Store.Sensors().CreateSensor(models.JobSensor{TenantID: tenant.ID, JobDefinitionID: exec.JobDefinitionID, Name: "file", Kind: "sql", Config: []byte(`{}`)})
+	check("sensor", err)
+	ids["sensorID"] = sensor.ID
+
+	window, err := h.Store.Tenants().CreateBlackoutWindow(models.BlackoutWindow{TenantID: tenant.ID, Name: "Month end", StartTime: "22:00", EndTime: "02:00", Timezone: "UTC"})
+	check("blackout window", err)
+	ids["windowID"] = window.ID
+
+	group, err := h.Store.Jobs().CreateRunGroup(models.RunGroup{TenantID: tenant.ID, Name: "Nightly", Parallelism: 1, Status: "running"})
+	check("run group", err)
+	ids["groupID"] = group.ID
+
+	invite, err := h.Store.Invites().CreateInvite(models.Invite{TenantID: tenant.ID, Email: "new@acme.test", Roles: []models.UserRole{models.RoleViewer}, TokenHash: uuid.NewString()})
+	check("invite", err)
+	ids["inviteID"] = invite.ID
+
+	key, err := h.Store.APIKeys().CreateAPIKey(models.APIKey{TenantID: tenant.ID, Name: "ci", KeyPrefix: "sk_acme", KeyHash: uuid.NewString()})
+	check("api key", err)
+	ids["keyID"] = key.ID
+
+	domain, err := h.Store.EmailDomains().CreateDomain(models.EmailDomain{TenantID: tenant.ID, Domain: "acme.test", VerificationToken: uuid.NewString()})
+	check("email domain", err)
+	ids["domainID"] = domain.ID
+	return ids
+}
+
+// routeParams matches the variables of a mux path template.
+var routeParams = regexp.MustCompile(`\{(\w+)\}`)
+
+// TestCrossTenantRequestsAreRefused replays every route that addresses a resource
+// by ID with another tenant's admin token and the IDs of the first tenant's
+// resources. Each must be refused with 403 or 404, answer exactly as it does for
+// an ID that does not exist, and leave the resources untouched. The replayed body
+// is empty, so routes that validate it before the lookup answer 400 for both.
+func TestCrossTenantRequestsAreRefused(t *testing.T) {
+	h := testutil.NewHarness(t)
+	acme, owner, ownerToken := h.SeedTenant("Acme", "admin@acme.test", models.RoleAdmin)
+	_, _, intruderToken := h.SeedTenant("Globex", "admin@globex.test", models.RoleAdmin)
+	ids := seedTenantResources(t, h, acme, owner)
+
+	// Variables that are not resource IDs take the same value in both requests.
+	fixed := map[string]string{
+		"category": models.WebhookCategoryExecution,
+		"list":     models.ViewListJobs,
+		"name":     "API_TOKEN",
+		"token":    "not-a-token",
+	}
+	fill := func(tmpl string, values func(name string) string) string {
+		return routeParams.ReplaceAllStringFunc(tmpl, func(param string) string {
+			name := param[1 : len(param)-1]
+			if v, ok := fixed[name]; ok {
+				return v
+			}
+			return values(name)
+		})
+	}
+
+	tried := 0
+	err := h.Router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(tmpl, "/api/v1/") {
+			return nil
+		}
+		names := routeParams.FindAllStringSubmatch(tmpl, -1)
+		targeted := false
+		for _, m := range names {
+			if _, ok := ids[m[1]]; ok {
+				targeted = true
+			}
+		}
+		if !targeted {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		probe := fill(tmpl, func(name string) string {
+			if id, ok := ids[name]; ok {
+				return id
+			}
+			return uuid.NewString()
+		})
+		control := fill(tmpl, func(string) string { return uuid.NewString() })
+		for _, method := range methods {
+			tried++
+			got := h.Do(method, probe, map[string]interface{}{}, intruderToken)
+			want := h.Do(method, control, map[string]interface{}{}, intruderToken)
+			refused := got.Code == http.StatusForbidden || got.Code == http.StatusNotFound ||
+				got.Code == http.StatusBadRequest && want.Code == http.StatusBadRequest
+			if !refused {
+				t.Errorf("%s %s with another tenant's token = %d, want 403 or 404; body: %s", method, tmpl, got.Code, got.Body.String())
+				continue
+			}
+			if got.Code != want.Code {
+				t.Errorf("%s %s = %d for another tenant's IDs but %d for unknown IDs", method, tmpl, got.Code, want.Code)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+	if tried == 0 {
+		t.Fatal("no routes replayed")
+	}
+
+	// The owner still sees everything the intruder tried to reach.
+	for _, path := range []string{
+		"/api/v1/jobs/" + ids["jobID"],
+		"/api/v1/jobs/executions/" + ids["execID"],
+		"/api/v1/connections/" + ids["id"],
+		"/api/v1/views/" + ids["viewID"],
+	} {
+		h.Decode(h.Do(http.MethodGet, path, nil, ownerToken), http.StatusOK, nil)
+	}
+	if cancelled := h.Temporal.Cancelled(); len(cancelled) != 0 {
+		t.Fatalf("cancelled workflows %v for another tenant", cancelled)
+	}
+	if terminated := h.Temporal.Terminated(); len(terminated) != 0 {
+		t.Fatalf("terminated workflows %v for another tenant", terminated)
+	}
+	if signals := h.Temporal.Signals(); len(signals) != 0 {
+		t.Fatalf("signalled workflows %+v for another tenant", signals)
+	}
+}
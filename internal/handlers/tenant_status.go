@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const tenantStatusTTL = 30 * time.Second
+
+// tenantStatusCache remembers for a short while whether tenants are suspended, so
+// authenticated requests do not each need a tenant lookup.
+type tenantStatusCache struct {
+	tenants repository.TenantRepository
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]tenantStatusEntry
+}
+
+type tenantStatusEntry struct {
+	suspended bool
+	expires   time.Time
+}
+
+func newTenantStatusCache(tenants repository.TenantRepository) *tenantStatusCache {
+	return &tenantStatusCache{tenants: tenants, ttl: tenantStatusTTL, entries: make(map[string]tenantStatusEntry)}
+}
+
+// suspended reports whether the tenant is scheduled for deletion.
+func (c *tenantStatusCache) suspended(tenantID string) (bool, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[tenantID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.suspended, nil
+	}
+
+	tenant, err := c.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		return false, err
+	}
+	entry = tenantStatusEntry{suspended: tenant.SuspendedAt != nil, expires: now.Add(c.ttl)}
+	c.mu.Lock()
+	c.entries[tenantID] = entry
+	c.mu.Unlock()
+	return entry.suspended, nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// staleValidationBlocked is the reason a run of a definition with source schema
+// drift is refused.
+const staleValidationBlocked = "the source schema changed since the job definition was validated; validate it again before running it"
+
+// validationFreshness returns how the tenant treats definitions with source schema
+// drift. It writes the error response and returns false as its second value when
+// the tenant cannot be loaded.
+func (h *JobHandler) validationFreshness(w http.ResponseWriter, tenantID string) (models.ValidationFreshness, bool) {
+	tenant, err := h.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return tenant.ValidationFreshness, true
+}
+
+// staleValidationWarning is the pre-flight check of a definition about to run. It
+// refuses a stale definition when the tenant blocks them, and otherwise returns
+// the warning to report with the run, if any.
+func staleValidationWarning(w http.ResponseWriter, freshness models.ValidationFreshness, def models.JobDefinition) (string, bool) {
+	if !def.StaleValidation {
+		return "", true
+	}
+	switch freshness {
+	case models.ValidationFreshnessBlock:
+		http.Error(w, "Stale validation: "+staleValidationBlocked, http.StatusConflict)
+		return "", false
+	case models.ValidationFreshnessWarn:
+		return models.StaleValidationMessage, true
+	}
+	return "", true
+}
+
+// enforceValidationFreshness applies the pre-flight check to a stored definition.
+func (h *JobHandler) enforceValidationFreshness(w http.ResponseWriter, tenantID, jobDefID string) (string, bool) {
+	freshness, ok := h.validationFreshness(w, tenantID)
+	if !ok || freshness == models.ValidationFreshnessOff {
+		return "", ok
+	}
+	def, err := h.repo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Job definition not found", http.StatusNotFound)
+			return "", false
+		}
+		http.Error(w, "Failed to load job definition: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return staleValidationWarning(w, freshness, def)
+}
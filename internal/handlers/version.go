@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/stanstork/stratum-api/internal/middleware"
+	"github.com/stanstork/stratum-api/internal/version"
+)
+
+type versionResponse struct {
+	Build               string                            `json:"build"`
+	Current             string                            `json:"current"`
+	Supported           []string                          `json:"supported"`
+	LegacyAlias         middleware.Deprecation            `json:"legacy_alias"`
+	DeprecatedEndpoints map[string]middleware.Deprecation `json:"deprecated_endpoints"`
+}
+
+// VersionInfo reports the running build, the API contracts it serves and the
+// endpoints scheduled for change.
+func VersionInfo(deprecated map[string]middleware.Deprecation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, versionResponse{
+			Build:     version.APIVersion,
+			Current:   version.CurrentContract,
+			Supported: version.SupportedContracts,
+			LegacyAlias: middleware.Deprecation{
+				Since:     version.LegacyAliasDeprecatedAt,
+				Sunset:    version.LegacyAliasSunset,
+				Successor: "/api/" + version.CurrentContract,
+			},
+			DeprecatedEndpoints: deprecated,
+		})
+	}
+}
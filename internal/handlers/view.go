@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// ViewHandler manages saved views of the executions and jobs lists. Every member
+// keeps their own views; admins also curate views shared with the whole tenant.
+type ViewHandler struct {
+	views  repository.ViewRepository
+	logger zerolog.Logger
+}
+
+type viewRequest struct {
+	Name    string          `json:"name"`
+	List    string          `json:"list"`
+	Filters json.RawMessage `json:"filters"`
+	Sort    string          `json:"sort"`
+	// Shared creates a view for the whole tenant (admins only).
+	Shared bool `json:"shared"`
+}
+
+type defaultViewRequest struct {
+	// ViewID is the view to open the list with; null clears the preference.
+	ViewID *string `json:"view_id"`
+}
+
+func NewViewHandler(views repository.ViewRepository, logger zerolog.Logger) *ViewHandler {
+	return &ViewHandler{views: views, logger: logger}
+}
+
+// viewCaller returns the tenant and user a view request is made for.
+func viewCaller(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return "", "", false
+	}
+	uid, ok := authz.UserIDFromRequest(r)
+	if !ok || uid == "" {
+		http.Error(w, "Missing user context", http.StatusUnauthorized)
+		return "", "", false
+	}
+	return tid, uid, true
+}
+
+func (h *ViewHandler) List(w http.ResponseWriter, r *http.Request) {
+	tid, uid, ok := viewCaller(w, r)
+	if !ok {
+		return
+	}
+	list := strings.ToLower(r.URL.Query().Get("list"))
+	if list != "" && !models.ValidViewList(list) {
+		http.Error(w, "list must be executions or jobs", http.StatusBadRequest)
+		return
+	}
+	views, err := h.views.ListViews(tid, uid, list)
+	if err != nil {
+		http.Error(w, "Failed to list views: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (h *ViewHandler) Get(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *ViewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tid, uid, ok := viewCaller(w, r)
+	if !ok {
+		return
+	}
+	var req viewRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	view := models.SavedView{
+		TenantID:  tid,
+		List:      req.List,
+		Name:      req.Name,
+		Filters:   req.Filters,
+		Sort:      req.Sort,
+		CreatedBy: &uid,
+	}
+	if err := view.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Shared {
+		roles, _ := authz.RolesFromRequest(r)
+		if !models.HasAtLeast(roles, models.RoleAdmin) {
+			http.Error(w, "only admins can create shared views", http.StatusForbidden)
+			return
+		}
+	} else {
+		view.OwnerID = &uid
+	}
+
+	created, err := h.views.CreateView(view)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A view with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create view: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *ViewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadForChange(w, r)
+	if !ok {
+		return
+	}
+	uid, _ := authz.UserIDFromRequest(r)
+	var req viewRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.List != "" && !strings.EqualFold(strings.TrimSpace(req.List), current.List) {
+		http.Error(w, "list of a view cannot be changed", http.StatusBadRequest)
+		return
+	}
+	view := current
+	view.Name, view.Filters, view.Sort = req.Name, req.Filters, req.Sort
+	if err := view.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.views.UpdateView(uid, view)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "View not found", http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "A view with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update view: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *ViewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadForChange(w, r)
+	if !ok {
+		return
+	}
+	if err := h.views.DeleteView(current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "View not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete view: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetDefault chooses the view the caller opens a list with. Any view the caller
+// can see may be their default, shared ones included.
+func (h *ViewHandler) SetDefault(w http.ResponseWriter, r *http.Request) {
+	tid, uid, ok := viewCaller(w, r)
+	if !ok {
+		return
+	}
+	list := strings.ToLower(mux.Vars(r)["list"])
+	if !models.ValidViewList(list) {
+		http.Error(w, "list must be executions or jobs", http.StatusBadRequest)
+		return
+	}
+	var req defaultViewRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.ViewID != nil && strings.TrimSpace(*req.ViewID) == "" {
+		req.ViewID = nil
+	}
+
+	if err := h.views.SetDefaultView(tid, uid, list, req.ViewID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "View not found for this list", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to set default view: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ViewHandler) load(w http.ResponseWriter, r *http.Request) (models.SavedView, bool) {
+	tid, uid, ok := viewCaller(w, r)
+	if !ok {
+		return models.SavedView{}, false
+	}
+	view, err := h.views.GetView(tid, uid, mux.Vars(r)["viewID"])
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, "View not found", http.StatusNotFound)
+			return models.SavedView{}, false
+		}
+		http.Error(w, "Failed to load view: "+err.Error(), http.StatusInternalServerError)
+		return models.SavedView{}, false
+	}
+	return view, true
+}
+
+// loadForChange loads a view the caller may modify: shared views are curated by
+// admins.
+func (h *ViewHandler) loadForChange(w http.ResponseWriter, r *http.Request) (models.SavedView, bool) {
+	view, ok := h.load(w, r)
+	if !ok {
+		return view, false
+	}
+	if view.Shared {
+		roles, _ := authz.RolesFromRequest(r)
+		if !models.HasAtLeast(roles, models.RoleAdmin) {
+			http.Error(w, "only admins can change shared views", http.StatusForbidden)
+			return view, false
+		}
+	}
+	return view, true
+}
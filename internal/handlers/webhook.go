@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/utils"
+)
+
+const webhookSecretPrefix = "whsec_"
+
+// WebhookHandler manages a tenant's webhook subscriptions. Each event category has
+// its own subscriptions, addressed by the {category} path segment.
+type WebhookHandler struct {
+	repo   repository.WebhookRepository
+	audit  repository.AuditRepository
+	logger zerolog.Logger
+}
+
+type webhookSubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+	// PayloadTemplate replaces the subscription's payload template when given;
+	// null removes it.
+	PayloadTemplate json.RawMessage `json:"payload_template"`
+}
+
+func NewWebhookHandler(repo repository.WebhookRepository, audit repository.AuditRepository, logger zerolog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		repo:   repo,
+		audit:  audit,
+		logger: logger.With().Str("handler", "webhook").Logger(),
+	}
+}
+
+// emitWebhook queues a webhook event for the tenant's subscribers of category.
+// Failures are logged: the change that raised the event has already happened.
+func emitWebhook(webhooks repository.WebhookRepository, logger zerolog.Logger, tenantID, category, event string, data map[string]interface{}) {
+	if webhooks == nil {
+		return
+	}
+	evt := models.WebhookEvent{
+		ID:         uuid.NewString(),
+		Category:   category,
+		Type:       event,
+		TenantID:   tenantID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	if _, err := webhooks.EnqueueEvent(evt); err != nil {
+		logger.Error().Err(err).Str("event", event).Str("tenant_id", tenantID).Msg("failed to queue webhook event")
+	}
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	tid, category, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+	subs, err := h.repo.ListSubscriptions(tid, category)
+	if err != nil {
+		http.Error(w, "Failed to list webhooks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tid, category, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+	var req webhookSubscriptionRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	sub := models.WebhookSubscription{TenantID: tid, Category: category, Active: true}
+	if !applyWebhookRequest(w, &sub, req) {
+		return
+	}
+	if uid, ok := authz.UserIDFromRequest(r); ok {
+		sub.CreatedBy = &uid
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate webhook secret", http.StatusInternalServerError)
+		return
+	}
+	secret := webhookSecretPrefix + token
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		http.Error(w, "Failed to encrypt webhook secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.repo.CreateSubscription(sub, encrypted)
+	if err != nil {
+		http.Error(w, "Failed to create webhook: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tid,
+		Action:     models.AuditWebhookCreated,
+		TargetType: "webhook",
+		TargetID:   created.ID,
+		Details:    map[string]interface{}{"category": created.Category, "url": created.URL, "events": created.Events},
+	})
+
+	// The signing secret is only ever returned here.
+	writeJSON(w, http.StatusCreated, struct {
+		models.WebhookSubscription
+		Secret string `json:"secret"`
+	}{WebhookSubscription: created, Secret: secret})
+}
+
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req webhookSubscriptionRequest
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		req.URL = sub.URL
+	}
+	if req.Events == nil {
+		req.Events = sub.Events
+	}
+	if !applyWebhookRequest(w, &sub, req) {
+		return
+	}
+	updated, err := h.repo.UpdateSubscription(sub)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update webhook: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteSubscription(sub.TenantID, sub.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete webhook: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &sub.TenantID,
+		Action:     models.AuditWebhookDeleted,
+		TargetType: "webhook",
+		TargetID:   sub.ID,
+		Details:    map[string]interface{}{"category": sub.Category, "url": sub.URL},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the subscription's recent deliveries, newest first.
+// ?limit= caps the result (default 50, at most 200).
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if n > 200 {
+			n = 200
+		}
+		limit = n
+	}
+	deliveries, err := h.repo.ListDeliveries(sub.TenantID, sub.ID, limit)
+	if err != nil {
+		http.Error(w, "Failed to list webhook deliveries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Test queues a sample event of the subscription's category for delivery to it,
+// rendered through its payload template, and returns the delivery so the payload
+// can be inspected. The body may name the event to sample.
+func (h *WebhookHandler) Test(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Event string `json:"event"`
+	}
+	if err := decodeAllowEmpty(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !sub.Active {
+		http.Error(w, "Webhook is disabled", http.StatusConflict)
+		return
+	}
+	evt := models.SampleWebhookEvent(sub.Category, sub.TenantID, time.Now().UTC())
+	evt.ID = uuid.NewString()
+	if event := strings.ToLower(strings.TrimSpace(req.Event)); event != "" {
+		if !models.ValidWebhookEvent(sub.Category, event) {
+			http.Error(w, "Unknown "+sub.Category+" event: "+event, http.StatusBadRequest)
+			return
+		}
+		evt.Type = event
+	}
+	payload, err := models.RenderWebhookPayload(sub, evt)
+	if err != nil {
+		http.Error(w, "Failed to render payload: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	delivery, err := h.repo.EnqueueDelivery(sub, evt.Type, payload)
+	if err != nil {
+		http.Error(w, "Failed to queue test delivery: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// scope returns the caller's tenant and the event category of the request path.
+func (h *WebhookHandler) scope(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	tid, ok := authz.TenantIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Missing tenant context", http.StatusUnauthorized)
+		return "", "", false
+	}
+	category := mux.Vars(r)["category"]
+	if !models.ValidWebhookCategory(category) {
+		http.Error(w, "Unknown webhook category", http.StatusNotFound)
+		return "", "", false
+	}
+	return tid, category, true
+}
+
+func (h *WebhookHandler) load(w http.ResponseWriter, r *http.Request) (models.WebhookSubscription, bool) {
+	tid, category, ok := h.scope(w, r)
+	if !ok {
+		return models.WebhookSubscription{}, false
+	}
+	sub, err := h.repo.GetSubscription(tid, mux.Vars(r)["webhookID"])
+	if err == nil && sub.Category != category {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return sub, false
+		}
+		http.Error(w, "Failed to load webhook: "+err.Error(), http.StatusInternalServerError)
+		return sub, false
+	}
+	return sub, true
+}
+
+// applyWebhookRequest validates the request and copies it onto sub.
+func applyWebhookRequest(w http.ResponseWriter, sub *models.WebhookSubscription, req webhookSubscriptionRequest) bool {
+	target := strings.TrimSpace(req.URL)
+	u, err := url.Parse(target)
+	if target == "" || err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		http.Error(w, "url must be an absolute http(s) URL", http.StatusBadRequest)
+		return false
+	}
+	events := make([]string, 0, len(req.Events))
+	seen := map[string]bool{}
+	for _, e := range req.Events {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if !models.ValidWebhookEvent(sub.Category, e) {
+			http.Error(w, "Unknown "+sub.Category+" event: "+e, http.StatusBadRequest)
+			return false
+		}
+		if !seen[e] {
+			seen[e] = true
+			events = append(events, e)
+		}
+	}
+	if len(req.PayloadTemplate) > 0 {
+		if bytes.Equal(bytes.TrimSpace(req.PayloadTemplate), []byte("null")) {
+			sub.PayloadTemplate = nil
+		} else {
+			var tmpl models.WebhookPayloadTemplate
+			if err := json.Unmarshal(req.PayloadTemplate, &tmpl); err != nil {
+				http.Error(w, "payload_template must be an object", http.StatusBadRequest)
+				return false
+			}
+			if err := tmpl.Validate(sub.Category); err != nil {
+				http.Error(w, "Invalid payload_template: "+err.Error(), http.StatusBadRequest)
+				return false
+			}
+			sub.PayloadTemplate = &tmpl
+		}
+	}
+	sub.URL = target
+	sub.Events = events
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	return true
+}
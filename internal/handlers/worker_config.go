@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// GetWorkerConfig returns the tenant's worker configuration. Fields left empty use
+// the worker's defaults.
+func (h *TenantHandler) GetWorkerConfig(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantID"]
+	if _, err := h.tenantRepo.GetTenantByID(tenantID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cfg, err := h.tenantRepo.GetWorkerConfig(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load worker config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// UpdateWorkerConfig replaces the tenant's worker configuration. The new limits
+// apply to runs prepared from now on.
+func (h *TenantHandler) UpdateWorkerConfig(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantID"]
+	if _, err := h.tenantRepo.GetTenantByID(tenantID); err != nil {
+		if isNotFound(err) {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load tenant: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var payload struct {
+		CPULimit            *int64   `json:"cpu_limit"`
+		MemoryLimit         *int64   `json:"memory_limit"`
+		EngineImage         *string  `json:"engine_image"`
+		AllowedEngineImages []string `json:"allowed_engine_images"`
+		MaxExecutionSeconds *int     `json:"max_execution_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	cfg := models.TenantWorkerConfig{
+		TenantID:            tenantID,
+		CPULimit:            payload.CPULimit,
+		MemoryLimit:         payload.MemoryLimit,
+		EngineImage:         payload.EngineImage,
+		AllowedEngineImages: payload.AllowedEngineImages,
+		MaxExecutionSeconds: payload.MaxExecutionSeconds,
+	}
+	if err := cfg.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if userID, ok := authz.UserIDFromRequest(r); ok && userID != "" {
+		cfg.UpdatedBy = &userID
+	}
+
+	saved, err := h.tenantRepo.SaveWorkerConfig(cfg)
+	if err != nil {
+		http.Error(w, "Failed to save worker config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(h.audit, h.logger, r, models.AuditEvent{
+		TenantID:   &tenantID,
+		Action:     models.AuditWorkerConfigUpdated,
+		TargetType: "tenant",
+		TargetID:   tenantID,
+	})
+	writeJSON(w, http.StatusOK, saved)
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/authz"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+const (
+	accessLogBuffer    = 4096
+	accessLogBatchSize = 500
+	accessLogFlush     = 2 * time.Second
+)
+
+// AccessLogger persists every API request as an access log entry. Entries are queued
+// and written in batches by Run so requests never wait on the database; when the
+// queue is full, entries are dropped and counted in the log.
+type AccessLogger struct {
+	repo    repository.AuditRepository
+	entries chan models.AccessLog
+	logger  zerolog.Logger
+}
+
+func NewAccessLogger(repo repository.AuditRepository, logger zerolog.Logger) *AccessLogger {
+	return &AccessLogger{
+		repo:    repo,
+		entries: make(chan models.AccessLog, accessLogBuffer),
+		logger:  logger.With().Str("component", "access_log").Logger(),
+	}
+}
+
+// Middleware records every matched request along with the tenant and user it was
+// authenticated as. It must be installed with mux's Router.Use.
+func (l *AccessLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, identity := authz.WithIdentityRecord(r.Context())
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		entry := models.AccessLog{
+			Method:     r.Method,
+			Route:      r.URL.Path,
+			Path:       r.URL.Path,
+			Status:     rw.status,
+			DurationMs: time.Since(start).Milliseconds(),
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+			CreatedAt:  start.UTC(),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				entry.Route = tpl
+			}
+		}
+		// Invite tokens are credentials; keep them out of the log.
+		if _, ok := mux.Vars(r)["token"]; ok {
+			entry.Path = entry.Route
+		}
+		if identity.TenantID != "" {
+			entry.TenantID = &identity.TenantID
+		}
+		if identity.UserID != "" {
+			entry.UserID = &identity.UserID
+		}
+
+		select {
+		case l.entries <- entry:
+		default:
+			l.logger.Warn().Str("path", entry.Path).Msg("access log queue full, entry dropped")
+		}
+	})
+}
+
+// Run writes queued entries until ctx is cancelled, then flushes what is left.
+func (l *AccessLogger) Run(ctx context.Context) {
+	ticker := time.NewTicker(accessLogFlush)
+	defer ticker.Stop()
+
+	batch := make([]models.AccessLog, 0, accessLogBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := l.repo.RecordAccessLogs(batch); err != nil {
+			l.logger.Error().Err(err).Int("entries", len(batch)).Msg("failed to write access logs")
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-l.entries:
+					batch = append(batch, entry)
+				default:
+					flush()
+					return
+				}
+			}
+		case entry := <-l.entries:
+			batch = append(batch, entry)
+			if len(batch) >= accessLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
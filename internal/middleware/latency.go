@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// maxLatencySamples caps the samples kept per route within the rolling window.
+const maxLatencySamples = 4096
+
+// LatencyBudget is the P99 target for one route, keyed "METHOD /path" relative to
+// the API prefix, e.g. "GET /jobs/{jobID}".
+type LatencyBudget struct {
+	Route string
+	P99   time.Duration
+}
+
+// RouteLatency is a percentile snapshot of one route over the rolling window.
+type RouteLatency struct {
+	Route         string     `json:"route"`
+	Count         int        `json:"count"`
+	P50Ms         float64    `json:"p50_ms"`
+	P90Ms         float64    `json:"p90_ms"`
+	P95Ms         float64    `json:"p95_ms"`
+	P99Ms         float64    `json:"p99_ms"`
+	MaxMs         float64    `json:"max_ms"`
+	BudgetMs      float64    `json:"budget_ms,omitempty"`
+	OverBudget    bool       `json:"over_budget"`
+	BreachedSince *time.Time `json:"breached_since,omitempty"`
+	Alerting      bool       `json:"alerting"`
+}
+
+// LatencyAlert is raised when a route's P99 stays above its budget for the alert
+// period, and again (with Resolved set) once it recovers.
+type LatencyAlert struct {
+	Route         string
+	P99           time.Duration
+	Budget        time.Duration
+	BreachedSince time.Time
+	Resolved      bool
+}
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+type routeWindow struct {
+	samples       []latencySample
+	breachedSince *time.Time
+	alerting      bool
+}
+
+// LatencyTracker records request durations per route and checks rolling P99s
+// against configured budgets.
+type LatencyTracker struct {
+	window        time.Duration
+	alertAfter    time.Duration
+	defaultBudget time.Duration
+	budgets       map[string]time.Duration
+	prefixes      []string
+	onAlert       func(LatencyAlert)
+	logger        zerolog.Logger
+
+	mu     sync.Mutex
+	routes map[string]*routeWindow
+}
+
+// NewLatencyTracker builds a tracker. Route keys are made relative by stripping the
+// first matching prefix (e.g. "/api/v1"), so versioned and legacy paths share a key.
+// onAlert may be nil, in which case alerts are only logged.
+func NewLatencyTracker(window, alertAfter, defaultBudget time.Duration, budgets []LatencyBudget, prefixes []string, onAlert func(LatencyAlert), logger zerolog.Logger) *LatencyTracker {
+	byRoute := make(map[string]time.Duration, len(budgets))
+	for _, b := range budgets {
+		byRoute[normalizeRouteKey(b.Route)] = b.P99
+	}
+	return &LatencyTracker{
+		window:        window,
+		alertAfter:    alertAfter,
+		defaultBudget: defaultBudget,
+		budgets:       byRoute,
+		prefixes:      prefixes,
+		onAlert:       onAlert,
+		logger:        logger.With().Str("component", "latency").Logger(),
+		routes:        make(map[string]*routeWindow),
+	}
+}
+
+// Middleware records the duration of every matched request. It must be installed
+// with mux's Router.Use so the matched route template is available. Long polls
+// (?wait) are held open on purpose and are not recorded.
+func (t *LatencyTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		if r.URL.Query().Get("wait") != "" {
+			return
+		}
+		key, ok := t.routeKey(r)
+		if !ok {
+			return
+		}
+		t.record(key, time.Since(start))
+	})
+}
+
+// Run evaluates budgets every interval until ctx is cancelled.
+func (t *LatencyTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.evaluate(now)
+		}
+	}
+}
+
+// Snapshot returns current percentiles for every route seen within the window.
+func (t *LatencyTracker) Snapshot() []RouteLatency {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]RouteLatency, 0, len(t.routes))
+	for key, rw := range t.routes {
+		rw.prune(now.Add(-t.window))
+		if len(rw.samples) == 0 {
+			continue
+		}
+		out = append(out, t.describe(key, rw))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Route < out[j].Route })
+	return out
+}
+
+func (t *LatencyTracker) record(key string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Stamped under the lock so samples stay ordered for pruning.
+	at := time.Now()
+	rw, ok := t.routes[key]
+	if !ok {
+		rw = &routeWindow{}
+		t.routes[key] = rw
+	}
+	rw.samples = append(rw.samples, latencySample{at: at, duration: d})
+	if len(rw.samples) > maxLatencySamples {
+		rw.samples = rw.samples[len(rw.samples)-maxLatencySamples:]
+	}
+}
+
+func (t *LatencyTracker) evaluate(now time.Time) {
+	var alerts []LatencyAlert
+
+	t.mu.Lock()
+	for key, rw := range t.routes {
+		rw.prune(now.Add(-t.window))
+		budget := t.budgetFor(key)
+		if budget <= 0 {
+			continue
+		}
+		p99 := percentile(durations(rw.samples), 0.99)
+		if len(rw.samples) > 0 && p99 > budget {
+			if rw.breachedSince == nil {
+				since := now
+				rw.breachedSince = &since
+			}
+			if !rw.alerting && now.Sub(*rw.breachedSince) >= t.alertAfter {
+				rw.alerting = true
+				alerts = append(alerts, LatencyAlert{Route: key, P99: p99, Budget: budget, BreachedSince: *rw.breachedSince})
+			}
+			continue
+		}
+		if rw.alerting {
+			alerts = append(alerts, LatencyAlert{Route: key, P99: p99, Budget: budget, BreachedSince: *rw.breachedSince, Resolved: true})
+		}
+		rw.breachedSince, rw.alerting = nil, false
+	}
+	t.mu.Unlock()
+
+	for _, alert := range alerts {
+		if alert.Resolved {
+			t.logger.Info().Str("route", alert.Route).Dur("p99", alert.P99).Dur("budget", alert.Budget).Msg("latency budget recovered")
+		} else {
+			t.logger.WithLevel(zerolog.WarnLevel).Str("event", "latency_budget_exceeded").Str("route", alert.Route).
+				Dur("p99", alert.P99).Dur("budget", alert.Budget).Time("breached_since", alert.BreachedSince).
+				Msg("latency budget exceeded")
+		}
+		if t.onAlert != nil {
+			t.onAlert(alert)
+		}
+	}
+}
+
+func (t *LatencyTracker) describe(key string, rw *routeWindow) RouteLatency {
+	ds := durations(rw.samples)
+	budget := t.budgetFor(key)
+	p99 := percentile(ds, 0.99)
+	return RouteLatency{
+		Route:         key,
+		Count:         len(ds),
+		P50Ms:         ms(percentile(ds, 0.50)),
+		P90Ms:         ms(percentile(ds, 0.90)),
+		P95Ms:         ms(percentile(ds, 0.95)),
+		P99Ms:         ms(p99),
+		MaxMs:         ms(ds[len(ds)-1]),
+		BudgetMs:      ms(budget),
+		OverBudget:    budget > 0 && p99 > budget,
+		BreachedSince: rw.breachedSince,
+		Alerting:      rw.alerting,
+	}
+}
+
+func (t *LatencyTracker) budgetFor(key string) time.Duration {
+	if b, ok := t.budgets[key]; ok {
+		return b
+	}
+	return t.defaultBudget
+}
+
+func (t *LatencyTracker) routeKey(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+	for _, prefix := range t.prefixes {
+		if strings.HasPrefix(tpl, prefix+"/") {
+			tpl = strings.TrimPrefix(tpl, prefix)
+			break
+		}
+	}
+	return r.Method + " " + tpl, true
+}
+
+func (rw *routeWindow) prune(cutoff time.Time) {
+	i := sort.Search(len(rw.samples), func(i int) bool { return !rw.samples[i].at.Before(cutoff) })
+	if i > 0 {
+		rw.samples = append(rw.samples[:0], rw.samples[i:]...)
+	}
+}
+
+// durations returns the sample durations sorted ascending.
+func durations(samples []latencySample) []time.Duration {
+	ds := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		ds[i] = s.duration
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	return ds
+}
+
+// percentile uses the nearest-rank method on sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func normalizeRouteKey(key string) string {
+	method, path, ok := strings.Cut(strings.TrimSpace(key), " ")
+	if !ok {
+		return key
+	}
+	return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+}
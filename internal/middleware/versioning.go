@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stanstork/stratum-api/internal/version"
+)
+
+// APIVersionHeader carries the negotiated API contract on requests and responses.
+const APIVersionHeader = "X-API-Version"
+
+var vendorMediaType = regexp.MustCompile(`application/vnd\.stratum\.(v[0-9]+)\+json`)
+
+// Deprecation describes an endpoint scheduled for removal or change.
+type Deprecation struct {
+	Since     time.Time `json:"since"`
+	Sunset    time.Time `json:"sunset"`
+	Successor string    `json:"successor,omitempty"`
+}
+
+// requestedContract extracts the contract a client asked for, either through the
+// X-API-Version header or an application/vnd.stratum.vN+json Accept media type.
+func requestedContract(r *http.Request) string {
+	if v := strings.ToLower(strings.TrimSpace(r.Header.Get(APIVersionHeader))); v != "" {
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		return v
+	}
+	if m := vendorMediaType.FindStringSubmatch(r.Header.Get("Accept")); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// NegotiateVersion resolves the API contract for requests under a route prefix.
+// pathContract is the contract implied by the URL ("" for unversioned aliases); a
+// conflicting or unsupported requested contract is rejected.
+func NegotiateVersion(pathContract string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contract := requestedContract(r)
+			switch {
+			case contract == "":
+				contract = pathContract
+				if contract == "" {
+					contract = version.CurrentContract
+				}
+			case !version.IsSupported(contract):
+				w.Header().Set(APIVersionHeader, strings.Join(version.SupportedContracts, ", "))
+				http.Error(w, "Unsupported API version: "+contract, http.StatusNotAcceptable)
+				return
+			case pathContract != "" && contract != pathContract:
+				http.Error(w, "Requested API version "+contract+" does not match path version "+pathContract, http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set(APIVersionHeader, contract)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DeprecatedAlias marks every response under an unversioned prefix as deprecated and
+// links to the equivalent path under the versioned prefix.
+func DeprecatedAlias(aliasPrefix, successorPrefix string, since, sunset time.Time) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			successor := successorPrefix + strings.TrimPrefix(r.URL.Path, aliasPrefix)
+			SetDeprecationHeaders(w, Deprecation{Since: since, Sunset: sunset, Successor: successor})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DeprecatedEndpoints emits deprecation headers for individual routes. Registry keys
+// are "METHOD /path/template" relative to prefix, e.g. "POST /connections/test".
+func DeprecatedEndpoints(prefix string, registry map[string]Deprecation) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if tpl, err := route.GetPathTemplate(); err == nil {
+					key := r.Method + " " + strings.TrimPrefix(tpl, prefix)
+					if dep, ok := registry[key]; ok {
+						if dep.Successor != "" && strings.HasPrefix(dep.Successor, "/") {
+							dep.Successor = prefix + dep.Successor
+						}
+						SetDeprecationHeaders(w, dep)
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetDeprecationHeaders writes RFC 9745 Deprecation and RFC 8594 Sunset headers.
+// Handlers call it directly for deprecated request forms of an endpoint.
+func SetDeprecationHeaders(w http.ResponseWriter, dep Deprecation) {
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(dep.Since.Unix(), 10))
+	if !dep.Sunset.IsZero() {
+		w.Header().Set("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if dep.Successor != "" {
+		w.Header().Add("Link", "<"+dep.Successor+`>; rel="successor-version"`)
+	}
+}
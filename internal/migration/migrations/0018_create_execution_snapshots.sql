@@ -0,0 +1,41 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.execution_snapshots (
+    execution_id UUID PRIMARY KEY REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    job_definition_id UUID NOT NULL,
+    ast_hash TEXT NOT NULL,
+    ast JSONB NOT NULL,
+    engine_image TEXT NOT NULL,
+    engine_image_digest TEXT,
+    source_connection JSONB NOT NULL,
+    destination_connection JSONB NOT NULL,
+    parameters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    container_cpu_limit BIGINT NOT NULL DEFAULT 0,
+    container_memory_limit BIGINT NOT NULL DEFAULT 0,
+    api_version TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_snapshots_tenant
+    ON tenant.execution_snapshots (tenant_id);
+
+-- Snapshots are audit evidence; reject any attempt to rewrite them.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tenant.reject_execution_snapshot_update() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'execution snapshots are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_execution_snapshots_immutable
+  BEFORE UPDATE ON tenant.execution_snapshots
+  FOR EACH ROW EXECUTE FUNCTION tenant.reject_execution_snapshot_update();
+
+-- +goose Down
+
+DROP TRIGGER IF EXISTS trg_execution_snapshots_immutable ON tenant.execution_snapshots;
+DROP FUNCTION IF EXISTS tenant.reject_execution_snapshot_update();
+DROP INDEX IF EXISTS idx_execution_snapshots_tenant;
+DROP TABLE IF EXISTS tenant.execution_snapshots;
@@ -0,0 +1,35 @@
+-- +goose Up
+
+ALTER TABLE tenant.users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- Existing accounts count as verified, so turning the tenant policy on only
+-- affects accounts created from here on.
+UPDATE tenant.users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS tenant.email_verifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES tenant.users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user
+    ON tenant.email_verifications (user_id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_email_verifications_user;
+DROP TABLE IF EXISTS tenant.email_verifications;
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS require_verified_email;
+
+ALTER TABLE tenant.users
+    DROP COLUMN IF EXISTS email_verified_at;
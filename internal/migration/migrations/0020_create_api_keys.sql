@@ -0,0 +1,25 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant
+    ON tenant.api_keys (tenant_id) WHERE revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_job_executions_tenant_created_at
+    ON tenant.job_executions (tenant_id, created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_job_executions_tenant_created_at;
+DROP INDEX IF EXISTS idx_api_keys_tenant;
+DROP TABLE IF EXISTS tenant.api_keys;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.scheduler_runs (
+    task_name TEXT PRIMARY KEY,
+    last_started_at TIMESTAMPTZ NOT NULL,
+    last_finished_at TIMESTAMPTZ,
+    last_error TEXT,
+    holder TEXT NOT NULL
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.scheduler_runs;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Ephemeral connections back inline DSNs on a single job definition. They are
+-- never listed and are removed together with their definition.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS ephemeral BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS ephemeral;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.execution_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    content JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (execution_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_artifacts_tenant
+    ON tenant.execution_artifacts (tenant_id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_execution_artifacts_tenant;
+DROP TABLE IF EXISTS tenant.execution_artifacts;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.execution_checkpoints (
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    last_key JSONB,
+    rows_processed BIGINT NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (execution_id, table_name)
+);
+
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS resumed_from_execution_id UUID REFERENCES tenant.job_executions(id) ON DELETE SET NULL;
+
+-- +goose Down
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS resumed_from_execution_id;
+DROP TABLE IF EXISTS tenant.execution_checkpoints;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en-US';
+
+-- +goose Down
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone;
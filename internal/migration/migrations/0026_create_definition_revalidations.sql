@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Pending background revalidations of READY definitions whose connections changed.
+CREATE TABLE IF NOT EXISTS tenant.definition_revalidations (
+    job_definition_id UUID PRIMARY KEY REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    connection_id UUID REFERENCES tenant.connections(id) ON DELETE SET NULL,
+    enqueued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_definition_revalidations_enqueued
+    ON tenant.definition_revalidations (enqueued_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_definition_revalidations_enqueued;
+DROP TABLE IF EXISTS tenant.definition_revalidations;
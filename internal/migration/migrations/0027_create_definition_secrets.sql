@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- Secret values referenced from definition ASTs, encrypted with the secrets key.
+CREATE TABLE IF NOT EXISTS tenant.definition_secrets (
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (job_definition_id, name)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.definition_secrets;
@@ -0,0 +1,17 @@
+-- +goose Up
+
+-- NULL means the deployment's default storage region.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS data_region TEXT;
+
+ALTER TABLE tenant.execution_artifacts
+    ADD COLUMN IF NOT EXISTS storage_region TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS storage_location TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+
+ALTER TABLE tenant.execution_artifacts
+    DROP COLUMN IF EXISTS storage_location,
+    DROP COLUMN IF EXISTS storage_region;
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS data_region;
@@ -0,0 +1,24 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.execution_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', body)) STORED,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_notes_execution
+    ON tenant.execution_notes (execution_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_execution_notes_search
+    ON tenant.execution_notes USING GIN (search_vector);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_execution_notes_search;
+DROP INDEX IF EXISTS idx_execution_notes_execution;
+DROP TABLE IF EXISTS tenant.execution_notes;
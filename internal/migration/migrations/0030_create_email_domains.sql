@@ -0,0 +1,39 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS tenant.email_domains (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    require_approval BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMPTZ,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, domain)
+);
+
+-- A domain can be claimed by many tenants but verified by only one.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_domains_verified
+    ON tenant.email_domains (domain) WHERE verified_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tenant.domain_join_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL UNIQUE REFERENCES tenant.users(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    decided_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_join_requests_tenant
+    ON tenant.domain_join_requests (tenant_id, status, created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_domain_join_requests_tenant;
+DROP TABLE IF EXISTS tenant.domain_join_requests;
+DROP INDEX IF EXISTS idx_email_domains_verified;
+DROP TABLE IF EXISTS tenant.email_domains;
@@ -0,0 +1,17 @@
+-- +goose Up
+
+-- Deleted tenants are suspended first and purged once purge_after has passed.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS purge_after TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_tenants_purge_after
+    ON tenant.tenants (purge_after) WHERE suspended_at IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_tenants_purge_after;
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS purge_after,
+    DROP COLUMN IF EXISTS suspended_at;
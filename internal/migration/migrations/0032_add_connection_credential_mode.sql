@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Connections in 'prompt' mode never store a password; it is supplied with each run.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS credential_mode TEXT NOT NULL DEFAULT 'stored'
+        CHECK (credential_mode IN ('stored', 'prompt'));
+
+-- +goose Down
+
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS credential_mode;
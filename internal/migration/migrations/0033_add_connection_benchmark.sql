@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- Latest latency/throughput benchmark of the connection, as reported by the engine.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS benchmark JSONB;
+
+-- +goose Down
+
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS benchmark;
@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- Erased users keep their row as a tombstone so foreign keys stay intact, but every
+-- piece of personal data on it is overwritten.
+ALTER TABLE tenant.users
+    ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_pending_erasure
+    ON tenant.users (deleted_at) WHERE deleted_at IS NOT NULL AND erased_at IS NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_users_pending_erasure;
+
+ALTER TABLE tenant.users
+    DROP COLUMN IF EXISTS erased_at;
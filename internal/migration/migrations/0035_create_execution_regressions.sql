@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- One row per metric of a succeeded execution that fell behind its definition's
+-- baseline. The baseline values are copied in since the baseline moves over time.
+CREATE TABLE IF NOT EXISTS tenant.execution_regressions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    metric TEXT NOT NULL CHECK (metric IN ('duration', 'throughput')),
+    baseline_value DOUBLE PRECISION NOT NULL,
+    observed_value DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    baseline_runs INTEGER NOT NULL,
+    engine_image_digest TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (execution_id, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_regressions_definition
+    ON tenant.execution_regressions (tenant_id, job_definition_id, created_at DESC);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_execution_regressions_definition;
+DROP TABLE IF EXISTS tenant.execution_regressions;
@@ -0,0 +1,42 @@
+-- +goose Up
+
+-- Security-relevant changes (membership, roles, API keys, tenant lifecycle) with the
+-- user who made them. tenant_id is NULL for platform-wide actions.
+CREATE TABLE IF NOT EXISTS tenant.audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    details JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created
+    ON tenant.audit_events (created_at);
+
+-- Every API request with the identity it was authenticated as, if any.
+CREATE TABLE IF NOT EXISTS tenant.access_logs (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID,
+    user_id UUID,
+    method TEXT NOT NULL,
+    route TEXT NOT NULL,
+    path TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    remote_addr TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_logs_created
+    ON tenant.access_logs (created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_access_logs_created;
+DROP TABLE IF EXISTS tenant.access_logs;
+DROP INDEX IF EXISTS idx_audit_events_created;
+DROP TABLE IF EXISTS tenant.audit_events;
@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- Run grants let users below editor run a specific definition. Each row grants either
+-- one user or everyone holding at least a role.
+CREATE TABLE IF NOT EXISTS tenant.job_run_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES tenant.users(id) ON DELETE CASCADE,
+    role TEXT,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((user_id IS NULL) <> (role IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_run_grants_user
+    ON tenant.job_run_grants (job_definition_id, user_id) WHERE user_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_run_grants_role
+    ON tenant.job_run_grants (job_definition_id, role) WHERE role IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_job_run_grants_role;
+DROP INDEX IF EXISTS idx_job_run_grants_user;
+DROP TABLE IF EXISTS tenant.job_run_grants;
@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- environment labels what a connection points at (prod, staging or dev); NULL means
+-- unlabeled. tags are free-form lowercase labels for filtering.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS environment TEXT CHECK (environment IN ('prod', 'staging', 'dev')),
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_connections_tags
+    ON tenant.connections USING GIN (tags);
+
+-- When set, definitions whose source and destination are labeled with different
+-- environments can neither be marked READY nor run.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS block_cross_environment_jobs BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS block_cross_environment_jobs;
+
+DROP INDEX IF EXISTS idx_connections_tags;
+
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS environment;
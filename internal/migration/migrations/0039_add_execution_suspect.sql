@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- suspect flags a succeeded execution that moved no data although earlier runs of
+-- its definition did; suspect_reason says why.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS suspect BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS suspect_reason TEXT;
+
+-- +goose Down
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS suspect_reason,
+    DROP COLUMN IF EXISTS suspect;
@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- Reusable job ASTs. Rows without a tenant are curated global templates visible to
+-- every tenant. kind 'job' templates create whole definitions; 'fragment' templates
+-- are pieces of an AST the client splices into its own.
+CREATE TABLE IF NOT EXISTS tenant.job_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL DEFAULT 'job' CHECK (kind IN ('job', 'fragment')),
+    ast JSONB NOT NULL,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_templates_tenant_name
+    ON tenant.job_templates (tenant_id, name) WHERE tenant_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_templates_global_name
+    ON tenant.job_templates (name) WHERE tenant_id IS NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_job_templates_global_name;
+DROP INDEX IF EXISTS idx_job_templates_tenant_name;
+DROP TABLE IF EXISTS tenant.job_templates;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- activity_attempts maps the name of each workflow activity that had to be retried
+-- to its latest attempt number.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS activity_attempts JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS activity_attempts;
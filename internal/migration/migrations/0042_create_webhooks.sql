@@ -0,0 +1,50 @@
+-- +goose Up
+
+-- webhook_subscriptions are the tenant endpoints events are delivered to. Each
+-- subscription covers one event category; an empty events list receives every
+-- event of the category. secret is encrypted with the secrets key and signs every
+-- payload.
+CREATE TABLE IF NOT EXISTS tenant.webhook_subscriptions (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id  UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    category   TEXT NOT NULL CHECK (category IN ('user')),
+    url        TEXT NOT NULL,
+    secret     BYTEA NOT NULL,
+    events     TEXT[] NOT NULL DEFAULT '{}',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_tenant
+    ON tenant.webhook_subscriptions (tenant_id, category);
+
+-- webhook_deliveries is the outbox: one row per event and subscription, queued
+-- when the event is raised and drained by the delivery task.
+CREATE TABLE IF NOT EXISTS tenant.webhook_deliveries (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES tenant.webhook_subscriptions(id) ON DELETE CASCADE,
+    tenant_id       UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    event_type      TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT,
+    response_status INT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON tenant.webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription
+    ON tenant.webhook_deliveries (subscription_id, created_at DESC);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.webhook_deliveries;
+DROP TABLE IF EXISTS tenant.webhook_subscriptions;
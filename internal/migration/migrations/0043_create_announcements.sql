@@ -0,0 +1,41 @@
+-- +goose Up
+
+-- Banner messages shown in the UI. Rows without a tenant are global and shown to
+-- every tenant. A banner is shown from starts_at until ends_at, or indefinitely
+-- when ends_at is NULL. source 'maintenance' rows are posted when maintenance mode
+-- is toggled.
+CREATE TABLE IF NOT EXISTS tenant.announcements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    severity TEXT NOT NULL DEFAULT 'info' CHECK (severity IN ('info', 'warning', 'critical')),
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ends_at TIMESTAMPTZ,
+    source TEXT NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'maintenance')),
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_window
+    ON tenant.announcements (starts_at, ends_at);
+
+-- maintenance_mode holds the single platform-wide maintenance switch.
+-- announcement_id is the banner posted when maintenance mode was enabled.
+CREATE TABLE IF NOT EXISTS tenant.maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    announcement_id UUID REFERENCES tenant.announcements(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO tenant.maintenance_mode (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.maintenance_mode;
+DROP INDEX IF EXISTS idx_announcements_window;
+DROP TABLE IF EXISTS tenant.announcements;
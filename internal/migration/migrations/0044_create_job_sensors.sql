@@ -0,0 +1,58 @@
+-- +goose Up
+
+-- Executions whose sensors timed out with on_timeout 'skip' end as skipped.
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'succeeded', 'failed', 'skipped'));
+
+-- Sensors are probes a definition's runs wait on before they start: a table's row
+-- count, a file landing on the worker or an HTTP endpoint answering. Each sensor is
+-- re-evaluated every poke_interval_seconds until it passes or timeout_seconds have
+-- passed, when on_timeout decides whether the run is skipped, failed or run anyway.
+CREATE TABLE IF NOT EXISTS tenant.job_sensors (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('row_count', 'file', 'http')),
+    config JSONB NOT NULL DEFAULT '{}'::jsonb,
+    poke_interval_seconds INT NOT NULL DEFAULT 60 CHECK (poke_interval_seconds > 0),
+    timeout_seconds INT NOT NULL DEFAULT 3600 CHECK (timeout_seconds > 0),
+    on_timeout TEXT NOT NULL DEFAULT 'fail' CHECK (on_timeout IN ('skip', 'fail', 'run')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (job_definition_id, name)
+);
+
+-- One row per sensor probe. observed_value is the probe's measurement: the row
+-- count, the file size or the HTTP status.
+CREATE TABLE IF NOT EXISTS tenant.sensor_evaluations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    sensor_id UUID NOT NULL REFERENCES tenant.job_sensors(id) ON DELETE CASCADE,
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    passed BOOLEAN NOT NULL,
+    observed_value BIGINT,
+    detail TEXT NOT NULL DEFAULT '',
+    evaluated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sensor_evaluations_sensor
+    ON tenant.sensor_evaluations (sensor_id, evaluated_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_sensor_evaluations_definition
+    ON tenant.sensor_evaluations (job_definition_id, evaluated_at DESC);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.sensor_evaluations;
+DROP TABLE IF EXISTS tenant.job_sensors;
+
+UPDATE tenant.job_executions SET status = 'failed' WHERE status = 'skipped';
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'succeeded', 'failed'));
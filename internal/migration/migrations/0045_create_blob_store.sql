@@ -0,0 +1,125 @@
+-- +goose Up
+
+-- Content-addressable blobs, keyed by the hex SHA-256 of their content and split
+-- into chunks so they can be streamed in and out.
+CREATE TABLE IF NOT EXISTS tenant.blobs (
+    hash TEXT PRIMARY KEY,
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    touched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS tenant.blob_chunks (
+    hash TEXT NOT NULL REFERENCES tenant.blobs(hash) ON UPDATE CASCADE ON DELETE CASCADE,
+    seq INT NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (hash, seq)
+);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tenant.blob_content(blob_hash TEXT) RETURNS BYTEA AS $$
+  SELECT string_agg(data, ''::bytea ORDER BY seq) FROM tenant.blob_chunks WHERE hash = blob_hash;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS ast_hash TEXT,
+    ADD COLUMN IF NOT EXISTS progress_snapshot_hash TEXT;
+
+ALTER TABLE tenant.job_definition_snapshots
+    ADD COLUMN IF NOT EXISTS snapshot_hash TEXT,
+    ALTER COLUMN snapshot DROP NOT NULL;
+
+ALTER TABLE tenant.execution_snapshots
+    ALTER COLUMN ast DROP NOT NULL;
+
+ALTER TABLE tenant.execution_artifacts
+    ADD COLUMN IF NOT EXISTS content_hash TEXT,
+    ALTER COLUMN content DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_job_definitions_ast_hash ON tenant.job_definitions (ast_hash);
+CREATE INDEX IF NOT EXISTS idx_job_definitions_progress_snapshot_hash ON tenant.job_definitions (progress_snapshot_hash);
+CREATE INDEX IF NOT EXISTS idx_job_definition_snapshots_hash ON tenant.job_definition_snapshots (snapshot_hash);
+CREATE INDEX IF NOT EXISTS idx_execution_snapshots_ast_hash ON tenant.execution_snapshots (ast_hash);
+CREATE INDEX IF NOT EXISTS idx_execution_artifacts_content_hash ON tenant.execution_artifacts (content_hash);
+
+-- Move existing payloads into the store. Execution snapshots are immutable audit
+-- evidence and keep their inline AST.
+-- +goose StatementBegin
+CREATE FUNCTION tenant.migrate_to_blob(content BYTEA) RETURNS TEXT AS $$
+DECLARE
+  digest TEXT := encode(sha256(content), 'hex');
+BEGIN
+  INSERT INTO tenant.blobs (hash, size) VALUES (digest, octet_length(content)) ON CONFLICT DO NOTHING;
+  INSERT INTO tenant.blob_chunks (hash, seq, data) VALUES (digest, 0, content) ON CONFLICT DO NOTHING;
+  RETURN digest;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+UPDATE tenant.job_definitions
+SET ast_hash = tenant.migrate_to_blob(convert_to(ast::text, 'UTF8')), ast = NULL
+WHERE ast IS NOT NULL;
+
+UPDATE tenant.job_definitions
+SET progress_snapshot_hash = tenant.migrate_to_blob(convert_to(progress_snapshot::text, 'UTF8')), progress_snapshot = NULL
+WHERE progress_snapshot IS NOT NULL;
+
+UPDATE tenant.job_definition_snapshots
+SET snapshot_hash = tenant.migrate_to_blob(convert_to(snapshot::text, 'UTF8')), snapshot = NULL
+WHERE snapshot IS NOT NULL;
+
+UPDATE tenant.execution_artifacts
+SET content_hash = tenant.migrate_to_blob(convert_to(content::text, 'UTF8')), content = NULL
+WHERE content IS NOT NULL;
+
+DROP FUNCTION tenant.migrate_to_blob(BYTEA);
+
+-- +goose Down
+
+UPDATE tenant.job_definitions
+SET ast = convert_from(tenant.blob_content(ast_hash), 'UTF8')::jsonb
+WHERE ast IS NULL AND ast_hash IS NOT NULL;
+
+UPDATE tenant.job_definitions
+SET progress_snapshot = convert_from(tenant.blob_content(progress_snapshot_hash), 'UTF8')::jsonb
+WHERE progress_snapshot IS NULL AND progress_snapshot_hash IS NOT NULL;
+
+UPDATE tenant.job_definition_snapshots
+SET snapshot = convert_from(tenant.blob_content(snapshot_hash), 'UTF8')::jsonb
+WHERE snapshot IS NULL AND snapshot_hash IS NOT NULL;
+
+UPDATE tenant.execution_artifacts
+SET content = convert_from(tenant.blob_content(content_hash), 'UTF8')::jsonb
+WHERE content IS NULL AND content_hash IS NOT NULL;
+
+ALTER TABLE tenant.execution_snapshots DISABLE TRIGGER trg_execution_snapshots_immutable;
+UPDATE tenant.execution_snapshots
+SET ast = convert_from(tenant.blob_content(ast_hash), 'UTF8')::jsonb
+WHERE ast IS NULL;
+ALTER TABLE tenant.execution_snapshots ENABLE TRIGGER trg_execution_snapshots_immutable;
+
+DROP INDEX IF EXISTS idx_execution_artifacts_content_hash;
+DROP INDEX IF EXISTS idx_execution_snapshots_ast_hash;
+DROP INDEX IF EXISTS idx_job_definition_snapshots_hash;
+DROP INDEX IF EXISTS idx_job_definitions_progress_snapshot_hash;
+DROP INDEX IF EXISTS idx_job_definitions_ast_hash;
+
+ALTER TABLE tenant.execution_artifacts
+    ALTER COLUMN content SET NOT NULL,
+    DROP COLUMN IF EXISTS content_hash;
+
+ALTER TABLE tenant.execution_snapshots
+    ALTER COLUMN ast SET NOT NULL;
+
+ALTER TABLE tenant.job_definition_snapshots
+    ALTER COLUMN snapshot SET NOT NULL,
+    DROP COLUMN IF EXISTS snapshot_hash;
+
+ALTER TABLE tenant.job_definitions
+    DROP COLUMN IF EXISTS progress_snapshot_hash,
+    DROP COLUMN IF EXISTS ast_hash;
+
+DROP FUNCTION IF EXISTS tenant.blob_content(TEXT);
+DROP TABLE IF EXISTS tenant.blob_chunks;
+DROP TABLE IF EXISTS tenant.blobs;
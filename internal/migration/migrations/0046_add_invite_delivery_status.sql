@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- Invite emails are delivered from an outbox on the invite itself. The raw token
+-- is kept, encrypted, only until delivery succeeds or is given up on.
+ALTER TABLE tenant.invites
+    ADD COLUMN IF NOT EXISTS delivery_status TEXT NOT NULL DEFAULT 'sent'
+        CHECK (delivery_status IN ('pending', 'sent', 'failed')),
+    ADD COLUMN IF NOT EXISTS delivery_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS delivery_error TEXT,
+    ADD COLUMN IF NOT EXISTS next_delivery_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS delivery_token BYTEA;
+
+CREATE INDEX IF NOT EXISTS idx_invites_delivery_due
+    ON tenant.invites (next_delivery_at)
+    WHERE delivery_status = 'pending';
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_invites_delivery_due;
+
+ALTER TABLE tenant.invites
+    DROP COLUMN IF EXISTS delivery_token,
+    DROP COLUMN IF EXISTS delivered_at,
+    DROP COLUMN IF EXISTS next_delivery_at,
+    DROP COLUMN IF EXISTS delivery_error,
+    DROP COLUMN IF EXISTS delivery_attempts,
+    DROP COLUMN IF EXISTS delivery_status;
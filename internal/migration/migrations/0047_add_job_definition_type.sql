@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS job_type TEXT NOT NULL DEFAULT 'engine'
+        CHECK (job_type IN ('engine', 'sql_script'));
+
+-- +goose Down
+ALTER TABLE tenant.job_definitions
+    DROP COLUMN IF EXISTS job_type;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- The execution calendar places runs at their start, or their creation before they start.
+CREATE INDEX IF NOT EXISTS idx_job_executions_tenant_calendar
+    ON tenant.job_executions (tenant_id, (COALESCE(run_started_at, created_at)));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_job_executions_tenant_calendar;
@@ -0,0 +1,44 @@
+-- +goose Up
+
+-- Per-definition execution totals for each calendar month (UTC). Rows outlive the
+-- executions they summarize, so long-range stats do not need the raw rows.
+CREATE TABLE IF NOT EXISTS tenant.execution_monthly_rollups (
+    tenant_id UUID NOT NULL,
+    job_definition_id UUID NOT NULL,
+    month DATE NOT NULL,
+    executions BIGINT NOT NULL DEFAULT 0,
+    succeeded BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    records_processed BIGINT NOT NULL DEFAULT 0,
+    bytes_transferred BIGINT NOT NULL DEFAULT 0,
+    -- Sum and count of the run durations of completed runs, for averages.
+    duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    timed_runs BIGINT NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, job_definition_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_monthly_rollups_tenant_month
+    ON tenant.execution_monthly_rollups (tenant_id, month);
+
+INSERT INTO tenant.execution_monthly_rollups (
+    tenant_id, job_definition_id, month, executions, succeeded, failed,
+    records_processed, bytes_transferred, duration_seconds, timed_runs)
+SELECT
+    tenant_id,
+    job_definition_id,
+    date_trunc('month', created_at AT TIME ZONE 'UTC')::date,
+    COUNT(*),
+    COUNT(*) FILTER (WHERE status = 'succeeded'),
+    COUNT(*) FILTER (WHERE status = 'failed'),
+    COALESCE(SUM(records_processed), 0),
+    COALESCE(SUM(bytes_transferred), 0),
+    COALESCE(SUM(EXTRACT(EPOCH FROM (run_completed_at - run_started_at))), 0),
+    COUNT(run_completed_at - run_started_at)
+FROM tenant.job_executions
+WHERE tenant_id IS NOT NULL
+GROUP BY 1, 2, 3
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.execution_monthly_rollups;
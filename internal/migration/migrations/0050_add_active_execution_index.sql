@@ -0,0 +1,8 @@
+-- +goose Up
+-- The execution reconciler scans the executions still pending or running.
+CREATE INDEX IF NOT EXISTS idx_job_executions_active
+    ON tenant.job_executions (updated_at)
+    WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_job_executions_active;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Per-tenant overrides of the worker's container limits and engine image. NULL
+-- columns fall back to the worker configuration.
+CREATE TABLE IF NOT EXISTS tenant.tenant_worker_configs (
+    tenant_id UUID PRIMARY KEY REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    cpu_limit BIGINT,
+    memory_limit BIGINT,
+    engine_image TEXT,
+    allowed_engine_images TEXT[] NOT NULL DEFAULT '{}',
+    max_execution_seconds INT,
+    updated_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.tenant_worker_configs;
@@ -0,0 +1,37 @@
+-- +goose Up
+
+-- Named filters and sorts for the executions and jobs lists. Rows without an owner
+-- are shared with every member of the tenant.
+CREATE TABLE IF NOT EXISTS tenant.saved_views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    owner_id UUID REFERENCES tenant.users(id) ON DELETE CASCADE,
+    list TEXT NOT NULL CHECK (list IN ('executions', 'jobs')),
+    name TEXT NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    sort TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_views_owner_name
+    ON tenant.saved_views (owner_id, list, name) WHERE owner_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_views_shared_name
+    ON tenant.saved_views (tenant_id, list, name) WHERE owner_id IS NULL;
+
+-- The view each user opens a list with.
+CREATE TABLE IF NOT EXISTS tenant.default_views (
+    user_id UUID NOT NULL REFERENCES tenant.users(id) ON DELETE CASCADE,
+    list TEXT NOT NULL,
+    view_id UUID NOT NULL REFERENCES tenant.saved_views(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, list)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.default_views;
+DROP INDEX IF EXISTS idx_saved_views_shared_name;
+DROP INDEX IF EXISTS idx_saved_views_owner_name;
+DROP TABLE IF EXISTS tenant.saved_views;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- What started each execution. Runs from before tracking are NULL, except resumed
+-- runs, which are retries.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS triggered_by TEXT
+        CHECK (triggered_by IN ('user', 'api_key', 'schedule', 'retry', 'pipeline')),
+    ADD COLUMN IF NOT EXISTS trigger_context JSONB NOT NULL DEFAULT '{}';
+
+UPDATE tenant.job_executions
+SET triggered_by = 'retry',
+    trigger_context = jsonb_build_object('resumed_from_execution_id', resumed_from_execution_id)
+WHERE resumed_from_execution_id IS NOT NULL AND triggered_by IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_job_executions_tenant_trigger
+    ON tenant.job_executions (tenant_id, triggered_by, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_job_executions_tenant_trigger;
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS trigger_context,
+    DROP COLUMN IF EXISTS triggered_by;
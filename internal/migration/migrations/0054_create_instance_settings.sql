@@ -0,0 +1,19 @@
+-- +goose Up
+-- Settings of a self-hosted instance. The single row is written by the setup wizard;
+-- once it exists, setup is locked for good. Instances that already have tenants are
+-- locked here so the wizard never opens on them.
+CREATE TABLE IF NOT EXISTS tenant.instance_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    name TEXT NOT NULL,
+    setup_tenant_id UUID REFERENCES tenant.tenants(id) ON DELETE SET NULL,
+    setup_user_id UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    setup_completed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO tenant.instance_settings (name)
+SELECT 'Stratum'
+WHERE EXISTS (SELECT 1 FROM tenant.tenants)
+ON CONFLICT (id) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.instance_settings;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- Long-running executions can be paused: the engine checkpoints and idles until
+-- resumed. Paused executions stay active for the reconciler.
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'paused', 'succeeded', 'failed', 'skipped'));
+
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_job_executions_active;
+CREATE INDEX IF NOT EXISTS idx_job_executions_active
+    ON tenant.job_executions (updated_at)
+    WHERE status IN ('pending', 'running', 'paused');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_job_executions_active;
+CREATE INDEX IF NOT EXISTS idx_job_executions_active
+    ON tenant.job_executions (updated_at)
+    WHERE status IN ('pending', 'running');
+
+UPDATE tenant.job_executions SET status = 'running' WHERE status = 'paused';
+
+ALTER TABLE tenant.job_executions DROP COLUMN IF EXISTS paused_at;
+
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'succeeded', 'failed', 'skipped'));
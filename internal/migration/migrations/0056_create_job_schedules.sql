@@ -0,0 +1,24 @@
+-- +goose Up
+-- Cron schedules of job definitions. Each row mirrors a Temporal Schedule that
+-- starts the definition's execution workflow.
+CREATE TABLE IF NOT EXISTS tenant.job_schedules (
+    job_definition_id UUID PRIMARY KEY REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    cron_expression TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    temporal_schedule_id TEXT NOT NULL UNIQUE,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_schedules_tenant ON tenant.job_schedules (tenant_id);
+
+-- Scheduled runs get their workflow ID from Temporal rather than from the
+-- execution ID, so it is recorded with the execution.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS workflow_id TEXT;
+
+-- +goose Down
+ALTER TABLE tenant.job_executions DROP COLUMN IF EXISTS workflow_id;
+DROP TABLE IF EXISTS tenant.job_schedules;
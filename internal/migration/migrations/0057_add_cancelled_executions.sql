@@ -0,0 +1,14 @@
+-- +goose Up
+-- Executions can be cancelled on request; cancelled is a terminal status.
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'paused', 'succeeded', 'failed', 'skipped', 'cancelled'));
+
+-- +goose Down
+UPDATE tenant.job_executions SET status = 'failed' WHERE status = 'cancelled';
+
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'paused', 'succeeded', 'failed', 'skipped'));
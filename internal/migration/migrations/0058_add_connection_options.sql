@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Driver options appended to the connection string (charset, TLS, timeouts), and
+-- the server version the last successful test-conn reported.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS server_version TEXT;
+
+-- +goose Down
+
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS server_version,
+    DROP COLUMN IF EXISTS options;
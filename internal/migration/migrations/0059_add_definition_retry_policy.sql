@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Per-definition override of the engine run's retry policy; NULL keeps the
+-- worker's defaults.
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS retry_policy JSONB;
+
+-- +goose Down
+
+ALTER TABLE tenant.job_definitions
+    DROP COLUMN IF EXISTS retry_policy;
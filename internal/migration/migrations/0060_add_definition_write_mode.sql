@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- How the engine writes destination tables: append, overwrite or fail_on_exists.
+-- The AST may override it per table.
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS write_mode TEXT NOT NULL DEFAULT 'append'
+        CHECK (write_mode IN ('append', 'overwrite', 'fail_on_exists'));
+
+-- +goose Down
+
+ALTER TABLE tenant.job_definitions
+    DROP COLUMN IF EXISTS write_mode;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Schedules paused while a connection's credentials rotate. The connection that
+-- paused a schedule resumes it once its new credentials test successfully.
+ALTER TABLE tenant.job_schedules
+    ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS paused_for_connection_id UUID REFERENCES tenant.connections(id) ON DELETE SET NULL;
+
+-- +goose Down
+
+ALTER TABLE tenant.job_schedules
+    DROP COLUMN IF EXISTS paused_for_connection_id,
+    DROP COLUMN IF EXISTS paused_at;
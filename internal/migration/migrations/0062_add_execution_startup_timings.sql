@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- startup_timings maps each startup stage of an engine run (workflow_started,
+-- prepared, image_pulled, container_started, first_log) to when it completed.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS startup_timings JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS startup_timings;
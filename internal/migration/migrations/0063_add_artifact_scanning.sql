@@ -0,0 +1,22 @@
+-- +goose Up
+
+-- scan_status records the malware scan of an artifact's content when it was stored.
+-- Quarantined artifacts failed scanning and are not served.
+ALTER TABLE tenant.execution_artifacts
+    ADD COLUMN IF NOT EXISTS scan_status TEXT NOT NULL DEFAULT 'unscanned'
+        CHECK (scan_status IN ('unscanned', 'clean', 'quarantined')),
+    ADD COLUMN IF NOT EXISTS scan_detail TEXT NOT NULL DEFAULT '';
+
+-- When set, artifacts that cannot be scanned are quarantined rather than stored
+-- unscanned.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS require_artifact_scan BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS require_artifact_scan;
+
+ALTER TABLE tenant.execution_artifacts
+    DROP COLUMN IF EXISTS scan_detail,
+    DROP COLUMN IF EXISTS scan_status;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Execution results are a webhook category of their own.
+ALTER TABLE tenant.webhook_subscriptions DROP CONSTRAINT IF EXISTS webhook_subscriptions_category_check;
+ALTER TABLE tenant.webhook_subscriptions
+    ADD CONSTRAINT webhook_subscriptions_category_check
+    CHECK (category IN ('user', 'execution'));
+
+-- payload_template shapes the body posted for each event (a Go template or a set
+-- of JSONPath selections); NULL posts the whole event.
+ALTER TABLE tenant.webhook_subscriptions
+    ADD COLUMN IF NOT EXISTS payload_template JSONB;
+
+-- +goose Down
+ALTER TABLE tenant.webhook_subscriptions
+    DROP COLUMN IF EXISTS payload_template;
+
+DELETE FROM tenant.webhook_subscriptions WHERE category = 'execution';
+
+ALTER TABLE tenant.webhook_subscriptions DROP CONSTRAINT IF EXISTS webhook_subscriptions_category_check;
+ALTER TABLE tenant.webhook_subscriptions
+    ADD CONSTRAINT webhook_subscriptions_category_check
+    CHECK (category IN ('user'));
@@ -0,0 +1,10 @@
+-- +goose Up
+-- read_only connections are sources the engine must never write to: their
+-- connection strings open read-only sessions, runs check the user cannot write
+-- and definitions cannot use them as a destination.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS read_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE tenant.connections
+    DROP COLUMN IF EXISTS read_only;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- A failed execution that was requeued from the dead-letter list points at the
+-- run that replaced it, so it leaves the list and cannot be requeued twice.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS requeued_as_execution_id UUID,
+    ADD COLUMN IF NOT EXISTS requeued_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_job_executions_dead_letter
+    ON tenant.job_executions (tenant_id, created_at DESC)
+    WHERE status = 'failed' AND requeued_as_execution_id IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS tenant.idx_job_executions_dead_letter;
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS requeued_at,
+    DROP COLUMN IF EXISTS requeued_as_execution_id;
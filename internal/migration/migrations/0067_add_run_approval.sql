@@ -0,0 +1,32 @@
+-- +goose Up
+-- Tenants can require every run to be approved by an admin other than the user
+-- who requested it. A run waiting for approval is recorded as awaiting_approval
+-- with the workflow input it will start with once approved.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS require_run_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('awaiting_approval', 'pending', 'running', 'paused', 'succeeded', 'failed', 'skipped', 'cancelled'));
+
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS approval_request JSONB,
+    ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ;
+
+-- +goose Down
+UPDATE tenant.job_executions SET status = 'cancelled' WHERE status = 'awaiting_approval';
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS approved_at,
+    DROP COLUMN IF EXISTS approved_by,
+    DROP COLUMN IF EXISTS approval_request;
+
+ALTER TABLE tenant.job_executions DROP CONSTRAINT IF EXISTS job_executions_status_check;
+ALTER TABLE tenant.job_executions
+    ADD CONSTRAINT job_executions_status_check
+    CHECK (status IN ('pending', 'running', 'paused', 'succeeded', 'failed', 'skipped', 'cancelled'));
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS require_run_approval;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Per-tenant execution start limits. The plan supplies default limits; NULL
+-- overrides fall back to the plan, and tenants without a plan are unlimited.
+CREATE TABLE IF NOT EXISTS tenant.tenant_execution_limits (
+    tenant_id UUID PRIMARY KEY REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    plan TEXT CHECK (plan IN ('free', 'team', 'enterprise')),
+    starts_per_hour INT CHECK (starts_per_hour >= 0),
+    burst INT CHECK (burst >= 0),
+    updated_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.tenant_execution_limits;
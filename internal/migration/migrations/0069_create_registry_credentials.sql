@@ -0,0 +1,20 @@
+-- +goose Up
+-- Credentials engine images are pulled with, one per private registry. Basic
+-- credentials keep their password encrypted; helper credentials name a Docker
+-- credential helper that issues short-lived tokens.
+CREATE TABLE IF NOT EXISTS tenant.registry_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    registry TEXT NOT NULL UNIQUE,
+    auth_type TEXT NOT NULL CHECK (auth_type IN ('basic', 'helper')),
+    username TEXT,
+    password_enc BYTEA,
+    helper TEXT,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (auth_type <> 'basic' OR (username IS NOT NULL AND password_enc IS NOT NULL)),
+    CHECK (auth_type <> 'helper' OR helper IS NOT NULL)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.registry_credentials;
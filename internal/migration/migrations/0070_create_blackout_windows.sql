@@ -0,0 +1,20 @@
+-- +goose Up
+-- Recurring windows in which no execution of the tenant may start. Times are
+-- wall-clock HH:MM in the window's timezone; an empty days list means every day.
+CREATE TABLE IF NOT EXISTS tenant.blackout_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    days TEXT[] NOT NULL DEFAULT '{}',
+    start_time TEXT NOT NULL,
+    end_time TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_blackout_windows_tenant
+    ON tenant.blackout_windows (tenant_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.blackout_windows;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Run groups start related job definitions together and report on them as one.
+-- Every member gets its execution ID up front; members without an execution row
+-- are pending or, once an earlier member failed under stop_on_failure, skipped.
+CREATE TABLE IF NOT EXISTS tenant.run_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    parallelism INT NOT NULL CHECK (parallelism > 0),
+    stop_on_failure BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+    report JSONB,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_groups_tenant_created
+    ON tenant.run_groups (tenant_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS tenant.run_group_members (
+    group_id UUID NOT NULL REFERENCES tenant.run_groups(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    job_definition_id UUID NOT NULL REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    execution_id UUID NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'skipped')),
+    PRIMARY KEY (group_id, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.run_group_members;
+DROP TABLE IF EXISTS tenant.run_groups;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Per-table results the engine reports with its completion callback, so a run that
+-- failed part-way shows which tables migrated.
+CREATE TABLE IF NOT EXISTS tenant.job_execution_tables (
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('succeeded', 'failed', 'skipped')),
+    rows_copied BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (execution_id, table_name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.job_execution_tables;
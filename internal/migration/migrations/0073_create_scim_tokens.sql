@@ -0,0 +1,14 @@
+-- +goose Up
+-- The bearer token an identity provider provisions a tenant's users with over
+-- SCIM. A tenant has at most one; rotating it replaces the previous token.
+CREATE TABLE IF NOT EXISTS tenant.scim_tokens (
+    tenant_id UUID PRIMARY KEY REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    token_prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_by UUID REFERENCES tenant.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.scim_tokens;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Execution logs split into runs of lines by level when they are stored, so a
+-- failed run's warnings and errors can be read without its debug output.
+CREATE TABLE IF NOT EXISTS tenant.job_execution_log_chunks (
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    level TEXT NOT NULL CHECK (level IN ('debug', 'info', 'warn', 'error')),
+    line_count INT NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (execution_id, seq)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.job_execution_log_chunks;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Structured timeline of an execution, written as the workflow's activities reach
+-- each step, for telling where a stuck run stopped.
+CREATE TABLE IF NOT EXISTS tenant.job_execution_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    execution_id UUID NOT NULL REFERENCES tenant.job_executions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('created', 'queued', 'image_pulled', 'container_created', 'started', 'callback_received', 'completed')),
+    details JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_execution_events_execution
+    ON tenant.job_execution_events (execution_id, occurred_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.job_execution_events;
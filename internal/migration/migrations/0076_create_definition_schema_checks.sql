@@ -0,0 +1,28 @@
+-- +goose Up
+-- Tenants can have READY definitions checked for source schema drift. The first
+-- check after a definition becomes READY records the source schema hash; later
+-- checks flag the definition stale when the schema no longer matches it.
+ALTER TABLE tenant.tenants
+    ADD COLUMN IF NOT EXISTS validation_freshness TEXT NOT NULL DEFAULT 'off'
+    CHECK (validation_freshness IN ('off', 'warn', 'block'));
+
+-- A check belongs to the definition as it was at definition_updated_at, so any
+-- edit or status change of the definition discards it.
+CREATE TABLE IF NOT EXISTS tenant.job_definition_schema_checks (
+    job_definition_id UUID PRIMARY KEY REFERENCES tenant.job_definitions(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenant.tenants(id) ON DELETE CASCADE,
+    definition_updated_at TIMESTAMPTZ NOT NULL,
+    schema_hash TEXT NOT NULL,
+    stale BOOLEAN NOT NULL DEFAULT FALSE,
+    stale_since TIMESTAMPTZ,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_definition_schema_checks_checked
+    ON tenant.job_definition_schema_checks (checked_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS tenant.job_definition_schema_checks;
+
+ALTER TABLE tenant.tenants
+    DROP COLUMN IF EXISTS validation_freshness;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- docker_network is a Docker network the engine container joins to reach the
+-- database, such as a docker-compose project network, so hosts like db:5432
+-- resolve. network_alias is the name the database answers to on that network
+-- and replaces host in the engine's connection string.
+ALTER TABLE tenant.connections
+    ADD COLUMN IF NOT EXISTS docker_network TEXT,
+    ADD COLUMN IF NOT EXISTS network_alias TEXT,
+    ADD CONSTRAINT connections_network_alias_needs_network
+        CHECK (network_alias IS NULL OR docker_network IS NOT NULL);
+
+-- +goose Down
+ALTER TABLE tenant.connections
+    DROP CONSTRAINT IF EXISTS connections_network_alias_needs_network,
+    DROP COLUMN IF EXISTS network_alias,
+    DROP COLUMN IF EXISTS docker_network;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- correlation_key groups the notifications of one execution: it holds the
+-- execution ID of execution events. Existing execution notifications take it from
+-- their metadata.
+ALTER TABLE tenant.notifications
+    ADD COLUMN IF NOT EXISTS correlation_key TEXT;
+
+UPDATE tenant.notifications
+SET correlation_key = metadata->>'execution_id'
+WHERE correlation_key IS NULL AND metadata ? 'execution_id';
+
+CREATE INDEX IF NOT EXISTS idx_notifications_correlation_key
+    ON tenant.notifications (tenant_id, correlation_key, created_at DESC)
+    WHERE correlation_key IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notifications_correlation_key;
+
+ALTER TABLE tenant.notifications
+    DROP COLUMN IF EXISTS correlation_key;
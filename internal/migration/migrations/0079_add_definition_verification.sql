@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Whether runs of a definition are verified after migrating: off, warn (a
+-- mismatch flags the execution suspect) or fail (a mismatch fails it).
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS verification TEXT NOT NULL DEFAULT 'off'
+        CHECK (verification IN ('off', 'warn', 'fail'));
+
+-- +goose Down
+
+ALTER TABLE tenant.job_definitions
+    DROP COLUMN IF EXISTS verification;
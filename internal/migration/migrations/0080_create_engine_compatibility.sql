@@ -0,0 +1,27 @@
+-- +goose Up
+
+-- Engine versions whose capabilities are known, from discovery against the running
+-- engine or recorded by an admin ahead of an upgrade. Definitions are checked
+-- against the current one, the version the configured engine reported.
+CREATE TABLE IF NOT EXISTS tenant.engine_versions (
+    version TEXT PRIMARY KEY,
+    current BOOLEAN NOT NULL DEFAULT FALSE,
+    discovered_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_engine_versions_current
+    ON tenant.engine_versions (current) WHERE current;
+
+-- The source/destination data format pairs each discovered engine version can
+-- migrate.
+CREATE TABLE IF NOT EXISTS tenant.engine_format_support (
+    engine_version TEXT NOT NULL REFERENCES tenant.engine_versions(version) ON DELETE CASCADE,
+    source_format TEXT NOT NULL,
+    destination_format TEXT NOT NULL,
+    PRIMARY KEY (engine_version, source_format, destination_format)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS tenant.engine_format_support;
+DROP TABLE IF EXISTS tenant.engine_versions;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- Archived definitions are kept for audits but hidden from lists and unable to run.
+-- archived_from_status is the status unarchiving restores.
+ALTER TABLE tenant.job_definitions
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS archived_from_status TEXT,
+    DROP CONSTRAINT IF EXISTS job_definitions_status_check,
+    ADD CONSTRAINT job_definitions_status_check CHECK (status IN ('DRAFT', 'VALIDATING', 'READY', 'ARCHIVED'));
+
+-- +goose Down
+
+UPDATE tenant.job_definitions
+SET status = COALESCE(archived_from_status, 'DRAFT')
+WHERE status = 'ARCHIVED';
+
+ALTER TABLE tenant.job_definitions
+    DROP CONSTRAINT IF EXISTS job_definitions_status_check,
+    ADD CONSTRAINT job_definitions_status_check CHECK (status IN ('DRAFT', 'VALIDATING', 'READY')),
+    DROP COLUMN IF EXISTS archived_from_status,
+    DROP COLUMN IF EXISTS archived_at;
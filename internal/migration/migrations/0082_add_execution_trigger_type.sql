@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- How each execution was started and, for manual runs, by whom. Both are derived
+-- from triggered_by and trigger_context; runs from before triggers were tracked
+-- stay NULL.
+ALTER TABLE tenant.job_executions
+    ADD COLUMN IF NOT EXISTS trigger_type TEXT
+        CHECK (trigger_type IN ('manual', 'schedule', 'api_key')),
+    ADD COLUMN IF NOT EXISTS triggered_by_user_id UUID REFERENCES tenant.users(id) ON DELETE SET NULL;
+
+UPDATE tenant.job_executions
+SET trigger_type = CASE
+        WHEN triggered_by = 'schedule' THEN 'schedule'
+        WHEN triggered_by = 'api_key' OR trigger_context ? 'api_key_id' THEN 'api_key'
+        ELSE 'manual'
+    END
+WHERE triggered_by IS NOT NULL AND trigger_type IS NULL;
+
+UPDATE tenant.job_executions je
+SET triggered_by_user_id = u.id
+FROM tenant.users u
+WHERE je.trigger_type = 'manual' AND je.triggered_by_user_id IS NULL
+    AND u.id::text = je.trigger_context->>'user_id';
+
+-- +goose Down
+
+ALTER TABLE tenant.job_executions
+    DROP COLUMN IF EXISTS triggered_by_user_id,
+    DROP COLUMN IF EXISTS trigger_type;
@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- 0054 locked setup on every instance with a tenant, which includes fresh installs
+-- holding only the default tenant and admins seeded by 0002 and 0010. Setup reopens
+-- on those; completing it replaces the seed tenant.
+DELETE FROM tenant.instance_settings
+WHERE setup_user_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM tenant.tenants WHERE name <> 'default')
+  AND NOT EXISTS (SELECT 1 FROM tenant.users WHERE email NOT IN ('admin@example.com', 'superadmin@example.com'))
+  AND NOT EXISTS (SELECT 1 FROM tenant.connections)
+  AND NOT EXISTS (SELECT 1 FROM tenant.job_definitions);
+
+-- +goose Down
+
+INSERT INTO tenant.instance_settings (name)
+SELECT 'Stratum'
+WHERE EXISTS (SELECT 1 FROM tenant.tenants)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- Kubernetes runs learn the engine image digest only when their pod starts, after
+-- the snapshot is written. A snapshot without a digest may have it filled in once;
+-- every other update is still rejected.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tenant.reject_execution_snapshot_update() RETURNS TRIGGER AS $$
+BEGIN
+  IF OLD.engine_image_digest IS NULL AND NEW.engine_image_digest IS NOT NULL
+     AND to_jsonb(NEW) - 'engine_image_digest' = to_jsonb(OLD) - 'engine_image_digest' THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'execution snapshots are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tenant.reject_execution_snapshot_update() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'execution snapshots are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Announcement severities.
+const (
+	AnnouncementInfo     = "info"
+	AnnouncementWarning  = "warning"
+	AnnouncementCritical = "critical"
+)
+
+// Announcement sources: banners are posted by operators or by toggling
+// maintenance mode.
+const (
+	AnnouncementSourceManual      = "manual"
+	AnnouncementSourceMaintenance = "maintenance"
+)
+
+// Announcement is a banner message shown in the UI between StartsAt and EndsAt.
+// Announcements without a tenant are global and shown to every tenant; a nil
+// EndsAt keeps the banner up until it is ended or deleted.
+type Announcement struct {
+	ID        string     `json:"id"`
+	TenantID  *string    `json:"tenant_id,omitempty"`
+	Global    bool       `json:"global"`
+	Message   string     `json:"message"`
+	Severity  string     `json:"severity"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	Source    string     `json:"source"`
+	CreatedBy *string    `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ValidAnnouncementSeverity reports whether severity is a known severity.
+func ValidAnnouncementSeverity(severity string) bool {
+	switch severity {
+	case AnnouncementInfo, AnnouncementWarning, AnnouncementCritical:
+		return true
+	}
+	return false
+}
+
+// ActiveAt reports whether the announcement is shown at t.
+func (a Announcement) ActiveAt(t time.Time) bool {
+	return !t.Before(a.StartsAt) && (a.EndsAt == nil || t.Before(*a.EndsAt))
+}
+
+// MaintenanceMode is the platform-wide maintenance switch. While enabled, only
+// super admins may change anything through the API.
+type MaintenanceMode struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	// AnnouncementID is the banner posted when maintenance mode was enabled.
+	AnnouncementID *string   `json:"announcement_id,omitempty"`
+	UpdatedBy      *string   `json:"updated_by,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
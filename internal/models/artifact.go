@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ArtifactKindPartialState is the destination state captured after a failed run.
+const ArtifactKindPartialState = "partial_state"
+
+// ArtifactScanStatus is the outcome of malware scanning an artifact when it was
+// stored.
+type ArtifactScanStatus string
+
+const (
+	ArtifactScanUnscanned ArtifactScanStatus = "unscanned"
+	ArtifactScanClean     ArtifactScanStatus = "clean"
+	// ArtifactScanQuarantined artifacts failed scanning; their content is kept for
+	// review but not served.
+	ArtifactScanQuarantined ArtifactScanStatus = "quarantined"
+)
+
+// AllArtifactScanStatuses enumerates valid artifact scan statuses.
+var AllArtifactScanStatuses = []ArtifactScanStatus{
+	ArtifactScanUnscanned,
+	ArtifactScanClean,
+	ArtifactScanQuarantined,
+}
+
+// ExecutionArtifact is a document produced for an execution, such as a report
+// captured by the engine. There is at most one artifact of each kind per execution.
+type ExecutionArtifact struct {
+	ID          string          `json:"id" db:"id"`
+	TenantID    string          `json:"tenant_id" db:"tenant_id"`
+	ExecutionID string          `json:"execution_id" db:"execution_id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Content     json.RawMessage `json:"content" db:"content"`
+	// StorageRegion and StorageLocation record where the artifact is kept under the
+	// tenant's data residency settings.
+	StorageRegion   string `json:"storage_region" db:"storage_region"`
+	StorageLocation string `json:"storage_location" db:"storage_location"`
+	// ScanStatus and ScanDetail record the malware scan of the content.
+	ScanStatus ArtifactScanStatus `json:"scan_status" db:"scan_status"`
+	ScanDetail string             `json:"scan_detail,omitempty" db:"scan_detail"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+}
+
+// Quarantined reports whether the artifact failed scanning and must not be served.
+func (a ExecutionArtifact) Quarantined() bool {
+	return a.ScanStatus == ArtifactScanQuarantined
+}
+
+// TableRowCount is the number of rows found in one destination table.
+type TableRowCount struct {
+	Table    string `json:"table"`
+	RowCount *int64 `json:"row_count"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PartialStateReport records how much data landed in the destination before a run
+// failed, so users can choose between resuming, rolling back or re-running.
+type PartialStateReport struct {
+	CapturedAt time.Time       `json:"captured_at"`
+	Tables     []TableRowCount `json:"tables"`
+	Error      string          `json:"error,omitempty"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Audit event actions.
+const (
+	AuditUserAdded                  = "user.added"
+	AuditUserRolesChanged           = "user.roles_changed"
+	AuditUserDeleted                = "user.deleted"
+	AuditUserErased                 = "user.erased"
+	AuditUserUpdated                = "user.updated"
+	AuditInviteAccepted             = "invite.accepted"
+	AuditJoinRequestApproved        = "join_request.approved"
+	AuditJoinRequestRejected        = "join_request.rejected"
+	AuditAPIKeyCreated              = "api_key.created"
+	AuditAPIKeyRevoked              = "api_key.revoked"
+	AuditSCIMTokenRotated           = "scim_token.rotated"
+	AuditSCIMTokenRevoked           = "scim_token.revoked"
+	AuditWebhookCreated             = "webhook.created"
+	AuditWebhookDeleted             = "webhook.deleted"
+	AuditTenantDeleted              = "tenant.deleted"
+	AuditTenantRestored             = "tenant.restored"
+	AuditWorkerConfigUpdated        = "tenant.worker_config_updated"
+	AuditExecutionLimitsUpdated     = "tenant.execution_limits_updated"
+	AuditBlackoutWindowCreated      = "tenant.blackout_window_created"
+	AuditBlackoutWindowDeleted      = "tenant.blackout_window_deleted"
+	AuditTenantImported             = "tenant.imported"
+	AuditComplianceExported         = "compliance.exported"
+	AuditExecutionTriggered         = "execution.triggered"
+	AuditExecutionPaused            = "execution.paused"
+	AuditExecutionResumed           = "execution.resumed"
+	AuditExecutionCancelled         = "execution.cancelled"
+	AuditExecutionApprovalRequested = "execution.approval_requested"
+	AuditExecutionApproved          = "execution.approved"
+	AuditRunGroupStarted            = "run_group.started"
+	AuditScheduleSet                = "job.schedule_set"
+	AuditScheduleDeleted            = "job.schedule_deleted"
+	AuditDefinitionArchived         = "job.archived"
+	AuditDefinitionUnarchived       = "job.unarchived"
+	AuditInstanceSetupCompleted     = "instance.setup_completed"
+)
+
+// MembershipAuditActions are the actions that change who belongs to a tenant or
+// what they may do there.
+var MembershipAuditActions = []string{
+	AuditUserAdded,
+	AuditUserRolesChanged,
+	AuditUserDeleted,
+	AuditUserErased,
+	AuditUserUpdated,
+	AuditInviteAccepted,
+	AuditJoinRequestApproved,
+	AuditJoinRequestRejected,
+}
+
+// AuditEvent records a security-relevant change and who made it. TenantID is nil for
+// platform-wide actions and ActorID for changes made without a signed-in user.
+type AuditEvent struct {
+	ID         string                 `json:"id"`
+	TenantID   *string                `json:"tenant_id,omitempty"`
+	ActorID    *string                `json:"actor_id,omitempty"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AccessLog is one API request and the identity it was authenticated as.
+type AccessLog struct {
+	TenantID   *string   `json:"tenant_id,omitempty"`
+	UserID     *string   `json:"user_id,omitempty"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const blackoutClockLayout = "15:04"
+
+// blackoutDays maps the day names of blackout windows to weekdays.
+var blackoutDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// BlackoutWindow is a recurring period in which no execution of the tenant may
+// start, e.g. "no migrations weekdays 08:00–18:00" to keep load off production
+// sources during business hours. Admins can override it per run.
+type BlackoutWindow struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+	// Days are the weekdays the window starts on, "mon" to "sun"; empty means every
+	// day.
+	Days []string `json:"days"`
+	// StartTime and EndTime are "HH:MM" wall-clock times in Timezone. A window that
+	// ends at or before its start runs past midnight into the next day.
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Timezone  string    `json:"timezone"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Normalize trims the window, lowercases its days, defaults the timezone to UTC
+// and validates the times.
+func (b *BlackoutWindow) Normalize() error {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return errors.New("name is required")
+	}
+	days := make([]string, 0, len(b.Days))
+	seen := make(map[string]bool, len(b.Days))
+	for _, day := range b.Days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		if _, ok := blackoutDays[day]; !ok {
+			return fmt.Errorf("unknown day %q; use mon to sun", day)
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	b.Days = days
+	if _, err := time.Parse(blackoutClockLayout, b.StartTime); err != nil {
+		return errors.New("start_time must be HH:MM")
+	}
+	if _, err := time.Parse(blackoutClockLayout, b.EndTime); err != nil {
+		return errors.New("end_time must be HH:MM")
+	}
+	b.Timezone = strings.TrimSpace(b.Timezone)
+	if b.Timezone == "" {
+		b.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %s", b.Timezone)
+	}
+	return nil
+}
+
+// ActiveAt reports whether the window covers now and, if so, when it ends.
+func (b BlackoutWindow) ActiveAt(now time.Time) (bool, time.Time) {
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return false, time.Time{}
+	}
+	start, err1 := time.Parse(blackoutClockLayout, b.StartTime)
+	end, err2 := time.Parse(blackoutClockLayout, b.EndTime)
+	if err1 != nil || err2 != nil {
+		return false, time.Time{}
+	}
+	local := now.In(loc)
+	// A window that runs past midnight may have started the day before.
+	for _, offset := range []int{0, -1} {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		if !b.startsOn(day.Weekday()) {
+			continue
+		}
+		from := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		to := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !to.After(from) {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !local.Before(from) && local.Before(to) {
+			return true, to
+		}
+	}
+	return false, time.Time{}
+}
+
+func (b BlackoutWindow) startsOn(day time.Weekday) bool {
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, name := range b.Days {
+		if blackoutDays[name] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveBlackout returns the window covering now that ends last, or nil when runs
+// may start.
+func ActiveBlackout(windows []BlackoutWindow, now time.Time) (*BlackoutWindow, time.Time) {
+	var (
+		active *BlackoutWindow
+		until  time.Time
+	)
+	for i := range windows {
+		if ok, end := windows[i].ActiveAt(now); ok && end.After(until) {
+			active, until = &windows[i], end
+		}
+	}
+	return active, until
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Calendar event kinds.
+const (
+	CalendarEventExecution = "execution"
+	CalendarEventSchedule  = "schedule"
+)
+
+// CalendarStatusScheduled is the status of an upcoming scheduled run.
+const CalendarStatusScheduled = "scheduled"
+
+// CalendarEvent is one entry of the execution calendar. Executions are placed at
+// their start, or their creation while they have not started; scheduled runs at
+// the time the schedule fires.
+type CalendarEvent struct {
+	Kind              string     `json:"kind"`
+	JobDefinitionID   string     `json:"job_definition_id"`
+	JobDefinitionName string     `json:"job_definition_name"`
+	ExecutionID       string     `json:"execution_id,omitempty"`
+	Status            string     `json:"status"`
+	At                time.Time  `json:"at"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`
+}
+
+// CalendarDay groups the events of one day, in the calendar's time zone, with a
+// count per status.
+type CalendarDay struct {
+	Date   string          `json:"date"`
+	Counts map[string]int  `json:"counts"`
+	Events []CalendarEvent `json:"events"`
+}
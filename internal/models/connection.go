@@ -1,34 +1,283 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Connection credential modes.
+const (
+	// CredentialModeStored keeps the password encrypted in the database.
+	CredentialModeStored = "stored"
+	// CredentialModePrompt never stores a password; callers supply it with each run.
+	CredentialModePrompt = "prompt"
+)
+
 type Connection struct {
-	ID         string    `json:"id" db:"id"`
-	TenantID   string    `json:"tenant_id" db:"tenant_id"`
-	Name       string    `json:"name" db:"name"`
-	DataFormat string    `json:"data_format" db:"data_format"` // enum: pg, mysql, api, csv
-	Host       string    `json:"host" db:"host"`
-	Port       int       `json:"port" db:"port"`
-	Username   string    `json:"username" db:"username"`
-	Password   string    `json:"password,omitempty" db:"password"`
-	DBName     string    `json:"db_name" db:"db_name"`
-	Status     string    `json:"status" db:"status"` // enum: valid, invalid, untested
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID             string               `json:"id" db:"id"`
+	TenantID       string               `json:"tenant_id" db:"tenant_id"`
+	Name           string               `json:"name" db:"name"`
+	DataFormat     string               `json:"data_format" db:"data_format"` // enum: pg, mysql, api, csv
+	Host           string               `json:"host" db:"host"`
+	Port           int                  `json:"port" db:"port"`
+	Username       string               `json:"username" db:"username"`
+	Password       string               `json:"password,omitempty" db:"password"`
+	DBName         string               `json:"db_name" db:"db_name"`
+	Status         ConnectionStatus     `json:"status" db:"status"`
+	Ephemeral      bool                 `json:"ephemeral" db:"ephemeral"`
+	CredentialMode string               `json:"credential_mode" db:"credential_mode"`   // enum: stored, prompt
+	Environment    string               `json:"environment,omitempty" db:"environment"` // enum: prod, staging, dev; empty when unlabeled
+	Tags           []string             `json:"tags" db:"tags"`
+	Benchmark      *ConnectionBenchmark `json:"benchmark,omitempty" db:"benchmark"`
+	Options        ConnectionOptions    `json:"options" db:"options"`
+	ReadOnly       bool                 `json:"read_only" db:"read_only"`                     // sessions are read-only; never a destination
+	ServerVersion  string               `json:"server_version,omitempty" db:"server_version"` // as reported by the last test-conn
+	DockerNetwork  string               `json:"docker_network,omitempty" db:"docker_network"` // network the engine container joins to reach the database
+	NetworkAlias   string               `json:"network_alias,omitempty" db:"network_alias"`   // the database's name on DockerNetwork; replaces Host for the engine
+	CreatedAt      time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// Connection environments.
+const (
+	EnvironmentProd    = "prod"
+	EnvironmentStaging = "staging"
+	EnvironmentDev     = "dev"
+)
+
+const (
+	maxConnectionTags = 20
+	maxTagLength      = 50
+)
+
+// ValidEnvironment reports whether env is a known environment label. The empty
+// string (unlabeled) is valid.
+func ValidEnvironment(env string) bool {
+	switch env {
+	case "", EnvironmentProd, EnvironmentStaging, EnvironmentDev:
+		return true
+	}
+	return false
+}
+
+// NormalizeTags lowercases, trims and deduplicates connection tags, keeping their
+// order. Tags may contain letters, digits and "-_.:".
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag := strings.ToLower(strings.TrimSpace(raw))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		for _, c := range tag {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+				return nil, fmt.Errorf("tag %q may only contain letters, digits and -_.:", tag)
+			}
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > maxConnectionTags {
+		return nil, fmt.Errorf("a connection can have at most %d tags", maxConnectionTags)
+	}
+	return out, nil
+}
+
+// EnvironmentMismatch describes a source and destination labeled with different
+// environments, e.g. a prod source copied into a dev database. It returns "" when
+// they match or either one is unlabeled.
+func EnvironmentMismatch(src, dst Connection) string {
+	if src.Environment == "" || dst.Environment == "" || src.Environment == dst.Environment {
+		return ""
+	}
+	return fmt.Sprintf("source connection %q is %s but destination connection %q is %s",
+		src.Name, src.Environment, dst.Name, dst.Environment)
+}
+
+// PromptsForCredentials reports whether the password must be supplied at run time.
+func (c *Connection) PromptsForCredentials() bool {
+	return c.CredentialMode == CredentialModePrompt
+}
+
+// ValidCredentialMode reports whether mode is a known credential mode.
+func ValidCredentialMode(mode string) bool {
+	return mode == CredentialModeStored || mode == CredentialModePrompt
+}
+
+// ReadOnlyDestination describes a read-only connection used as a destination. It
+// returns "" when dst may be written to.
+func ReadOnlyDestination(dst Connection) string {
+	if !dst.ReadOnly {
+		return ""
+	}
+	return fmt.Sprintf("connection %q is read-only and cannot be a destination", dst.Name)
+}
+
+// pgReadOnlyOptions makes every transaction of a session read-only.
+const pgReadOnlyOptions = "-c default_transaction_read_only=on"
+
+// EngineHost is the host the engine connects to: the connection's network alias
+// when it has one, and its host otherwise.
+func (c *Connection) EngineHost() string {
+	if c.NetworkAlias != "" {
+		return c.NetworkAlias
+	}
+	return c.Host
 }
 
 func (c *Connection) GenerateConnString() (string, error) {
 	switch c.DataFormat {
 	case "pg", "postgresql", "postgres":
-		return fmt.Sprintf("postgres://%s:%s@%s:%d/%s",
-			c.Username, c.Password, c.Host, c.Port, c.DBName), nil
+		u := c.dsnURL("postgres")
+		if c.ReadOnly {
+			// libpq does not decode "+" as a space.
+			u.RawQuery = "options=" + strings.ReplaceAll(url.QueryEscape(pgReadOnlyOptions), "+", "%20")
+		}
+		return u.String(), nil
 	case "mysql":
-		return fmt.Sprintf("mysql://%s:%s@%s:%d/%s",
-			c.Username, c.Password, c.Host, c.Port, c.DBName), nil
+		q := c.Options.mysqlQuery(c.ServerVersion)
+		if c.ReadOnly {
+			q.Set(mysqlReadOnlyVariable(c.ServerVersion), "1")
+		}
+		u := c.dsnURL("mysql")
+		u.RawQuery, u.ForceQuery = q.Encode(), true
+		return u.String(), nil
 	default:
 		return "", fmt.Errorf("unknown format: %s", c.DataFormat)
 	}
 }
+
+// dsnURL is the connection as a URL without query parameters. The credentials are
+// escaped, so a password holding "@", "/", "#" or "%" still parses back out of it.
+func (c *Connection) dsnURL(scheme string) *url.URL {
+	return &url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(c.Username, c.Password),
+		Host:   fmt.Sprintf("%s:%d", c.EngineHost(), c.Port),
+		Path:   "/" + c.DBName,
+	}
+}
+
+// ParseDSN builds an unsaved connection from a postgres:// or mysql:// URL. MySQL
+// URL parameters become the connection's options.
+func ParseDSN(dsn string) (Connection, error) {
+	u, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil {
+		return Connection{}, fmt.Errorf("invalid DSN: %w", err)
+	}
+
+	var (
+		format      string
+		defaultPort int
+	)
+	switch strings.ToLower(u.Scheme) {
+	case "postgres", "postgresql", "pg":
+		format, defaultPort = "pg", 5432
+	case "mysql":
+		format, defaultPort = "mysql", 3306
+	default:
+		return Connection{}, fmt.Errorf("unsupported DSN scheme %q", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return Connection{}, fmt.Errorf("DSN is missing a host")
+	}
+	dbName := strings.TrimPrefix(u.Path, "/")
+	if dbName == "" {
+		return Connection{}, fmt.Errorf("DSN is missing a database name")
+	}
+
+	port := defaultPort
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return Connection{}, fmt.Errorf("invalid DSN port %q", p)
+		}
+	}
+
+	conn := Connection{
+		DataFormat: format,
+		Host:       u.Hostname(),
+		Port:       port,
+		DBName:     dbName,
+	}
+	if u.User != nil {
+		conn.Username = u.User.Username()
+		conn.Password, _ = u.User.Password()
+	}
+	q := u.Query()
+	if format == "mysql" {
+		for _, name := range []string{"transaction_read_only", "tx_read_only"} {
+			if v := q.Get(name); v != "" {
+				conn.ReadOnly = v == "1" || strings.EqualFold(v, "on") || strings.EqualFold(v, "true")
+				q.Del(name)
+			}
+		}
+		if conn.Options, err = mysqlOptionsFromQuery(q); err != nil {
+			return Connection{}, err
+		}
+	} else {
+		conn.ReadOnly = q.Get("options") == pgReadOnlyOptions
+	}
+	return conn, nil
+}
+
+// RedactDSN returns dsn with its password masked, for logs and error messages. A
+// DSN that cannot be parsed is masked whole.
+func RedactDSN(dsn string) string {
+	u, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil {
+		return "****"
+	}
+	if u.User == nil {
+		return dsn
+	}
+	if _, ok := u.User.Password(); !ok {
+		return dsn
+	}
+	u.User = url.UserPassword(u.User.Username(), "****")
+	return u.String()
+}
+
+// ScrubDSN masks dsn, and the password it carries, wherever they appear in text.
+func ScrubDSN(text, dsn string) string {
+	if dsn == "" {
+		return text
+	}
+	text = strings.ReplaceAll(text, dsn, RedactDSN(dsn))
+	u, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil || u.User == nil {
+		return text
+	}
+	password, ok := u.User.Password()
+	if !ok || password == "" {
+		return text
+	}
+	for _, form := range []string{password, url.QueryEscape(password), url.PathEscape(password)} {
+		text = strings.ReplaceAll(text, form, "****")
+	}
+	return text
+}
+
+// Fingerprint describes where the connection points without including credentials.
+func (c *Connection) Fingerprint() ConnectionFingerprint {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s", c.DataFormat, c.Host, c.Port, c.DBName)))
+	return ConnectionFingerprint{
+		ConnectionID: c.ID,
+		Name:         c.Name,
+		DataFormat:   c.DataFormat,
+		Host:         c.Host,
+		Port:         c.Port,
+		DBName:       c.DBName,
+		Fingerprint:  hex.EncodeToString(sum[:]),
+	}
+}
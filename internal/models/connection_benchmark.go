@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ConnectionBenchmark is the engine's measurement of how fast a connection's
+// database can be reached and read from.
+type ConnectionBenchmark struct {
+	LatencyMs             float64   `json:"latency_ms"`
+	LatencyP95Ms          float64   `json:"latency_p95_ms"`
+	ThroughputBytesPerSec float64   `json:"throughput_bytes_per_sec"`
+	BytesRead             int64     `json:"bytes_read"`
+	Samples               int       `json:"samples"`
+	MeasuredAt            time.Time `json:"measured_at"`
+}
+
+// TransferEstimate projects how long a run of a definition will take from the bytes
+// its last run moved and the slowest benchmarked connection.
+type TransferEstimate struct {
+	SourceBenchmark      *ConnectionBenchmark `json:"source_benchmark,omitempty"`
+	DestinationBenchmark *ConnectionBenchmark `json:"destination_benchmark,omitempty"`
+	BasedOnExecutionID   string               `json:"based_on_execution_id,omitempty"`
+	EstimatedBytes       *int64               `json:"estimated_bytes,omitempty"`
+	EstimatedSeconds     *float64             `json:"estimated_seconds,omitempty"`
+}
+
+// NewTransferEstimate combines the connection benchmarks with the bytes a previous
+// run transferred. EstimatedSeconds is only set when both are known.
+func NewTransferEstimate(src, dst *ConnectionBenchmark, lastExec *JobExecution) TransferEstimate {
+	est := TransferEstimate{SourceBenchmark: src, DestinationBenchmark: dst}
+	if lastExec == nil || lastExec.BytesTransferred == nil || *lastExec.BytesTransferred <= 0 {
+		return est
+	}
+	est.BasedOnExecutionID = lastExec.ID
+	est.EstimatedBytes = lastExec.BytesTransferred
+
+	var slowest float64
+	for _, b := range []*ConnectionBenchmark{src, dst} {
+		if b == nil || b.ThroughputBytesPerSec <= 0 {
+			continue
+		}
+		if slowest == 0 || b.ThroughputBytesPerSec < slowest {
+			slowest = b.ThroughputBytesPerSec
+		}
+	}
+	if slowest > 0 {
+		seconds := float64(*lastExec.BytesTransferred) / slowest
+		est.EstimatedSeconds = &seconds
+	}
+	return est
+}
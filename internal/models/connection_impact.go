@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Bounds of the look-ahead window of a connection impact report.
+const (
+	DefaultImpactDays = 7
+	MaxImpactDays     = 90
+	// MaxImpactRunsPerSchedule caps the runs listed for one schedule.
+	MaxImpactRunsPerSchedule = 50
+)
+
+// ConnectionImpact lists what depends on a connection, so users can see what a
+// deletion or credential rotation would break before making it.
+type ConnectionImpact struct {
+	ConnectionID string `json:"connection_id"`
+	// Days is the window ScheduledRuns covers, starting now.
+	Days              int                  `json:"days"`
+	ReadyDefinitions  []ImpactedDefinition `json:"ready_definitions"`
+	ScheduledRuns     []ScheduledRun       `json:"scheduled_runs"`
+	PausedSchedules   []JobSchedule        `json:"paused_schedules"`
+	RunningExecutions []JobExecution       `json:"running_executions"`
+}
+
+// ImpactedDefinition is a definition that uses the connection. Role is "source",
+// "destination" or "both".
+type ImpactedDefinition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// ScheduledRun is one upcoming run of a schedule.
+type ScheduledRun struct {
+	JobDefinitionID   string    `json:"job_definition_id"`
+	JobDefinitionName string    `json:"job_definition_name"`
+	ScheduleID        string    `json:"schedule_id"`
+	RunAt             time.Time `json:"run_at"`
+}
+
+// ConnectionRole returns how the definition uses the connection, or "" when it
+// does not.
+func (d JobDefinition) ConnectionRole(connectionID string) string {
+	src := d.SourceConnectionID == connectionID
+	dst := d.DestinationConnectionID == connectionID
+	switch {
+	case src && dst:
+		return "both"
+	case src:
+		return "source"
+	case dst:
+		return "destination"
+	}
+	return ""
+}
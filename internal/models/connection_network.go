@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Docker accepts these network names; compose names its project networks
+	// <project>_<network>.
+	dockerNetworkPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+	networkAliasPattern  = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]{0,251}[a-zA-Z0-9])?$`)
+)
+
+// NormalizeNetwork trims the connection's Docker network settings and checks
+// them. A network alias is only resolvable on a network, so it requires one.
+func (c *Connection) NormalizeNetwork() error {
+	c.DockerNetwork = strings.TrimSpace(c.DockerNetwork)
+	c.NetworkAlias = strings.TrimSpace(c.NetworkAlias)
+	if c.DockerNetwork != "" && !dockerNetworkPattern.MatchString(c.DockerNetwork) {
+		return fmt.Errorf("invalid docker_network %q", c.DockerNetwork)
+	}
+	if c.NetworkAlias == "" {
+		return nil
+	}
+	if c.DockerNetwork == "" {
+		return errors.New("network_alias requires docker_network")
+	}
+	if !networkAliasPattern.MatchString(c.NetworkAlias) {
+		return fmt.Errorf("invalid network_alias %q", c.NetworkAlias)
+	}
+	return nil
+}
+
+// DockerNetworks returns the distinct Docker networks the engine must join to
+// reach the connections, in order.
+func DockerNetworks(conns ...*Connection) []string {
+	var networks []string
+	seen := map[string]bool{}
+	for _, conn := range conns {
+		if conn == nil || conn.DockerNetwork == "" || seen[conn.DockerNetwork] {
+			continue
+		}
+		seen[conn.DockerNetwork] = true
+		networks = append(networks, conn.DockerNetwork)
+	}
+	return networks
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConnectionOptions are driver parameters appended to a MySQL connection string.
+// Unset fields fall back to defaults chosen for the server version.
+type ConnectionOptions struct {
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	// TLS is true, false, skip-verify or preferred.
+	TLS                   string `json:"tls,omitempty"`
+	ConnectTimeoutSeconds int    `json:"connect_timeout_seconds,omitempty"`
+	ReadTimeoutSeconds    int    `json:"read_timeout_seconds,omitempty"`
+	WriteTimeoutSeconds   int    `json:"write_timeout_seconds,omitempty"`
+	// AllowNativePasswords permits the mysql_native_password auth plugin.
+	AllowNativePasswords *bool `json:"allow_native_passwords,omitempty"`
+}
+
+const (
+	defaultMySQLConnectTimeout = 10
+	maxConnectionTimeout       = 3600
+)
+
+var (
+	validTLSModes  = map[string]bool{"true": true, "false": true, "skip-verify": true, "preferred": true}
+	charsetPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	versionPattern = regexp.MustCompile(`(?i)\bversion\W{0,3}(\d+)\.(\d+)(?:\.(\d+))?`)
+)
+
+// IsZero reports whether no option is set.
+func (o ConnectionOptions) IsZero() bool {
+	return o.Equal(ConnectionOptions{})
+}
+
+// Equal reports whether both option sets produce the same connection string.
+func (o ConnectionOptions) Equal(other ConnectionOptions) bool {
+	if (o.AllowNativePasswords == nil) != (other.AllowNativePasswords == nil) {
+		return false
+	}
+	if o.AllowNativePasswords != nil && *o.AllowNativePasswords != *other.AllowNativePasswords {
+		return false
+	}
+	return o.Charset == other.Charset && o.Collation == other.Collation && o.TLS == other.TLS &&
+		o.ConnectTimeoutSeconds == other.ConnectTimeoutSeconds &&
+		o.ReadTimeoutSeconds == other.ReadTimeoutSeconds &&
+		o.WriteTimeoutSeconds == other.WriteTimeoutSeconds
+}
+
+// Normalize lowercases and trims the options and checks them for the data format.
+// Only MySQL connections take options.
+func (o *ConnectionOptions) Normalize(format string) error {
+	o.Charset = strings.ToLower(strings.TrimSpace(o.Charset))
+	o.Collation = strings.ToLower(strings.TrimSpace(o.Collation))
+	o.TLS = strings.ToLower(strings.TrimSpace(o.TLS))
+	if o.IsZero() {
+		return nil
+	}
+	if format != "mysql" {
+		return fmt.Errorf("options are only supported for mysql connections")
+	}
+	if o.Charset != "" && !charsetPattern.MatchString(o.Charset) {
+		return fmt.Errorf("invalid charset %q", o.Charset)
+	}
+	if o.Collation != "" && !charsetPattern.MatchString(o.Collation) {
+		return fmt.Errorf("invalid collation %q", o.Collation)
+	}
+	if o.TLS != "" && !validTLSModes[o.TLS] {
+		return fmt.Errorf("tls must be true, false, skip-verify or preferred")
+	}
+	for name, secs := range map[string]int{
+		"connect_timeout_seconds": o.ConnectTimeoutSeconds,
+		"read_timeout_seconds":    o.ReadTimeoutSeconds,
+		"write_timeout_seconds":   o.WriteTimeoutSeconds,
+	} {
+		if secs < 0 || secs > maxConnectionTimeout {
+			return fmt.Errorf("%s must be between 0 and %d", name, maxConnectionTimeout)
+		}
+	}
+	return nil
+}
+
+// MySQLDefaults returns the options used for a server of the given version where
+// the connection sets none. An unknown version gets the defaults of a 5.7 server,
+// which 8.x servers accept too.
+func MySQLDefaults(serverVersion string) ConnectionOptions {
+	native := true
+	defaults := ConnectionOptions{
+		Charset:               "utf8mb4",
+		Collation:             "utf8mb4_general_ci",
+		ConnectTimeoutSeconds: defaultMySQLConnectTimeout,
+		AllowNativePasswords:  &native,
+	}
+	v, ok := parseServerVersion(serverVersion)
+	switch {
+	case !ok:
+	case v.atLeast(8, 0, 0):
+		// 8.0 defaults to caching_sha2_password and the 0900 collations.
+		defaults.Collation = "utf8mb4_0900_ai_ci"
+	case !v.atLeast(5, 5, 3):
+		// utf8mb4 arrived in 5.5.3.
+		defaults.Charset, defaults.Collation = "utf8", "utf8_general_ci"
+	}
+	return defaults
+}
+
+// mysqlQuery renders the options over the version defaults as connection string
+// parameters.
+func (o ConnectionOptions) mysqlQuery(serverVersion string) url.Values {
+	opts := MySQLDefaults(serverVersion)
+	if o.Charset != "" {
+		opts.Charset = o.Charset
+		// A charset chosen without a collation uses the server's default for it.
+		opts.Collation = o.Collation
+	}
+	if o.Collation != "" {
+		opts.Collation = o.Collation
+	}
+	if o.TLS != "" {
+		opts.TLS = o.TLS
+	}
+	if o.ConnectTimeoutSeconds > 0 {
+		opts.ConnectTimeoutSeconds = o.ConnectTimeoutSeconds
+	}
+	opts.ReadTimeoutSeconds, opts.WriteTimeoutSeconds = o.ReadTimeoutSeconds, o.WriteTimeoutSeconds
+	if o.AllowNativePasswords != nil {
+		opts.AllowNativePasswords = o.AllowNativePasswords
+	}
+
+	q := url.Values{}
+	q.Set("charset", opts.Charset)
+	if opts.Collation != "" {
+		q.Set("collation", opts.Collation)
+	}
+	if opts.TLS != "" {
+		q.Set("tls", opts.TLS)
+	}
+	q.Set("timeout", fmt.Sprintf("%ds", opts.ConnectTimeoutSeconds))
+	if opts.ReadTimeoutSeconds > 0 {
+		q.Set("readTimeout", fmt.Sprintf("%ds", opts.ReadTimeoutSeconds))
+	}
+	if opts.WriteTimeoutSeconds > 0 {
+		q.Set("writeTimeout", fmt.Sprintf("%ds", opts.WriteTimeoutSeconds))
+	}
+	q.Set("allowNativePasswords", strconv.FormatBool(*opts.AllowNativePasswords))
+	return q
+}
+
+// mysqlReadOnlyVariable is the session variable that makes a MySQL server refuse
+// writes: transaction_read_only since 5.7.20, tx_read_only before.
+func mysqlReadOnlyVariable(serverVersion string) string {
+	if v, ok := parseServerVersion(serverVersion); ok && !v.atLeast(5, 7, 20) {
+		return "tx_read_only"
+	}
+	return "transaction_read_only"
+}
+
+// mysqlOptionsFromQuery reads connection string parameters back into options.
+// Parameters it does not know are rejected rather than silently dropped.
+func mysqlOptionsFromQuery(q url.Values) (ConnectionOptions, error) {
+	var opts ConnectionOptions
+	for key := range q {
+		value := q.Get(key)
+		switch key {
+		case "charset":
+			opts.Charset = value
+		case "collation":
+			opts.Collation = value
+		case "tls":
+			opts.TLS = value
+		case "timeout", "readTimeout", "writeTimeout":
+			secs, err := parseSeconds(value)
+			if err != nil {
+				return ConnectionOptions{}, fmt.Errorf("invalid %s %q", key, value)
+			}
+			switch key {
+			case "timeout":
+				opts.ConnectTimeoutSeconds = secs
+			case "readTimeout":
+				opts.ReadTimeoutSeconds = secs
+			default:
+				opts.WriteTimeoutSeconds = secs
+			}
+		case "allowNativePasswords":
+			allow, err := strconv.ParseBool(value)
+			if err != nil {
+				return ConnectionOptions{}, fmt.Errorf("invalid allowNativePasswords %q", value)
+			}
+			opts.AllowNativePasswords = &allow
+		default:
+			return ConnectionOptions{}, fmt.Errorf("unsupported DSN parameter %q", key)
+		}
+	}
+	if err := opts.Normalize("mysql"); err != nil {
+		return ConnectionOptions{}, err
+	}
+	return opts, nil
+}
+
+// parseSeconds accepts a whole number of seconds with or without an "s" suffix.
+func parseSeconds(value string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "s"))
+}
+
+// DetectServerVersion finds the server version in test-conn output, e.g.
+// "server version: 8.0.36". It returns "" when the output names none.
+func DetectServerVersion(logs string) string {
+	m := versionPattern.FindStringSubmatch(logs)
+	if m == nil {
+		return ""
+	}
+	if m[3] == "" {
+		return m[1] + "." + m[2]
+	}
+	return m[1] + "." + m[2] + "." + m[3]
+}
+
+// MySQLWarnings lists deprecated settings the connection relies on for a server of
+// the given version. logs is test-conn output, which names the auth plugin the
+// server negotiated when the engine reports it.
+func MySQLWarnings(opts ConnectionOptions, serverVersion, logs string) []string {
+	v, ok := parseServerVersion(serverVersion)
+	if !ok {
+		return nil
+	}
+	var warnings []string
+	lower := strings.ToLower(logs)
+	native := strings.Contains(lower, "mysql_native_password") ||
+		(opts.AllowNativePasswords != nil && *opts.AllowNativePasswords)
+	switch {
+	case native && v.atLeast(9, 0, 0):
+		warnings = append(warnings, "mysql_native_password was removed in MySQL 9.0; switch the user to caching_sha2_password")
+	case native && v.atLeast(8, 0, 34):
+		warnings = append(warnings, "mysql_native_password is deprecated since MySQL 8.0.34; switch the user to caching_sha2_password")
+	}
+	if strings.Contains(lower, "sha256_password") && !strings.Contains(lower, "caching_sha2_password") && v.atLeast(8, 0, 16) {
+		warnings = append(warnings, "sha256_password is deprecated since MySQL 8.0.16; switch the user to caching_sha2_password")
+	}
+	if (opts.Charset == "utf8" || opts.Charset == "utf8mb3") && v.atLeast(8, 0, 0) {
+		warnings = append(warnings, "the utf8mb3 charset is deprecated since MySQL 8.0; use utf8mb4")
+	}
+	return warnings
+}
+
+type serverVersion [3]int
+
+func parseServerVersion(raw string) (serverVersion, bool) {
+	var v serverVersion
+	parts := strings.SplitN(strings.TrimSpace(raw), ".", 3)
+	if len(parts) < 2 {
+		return v, false
+	}
+	for i, part := range parts {
+		// Drop suffixes such as "-0ubuntu0.22.04.1" or "-log".
+		if end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			part = part[:end]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func (v serverVersion) atLeast(major, minor, patch int) bool {
+	if v[0] != major {
+		return v[0] > major
+	}
+	if v[1] != minor {
+		return v[1] > minor
+	}
+	return v[2] >= patch
+}
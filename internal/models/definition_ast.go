@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ASTTablesPath is where an AST lists the tables it migrates. Each entry is the
+// table's name, or an object with the name in "name" next to the table's settings.
+var ASTTablesPath = []string{"migrate", "tables"}
+
+// ParseASTPath splits a dotted AST path such as migrate.settings into its keys.
+// Array elements are addressed by index, as in migrate.tables.0.
+func ParseASTPath(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("ast_path is empty")
+	}
+	keys := strings.Split(raw, ".")
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return nil, errors.New("ast_path must be a dot-separated list of keys")
+		}
+	}
+	return keys, nil
+}
+
+// ASTTableName returns the table an entry of the AST's table list names, or ""
+// for entries that name none.
+func ASTTableName(entry json.RawMessage) string {
+	var name string
+	if err := json.Unmarshal(entry, &name); err == nil {
+		return name
+	}
+	var table struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(entry, &table); err == nil {
+		return table.Name
+	}
+	return ""
+}
+
+// FilterASTTables returns ast with its table list cut down to the entries naming
+// one of tables, in their original order, and the names it kept. Everything else
+// in the AST is left as it was.
+func FilterASTTables(ast json.RawMessage, tables []string) (json.RawMessage, []string, error) {
+	doc, err := decodeAST(ast)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, ok := doc.(map[string]interface{})
+	for _, key := range ASTTablesPath[:len(ASTTablesPath)-1] {
+		if !ok {
+			break
+		}
+		parent, ok = parent[key].(map[string]interface{})
+	}
+	last := ASTTablesPath[len(ASTTablesPath)-1]
+	var entries []interface{}
+	if ok {
+		entries, ok = parent[last].([]interface{})
+	}
+	if !ok {
+		return nil, nil, errors.New("AST has no table list at " + strings.Join(ASTTablesPath, "."))
+	}
+
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[table] = true
+	}
+	kept := []interface{}{}
+	var names []string
+	for _, entry := range entries {
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		if name := ASTTableName(raw); name != "" && wanted[name] {
+			kept = append(kept, entry)
+			names = append(names, name)
+		}
+	}
+	parent[last] = kept
+	filtered, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filtered, names, nil
+}
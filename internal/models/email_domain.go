@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Domain join request states.
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestRejected = "rejected"
+)
+
+// DomainVerificationPrefix prefixes both the TXT record name and its value.
+const DomainVerificationPrefix = "stratum-verification"
+
+// publicEmailDomains are shared mailbox providers no tenant may claim.
+var publicEmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"outlook.com":    true,
+	"hotmail.com":    true,
+	"live.com":       true,
+	"yahoo.com":      true,
+	"icloud.com":     true,
+	"me.com":         true,
+	"aol.com":        true,
+	"proton.me":      true,
+	"protonmail.com": true,
+	"gmx.com":        true,
+	"yandex.com":     true,
+	"mail.com":       true,
+}
+
+// EmailDomain is an email domain a tenant claims. Once verified through DNS, people
+// signing up with an address at the domain join the tenant as viewers.
+type EmailDomain struct {
+	ID                string     `json:"id"`
+	TenantID          string     `json:"tenant_id"`
+	Domain            string     `json:"domain"`
+	VerificationToken string     `json:"-"`
+	RequireApproval   bool       `json:"require_approval"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedBy         *string    `json:"created_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TXTRecordName is the DNS name the verification TXT record must be published at.
+func (d EmailDomain) TXTRecordName() string {
+	return "_" + DomainVerificationPrefix + "." + d.Domain
+}
+
+// TXTRecordValue is the TXT record content proving control of the domain.
+func (d EmailDomain) TXTRecordValue() string {
+	return DomainVerificationPrefix + "=" + d.VerificationToken
+}
+
+// DomainJoinRequest records a signup that joined a tenant through its email domain.
+type DomainJoinRequest struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	UserID    string     `json:"user_id"`
+	Email     string     `json:"email"`
+	Domain    string     `json:"domain"`
+	Status    string     `json:"status"`
+	DecidedBy *string    `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NormalizeEmailDomain lowercases and validates a domain a tenant wants to claim.
+func NormalizeEmailDomain(domain string) (string, error) {
+	d := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if d == "" {
+		return "", errors.New("domain is required")
+	}
+	if len(d) > 253 || !strings.Contains(d, ".") {
+		return "", errors.New("domain must be a fully qualified domain name")
+	}
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", errors.New("domain must be a fully qualified domain name")
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return "", errors.New("domain must be a fully qualified domain name")
+			}
+		}
+	}
+	if publicEmailDomains[d] {
+		return "", errors.New("public email providers cannot be claimed")
+	}
+	return d, nil
+}
+
+// EmailDomainOf returns the lowercased domain part of an email address.
+func EmailDomainOf(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatPair is a source and destination data format an engine migrates between.
+type FormatPair struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// EngineCapabilities is an engine version and the format pairs it can migrate, as
+// the engine reports them to capability discovery.
+type EngineCapabilities struct {
+	Version string       `json:"version"`
+	Pairs   []FormatPair `json:"pairs"`
+}
+
+// Normalize trims the version and puts every pair's formats in canonical form,
+// dropping incomplete and repeated pairs.
+func (c *EngineCapabilities) Normalize() {
+	c.Version = strings.TrimSpace(c.Version)
+	seen := map[FormatPair]bool{}
+	pairs := make([]FormatPair, 0, len(c.Pairs))
+	for _, p := range c.Pairs {
+		p = FormatPair{Source: CanonicalDataFormat(p.Source), Destination: CanonicalDataFormat(p.Destination)}
+		if p.Source == "" || p.Destination == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		pairs = append(pairs, p)
+	}
+	c.Pairs = pairs
+}
+
+// EngineFormatSupport records that an engine version migrates one format pair.
+type EngineFormatSupport struct {
+	EngineVersion     string `json:"engine_version"`
+	SourceFormat      string `json:"source_format"`
+	DestinationFormat string `json:"destination_format"`
+}
+
+// CompatibilityMatrix is every format pair each known engine version migrates.
+// CurrentVersion is the version the running engine reported when it was last
+// discovered; it is empty before the first discovery.
+type CompatibilityMatrix struct {
+	CurrentVersion string                `json:"current_version"`
+	DiscoveredAt   *time.Time            `json:"discovered_at,omitempty"`
+	Entries        []EngineFormatSupport `json:"entries"`
+}
+
+// Check returns "" when the current engine migrates source to destination, or when
+// the running engine was not discovered yet. Otherwise it names the engine version
+// the pair requires, or says that no known version supports it.
+func (m CompatibilityMatrix) Check(source, destination string) string {
+	if m.CurrentVersion == "" {
+		return ""
+	}
+	source, destination = CanonicalDataFormat(source), CanonicalDataFormat(destination)
+	var minVersion string
+	for _, e := range m.Entries {
+		if e.SourceFormat != source || e.DestinationFormat != destination {
+			continue
+		}
+		if e.EngineVersion == m.CurrentVersion {
+			return ""
+		}
+		if minVersion == "" || CompareEngineVersions(e.EngineVersion, minVersion) < 0 {
+			minVersion = e.EngineVersion
+		}
+	}
+	pair := DataFormatLabel(source) + "→" + DataFormatLabel(destination)
+	if minVersion == "" || CompareEngineVersions(minVersion, m.CurrentVersion) <= 0 {
+		return fmt.Sprintf("%s is not supported by engine %s", pair, m.CurrentVersion)
+	}
+	return fmt.Sprintf("%s requires engine >= %s (running %s)", pair, minVersion, m.CurrentVersion)
+}
+
+// CanonicalDataFormat lowercases a data format and maps aliases to the name
+// connections store.
+func CanonicalDataFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "postgres", "postgresql":
+		return "pg"
+	case "mongodb":
+		return "mongo"
+	}
+	return format
+}
+
+var dataFormatLabels = map[string]string{
+	"pg":    "Postgres",
+	"mysql": "MySQL",
+	"mongo": "Mongo",
+	"csv":   "CSV",
+	"api":   "API",
+}
+
+// DataFormatLabel is the display name of a canonical data format.
+func DataFormatLabel(format string) string {
+	if label, ok := dataFormatLabels[format]; ok {
+		return label
+	}
+	return format
+}
+
+// CompareEngineVersions orders dotted engine versions such as "1.4" and "v1.10.2"
+// numerically. Missing parts count as zero; pre-release and build suffixes are
+// ignored.
+func CompareEngineVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			n = 0
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
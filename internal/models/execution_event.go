@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Timeline events of an execution, in the order a run normally records them.
+const (
+	ExecutionEventCreated          = "created"
+	ExecutionEventQueued           = "queued"
+	ExecutionEventImagePulled      = "image_pulled"
+	ExecutionEventContainerCreated = "container_created"
+	ExecutionEventStarted          = "started"
+	ExecutionEventCallbackReceived = "callback_received"
+	ExecutionEventCompleted        = "completed"
+)
+
+// ExecutionEvent is one step of an execution's timeline. Retried activities record
+// their steps again, so a type may appear more than once.
+type ExecutionEvent struct {
+	ID          string                 `json:"id" db:"id"`
+	ExecutionID string                 `json:"execution_id" db:"execution_id"`
+	Type        string                 `json:"type" db:"type"`
+	Details     map[string]interface{} `json:"details,omitempty" db:"details"`
+	OccurredAt  time.Time              `json:"occurred_at" db:"occurred_at"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Plans a tenant can be on. Each plan carries default execution start limits.
+const (
+	PlanFree       = "free"
+	PlanTeam       = "team"
+	PlanEnterprise = "enterprise"
+)
+
+// Windows of the execution start limits: the rate limit counts starts over the
+// last hour, the burst limit over the last minute.
+const (
+	ExecutionStartRateWindow  = time.Hour
+	ExecutionStartBurstWindow = time.Minute
+)
+
+// PlanExecutionStartLimits are the start limits of each plan. Zero means unlimited.
+var PlanExecutionStartLimits = map[string]ExecutionStartLimit{
+	PlanFree:       {StartsPerHour: 10, Burst: 3},
+	PlanTeam:       {StartsPerHour: 100, Burst: 10},
+	PlanEnterprise: {},
+}
+
+// ExecutionStartLimit caps how many executions a tenant may start. Zero fields are
+// unlimited.
+type ExecutionStartLimit struct {
+	// StartsPerHour caps the starts within any sliding hour.
+	StartsPerHour int `json:"starts_per_hour"`
+	// Burst caps the starts within any sliding minute.
+	Burst int `json:"burst"`
+}
+
+// Unlimited reports whether the limit allows any number of starts.
+func (l ExecutionStartLimit) Unlimited() bool {
+	return l.StartsPerHour == 0 && l.Burst == 0
+}
+
+// Check reports whether another execution may start at now given the earlier
+// starts, which must cover at least the last ExecutionStartRateWindow. When it may
+// not, resetAt is when enough of those starts leave their window to allow one.
+func (l ExecutionStartLimit) Check(starts []time.Time, now time.Time) (allowed bool, resetAt time.Time) {
+	sorted := append([]time.Time(nil), starts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	allowed = true
+	for _, w := range []struct {
+		max    int
+		window time.Duration
+	}{{l.StartsPerHour, ExecutionStartRateWindow}, {l.Burst, ExecutionStartBurstWindow}} {
+		if w.max == 0 {
+			continue
+		}
+		inWindow := startsSince(sorted, now.Add(-w.window))
+		if len(inWindow) < w.max {
+			continue
+		}
+		allowed = false
+		// One more start fits once all but max-1 of the window's starts aged out.
+		if reset := inWindow[len(inWindow)-w.max].Add(w.window); reset.After(resetAt) {
+			resetAt = reset
+		}
+	}
+	return allowed, resetAt
+}
+
+// startsSince returns the sorted starts after since.
+func startsSince(sorted []time.Time, since time.Time) []time.Time {
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i].After(since) })
+	return sorted[i:]
+}
+
+// TenantExecutionLimits are a tenant's execution start limits: its plan's defaults
+// with optional per-tenant overrides. Nil fields fall back to the plan, and
+// tenants without a plan are unlimited.
+type TenantExecutionLimits struct {
+	TenantID      string     `json:"tenant_id"`
+	Plan          *string    `json:"plan"`
+	StartsPerHour *int       `json:"starts_per_hour"`
+	Burst         *int       `json:"burst"`
+	UpdatedBy     *string    `json:"updated_by,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// Effective returns the limit the tenant's starts are held to.
+func (l TenantExecutionLimits) Effective() ExecutionStartLimit {
+	var limit ExecutionStartLimit
+	if l.Plan != nil {
+		limit = PlanExecutionStartLimits[*l.Plan]
+	}
+	if l.StartsPerHour != nil {
+		limit.StartsPerHour = *l.StartsPerHour
+	}
+	if l.Burst != nil {
+		limit.Burst = *l.Burst
+	}
+	return limit
+}
+
+// Normalize validates the plan and the overrides.
+func (l *TenantExecutionLimits) Normalize() error {
+	if l.Plan != nil {
+		if *l.Plan == "" {
+			l.Plan = nil
+		} else if _, ok := PlanExecutionStartLimits[*l.Plan]; !ok {
+			return fmt.Errorf("plan must be one of %s, %s or %s", PlanFree, PlanTeam, PlanEnterprise)
+		}
+	}
+	if l.StartsPerHour != nil && *l.StartsPerHour < 0 {
+		return errors.New("starts_per_hour must not be negative")
+	}
+	if l.Burst != nil && *l.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	return nil
+}
+
+// ExecutionStartUsage reports a tenant's execution start limits and how much of
+// them is used, as returned by GET /tenants/{tenantID}/limits.
+type ExecutionStartUsage struct {
+	TenantExecutionLimits
+	Effective ExecutionStartLimit `json:"effective"`
+	// StartsLastHour and StartsLastMinute count the starts in the sliding windows.
+	StartsLastHour   int `json:"starts_last_hour"`
+	StartsLastMinute int `json:"starts_last_minute"`
+	// ResetAt is when another start is allowed; nil while starts are allowed.
+	ResetAt *time.Time `json:"reset_at,omitempty"`
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LogLevel is the severity a stored log line was classified with.
+type LogLevel string
+
+const (
+	LogLevelDebug LogLevel = "debug"
+	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+)
+
+var logLevelRank = map[LogLevel]int{
+	LogLevelDebug: 1,
+	LogLevelInfo:  2,
+	LogLevelWarn:  3,
+	LogLevelError: 4,
+}
+
+// ParseLogLevel reads a level filter, accepting the common spellings of each
+// level. It reports false for anything else.
+func ParseLogLevel(s string) (LogLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace", "debug":
+		return LogLevelDebug, true
+	case "info":
+		return LogLevelInfo, true
+	case "warn", "warning":
+		return LogLevelWarn, true
+	case "error", "fatal", "panic", "critical":
+		return LogLevelError, true
+	}
+	return "", false
+}
+
+// AtLeast reports whether l is as severe as level or more.
+func (l LogLevel) AtLeast(level LogLevel) bool {
+	return logLevelRank[l] >= logLevelRank[level]
+}
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	// logLevelToken finds the level the engine's log formats put near the start of
+	// a line: "2024-05-01T10:00:00Z  WARN stratum::copy: ...", "[ERROR] ...",
+	// "level=error" or `"level":"error"`.
+	logLevelToken = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|PANIC)\b|(?i)\blevel"?\s*[=:]\s*"?(trace|debug|info|warn|warning|error|fatal|panic)\b`)
+)
+
+// StripANSI removes terminal color and cursor escape sequences.
+func StripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// ClassifyLogLine returns the level a line states, and false when it states none,
+// as continuation lines of a multi-line message do.
+func ClassifyLogLine(line string) (LogLevel, bool) {
+	if strings.Contains(line, "panicked at") {
+		return LogLevelError, true
+	}
+	m := logLevelToken.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	token := m[1]
+	if token == "" {
+		token = m[2]
+	}
+	return ParseLogLevel(token)
+}
+
+// ExecutionLogChunk is a run of consecutive log lines of one level.
+type ExecutionLogChunk struct {
+	Seq     int      `json:"seq" db:"seq"`
+	Level   LogLevel `json:"level" db:"level"`
+	Lines   int      `json:"lines" db:"line_count"`
+	Content string   `json:"content" db:"content"`
+}
+
+// maxLogChunkLines caps a chunk, so that a long stretch of one level is still
+// stored and returned in pieces.
+const maxLogChunkLines = 500
+
+// ChunkLogs splits ANSI-stripped logs into chunks by level. Lines that state no
+// level belong to the message above them; leading ones count as info.
+func ChunkLogs(logs string) []ExecutionLogChunk {
+	logs = strings.TrimRight(logs, "\n")
+	if logs == "" {
+		return nil
+	}
+	var chunks []ExecutionLogChunk
+	var lines []string
+	level := LogLevelInfo
+	flush := func() {
+		if len(lines) > 0 {
+			chunks = append(chunks, ExecutionLogChunk{Seq: len(chunks), Level: level, Lines: len(lines), Content: strings.Join(lines, "\n")})
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(logs, "\n") {
+		if lineLevel, ok := ClassifyLogLine(line); ok && lineLevel != level {
+			flush()
+			level = lineLevel
+		} else if len(lines) == maxLogChunkLines {
+			flush()
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	flush()
+	return chunks
+}
+
+// ExecutionLogs is an execution's stored logs, optionally narrowed to a minimum
+// level. Counts tallies the lines of every level, whatever the filter.
+type ExecutionLogs struct {
+	ExecutionID string              `json:"execution_id"`
+	Level       LogLevel            `json:"level"`
+	Counts      map[LogLevel]int    `json:"counts"`
+	Chunks      []ExecutionLogChunk `json:"chunks"`
+}
+
+// FilterLogChunks builds the logs response from all of an execution's chunks.
+func FilterLogChunks(executionID string, chunks []ExecutionLogChunk, minLevel LogLevel) ExecutionLogs {
+	logs := ExecutionLogs{
+		ExecutionID: executionID,
+		Level:       minLevel,
+		Counts:      map[LogLevel]int{LogLevelDebug: 0, LogLevelInfo: 0, LogLevelWarn: 0, LogLevelError: 0},
+		Chunks:      []ExecutionLogChunk{},
+	}
+	for _, chunk := range chunks {
+		logs.Counts[chunk.Level] += chunk.Lines
+		if chunk.Level.AtLeast(minLevel) {
+			logs.Chunks = append(logs.Chunks, chunk)
+		}
+	}
+	return logs
+}
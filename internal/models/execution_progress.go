@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Steps of an execution, in the order ExecutionWorkflow goes through them.
+const (
+	// ProgressStepPending covers the time before the run starts: waiting for the
+	// definition's sensors or a worker slot.
+	ProgressStepPending = "pending"
+	// ProgressStepPrepare resolves connections and writes the engine config.
+	ProgressStepPrepare = "prepare"
+	// ProgressStepPull records the environment snapshot, pulling the engine image.
+	ProgressStepPull = "pull"
+	// ProgressStepRun is the engine, or the SQL script, doing the work.
+	ProgressStepRun = "run"
+	// ProgressStepComplete follows the run, whatever its outcome.
+	ProgressStepComplete = "complete"
+)
+
+// progressStepStart is the percent complete an execution has reached when it
+// enters each step. The run step spans everything up to ProgressStepComplete.
+var progressStepStart = map[string]int{
+	ProgressStepPending:  0,
+	ProgressStepPrepare:  5,
+	ProgressStepPull:     15,
+	ProgressStepRun:      25,
+	ProgressStepComplete: 100,
+}
+
+// ExecutionProgress is how far an execution has come. ExecutionWorkflow answers
+// the progress query with Step, Percent and StepStartedAt; the API adds the rest.
+type ExecutionProgress struct {
+	ExecutionID     string          `json:"execution_id,omitempty"`
+	Status          ExecutionStatus `json:"status,omitempty"`
+	Step            string          `json:"step"`
+	Percent         int             `json:"percent"`
+	StepStartedAt   *time.Time      `json:"step_started_at,omitempty"`
+	TablesTotal     int             `json:"tables_total,omitempty"`
+	TablesCompleted int             `json:"tables_completed,omitempty"`
+	// Live is false when the workflow could not be asked and the step was derived
+	// from the recorded status instead.
+	Live bool `json:"live"`
+}
+
+// EnterStep moves the progress to step, started at now.
+func (p *ExecutionProgress) EnterStep(step string, now time.Time) {
+	p.Step, p.Percent, p.StepStartedAt = step, progressStepStart[step], &now
+}
+
+// ProgressFromStatus derives the progress of an execution whose workflow cannot
+// be asked from its recorded status.
+func ProgressFromStatus(exec JobExecution) ExecutionProgress {
+	p := ExecutionProgress{ExecutionID: exec.ID, Status: exec.Status}
+	switch {
+	case exec.Status == ExecutionStatusPending || exec.Status == ExecutionStatusAwaitingApproval:
+		p.Step = ProgressStepPending
+	case exec.Status.Active():
+		p.Step, p.StepStartedAt = ProgressStepRun, exec.RunStartedAt
+	default:
+		p.Step, p.StepStartedAt = ProgressStepComplete, exec.RunCompletedAt
+	}
+	p.Percent = progressStepStart[p.Step]
+	return p
+}
+
+// ApplyCheckpoints spreads the run step's share of the percent complete over the
+// tables the AST lists, counting those the engine reported as completed.
+func (p *ExecutionProgress) ApplyCheckpoints(tables []string, checkpoints []ExecutionCheckpoint) {
+	if len(tables) == 0 {
+		return
+	}
+	completed := make(map[string]bool, len(checkpoints))
+	for _, c := range checkpoints {
+		if c.Completed {
+			completed[c.Table] = true
+		}
+	}
+	p.TablesTotal, p.TablesCompleted = len(tables), 0
+	for _, table := range tables {
+		if completed[table] {
+			p.TablesCompleted++
+		}
+	}
+	if p.Step == ProgressStepRun {
+		start, end := progressStepStart[ProgressStepRun], progressStepStart[ProgressStepComplete]
+		// A run whose tables are all done still has its completion handling ahead.
+		p.Percent = min(start+(end-start)*p.TablesCompleted/p.TablesTotal, end-1)
+	}
+}
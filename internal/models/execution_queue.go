@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// ExecutionQueue reports where a pending execution stands among the tenant's
+// executions and when it is predicted to start and finish. Predictions come from the
+// average run duration of each definition and are nil when a definition involved has
+// no timed runs to average.
+type ExecutionQueue struct {
+	ExecutionID string          `json:"execution_id"`
+	Status      ExecutionStatus `json:"status"`
+	// Ahead is the number of the tenant's pending executions created before this one.
+	Ahead int `json:"ahead"`
+	// Running is the number of the tenant's executions running or paused right now.
+	Running           int        `json:"running"`
+	EstimatedStartAt  *time.Time `json:"estimated_start_at"`
+	EstimatedFinishAt *time.Time `json:"estimated_finish_at"`
+}
+
+// EstimateQueue places an active execution in the tenant's queue. active holds the
+// tenant's active executions, oldest first, and averages the average run duration in
+// seconds per definition. Pending executions are assumed to start as the running
+// ones finish, with as many running side by side as run now (at least one).
+func EstimateQueue(exec JobExecution, active []JobExecution, averages map[string]float64, now time.Time) ExecutionQueue {
+	queue := ExecutionQueue{ExecutionID: exec.ID, Status: exec.Status}
+	ownAvg, known := averages[exec.JobDefinitionID]
+	if exec.Status != ExecutionStatusPending {
+		if exec.RunStartedAt != nil {
+			start := *exec.RunStartedAt
+			queue.EstimatedStartAt = &start
+			if known {
+				finish := start.Add(seconds(ownAvg))
+				queue.EstimatedFinishAt = &finish
+			}
+		}
+		for _, other := range active {
+			if other.Status != ExecutionStatusPending {
+				queue.Running++
+			}
+		}
+		return queue
+	}
+
+	var work float64
+	for _, other := range active {
+		avg, ok := averages[other.JobDefinitionID]
+		switch {
+		case other.ID == exec.ID:
+			continue
+		case other.Status != ExecutionStatusPending:
+			queue.Running++
+			known = known && ok
+			remaining := avg
+			if other.RunStartedAt != nil {
+				remaining -= now.Sub(*other.RunStartedAt).Seconds()
+			}
+			if remaining > 0 {
+				work += remaining
+			}
+		case other.CreatedAt.Before(exec.CreatedAt):
+			queue.Ahead++
+			known = known && ok
+			work += avg
+		}
+	}
+	if !known {
+		return queue
+	}
+	start := now.Add(seconds(work / float64(max(queue.Running, 1))))
+	finish := start.Add(seconds(ownAvg))
+	queue.EstimatedStartAt, queue.EstimatedFinishAt = &start, &finish
+	return queue
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Statuses of a table within an execution, as the engine reports them.
+const (
+	ExecutionTableSucceeded = "succeeded"
+	ExecutionTableFailed    = "failed"
+	ExecutionTableSkipped   = "skipped"
+)
+
+// ValidExecutionTableStatus reports whether status is one the engine may report.
+func ValidExecutionTableStatus(status string) bool {
+	switch status {
+	case ExecutionTableSucceeded, ExecutionTableFailed, ExecutionTableSkipped:
+		return true
+	}
+	return false
+}
+
+// JobExecutionTable is the outcome of one table of an execution, reported by the
+// engine when the run completes.
+type JobExecutionTable struct {
+	ExecutionID string    `json:"execution_id" db:"execution_id"`
+	Table       string    `json:"table" db:"table_name"`
+	Status      string    `json:"status" db:"status"`
+	RowsCopied  int64     `json:"rows_copied" db:"rows_copied"`
+	Error       *string   `json:"error,omitempty" db:"error"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
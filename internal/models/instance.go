@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Instance describes a self-hosted deployment. It exists once first-run setup has
+// completed, after which setup stays locked.
+type Instance struct {
+	Name             string    `json:"name"`
+	SetupTenantID    *string   `json:"setup_tenant_id,omitempty"`
+	SetupUserID      *string   `json:"setup_user_id,omitempty"`
+	SetupCompletedAt time.Time `json:"setup_completed_at"`
+}
+
+// The tenant and admins the initial migrations seed into every database. An
+// instance holding nothing else has not been set up yet.
+const SeedTenantName = "default"
+
+var SeedUserEmails = []string{"admin@example.com", "superadmin@example.com"}
+
+// Outcomes of a setup connectivity check.
+const (
+	SetupCheckOK      = "ok"
+	SetupCheckFailed  = "failed"
+	SetupCheckSkipped = "skipped"
+)
+
+// SetupCheck is the result of probing one dependency during first-run setup.
+type SetupCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
@@ -2,6 +2,13 @@ package models
 
 import "time"
 
+// Invite email delivery states.
+const (
+	InviteDeliveryPending = "pending"
+	InviteDeliverySent    = "sent"
+	InviteDeliveryFailed  = "failed"
+)
+
 // Invite represents a pending invitation to join a tenant.
 type Invite struct {
 	ID         string     `json:"id"`
@@ -14,6 +21,16 @@ type Invite struct {
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
 	CreatedBy  *string    `json:"created_by,omitempty"`
+
+	// DeliveryStatus tracks the invite email: pending while it is being sent or
+	// retried, then sent or failed.
+	DeliveryStatus   string     `json:"delivery_status"`
+	DeliveryAttempts int        `json:"delivery_attempts"`
+	DeliveryError    *string    `json:"delivery_error,omitempty"`
+	NextDeliveryAt   *time.Time `json:"next_delivery_at,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+	// DeliveryToken is the encrypted raw token, kept until delivery settles.
+	DeliveryToken []byte `json:"-"`
 }
 
 // IsExpired determines whether the invite has expired.
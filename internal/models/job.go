@@ -6,35 +6,221 @@ import (
 )
 
 type JobDefinition struct {
-	ID                      string                  `json:"id" db:"id"`
-	TenantID                string                  `json:"tenant_id" db:"tenant_id"`
-	Name                    string                  `json:"name" db:"name"`
-	Description             string                  `json:"description" db:"description"`
+	ID          string `json:"id" db:"id"`
+	TenantID    string `json:"tenant_id" db:"tenant_id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// JobType is JobTypeEngine or JobTypeSQLScript.
+	JobType                 string                  `json:"job_type" db:"job_type"`
 	AST                     json.RawMessage         `json:"ast" db:"ast"`
 	SourceConnectionID      string                  `json:"-" db:"source_connection_id"`
 	DestinationConnectionID string                  `json:"-" db:"destination_connection_id"`
 	SourceConnection        Connection              `json:"source_connection"`
 	DestinationConnection   Connection              `json:"destination_connection"`
-	Status                  string                  `json:"status" db:"status"`
+	Status                  DefinitionStatus        `json:"status" db:"status"`
 	ProgressSnapshot        json.RawMessage         `json:"progress_snapshot,omitempty" db:"progress_snapshot"`
 	ProgressSnapshots       []JobDefinitionSnapshot `json:"progress_snapshots,omitempty"`
-	CreatedAt               time.Time               `json:"created_at" db:"created_at"`
-	UpdatedAt               time.Time               `json:"updated_at" db:"updated_at"`
+	// WriteMode is how the engine writes destination tables; the AST may override it
+	// per table.
+	WriteMode string `json:"write_mode" db:"write_mode"`
+	// RetryPolicy overrides how the engine run is retried; nil keeps the defaults.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty" db:"retry_policy"`
+	// Verification is whether runs compare source and destination after migrating
+	// (off, warn or fail).
+	Verification string `json:"verification" db:"verification"`
+	// ArchivedAt is when an ARCHIVED definition was archived.
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	// RunGrants is only loaded for definition details.
+	RunGrants *RunGrants `json:"run_grants,omitempty" db:"-"`
+	// Schedule is only loaded for definition details.
+	Schedule *JobSchedule `json:"schedule,omitempty" db:"-"`
+	// Lock is only loaded for definition details, and set while an execution is in
+	// progress.
+	Lock *DefinitionLock `json:"lock,omitempty" db:"-"`
+	// StaleValidation flags a READY definition whose source schema changed since it
+	// was validated; StaleSince is when the drift was first seen.
+	StaleValidation bool       `json:"stale_validation" db:"-"`
+	StaleSince      *time.Time `json:"stale_validation_since,omitempty" db:"-"`
+	SchemaCheckedAt *time.Time `json:"schema_checked_at,omitempty" db:"-"`
+	// Warnings flag risky but allowed setups, such as mixed connection environments.
+	Warnings  []string  `json:"warnings,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EnvironmentWarnings returns the warnings of a READY definition whose connections
+// are labeled with different environments.
+func (d JobDefinition) EnvironmentWarnings() []string {
+	if d.Status != DefinitionStatusReady {
+		return nil
+	}
+	if msg := EnvironmentMismatch(d.SourceConnection, d.DestinationConnection); msg != "" {
+		return []string{"Mixed environments: " + msg}
+	}
+	return nil
+}
+
+// DefinitionLock blocks edits of a definition's AST and connections while one of
+// its executions is pending, running or paused, so what ran stays what was saved.
+type DefinitionLock struct {
+	ExecutionID string          `json:"execution_id"`
+	Status      ExecutionStatus `json:"status"`
+	Since       time.Time       `json:"since"`
+}
+
+// RunGrants lists who may run a definition without being an editor: the given users,
+// and everyone holding at least one of the given roles.
+type RunGrants struct {
+	Users []string   `json:"users"`
+	Roles []UserRole `json:"roles"`
+}
+
+// Allows reports whether a user with the given roles is covered by the grants.
+func (g RunGrants) Allows(userID string, roles []UserRole) bool {
+	for _, id := range g.Users {
+		if id == userID {
+			return true
+		}
+	}
+	for _, role := range g.Roles {
+		if HasAtLeast(roles, role) {
+			return true
+		}
+	}
+	return false
 }
 
 type JobExecution struct {
-	ID               string     `json:"id" db:"id"`
-	TenantID         string     `json:"tenant_id" db:"tenant_id"`
-	JobDefinitionID  string     `json:"job_definition_id" db:"job_definition_id"`
-	Status           string     `json:"status" db:"status"`
-	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
-	RunStartedAt     *time.Time `json:"run_started_at" db:"run_started_at"`
-	RunCompletedAt   *time.Time `json:"run_completed_at" db:"run_completed_at"`
-	ErrorMessage     *string    `json:"error_message" db:"error_message"`
-	Logs             *string    `json:"logs" db:"logs"`
-	RecordsProcessed *int64     `json:"records_processed" db:"records_processed"`
-	BytesTransferred *int64     `json:"bytes_transferred" db:"bytes_transferred"`
+	ID                     string          `json:"id" db:"id"`
+	TenantID               string          `json:"tenant_id" db:"tenant_id"`
+	JobDefinitionID        string          `json:"job_definition_id" db:"job_definition_id"`
+	Status                 ExecutionStatus `json:"status" db:"status"`
+	CreatedAt              time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at" db:"updated_at"`
+	RunStartedAt           *time.Time      `json:"run_started_at" db:"run_started_at"`
+	RunCompletedAt         *time.Time      `json:"run_completed_at" db:"run_completed_at"`
+	PausedAt               *time.Time      `json:"paused_at,omitempty" db:"paused_at"`
+	ErrorMessage           *string         `json:"error_message" db:"error_message"`
+	Logs                   *string         `json:"logs" db:"logs"`
+	RecordsProcessed       *int64          `json:"records_processed" db:"records_processed"`
+	BytesTransferred       *int64          `json:"bytes_transferred" db:"bytes_transferred"`
+	ResumedFromExecutionID *string         `json:"resumed_from_execution_id" db:"resumed_from_execution_id"`
+	// RequeuedAsExecutionID is the run that replaced this failed execution when it
+	// was requeued from the dead-letter list.
+	RequeuedAsExecutionID *string    `json:"requeued_as_execution_id,omitempty" db:"requeued_as_execution_id"`
+	RequeuedAt            *time.Time `json:"requeued_at,omitempty" db:"requeued_at"`
+	// ApprovedBy is the admin who approved a run of a tenant that requires run
+	// approval.
+	ApprovedBy *string    `json:"approved_by,omitempty" db:"approved_by"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty" db:"approved_at"`
+	// TriggeredBy is what started the run, one of the Trigger constants; nil for runs
+	// from before triggers were tracked. TriggerContext identifies the user, API key,
+	// schedule or execution behind it.
+	TriggeredBy    *string           `json:"triggered_by" db:"triggered_by"`
+	TriggerContext map[string]string `json:"trigger_context,omitempty" db:"trigger_context"`
+	// TriggerType is how the run was started, one of the TriggerType constants, and
+	// TriggeredByUserID the user who started a manual run. Both are nil for runs from
+	// before triggers were tracked.
+	TriggerType       *string `json:"trigger_type" db:"trigger_type"`
+	TriggeredByUserID *string `json:"triggered_by_user_id" db:"triggered_by_user_id"`
+	// WorkflowID is the Temporal workflow running the execution; nil for runs from
+	// before it was recorded, whose workflow ID derives from the execution ID.
+	WorkflowID *string `json:"workflow_id,omitempty" db:"workflow_id"`
+	// Suspect flags a succeeded run that moved no data although earlier runs did, or
+	// whose destination failed a warn-only verification.
+	Suspect       bool    `json:"suspect" db:"suspect"`
+	SuspectReason *string `json:"suspect_reason,omitempty" db:"suspect_reason"`
+	// ActivityAttempts maps each workflow activity that was retried to its latest
+	// attempt number.
+	ActivityAttempts map[string]int32 `json:"activity_attempts,omitempty" db:"activity_attempts"`
+	// StartupTimings maps each startup stage the run completed to when it did.
+	StartupTimings map[string]time.Time `json:"-" db:"startup_timings"`
+	// StartupBreakdown is the time spent in each startup stage.
+	StartupBreakdown *StartupBreakdown `json:"startup_breakdown,omitempty" db:"-"`
+	// PartialState is the destination state report captured when the run failed.
+	PartialState *PartialStateReport `json:"partial_state,omitempty" db:"-"`
+	// Verification is the post-migration comparison report of a finished run, if
+	// its definition verifies runs.
+	Verification *VerificationReport `json:"verification,omitempty" db:"-"`
+	// Rollback is the report of a finished rollback execution.
+	Rollback *RollbackReport `json:"rollback,omitempty" db:"-"`
+	// Notes are the post-mortem annotations attached to the execution.
+	Notes []ExecutionNote `json:"notes,omitempty" db:"-"`
+}
+
+// Execution trigger sources.
+const (
+	TriggerUser     = "user"
+	TriggerAPIKey   = "api_key"
+	TriggerSchedule = "schedule"
+	TriggerRetry    = "retry"
+	TriggerPipeline = "pipeline"
+)
+
+// How executions are started, coarser than their trigger source.
+const (
+	TriggerTypeManual   = "manual"
+	TriggerTypeSchedule = "schedule"
+	TriggerTypeAPIKey   = "api_key"
+)
+
+// ExecutionTrigger derives how a run with trigger source triggeredBy and
+// triggerContext was started, and the user who started it when that was by hand.
+// Retries and pipeline runs count as manual unless an API key started them.
+func ExecutionTrigger(triggeredBy string, triggerContext map[string]string) (triggerType, userID string) {
+	switch {
+	case triggeredBy == "":
+		return "", ""
+	case triggeredBy == TriggerSchedule:
+		return TriggerTypeSchedule, ""
+	case triggeredBy == TriggerAPIKey || triggerContext["api_key_id"] != "":
+		return TriggerTypeAPIKey, ""
+	}
+	return TriggerTypeManual, triggerContext["user_id"]
+}
+
+// ValidTrigger reports whether t is a known trigger source.
+func ValidTrigger(t string) bool {
+	switch t {
+	case TriggerUser, TriggerAPIKey, TriggerSchedule, TriggerRetry, TriggerPipeline:
+		return true
+	}
+	return false
+}
+
+// Execution priorities. High-priority runs go to a task queue of their own, served
+// by reserved worker slots, so they don't wait behind long bulk loads.
+const (
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// ValidPriority reports whether p is a known execution priority.
+func ValidPriority(p string) bool {
+	return p == PriorityNormal || p == PriorityHigh
+}
+
+// MovedNoData reports whether the engine reported the execution's metrics and they
+// are all zero. Executions without any reported metrics are not judged.
+func (e JobExecution) MovedNoData() bool {
+	if e.RecordsProcessed == nil && e.BytesTransferred == nil {
+		return false
+	}
+	return (e.RecordsProcessed == nil || *e.RecordsProcessed == 0) &&
+		(e.BytesTransferred == nil || *e.BytesTransferred == 0)
+}
+
+// ExecutionNote is a markdown annotation attached to an execution, typically the
+// conclusions of investigating a failed run.
+type ExecutionNote struct {
+	ID          string    `json:"id" db:"id"`
+	ExecutionID string    `json:"execution_id" db:"execution_id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	AuthorID    *string   `json:"author_id,omitempty" db:"author_id"`
+	AuthorEmail string    `json:"author_email,omitempty" db:"author_email"`
+	Body        string    `json:"body" db:"body"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type JobDefinitionSnapshot struct {
@@ -44,3 +230,53 @@ type JobDefinitionSnapshot struct {
 	Snapshot        json.RawMessage `json:"snapshot" db:"snapshot"`
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 }
+
+// ConnectionFingerprint identifies a connection endpoint without exposing credentials.
+type ConnectionFingerprint struct {
+	ConnectionID string `json:"connection_id"`
+	Name         string `json:"name"`
+	DataFormat   string `json:"data_format"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	DBName       string `json:"db_name"`
+	Fingerprint  string `json:"fingerprint"`
+}
+
+// ExecutionSnapshot is the immutable record of everything an execution ran with.
+type ExecutionSnapshot struct {
+	ExecutionID           string                 `json:"execution_id" db:"execution_id"`
+	TenantID              string                 `json:"tenant_id" db:"tenant_id"`
+	JobDefinitionID       string                 `json:"job_definition_id" db:"job_definition_id"`
+	ASTHash               string                 `json:"ast_hash" db:"ast_hash"`
+	AST                   json.RawMessage        `json:"ast" db:"ast"`
+	EngineImage           string                 `json:"engine_image" db:"engine_image"`
+	EngineImageDigest     *string                `json:"engine_image_digest" db:"engine_image_digest"`
+	SourceConnection      ConnectionFingerprint  `json:"source_connection" db:"source_connection"`
+	DestinationConnection ConnectionFingerprint  `json:"destination_connection" db:"destination_connection"`
+	Parameters            map[string]interface{} `json:"parameters" db:"parameters"`
+	ContainerCPULimit     int64                  `json:"container_cpu_limit" db:"container_cpu_limit"`
+	ContainerMemoryLimit  int64                  `json:"container_memory_limit" db:"container_memory_limit"`
+	APIVersion            string                 `json:"api_version" db:"api_version"`
+	CreatedAt             time.Time              `json:"created_at" db:"created_at"`
+}
+
+// ExecutionCheckpoint is the engine's last reported progress through one table.
+type ExecutionCheckpoint struct {
+	ExecutionID   string          `json:"execution_id" db:"execution_id"`
+	Table         string          `json:"table" db:"table_name"`
+	LastKey       json.RawMessage `json:"last_key" db:"last_key"`
+	RowsProcessed int64           `json:"rows_processed" db:"rows_processed"`
+	Completed     bool            `json:"completed" db:"completed"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// DefinitionRevalidation is a queued re-check of a READY definition after one of its
+// connections changed.
+type DefinitionRevalidation struct {
+	JobDefinitionID string    `json:"job_definition_id" db:"job_definition_id"`
+	TenantID        string    `json:"tenant_id" db:"tenant_id"`
+	ConnectionID    *string   `json:"connection_id,omitempty" db:"connection_id"`
+	EnqueuedAt      time.Time `json:"enqueued_at" db:"enqueued_at"`
+	Attempts        int       `json:"attempts" db:"attempts"`
+	LastError       *string   `json:"last_error,omitempty" db:"last_error"`
+}
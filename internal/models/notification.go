@@ -19,17 +19,64 @@ const (
 	NotificationEventExecutionStarted   NotificationEvent = "execution_started"
 	NotificationEventExecutionSucceeded NotificationEvent = "execution_succeeded"
 	NotificationEventExecutionFailed    NotificationEvent = "execution_failed"
+	NotificationEventExecutionRegressed NotificationEvent = "execution_regressed"
+	NotificationEventExecutionSuspect   NotificationEvent = "execution_suspect"
+	NotificationEventExecutionPaused    NotificationEvent = "execution_paused"
+	NotificationEventExecutionResumed   NotificationEvent = "execution_resumed"
+	NotificationEventRunGroupCompleted  NotificationEvent = "run_group_completed"
 	NotificationEventValidationComplete NotificationEvent = "validation_complete"
+	NotificationEventValidationFailed   NotificationEvent = "validation_failed"
+	NotificationEventValidationStale    NotificationEvent = "validation_stale"
+	NotificationEventLatencyBudget      NotificationEvent = "latency_budget_exceeded"
 )
 
 type Notification struct {
-	ID        string               `json:"id" db:"id"`
-	TenantID  *string              `json:"tenant_id,omitempty" db:"tenant_id"`
-	EventType NotificationEvent    `json:"event_type" db:"event_type"`
-	Severity  NotificationSeverity `json:"severity" db:"severity"`
-	Title     string               `json:"title" db:"title"`
-	Message   string               `json:"message" db:"message"`
-	Metadata  json.RawMessage      `json:"metadata,omitempty" db:"metadata"`
-	CreatedAt time.Time            `json:"created_at" db:"created_at"`
-	ReadAt    *time.Time           `json:"read_at,omitempty" db:"read_at"`
+	ID             string               `json:"id" db:"id"`
+	TenantID       *string              `json:"tenant_id,omitempty" db:"tenant_id"`
+	CorrelationKey string               `json:"correlation_key,omitempty" db:"correlation_key"` // execution ID of execution events
+	EventType      NotificationEvent    `json:"event_type" db:"event_type"`
+	Severity       NotificationSeverity `json:"severity" db:"severity"`
+	Title          string               `json:"title" db:"title"`
+	Message        string               `json:"message" db:"message"`
+	Metadata       json.RawMessage      `json:"metadata,omitempty" db:"metadata"`
+	CreatedAt      time.Time            `json:"created_at" db:"created_at"`
+	ReadAt         *time.Time           `json:"read_at,omitempty" db:"read_at"`
+}
+
+// NotificationGroup collapses the notifications sharing a correlation key into
+// the latest of them. A notification without a key is a group of its own, with
+// an empty CorrelationKey. Status is the execution status the group's latest
+// execution lifecycle event implies, empty when it has none.
+type NotificationGroup struct {
+	CorrelationKey string       `json:"correlation_key,omitempty"`
+	Latest         Notification `json:"latest"`
+	Status         string       `json:"status,omitempty"`
+	Count          int          `json:"count"`
+	UnreadCount    int          `json:"unread_count"`
+	FirstAt        time.Time    `json:"first_at"`
+}
+
+// notificationStatuses maps execution lifecycle events to the execution status
+// they leave behind.
+var notificationStatuses = map[NotificationEvent]ExecutionStatus{
+	NotificationEventExecutionStarted:   ExecutionStatusRunning,
+	NotificationEventExecutionResumed:   ExecutionStatusRunning,
+	NotificationEventExecutionPaused:    ExecutionStatusPaused,
+	NotificationEventExecutionSucceeded: ExecutionStatusSucceeded,
+	NotificationEventExecutionFailed:    ExecutionStatusFailed,
+}
+
+// ExecutionLifecycleEvents are the events that change an execution's status.
+func ExecutionLifecycleEvents() []string {
+	events := make([]string, 0, len(notificationStatuses))
+	for event := range notificationStatuses {
+		events = append(events, string(event))
+	}
+	return events
+}
+
+// StatusAfter returns the execution status event leaves behind, or "" for an
+// event that does not change it.
+func StatusAfter(event NotificationEvent) string {
+	return string(notificationStatuses[event])
 }
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Ways of authenticating to a container registry.
+const (
+	// RegistryAuthBasic logs in with a stored username and password or token.
+	RegistryAuthBasic = "basic"
+	// RegistryAuthHelper runs a Docker credential helper, such as ecr-login, for
+	// short-lived credentials that are refreshed before they expire.
+	RegistryAuthHelper = "helper"
+)
+
+// DockerHubRegistry is the registry of image references that name none.
+const DockerHubRegistry = "docker.io"
+
+var (
+	registryHostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]+)?$`)
+	helperNamePattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+)
+
+// RegistryCredential is how engine images are pulled from one private registry.
+// The password of basic credentials is stored encrypted and never returned.
+type RegistryCredential struct {
+	ID string `json:"id"`
+	// Registry is the registry host, e.g. "123456789012.dkr.ecr.eu-west-1.amazonaws.com".
+	Registry string  `json:"registry"`
+	AuthType string  `json:"auth_type"`
+	Username *string `json:"username,omitempty"`
+	// Helper names the credential helper binary without its "docker-credential-"
+	// prefix.
+	Helper      *string   `json:"helper,omitempty"`
+	HasPassword bool      `json:"has_password"`
+	CreatedBy   *string   `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Normalize lowercases the registry and checks the fields of the auth type.
+// Basic credentials need a username; helper credentials need a helper name.
+func (c *RegistryCredential) Normalize() error {
+	c.Registry = strings.ToLower(strings.TrimSpace(c.Registry))
+	c.Registry = strings.TrimPrefix(strings.TrimPrefix(c.Registry, "https://"), "http://")
+	c.Registry = strings.TrimSuffix(c.Registry, "/")
+	if c.Registry == "index.docker.io" || c.Registry == "registry-1.docker.io" {
+		c.Registry = DockerHubRegistry
+	}
+	if !registryHostPattern.MatchString(c.Registry) {
+		return errors.New("registry must be a registry host, optionally with a port")
+	}
+	switch c.AuthType {
+	case RegistryAuthBasic:
+		if c.Username == nil || strings.TrimSpace(*c.Username) == "" {
+			return errors.New("username is required for basic registry credentials")
+		}
+		c.Helper = nil
+	case RegistryAuthHelper:
+		if c.Helper == nil {
+			return errors.New("helper is required for helper registry credentials")
+		}
+		helper := strings.TrimPrefix(strings.TrimSpace(*c.Helper), "docker-credential-")
+		if !helperNamePattern.MatchString(helper) {
+			return errors.New("helper must name a docker-credential helper, e.g. ecr-login")
+		}
+		c.Helper, c.Username = &helper, nil
+	default:
+		return errors.New("auth_type must be basic or helper")
+	}
+	return nil
+}
+
+// ImageRegistry returns the registry host of an image reference. References
+// without one, such as "postgres:16" or "stratum/engine", come from Docker Hub.
+func ImageRegistry(ref string) string {
+	first, _, found := strings.Cut(ref, "/")
+	if !found {
+		return DockerHubRegistry
+	}
+	if first == "localhost" || strings.ContainsAny(first, ".:") {
+		return strings.ToLower(first)
+	}
+	return DockerHubRegistry
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Metrics an execution is compared against its definition's baseline on.
+const (
+	RegressionMetricDuration   = "duration"
+	RegressionMetricThroughput = "throughput"
+)
+
+// ExecutionBaseline is the typical performance of a definition: the medians over its
+// most recent succeeded executions. MedianThroughput is in records per second and nil
+// when none of those runs reported records.
+type ExecutionBaseline struct {
+	Runs                  int      `json:"runs"`
+	MedianDurationSeconds float64  `json:"median_duration_seconds"`
+	MedianThroughput      *float64 `json:"median_throughput,omitempty"`
+}
+
+// ExecutionRegression records a succeeded execution that was slower than its
+// definition's baseline by more than the configured threshold. Deviation is the
+// relative change from the baseline, e.g. 0.8 for a run that took 80% longer or
+// -0.5 for one that moved half as many records per second.
+type ExecutionRegression struct {
+	ID                string    `json:"id"`
+	TenantID          string    `json:"tenant_id"`
+	JobDefinitionID   string    `json:"job_definition_id"`
+	ExecutionID       string    `json:"execution_id"`
+	Metric            string    `json:"metric"`
+	BaselineValue     float64   `json:"baseline_value"`
+	ObservedValue     float64   `json:"observed_value"`
+	Deviation         float64   `json:"deviation"`
+	BaselineRuns      int       `json:"baseline_runs"`
+	EngineImageDigest *string   `json:"engine_image_digest,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// DetectRegressions compares a succeeded execution against the baseline. Duration
+// regresses when it grows by more than threshold, throughput when it drops by more
+// than threshold. Executions without start and completion times are not compared.
+func DetectRegressions(baseline ExecutionBaseline, exec JobExecution, threshold float64) []ExecutionRegression {
+	if exec.RunStartedAt == nil || exec.RunCompletedAt == nil {
+		return nil
+	}
+	duration := exec.RunCompletedAt.Sub(*exec.RunStartedAt).Seconds()
+	if duration <= 0 {
+		return nil
+	}
+
+	regression := func(metric string, base, observed float64) ExecutionRegression {
+		return ExecutionRegression{
+			TenantID:        exec.TenantID,
+			JobDefinitionID: exec.JobDefinitionID,
+			ExecutionID:     exec.ID,
+			Metric:          metric,
+			BaselineValue:   base,
+			ObservedValue:   observed,
+			Deviation:       (observed - base) / base,
+			BaselineRuns:    baseline.Runs,
+		}
+	}
+
+	var found []ExecutionRegression
+	if base := baseline.MedianDurationSeconds; base > 0 && duration > base*(1+threshold) {
+		found = append(found, regression(RegressionMetricDuration, base, duration))
+	}
+	if base := baseline.MedianThroughput; base != nil && *base > 0 && exec.RecordsProcessed != nil && *exec.RecordsProcessed > 0 {
+		throughput := float64(*exec.RecordsProcessed) / duration
+		if throughput < *base*(1-threshold) {
+			found = append(found, regression(RegressionMetricThroughput, *base, throughput))
+		}
+	}
+	return found
+}
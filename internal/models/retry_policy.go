@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Bounds of a definition's retry policy.
+const (
+	MaxRetryAttempts        = 10
+	MaxRetryIntervalSeconds = 3600
+	MaxRetryBackoff         = 10
+)
+
+// RetryPolicy controls how often the engine run of a definition is retried after a
+// transient failure, such as a lost container or a dropped network connection.
+// Fields left at zero keep the worker's defaults.
+type RetryPolicy struct {
+	// MaxAttempts counts the first run; 1 disables retries.
+	MaxAttempts            int     `json:"max_attempts"`
+	InitialIntervalSeconds int     `json:"initial_interval_seconds,omitempty"`
+	BackoffCoefficient     float64 `json:"backoff_coefficient,omitempty"`
+	MaxIntervalSeconds     int     `json:"max_interval_seconds,omitempty"`
+}
+
+// IsZero reports whether the policy sets nothing, leaving every default in place.
+func (p RetryPolicy) IsZero() bool {
+	return p == RetryPolicy{}
+}
+
+// InitialInterval returns the delay before the first retry, or zero for the default.
+func (p RetryPolicy) InitialInterval() time.Duration {
+	return time.Duration(p.InitialIntervalSeconds) * time.Second
+}
+
+// MaxInterval returns the cap on the delay between retries, or zero for the default.
+func (p RetryPolicy) MaxInterval() time.Duration {
+	return time.Duration(p.MaxIntervalSeconds) * time.Second
+}
+
+// Validate checks the policy against the allowed bounds.
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("max_attempts must be between 1 and %d", MaxRetryAttempts)
+	}
+	if p.InitialIntervalSeconds < 0 || p.InitialIntervalSeconds > MaxRetryIntervalSeconds {
+		return fmt.Errorf("initial_interval_seconds must be between 1 and %d", MaxRetryIntervalSeconds)
+	}
+	if p.MaxIntervalSeconds < 0 || p.MaxIntervalSeconds > MaxRetryIntervalSeconds {
+		return fmt.Errorf("max_interval_seconds must be between 1 and %d", MaxRetryIntervalSeconds)
+	}
+	if p.BackoffCoefficient != 0 && (p.BackoffCoefficient < 1 || p.BackoffCoefficient > MaxRetryBackoff) {
+		return fmt.Errorf("backoff_coefficient must be between 1 and %d", MaxRetryBackoff)
+	}
+	if p.MaxIntervalSeconds > 0 && p.MaxIntervalSeconds < p.InitialIntervalSeconds {
+		return errors.New("max_interval_seconds must not be shorter than initial_interval_seconds")
+	}
+	return nil
+}
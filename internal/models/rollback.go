@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ArtifactKindRollback is the report of a rollback execution: what it undid in the
+// destination of the execution it rolled back.
+const ArtifactKindRollback = "rollback"
+
+// What the engine did to a destination object when rolling back an execution.
+const (
+	// RollbackActionDropped objects were created by the execution and removed.
+	RollbackActionDropped = "dropped"
+	// RollbackActionRestored objects existed before the execution and were put
+	// back the way the execution found them.
+	RollbackActionRestored = "restored"
+	// RollbackActionSkipped objects were left alone, such as tables the execution
+	// never reached.
+	RollbackActionSkipped = "skipped"
+)
+
+// RolledBackObject is one destination object a rollback acted on.
+type RolledBackObject struct {
+	Object string `json:"object"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RollbackReport records how a rollback execution undid what another execution
+// wrote to its destination. Error is set when the engine could not roll back at
+// all; Failed lists the objects it could not roll back.
+type RollbackReport struct {
+	RolledBackExecutionID string             `json:"rolled_back_execution_id"`
+	CapturedAt            time.Time          `json:"captured_at"`
+	Objects               []RolledBackObject `json:"objects"`
+	Failed                []string           `json:"failed,omitempty"`
+	Error                 string             `json:"error,omitempty"`
+}
+
+// Evaluate fills in Failed from the objects.
+func (r *RollbackReport) Evaluate() {
+	r.Failed = nil
+	for _, o := range r.Objects {
+		if o.Error != "" {
+			r.Failed = append(r.Failed, o.Object)
+		}
+	}
+}
+
+// Problem describes why the rollback is incomplete, or returns "" when every
+// object was rolled back.
+func (r RollbackReport) Problem() string {
+	if r.Error != "" {
+		return fmt.Sprintf("rollback could not run: %s", r.Error)
+	}
+	if len(r.Failed) > 0 {
+		return "could not roll back: " + strings.Join(r.Failed, ", ")
+	}
+	return ""
+}
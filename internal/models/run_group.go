@@ -0,0 +1,160 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Run group statuses.
+const (
+	RunGroupStatusRunning   = "running"
+	RunGroupStatusSucceeded = "succeeded"
+	RunGroupStatusFailed    = "failed"
+)
+
+// Statuses of run group members that have no execution: pending members wait for a
+// free slot, skipped ones were never started because an earlier member failed.
+const (
+	RunGroupMemberPending = "pending"
+	RunGroupMemberSkipped = "skipped"
+)
+
+// MaxRunGroupMembers bounds the definitions of one run group.
+const MaxRunGroupMembers = 50
+
+// RunGroup runs related job definitions together, at most Parallelism at a time,
+// and reports on them as one. With StopOnFailure, members not started yet are
+// skipped once a member fails.
+type RunGroup struct {
+	ID            string           `json:"id"`
+	TenantID      string           `json:"tenant_id"`
+	Name          string           `json:"name"`
+	Parallelism   int              `json:"parallelism"`
+	StopOnFailure bool             `json:"stop_on_failure"`
+	Status        string           `json:"status"`
+	CreatedBy     *string          `json:"created_by,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	CompletedAt   *time.Time       `json:"completed_at,omitempty"`
+	Members       []RunGroupMember `json:"members"`
+	Progress      RunGroupProgress `json:"progress"`
+}
+
+// RunGroupMember is one definition of a run group and the execution it runs as.
+// Status is the execution's status once it started, and RunGroupMemberPending or
+// RunGroupMemberSkipped before.
+type RunGroupMember struct {
+	Position          int        `json:"position"`
+	JobDefinitionID   string     `json:"job_definition_id"`
+	JobDefinitionName string     `json:"job_definition_name,omitempty"`
+	ExecutionID       string     `json:"execution_id"`
+	Status            string     `json:"status"`
+	ErrorMessage      *string    `json:"error_message,omitempty"`
+	RecordsProcessed  *int64     `json:"records_processed,omitempty"`
+	BytesTransferred  *int64     `json:"bytes_transferred,omitempty"`
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+// RunGroupProgress counts the members of a run group by outcome.
+type RunGroupProgress struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// Normalize checks the group's settings and members. Parallelism defaults to one
+// member at a time and is capped at the number of members.
+func (g *RunGroup) Normalize() error {
+	if len(g.Members) == 0 {
+		return errors.New("at least one member definition is required")
+	}
+	if len(g.Members) > MaxRunGroupMembers {
+		return fmt.Errorf("a run group has at most %d members", MaxRunGroupMembers)
+	}
+	seen := make(map[string]bool, len(g.Members))
+	for i := range g.Members {
+		id := g.Members[i].JobDefinitionID
+		if id == "" {
+			return errors.New("every member needs a job_definition_id")
+		}
+		if seen[id] {
+			return fmt.Errorf("job definition %s is listed more than once", id)
+		}
+		seen[id] = true
+		g.Members[i].Position = i
+	}
+	if g.Parallelism < 0 {
+		return errors.New("parallelism must not be negative")
+	}
+	if g.Parallelism == 0 {
+		g.Parallelism = 1
+	}
+	g.Parallelism = min(g.Parallelism, len(g.Members))
+	return nil
+}
+
+// Summarize fills Progress from the members.
+func (g *RunGroup) Summarize() {
+	p := RunGroupProgress{Total: len(g.Members)}
+	for _, m := range g.Members {
+		switch {
+		case m.Status == RunGroupMemberPending || m.Status == string(ExecutionStatusAwaitingApproval):
+			p.Pending++
+		case m.Status == RunGroupMemberSkipped || m.Status == string(ExecutionStatusSkipped):
+			p.Skipped++
+		case m.Status == string(ExecutionStatusSucceeded):
+			p.Succeeded++
+		case ExecutionStatus(m.Status).Active():
+			p.Running++
+		default:
+			p.Failed++
+		}
+	}
+	g.Progress = p
+}
+
+// RunGroupReport is the consolidated report of a finished run group.
+type RunGroupReport struct {
+	GroupID          string           `json:"group_id"`
+	Name             string           `json:"name"`
+	Status           string           `json:"status"`
+	StartedAt        time.Time        `json:"started_at"`
+	CompletedAt      time.Time        `json:"completed_at"`
+	DurationSeconds  float64          `json:"duration_seconds"`
+	Progress         RunGroupProgress `json:"progress"`
+	RecordsProcessed int64            `json:"records_processed"`
+	BytesTransferred int64            `json:"bytes_transferred"`
+	Members          []RunGroupMember `json:"members"`
+}
+
+// NewRunGroupReport reports on a group whose members all finished at completedAt.
+// The group succeeded when every member did.
+func NewRunGroupReport(g RunGroup, completedAt time.Time) RunGroupReport {
+	g.Summarize()
+	report := RunGroupReport{
+		GroupID:         g.ID,
+		Name:            g.Name,
+		Status:          RunGroupStatusSucceeded,
+		StartedAt:       g.CreatedAt,
+		CompletedAt:     completedAt,
+		DurationSeconds: completedAt.Sub(g.CreatedAt).Seconds(),
+		Progress:        g.Progress,
+		Members:         g.Members,
+	}
+	if g.Progress.Succeeded != g.Progress.Total {
+		report.Status = RunGroupStatusFailed
+	}
+	for _, m := range g.Members {
+		if m.RecordsProcessed != nil {
+			report.RecordsProcessed += *m.RecordsProcessed
+		}
+		if m.BytesTransferred != nil {
+			report.BytesTransferred += *m.BytesTransferred
+		}
+	}
+	return report
+}
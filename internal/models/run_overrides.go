@@ -0,0 +1,136 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MaxRunOverridesBytes bounds the overrides a single run may carry; they travel in
+// the workflow input.
+const MaxRunOverridesBytes = 64 * 1024
+
+// reservedOverrideKeys are AST keys the API fills in when it prepares a run, so
+// overrides cannot set them.
+var reservedOverrideKeys = []string{"connections", "resume", "write_mode"}
+
+// runParameterKeys are the AST settings a run may vary without changing what the
+// definition migrates: table filters, batch size and target schema. They may be
+// set at the top of the AST or inside the objects in runParameterScopes.
+var runParameterKeys = map[string]bool{
+	"batch_size":    true,
+	"target_schema": true,
+	"filter":        true,
+	"filters":       true,
+}
+
+// runParameterScopes are the AST objects that hold run parameters.
+var runParameterScopes = map[string]bool{
+	"migrate":  true,
+	"settings": true,
+}
+
+// NonParameterOverrides returns the dotted paths of the overrides that set more
+// than a run parameter, such as the tables, queries or mappings a definition
+// migrates. Only editors may send those.
+func NonParameterOverrides(overrides json.RawMessage) []string {
+	doc, err := decodeAST(overrides)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	var walk func(prefix string, fields map[string]interface{})
+	walk = func(prefix string, fields map[string]interface{}) {
+		for key, value := range fields {
+			if runParameterKeys[key] {
+				continue
+			}
+			if nested, ok := value.(map[string]interface{}); ok && runParameterScopes[key] {
+				walk(prefix+key+".", nested)
+				continue
+			}
+			paths = append(paths, prefix+key)
+		}
+	}
+	if fields, ok := doc.(map[string]interface{}); ok {
+		walk("", fields)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ValidateRunOverrides checks the overrides of an ad-hoc run: a JSON object merged
+// into the definition's AST for that run only. Secrets may be referenced but not
+// given inline, since overrides are not stored encrypted. Per-table write modes
+// are validated like the definition's own.
+func ValidateRunOverrides(overrides json.RawMessage) error {
+	if len(overrides) > MaxRunOverridesBytes {
+		return fmt.Errorf("overrides must be at most %d bytes", MaxRunOverridesBytes)
+	}
+	doc, err := decodeAST(overrides)
+	if err != nil {
+		return fmt.Errorf("invalid overrides: %w", err)
+	}
+	fields, ok := doc.(map[string]interface{})
+	if !ok {
+		return errors.New("overrides must be a JSON object")
+	}
+	for _, key := range reservedOverrideKeys {
+		if _, set := fields[key]; set {
+			return fmt.Errorf("overrides cannot set %q", key)
+		}
+	}
+	inline := false
+	walkSecretRefs(doc, func(name string, ref map[string]interface{}) interface{} {
+		if _, ok := ref["value"]; ok {
+			inline = true
+		}
+		return ref
+	})
+	if inline {
+		return errors.New("overrides cannot carry secret values; reference a definition secret instead")
+	}
+	if _, err := TableWriteModes(overrides); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ApplyRunOverrides merges overrides into ast the way a JSON merge patch (RFC 7396)
+// does: objects are merged key by key, null removes a key and any other value
+// replaces what the AST had.
+func ApplyRunOverrides(ast json.RawMessage, overrides json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(overrides)) == 0 {
+		return ast, nil
+	}
+	base, err := decodeAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := decodeAST(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid overrides: %w", err)
+	}
+	return json.Marshal(mergePatch(base, patch))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = map[string]interface{}{}
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergePatch(merged[key], value)
+	}
+	return merged
+}
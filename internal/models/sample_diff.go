@@ -0,0 +1,174 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArtifactKindSampleDiff is the source/destination row sample comparison of an execution.
+const ArtifactKindSampleDiff = "sample_diff"
+
+// maxMismatchExamples caps the example values kept per mismatching column.
+const maxMismatchExamples = 3
+
+// SampledTable is the engine's sample of one table: random source rows and the
+// destination rows with the same key values.
+type SampledTable struct {
+	Table       string                   `json:"table"`
+	Key         []string                 `json:"key"`
+	Source      []map[string]interface{} `json:"source"`
+	Destination []map[string]interface{} `json:"destination"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// SampleReport is the engine's output for a row sampling request.
+type SampleReport struct {
+	Tables []SampledTable `json:"tables"`
+}
+
+// MismatchExample shows one differing value of a column.
+type MismatchExample struct {
+	Key         map[string]interface{} `json:"key"`
+	Source      interface{}            `json:"source"`
+	Destination interface{}            `json:"destination"`
+}
+
+// ColumnMismatch counts the sampled rows whose value of Column differs between
+// source and destination.
+type ColumnMismatch struct {
+	Column       string            `json:"column"`
+	Mismatches   int               `json:"mismatches"`
+	MismatchRate float64           `json:"mismatch_rate"`
+	Examples     []MismatchExample `json:"examples,omitempty"`
+}
+
+// TableSampleDiff summarises the comparison of one table's sampled rows.
+type TableSampleDiff struct {
+	Table          string           `json:"table"`
+	KeyColumns     []string         `json:"key_columns"`
+	SampledRows    int              `json:"sampled_rows"`
+	MatchedRows    int              `json:"matched_rows"`
+	MismatchedRows int              `json:"mismatched_rows"`
+	MissingRows    int              `json:"missing_rows"`
+	Columns        []ColumnMismatch `json:"columns"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// SampleDiffReport is the column-level comparison of random source rows against
+// their destination counterparts, stored as an execution artifact.
+type SampleDiffReport struct {
+	CapturedAt time.Time         `json:"captured_at"`
+	SampleSize int               `json:"sample_size"`
+	Tables     []TableSampleDiff `json:"tables"`
+}
+
+// CompareSampledTable matches destination rows to source rows by key and counts
+// per-column differences. Columns missing from the destination row count as
+// mismatches; columns only present in the destination are ignored.
+func CompareSampledTable(t SampledTable) TableSampleDiff {
+	diff := TableSampleDiff{Table: t.Table, KeyColumns: t.Key, SampledRows: len(t.Source), Columns: []ColumnMismatch{}, Error: t.Error}
+	if t.Error != "" {
+		return diff
+	}
+	if len(t.Key) == 0 {
+		diff.Error = "table has no key columns to match rows on"
+		return diff
+	}
+
+	dest := make(map[string]map[string]interface{}, len(t.Destination))
+	for _, row := range t.Destination {
+		dest[rowKey(t.Key, row)] = row
+	}
+
+	byColumn := map[string]*ColumnMismatch{}
+	for _, src := range t.Source {
+		dst, ok := dest[rowKey(t.Key, src)]
+		if !ok {
+			diff.MissingRows++
+			continue
+		}
+		rowMismatched := false
+		for col, srcVal := range src {
+			dstVal, present := dst[col]
+			if present && valuesEqual(srcVal, dstVal) {
+				continue
+			}
+			rowMismatched = true
+			cm, ok := byColumn[col]
+			if !ok {
+				cm = &ColumnMismatch{Column: col}
+				byColumn[col] = cm
+			}
+			cm.Mismatches++
+			if len(cm.Examples) < maxMismatchExamples {
+				key := make(map[string]interface{}, len(t.Key))
+				for _, k := range t.Key {
+					key[k] = src[k]
+				}
+				cm.Examples = append(cm.Examples, MismatchExample{Key: key, Source: srcVal, Destination: dstVal})
+			}
+		}
+		if rowMismatched {
+			diff.MismatchedRows++
+		} else {
+			diff.MatchedRows++
+		}
+	}
+
+	compared := diff.SampledRows - diff.MissingRows
+	for _, cm := range byColumn {
+		if compared > 0 {
+			cm.MismatchRate = float64(cm.Mismatches) / float64(compared)
+		}
+		diff.Columns = append(diff.Columns, *cm)
+	}
+	sort.Slice(diff.Columns, func(i, j int) bool {
+		if diff.Columns[i].Mismatches != diff.Columns[j].Mismatches {
+			return diff.Columns[i].Mismatches > diff.Columns[j].Mismatches
+		}
+		return diff.Columns[i].Column < diff.Columns[j].Column
+	})
+	return diff
+}
+
+func rowKey(cols []string, row map[string]interface{}) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		parts[i] = canonicalValue(row[c])
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// valuesEqual compares decoded JSON values, treating numbers by value so 1 and 1.0
+// from different drivers compare equal.
+func valuesEqual(a, b interface{}) bool {
+	return canonicalValue(a) == canonicalValue(b)
+}
+
+func canonicalValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case json.Number:
+		if f, ok := new(big.Float).SetString(x.String()); ok {
+			return "n:" + f.Text('g', -1)
+		}
+		return "n:" + x.String()
+	case float64:
+		return "n:" + new(big.Float).SetFloat64(x).Text('g', -1)
+	case string:
+		return "s:" + x
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		if err := enc.Encode(x); err != nil {
+			return fmt.Sprintf("%v", x)
+		}
+		return strings.TrimSpace(buf.String())
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// JobSchedule runs a job definition on a cron expression, evaluated in Timezone.
+// ScheduleID names the Temporal Schedule that starts the runs.
+type JobSchedule struct {
+	JobDefinitionID   string    `json:"job_definition_id"`
+	JobDefinitionName string    `json:"job_definition_name,omitempty"`
+	TenantID          string    `json:"tenant_id"`
+	CronExpression    string    `json:"cron_expression"`
+	Timezone          string    `json:"timezone"`
+	ScheduleID        string    `json:"schedule_id"`
+	CreatedBy         *string   `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// PausedAt is set while the schedule is paused for the credential rotation of
+	// PausedForConnectionID.
+	PausedAt              *time.Time `json:"paused_at,omitempty"`
+	PausedForConnectionID *string    `json:"paused_for_connection_id,omitempty"`
+	// NextRuns lists the upcoming run times; it is only filled for schedule details.
+	NextRuns []time.Time `json:"next_runs,omitempty"`
+}
+
+// Normalize trims the schedule, defaults the timezone to UTC and validates the
+// cron expression (five fields or a descriptor such as @daily).
+func (s *JobSchedule) Normalize() error {
+	s.CronExpression = strings.Join(strings.Fields(s.CronExpression), " ")
+	s.Timezone = strings.TrimSpace(s.Timezone)
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if s.CronExpression == "" {
+		return errors.New("cron_expression is required")
+	}
+	if _, err := cron.ParseStandard(s.CronExpression); err != nil {
+		return fmt.Errorf("invalid cron_expression: %w", err)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %s", s.Timezone)
+	}
+	return nil
+}
+
+// Occurrences returns up to limit run times after from and before to.
+func (s JobSchedule) Occurrences(from, to time.Time, limit int) []time.Time {
+	sched, err := cron.ParseStandard(s.CronExpression)
+	if err != nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil
+	}
+	var out []time.Time
+	for t := sched.Next(from.In(loc)); !t.IsZero() && t.Before(to) && len(out) < limit; t = sched.Next(t) {
+		out = append(out, t)
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ValidationFreshness is how a tenant treats READY definitions whose source schema
+// changed since they were validated.
+type ValidationFreshness string
+
+const (
+	// ValidationFreshnessOff skips schema drift checks.
+	ValidationFreshnessOff ValidationFreshness = "off"
+	// ValidationFreshnessWarn flags drifted definitions and warns before they run.
+	ValidationFreshnessWarn ValidationFreshness = "warn"
+	// ValidationFreshnessBlock also refuses to run them until they are revalidated.
+	ValidationFreshnessBlock ValidationFreshness = "block"
+)
+
+// ParseValidationFreshness reads a freshness setting, case-insensitively.
+func ParseValidationFreshness(s string) (ValidationFreshness, error) {
+	switch f := ValidationFreshness(strings.ToLower(strings.TrimSpace(s))); f {
+	case ValidationFreshnessOff, ValidationFreshnessWarn, ValidationFreshnessBlock:
+		return f, nil
+	}
+	return "", fmt.Errorf("validation_freshness must be %s, %s or %s", ValidationFreshnessOff, ValidationFreshnessWarn, ValidationFreshnessBlock)
+}
+
+// StaleValidationMessage explains a stale_validation flag.
+const StaleValidationMessage = "Stale validation: the source schema changed since the definition was validated"
+
+// StaleValidationWarnings returns the warning of a READY definition flagged with
+// schema drift.
+func (d JobDefinition) StaleValidationWarnings() []string {
+	if d.Status != DefinitionStatusReady || !d.StaleValidation {
+		return nil
+	}
+	return []string{StaleValidationMessage}
+}
+
+// DefinitionSchemaCheck is the last comparison of a READY definition's source
+// schema with the one recorded when it was first checked after becoming READY.
+// A check belongs to the definition as it was at DefinitionUpdatedAt; any later
+// edit or status change starts over with a new baseline.
+type DefinitionSchemaCheck struct {
+	JobDefinitionID     string
+	TenantID            string
+	DefinitionUpdatedAt time.Time
+	SchemaHash          string
+	Stale               bool
+	StaleSince          *time.Time
+	CheckedAt           time.Time
+}
+
+// SourceSchemaHash fingerprints the engine's source metadata. The JSON is
+// re-encoded first so that key order and whitespace do not count as drift.
+func SourceSchemaHash(metadata []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(metadata, &v); err != nil {
+		return "", fmt.Errorf("decode source metadata: %w", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SCIMToken is the bearer token a tenant's identity provider provisions users with
+// over SCIM. Each tenant has at most one.
+type SCIMToken struct {
+	TenantID    string     `json:"tenant_id"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"-"`
+	CreatedBy   *string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SecretRefKey marks an AST object as a reference to a definition secret, e.g.
+// {"$secret": "api_token"}. On create/update the object may carry the plain value as
+// {"$secret": "api_token", "value": "..."}; the value is moved to encrypted storage
+// and only the reference is kept in the stored AST.
+const SecretRefKey = "$secret"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// DefinitionSecret describes a stored secret without its value.
+type DefinitionSecret struct {
+	JobDefinitionID string    `json:"job_definition_id" db:"job_definition_id"`
+	Name            string    `json:"name" db:"name"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ValidateSecretName checks that name can be used as a secret reference.
+func ValidateSecretName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: use 1-128 letters, digits, '_', '.' or '-'", name)
+	}
+	return nil
+}
+
+// ExtractASTSecrets strips inline secret values from ast and returns the redacted AST
+// together with the extracted name/value pairs.
+func ExtractASTSecrets(ast json.RawMessage) (json.RawMessage, map[string]string, error) {
+	if len(bytes.TrimSpace(ast)) == 0 {
+		return ast, nil, nil
+	}
+	doc, err := decodeAST(ast)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets := map[string]string{}
+	var walkErr error
+	doc = walkSecretRefs(doc, func(name string, ref map[string]interface{}) interface{} {
+		if err := ValidateSecretName(name); err != nil && walkErr == nil {
+			walkErr = err
+		}
+		if value, ok := ref["value"]; ok {
+			str, isString := value.(string)
+			if !isString && walkErr == nil {
+				walkErr = fmt.Errorf("secret %q value must be a string", name)
+			}
+			secrets[name] = str
+		}
+		return map[string]interface{}{SecretRefKey: name}
+	})
+	if walkErr != nil {
+		return nil, nil, walkErr
+	}
+	if len(secrets) == 0 {
+		return ast, nil, nil
+	}
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return redacted, secrets, nil
+}
+
+// ASTSecretRefs lists the secret names referenced by ast, sorted.
+func ASTSecretRefs(ast json.RawMessage) ([]string, error) {
+	if len(bytes.TrimSpace(ast)) == 0 {
+		return nil, nil
+	}
+	doc, err := decodeAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	walkSecretRefs(doc, func(name string, ref map[string]interface{}) interface{} {
+		seen[name] = struct{}{}
+		return ref
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ResolveASTSecrets replaces every secret reference in a decoded AST with its plain
+// value. It fails if a referenced secret has no value.
+func ResolveASTSecrets(ast interface{}, values map[string]string) (interface{}, error) {
+	var missing []string
+	resolved := walkSecretRefs(ast, func(name string, ref map[string]interface{}) interface{} {
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+			return ref
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing values for secrets: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+func decodeAST(ast json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(ast))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid AST: %w", err)
+	}
+	return doc, nil
+}
+
+// walkSecretRefs rebuilds node, replacing each secret reference object with the
+// result of fn.
+func walkSecretRefs(node interface{}, fn func(name string, ref map[string]interface{}) interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if name, ok := v[SecretRefKey].(string); ok {
+			return fn(name, v)
+		}
+		for key, child := range v {
+			v[key] = walkSecretRefs(child, fn)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = walkSecretRefs(child, fn)
+		}
+		return v
+	default:
+		return node
+	}
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sensor kinds.
+const (
+	SensorKindRowCount = "row_count"
+	SensorKindFile     = "file"
+	SensorKindHTTP     = "http"
+)
+
+// What a run does when one of its sensors has not passed within its timeout.
+const (
+	SensorTimeoutSkip = "skip"
+	SensorTimeoutFail = "fail"
+	SensorTimeoutRun  = "run"
+)
+
+// Bounds of a sensor's polling. A run waits at most MaxSensorTimeout for a sensor,
+// probing it no more often than every MinSensorPokeInterval.
+const (
+	MinSensorPokeInterval = 30 * time.Second
+	MaxSensorTimeout      = 24 * time.Hour
+)
+
+// JobSensor is a readiness probe a definition's runs wait on before they start.
+// Config holds the kind's settings: RowCountSensorConfig, FileSensorConfig or
+// HTTPSensorConfig.
+type JobSensor struct {
+	ID                  string          `json:"id"`
+	TenantID            string          `json:"tenant_id"`
+	JobDefinitionID     string          `json:"job_definition_id"`
+	Name                string          `json:"name"`
+	Kind                string          `json:"kind"`
+	Config              json.RawMessage `json:"config"`
+	PokeIntervalSeconds int             `json:"poke_interval_seconds"`
+	TimeoutSeconds      int             `json:"timeout_seconds"`
+	OnTimeout           string          `json:"on_timeout"`
+	Enabled             bool            `json:"enabled"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// RowCountSensorConfig passes once the table on the connection holds at least
+// MinRows rows and, with Changed, once its row count differs from the count seen
+// when the sensor last passed for an earlier run.
+type RowCountSensorConfig struct {
+	ConnectionID string `json:"connection_id"`
+	Table        string `json:"table"`
+	MinRows      int64  `json:"min_rows"`
+	Changed      bool   `json:"changed"`
+}
+
+// FileSensorConfig passes once a file matching the glob Path exists on the worker
+// and is at least MinSizeBytes long.
+type FileSensorConfig struct {
+	Path         string `json:"path"`
+	MinSizeBytes int64  `json:"min_size_bytes"`
+}
+
+// HTTPSensorConfig passes once a request to URL answers with ExpectedStatus
+// (200 by default).
+type HTTPSensorConfig struct {
+	URL            string `json:"url"`
+	Method         string `json:"method"`
+	ExpectedStatus int    `json:"expected_status"`
+}
+
+// SensorEvaluation is one probe of a sensor on behalf of an execution.
+// ObservedValue is what the probe measured: the row count, the file size or the
+// HTTP status.
+type SensorEvaluation struct {
+	ID              string    `json:"id"`
+	TenantID        string    `json:"tenant_id"`
+	SensorID        string    `json:"sensor_id"`
+	JobDefinitionID string    `json:"job_definition_id"`
+	ExecutionID     string    `json:"execution_id"`
+	Passed          bool      `json:"passed"`
+	ObservedValue   *int64    `json:"observed_value,omitempty"`
+	Detail          string    `json:"detail"`
+	EvaluatedAt     time.Time `json:"evaluated_at"`
+}
+
+// PokeInterval returns how long to wait between probes of the sensor.
+func (s JobSensor) PokeInterval() time.Duration {
+	return time.Duration(s.PokeIntervalSeconds) * time.Second
+}
+
+// Timeout returns how long a run waits for the sensor to pass.
+func (s JobSensor) Timeout() time.Duration {
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// Normalize fills in defaults and validates the sensor's settings and config.
+func (s *JobSensor) Normalize() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Kind = strings.ToLower(strings.TrimSpace(s.Kind))
+	s.OnTimeout = strings.ToLower(strings.TrimSpace(s.OnTimeout))
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if s.PokeIntervalSeconds == 0 {
+		s.PokeIntervalSeconds = 60
+	}
+	if s.TimeoutSeconds == 0 {
+		s.TimeoutSeconds = 3600
+	}
+	if s.OnTimeout == "" {
+		s.OnTimeout = SensorTimeoutFail
+	}
+	if s.PokeInterval() < MinSensorPokeInterval {
+		return fmt.Errorf("poke_interval_seconds must be at least %d", int(MinSensorPokeInterval.Seconds()))
+	}
+	if s.Timeout() > MaxSensorTimeout {
+		return fmt.Errorf("timeout_seconds must be at most %d", int(MaxSensorTimeout.Seconds()))
+	}
+	if s.TimeoutSeconds < s.PokeIntervalSeconds {
+		return errors.New("timeout_seconds must not be shorter than poke_interval_seconds")
+	}
+	switch s.OnTimeout {
+	case SensorTimeoutSkip, SensorTimeoutFail, SensorTimeoutRun:
+	default:
+		return errors.New("on_timeout must be skip, fail or run")
+	}
+
+	if len(s.Config) == 0 {
+		s.Config = json.RawMessage(`{}`)
+	}
+	switch s.Kind {
+	case SensorKindRowCount:
+		var cfg RowCountSensorConfig
+		if err := json.Unmarshal(s.Config, &cfg); err != nil {
+			return fmt.Errorf("invalid row_count config: %w", err)
+		}
+		if strings.TrimSpace(cfg.ConnectionID) == "" || strings.TrimSpace(cfg.Table) == "" {
+			return errors.New("row_count sensors need a connection_id and a table")
+		}
+		if cfg.MinRows < 0 {
+			return errors.New("min_rows must not be negative")
+		}
+	case SensorKindFile:
+		var cfg FileSensorConfig
+		if err := json.Unmarshal(s.Config, &cfg); err != nil {
+			return fmt.Errorf("invalid file config: %w", err)
+		}
+		if !filepath.IsAbs(cfg.Path) {
+			return errors.New("file sensors need an absolute path")
+		}
+		if _, err := filepath.Match(cfg.Path, ""); err != nil {
+			return fmt.Errorf("invalid path pattern: %w", err)
+		}
+	case SensorKindHTTP:
+		var cfg HTTPSensorConfig
+		if err := json.Unmarshal(s.Config, &cfg); err != nil {
+			return fmt.Errorf("invalid http config: %w", err)
+		}
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("http sensors need an absolute http(s) url")
+		}
+		switch strings.ToUpper(cfg.Method) {
+		case "", "GET", "HEAD":
+		default:
+			return errors.New("http sensor method must be GET or HEAD")
+		}
+		if cfg.ExpectedStatus != 0 && (cfg.ExpectedStatus < 100 || cfg.ExpectedStatus > 599) {
+			return errors.New("expected_status must be an HTTP status code")
+		}
+	default:
+		return errors.New("kind must be row_count, file or http")
+	}
+	return nil
+}
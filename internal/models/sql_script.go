@@ -0,0 +1,259 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Job types. Engine definitions run in the engine container; SQL script
+// definitions are executed by the worker itself and keep their SQLScriptSpec in
+// the AST.
+const (
+	JobTypeEngine    = "engine"
+	JobTypeSQLScript = "sql_script"
+)
+
+// ValidJobType reports whether t is a known job type.
+func ValidJobType(t string) bool {
+	return t == JobTypeEngine || t == JobTypeSQLScript
+}
+
+// SQL script modes.
+const (
+	// SQLScriptModeScript runs statements on the destination in one transaction.
+	SQLScriptModeScript = "script"
+	// SQLScriptModeCopy copies one source table into a destination table.
+	SQLScriptModeCopy = "copy"
+)
+
+// Limits of a SQL script run. Larger jobs belong in the engine.
+const (
+	DefaultSQLScriptTimeout = time.Minute
+	MaxSQLScriptTimeout     = 10 * time.Minute
+	DefaultSQLScriptMaxRows = 10000
+	MaxSQLScriptMaxRows     = 100000
+)
+
+// SQLScriptSpec is the AST of a sql_script definition. Script mode needs only the
+// destination connection; copy mode reads SourceTable from the source connection
+// into DestinationTable, which defaults to the same name.
+// A run fails, and changes nothing, once it would touch more than MaxRows rows or
+// take longer than TimeoutSeconds.
+type SQLScriptSpec struct {
+	Mode             string `json:"mode"`
+	Script           string `json:"script,omitempty"`
+	SourceTable      string `json:"source_table,omitempty"`
+	DestinationTable string `json:"destination_table,omitempty"`
+	// Truncate empties the destination table before a copy.
+	Truncate       bool  `json:"truncate,omitempty"`
+	TimeoutSeconds int   `json:"timeout_seconds"`
+	MaxRows        int64 `json:"max_rows"`
+}
+
+// ParseSQLScript decodes and normalizes the spec stored in a definition's AST.
+func ParseSQLScript(ast json.RawMessage) (SQLScriptSpec, error) {
+	var spec SQLScriptSpec
+	if len(ast) == 0 {
+		return spec, errors.New("sql script is required")
+	}
+	if err := json.Unmarshal(ast, &spec); err != nil {
+		return spec, fmt.Errorf("invalid sql script: %w", err)
+	}
+	return spec, spec.Normalize()
+}
+
+// Timeout returns how long a run may take.
+func (s SQLScriptSpec) Timeout() time.Duration {
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// NeedsSource reports whether the spec reads from the source connection.
+func (s SQLScriptSpec) NeedsSource() bool {
+	return s.Mode == SQLScriptModeCopy
+}
+
+// Normalize fills in defaults and validates the spec.
+func (s *SQLScriptSpec) Normalize() error {
+	s.Mode = strings.ToLower(strings.TrimSpace(s.Mode))
+	s.Script = strings.TrimSpace(s.Script)
+	s.SourceTable = strings.TrimSpace(s.SourceTable)
+	s.DestinationTable = strings.TrimSpace(s.DestinationTable)
+	if s.Mode == "" {
+		s.Mode = SQLScriptModeScript
+	}
+	if s.TimeoutSeconds == 0 {
+		s.TimeoutSeconds = int(DefaultSQLScriptTimeout.Seconds())
+	}
+	if s.MaxRows == 0 {
+		s.MaxRows = DefaultSQLScriptMaxRows
+	}
+	switch s.Mode {
+	case SQLScriptModeScript:
+		if s.Script == "" {
+			return errors.New("script is required")
+		}
+		if _, err := SQLScriptStatements(s.Script); err != nil {
+			return err
+		}
+	case SQLScriptModeCopy:
+		if s.SourceTable == "" {
+			return errors.New("source_table is required")
+		}
+		if s.DestinationTable == "" {
+			s.DestinationTable = s.SourceTable
+		}
+	default:
+		return fmt.Errorf("mode must be %s or %s", SQLScriptModeScript, SQLScriptModeCopy)
+	}
+	if s.TimeoutSeconds < 0 || s.Timeout() > MaxSQLScriptTimeout {
+		return fmt.Errorf("timeout_seconds must be between 1 and %d", int(MaxSQLScriptTimeout.Seconds()))
+	}
+	if s.MaxRows < 0 || s.MaxRows > MaxSQLScriptMaxRows {
+		return fmt.Errorf("max_rows must be between 1 and %d", MaxSQLScriptMaxRows)
+	}
+	return nil
+}
+
+// transactionControlKeywords start statements that would end or nest the
+// transaction a script runs in, letting its changes escape the rollback on failure.
+var transactionControlKeywords = map[string]bool{
+	"begin":     true,
+	"start":     true,
+	"commit":    true,
+	"end":       true,
+	"rollback":  true,
+	"abort":     true,
+	"savepoint": true,
+	"release":   true,
+	"prepare":   true,
+}
+
+// SQLScriptStatements splits a PostgreSQL script into its statements, so each can
+// be run and counted against MaxRows on its own. Semicolons inside quotes,
+// dollar-quoted bodies and comments do not split. Transaction control statements
+// are rejected: a script always runs in the one transaction the run provides.
+func SQLScriptStatements(script string) ([]string, error) {
+	var (
+		statements []string
+		start      int
+		words      strings.Builder
+	)
+	flush := func(end int) error {
+		stmt := strings.TrimSpace(script[start:end])
+		fields := strings.Fields(strings.ToLower(words.String()))
+		words.Reset()
+		start = end + 1
+		if len(fields) == 0 {
+			return nil
+		}
+		keyword := fields[0]
+		// PREPARE on its own prepares a statement; PREPARE TRANSACTION ends one.
+		if keyword == "prepare" && (len(fields) < 2 || fields[1] != "transaction") {
+			keyword = ""
+		}
+		if transactionControlKeywords[keyword] {
+			return fmt.Errorf("script cannot use transaction control statements (%s); it runs in a single transaction", strings.ToUpper(keyword))
+		}
+		statements = append(statements, stmt)
+		return nil
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+			words.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			depth := 0
+			for ; i < len(script); i++ {
+				if strings.HasPrefix(script[i:], "/*") {
+					depth++
+					i++
+				} else if strings.HasPrefix(script[i:], "*/") {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth > 0 {
+				return nil, errors.New("script has an unterminated comment")
+			}
+			words.WriteByte(' ')
+		case c == '\'' || c == '"':
+			// E'' strings escape quotes with backslashes as well as by doubling them.
+			escapes := c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') &&
+				(i == 1 || !isIdentifierByte(script[i-2]))
+			j := i + 1
+			for ; j < len(script); j++ {
+				if escapes && script[j] == '\\' {
+					j++
+					continue
+				}
+				if script[j] == c {
+					if j+1 < len(script) && script[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(script) {
+				return nil, errors.New("script has an unterminated quoted string")
+			}
+			words.WriteString(" x ")
+			i = j
+		case c == '$' && (i == 0 || !isIdentifierByte(script[i-1])):
+			tag, ok := dollarQuoteTag(script[i:])
+			if !ok {
+				words.WriteByte(c)
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				return nil, errors.New("script has an unterminated dollar-quoted string")
+			}
+			words.WriteString(" x ")
+			i += len(tag) + end + len(tag) - 1
+		case c == ';':
+			if err := flush(i); err != nil {
+				return nil, err
+			}
+		default:
+			words.WriteByte(c)
+		}
+	}
+	if err := flush(len(script)); err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("script has no statements")
+	}
+	return statements, nil
+}
+
+// dollarQuoteTag returns the $tag$ opening a dollar-quoted string at the start of s.
+func dollarQuoteTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,126 @@
+package models
+
+import "time"
+
+// Startup stages of an engine execution, in the order they happen. Each is
+// recorded on the execution when it completes.
+const (
+	StartupStageWorkflowStarted  = "workflow_started"
+	StartupStagePrepared         = "prepared"
+	StartupStageImagePulled      = "image_pulled"
+	StartupStageContainerStarted = "container_started"
+	StartupStageFirstLog         = "first_log"
+)
+
+// StartupStages lists the startup stages in order.
+var StartupStages = []string{
+	StartupStageWorkflowStarted,
+	StartupStagePrepared,
+	StartupStageImagePulled,
+	StartupStageContainerStarted,
+	StartupStageFirstLog,
+}
+
+// Bounds of the window startup latency is averaged over.
+const (
+	DefaultStartupLatencyDays = 7
+	MaxStartupLatencyDays     = 90
+)
+
+// StartupPhase is one recorded startup stage and how long it took since the
+// previous recorded stage.
+type StartupPhase struct {
+	Stage   string    `json:"stage"`
+	At      time.Time `json:"at"`
+	Seconds float64   `json:"seconds"`
+}
+
+// StartupBreakdown is how long an execution took from its workflow starting to the
+// engine's first log line, stage by stage.
+type StartupBreakdown struct {
+	Phases       []StartupPhase `json:"phases"`
+	TotalSeconds float64        `json:"total_seconds"`
+}
+
+// BreakdownStartup orders the recorded stage timestamps into phases. Stages that
+// were not recorded are skipped; nil is returned when none were.
+func BreakdownStartup(timings map[string]time.Time) *StartupBreakdown {
+	var (
+		breakdown StartupBreakdown
+		first     time.Time
+		prev      time.Time
+	)
+	for _, stage := range StartupStages {
+		at, ok := timings[stage]
+		if !ok {
+			continue
+		}
+		phase := StartupPhase{Stage: stage, At: at}
+		if prev.IsZero() {
+			first = at
+		} else {
+			phase.Seconds = at.Sub(prev).Seconds()
+		}
+		breakdown.Phases = append(breakdown.Phases, phase)
+		prev = at
+	}
+	if len(breakdown.Phases) == 0 {
+		return nil
+	}
+	breakdown.TotalSeconds = prev.Sub(first).Seconds()
+	return &breakdown
+}
+
+// StartupStageAverage is the average time executions spent reaching a stage from
+// the previous one.
+type StartupStageAverage struct {
+	Stage      string  `json:"stage"`
+	AvgSeconds float64 `json:"avg_seconds"`
+	Samples    int     `json:"samples"`
+}
+
+// StartupLatency aggregates the startup breakdowns of executions over a window.
+type StartupLatency struct {
+	Days            int                   `json:"days"`
+	Executions      int                   `json:"executions"`
+	Stages          []StartupStageAverage `json:"stages"`
+	AvgTotalSeconds float64               `json:"avg_total_seconds"`
+}
+
+// AverageStartup averages the per-stage durations of the given executions' stage
+// timestamps. The first stage has no duration of its own and is not averaged.
+func AverageStartup(days int, timings []map[string]time.Time) StartupLatency {
+	latency := StartupLatency{Days: days, Stages: []StartupStageAverage{}}
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	var total float64
+	for _, t := range timings {
+		breakdown := BreakdownStartup(t)
+		if breakdown == nil {
+			continue
+		}
+		latency.Executions++
+		total += breakdown.TotalSeconds
+		for i, phase := range breakdown.Phases {
+			if i == 0 {
+				continue
+			}
+			sums[phase.Stage] += phase.Seconds
+			counts[phase.Stage]++
+		}
+	}
+	for _, stage := range StartupStages[1:] {
+		if counts[stage] == 0 {
+			continue
+		}
+		latency.Stages = append(latency.Stages, StartupStageAverage{
+			Stage:      stage,
+			AvgSeconds: sums[stage] / float64(counts[stage]),
+			Samples:    counts[stage],
+		})
+	}
+	if latency.Executions > 0 {
+		latency.AvgTotalSeconds = total / float64(latency.Executions)
+	}
+	return latency
+}
@@ -8,7 +8,9 @@ type ExecutionStatDay struct {
 	Succeeded int       `json:"succeeded" db:"succeeded"`
 	Failed    int       `json:"failed" db:"failed"`
 	Running   int       `json:"running" db:"running"`
+	Paused    int       `json:"paused" db:"paused"`
 	Pending   int       `json:"pending" db:"pending"`
+	Cancelled int       `json:"cancelled" db:"cancelled"`
 }
 
 // ExecutionStat is the aggregated stats over a period, plus per-day details.
@@ -17,9 +19,37 @@ type ExecutionStat struct {
 	Succeeded        int                `json:"succeeded" db:"succeeded"`
 	Failed           int                `json:"failed" db:"failed"`
 	Running          int                `json:"running" db:"running"`
+	Paused           int                `json:"paused" db:"paused"`
 	SuccessRate      float64            `json:"success_rate" db:"success_rate"` // succeeded/total
 	TotalDefinitions int                `json:"total_definitions" db:"total_definitions"`
+	Timezone         string             `json:"timezone" db:"-"` // zone per_day is bucketed in
 	PerDay           []ExecutionStatDay `json:"per_day" db:"per_day"`
+	// PerTrigger counts the executions created over the per_day window by what
+	// triggered them; untracked runs count as "unknown".
+	PerTrigger map[string]int `json:"per_trigger" db:"-"`
+	// PerMonth is only filled when monthly history is requested.
+	PerMonth []ExecutionMonthStat `json:"per_month,omitempty" db:"-"`
+}
+
+// Sources of an ExecutionMonthStat.
+const (
+	MonthStatSourceRollup = "rollup"
+	MonthStatSourceRaw    = "raw"
+)
+
+// ExecutionMonthStat aggregates the executions created in one calendar month (UTC).
+// Settled months are served from the monthly rollups; recent months are computed
+// from the executions themselves.
+type ExecutionMonthStat struct {
+	Month              time.Time `json:"month"`
+	Executions         int64     `json:"executions"`
+	Succeeded          int64     `json:"succeeded"`
+	Failed             int64     `json:"failed"`
+	RecordsProcessed   int64     `json:"records_processed"`
+	BytesTransferred   int64     `json:"bytes_transferred"`
+	SuccessRate        float64   `json:"success_rate"`
+	AvgDurationSeconds *float64  `json:"avg_duration_seconds"`
+	Source             string    `json:"source"`
 }
 
 type JobDefinitionStat struct {
@@ -31,3 +61,18 @@ type JobDefinitionStat struct {
 	TotalBytesTransferred int64    `db:"total_bytes_transferred" json:"total_bytes_transferred"`
 	AvgDurationSeconds    *float64 `db:"avg_duration_seconds" json:"avg_duration_seconds"`
 }
+
+// ExecutionSeriesPoint aggregates executions of one definition within a time bucket.
+type ExecutionSeriesPoint struct {
+	Bucket             time.Time `json:"bucket"`
+	JobDefinitionID    string    `json:"job_definition_id"`
+	JobDefinitionName  string    `json:"job_definition_name"`
+	Total              int64     `json:"total"`
+	Succeeded          int64     `json:"succeeded"`
+	Failed             int64     `json:"failed"`
+	AvgDurationSeconds *float64  `json:"avg_duration_seconds"`
+	// TimedRuns counts the runs AvgDurationSeconds covers: those with both a start
+	// and a completion time.
+	TimedRuns        int64 `json:"timed_runs"`
+	BytesTransferred int64 `json:"bytes_transferred"`
+}
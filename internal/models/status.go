@@ -0,0 +1,125 @@
+package models
+
+// DefinitionStatus is the lifecycle state of a job definition.
+type DefinitionStatus string
+
+const (
+	DefinitionStatusDraft      DefinitionStatus = "DRAFT"
+	DefinitionStatusValidating DefinitionStatus = "VALIDATING"
+	DefinitionStatusReady      DefinitionStatus = "READY"
+	// DefinitionStatusArchived definitions are kept for audits but hidden from
+	// lists by default; they cannot be edited or run until unarchived.
+	DefinitionStatusArchived DefinitionStatus = "ARCHIVED"
+)
+
+// AllDefinitionStatuses enumerates valid definition statuses.
+var AllDefinitionStatuses = []DefinitionStatus{
+	DefinitionStatusDraft,
+	DefinitionStatusValidating,
+	DefinitionStatusReady,
+	DefinitionStatusArchived,
+}
+
+// IsValidDefinitionStatus returns true if the status exists in the allowed set.
+func IsValidDefinitionStatus(status DefinitionStatus) bool {
+	for _, s := range AllDefinitionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecutionStatus is the state of a job execution.
+type ExecutionStatus string
+
+const (
+	// ExecutionStatusAwaitingApproval holds a run of a tenant that requires run
+	// approval until an admin approves it.
+	ExecutionStatusAwaitingApproval ExecutionStatus = "awaiting_approval"
+	ExecutionStatusPending          ExecutionStatus = "pending"
+	ExecutionStatusRunning          ExecutionStatus = "running"
+	ExecutionStatusPaused           ExecutionStatus = "paused"
+	ExecutionStatusSucceeded        ExecutionStatus = "succeeded"
+	ExecutionStatusFailed           ExecutionStatus = "failed"
+	ExecutionStatusSkipped          ExecutionStatus = "skipped"
+	ExecutionStatusCancelled        ExecutionStatus = "cancelled"
+)
+
+// AllExecutionStatuses enumerates valid execution statuses.
+var AllExecutionStatuses = []ExecutionStatus{
+	ExecutionStatusAwaitingApproval,
+	ExecutionStatusPending,
+	ExecutionStatusRunning,
+	ExecutionStatusPaused,
+	ExecutionStatusSucceeded,
+	ExecutionStatusFailed,
+	ExecutionStatusSkipped,
+	ExecutionStatusCancelled,
+}
+
+// IsValidExecutionStatus returns true if the status exists in the allowed set.
+func IsValidExecutionStatus(status ExecutionStatus) bool {
+	for _, s := range AllExecutionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether an execution in this status has not finished yet.
+func (s ExecutionStatus) Active() bool {
+	return s == ExecutionStatusPending || s == ExecutionStatusRunning || s == ExecutionStatusPaused
+}
+
+// ConnectionStatus is the outcome of a connection's last test.
+type ConnectionStatus string
+
+const (
+	ConnectionStatusUntested ConnectionStatus = "untested"
+	ConnectionStatusValid    ConnectionStatus = "valid"
+	ConnectionStatusInvalid  ConnectionStatus = "invalid"
+)
+
+// AllConnectionStatuses enumerates valid connection statuses.
+var AllConnectionStatuses = []ConnectionStatus{
+	ConnectionStatusUntested,
+	ConnectionStatusValid,
+	ConnectionStatusInvalid,
+}
+
+// Enums lists the valid values of every enumeration clients display or send, so
+// they don't hard-code them.
+type Enums struct {
+	DefinitionStatuses   []DefinitionStatus   `json:"definition_statuses"`
+	ExecutionStatuses    []ExecutionStatus    `json:"execution_statuses"`
+	ConnectionStatuses   []ConnectionStatus   `json:"connection_statuses"`
+	ArtifactScanStatuses []ArtifactScanStatus `json:"artifact_scan_statuses"`
+	JobTypes             []string             `json:"job_types"`
+	Triggers             []string             `json:"triggers"`
+	Priorities           []string             `json:"priorities"`
+	Roles                []UserRole           `json:"roles"`
+	Environments         []string             `json:"environments"`
+	CredentialModes      []string             `json:"credential_modes"`
+	WriteModes           []string             `json:"write_modes"`
+	VerificationModes    []string             `json:"verification_modes"`
+}
+
+// AllEnums returns the registry of enumerations.
+func AllEnums() Enums {
+	return Enums{
+		DefinitionStatuses:   AllDefinitionStatuses,
+		ExecutionStatuses:    AllExecutionStatuses,
+		ConnectionStatuses:   AllConnectionStatuses,
+		ArtifactScanStatuses: AllArtifactScanStatuses,
+		JobTypes:             []string{JobTypeEngine, JobTypeSQLScript},
+		Triggers:             []string{TriggerUser, TriggerAPIKey, TriggerSchedule, TriggerRetry, TriggerPipeline},
+		Priorities:           []string{PriorityNormal, PriorityHigh},
+		Roles:                AllUserRoles,
+		Environments:         []string{EnvironmentProd, EnvironmentStaging, EnvironmentDev},
+		CredentialModes:      []string{CredentialModeStored, CredentialModePrompt},
+		WriteModes:           []string{WriteModeAppend, WriteModeOverwrite, WriteModeFailOnExists},
+		VerificationModes:    []string{VerificationOff, VerificationWarn, VerificationFail},
+	}
+}
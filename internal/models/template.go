@@ -0,0 +1,129 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job template kinds.
+const (
+	TemplateKindJob      = "job"
+	TemplateKindFragment = "fragment"
+)
+
+// Placeholders filled from the connections chosen when a template is applied.
+const (
+	PlaceholderSourceConnection      = "source_connection"
+	PlaceholderDestinationConnection = "destination_connection"
+)
+
+// placeholderPattern matches {{name}} tokens in AST keys and string values.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// JobTemplate is a reusable job AST, or a fragment of one, with {{name}}
+// placeholders for table names, connections and other values that change between
+// definitions. Templates without a tenant are curated and shared by every tenant.
+type JobTemplate struct {
+	ID          string          `json:"id"`
+	TenantID    *string         `json:"tenant_id,omitempty"`
+	Global      bool            `json:"global"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Kind        string          `json:"kind"`
+	AST         json.RawMessage `json:"ast"`
+	// Placeholders lists the placeholder names found in the AST, sorted.
+	Placeholders []string  `json:"placeholders"`
+	CreatedBy    *string   `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ValidTemplateKind reports whether kind is a known template kind.
+func ValidTemplateKind(kind string) bool {
+	return kind == TemplateKindJob || kind == TemplateKindFragment
+}
+
+// TemplatePlaceholders lists the placeholder names used in ast, sorted.
+func TemplatePlaceholders(ast json.RawMessage) ([]string, error) {
+	if len(bytes.TrimSpace(ast)) == 0 {
+		return []string{}, nil
+	}
+	doc, err := decodeAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	walkTemplateStrings(doc, func(s string) string {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+			seen[m[1]] = struct{}{}
+		}
+		return s
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RenderTemplate substitutes every placeholder in ast with its value. It fails
+// listing the placeholders that have no value.
+func RenderTemplate(ast json.RawMessage, values map[string]string) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(ast)) == 0 {
+		return nil, errors.New("template AST is empty")
+	}
+	doc, err := decodeAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]struct{}{}
+	doc = walkTemplateStrings(doc, func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(token string) string {
+			name := placeholderPattern.FindStringSubmatch(token)[1]
+			value, ok := values[name]
+			if !ok {
+				missing[name] = struct{}{}
+				return token
+			}
+			return value
+		})
+	})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("missing values for placeholders: %s", strings.Join(names, ", "))
+	}
+	return json.Marshal(doc)
+}
+
+// walkTemplateStrings rebuilds node, passing every object key and string value
+// through fn.
+func walkTemplateStrings(node interface{}, fn func(string) string) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[fn(key)] = walkTemplateStrings(child, fn)
+		}
+		return out
+	case []interface{}:
+		for i, child := range v {
+			v[i] = walkTemplateStrings(child, fn)
+		}
+		return v
+	case string:
+		return fn(v)
+	default:
+		return v
+	}
+}
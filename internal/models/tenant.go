@@ -1,10 +1,130 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Tenant struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID                   string  `json:"id" db:"id"`
+	Name                 string  `json:"name" db:"name"`
+	RequireVerifiedEmail bool    `json:"require_verified_email" db:"require_verified_email"`
+	Timezone             string  `json:"timezone" db:"timezone"`
+	Locale               string  `json:"locale" db:"locale"`
+	DataRegion           *string `json:"data_region" db:"data_region"`
+	// BlockCrossEnvironmentJobs stops definitions mixing connection environments
+	// from being marked READY or run.
+	BlockCrossEnvironmentJobs bool `json:"block_cross_environment_jobs" db:"block_cross_environment_jobs"`
+	// RequireArtifactScan quarantines execution artifacts that could not be malware
+	// scanned instead of storing them unscanned.
+	RequireArtifactScan bool `json:"require_artifact_scan" db:"require_artifact_scan"`
+	// RequireRunApproval holds every run requested over the API until an admin
+	// other than the requester approves it.
+	RequireRunApproval bool `json:"require_run_approval" db:"require_run_approval"`
+	// ValidationFreshness checks READY definitions for source schema drift and
+	// decides whether drifted ones still run.
+	ValidationFreshness ValidationFreshness `json:"validation_freshness" db:"validation_freshness"`
+	SuspendedAt         *time.Time          `json:"suspended_at,omitempty" db:"suspended_at"`
+	PurgeAfter          *time.Time          `json:"purge_after,omitempty" db:"purge_after"`
+	CreatedAt           time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// Bounds of a tenant's worker configuration.
+const (
+	// MinContainerMemoryLimit is the smallest memory limit Docker accepts.
+	MinContainerMemoryLimit = 6 * 1024 * 1024
+	MinMaxExecutionDuration = time.Minute
+	MaxMaxExecutionDuration = 7 * 24 * time.Hour
+)
+
+// TenantWorkerConfig overrides the worker defaults for one tenant's engine runs.
+// Nil and empty fields fall back to the worker's own configuration.
+type TenantWorkerConfig struct {
+	TenantID string `json:"tenant_id"`
+	// CPULimit and MemoryLimit are the container's CPU shares and memory in bytes.
+	CPULimit    *int64 `json:"cpu_limit"`
+	MemoryLimit *int64 `json:"memory_limit"`
+	// EngineImage is the image the tenant's runs use instead of the worker's.
+	EngineImage *string `json:"engine_image"`
+	// AllowedEngineImages, when set, are the only images the tenant's runs may use.
+	AllowedEngineImages []string `json:"allowed_engine_images"`
+	// MaxExecutionSeconds stops engine runs that take longer.
+	MaxExecutionSeconds *int       `json:"max_execution_seconds"`
+	UpdatedBy           *string    `json:"updated_by,omitempty"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+}
+
+// MaxExecutionDuration returns how long an engine run may take, or zero for no limit.
+func (c TenantWorkerConfig) MaxExecutionDuration() time.Duration {
+	if c.MaxExecutionSeconds == nil {
+		return 0
+	}
+	return time.Duration(*c.MaxExecutionSeconds) * time.Second
+}
+
+// AllowsImage reports whether the tenant's runs may use image.
+func (c TenantWorkerConfig) AllowsImage(image string) bool {
+	if len(c.AllowedEngineImages) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedEngineImages {
+		if allowed == image {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize trims and de-duplicates the images and validates the limits.
+func (c *TenantWorkerConfig) Normalize() error {
+	if c.CPULimit != nil && *c.CPULimit < 2 {
+		return errors.New("cpu_limit must be at least 2")
+	}
+	if c.MemoryLimit != nil && *c.MemoryLimit < MinContainerMemoryLimit {
+		return fmt.Errorf("memory_limit must be at least %d bytes", MinContainerMemoryLimit)
+	}
+	if d := c.MaxExecutionDuration(); c.MaxExecutionSeconds != nil && (d < MinMaxExecutionDuration || d > MaxMaxExecutionDuration) {
+		return fmt.Errorf("max_execution_seconds must be between %d and %d",
+			int(MinMaxExecutionDuration.Seconds()), int(MaxMaxExecutionDuration.Seconds()))
+	}
+
+	images := make([]string, 0, len(c.AllowedEngineImages))
+	seen := map[string]bool{}
+	for _, image := range c.AllowedEngineImages {
+		image = strings.TrimSpace(image)
+		if image == "" {
+			return errors.New("allowed_engine_images must not contain empty names")
+		}
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	c.AllowedEngineImages = images
+
+	if c.EngineImage != nil {
+		image := strings.TrimSpace(*c.EngineImage)
+		if image == "" {
+			c.EngineImage = nil
+		} else {
+			c.EngineImage = &image
+			if !c.AllowsImage(image) {
+				return errors.New("engine_image must be one of allowed_engine_images")
+			}
+		}
+	}
+	return nil
+}
+
+// TenantUsage is a tenant's consumption in the current calendar month (UTC) and
+// its active user count, as exported to metrics scrapers.
+type TenantUsage struct {
+	TenantID            string
+	TenantName          string
+	ExecutionsThisMonth int64
+	BytesThisMonth      int64
+	ActiveUsers         int64
 }
@@ -0,0 +1,84 @@
+package models
+
+import "encoding/json"
+
+// Kinds of item a tenant import creates.
+const (
+	ImportKindConnection = "connection"
+	ImportKindDefinition = "definition"
+	ImportKindSchedule   = "schedule"
+)
+
+// TenantImportBundle is the documented format for moving a tenant's connections,
+// definitions and schedules over from another system. Items reference each other
+// by their bundle-local ref; definitions may also use connections that already
+// exist in the tenant by ID.
+type TenantImportBundle struct {
+	Connections []ImportConnection `json:"connections"`
+	Definitions []ImportDefinition `json:"definitions"`
+	Schedules   []ImportSchedule   `json:"schedules"`
+}
+
+// ImportConnection is a connection in an import bundle. Its password is given
+// either in plaintext or as password_encrypted: base64 ciphertext produced with
+// this instance's connection encryption key, e.g. by a previous export.
+type ImportConnection struct {
+	Ref               string   `json:"ref"`
+	Name              string   `json:"name"`
+	DataFormat        string   `json:"data_format"`
+	Host              string   `json:"host"`
+	Port              int      `json:"port"`
+	Username          string   `json:"username"`
+	Password          string   `json:"password,omitempty"`
+	PasswordEncrypted []byte   `json:"password_encrypted,omitempty"`
+	DBName            string   `json:"db_name"`
+	CredentialMode    string   `json:"credential_mode,omitempty"`
+	Environment       string   `json:"environment,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	ReadOnly          bool     `json:"read_only,omitempty"`
+	DockerNetwork     string   `json:"docker_network,omitempty"`
+	NetworkAlias      string   `json:"network_alias,omitempty"`
+}
+
+// ImportDefinition is a job definition in an import bundle. Each connection is
+// either the ref of a bundle connection or the ID of an existing one. Imported
+// definitions are READY.
+type ImportDefinition struct {
+	Ref                     string          `json:"ref"`
+	Name                    string          `json:"name"`
+	Description             string          `json:"description,omitempty"`
+	JobType                 string          `json:"job_type,omitempty"`
+	AST                     json.RawMessage `json:"ast"`
+	SourceConnection        string          `json:"source_connection,omitempty"`
+	SourceConnectionID      string          `json:"source_connection_id,omitempty"`
+	DestinationConnection   string          `json:"destination_connection,omitempty"`
+	DestinationConnectionID string          `json:"destination_connection_id,omitempty"`
+	WriteMode               string          `json:"write_mode,omitempty"`
+	RetryPolicy             *RetryPolicy    `json:"retry_policy,omitempty"`
+	Verification            string          `json:"verification,omitempty"`
+}
+
+// ImportSchedule schedules the bundle definition with the given ref.
+type ImportSchedule struct {
+	Definition     string `json:"definition"`
+	CronExpression string `json:"cron_expression"`
+	Timezone       string `json:"timezone,omitempty"`
+}
+
+// TenantImportItem is an item an import creates, or would create on a dry run,
+// with the ID it is created under.
+type TenantImportItem struct {
+	Kind string `json:"kind"`
+	Ref  string `json:"ref,omitempty"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// TenantImportResult reports a tenant import. Nothing is created when it has
+// errors or is a dry run.
+type TenantImportResult struct {
+	DryRun    bool               `json:"dry_run"`
+	Committed bool               `json:"committed"`
+	Errors    []string           `json:"errors"`
+	Items     []TenantImportItem `json:"items"`
+}
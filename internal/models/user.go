@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 // UserRole represents the permission tier for a user within a tenant.
 type UserRole string
 
@@ -98,12 +103,19 @@ func HighestRole(roles []UserRole) UserRole {
 }
 
 type User struct {
-	ID           string     `json:"id"`
-	TenantID     string     `json:"tenant_id"`
-	Email        string     `json:"email"`
-	FirstName    string     `json:"first_name"`
-	LastName     string     `json:"last_name"`
-	PasswordHash string     `json:"password_hash"`
-	IsActive     bool       `json:"is_active"`
-	Roles        []UserRole `json:"roles"`
+	ID              string     `json:"id"`
+	TenantID        string     `json:"tenant_id"`
+	Email           string     `json:"email"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	PasswordHash    string     `json:"password_hash"`
+	IsActive        bool       `json:"is_active"`
+	Roles           []UserRole `json:"roles"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+// ErasedEmail is the placeholder address an erased user's email is replaced with. It
+// is unique per user and can never receive mail.
+func ErasedEmail(userID string) string {
+	return fmt.Sprintf("erased-%s@erased.invalid", userID)
 }
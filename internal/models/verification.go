@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ArtifactKindVerification is the post-migration source/destination comparison of
+// an execution.
+const ArtifactKindVerification = "verification"
+
+// Post-migration verification modes of a definition.
+const (
+	// VerificationOff skips verification.
+	VerificationOff = "off"
+	// VerificationWarn flags a run whose destination does not match its source as
+	// suspect.
+	VerificationWarn = "warn"
+	// VerificationFail fails a run whose destination does not match its source.
+	VerificationFail = "fail"
+)
+
+// NormalizeVerification lowercases and validates a verification mode. An empty
+// mode is off.
+func NormalizeVerification(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return VerificationOff, nil
+	case VerificationOff, VerificationWarn, VerificationFail:
+		return mode, nil
+	}
+	return "", fmt.Errorf("verification must be %s, %s or %s", VerificationOff, VerificationWarn, VerificationFail)
+}
+
+// TableVerification compares one migrated table. Checksums are only set when the
+// engine can compute them for both sides.
+type TableVerification struct {
+	Table               string `json:"table"`
+	SourceRows          *int64 `json:"source_rows"`
+	DestinationRows     *int64 `json:"destination_rows"`
+	SourceChecksum      string `json:"source_checksum,omitempty"`
+	DestinationChecksum string `json:"destination_checksum,omitempty"`
+	Match               bool   `json:"match"`
+	Error               string `json:"error,omitempty"`
+}
+
+// VerificationReport is the engine's row count and checksum comparison of the
+// tables a run migrated, stored as an execution artifact.
+type VerificationReport struct {
+	CapturedAt time.Time           `json:"captured_at"`
+	Mode       string              `json:"mode"`
+	Tables     []TableVerification `json:"tables"`
+	// Mismatched lists the tables that differ or could not be compared.
+	Mismatched []string `json:"mismatched"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Evaluate sets each table's Match and collects the tables that did not match. A
+// table matches when both row counts are known and equal, and its checksums, when
+// the engine reported both, are equal too.
+func (r *VerificationReport) Evaluate() {
+	r.Mismatched = []string{}
+	for i := range r.Tables {
+		t := &r.Tables[i]
+		t.Match = t.Error == "" && t.SourceRows != nil && t.DestinationRows != nil && *t.SourceRows == *t.DestinationRows
+		if t.Match && t.SourceChecksum != "" && t.DestinationChecksum != "" {
+			t.Match = t.SourceChecksum == t.DestinationChecksum
+		}
+		if !t.Match {
+			r.Mismatched = append(r.Mismatched, t.Table)
+		}
+	}
+}
+
+// Problem describes why the run did not verify, or returns "" when every table
+// matched.
+func (r VerificationReport) Problem() string {
+	if r.Error != "" {
+		return "verification could not run: " + r.Error
+	}
+	if len(r.Mismatched) > 0 {
+		return "destination differs from source in tables: " + strings.Join(r.Mismatched, ", ")
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lists a saved view applies to.
+const (
+	ViewListExecutions = "executions"
+	ViewListJobs       = "jobs"
+)
+
+const (
+	maxViewNameLength = 100
+	maxViewFilterSize = 8 * 1024
+	maxViewSortLength = 200
+)
+
+// ValidViewList reports whether list is a list saved views apply to.
+func ValidViewList(list string) bool {
+	return list == ViewListExecutions || list == ViewListJobs
+}
+
+// SavedView is a named filter and sort for the executions or jobs list. Views
+// without an owner are shared with the whole tenant and managed by admins.
+type SavedView struct {
+	ID       string  `json:"id"`
+	TenantID string  `json:"tenant_id"`
+	OwnerID  *string `json:"owner_id,omitempty"`
+	Shared   bool    `json:"shared"`
+	List     string  `json:"list"`
+	Name     string  `json:"name"`
+	// Filters is the client's serialized filter state, a JSON object the API stores
+	// as is.
+	Filters json.RawMessage `json:"filters"`
+	Sort    string          `json:"sort"`
+	// IsDefault marks the view the requesting user opens the list with.
+	IsDefault bool      `json:"is_default"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize trims the view's fields and validates them.
+func (v *SavedView) Normalize() error {
+	v.Name = strings.TrimSpace(v.Name)
+	v.List = strings.ToLower(strings.TrimSpace(v.List))
+	v.Sort = strings.TrimSpace(v.Sort)
+	if v.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(v.Name) > maxViewNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxViewNameLength)
+	}
+	if !ValidViewList(v.List) {
+		return fmt.Errorf("list must be %s or %s", ViewListExecutions, ViewListJobs)
+	}
+	if len(v.Sort) > maxViewSortLength {
+		return fmt.Errorf("sort must be at most %d characters", maxViewSortLength)
+	}
+
+	filters := bytes.TrimSpace(v.Filters)
+	if len(filters) == 0 || bytes.Equal(filters, []byte("null")) {
+		filters = []byte("{}")
+	}
+	if len(filters) > maxViewFilterSize {
+		return fmt.Errorf("filters must be at most %d bytes", maxViewFilterSize)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(filters, &obj); err != nil {
+		return errors.New("filters must be a JSON object")
+	}
+	v.Filters = json.RawMessage(filters)
+	return nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event categories. A subscription receives the events of one category.
+const (
+	WebhookCategoryUser      = "user"
+	WebhookCategoryExecution = "execution"
+)
+
+// User lifecycle webhook events.
+const (
+	WebhookEventUserCreated     = "user_created"
+	WebhookEventRolesChanged    = "roles_changed"
+	WebhookEventUserDeactivated = "user_deactivated"
+	WebhookEventInviteAccepted  = "invite_accepted"
+)
+
+// Execution result webhook events.
+const (
+	WebhookEventExecutionSucceeded = "execution_succeeded"
+	WebhookEventExecutionFailed    = "execution_failed"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+var webhookCategoryEvents = map[string][]string{
+	WebhookCategoryUser: {
+		WebhookEventUserCreated,
+		WebhookEventRolesChanged,
+		WebhookEventUserDeactivated,
+		WebhookEventInviteAccepted,
+	},
+	WebhookCategoryExecution: {
+		WebhookEventExecutionSucceeded,
+		WebhookEventExecutionFailed,
+	},
+}
+
+// ValidWebhookCategory reports whether category is a known event category.
+func ValidWebhookCategory(category string) bool {
+	_, ok := webhookCategoryEvents[category]
+	return ok
+}
+
+// ValidWebhookEvent reports whether event belongs to category.
+func ValidWebhookEvent(category, event string) bool {
+	for _, e := range webhookCategoryEvents[category] {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription is a tenant endpoint receiving the events of one category.
+// An empty Events list subscribes to every event of the category.
+type WebhookSubscription struct {
+	ID       string   `json:"id"`
+	TenantID string   `json:"tenant_id"`
+	Category string   `json:"category"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	Active   bool     `json:"active"`
+	// PayloadTemplate shapes the body posted for each event; nil posts the event.
+	PayloadTemplate *WebhookPayloadTemplate `json:"payload_template,omitempty"`
+	CreatedBy       *string                 `json:"created_by,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for, or sent to, one subscription.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	TenantID       string          `json:"tenant_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastError      *string         `json:"last_error,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// WebhookEvent is the body posted to subscribers.
+type WebhookEvent struct {
+	ID         string                 `json:"id"`
+	Category   string                 `json:"category"`
+	Type       string                 `json:"type"`
+	TenantID   string                 `json:"tenant_id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// ExecutionWebhookData is the data of an execution result event.
+func ExecutionWebhookData(exec JobExecution, jobName string) map[string]interface{} {
+	data := map[string]interface{}{
+		"execution_id":      exec.ID,
+		"job_definition_id": exec.JobDefinitionID,
+		"job_definition":    jobName,
+		"status":            exec.Status,
+		"triggered_by":      exec.TriggeredBy,
+		"run_started_at":    exec.RunStartedAt,
+		"run_completed_at":  exec.RunCompletedAt,
+		"records_processed": exec.RecordsProcessed,
+		"bytes_transferred": exec.BytesTransferred,
+		"error_message":     exec.ErrorMessage,
+	}
+	if exec.RunStartedAt != nil && exec.RunCompletedAt != nil {
+		data["duration_seconds"] = exec.RunCompletedAt.Sub(*exec.RunStartedAt).Seconds()
+	}
+	return data
+}
+
+// SampleWebhookEvent returns a representative event of category, used to check
+// payload templates and for test deliveries.
+func SampleWebhookEvent(category, tenantID string, now time.Time) WebhookEvent {
+	evt := WebhookEvent{
+		ID:         "00000000-0000-0000-0000-000000000000",
+		Category:   category,
+		TenantID:   tenantID,
+		OccurredAt: now,
+	}
+	switch category {
+	case WebhookCategoryExecution:
+		started := now.Add(-2 * time.Minute)
+		trigger := TriggerSchedule
+		records, bytes := int64(1200), int64(48000)
+		evt.Type = WebhookEventExecutionSucceeded
+		evt.Data = ExecutionWebhookData(JobExecution{
+			ID:               "00000000-0000-0000-0000-000000000001",
+			JobDefinitionID:  "00000000-0000-0000-0000-000000000002",
+			Status:           ExecutionStatusSucceeded,
+			RunStartedAt:     &started,
+			RunCompletedAt:   &now,
+			RecordsProcessed: &records,
+			BytesTransferred: &bytes,
+			TriggeredBy:      &trigger,
+		}, "Sample job")
+	default:
+		evt.Type = WebhookEventUserCreated
+		evt.Data = map[string]interface{}{
+			"user_id": "00000000-0000-0000-0000-000000000001",
+			"email":   "jane@example.com",
+			"roles":   []UserRole{RoleViewer},
+		}
+	}
+	return evt
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of payload template a webhook subscription may use.
+const (
+	// WebhookTemplateGo renders the event through a Go text/template whose output
+	// must be JSON.
+	WebhookTemplateGo = "go_template"
+	// WebhookTemplateJSONPath builds a JSON object whose fields are JSONPath
+	// selections from the event.
+	WebhookTemplateJSONPath = "jsonpath"
+)
+
+// MaxWebhookTemplateBytes bounds a Go payload template.
+const MaxWebhookTemplateBytes = 16 * 1024
+
+// WebhookPayloadTemplate shapes what a subscription receives instead of the whole
+// event. Both kinds see the event as it would otherwise be posted, so paths and
+// template fields start at its top level, e.g. $.data.status or {{.data.status}}.
+type WebhookPayloadTemplate struct {
+	Type string `json:"type"`
+	// Template is the Go template of a go_template payload. The json function
+	// encodes a value, e.g. {"status": {{json .data.status}}}.
+	Template string `json:"template,omitempty"`
+	// Fields maps each field of a jsonpath payload to the path selecting it. Paths
+	// support .name, ['name'], [index] and [*]; a path matching nothing yields null.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Validate checks the template and renders it against a sample event of category,
+// so templates that cannot produce JSON are rejected when they are saved rather
+// than when an event is delivered.
+func (t WebhookPayloadTemplate) Validate(category string) error {
+	switch t.Type {
+	case WebhookTemplateGo:
+		if strings.TrimSpace(t.Template) == "" {
+			return errors.New("template is required for a go_template payload")
+		}
+		if len(t.Template) > MaxWebhookTemplateBytes {
+			return fmt.Errorf("template must be at most %d bytes", MaxWebhookTemplateBytes)
+		}
+		if _, err := template.New("payload").Funcs(webhookTemplateFuncs).Parse(t.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	case WebhookTemplateJSONPath:
+		if len(t.Fields) == 0 {
+			return errors.New("fields are required for a jsonpath payload")
+		}
+		for name, path := range t.Fields {
+			if strings.TrimSpace(name) == "" {
+				return errors.New("jsonpath field names must not be empty")
+			}
+			if _, err := parseJSONPath(path); err != nil {
+				return fmt.Errorf("field %q: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown payload template type %q", t.Type)
+	}
+	if _, err := t.Render(SampleWebhookEvent(category, "", time.Now().UTC())); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Render shapes evt into the payload the subscription receives.
+func (t WebhookPayloadTemplate) Render(evt WebhookEvent) (json.RawMessage, error) {
+	raw, err := json.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	switch t.Type {
+	case WebhookTemplateGo:
+		tmpl, err := template.New("payload").Funcs(webhookTemplateFuncs).Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, doc); err != nil {
+			return nil, fmt.Errorf("render template: %w", err)
+		}
+		if !json.Valid(out.Bytes()) {
+			return nil, errors.New("template did not render valid JSON")
+		}
+		return json.RawMessage(out.Bytes()), nil
+	case WebhookTemplateJSONPath:
+		payload := make(map[string]interface{}, len(t.Fields))
+		for name, path := range t.Fields {
+			steps, err := parseJSONPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			payload[name] = selectJSONPath(doc, steps)
+		}
+		return json.Marshal(payload)
+	}
+	return nil, fmt.Errorf("unknown payload template type %q", t.Type)
+}
+
+// RenderWebhookPayload returns the body sub receives for evt: the event itself,
+// or what the subscription's payload template makes of it.
+func RenderWebhookPayload(sub WebhookSubscription, evt WebhookEvent) (json.RawMessage, error) {
+	if sub.PayloadTemplate == nil {
+		return json.Marshal(evt)
+	}
+	return sub.PayloadTemplate.Render(evt)
+}
+
+// jsonPathStep is one step of a parsed JSONPath: a key, an index or, when all is
+// set, every element.
+type jsonPathStep struct {
+	key   string
+	index int
+	isKey bool
+	all   bool
+}
+
+// parseJSONPath parses the subset of JSONPath payload templates support: a
+// leading $ followed by .name, ['name'], [index] and [*] steps.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("path %q has an empty field name", path)
+			}
+			if name == "*" {
+				steps = append(steps, jsonPathStep{all: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: name, isKey: true})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q has an unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{all: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1], isKey: true})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("path %q has an invalid index %q", path, inner)
+				}
+				steps = append(steps, jsonPathStep{index: n})
+			}
+		default:
+			return nil, fmt.Errorf("path %q is invalid at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSONPath applies steps to doc. A wildcard collects what the remaining
+// steps select from each element into a list.
+func selectJSONPath(doc interface{}, steps []jsonPathStep) interface{} {
+	for i, step := range steps {
+		switch {
+		case step.all:
+			var elems []interface{}
+			switch v := doc.(type) {
+			case []interface{}:
+				elems = v
+			case map[string]interface{}:
+				for _, e := range v {
+					elems = append(elems, e)
+				}
+			default:
+				return nil
+			}
+			out := make([]interface{}, 0, len(elems))
+			for _, e := range elems {
+				out = append(out, selectJSONPath(e, steps[i+1:]))
+			}
+			return out
+		case step.isKey:
+			obj, ok := doc.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			doc = obj[step.key]
+		default:
+			arr, ok := doc.([]interface{})
+			if !ok {
+				return nil
+			}
+			idx := step.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil
+			}
+			doc = arr[idx]
+		}
+	}
+	return doc
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Destination write modes. A definition sets one for all its tables; the AST may
+// override it per table under "write_modes", keyed by destination table.
+const (
+	// WriteModeAppend inserts into the destination tables and keeps their rows.
+	WriteModeAppend = "append"
+	// WriteModeOverwrite empties each destination table before writing it.
+	WriteModeOverwrite = "overwrite"
+	// WriteModeFailOnExists fails the run when a destination table already exists.
+	WriteModeFailOnExists = "fail_on_exists"
+)
+
+// DefaultWriteMode is used by definitions that never chose a write mode.
+const DefaultWriteMode = WriteModeAppend
+
+// NormalizeWriteMode lowercases and validates a write mode. An empty mode is the
+// default.
+func NormalizeWriteMode(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return DefaultWriteMode, nil
+	case WriteModeAppend, WriteModeOverwrite, WriteModeFailOnExists:
+		return mode, nil
+	}
+	return "", fmt.Errorf("write_mode must be %s, %s or %s", WriteModeAppend, WriteModeOverwrite, WriteModeFailOnExists)
+}
+
+// TableWriteModes returns the per-table write modes an AST overrides, normalized.
+// An AST without overrides returns nil.
+func TableWriteModes(ast json.RawMessage) (map[string]string, error) {
+	if len(ast) == 0 {
+		return nil, nil
+	}
+	var doc struct {
+		WriteModes map[string]string `json:"write_modes"`
+	}
+	if err := json.Unmarshal(ast, &doc); err != nil {
+		return nil, fmt.Errorf("invalid write_modes: %w", err)
+	}
+	if len(doc.WriteModes) == 0 {
+		return nil, nil
+	}
+	modes := make(map[string]string, len(doc.WriteModes))
+	for table, mode := range doc.WriteModes {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			return nil, errors.New("write_modes has an empty table name")
+		}
+		if strings.TrimSpace(mode) == "" {
+			return nil, fmt.Errorf("write_modes.%s is empty", table)
+		}
+		normalized, err := NormalizeWriteMode(mode)
+		if err != nil {
+			return nil, fmt.Errorf("write_modes.%s: %w", table, err)
+		}
+		modes[table] = normalized
+	}
+	return modes, nil
+}
+
+// WriteModeFor returns the write mode of one destination table: its override, or
+// the definition's mode.
+func WriteModeFor(table, defMode string, overrides map[string]string) string {
+	if mode, ok := overrides[table]; ok {
+		return mode
+	}
+	if defMode == "" {
+		return DefaultWriteMode
+	}
+	return defMode
+}
+
+// OverwriteWarnings returns a warning for every destination table that holds rows
+// and would be overwritten. Tables whose row count is unknown are skipped.
+func OverwriteWarnings(tables []TableRowCount, defMode string, overrides map[string]string) []string {
+	var warnings []string
+	for _, t := range tables {
+		if t.RowCount == nil || *t.RowCount == 0 {
+			continue
+		}
+		if WriteModeFor(t.Table, defMode, overrides) == WriteModeOverwrite {
+			warnings = append(warnings, fmt.Sprintf("Destination table %s has %d rows that overwrite mode will replace", t.Table, *t.RowCount))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// EffectiveWriteMode returns the definition's write mode, or the default for
+// definitions loaded without one.
+func (d JobDefinition) EffectiveWriteMode() string {
+	if d.WriteMode == "" {
+		return DefaultWriteMode
+	}
+	return d.WriteMode
+}
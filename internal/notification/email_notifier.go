@@ -9,6 +9,8 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stanstork/stratum-api/internal/config"
 	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/utils"
 )
 
 type EmailNotifier struct {
@@ -18,10 +20,13 @@ type EmailNotifier struct {
 	password   string
 	from       string
 	recipients []string
+	tenants    repository.TenantRepository
 	logger     zerolog.Logger
 }
 
-func NewEmailNotifier(cfg config.EmailConfig, logger zerolog.Logger) (*EmailNotifier, error) {
+// NewEmailNotifier builds the alert email channel. Timestamps are rendered in the
+// tenant's timezone and locale when tenants is provided.
+func NewEmailNotifier(cfg config.EmailConfig, tenants repository.TenantRepository, logger zerolog.Logger) (*EmailNotifier, error) {
 	recipients := sanitizeRecipients(cfg.AlertRecipients)
 	host := strings.TrimSpace(cfg.SMTPHost)
 	from := strings.TrimSpace(cfg.From)
@@ -43,6 +48,7 @@ func NewEmailNotifier(cfg config.EmailConfig, logger zerolog.Logger) (*EmailNoti
 		password:   cfg.Password,
 		from:       from,
 		recipients: recipients,
+		tenants:    tenants,
 		logger:     logger.With().Str("notifier", "email").Logger(),
 	}, nil
 }
@@ -62,7 +68,7 @@ func (n *EmailNotifier) Notify(_ context.Context, notif models.Notification) err
 	body.WriteString("\n\n")
 	body.WriteString(fmt.Sprintf("Event: %s\n", notif.EventType))
 	body.WriteString(fmt.Sprintf("Severity: %s\n", notif.Severity))
-	body.WriteString(fmt.Sprintf("Created: %s\n", notif.CreatedAt.Format("2006-01-02 15:04:05 MST")))
+	body.WriteString(fmt.Sprintf("Created: %s\n", n.formatTimestamp(notif)))
 	if len(notif.Metadata) > 0 {
 		body.WriteString(fmt.Sprintf("Metadata: %s\n", string(notif.Metadata)))
 	}
@@ -91,6 +97,21 @@ func (n *EmailNotifier) Notify(_ context.Context, notif models.Notification) err
 	return nil
 }
 
+// formatTimestamp renders the notification time for the tenant it belongs to,
+// defaulting to UTC for system-wide notifications.
+func (n *EmailNotifier) formatTimestamp(notif models.Notification) string {
+	tz, locale := "UTC", ""
+	if n.tenants != nil && notif.TenantID != nil {
+		tenant, err := n.tenants.GetTenantByID(*notif.TenantID)
+		if err != nil {
+			n.logger.Warn().Err(err).Str("tenant_id", *notif.TenantID).Msg("failed to load tenant locale settings")
+		} else {
+			tz, locale = tenant.Timezone, tenant.Locale
+		}
+	}
+	return utils.FormatLocalTime(notif.CreatedAt, tz, locale)
+}
+
 func (n *EmailNotifier) String() string {
 	return "EmailNotifier"
 }
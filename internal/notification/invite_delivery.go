@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/utils"
+)
+
+const (
+	inviteInitialBackoff = 30 * time.Second
+	inviteMaxBackoff     = time.Hour
+	// inviteSendLease hides a claimed invite email from other replicas while it is
+	// being sent.
+	inviteSendLease = 5 * time.Minute
+)
+
+// InviteDelivery sends queued invite emails, retrying failed sends with
+// exponential backoff until maxAttempts is used up.
+type InviteDelivery struct {
+	invites     repository.InviteRepository
+	tenants     repository.TenantRepository
+	mailer      InviteMailer
+	urlTpl      string
+	maxAttempts int
+	batchSize   int
+	logger      zerolog.Logger
+}
+
+func NewInviteDelivery(invites repository.InviteRepository, tenants repository.TenantRepository, mailer InviteMailer, inviteURLTemplate string, maxAttempts int, logger zerolog.Logger) *InviteDelivery {
+	if maxAttempts <= 0 {
+		maxAttempts = 6
+	}
+	return &InviteDelivery{
+		invites:     invites,
+		tenants:     tenants,
+		mailer:      mailer,
+		urlTpl:      inviteURLTemplate,
+		maxAttempts: maxAttempts,
+		batchSize:   50,
+		logger:      logger.With().Str("component", "invite_delivery").Logger(),
+	}
+}
+
+// FirstRetryAt is when a queued invite email is retried if the immediate attempt
+// made by Send does not settle it.
+func (d *InviteDelivery) FirstRetryAt() time.Time {
+	return time.Now().Add(inviteInitialBackoff)
+}
+
+// Send makes one attempt to deliver a queued invite email whose raw token the
+// caller still holds, and returns the invite with its delivery state updated.
+func (d *InviteDelivery) Send(invite models.Invite, tenantName, token string) models.Invite {
+	err := d.mailer.SendInvite(invite.Email, tenantName, fmt.Sprintf(d.urlTpl, token))
+	d.record(&invite, err, true)
+	return invite
+}
+
+// RunOnce retries one batch of due invite emails.
+func (d *InviteDelivery) RunOnce(ctx context.Context) error {
+	due, err := d.invites.ClaimDueInviteDeliveries(d.batchSize, inviteSendLease)
+	if err != nil {
+		return fmt.Errorf("claim invite deliveries: %w", err)
+	}
+	tenantNames := map[string]string{}
+	for _, invite := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		token, err := utils.DecryptSecret(invite.DeliveryToken)
+		if err != nil {
+			d.record(&invite, fmt.Errorf("decrypt invite token: %w", err), false)
+			continue
+		}
+		name, ok := tenantNames[invite.TenantID]
+		if !ok {
+			tenant, err := d.tenants.GetTenantByID(invite.TenantID)
+			if err != nil {
+				d.record(&invite, fmt.Errorf("load tenant: %w", err), true)
+				continue
+			}
+			name = tenant.Name
+			tenantNames[invite.TenantID] = name
+		}
+		err = d.mailer.SendInvite(invite.Email, name, fmt.Sprintf(d.urlTpl, token))
+		d.record(&invite, err, true)
+	}
+	return nil
+}
+
+// record stores the outcome of an attempt on invite, rescheduling failures unless
+// they may not be retried or the email has used up its attempts.
+func (d *InviteDelivery) record(invite *models.Invite, sendErr error, retryable bool) {
+	log := d.logger.With().Str("invite_id", invite.ID).Logger()
+	invite.DeliveryAttempts++
+	if sendErr == nil {
+		now := time.Now()
+		invite.DeliveryStatus, invite.DeliveredAt, invite.DeliveryError, invite.NextDeliveryAt = models.InviteDeliverySent, &now, nil, nil
+		if err := d.invites.MarkInviteDelivered(invite.ID); err != nil {
+			log.Error().Err(err).Msg("failed to mark invite email delivered")
+		}
+		return
+	}
+
+	msg := sendErr.Error()
+	invite.DeliveryError = &msg
+	var retryAt *time.Time
+	if retryable && invite.DeliveryAttempts < d.maxAttempts {
+		backoff := inviteInitialBackoff << uint(invite.DeliveryAttempts-1)
+		if backoff <= 0 || backoff > inviteMaxBackoff {
+			backoff = inviteMaxBackoff
+		}
+		next := time.Now().Add(backoff)
+		retryAt = &next
+		log.Debug().Int("attempts", invite.DeliveryAttempts).Str("error", msg).Msg("invite email failed")
+	} else {
+		invite.DeliveryStatus = models.InviteDeliveryFailed
+		log.Warn().Int("attempts", invite.DeliveryAttempts).Str("error", msg).Msg("giving up on invite email")
+	}
+	invite.NextDeliveryAt = retryAt
+	if err := d.invites.MarkInviteDeliveryFailed(invite.ID, msg, retryAt); err != nil {
+		log.Error().Err(err).Msg("failed to record invite email failure")
+	}
+}
@@ -1,7 +1,10 @@
 package notification
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/smtp"
 	"strings"
 
@@ -13,6 +16,11 @@ type InviteMailer interface {
 	SendInvite(recipientEmail, tenantName, inviteURL string) error
 }
 
+// VerificationMailer delivers email ownership verification links.
+type VerificationMailer interface {
+	SendVerification(recipientEmail, verifyURL string) error
+}
+
 // SMTPInviteMailer sends invite emails using an SMTP server.
 type SMTPInviteMailer struct {
 	host     string
@@ -56,8 +64,25 @@ func (m *SMTPInviteMailer) SendInvite(recipientEmail, tenantName, inviteURL stri
 	body.WriteString("This invite is valid for a limited time. If you did not expect this email, you can ignore it.\n\n")
 	body.WriteString("Thanks,\nThe Stratum Team\n")
 
-	message := []byte(headers + body.String())
+	return m.send(recipientEmail, []byte(headers+body.String()))
+}
+
+// SendVerification asks the recipient to confirm they own the email address.
+func (m *SMTPInviteMailer) SendVerification(recipientEmail, verifyURL string) error {
+	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n",
+		m.from, recipientEmail, "Verify your Stratum email address")
+
+	body := strings.Builder{}
+	body.WriteString("Hello,\n\n")
+	body.WriteString("Please confirm this email address for your Stratum account by opening the link below:\n\n")
+	body.WriteString(verifyURL + "\n\n")
+	body.WriteString("The link expires after a limited time. If you did not request this, you can ignore this email.\n\n")
+	body.WriteString("Thanks,\nThe Stratum Team\n")
+
+	return m.send(recipientEmail, []byte(headers+body.String()))
+}
 
+func (m *SMTPInviteMailer) send(recipientEmail string, message []byte) error {
 	addr := fmt.Sprintf("%s:%d", m.host, m.port)
 
 	var auth smtp.Auth
@@ -67,3 +92,35 @@ func (m *SMTPInviteMailer) SendInvite(recipientEmail, tenantName, inviteURL stri
 
 	return smtp.SendMail(addr, auth, m.from, []string{recipientEmail}, message)
 }
+
+// Check connects to the SMTP server and authenticates without sending mail, to
+// validate the configuration.
+func (m *SMTPInviteMailer) Check(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%d", m.host, m.port)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(m.username) != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+	return c.Quit()
+}
@@ -12,21 +12,34 @@ import (
 
 type Event struct {
 	TenantID string
-	Event    models.NotificationEvent
-	Severity models.NotificationSeverity
-	Title    string
-	Message  string
-	Metadata map[string]interface{}
+	// CorrelationKey groups the notifications of one execution; it is the
+	// execution ID for execution events and empty otherwise.
+	CorrelationKey string
+	Event          models.NotificationEvent
+	Severity       models.NotificationSeverity
+	Title          string
+	Message        string
+	Metadata       map[string]interface{}
 }
 
 type Service interface {
 	Publish(ctx context.Context, evt Event) (models.Notification, error)
 	NotifyValidationComplete(ctx context.Context, tenantID, jobDefID, jobName string) error
+	NotifyValidationFailed(ctx context.Context, tenantID, jobDefID, jobName string, errs []string, demoted bool) error
+	NotifyValidationStale(ctx context.Context, tenantID, jobDefID, jobName string, blocked bool) error
 	NotifyExecutionStarted(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error
-	NotifyExecutionSucceeded(ctx context.Context, tenantID, jobDefID, executionID, jobName string, recordsProcessed, bytesTransferred int64) error
-	NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string) error
+	NotifyExecutionSucceeded(ctx context.Context, tenantID, jobDefID, executionID, jobName string, recordsProcessed, bytesTransferred int64, attempts map[string]int32) error
+	NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string, attempts map[string]int32) error
+	NotifyExecutionRegressed(ctx context.Context, tenantID, jobDefID, executionID, jobName string, regressions []models.ExecutionRegression) error
+	NotifyExecutionSuspect(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string) error
+	NotifyExecutionPaused(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error
+	NotifyExecutionResumed(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error
+	NotifyRunGroupCompleted(ctx context.Context, tenantID string, report models.RunGroupReport) error
 	ListRecent(ctx context.Context, tenantID string, limit int) ([]models.Notification, error)
+	ListExecutionGroups(ctx context.Context, tenantID string, limit int) ([]models.NotificationGroup, error)
 	MarkRead(ctx context.Context, tenantID, notificationID string) (models.Notification, error)
+	MarkGroupRead(ctx context.Context, tenantID, correlationKey string) (int64, error)
+	ListVersion(ctx context.Context, tenantID string) (string, error)
 }
 
 type service struct {
@@ -62,11 +75,12 @@ func (s *service) Publish(ctx context.Context, evt Event) (models.Notification,
 		title = string(evt.Event)
 	}
 	params := repository.CreateNotificationParams{
-		Event:    evt.Event,
-		Severity: evt.Severity,
-		Title:    title,
-		Message:  message,
-		Metadata: evt.Metadata,
+		CorrelationKey: strings.TrimSpace(evt.CorrelationKey),
+		Event:          evt.Event,
+		Severity:       evt.Severity,
+		Title:          title,
+		Message:        message,
+		Metadata:       evt.Metadata,
 	}
 	if tid := strings.TrimSpace(evt.TenantID); tid != "" {
 		params.TenantID = &tid
@@ -103,17 +117,70 @@ func (s *service) NotifyValidationComplete(ctx context.Context, tenantID, jobDef
 	return err
 }
 
+func (s *service) NotifyValidationFailed(ctx context.Context, tenantID, jobDefID, jobName string, errs []string, demoted bool) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for validation notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	message := fmt.Sprintf("Job definition %q no longer validates after a connection change", name)
+	if demoted {
+		message += " and was moved back to DRAFT"
+	}
+	message += ": " + strings.Join(errs, "; ")
+	_, err := s.Publish(ctx, Event{
+		TenantID: tenantID,
+		Event:    models.NotificationEventValidationFailed,
+		Severity: models.NotificationSeverityWarning,
+		Title:    fmt.Sprintf("Revalidation failed: %s", name),
+		Message:  message,
+		Metadata: map[string]interface{}{
+			"job_definition_id": jobDefID,
+			"job_definition":    name,
+			"errors":            errs,
+			"demoted":           demoted,
+		},
+	})
+	return err
+}
+
+func (s *service) NotifyValidationStale(ctx context.Context, tenantID, jobDefID, jobName string, blocked bool) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for validation notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	message := fmt.Sprintf("The source schema of job definition %q changed since it was validated", name)
+	if blocked {
+		message += "; it will not run until it is validated again"
+	} else {
+		message += "; validate it again before its next run"
+	}
+	_, err := s.Publish(ctx, Event{
+		TenantID: tenantID,
+		Event:    models.NotificationEventValidationStale,
+		Severity: models.NotificationSeverityWarning,
+		Title:    fmt.Sprintf("Stale validation: %s", name),
+		Message:  message + ".",
+		Metadata: map[string]interface{}{
+			"job_definition_id": jobDefID,
+			"job_definition":    name,
+			"blocked":           blocked,
+		},
+	})
+	return err
+}
+
 func (s *service) NotifyExecutionStarted(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error {
 	if strings.TrimSpace(tenantID) == "" {
 		return fmt.Errorf("tenant id is required for execution notifications")
 	}
 	name := fallbackName(jobName, jobDefID)
 	_, err := s.Publish(ctx, Event{
-		TenantID: tenantID,
-		Event:    models.NotificationEventExecutionStarted,
-		Severity: models.NotificationSeverityInfo,
-		Title:    fmt.Sprintf("Execution started: %s", name),
-		Message:  fmt.Sprintf("Job %s execution %s has started.", name, executionID),
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionStarted,
+		Severity:       models.NotificationSeverityInfo,
+		Title:          fmt.Sprintf("Execution started: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s has started.", name, executionID),
 		Metadata: map[string]interface{}{
 			"job_definition_id": jobDefID,
 			"job_definition":    name,
@@ -123,7 +190,7 @@ func (s *service) NotifyExecutionStarted(ctx context.Context, tenantID, jobDefID
 	return err
 }
 
-func (s *service) NotifyExecutionSucceeded(ctx context.Context, tenantID, jobDefID, executionID, jobName string, recordsProcessed, bytesTransferred int64) error {
+func (s *service) NotifyExecutionSucceeded(ctx context.Context, tenantID, jobDefID, executionID, jobName string, recordsProcessed, bytesTransferred int64, attempts map[string]int32) error {
 	if strings.TrimSpace(tenantID) == "" {
 		return fmt.Errorf("tenant id is required for execution notifications")
 	}
@@ -139,18 +206,22 @@ func (s *service) NotifyExecutionSucceeded(ctx context.Context, tenantID, jobDef
 	if bytesTransferred > 0 {
 		metadata["bytes_transferred"] = bytesTransferred
 	}
+	if len(attempts) > 0 {
+		metadata["activity_attempts"] = attempts
+	}
 	_, err := s.Publish(ctx, Event{
-		TenantID: tenantID,
-		Event:    models.NotificationEventExecutionSucceeded,
-		Severity: models.NotificationSeverityInfo,
-		Title:    fmt.Sprintf("Execution succeeded: %s", name),
-		Message:  fmt.Sprintf("Job %s execution %s completed successfully.", name, executionID),
-		Metadata: metadata,
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionSucceeded,
+		Severity:       models.NotificationSeverityInfo,
+		Title:          fmt.Sprintf("Execution succeeded: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s completed successfully.", name, executionID),
+		Metadata:       metadata,
 	})
 	return err
 }
 
-func (s *service) NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string) error {
+func (s *service) NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string, attempts map[string]int32) error {
 	if strings.TrimSpace(tenantID) == "" {
 		return fmt.Errorf("tenant id is required for execution notifications")
 	}
@@ -159,12 +230,70 @@ func (s *service) NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID,
 	if reason == "" {
 		reason = "Unknown error"
 	}
+	metadata := map[string]interface{}{
+		"job_definition_id": jobDefID,
+		"job_definition":    name,
+		"execution_id":      executionID,
+		"reason":            reason,
+	}
+	if len(attempts) > 0 {
+		metadata["activity_attempts"] = attempts
+	}
 	_, err := s.Publish(ctx, Event{
-		TenantID: tenantID,
-		Event:    models.NotificationEventExecutionFailed,
-		Severity: models.NotificationSeverityError,
-		Title:    fmt.Sprintf("Execution failed: %s", name),
-		Message:  fmt.Sprintf("Job %s execution %s failed: %s", name, executionID, reason),
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionFailed,
+		Severity:       models.NotificationSeverityError,
+		Title:          fmt.Sprintf("Execution failed: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s failed: %s", name, executionID, reason),
+		Metadata:       metadata,
+	})
+	return err
+}
+
+func (s *service) NotifyExecutionRegressed(ctx context.Context, tenantID, jobDefID, executionID, jobName string, regressions []models.ExecutionRegression) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for execution notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	details := make([]string, 0, len(regressions))
+	for _, reg := range regressions {
+		switch reg.Metric {
+		case models.RegressionMetricDuration:
+			details = append(details, fmt.Sprintf("took %.0fs against a median of %.0fs (%+.0f%%)", reg.ObservedValue, reg.BaselineValue, reg.Deviation*100))
+		case models.RegressionMetricThroughput:
+			details = append(details, fmt.Sprintf("moved %.0f records/s against a median of %.0f (%+.0f%%)", reg.ObservedValue, reg.BaselineValue, reg.Deviation*100))
+		}
+	}
+	_, err := s.Publish(ctx, Event{
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionRegressed,
+		Severity:       models.NotificationSeverityWarning,
+		Title:          fmt.Sprintf("Execution slower than usual: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s %s.", name, executionID, strings.Join(details, " and ")),
+		Metadata: map[string]interface{}{
+			"job_definition_id": jobDefID,
+			"job_definition":    name,
+			"execution_id":      executionID,
+			"regressions":       regressions,
+		},
+	})
+	return err
+}
+
+func (s *service) NotifyExecutionSuspect(ctx context.Context, tenantID, jobDefID, executionID, jobName, reason string) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for execution notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	_, err := s.Publish(ctx, Event{
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionSuspect,
+		Severity:       models.NotificationSeverityWarning,
+		Title:          fmt.Sprintf("Execution moved no data: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s succeeded but %s. Check the engine logs and the job configuration.", name, executionID, reason),
 		Metadata: map[string]interface{}{
 			"job_definition_id": jobDefID,
 			"job_definition":    name,
@@ -175,14 +304,98 @@ func (s *service) NotifyExecutionFailed(ctx context.Context, tenantID, jobDefID,
 	return err
 }
 
+func (s *service) NotifyExecutionPaused(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for execution notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	_, err := s.Publish(ctx, Event{
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionPaused,
+		Severity:       models.NotificationSeverityInfo,
+		Title:          fmt.Sprintf("Execution paused: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s has been paused.", name, executionID),
+		Metadata: map[string]interface{}{
+			"job_definition_id": jobDefID,
+			"job_definition":    name,
+			"execution_id":      executionID,
+		},
+	})
+	return err
+}
+
+func (s *service) NotifyExecutionResumed(ctx context.Context, tenantID, jobDefID, executionID, jobName string) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for execution notifications")
+	}
+	name := fallbackName(jobName, jobDefID)
+	_, err := s.Publish(ctx, Event{
+		TenantID:       tenantID,
+		CorrelationKey: executionID,
+		Event:          models.NotificationEventExecutionResumed,
+		Severity:       models.NotificationSeverityInfo,
+		Title:          fmt.Sprintf("Execution resumed: %s", name),
+		Message:        fmt.Sprintf("Job %s execution %s has resumed.", name, executionID),
+		Metadata: map[string]interface{}{
+			"job_definition_id": jobDefID,
+			"job_definition":    name,
+			"execution_id":      executionID,
+		},
+	})
+	return err
+}
+
+func (s *service) NotifyRunGroupCompleted(ctx context.Context, tenantID string, report models.RunGroupReport) error {
+	if strings.TrimSpace(tenantID) == "" {
+		return fmt.Errorf("tenant id is required for run group notifications")
+	}
+	name := fallbackName(report.Name, report.GroupID)
+	severity, outcome := models.NotificationSeverityInfo, "succeeded"
+	if report.Status != models.RunGroupStatusSucceeded {
+		severity, outcome = models.NotificationSeverityError, "failed"
+	}
+	p := report.Progress
+	_, err := s.Publish(ctx, Event{
+		TenantID: tenantID,
+		Event:    models.NotificationEventRunGroupCompleted,
+		Severity: severity,
+		Title:    fmt.Sprintf("Run group %s: %s", outcome, name),
+		Message: fmt.Sprintf("Run group %s finished: %d of %d succeeded, %d failed, %d skipped.",
+			name, p.Succeeded, p.Total, p.Failed, p.Skipped),
+		Metadata: map[string]interface{}{
+			"run_group_id":      report.GroupID,
+			"run_group":         name,
+			"status":            report.Status,
+			"progress":          p,
+			"records_processed": report.RecordsProcessed,
+			"bytes_transferred": report.BytesTransferred,
+			"duration_seconds":  report.DurationSeconds,
+		},
+	})
+	return err
+}
+
 func (s *service) ListRecent(ctx context.Context, tenantID string, limit int) ([]models.Notification, error) {
 	return s.repo.ListRecent(ctx, tenantID, limit)
 }
 
+func (s *service) ListExecutionGroups(ctx context.Context, tenantID string, limit int) ([]models.NotificationGroup, error) {
+	return s.repo.ListGroups(ctx, tenantID, limit)
+}
+
 func (s *service) MarkRead(ctx context.Context, tenantID, notificationID string) (models.Notification, error) {
 	return s.repo.MarkRead(ctx, tenantID, notificationID)
 }
 
+func (s *service) MarkGroupRead(ctx context.Context, tenantID, correlationKey string) (int64, error) {
+	return s.repo.MarkGroupRead(ctx, tenantID, correlationKey)
+}
+
+func (s *service) ListVersion(ctx context.Context, tenantID string) (string, error) {
+	return s.repo.ListVersion(ctx, tenantID)
+}
+
 func fallbackName(name, fallback string) string {
 	if trimmed := strings.TrimSpace(name); trimmed != "" {
 		return trimmed
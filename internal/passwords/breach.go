@@ -0,0 +1,86 @@
+package passwords
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultBreachCheckURL is the Pwned Passwords range API.
+const DefaultBreachCheckURL = "https://api.pwnedpasswords.com/range/"
+
+// BreachCheck rejects passwords that appear in a known breach corpus. Only the
+// first five hex characters of the password's SHA-1 leave the process
+// (k-anonymity); the matching suffixes are compared locally.
+type BreachCheck struct {
+	url    string
+	client *http.Client
+	logger zerolog.Logger
+}
+
+func NewBreachCheck(url string, timeout time.Duration, logger zerolog.Logger) *BreachCheck {
+	if url == "" {
+		url = DefaultBreachCheckURL
+	}
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &BreachCheck{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		logger: logger.With().Str("component", "password_breach_check").Logger(),
+	}
+}
+
+// Check fails open: a password is accepted when the breach corpus cannot be reached,
+// so an outage of the range API does not block signups.
+func (b *BreachCheck) Check(ctx context.Context, password string) string {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	found, err := b.lookup(ctx, digest[:5], digest[5:])
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("password breach check unavailable; accepting password")
+		return ""
+	}
+	if found {
+		return "appears in a known data breach; choose a different password"
+	}
+	return ""
+}
+
+func (b *BreachCheck) Describe() string {
+	return "Not found in known data breaches"
+}
+
+func (b *BreachCheck) lookup(ctx context.Context, prefix, suffix string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides how many suffixes share the prefix from observers.
+	req.Header.Set("Add-Padding", "true")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach range API returned %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padding entries have a zero count.
+		if ok && strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,137 @@
+// Package passwords checks new passwords against the deployment's password policy.
+package passwords
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/config"
+)
+
+// Rule is one requirement of a password policy.
+type Rule interface {
+	// Check returns why password breaks the rule, or "" when it complies.
+	Check(ctx context.Context, password string) string
+	// Describe states the requirement for users choosing a password.
+	Describe() string
+}
+
+// PolicyError lists every rule a rejected password breaks.
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the password policy: " + strings.Join(e.Problems, "; ")
+}
+
+// Description is the policy as shown to clients before they choose a password.
+type Description struct {
+	MinLength        int      `json:"min_length"`
+	RequireUppercase bool     `json:"require_uppercase"`
+	RequireLowercase bool     `json:"require_lowercase"`
+	RequireDigit     bool     `json:"require_digit"`
+	RequireSymbol    bool     `json:"require_symbol"`
+	BreachCheck      bool     `json:"breach_check"`
+	Rules            []string `json:"rules"`
+}
+
+// Policy validates passwords against its rules. Rules beyond the configured ones
+// can be added with AddRule.
+type Policy struct {
+	desc  Description
+	rules []Rule
+}
+
+// NewPolicy builds the policy described by cfg.
+func NewPolicy(cfg config.PasswordPolicyConfig, logger zerolog.Logger) *Policy {
+	p := &Policy{desc: Description{
+		MinLength:        cfg.MinLength,
+		RequireUppercase: cfg.RequireUppercase,
+		RequireLowercase: cfg.RequireLowercase,
+		RequireDigit:     cfg.RequireDigit,
+		RequireSymbol:    cfg.RequireSymbol,
+		BreachCheck:      cfg.BreachCheck,
+	}}
+	p.AddRule(minLength(max(cfg.MinLength, 1)))
+	if cfg.RequireUppercase {
+		p.AddRule(charClass{"an uppercase letter", unicode.IsUpper})
+	}
+	if cfg.RequireLowercase {
+		p.AddRule(charClass{"a lowercase letter", unicode.IsLower})
+	}
+	if cfg.RequireDigit {
+		p.AddRule(charClass{"a digit", unicode.IsDigit})
+	}
+	if cfg.RequireSymbol {
+		p.AddRule(charClass{"a symbol", isSymbol})
+	}
+	if cfg.BreachCheck {
+		p.AddRule(NewBreachCheck(cfg.BreachCheckURL, cfg.BreachCheckTimeout, logger))
+	}
+	return p
+}
+
+// AddRule appends a rule to the policy.
+func (p *Policy) AddRule(rule Rule) {
+	p.rules = append(p.rules, rule)
+	p.desc.Rules = append(p.desc.Rules, rule.Describe())
+}
+
+// Validate returns a *PolicyError when password breaks any of the policy's rules.
+func (p *Policy) Validate(ctx context.Context, password string) error {
+	var problems []string
+	for _, rule := range p.rules {
+		if problem := rule.Check(ctx, password); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return &PolicyError{Problems: problems}
+	}
+	return nil
+}
+
+// Describe returns the policy for display.
+func (p *Policy) Describe() Description {
+	desc := p.desc
+	desc.Rules = append([]string(nil), p.desc.Rules...)
+	return desc
+}
+
+type minLength int
+
+func (m minLength) Check(_ context.Context, password string) string {
+	if len([]rune(password)) < int(m) {
+		return fmt.Sprintf("must be at least %d characters long", int(m))
+	}
+	return ""
+}
+
+func (m minLength) Describe() string {
+	return fmt.Sprintf("At least %d characters", int(m))
+}
+
+// charClass requires at least one character of a class.
+type charClass struct {
+	name string
+	is   func(rune) bool
+}
+
+func (c charClass) Check(_ context.Context, password string) string {
+	if strings.IndexFunc(password, c.is) < 0 {
+		return "must contain " + c.name
+	}
+	return ""
+}
+
+func (c charClass) Describe() string {
+	return "Contains " + c.name
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// maintenanceCompleteFor is how long the banner announcing the end of maintenance
+// stays up.
+const maintenanceCompleteFor = time.Hour
+
+type AnnouncementRepository interface {
+	// ListAnnouncements returns every announcement, newest first. A non-empty
+	// tenantID limits the list to that tenant's announcements.
+	ListAnnouncements(tenantID string) ([]models.Announcement, error)
+	// ActiveAnnouncements returns the global and tenant announcements shown now,
+	// most severe first.
+	ActiveAnnouncements(tenantID string) ([]models.Announcement, error)
+	GetAnnouncement(announcementID string) (models.Announcement, error)
+	CreateAnnouncement(a models.Announcement) (models.Announcement, error)
+	UpdateAnnouncement(a models.Announcement) (models.Announcement, error)
+	DeleteAnnouncement(announcementID string) error
+
+	GetMaintenanceMode() (models.MaintenanceMode, error)
+	// SetMaintenanceMode toggles maintenance mode and posts the matching global
+	// announcement: a critical banner carrying message while maintenance lasts, and
+	// a short-lived notice once it ends.
+	SetMaintenanceMode(enabled bool, message, completeMessage string, updatedBy *string) (models.MaintenanceMode, error)
+}
+
+type announcementRepository struct {
+	db *sql.DB
+}
+
+func NewAnnouncementRepository(db *sql.DB) AnnouncementRepository {
+	return &announcementRepository{db: db}
+}
+
+const announcementColumns = `id, tenant_id, message, severity, starts_at, ends_at, source, created_by, created_at, updated_at`
+
+const maintenanceColumns = `enabled, message, announcement_id, updated_by, updated_at`
+
+func scanAnnouncement(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.Announcement, error) {
+	var (
+		a         models.Announcement
+		tenantID  sql.NullString
+		endsAt    sql.NullTime
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&a.ID,
+		&tenantID,
+		&a.Message,
+		&a.Severity,
+		&a.StartsAt,
+		&endsAt,
+		&a.Source,
+		&createdBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	); err != nil {
+		return a, err
+	}
+	if tenantID.Valid {
+		a.TenantID = &tenantID.String
+	}
+	a.Global = a.TenantID == nil
+	if endsAt.Valid {
+		a.EndsAt = &endsAt.Time
+	}
+	if createdBy.Valid {
+		a.CreatedBy = &createdBy.String
+	}
+	return a, nil
+}
+
+func scanMaintenanceMode(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.MaintenanceMode, error) {
+	var (
+		m              models.MaintenanceMode
+		announcementID sql.NullString
+		updatedBy      sql.NullString
+	)
+	if err := scanner.Scan(&m.Enabled, &m.Message, &announcementID, &updatedBy, &m.UpdatedAt); err != nil {
+		return m, err
+	}
+	if announcementID.Valid {
+		m.AnnouncementID = &announcementID.String
+	}
+	if updatedBy.Valid {
+		m.UpdatedBy = &updatedBy.String
+	}
+	return m, nil
+}
+
+func (r *announcementRepository) queryAnnouncements(query string, args ...interface{}) ([]models.Announcement, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+func (r *announcementRepository) ListAnnouncements(tenantID string) ([]models.Announcement, error) {
+	return r.queryAnnouncements(`
+		SELECT `+announcementColumns+`
+		FROM tenant.announcements
+		WHERE $1 = '' OR tenant_id::text = $1
+		ORDER BY starts_at DESC`, tenantID)
+}
+
+func (r *announcementRepository) ActiveAnnouncements(tenantID string) ([]models.Announcement, error) {
+	return r.queryAnnouncements(`
+		SELECT `+announcementColumns+`
+		FROM tenant.announcements
+		WHERE (tenant_id IS NULL OR tenant_id = $1)
+		  AND starts_at <= now() AND (ends_at IS NULL OR ends_at > now())
+		ORDER BY CASE severity WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, starts_at DESC`, tenantID)
+}
+
+func (r *announcementRepository) GetAnnouncement(announcementID string) (models.Announcement, error) {
+	return scanAnnouncement(r.db.QueryRow(`SELECT `+announcementColumns+` FROM tenant.announcements WHERE id = $1 /* cross-tenant: announcements are managed by platform admins */`, announcementID))
+}
+
+func (r *announcementRepository) CreateAnnouncement(a models.Announcement) (models.Announcement, error) {
+	return createAnnouncement(r.db, a)
+}
+
+func createAnnouncement(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, a models.Announcement) (models.Announcement, error) {
+	query := `
+		INSERT INTO tenant.announcements (tenant_id, message, severity, starts_at, ends_at, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + announcementColumns
+	startsAt := a.StartsAt
+	if startsAt.IsZero() {
+		startsAt = time.Now()
+	}
+	source := a.Source
+	if source == "" {
+		source = models.AnnouncementSourceManual
+	}
+	return scanAnnouncement(q.QueryRow(query, a.TenantID, a.Message, a.Severity, startsAt, a.EndsAt, source, a.CreatedBy))
+}
+
+// UpdateAnnouncement replaces the announcement's message, severity and window.
+func (r *announcementRepository) UpdateAnnouncement(a models.Announcement) (models.Announcement, error) {
+	query := `
+		/* cross-tenant: announcements are managed by platform admins */
+		UPDATE tenant.announcements
+		SET message = $2, severity = $3, starts_at = $4, ends_at = $5, updated_at = now()
+		WHERE id = $1
+		RETURNING ` + announcementColumns
+	return scanAnnouncement(r.db.QueryRow(query, a.ID, a.Message, a.Severity, a.StartsAt, a.EndsAt))
+}
+
+func (r *announcementRepository) DeleteAnnouncement(announcementID string) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.announcements WHERE id = $1 /* cross-tenant: announcements are managed by platform admins */`, announcementID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *announcementRepository) GetMaintenanceMode() (models.MaintenanceMode, error) {
+	return scanMaintenanceMode(r.db.QueryRow(`SELECT ` + maintenanceColumns + ` FROM tenant.maintenance_mode`))
+}
+
+func (r *announcementRepository) SetMaintenanceMode(enabled bool, message, completeMessage string, updatedBy *string) (models.MaintenanceMode, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.MaintenanceMode{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := scanMaintenanceMode(tx.QueryRow(`SELECT ` + maintenanceColumns + ` FROM tenant.maintenance_mode FOR UPDATE`))
+	if err != nil {
+		return current, err
+	}
+
+	announcementID := current.AnnouncementID
+	switch {
+	case enabled && current.Enabled && announcementID != nil:
+		// Already in maintenance: only the message changes.
+		if _, err := tx.Exec(`UPDATE tenant.announcements SET message = $2, updated_at = now() WHERE id = $1 /* cross-tenant: maintenance announcements are global */`,
+			*announcementID, message); err != nil {
+			return current, err
+		}
+	case enabled:
+		a, err := createAnnouncement(tx, models.Announcement{
+			Message:   message,
+			Severity:  models.AnnouncementCritical,
+			Source:    models.AnnouncementSourceMaintenance,
+			CreatedBy: updatedBy,
+		})
+		if err != nil {
+			return current, err
+		}
+		announcementID = &a.ID
+	case !enabled && current.Enabled:
+		if announcementID != nil {
+			if _, err := tx.Exec(`
+				/* cross-tenant: maintenance announcements are global */
+				UPDATE tenant.announcements
+				SET ends_at = GREATEST(now(), starts_at + interval '1 second'), updated_at = now()
+				WHERE id = $1 AND (ends_at IS NULL OR ends_at > now())`, *announcementID); err != nil {
+				return current, err
+			}
+		}
+		endsAt := time.Now().Add(maintenanceCompleteFor)
+		if _, err := createAnnouncement(tx, models.Announcement{
+			Message:   completeMessage,
+			Severity:  models.AnnouncementInfo,
+			EndsAt:    &endsAt,
+			Source:    models.AnnouncementSourceMaintenance,
+			CreatedBy: updatedBy,
+		}); err != nil {
+			return current, err
+		}
+		announcementID = nil
+	}
+
+	updated, err := scanMaintenanceMode(tx.QueryRow(`
+		UPDATE tenant.maintenance_mode
+		SET enabled = $1, message = $2, announcement_id = $3, updated_by = $4, updated_at = now()
+		RETURNING `+maintenanceColumns, enabled, message, announcementID, updatedBy))
+	if err != nil {
+		return updated, err
+	}
+	return updated, tx.Commit()
+}
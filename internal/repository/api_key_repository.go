@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key models.APIKey) (models.APIKey, error)
+	ListAPIKeys(tenantID string) ([]models.APIKey, error)
+	RevokeAPIKey(tenantID, keyID string) error
+	AuthenticateAPIKey(keyHash string) (models.APIKey, error)
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func scanAPIKey(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.APIKey, error) {
+	var (
+		key       models.APIKey
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&key.ID,
+		&key.TenantID,
+		&key.Name,
+		&key.KeyPrefix,
+		&key.KeyHash,
+		&createdBy,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	); err != nil {
+		return models.APIKey{}, err
+	}
+	if createdBy.Valid {
+		key.CreatedBy = &createdBy.String
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	const query = `
+		INSERT INTO tenant.api_keys (tenant_id, name, key_prefix, key_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, tenant_id, name, key_prefix, key_hash, created_by, created_at, last_used_at, revoked_at;
+	`
+	var createdBy interface{}
+	if key.CreatedBy != nil && *key.CreatedBy != "" {
+		createdBy = *key.CreatedBy
+	}
+	return scanAPIKey(r.db.QueryRow(query, key.TenantID, key.Name, key.KeyPrefix, key.KeyHash, createdBy))
+}
+
+func (r *apiKeyRepository) ListAPIKeys(tenantID string) ([]models.APIKey, error) {
+	const query = `
+		SELECT id, tenant_id, name, key_prefix, key_hash, created_by, created_at, last_used_at, revoked_at
+		FROM tenant.api_keys
+		WHERE tenant_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC;
+	`
+	rows, err := r.db.Query(query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(tenantID, keyID string) error {
+	const query = `
+		UPDATE tenant.api_keys
+		SET revoked_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL;
+	`
+	result, err := r.db.Exec(query, keyID, tenantID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves an active key of a tenant that is not suspended by hash
+// and records that it was used.
+func (r *apiKeyRepository) AuthenticateAPIKey(keyHash string) (models.APIKey, error) {
+	const query = `
+		UPDATE tenant.api_keys
+		SET last_used_at = now()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		  AND tenant_id IN (SELECT id FROM tenant.tenants WHERE suspended_at IS NULL)
+		RETURNING id, tenant_id, name, key_prefix, key_hash, created_by, created_at, last_used_at, revoked_at;
+	`
+	return scanAPIKey(r.db.QueryRow(query, keyHash))
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// AuditRepository stores audit events and API access logs, and streams the evidence
+// compliance exports are built from.
+type AuditRepository interface {
+	RecordEvent(evt models.AuditEvent) error
+	RecordAccessLogs(entries []models.AccessLog) error
+
+	// The Each* methods call fn with every matching row as JSON, oldest first. An empty
+	// tenantID covers every tenant.
+	EachAuditEvent(tenantID string, from, to time.Time, actions []string, fn func(json.RawMessage) error) error
+	EachAccessLog(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error
+	EachExecutionSnapshot(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) RecordEvent(evt models.AuditEvent) error {
+	var details interface{}
+	if len(evt.Details) > 0 {
+		raw, err := json.Marshal(evt.Details)
+		if err != nil {
+			return err
+		}
+		details = raw
+	}
+	const query = `
+		INSERT INTO tenant.audit_events (tenant_id, actor_id, action, target_type, target_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(query, evt.TenantID, evt.ActorID, evt.Action, evt.TargetType, evt.TargetID, details)
+	return err
+}
+
+func (r *auditRepository) RecordAccessLogs(entries []models.AccessLog) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyInSchema("tenant", "access_logs",
+		"tenant_id", "user_id", "method", "route", "path", "status", "duration_ms", "remote_addr", "user_agent", "created_at"))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := stmt.Exec(e.TenantID, e.UserID, e.Method, e.Route, e.Path, e.Status, e.DurationMs, e.RemoteAddr, e.UserAgent, e.CreatedAt); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *auditRepository) EachAuditEvent(tenantID string, from, to time.Time, actions []string, fn func(json.RawMessage) error) error {
+	const query = `
+		SELECT to_jsonb(e) FROM tenant.audit_events e
+		WHERE ($1 = '' OR e.tenant_id::text = $1)
+		  AND e.created_at >= $2 AND e.created_at < $3
+		  AND (cardinality($4::text[]) = 0 OR e.action = ANY($4))
+		ORDER BY e.created_at, e.id
+	`
+	if actions == nil {
+		actions = []string{}
+	}
+	return r.eachJSONRow(fn, query, tenantID, from, to, pq.Array(actions))
+}
+
+func (r *auditRepository) EachAccessLog(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error {
+	const query = `
+		SELECT to_jsonb(l) - 'id' FROM tenant.access_logs l
+		WHERE ($1 = '' OR l.tenant_id::text = $1)
+		  AND l.created_at >= $2 AND l.created_at < $3
+		ORDER BY l.created_at, l.id
+	`
+	return r.eachJSONRow(fn, query, tenantID, from, to)
+}
+
+func (r *auditRepository) EachExecutionSnapshot(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error {
+	const query = `
+		SELECT to_jsonb(s) || jsonb_build_object('ast',
+			COALESCE(s.ast, convert_from(tenant.blob_content(s.ast_hash), 'UTF8')::jsonb))
+		FROM tenant.execution_snapshots s
+		WHERE ($1 = '' OR s.tenant_id::text = $1)
+		  AND s.created_at >= $2 AND s.created_at < $3
+		ORDER BY s.created_at, s.execution_id
+	`
+	return r.eachJSONRow(fn, query, tenantID, from, to)
+}
+
+func (r *auditRepository) eachJSONRow(fn func(json.RawMessage) error, query string, args ...interface{}) error {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
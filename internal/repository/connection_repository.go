@@ -2,8 +2,11 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/utils"
 )
@@ -13,25 +16,43 @@ type connectionRepository struct {
 }
 
 type ConnectionRepository interface {
-	List(tenantID string) ([]*models.Connection, error)
+	List(tenantID string, filter ConnectionFilter) ([]*models.Connection, error)
 	Get(tenantID, id string) (*models.Connection, error)
 	Create(conn *models.Connection) (*models.Connection, error)
 	Update(conn *models.Connection) (*models.Connection, error)
 	Delete(tenantID, id string) error
+	// DeleteEphemeral deletes an inline DSN connection no definition was stored with.
+	DeleteEphemeral(tenantID, id string) error
+	SaveBenchmark(tenantID, id string, benchmark models.ConnectionBenchmark) error
+	SaveServerVersion(tenantID, id, version string) error
+	ListVersion(tenantID string) (string, error)
+}
+
+// ConnectionFilter narrows a connection listing. An empty Environment matches every
+// connection; a connection must carry all of Tags to match.
+type ConnectionFilter struct {
+	Environment string
+	Tags        []string
 }
 
 func NewConnectionRepository(db *sql.DB) ConnectionRepository {
 	return &connectionRepository{db: db}
 }
 
-func (r *connectionRepository) List(tenantID string) ([]*models.Connection, error) {
+func (r *connectionRepository) List(tenantID string, filter ConnectionFilter) ([]*models.Connection, error) {
 	const q = `
-SELECT id, tenant_id, name, data_format, host, port, username, password, db_name, status, created_at, updated_at
+SELECT id, tenant_id, name, data_format, host, port, username, password, db_name, status, ephemeral, credential_mode, COALESCE(environment, ''), tags, benchmark, options, read_only, COALESCE(server_version, ''), COALESCE(docker_network, ''), COALESCE(network_alias, ''), created_at, updated_at
 FROM tenant.connections
-WHERE tenant_id = $1 AND deleted_at IS NULL
+WHERE tenant_id = $1 AND deleted_at IS NULL AND NOT ephemeral
+  AND ($2 = '' OR environment = $2)
+  AND tags @> $3
 ORDER BY name;
 `
-	rows, err := r.db.Query(q, tenantID)
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	rows, err := r.db.Query(q, tenantID, filter.Environment, pq.Array(tags))
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +61,12 @@ ORDER BY name;
 	var conns []*models.Connection
 	for rows.Next() {
 		var c models.Connection
-		var encPwd []byte
+		var encPwd, benchmark, options []byte
 		if err := rows.Scan(
 			&c.ID, &c.TenantID, &c.Name, &c.DataFormat,
-			&c.Host, &c.Port, &c.Username, &encPwd, &c.DBName, &c.Status,
+			&c.Host, &c.Port, &c.Username, &encPwd, &c.DBName, &c.Status, &c.Ephemeral, &c.CredentialMode,
+			&c.Environment, pq.Array(&c.Tags), &benchmark, &options, &c.ReadOnly, &c.ServerVersion,
+			&c.DockerNetwork, &c.NetworkAlias,
 			&c.CreatedAt, &c.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -53,6 +76,12 @@ ORDER BY name;
 			return nil, fmt.Errorf("decrypt password: %w", err)
 		}
 		c.Password = pwd
+		if c.Benchmark, err = decodeBenchmark(benchmark); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(options, &c.Options); err != nil {
+			return nil, fmt.Errorf("decode options: %w", err)
+		}
 		conns = append(conns, &c)
 	}
 	return conns, rows.Err()
@@ -60,15 +89,17 @@ ORDER BY name;
 
 func (r *connectionRepository) Get(tenantID, id string) (*models.Connection, error) {
 	const q = `
-SELECT id, tenant_id, name, data_format, host, port, username, password, db_name, status, created_at, updated_at
+SELECT id, tenant_id, name, data_format, host, port, username, password, db_name, status, ephemeral, credential_mode, COALESCE(environment, ''), tags, benchmark, options, read_only, COALESCE(server_version, ''), COALESCE(docker_network, ''), COALESCE(network_alias, ''), created_at, updated_at
 FROM tenant.connections
 WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
 `
 	var c models.Connection
-	var encPwd []byte
+	var encPwd, benchmark, options []byte
 	if err := r.db.QueryRow(q, id, tenantID).Scan(
 		&c.ID, &c.TenantID, &c.Name, &c.DataFormat,
-		&c.Host, &c.Port, &c.Username, &encPwd, &c.DBName, &c.Status,
+		&c.Host, &c.Port, &c.Username, &encPwd, &c.DBName, &c.Status, &c.Ephemeral, &c.CredentialMode,
+		&c.Environment, pq.Array(&c.Tags), &benchmark, &options, &c.ReadOnly, &c.ServerVersion,
+		&c.DockerNetwork, &c.NetworkAlias,
 		&c.CreatedAt, &c.UpdatedAt,
 	); err != nil {
 		return nil, err
@@ -78,25 +109,39 @@ WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
 		return nil, fmt.Errorf("decrypt password: %w", err)
 	}
 	c.Password = pwd
+	if c.Benchmark, err = decodeBenchmark(benchmark); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(options, &c.Options); err != nil {
+		return nil, fmt.Errorf("decode options: %w", err)
+	}
 	return &c, nil
 }
 
 func (r *connectionRepository) Create(conn *models.Connection) (*models.Connection, error) {
+	normalizeCredentialMode(conn)
 	encPwd, err := utils.EncryptPassword(conn.Password)
 	if err != nil {
 		return conn, fmt.Errorf("encrypt password: %w", err)
 	}
+	options, err := json.Marshal(conn.Options)
+	if err != nil {
+		return conn, err
+	}
 	const q = `
 INSERT INTO tenant.connections (
-  tenant_id, name, data_format, host, port, username, password, db_name
+  tenant_id, name, data_format, host, port, username, password, db_name, ephemeral, credential_mode,
+  environment, tags, options, read_only, docker_network, network_alias
 )
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
 RETURNING id, tenant_id, created_at, updated_at;
 `
 	if err := r.db.QueryRow(
 		q,
 		conn.TenantID, conn.Name, conn.DataFormat,
-		conn.Host, conn.Port, conn.Username, encPwd, conn.DBName,
+		conn.Host, conn.Port, conn.Username, encPwd, conn.DBName, conn.Ephemeral, conn.CredentialMode,
+		nullIfEmpty(conn.Environment), pq.Array(conn.Tags), options, conn.ReadOnly,
+		nullIfEmpty(conn.DockerNetwork), nullIfEmpty(conn.NetworkAlias),
 	).Scan(&conn.ID, &conn.TenantID, &conn.CreatedAt, &conn.UpdatedAt); err != nil {
 		return conn, err
 	}
@@ -104,10 +149,15 @@ RETURNING id, tenant_id, created_at, updated_at;
 }
 
 func (r *connectionRepository) Update(conn *models.Connection) (*models.Connection, error) {
+	normalizeCredentialMode(conn)
 	encPwd, err := utils.EncryptPassword(conn.Password)
 	if err != nil {
 		return conn, fmt.Errorf("encrypt password: %w", err)
 	}
+	options, err := json.Marshal(conn.Options)
+	if err != nil {
+		return conn, err
+	}
 	const q = `
 UPDATE tenant.connections
 SET name = $1,
@@ -118,27 +168,50 @@ SET name = $1,
     username = $6,
     password = $7,
     db_name = $8,
+    credential_mode = $11,
+    environment = $12,
+    tags = $13,
+    options = $14,
+    read_only = $15,
+    docker_network = $16,
+    network_alias = $17,
     updated_at = now()
-WHERE id = $9 AND tenant_id = $10 AND deleted_at IS NULL
-RETURNING tenant_id, created_at, updated_at;
+WHERE id = $9 AND tenant_id = $10 AND deleted_at IS NULL AND NOT ephemeral
+RETURNING tenant_id, COALESCE(server_version, ''), created_at, updated_at;
 `
 	if err := r.db.QueryRow(
 		q,
 		conn.Name, conn.DataFormat, conn.Status,
 		conn.Host, conn.Port, conn.Username, encPwd, conn.DBName,
-		conn.ID, conn.TenantID,
-	).Scan(&conn.TenantID, &conn.CreatedAt, &conn.UpdatedAt); err != nil {
+		conn.ID, conn.TenantID, conn.CredentialMode,
+		nullIfEmpty(conn.Environment), pq.Array(conn.Tags), options, conn.ReadOnly,
+		nullIfEmpty(conn.DockerNetwork), nullIfEmpty(conn.NetworkAlias),
+	).Scan(&conn.TenantID, &conn.ServerVersion, &conn.CreatedAt, &conn.UpdatedAt); err != nil {
 		return conn, err
 	}
 	return conn, nil
 }
 
+// normalizeCredentialMode defaults the credential mode and makes sure a password
+// supplied for a prompt-mode connection is never stored.
+func normalizeCredentialMode(conn *models.Connection) {
+	if conn.CredentialMode == "" {
+		conn.CredentialMode = models.CredentialModeStored
+	}
+	if conn.PromptsForCredentials() {
+		conn.Password = ""
+	}
+	if conn.Tags == nil {
+		conn.Tags = []string{}
+	}
+}
+
 func (r *connectionRepository) Delete(tenantID, id string) error {
 	const q = `
 UPDATE tenant.connections
 SET deleted_at = now(),
     updated_at = now()
-WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL AND NOT ephemeral;
 `
 	res, err := r.db.Exec(q, id, tenantID)
 	if err != nil {
@@ -153,3 +226,91 @@ WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
 	}
 	return nil
 }
+
+func (r *connectionRepository) DeleteEphemeral(tenantID, id string) error {
+	const q = `
+UPDATE tenant.connections
+SET deleted_at = now(),
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL AND ephemeral;
+`
+	_, err := r.db.Exec(q, id, tenantID)
+	return err
+}
+
+// SaveBenchmark records the connection's latest benchmark result.
+func (r *connectionRepository) SaveBenchmark(tenantID, id string, benchmark models.ConnectionBenchmark) error {
+	raw, err := json.Marshal(benchmark)
+	if err != nil {
+		return err
+	}
+	const q = `
+UPDATE tenant.connections
+SET benchmark = $1
+WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL;
+`
+	res, err := r.db.Exec(q, raw, id, tenantID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SaveServerVersion records the server version a test-conn of the connection
+// reported. Connection strings pick their driver defaults from it.
+func (r *connectionRepository) SaveServerVersion(tenantID, id, version string) error {
+	const q = `
+UPDATE tenant.connections
+SET server_version = $1
+WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL;
+`
+	res, err := r.db.Exec(q, nullIfEmpty(version), id, tenantID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func decodeBenchmark(raw []byte) (*models.ConnectionBenchmark, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var b models.ConnectionBenchmark
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("decode benchmark: %w", err)
+	}
+	return &b, nil
+}
+
+// ListVersion returns a cheap fingerprint of the tenant's connection list.
+// Soft-deleted rows still count towards max(updated_at) so deletes change it too.
+func (r *connectionRepository) ListVersion(tenantID string) (string, error) {
+	const q = `
+SELECT COUNT(*) FILTER (WHERE deleted_at IS NULL),
+       COALESCE(MAX(updated_at), 'epoch'::timestamptz)
+FROM tenant.connections
+WHERE tenant_id = $1 AND NOT ephemeral;
+`
+	var (
+		count   int64
+		updated time.Time
+	)
+	if err := r.db.QueryRow(q, tenantID).Scan(&count, &updated); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", count, updated.UnixNano()), nil
+}
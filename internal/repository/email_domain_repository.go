@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type EmailDomainRepository interface {
+	CreateDomain(domain models.EmailDomain) (models.EmailDomain, error)
+	ListDomains(tenantID string) ([]models.EmailDomain, error)
+	GetDomain(tenantID, domainID string) (models.EmailDomain, error)
+	MarkDomainVerified(tenantID, domainID string) (models.EmailDomain, error)
+	SetDomainApproval(tenantID, domainID string, requireApproval bool) (models.EmailDomain, error)
+	DeleteDomain(tenantID, domainID string) error
+	FindVerifiedDomain(domain string) (models.EmailDomain, error)
+	CreateJoinRequest(req models.DomainJoinRequest) (models.DomainJoinRequest, error)
+	ListJoinRequests(tenantID, status string) ([]models.DomainJoinRequest, error)
+	DecideJoinRequest(tenantID, requestID, status, decidedBy string) (models.DomainJoinRequest, error)
+	ActivateDomainMember(userID string) (bool, error)
+}
+
+type emailDomainRepository struct {
+	db *sql.DB
+}
+
+func NewEmailDomainRepository(db *sql.DB) EmailDomainRepository {
+	return &emailDomainRepository{db: db}
+}
+
+const emailDomainColumns = `id, tenant_id, domain, verification_token, require_approval, verified_at, created_by, created_at, updated_at`
+
+func scanEmailDomain(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.EmailDomain, error) {
+	var d models.EmailDomain
+	var createdBy sql.NullString
+	err := scanner.Scan(
+		&d.ID,
+		&d.TenantID,
+		&d.Domain,
+		&d.VerificationToken,
+		&d.RequireApproval,
+		&d.VerifiedAt,
+		&createdBy,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if createdBy.Valid {
+		d.CreatedBy = &createdBy.String
+	}
+	return d, err
+}
+
+func (r *emailDomainRepository) CreateDomain(domain models.EmailDomain) (models.EmailDomain, error) {
+	query := `
+		INSERT INTO tenant.email_domains (tenant_id, domain, verification_token, require_approval, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + emailDomainColumns
+	var createdBy interface{}
+	if domain.CreatedBy != nil {
+		createdBy = *domain.CreatedBy
+	}
+	return scanEmailDomain(r.db.QueryRow(query, domain.TenantID, domain.Domain, domain.VerificationToken, domain.RequireApproval, createdBy))
+}
+
+func (r *emailDomainRepository) ListDomains(tenantID string) ([]models.EmailDomain, error) {
+	query := `
+		SELECT ` + emailDomainColumns + `
+		FROM tenant.email_domains
+		WHERE tenant_id = $1
+		ORDER BY domain`
+	rows, err := r.db.Query(query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []models.EmailDomain{}
+	for rows.Next() {
+		d, err := scanEmailDomain(rows)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, d)
+	}
+	return domains, rows.Err()
+}
+
+func (r *emailDomainRepository) GetDomain(tenantID, domainID string) (models.EmailDomain, error) {
+	query := `
+		SELECT ` + emailDomainColumns + `
+		FROM tenant.email_domains
+		WHERE tenant_id = $1 AND id = $2`
+	return scanEmailDomain(r.db.QueryRow(query, tenantID, domainID))
+}
+
+// MarkDomainVerified records a successful DNS check. It fails with a unique violation
+// when another tenant has already verified the same domain.
+func (r *emailDomainRepository) MarkDomainVerified(tenantID, domainID string) (models.EmailDomain, error) {
+	query := `
+		UPDATE tenant.email_domains
+		SET verified_at = COALESCE(verified_at, now()), updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING ` + emailDomainColumns
+	return scanEmailDomain(r.db.QueryRow(query, tenantID, domainID))
+}
+
+func (r *emailDomainRepository) SetDomainApproval(tenantID, domainID string, requireApproval bool) (models.EmailDomain, error) {
+	query := `
+		UPDATE tenant.email_domains
+		SET require_approval = $3, updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING ` + emailDomainColumns
+	return scanEmailDomain(r.db.QueryRow(query, tenantID, domainID, requireApproval))
+}
+
+func (r *emailDomainRepository) DeleteDomain(tenantID, domainID string) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.email_domains WHERE tenant_id = $1 AND id = $2`, tenantID, domainID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FindVerifiedDomain returns the verified claim on domain of a tenant that is not
+// suspended, if any.
+func (r *emailDomainRepository) FindVerifiedDomain(domain string) (models.EmailDomain, error) {
+	query := `
+		SELECT ` + emailDomainColumns + `
+		FROM tenant.email_domains
+		WHERE domain = $1 AND verified_at IS NOT NULL
+		  AND tenant_id IN (SELECT id FROM tenant.tenants WHERE suspended_at IS NULL)`
+	return scanEmailDomain(r.db.QueryRow(query, domain))
+}
+
+const joinRequestSelect = `
+	SELECT j.id, j.tenant_id, j.user_id, u.email, j.domain, j.status, j.decided_by, j.decided_at, j.created_at
+	FROM tenant.domain_join_requests j
+	JOIN tenant.users u ON u.id = j.user_id`
+
+func scanJoinRequest(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.DomainJoinRequest, error) {
+	var req models.DomainJoinRequest
+	var decidedBy sql.NullString
+	err := scanner.Scan(
+		&req.ID,
+		&req.TenantID,
+		&req.UserID,
+		&req.Email,
+		&req.Domain,
+		&req.Status,
+		&decidedBy,
+		&req.DecidedAt,
+		&req.CreatedAt,
+	)
+	if decidedBy.Valid {
+		req.DecidedBy = &decidedBy.String
+	}
+	return req, err
+}
+
+func (r *emailDomainRepository) CreateJoinRequest(req models.DomainJoinRequest) (models.DomainJoinRequest, error) {
+	const query = `
+		INSERT INTO tenant.domain_join_requests (tenant_id, user_id, domain, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	err := r.db.QueryRow(query, req.TenantID, req.UserID, req.Domain, req.Status).Scan(&req.ID, &req.CreatedAt)
+	return req, err
+}
+
+// ListJoinRequests returns the tenant's domain joins, newest first. An empty status
+// returns every request.
+func (r *emailDomainRepository) ListJoinRequests(tenantID, status string) ([]models.DomainJoinRequest, error) {
+	query := joinRequestSelect + `
+		WHERE j.tenant_id = $1 AND ($2 = '' OR j.status = $2)
+		ORDER BY j.created_at DESC`
+	rows, err := r.db.Query(query, tenantID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.DomainJoinRequest{}
+	for rows.Next() {
+		req, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// DecideJoinRequest approves or rejects a pending request. Requests that were already
+// decided are reported as sql.ErrNoRows.
+func (r *emailDomainRepository) DecideJoinRequest(tenantID, requestID, status, decidedBy string) (models.DomainJoinRequest, error) {
+	const update = `
+		UPDATE tenant.domain_join_requests
+		SET status = $3, decided_by = $4, decided_at = now()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending'`
+	result, err := r.db.Exec(update, tenantID, requestID, status, decidedBy)
+	if err != nil {
+		return models.DomainJoinRequest{}, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return models.DomainJoinRequest{}, err
+	}
+	if rows == 0 {
+		return models.DomainJoinRequest{}, sql.ErrNoRows
+	}
+	return scanJoinRequest(r.db.QueryRow(joinRequestSelect+` WHERE j.tenant_id = $1 AND j.id = $2`, tenantID, requestID))
+}
+
+// ActivateDomainMember activates a user who joined through an email domain once
+// their address is verified and their join request approved. It reports whether the
+// user was activated.
+func (r *emailDomainRepository) ActivateDomainMember(userID string) (bool, error) {
+	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
+		UPDATE tenant.users u
+		SET is_active = TRUE, updated_at = now()
+		WHERE u.id = $1
+		  AND u.deleted_at IS NULL
+		  AND NOT u.is_active
+		  AND u.email_verified_at IS NOT NULL
+		  AND EXISTS (
+		      SELECT 1 FROM tenant.domain_join_requests j
+		      WHERE j.user_id = u.id AND j.status = 'approved'
+		  )`
+	result, err := r.db.Exec(query, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type EngineRepository interface {
+	// RecordEngineCapabilities stores the format pairs of an engine version,
+	// replacing the pairs recorded for it before. current marks it as the version of
+	// the running engine, which definitions are checked against.
+	RecordEngineCapabilities(caps models.EngineCapabilities, current bool) error
+	// GetCompatibilityMatrix returns the format pairs of every discovered engine
+	// version.
+	GetCompatibilityMatrix() (models.CompatibilityMatrix, error)
+}
+
+type engineRepository struct {
+	db *sql.DB
+}
+
+func NewEngineRepository(db *sql.DB) EngineRepository {
+	return &engineRepository{db: db}
+}
+
+func (r *engineRepository) RecordEngineCapabilities(caps models.EngineCapabilities, current bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const versionQuery = `
+		INSERT INTO tenant.engine_versions (version, discovered_at)
+		VALUES ($1, now())
+		ON CONFLICT (version) DO UPDATE SET discovered_at = EXCLUDED.discovered_at
+	`
+	if _, err := tx.Exec(versionQuery, caps.Version); err != nil {
+		return err
+	}
+	if current {
+		// Cleared first, so the unique index on the current version never sees two.
+		if _, err := tx.Exec(`UPDATE tenant.engine_versions SET current = FALSE WHERE current AND version <> $1`, caps.Version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE tenant.engine_versions SET current = TRUE WHERE version = $1`, caps.Version); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM tenant.engine_format_support WHERE engine_version = $1`, caps.Version); err != nil {
+		return err
+	}
+	const pairQuery = `
+		INSERT INTO tenant.engine_format_support (engine_version, source_format, destination_format)
+		VALUES ($1, $2, $3)
+	`
+	for _, pair := range caps.Pairs {
+		if _, err := tx.Exec(pairQuery, caps.Version, pair.Source, pair.Destination); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *engineRepository) GetCompatibilityMatrix() (models.CompatibilityMatrix, error) {
+	matrix := models.CompatibilityMatrix{Entries: []models.EngineFormatSupport{}}
+	var discoveredAt time.Time
+	err := r.db.QueryRow(`SELECT version, discovered_at FROM tenant.engine_versions WHERE current`).
+		Scan(&matrix.CurrentVersion, &discoveredAt)
+	switch {
+	case err == nil:
+		matrix.DiscoveredAt = &discoveredAt
+	case err != sql.ErrNoRows:
+		return matrix, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT engine_version, source_format, destination_format
+		FROM tenant.engine_format_support
+		ORDER BY source_format, destination_format, engine_version
+	`)
+	if err != nil {
+		return matrix, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry models.EngineFormatSupport
+		if err := rows.Scan(&entry.EngineVersion, &entry.SourceFormat, &entry.DestinationFormat); err != nil {
+			return matrix, err
+		}
+		matrix.Entries = append(matrix.Entries, entry)
+	}
+	return matrix, rows.Err()
+}
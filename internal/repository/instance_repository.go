@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/stanstork/stratum-api/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrSetupLocked is returned by Bootstrap once the instance has been set up.
+var ErrSetupLocked = errors.New("instance setup is locked")
+
+// BootstrapInput is what first-run setup creates: the instance settings, the first
+// tenant and its super-admin.
+type BootstrapInput struct {
+	InstanceName string
+	TenantName   string
+	Email        string
+	Password     string
+	FirstName    string
+	LastName     string
+}
+
+// onlySeedDataCondition holds while the database has nothing but what the initial
+// migrations seed: the default tenant and its admins, with no connections or
+// definitions.
+const onlySeedDataCondition = `
+	NOT EXISTS (SELECT 1 FROM tenant.tenants WHERE name <> $1)
+	AND NOT EXISTS (SELECT 1 FROM tenant.users WHERE email <> ALL($2))
+	AND NOT EXISTS (SELECT 1 FROM tenant.connections)
+	AND NOT EXISTS (SELECT 1 FROM tenant.job_definitions)`
+
+type InstanceRepository interface {
+	// GetInstance returns sql.ErrNoRows until setup has completed.
+	GetInstance() (models.Instance, error)
+	// SetupRequired reports whether setup is still open: the instance has no
+	// settings and no data beyond the seeded default tenant.
+	SetupRequired() (bool, error)
+	// Bootstrap creates the first tenant and super-admin, replacing the seeded default
+	// tenant, and locks setup, all in one transaction. It returns ErrSetupLocked when
+	// setup already ran or the instance holds more than the seed data.
+	Bootstrap(input BootstrapInput) (models.Instance, models.Tenant, models.User, error)
+}
+
+type instanceRepository struct {
+	db *sql.DB
+}
+
+func NewInstanceRepository(db *sql.DB) InstanceRepository {
+	return &instanceRepository{db: db}
+}
+
+func (r *instanceRepository) GetInstance() (models.Instance, error) {
+	var inst models.Instance
+	err := r.db.QueryRow(`
+		SELECT name, setup_tenant_id, setup_user_id, setup_completed_at
+		FROM tenant.instance_settings`).
+		Scan(&inst.Name, &inst.SetupTenantID, &inst.SetupUserID, &inst.SetupCompletedAt)
+	return inst, err
+}
+
+func (r *instanceRepository) SetupRequired() (bool, error) {
+	var required bool
+	err := r.db.QueryRow(`
+		SELECT NOT EXISTS (SELECT 1 FROM tenant.instance_settings)
+		   AND `+onlySeedDataCondition, models.SeedTenantName, pq.Array(models.SeedUserEmails)).Scan(&required)
+	return required, err
+}
+
+func (r *instanceRepository) Bootstrap(input BootstrapInput) (models.Instance, models.Tenant, models.User, error) {
+	var (
+		inst   models.Instance
+		tenant models.Tenant
+		user   models.User
+	)
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return inst, tenant, user, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return inst, tenant, user, err
+	}
+	defer tx.Rollback()
+
+	// The settings row is claimed first: a concurrent setup blocks on it and then
+	// finds setup locked.
+	res, err := tx.Exec(`
+		INSERT INTO tenant.instance_settings (name)
+		VALUES ($1)
+		ON CONFLICT (id) DO NOTHING`, input.InstanceName)
+	if err != nil {
+		return inst, tenant, user, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return inst, tenant, user, err
+	} else if n == 0 {
+		return inst, tenant, user, ErrSetupLocked
+	}
+	var onlySeedData bool
+	if err := tx.QueryRow(`SELECT `+onlySeedDataCondition, models.SeedTenantName, pq.Array(models.SeedUserEmails)).Scan(&onlySeedData); err != nil {
+		return inst, tenant, user, err
+	}
+	if !onlySeedData {
+		return inst, tenant, user, ErrSetupLocked
+	}
+	// The seeded tenant and its well-known admin passwords make way for the ones
+	// chosen here; everything in the tenant goes with it.
+	if _, err := tx.Exec(`DELETE FROM tenant.tenants WHERE name = $1`, models.SeedTenantName); err != nil {
+		return inst, tenant, user, err
+	}
+
+	tenant, err = scanTenant(tx.QueryRow(`
+		INSERT INTO tenant.tenants (name)
+		VALUES ($1)
+		RETURNING `+tenantColumns, input.TenantName))
+	if err != nil {
+		return inst, tenant, user, err
+	}
+
+	// The super-admin chose their own address during setup, so it counts as verified.
+	user = models.User{
+		TenantID:     tenant.ID,
+		Email:        input.Email,
+		FirstName:    strings.TrimSpace(input.FirstName),
+		LastName:     strings.TrimSpace(input.LastName),
+		PasswordHash: string(hash),
+		IsActive:     true,
+		Roles:        models.EnsureDefaultRole(models.NormalizeRoles([]models.UserRole{models.RoleSuperAdmin})),
+	}
+	err = tx.QueryRow(`
+		INSERT INTO tenant.users (tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6, now())
+		RETURNING id, email_verified_at`,
+		user.TenantID, user.Email, user.FirstName, user.LastName, user.PasswordHash, pq.Array(toStringSlice(user.Roles))).
+		Scan(&user.ID, &user.EmailVerifiedAt)
+	if err != nil {
+		return inst, tenant, user, err
+	}
+
+	err = tx.QueryRow(`
+		UPDATE tenant.instance_settings
+		SET setup_tenant_id = $1, setup_user_id = $2, setup_completed_at = now()
+		RETURNING name, setup_tenant_id, setup_user_id, setup_completed_at`, tenant.ID, user.ID).
+		Scan(&inst.Name, &inst.SetupTenantID, &inst.SetupUserID, &inst.SetupCompletedAt)
+	if err != nil {
+		return inst, tenant, user, err
+	}
+	return inst, tenant, user, tx.Commit()
+}
@@ -2,17 +2,35 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/stanstork/stratum-api/internal/models"
 )
 
 type InviteRepository interface {
+	// CreateInvite stores the invite with its email queued for delivery at
+	// invite.NextDeliveryAt; invite.DeliveryToken carries the encrypted raw token.
 	CreateInvite(invite models.Invite) (models.Invite, error)
+	// CreateInvites stores all of the invites or, on any error, none of them.
+	CreateInvites(invites []models.Invite) ([]models.Invite, error)
 	GetInviteByTokenHash(tokenHash string) (models.Invite, error)
+	GetInvite(inviteID, tenantID string) (models.Invite, error)
 	MarkInviteAccepted(inviteID string) (models.Invite, error)
 	ListInvitesByTenant(tenantID string) ([]models.Invite, error)
 	CancelInvite(inviteID, tenantID string) error
+
+	// ClaimDueInviteDeliveries returns pending invite emails whose next attempt is
+	// due and hides them from other claimers for lease.
+	ClaimDueInviteDeliveries(limit int, lease time.Duration) ([]models.Invite, error)
+	MarkInviteDelivered(inviteID string) error
+	// MarkInviteDeliveryFailed records a failed attempt. A nil retryAt gives up on
+	// the email.
+	MarkInviteDeliveryFailed(inviteID, errMsg string, retryAt *time.Time) error
+	// RequeueInviteDelivery replaces the invite's token and queues its email again
+	// with a fresh attempt budget. It returns sql.ErrNoRows for invites that are
+	// accepted or cancelled.
+	RequeueInviteDelivery(inviteID, tenantID, tokenHash string, deliveryToken []byte, nextAt time.Time) (models.Invite, error)
 }
 
 type inviteRepository struct {
@@ -23,30 +41,18 @@ func NewInviteRepository(db *sql.DB) InviteRepository {
 	return &inviteRepository{db: db}
 }
 
-func (r *inviteRepository) CreateInvite(invite models.Invite) (models.Invite, error) {
-	const query = `
-		INSERT INTO tenant.invites (tenant_id, email, roles, token_hash, created_by, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, tenant_id, email, roles, token_hash, created_by, created_at, updated_at, expires_at, accepted_at;
-	`
+const inviteColumns = `id, tenant_id, email, roles, token_hash, created_by, created_at, updated_at, expires_at, accepted_at,
+	delivery_status, delivery_attempts, delivery_error, next_delivery_at, delivered_at, delivery_token`
 
+func scanInvite(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.Invite, error) {
 	var (
+		invite    models.Invite
 		roles     pq.StringArray
 		createdBy sql.NullString
 	)
-	var createdByValue interface{}
-	if invite.CreatedBy != nil && *invite.CreatedBy != "" {
-		createdByValue = *invite.CreatedBy
-	}
-
-	err := r.db.QueryRow(query,
-		invite.TenantID,
-		invite.Email,
-		pq.Array(toStringSlice(invite.Roles)),
-		invite.TokenHash,
-		createdByValue,
-		invite.ExpiresAt,
-	).Scan(
+	if err := scanner.Scan(
 		&invite.ID,
 		&invite.TenantID,
 		&invite.Email,
@@ -57,101 +63,106 @@ func (r *inviteRepository) CreateInvite(invite models.Invite) (models.Invite, er
 		&invite.UpdatedAt,
 		&invite.ExpiresAt,
 		&invite.AcceptedAt,
-	)
-	if err != nil {
+		&invite.DeliveryStatus,
+		&invite.DeliveryAttempts,
+		&invite.DeliveryError,
+		&invite.NextDeliveryAt,
+		&invite.DeliveredAt,
+		&invite.DeliveryToken,
+	); err != nil {
 		return models.Invite{}, err
 	}
 
 	invite.Roles = toUserRoleSlice(roles)
 	if createdBy.Valid {
 		invite.CreatedBy = &createdBy.String
-	} else {
-		invite.CreatedBy = nil
 	}
-
 	return invite, nil
 }
 
-func (r *inviteRepository) GetInviteByTokenHash(tokenHash string) (models.Invite, error) {
-	const query = `
-		SELECT id, tenant_id, email, roles, token_hash, created_by, created_at, updated_at, expires_at, accepted_at
-		FROM tenant.invites
-		WHERE token_hash = $1 AND deleted_at IS NULL;
-	`
+func (r *inviteRepository) CreateInvite(invite models.Invite) (models.Invite, error) {
+	return createInvite(r.db, invite)
+}
 
-	var (
-		invite    models.Invite
-		roles     pq.StringArray
-		createdBy sql.NullString
-	)
-	err := r.db.QueryRow(query, tokenHash).Scan(
-		&invite.ID,
-		&invite.TenantID,
-		&invite.Email,
-		&roles,
-		&invite.TokenHash,
-		&createdBy,
-		&invite.CreatedAt,
-		&invite.UpdatedAt,
-		&invite.ExpiresAt,
-		&invite.AcceptedAt,
-	)
+func (r *inviteRepository) CreateInvites(invites []models.Invite) ([]models.Invite, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return models.Invite{}, err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	invite.Roles = toUserRoleSlice(roles)
-	if createdBy.Valid {
-		invite.CreatedBy = &createdBy.String
-	} else {
-		invite.CreatedBy = nil
+	created := make([]models.Invite, 0, len(invites))
+	for _, invite := range invites {
+		inv, err := createInvite(tx, invite)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, inv)
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
 
-	return invite, nil
+func createInvite(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, invite models.Invite) (models.Invite, error) {
+	query := `
+		INSERT INTO tenant.invites (tenant_id, email, roles, token_hash, created_by, expires_at,
+			delivery_status, delivery_token, next_delivery_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', $7, $8)
+		RETURNING ` + inviteColumns
+
+	var createdByValue interface{}
+	if invite.CreatedBy != nil && *invite.CreatedBy != "" {
+		createdByValue = *invite.CreatedBy
+	}
+
+	return scanInvite(q.QueryRow(query,
+		invite.TenantID,
+		invite.Email,
+		pq.Array(toStringSlice(invite.Roles)),
+		invite.TokenHash,
+		createdByValue,
+		invite.ExpiresAt,
+		invite.DeliveryToken,
+		invite.NextDeliveryAt,
+	))
+}
+
+func (r *inviteRepository) GetInviteByTokenHash(tokenHash string) (models.Invite, error) {
+	query := `
+		/* cross-tenant: the invite token identifies the tenant */
+		SELECT ` + inviteColumns + `
+		FROM tenant.invites
+		WHERE token_hash = $1 AND deleted_at IS NULL;
+	`
+	return scanInvite(r.db.QueryRow(query, tokenHash))
+}
+
+func (r *inviteRepository) GetInvite(inviteID, tenantID string) (models.Invite, error) {
+	query := `
+		SELECT ` + inviteColumns + `
+		FROM tenant.invites
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
+	`
+	return scanInvite(r.db.QueryRow(query, inviteID, tenantID))
 }
 
 func (r *inviteRepository) MarkInviteAccepted(inviteID string) (models.Invite, error) {
-	const query = `
+	query := `
+		/* cross-tenant: accepted by token, see GetInviteByTokenHash */
 		UPDATE tenant.invites
 		SET accepted_at = now(), updated_at = now()
 		WHERE id = $1 AND accepted_at IS NULL AND deleted_at IS NULL
-		RETURNING id, tenant_id, email, roles, token_hash, created_by, created_at, updated_at, expires_at, accepted_at;
-	`
-
-	var (
-		invite    models.Invite
-		roles     pq.StringArray
-		createdBy sql.NullString
-	)
-	err := r.db.QueryRow(query, inviteID).Scan(
-		&invite.ID,
-		&invite.TenantID,
-		&invite.Email,
-		&roles,
-		&invite.TokenHash,
-		&createdBy,
-		&invite.CreatedAt,
-		&invite.UpdatedAt,
-		&invite.ExpiresAt,
-		&invite.AcceptedAt,
-	)
-	if err != nil {
-		return models.Invite{}, err
-	}
-
-	invite.Roles = toUserRoleSlice(roles)
-	if createdBy.Valid {
-		invite.CreatedBy = &createdBy.String
-	} else {
-		invite.CreatedBy = nil
-	}
-
-	return invite, nil
+		RETURNING ` + inviteColumns
+	return scanInvite(r.db.QueryRow(query, inviteID))
 }
 
 func (r *inviteRepository) ListInvitesByTenant(tenantID string) ([]models.Invite, error) {
-	const query = `
-		SELECT id, tenant_id, email, roles, token_hash, created_by, created_at, updated_at, expires_at, accepted_at
+	query := `
+		SELECT ` + inviteColumns + `
 		FROM tenant.invites
 		WHERE tenant_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC;
@@ -165,33 +176,10 @@ func (r *inviteRepository) ListInvitesByTenant(tenantID string) ([]models.Invite
 
 	var invites []models.Invite
 	for rows.Next() {
-		var (
-			invite    models.Invite
-			roles     pq.StringArray
-			createdBy sql.NullString
-		)
-		if err := rows.Scan(
-			&invite.ID,
-			&invite.TenantID,
-			&invite.Email,
-			&roles,
-			&invite.TokenHash,
-			&createdBy,
-			&invite.CreatedAt,
-			&invite.UpdatedAt,
-			&invite.ExpiresAt,
-			&invite.AcceptedAt,
-		); err != nil {
+		invite, err := scanInvite(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		invite.Roles = toUserRoleSlice(roles)
-		if createdBy.Valid {
-			invite.CreatedBy = &createdBy.String
-		} else {
-			invite.CreatedBy = nil
-		}
-
 		invites = append(invites, invite)
 	}
 
@@ -205,7 +193,9 @@ func (r *inviteRepository) ListInvitesByTenant(tenantID string) ([]models.Invite
 func (r *inviteRepository) CancelInvite(inviteID, tenantID string) error {
 	const query = `
 		UPDATE tenant.invites
-		SET deleted_at = now(), updated_at = now()
+		SET deleted_at = now(), updated_at = now(),
+		    delivery_token = NULL,
+		    delivery_status = CASE WHEN delivery_status = 'pending' THEN 'failed' ELSE delivery_status END
 		WHERE id = $1 AND tenant_id = $2 AND accepted_at IS NULL AND deleted_at IS NULL;
 	`
 
@@ -224,3 +214,76 @@ func (r *inviteRepository) CancelInvite(inviteID, tenantID string) error {
 
 	return nil
 }
+
+func (r *inviteRepository) ClaimDueInviteDeliveries(limit int, lease time.Duration) ([]models.Invite, error) {
+	query := `
+		/* cross-tenant: invite delivery serves every tenant */
+		WITH due AS (
+			SELECT id AS due_id
+			FROM tenant.invites
+			WHERE delivery_status = 'pending' AND next_delivery_at <= now()
+			  AND accepted_at IS NULL AND deleted_at IS NULL
+			ORDER BY next_delivery_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE tenant.invites i
+		SET next_delivery_at = now() + make_interval(secs => $2)
+		FROM due
+		WHERE i.id = due.due_id
+		RETURNING ` + inviteColumns
+	rows, err := r.db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+func (r *inviteRepository) MarkInviteDelivered(inviteID string) error {
+	_, err := r.db.Exec(`
+		/* cross-tenant: invite delivery serves every tenant */
+		UPDATE tenant.invites
+		SET delivery_status = 'sent', delivery_attempts = delivery_attempts + 1, delivery_error = NULL,
+		    delivered_at = now(), next_delivery_at = NULL, delivery_token = NULL, updated_at = now()
+		WHERE id = $1`, inviteID)
+	return err
+}
+
+func (r *inviteRepository) MarkInviteDeliveryFailed(inviteID, errMsg string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = r.db.Exec(`
+			/* cross-tenant: invite delivery serves every tenant */
+			UPDATE tenant.invites
+			SET delivery_attempts = delivery_attempts + 1, delivery_error = $2, next_delivery_at = $3, updated_at = now()
+			WHERE id = $1`, inviteID, errMsg, *retryAt)
+	} else {
+		_, err = r.db.Exec(`
+			/* cross-tenant: invite delivery serves every tenant */
+			UPDATE tenant.invites
+			SET delivery_status = 'failed', delivery_attempts = delivery_attempts + 1, delivery_error = $2,
+			    next_delivery_at = NULL, delivery_token = NULL, updated_at = now()
+			WHERE id = $1`, inviteID, errMsg)
+	}
+	return err
+}
+
+func (r *inviteRepository) RequeueInviteDelivery(inviteID, tenantID, tokenHash string, deliveryToken []byte, nextAt time.Time) (models.Invite, error) {
+	query := `
+		UPDATE tenant.invites
+		SET token_hash = $3, delivery_token = $4, next_delivery_at = $5,
+		    delivery_status = 'pending', delivery_attempts = 0, delivery_error = NULL, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND accepted_at IS NULL AND deleted_at IS NULL
+		RETURNING ` + inviteColumns
+	return scanInvite(r.db.QueryRow(query, inviteID, tenantID, tokenHash, deliveryToken, nextAt))
+}
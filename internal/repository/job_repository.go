@@ -1,39 +1,228 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
+	"github.com/stanstork/stratum-api/internal/blobstore"
 	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/utils"
 )
 
 var ErrJobDefinitionNotReady = errors.New("job definition not ready")
 
+// ErrExecutionActive is returned when a definition that already has a pending,
+// running or paused execution is started again without allowing concurrent runs.
+var ErrExecutionActive = errors.New("job definition has an active execution")
+
+// ErrRunGrantUserNotFound is returned when a run grant names a user outside the tenant.
+var ErrRunGrantUserNotFound = errors.New("run grant user not found in tenant")
+
 type JobRepository interface {
 	// JobDefinition methods
 	CrateDefinition(def models.JobDefinition) (models.JobDefinition, error)
 	GetJobDefinitionByID(tenantID, jobDefID string) (models.JobDefinition, error)
+	// GetDefinitionASTPath returns the subtree of the definition's AST at path, or
+	// nil when the AST has nothing there.
+	GetDefinitionASTPath(tenantID, jobDefID string, path []string) (json.RawMessage, error)
+	// GetDefinitionASTTables returns the entries of the AST's table list that name
+	// one of tables, in AST order, as a JSON array.
+	GetDefinitionASTTables(tenantID, jobDefID string, tables []string) (json.RawMessage, error)
+	// ListDefinitionASTTables returns the names of the tables the AST lists, in order.
+	ListDefinitionASTTables(tenantID, jobDefID string) ([]string, error)
 	ListDefinitions(tenantID string) ([]models.JobDefinition, error)
 	UpdateDefinition(tenantID, jobDefID string, update DefinitionUpdate) (models.JobDefinition, error)
 	DeleteDefinition(tenantID, jobDefID string) error
 	ListJobDefinitionsWithStats(tenantID string) ([]models.JobDefinitionStat, error)
+	DefinitionsVersion(tenantID string) (string, error)
+	DemoteReadyDefinition(tenantID, jobDefID string) (bool, error)
+	// ArchiveDefinition moves a definition to ARCHIVED, and UnarchiveDefinition back
+	// to the status it had before. Both report false when the definition was not in
+	// the expected state.
+	ArchiveDefinition(tenantID, jobDefID string) (bool, error)
+	UnarchiveDefinition(tenantID, jobDefID string) (bool, error)
+	// ImportTenant creates the connections, definitions, definition secrets and
+	// schedules of a validated import in one transaction.
+	ImportTenant(tenantID string, bundle TenantImport) error
+
+	// Run grant methods
+	GetRunGrants(tenantID, jobDefID string) (models.RunGrants, error)
+
+	// GetSchedule returns sql.ErrNoRows when the definition is not scheduled.
+	GetSchedule(tenantID, jobDefID string) (models.JobSchedule, error)
+	// ListSchedules returns the schedules of the tenant's live definitions.
+	ListSchedules(tenantID string) ([]models.JobSchedule, error)
+	SaveSchedule(schedule models.JobSchedule) (models.JobSchedule, error)
+	DeleteSchedule(tenantID, jobDefID string) error
+	// SetSchedulePaused records the schedule as paused for the connection's
+	// credential rotation, or as running again when connectionID is nil.
+	SetSchedulePaused(tenantID, jobDefID string, connectionID *string) error
+	SetRunGrants(tenantID, jobDefID string, grants models.RunGrants, grantedBy string) (models.RunGrants, error)
+
+	// Definition secret methods
+	SetDefinitionSecrets(tenantID, jobDefID string, secrets map[string]string) error
+	ListDefinitionSecrets(tenantID, jobDefID string) ([]models.DefinitionSecret, error)
+	DeleteDefinitionSecret(tenantID, jobDefID, name string) error
+	GetDefinitionSecretValues(tenantID, jobDefID string) (map[string]string, error)
+
+	// Revalidation queue methods
+	EnqueueDefinitionRevalidations(tenantID, connectionID string) (int64, error)
+	ListDefinitionRevalidations(limit int) ([]models.DefinitionRevalidation, error)
+	CompleteDefinitionRevalidation(rev models.DefinitionRevalidation) error
+	RecordDefinitionRevalidationError(jobDefID, message string) error
+
+	// Schema freshness methods
+	// ListSchemaCheckDefinitions returns, across all tenants that check validation
+	// freshness, the READY engine definitions not checked since checkedBefore,
+	// never-checked ones first.
+	ListSchemaCheckDefinitions(checkedBefore time.Time, limit int) ([]models.JobDefinition, error)
+	// RecordDefinitionSchemaCheck compares schemaHash with the baseline of the
+	// definition as it was at updatedAt, recording it as the baseline when there is
+	// none. It reports whether the definition has just been flagged stale.
+	RecordDefinitionSchemaCheck(tenantID, jobDefID string, updatedAt time.Time, schemaHash string) (bool, error)
+	// ResetDefinitionSchemaCheck discards the definition's baseline, so the next
+	// check records a new one.
+	ResetDefinitionSchemaCheck(tenantID, jobDefID string) error
 
 	// JobExecution methods
-	CreateExecution(tenantID, jobDefID, executionID string) (models.JobExecution, error)
+	// CreateExecution records a pending execution, or moves an approved run
+	// awaiting approval with the same ID to pending. Unless allowConcurrent is set
+	// it fails with ErrExecutionActive while the definition has an active execution.
+	CreateExecution(tenantID, jobDefID, executionID, workflowID, triggeredBy string, triggerContext map[string]string, allowConcurrent bool) (models.JobExecution, error)
+	// GetActiveDefinitionExecution returns the oldest pending, running or paused
+	// execution of a definition, or sql.ErrNoRows when it has none.
+	GetActiveDefinitionExecution(tenantID, jobDefID string) (models.JobExecution, error)
+	// GetAwaitingApprovalExecution returns the oldest run of a definition held for
+	// approval, or sql.ErrNoRows when it has none.
+	GetAwaitingApprovalExecution(tenantID, jobDefID string) (models.JobExecution, error)
 	GetLastExecution(tenantID, jobDefID string) (models.JobExecution, error)
-	UpdateExecution(tenantID, execID string, status string, errorMessage string, logs string) (int64, error)
-	ListExecutions(tenantID string, limit, offset int) ([]models.JobExecution, error)
-	ListExecutionStats(tenantID string, days int) (models.ExecutionStat, error)
+	// UpdateExecution records a status change. Logs given with a final status are
+	// stored without ANSI escapes and split into chunks by level.
+	UpdateExecution(tenantID, execID string, status models.ExecutionStatus, errorMessage string, logs string) (int64, error)
+	// ListExecutions returns the tenant's executions, newest first. An empty
+	// triggeredBy lists executions of every trigger.
+	ListExecutions(tenantID, triggeredBy string, limit, offset int) ([]models.JobExecution, error)
+	ListExecutionStats(tenantID string, days int, tz string) (models.ExecutionStat, error)
+	ListExecutionSeries(tenantID string, from, to time.Time, bucket time.Duration) ([]models.ExecutionSeriesPoint, error)
+	// ListMonthlyStats returns the executions per month from the month of from up to
+	// the month of to, of the whole tenant or, with jobDefID, of one definition.
+	ListMonthlyStats(tenantID, jobDefID string, from, to time.Time) ([]models.ExecutionMonthStat, error)
+	// RefreshMonthlyRollups recomputes the monthly rollups of the months that may
+	// still change and returns how many rollup rows it wrote.
+	RefreshMonthlyRollups() (int64, error)
+	// ListCalendarExecutions returns up to limit executions placed between from and
+	// to, oldest first.
+	ListCalendarExecutions(tenantID string, from, to time.Time, limit int) ([]models.CalendarEvent, error)
 	GetExecution(tenantID, execID string) (models.JobExecution, error)
-	SetExecutionComplete(tenantID, execID string, status string, recordsProcessed int64, bytesTransferred int64) error
+	SetExecutionComplete(tenantID, execID string, status models.ExecutionStatus, recordsProcessed int64, bytesTransferred int64) error
+	// SetExecutionPaused moves a running execution to paused, or a paused one back to
+	// running. It reports false when the execution was not in the expected status.
+	SetExecutionPaused(tenantID, execID string, paused bool) (bool, error)
+	// FailActiveExecution marks the execution failed if it is still pending, running
+	// or paused and reports whether it did.
+	FailActiveExecution(tenantID, execID, errorMessage string) (bool, error)
+	// CancelPendingExecution marks the execution cancelled if it is still pending and
+	// reports whether it did.
+	CancelPendingExecution(tenantID, execID, errorMessage string) (bool, error)
+	// ListActiveExecutions returns up to limit pending, running or paused executions of all
+	// tenants last updated before updatedBefore, oldest first.
+	ListActiveExecutions(updatedBefore time.Time, limit int) ([]models.JobExecution, error)
+	// ListConnectionActiveExecutions returns the pending, running or paused executions
+	// of definitions that read from or write to the connection, oldest first.
+	ListConnectionActiveExecutions(tenantID, connectionID string) ([]models.JobExecution, error)
+	// ListTenantActiveExecutions returns the tenant's pending, running or paused
+	// executions, oldest first.
+	ListTenantActiveExecutions(tenantID string) ([]models.JobExecution, error)
+	// AverageDefinitionDurations returns the average run duration in seconds of each
+	// of the tenant's definitions that has timed runs.
+	AverageDefinitionDurations(tenantID string) (map[string]float64, error)
+	CreateExecutionSnapshot(snapshot models.ExecutionSnapshot) error
+	// SetExecutionSnapshotImageDigest records the engine image digest of a snapshot
+	// written without one. A digest already recorded is kept.
+	SetExecutionSnapshotImageDigest(tenantID, execID, digest string) error
+	GetExecutionSnapshot(tenantID, execID string) (models.ExecutionSnapshot, error)
+	SaveExecutionArtifact(artifact models.ExecutionArtifact) (models.ExecutionArtifact, error)
+	GetExecutionArtifact(tenantID, execID, kind string) (models.ExecutionArtifact, error)
+	ListExecutionArtifacts(tenantID, execID string) ([]models.ExecutionArtifact, error)
+	SaveExecutionCheckpoints(tenantID, execID string, checkpoints []models.ExecutionCheckpoint) error
+	ListExecutionCheckpoints(tenantID, execID string) ([]models.ExecutionCheckpoint, error)
+	ResumeExecutionFrom(tenantID, execID, fromExecID string) ([]models.ExecutionCheckpoint, error)
+	// SaveExecutionTables records the per-table results the engine reported for an
+	// execution, replacing earlier results for the same tables.
+	SaveExecutionTables(tenantID, execID string, tables []models.JobExecutionTable) error
+	ListExecutionTables(tenantID, execID string) ([]models.JobExecutionTable, error)
+	// ListExecutionLogChunks returns an execution's stored log chunks in order.
+	ListExecutionLogChunks(tenantID, execID string) ([]models.ExecutionLogChunk, error)
+	CreateExecutionNote(note models.ExecutionNote) (models.ExecutionNote, error)
+	ListExecutionNotes(tenantID, execID string) ([]models.ExecutionNote, error)
+	SearchExecutionNotes(tenantID, query string, limit int) ([]models.ExecutionNote, error)
+	DeleteExecutionNote(tenantID, execID, noteID string) error
+
+	// Regression methods
+	GetExecutionBaseline(tenantID, jobDefID, excludeExecID string, runs int) (models.ExecutionBaseline, error)
+	SaveExecutionRegressions(regressions []models.ExecutionRegression) error
+	ListExecutionRegressions(tenantID, jobDefID string, limit int) ([]models.ExecutionRegression, error)
+	CountDataMovingRuns(tenantID, jobDefID, excludeExecID string) (int, error)
+	MarkExecutionSuspect(tenantID, execID, reason string) error
+	// CreateAwaitingExecution records a run held for approval together with the
+	// workflow input it starts with once approved.
+	CreateAwaitingExecution(tenantID, jobDefID, executionID, triggeredBy string, triggerContext map[string]string, request json.RawMessage) (models.JobExecution, error)
+	// ClaimExecutionApproval records approvedBy as the approver of a run awaiting
+	// approval and returns the workflow input it was held with. It fails with
+	// sql.ErrNoRows unless the run awaits approval and nobody approved it yet.
+	// CreateExecution moves the approved run to pending once its workflow starts.
+	ClaimExecutionApproval(tenantID, execID, approvedBy string) (json.RawMessage, error)
+	// ReleaseExecutionApproval undoes ClaimExecutionApproval when the approved run's
+	// workflow could not be started.
+	ReleaseExecutionApproval(tenantID, execID string) error
+	// ListDeadLetterExecutions returns up to limit of the tenant's failed executions
+	// that were not requeued yet, newest first.
+	ListDeadLetterExecutions(tenantID string, limit int) ([]models.JobExecution, error)
+	// MarkExecutionRequeued records that a failed execution was requeued as
+	// requeuedAsID. It reports false when the execution is not failed or was
+	// already requeued.
+	MarkExecutionRequeued(tenantID, execID, requeuedAsID string) (bool, error)
+	// ClearExecutionRequeued undoes MarkExecutionRequeued when the run it recorded
+	// could not be started.
+	ClearExecutionRequeued(tenantID, execID, requeuedAsID string) error
+	RecordActivityAttempt(tenantID, execID, activity string, attempt int32) error
+	// RecordExecutionEvent appends an event to an execution's timeline.
+	RecordExecutionEvent(tenantID, execID string, event models.ExecutionEvent) error
+	// ListExecutionEvents returns an execution's timeline, oldest first.
+	ListExecutionEvents(tenantID, execID string) ([]models.ExecutionEvent, error)
+	// RecordStartupStage stores when an execution completed a startup stage.
+	RecordStartupStage(tenantID, execID, stage string, at time.Time) error
+	// ListStartupTimings returns the startup stage timestamps of executions created
+	// since the given time, of one tenant or, when tenantID is empty, of all tenants.
+	ListStartupTimings(tenantID string, since time.Time) ([]map[string]time.Time, error)
+
+	// CreateRunGroup records a run group and its members, all pending. Members must
+	// carry their execution IDs.
+	CreateRunGroup(group models.RunGroup) (models.RunGroup, error)
+	// GetRunGroup returns the group with the current status of every member; it
+	// returns sql.ErrNoRows when the tenant has no such group.
+	GetRunGroup(tenantID, groupID string) (models.RunGroup, error)
+	// SkipRunGroupMembers marks the members that were to run as the given executions
+	// skipped.
+	SkipRunGroupMembers(tenantID, groupID string, executionIDs []string) error
+	// CompleteRunGroup records the outcome and report of a finished group.
+	CompleteRunGroup(tenantID, groupID string, report models.RunGroupReport) error
+	// GetRunGroupReport returns the report of a finished group, or sql.ErrNoRows
+	// while it runs.
+	GetRunGroupReport(tenantID, groupID string) (models.RunGroupReport, error)
 }
 
 type jobRepository struct {
 	db *sql.DB
+	// blobs holds ASTs, progress snapshots and artifacts; rows keep only their hash.
+	blobs blobstore.Store
 }
 
 type DefinitionUpdate struct {
@@ -42,20 +231,22 @@ type DefinitionUpdate struct {
 	AST                     *json.RawMessage
 	SourceConnectionID      *string
 	DestinationConnectionID *string
-	Status                  *string
+	Status                  *models.DefinitionStatus
 	ProgressSnapshot        *json.RawMessage
+	// RetryPolicy replaces the stored policy; a zero policy clears it.
+	RetryPolicy  *models.RetryPolicy
+	WriteMode    *string
+	Verification *string
 }
 
-const (
-	definitionStatusDraft      = "DRAFT"
-	definitionStatusValidating = "VALIDATING"
-	definitionStatusReady      = "READY"
-)
-
-var allowedDefinitionStatuses = map[string]struct{}{
-	definitionStatusDraft:      {},
-	definitionStatusValidating: {},
-	definitionStatusReady:      {},
+// TenantImport is a validated tenant import. Rows carry the IDs they are created
+// with so definitions and schedules can reference rows of the same import.
+type TenantImport struct {
+	Connections []models.Connection
+	Definitions []models.JobDefinition
+	// Secrets holds the inline AST secret values of each definition, by definition ID.
+	Secrets   map[string]map[string]string
+	Schedules []models.JobSchedule
 }
 
 const jobDefinitionSelectColumns = `
@@ -64,11 +255,16 @@ const jobDefinitionSelectColumns = `
 		jd.tenant_id,
 		jd.name,
 		jd.description,
-		jd.ast,
+		jd.job_type,
+		COALESCE(tenant.blob_content(jd.ast_hash), convert_to(jd.ast::text, 'UTF8')),
 		jd.source_connection_id,
 		jd.destination_connection_id,
 		jd.status,
-		jd.progress_snapshot,
+		COALESCE(tenant.blob_content(jd.progress_snapshot_hash), convert_to(jd.progress_snapshot::text, 'UTF8')),
+		jd.retry_policy,
+		jd.write_mode,
+		jd.verification,
+		jd.archived_at,
 		jd.created_at,
 		jd.updated_at,
 		sc.id,
@@ -80,6 +276,9 @@ const jobDefinitionSelectColumns = `
 		sc.username,
 		sc.db_name,
 		sc.status,
+		sc.ephemeral,
+		COALESCE(sc.environment, ''),
+		COALESCE(sc.tags, '{}'),
 		sc.created_at,
 		sc.updated_at,
 		dc.id,
@@ -91,23 +290,31 @@ const jobDefinitionSelectColumns = `
 		dc.username,
 		dc.db_name,
 		dc.status,
+		dc.ephemeral,
+		COALESCE(dc.environment, ''),
+		COALESCE(dc.tags, '{}'),
 		dc.created_at,
-		dc.updated_at
+		dc.updated_at,
+		COALESCE(sv.stale, FALSE),
+		sv.stale_since,
+		sv.checked_at
 	FROM tenant.job_definitions jd
 	LEFT JOIN tenant.connections sc ON jd.source_connection_id = sc.id AND sc.deleted_at IS NULL
 	LEFT JOIN tenant.connections dc ON jd.destination_connection_id = dc.id AND dc.deleted_at IS NULL
+	LEFT JOIN tenant.job_definition_schema_checks sv
+		ON sv.job_definition_id = jd.id AND sv.definition_updated_at = jd.updated_at
 `
 
-func normalizeDefinitionStatus(status string) string {
-	trimmed := strings.ToUpper(strings.TrimSpace(status))
+func normalizeDefinitionStatus(status models.DefinitionStatus) models.DefinitionStatus {
+	trimmed := strings.ToUpper(strings.TrimSpace(string(status)))
 	if trimmed == "" {
-		return definitionStatusReady
+		return models.DefinitionStatusReady
 	}
-	return trimmed
+	return models.DefinitionStatus(trimmed)
 }
 
-func validateDefinitionStatus(status string) error {
-	if _, ok := allowedDefinitionStatuses[status]; !ok {
+func validateDefinitionStatus(status models.DefinitionStatus) error {
+	if !models.IsValidDefinitionStatus(status) {
 		return fmt.Errorf("invalid job definition status %q", status)
 	}
 	return nil
@@ -121,7 +328,19 @@ func nullIfEmpty(value string) interface{} {
 }
 
 func NewJobRepository(db *sql.DB) JobRepository {
-	return &jobRepository{db: db}
+	return &jobRepository{db: db, blobs: blobstore.NewPostgresStore(db)}
+}
+
+// putJSONBlob stores a JSON payload in the blob store and returns its hash.
+func (r *jobRepository) putJSONBlob(payload json.RawMessage) (string, error) {
+	if !json.Valid(payload) {
+		return "", errors.New("payload is not valid JSON")
+	}
+	ref, err := blobstore.PutBytes(context.Background(), r.blobs, payload)
+	if err != nil {
+		return "", fmt.Errorf("store blob: %w", err)
+	}
+	return ref.Hash, nil
 }
 
 func (r *jobRepository) validateTennantConnection(tenantID, connectionID string) error {
@@ -147,20 +366,20 @@ func (r *jobRepository) validateTennantConnection(tenantID, connectionID string)
 	return nil
 }
 
-func (r *jobRepository) getDefinitionStatus(tenantID, jobDefID string) (string, error) {
+func (r *jobRepository) getDefinitionStatus(tenantID, jobDefID string) (models.DefinitionStatus, error) {
 	const query = `
 		SELECT status
 		FROM tenant.job_definitions
 		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
-	var status string
+	var status models.DefinitionStatus
 	if err := r.db.QueryRow(query, jobDefID, tenantID).Scan(&status); err != nil {
 		return "", err
 	}
 	return status, nil
 }
 
-func (r *jobRepository) recordDefinitionSnapshot(jobDefID, status string, snapshot json.RawMessage) error {
+func (r *jobRepository) recordDefinitionSnapshot(jobDefID string, status models.DefinitionStatus, snapshot json.RawMessage) error {
 	if len(snapshot) == 0 {
 		return nil
 	}
@@ -168,11 +387,15 @@ func (r *jobRepository) recordDefinitionSnapshot(jobDefID, status string, snapsh
 	if err := validateDefinitionStatus(status); err != nil {
 		return err
 	}
+	hash, err := r.putJSONBlob(snapshot)
+	if err != nil {
+		return err
+	}
 	const query = `
-		INSERT INTO tenant.job_definition_snapshots (job_definition_id, status, snapshot)
+		INSERT INTO tenant.job_definition_snapshots (job_definition_id, status, snapshot_hash)
 		VALUES ($1, $2, $3)
 	`
-	_, err := r.db.Exec(query, jobDefID, status, []byte(snapshot))
+	_, err = r.db.Exec(query, jobDefID, status, hash)
 	return err
 }
 
@@ -183,6 +406,7 @@ func scanJobDefinition(scanner interface {
 		def          models.JobDefinition
 		ast          []byte
 		progress     []byte
+		retryPolicy  []byte
 		srcConnID    sql.NullString
 		dstConnID    sql.NullString
 		srcID        sql.NullString
@@ -194,6 +418,9 @@ func scanJobDefinition(scanner interface {
 		srcUsername  sql.NullString
 		srcDBName    sql.NullString
 		srcStatus    sql.NullString
+		srcEphemeral sql.NullBool
+		srcEnv       string
+		srcTags      []string
 		srcCreatedAt sql.NullTime
 		srcUpdatedAt sql.NullTime
 		dstID        sql.NullString
@@ -205,6 +432,9 @@ func scanJobDefinition(scanner interface {
 		dstUsername  sql.NullString
 		dstDBName    sql.NullString
 		dstStatus    sql.NullString
+		dstEphemeral sql.NullBool
+		dstEnv       string
+		dstTags      []string
 		dstCreatedAt sql.NullTime
 		dstUpdatedAt sql.NullTime
 	)
@@ -214,11 +444,16 @@ func scanJobDefinition(scanner interface {
 		&def.TenantID,
 		&def.Name,
 		&def.Description,
+		&def.JobType,
 		&ast,
 		&srcConnID,
 		&dstConnID,
 		&def.Status,
 		&progress,
+		&retryPolicy,
+		&def.WriteMode,
+		&def.Verification,
+		&def.ArchivedAt,
 		&def.CreatedAt,
 		&def.UpdatedAt,
 		&srcID,
@@ -230,6 +465,9 @@ func scanJobDefinition(scanner interface {
 		&srcUsername,
 		&srcDBName,
 		&srcStatus,
+		&srcEphemeral,
+		&srcEnv,
+		pq.Array(&srcTags),
 		&srcCreatedAt,
 		&srcUpdatedAt,
 		&dstID,
@@ -241,8 +479,14 @@ func scanJobDefinition(scanner interface {
 		&dstUsername,
 		&dstDBName,
 		&dstStatus,
+		&dstEphemeral,
+		&dstEnv,
+		pq.Array(&dstTags),
 		&dstCreatedAt,
 		&dstUpdatedAt,
+		&def.StaleValidation,
+		&def.StaleSince,
+		&def.SchemaCheckedAt,
 	); err != nil {
 		return def, err
 	}
@@ -253,20 +497,30 @@ func scanJobDefinition(scanner interface {
 	if len(progress) > 0 {
 		def.ProgressSnapshot = json.RawMessage(append([]byte(nil), progress...))
 	}
+	if len(retryPolicy) > 0 {
+		var policy models.RetryPolicy
+		if err := json.Unmarshal(retryPolicy, &policy); err != nil {
+			return def, fmt.Errorf("decode retry policy: %w", err)
+		}
+		def.RetryPolicy = &policy
+	}
 
 	if srcConnID.Valid {
 		def.SourceConnectionID = srcConnID.String
 		if srcID.Valid {
 			def.SourceConnection = models.Connection{
-				ID:         srcID.String,
-				TenantID:   srcTenantID.String,
-				Name:       srcName.String,
-				DataFormat: srcFormat.String,
-				Host:       srcHost.String,
-				Port:       int(srcPort.Int64),
-				Username:   srcUsername.String,
-				DBName:     srcDBName.String,
-				Status:     srcStatus.String,
+				ID:          srcID.String,
+				TenantID:    srcTenantID.String,
+				Name:        srcName.String,
+				DataFormat:  srcFormat.String,
+				Host:        srcHost.String,
+				Port:        int(srcPort.Int64),
+				Username:    srcUsername.String,
+				DBName:      srcDBName.String,
+				Status:      models.ConnectionStatus(srcStatus.String),
+				Ephemeral:   srcEphemeral.Bool,
+				Environment: srcEnv,
+				Tags:        srcTags,
 			}
 			if srcCreatedAt.Valid {
 				def.SourceConnection.CreatedAt = srcCreatedAt.Time
@@ -281,15 +535,18 @@ func scanJobDefinition(scanner interface {
 		def.DestinationConnectionID = dstConnID.String
 		if dstID.Valid {
 			def.DestinationConnection = models.Connection{
-				ID:         dstID.String,
-				TenantID:   dstTenantID.String,
-				Name:       dstName.String,
-				DataFormat: dstFormat.String,
-				Host:       dstHost.String,
-				Port:       int(dstPort.Int64),
-				Username:   dstUsername.String,
-				DBName:     dstDBName.String,
-				Status:     dstStatus.String,
+				ID:          dstID.String,
+				TenantID:    dstTenantID.String,
+				Name:        dstName.String,
+				DataFormat:  dstFormat.String,
+				Host:        dstHost.String,
+				Port:        int(dstPort.Int64),
+				Username:    dstUsername.String,
+				DBName:      dstDBName.String,
+				Status:      models.ConnectionStatus(dstStatus.String),
+				Ephemeral:   dstEphemeral.Bool,
+				Environment: dstEnv,
+				Tags:        dstTags,
 			}
 			if dstCreatedAt.Valid {
 				def.DestinationConnection.CreatedAt = dstCreatedAt.Time
@@ -299,13 +556,106 @@ func scanJobDefinition(scanner interface {
 			}
 		}
 	}
+	def.Warnings = append(def.EnvironmentWarnings(), def.StaleValidationWarnings()...)
 
 	return def, nil
 }
 
+const executionSelectColumns = `
+	SELECT
+		id,
+		tenant_id,
+		job_definition_id,
+		status,
+		created_at,
+		updated_at,
+		run_started_at,
+		run_completed_at,
+		paused_at,
+		error_message,
+		logs,
+		records_processed,
+		bytes_transferred,
+		resumed_from_execution_id,
+		suspect,
+		suspect_reason,
+		activity_attempts,
+		startup_timings,
+		triggered_by,
+		trigger_context,
+		trigger_type,
+		triggered_by_user_id,
+		workflow_id,
+		requeued_as_execution_id,
+		requeued_at,
+		approved_by,
+		approved_at
+	FROM tenant.job_executions
+`
+
+func scanExecution(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.JobExecution, error) {
+	var (
+		exec           models.JobExecution
+		attempts       []byte
+		startup        []byte
+		triggerContext []byte
+	)
+	err := scanner.Scan(
+		&exec.ID,
+		&exec.TenantID,
+		&exec.JobDefinitionID,
+		&exec.Status,
+		&exec.CreatedAt,
+		&exec.UpdatedAt,
+		&exec.RunStartedAt,
+		&exec.RunCompletedAt,
+		&exec.PausedAt,
+		&exec.ErrorMessage,
+		&exec.Logs,
+		&exec.RecordsProcessed,
+		&exec.BytesTransferred,
+		&exec.ResumedFromExecutionID,
+		&exec.Suspect,
+		&exec.SuspectReason,
+		&attempts,
+		&startup,
+		&exec.TriggeredBy,
+		&triggerContext,
+		&exec.TriggerType,
+		&exec.TriggeredByUserID,
+		&exec.WorkflowID,
+		&exec.RequeuedAsExecutionID,
+		&exec.RequeuedAt,
+		&exec.ApprovedBy,
+		&exec.ApprovedAt,
+	)
+	if err != nil {
+		return exec, err
+	}
+	if len(attempts) > 0 {
+		if err := json.Unmarshal(attempts, &exec.ActivityAttempts); err != nil {
+			return exec, fmt.Errorf("decode activity attempts: %w", err)
+		}
+	}
+	if len(startup) > 0 {
+		if err := json.Unmarshal(startup, &exec.StartupTimings); err != nil {
+			return exec, fmt.Errorf("decode startup timings: %w", err)
+		}
+	}
+	if len(triggerContext) > 0 {
+		if err := json.Unmarshal(triggerContext, &exec.TriggerContext); err != nil {
+			return exec, fmt.Errorf("decode trigger context: %w", err)
+		}
+	}
+	return exec, nil
+}
+
 func (r *jobRepository) loadDefinitionSnapshots(jobDefID string) ([]models.JobDefinitionSnapshot, error) {
 	const query = `
-		SELECT id, job_definition_id, status, snapshot, created_at
+		SELECT id, job_definition_id, status,
+		       COALESCE(tenant.blob_content(snapshot_hash), convert_to(snapshot::text, 'UTF8')), created_at
 		FROM tenant.job_definition_snapshots
 		WHERE job_definition_id = $1
 		ORDER BY created_at DESC
@@ -419,16 +769,41 @@ func (r *jobRepository) CrateDefinition(def models.JobDefinition) (models.JobDef
 	if err := validateDefinitionStatus(def.Status); err != nil {
 		return def, err
 	}
+	if def.JobType == "" {
+		def.JobType = models.JobTypeEngine
+	}
+	writeMode, err := models.NormalizeWriteMode(def.WriteMode)
+	if err != nil {
+		return def, err
+	}
+	def.WriteMode = writeMode
+	verification, err := models.NormalizeVerification(def.Verification)
+	if err != nil {
+		return def, err
+	}
+	def.Verification = verification
 
 	var (
-		astPayload       interface{}
+		astHash          interface{}
 		progressSnapshot interface{}
 	)
 	if len(def.AST) > 0 {
-		astPayload = []byte(def.AST)
+		hash, err := r.putJSONBlob(def.AST)
+		if err != nil {
+			return def, err
+		}
+		astHash = hash
 	}
 	if len(def.ProgressSnapshot) > 0 {
-		progressSnapshot = []byte(def.ProgressSnapshot)
+		hash, err := r.putJSONBlob(def.ProgressSnapshot)
+		if err != nil {
+			return def, err
+		}
+		progressSnapshot = hash
+	}
+	retryPolicy, err := encodeRetryPolicy(def.RetryPolicy)
+	if err != nil {
+		return def, err
 	}
 
 	query := `
@@ -436,12 +811,16 @@ func (r *jobRepository) CrateDefinition(def models.JobDefinition) (models.JobDef
 			tenant_id,
 			name,
 			description,
-			ast,
+			ast_hash,
 			source_connection_id,
 			destination_connection_id,
 			status,
-			progress_snapshot
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			progress_snapshot_hash,
+			job_type,
+			retry_policy,
+			write_mode,
+			verification
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
 
@@ -450,11 +829,15 @@ func (r *jobRepository) CrateDefinition(def models.JobDefinition) (models.JobDef
 		def.TenantID,
 		def.Name,
 		def.Description,
-		astPayload,
+		astHash,
 		nullIfEmpty(def.SourceConnectionID),
 		nullIfEmpty(def.DestinationConnectionID),
 		def.Status,
 		progressSnapshot,
+		def.JobType,
+		retryPolicy,
+		def.WriteMode,
+		def.Verification,
 	).Scan(&def.ID); err != nil {
 		return def, err
 	}
@@ -468,6 +851,99 @@ func (r *jobRepository) CrateDefinition(def models.JobDefinition) (models.JobDef
 	return r.GetJobDefinitionByID(def.TenantID, def.ID)
 }
 
+func (r *jobRepository) ImportTenant(tenantID string, bundle TenantImport) error {
+	// Blobs are content-addressed, so storing ASTs ahead of the transaction leaves
+	// nothing behind that a later import could conflict with.
+	astHashes := make([]interface{}, len(bundle.Definitions))
+	for i, def := range bundle.Definitions {
+		if len(def.AST) == 0 {
+			continue
+		}
+		hash, err := r.putJSONBlob(def.AST)
+		if err != nil {
+			return err
+		}
+		astHashes[i] = hash
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const connQuery = `
+		INSERT INTO tenant.connections (
+			id, tenant_id, name, data_format, host, port, username, password, db_name, credential_mode,
+			environment, tags, options, read_only, docker_network, network_alias
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+	for _, conn := range bundle.Connections {
+		normalizeCredentialMode(&conn)
+		encPwd, err := utils.EncryptPassword(conn.Password)
+		if err != nil {
+			return fmt.Errorf("encrypt password of connection %s: %w", conn.Name, err)
+		}
+		options, err := json.Marshal(conn.Options)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(connQuery, conn.ID, tenantID, conn.Name, conn.DataFormat, conn.Host, conn.Port,
+			conn.Username, encPwd, conn.DBName, conn.CredentialMode, nullIfEmpty(conn.Environment),
+			pq.Array(conn.Tags), options, conn.ReadOnly, nullIfEmpty(conn.DockerNetwork), nullIfEmpty(conn.NetworkAlias)); err != nil {
+			return fmt.Errorf("create connection %s: %w", conn.Name, err)
+		}
+	}
+
+	const defQuery = `
+		INSERT INTO tenant.job_definitions (
+			id, tenant_id, name, description, ast_hash, source_connection_id, destination_connection_id,
+			status, job_type, retry_policy, write_mode, verification
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	for i, def := range bundle.Definitions {
+		retryPolicy, err := encodeRetryPolicy(def.RetryPolicy)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(defQuery, def.ID, tenantID, def.Name, def.Description, astHashes[i],
+			nullIfEmpty(def.SourceConnectionID), nullIfEmpty(def.DestinationConnectionID),
+			normalizeDefinitionStatus(def.Status), def.JobType, retryPolicy, def.WriteMode, def.Verification); err != nil {
+			return fmt.Errorf("create job definition %s: %w", def.Name, err)
+		}
+	}
+
+	const secretQuery = `
+		INSERT INTO tenant.definition_secrets (job_definition_id, tenant_id, name, value)
+		VALUES ($1, $2, $3, $4)
+	`
+	for jobDefID, secrets := range bundle.Secrets {
+		for name, value := range secrets {
+			enc, err := utils.EncryptSecret(value)
+			if err != nil {
+				return fmt.Errorf("encrypt secret %q: %w", name, err)
+			}
+			if _, err := tx.Exec(secretQuery, jobDefID, tenantID, name, enc); err != nil {
+				return err
+			}
+		}
+	}
+
+	const scheduleQuery = `
+		INSERT INTO tenant.job_schedules (job_definition_id, tenant_id, cron_expression, timezone, temporal_schedule_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, schedule := range bundle.Schedules {
+		if _, err := tx.Exec(scheduleQuery, schedule.JobDefinitionID, tenantID, schedule.CronExpression,
+			schedule.Timezone, schedule.ScheduleID, schedule.CreatedBy); err != nil {
+			return fmt.Errorf("create schedule of job definition %s: %w", schedule.JobDefinitionID, err)
+		}
+	}
+	return tx.Commit()
+}
+
 func (r *jobRepository) ListDefinitions(tenantID string) ([]models.JobDefinition, error) {
 	query := jobDefinitionSelectColumns + `
 		WHERE jd.tenant_id = $1
@@ -497,6 +973,39 @@ func (r *jobRepository) ListDefinitions(tenantID string) ([]models.JobDefinition
 	return definitions, nil
 }
 
+// DefinitionsVersion returns a cheap fingerprint of the tenant's definition list.
+// It changes whenever a definition or one of the embedded connections is written,
+// and whenever a schema check updates a definition's stale validation flags.
+func (r *jobRepository) DefinitionsVersion(tenantID string) (string, error) {
+	const query = `
+		SELECT
+			COUNT(*) FILTER (WHERE jd.deleted_at IS NULL),
+			COALESCE(MAX(jd.updated_at), 'epoch'::timestamptz),
+			(
+				SELECT COALESCE(MAX(c.updated_at), 'epoch'::timestamptz)
+				FROM tenant.connections c
+				WHERE c.tenant_id = $1
+			),
+			(
+				SELECT COALESCE(MAX(sv.checked_at), 'epoch'::timestamptz)
+				FROM tenant.job_definition_schema_checks sv
+				WHERE sv.tenant_id = $1
+			)
+		FROM tenant.job_definitions jd
+		WHERE jd.tenant_id = $1
+	`
+	var (
+		count        int64
+		defUpdated   time.Time
+		connUpdated  time.Time
+		schemaChecks time.Time
+	)
+	if err := r.db.QueryRow(query, tenantID).Scan(&count, &defUpdated, &connUpdated, &schemaChecks); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d-%d-%d", count, defUpdated.UnixNano(), connUpdated.UnixNano(), schemaChecks.UnixNano()), nil
+}
+
 func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update DefinitionUpdate) (models.JobDefinition, error) {
 	var result models.JobDefinition
 
@@ -515,7 +1024,7 @@ func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update Defin
 		*update.DestinationConnectionID = dst
 	}
 
-	var statusValue string
+	var statusValue models.DefinitionStatus
 	if update.Status != nil {
 		statusValue = normalizeDefinitionStatus(*update.Status)
 		if err := validateDefinitionStatus(statusValue); err != nil {
@@ -523,8 +1032,23 @@ func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update Defin
 		}
 	}
 
-	setClauses := make([]string, 0, 7)
-	args := make([]interface{}, 0, 9)
+	var writeMode string
+	if update.WriteMode != nil {
+		var err error
+		if writeMode, err = models.NormalizeWriteMode(*update.WriteMode); err != nil {
+			return result, err
+		}
+	}
+	var verification string
+	if update.Verification != nil {
+		var err error
+		if verification, err = models.NormalizeVerification(*update.Verification); err != nil {
+			return result, err
+		}
+	}
+
+	setClauses := make([]string, 0, 10)
+	args := make([]interface{}, 0, 12)
 	idx := 1
 
 	if update.Name != nil {
@@ -540,9 +1064,13 @@ func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update Defin
 	if update.AST != nil {
 		var payload interface{}
 		if len(*update.AST) > 0 {
-			payload = []byte(*update.AST)
+			hash, err := r.putJSONBlob(*update.AST)
+			if err != nil {
+				return result, err
+			}
+			payload = hash
 		}
-		setClauses = append(setClauses, fmt.Sprintf("ast = $%d", idx))
+		setClauses = append(setClauses, fmt.Sprintf("ast = NULL, ast_hash = $%d", idx))
 		args = append(args, payload)
 		idx++
 	}
@@ -564,12 +1092,35 @@ func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update Defin
 	if update.ProgressSnapshot != nil {
 		var payload interface{}
 		if len(*update.ProgressSnapshot) > 0 {
-			payload = []byte(*update.ProgressSnapshot)
+			hash, err := r.putJSONBlob(*update.ProgressSnapshot)
+			if err != nil {
+				return result, err
+			}
+			payload = hash
+		}
+		setClauses = append(setClauses, fmt.Sprintf("progress_snapshot = NULL, progress_snapshot_hash = $%d", idx))
+		args = append(args, payload)
+		idx++
+	}
+	if update.RetryPolicy != nil {
+		payload, err := encodeRetryPolicy(update.RetryPolicy)
+		if err != nil {
+			return result, err
 		}
-		setClauses = append(setClauses, fmt.Sprintf("progress_snapshot = $%d", idx))
+		setClauses = append(setClauses, fmt.Sprintf("retry_policy = $%d", idx))
 		args = append(args, payload)
 		idx++
 	}
+	if update.WriteMode != nil {
+		setClauses = append(setClauses, fmt.Sprintf("write_mode = $%d", idx))
+		args = append(args, writeMode)
+		idx++
+	}
+	if update.Verification != nil {
+		setClauses = append(setClauses, fmt.Sprintf("verification = $%d", idx))
+		args = append(args, verification)
+		idx++
+	}
 
 	if len(setClauses) == 0 {
 		return r.GetJobDefinitionByID(tenantID, jobDefID)
@@ -611,58 +1162,208 @@ func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update Defin
 	return r.GetJobDefinitionByID(tenantID, jobDefID)
 }
 
-func (r *jobRepository) CreateExecution(tenantID, jobDefID, executionID string) (models.JobExecution, error) {
+// encodeRetryPolicy returns the column value of a retry policy: NULL when it sets
+// nothing.
+func encodeRetryPolicy(policy *models.RetryPolicy) (interface{}, error) {
+	if policy == nil || policy.IsZero() {
+		return nil, nil
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (r *jobRepository) CreateExecution(tenantID, jobDefID, executionID, workflowID, triggeredBy string, triggerContext map[string]string, allowConcurrent bool) (models.JobExecution, error) {
 	var exec models.JobExecution
 	exec.ID = executionID
 	exec.JobDefinitionID = jobDefID
 	exec.TenantID = tenantID
-	exec.Status = "pending"
+	exec.Status = models.ExecutionStatusPending
+	exec.TriggerContext = triggerContext
+	var triggeredByValue interface{}
+	if triggeredBy != "" {
+		exec.TriggeredBy = &triggeredBy
+		triggeredByValue = triggeredBy
+	}
+	triggerType, triggerUserID := models.ExecutionTrigger(triggeredBy, triggerContext)
+	if triggerType != "" {
+		exec.TriggerType = &triggerType
+	}
+	if triggerUserID != "" {
+		exec.TriggeredByUserID = &triggerUserID
+	}
+	var workflowIDValue interface{}
+	if workflowID != "" {
+		exec.WorkflowID = &workflowID
+		workflowIDValue = workflowID
+	}
+	contextJSON := []byte("{}")
+	if len(triggerContext) > 0 {
+		b, err := json.Marshal(triggerContext)
+		if err != nil {
+			return exec, fmt.Errorf("encode trigger context: %w", err)
+		}
+		contextJSON = b
+	}
 	currentStatus, err := r.getDefinitionStatus(tenantID, jobDefID)
 	if err != nil {
 		return exec, err
 	}
-	if normalizeDefinitionStatus(currentStatus) != definitionStatusReady {
+	if normalizeDefinitionStatus(currentStatus) != models.DefinitionStatusReady {
 		return exec, fmt.Errorf("%w: current status %s", ErrJobDefinitionNotReady, currentStatus)
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return exec, err
+	}
+	defer tx.Rollback()
+
+	if !allowConcurrent {
+		// Serializes executions of the definition being created until commit, so two
+		// starts cannot both see no active execution.
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, jobDefID); err != nil {
+			return exec, err
+		}
+		var activeID string
+		err := tx.QueryRow(`
+			SELECT id FROM tenant.job_executions
+			WHERE tenant_id = $1 AND job_definition_id = $2 AND status IN ('pending', 'running', 'paused')
+			LIMIT 1
+		`, tenantID, jobDefID).Scan(&activeID)
+		if err == nil {
+			return exec, fmt.Errorf("%w: execution %s", ErrExecutionActive, activeID)
+		}
+		if err != sql.ErrNoRows {
+			return exec, err
+		}
+	}
+
 	query := `
-		INSERT INTO tenant.job_executions (id, tenant_id, job_definition_id, status, run_started_at, run_completed_at)
-		VALUES ($1, $2, $3, $4, NULL, NULL)
+		INSERT INTO tenant.job_executions (id, tenant_id, job_definition_id, status, run_started_at, run_completed_at,
+			triggered_by, trigger_context, workflow_id, trigger_type, triggered_by_user_id)
+		VALUES ($1, $2, $3, $4, NULL, NULL, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE
+		SET status = EXCLUDED.status, workflow_id = EXCLUDED.workflow_id, updated_at = now()
+		WHERE job_executions.tenant_id = EXCLUDED.tenant_id
+			AND job_executions.status = 'awaiting_approval'
+			AND job_executions.approved_by IS NOT NULL
 		RETURNING created_at, updated_at
 	`
-	if err := r.db.QueryRow(query, executionID, tenantID, jobDefID, exec.Status).
-		Scan(&exec.CreatedAt, &exec.UpdatedAt); err != nil {
+	err = tx.QueryRow(query, executionID, tenantID, jobDefID, exec.Status, triggeredByValue, contextJSON, workflowIDValue,
+		nullIfEmpty(triggerType), nullIfEmpty(triggerUserID)).
+		Scan(&exec.CreatedAt, &exec.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return exec, fmt.Errorf("execution %s already exists", executionID)
+	}
+	if err != nil {
 		return exec, err
 	}
-	return exec, nil
+	return exec, tx.Commit()
+}
+
+func (r *jobRepository) CreateAwaitingExecution(tenantID, jobDefID, executionID, triggeredBy string, triggerContext map[string]string, request json.RawMessage) (models.JobExecution, error) {
+	exec := models.JobExecution{
+		ID:              executionID,
+		TenantID:        tenantID,
+		JobDefinitionID: jobDefID,
+		Status:          models.ExecutionStatusAwaitingApproval,
+		TriggerContext:  triggerContext,
+	}
+	var triggeredByValue interface{}
+	if triggeredBy != "" {
+		exec.TriggeredBy = &triggeredBy
+		triggeredByValue = triggeredBy
+	}
+	triggerType, triggerUserID := models.ExecutionTrigger(triggeredBy, triggerContext)
+	if triggerType != "" {
+		exec.TriggerType = &triggerType
+	}
+	if triggerUserID != "" {
+		exec.TriggeredByUserID = &triggerUserID
+	}
+	contextJSON := []byte("{}")
+	if len(triggerContext) > 0 {
+		b, err := json.Marshal(triggerContext)
+		if err != nil {
+			return exec, fmt.Errorf("encode trigger context: %w", err)
+		}
+		contextJSON = b
+	}
+	currentStatus, err := r.getDefinitionStatus(tenantID, jobDefID)
+	if err != nil {
+		return exec, err
+	}
+	if normalizeDefinitionStatus(currentStatus) != models.DefinitionStatusReady {
+		return exec, fmt.Errorf("%w: current status %s", ErrJobDefinitionNotReady, currentStatus)
+	}
+
+	const query = `
+		INSERT INTO tenant.job_executions (id, tenant_id, job_definition_id, status, triggered_by, trigger_context, approval_request,
+			trigger_type, triggered_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at
+	`
+	err = r.db.QueryRow(query, executionID, tenantID, jobDefID, exec.Status, triggeredByValue, contextJSON, []byte(request),
+		nullIfEmpty(triggerType), nullIfEmpty(triggerUserID)).
+		Scan(&exec.CreatedAt, &exec.UpdatedAt)
+	return exec, err
+}
+
+func (r *jobRepository) ClaimExecutionApproval(tenantID, execID, approvedBy string) (json.RawMessage, error) {
+	const query = `
+		UPDATE tenant.job_executions
+		SET approved_by = $3, approved_at = now(), updated_at = now()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'awaiting_approval' AND approved_by IS NULL
+		RETURNING approval_request
+	`
+	var request []byte
+	if err := r.db.QueryRow(query, tenantID, execID, approvedBy).Scan(&request); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(request), nil
+}
+
+func (r *jobRepository) ReleaseExecutionApproval(tenantID, execID string) error {
+	const query = `
+		UPDATE tenant.job_executions
+		SET approved_by = NULL, approved_at = NULL, updated_at = now()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'awaiting_approval'
+	`
+	_, err := r.db.Exec(query, tenantID, execID)
+	return err
+}
+
+func (r *jobRepository) GetActiveDefinitionExecution(tenantID, jobDefID string) (models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1 AND job_definition_id = $2 AND status IN ('pending', 'running', 'paused')
+		ORDER BY created_at
+		LIMIT 1
+	`
+	return scanExecution(r.db.QueryRow(query, tenantID, jobDefID))
+}
+
+func (r *jobRepository) GetAwaitingApprovalExecution(tenantID, jobDefID string) (models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1 AND job_definition_id = $2 AND status = 'awaiting_approval'
+		ORDER BY created_at
+		LIMIT 1
+	`
+	return scanExecution(r.db.QueryRow(query, tenantID, jobDefID))
 }
 
 func (r *jobRepository) GetLastExecution(tenantID, jobDefID string) (models.JobExecution, error) {
-	query := `
-		SELECT id, tenant_id, job_definition_id, status, created_at, updated_at, run_started_at, run_completed_at, error_message, logs, records_processed, bytes_transferred
-		FROM tenant.job_executions
+	query := executionSelectColumns + `
 		WHERE job_definition_id = $1 AND tenant_id = $2
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
-	var exec models.JobExecution
-	err := r.db.QueryRow(query, jobDefID, tenantID).Scan(
-		&exec.ID,
-		&exec.TenantID,
-		&exec.JobDefinitionID,
-		&exec.Status,
-		&exec.CreatedAt,
-		&exec.UpdatedAt,
-		&exec.RunStartedAt,
-		&exec.RunCompletedAt,
-		&exec.ErrorMessage,
-		&exec.Logs,
-		&exec.RecordsProcessed,
-		&exec.BytesTransferred,
-	)
+	exec, err := scanExecution(r.db.QueryRow(query, jobDefID, tenantID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return exec, errors.New("no executions found") // No execution found
+			return exec, errors.New("job execution not found") // No execution found
 		}
 		return exec, err // Other error
 	}
@@ -690,6 +1391,84 @@ func (r *jobRepository) GetJobDefinitionByID(tenantID, jobDefID string) (models.
 	return def, nil
 }
 
+// definitionASTDocument is a definition's AST as JSONB, wherever it is stored.
+const definitionASTDocument = `COALESCE(convert_from(tenant.blob_content(ast_hash), 'UTF8')::jsonb, ast)`
+
+// astTableEntries expands the table list selected as tables by a "def" CTE into
+// (entry, pos, name) rows. ASTs without a table list yield none.
+const astTableEntries = `
+	jsonb_array_elements(CASE WHEN jsonb_typeof(def.tables) = 'array' THEN def.tables ELSE '[]'::jsonb END)
+		WITH ORDINALITY AS t(entry, pos),
+	LATERAL (SELECT CASE jsonb_typeof(t.entry) WHEN 'string' THEN t.entry #>> '{}' ELSE t.entry->>'name' END AS name) AS n
+`
+
+func (r *jobRepository) GetDefinitionASTPath(tenantID, jobDefID string, path []string) (json.RawMessage, error) {
+	query := `
+		SELECT ` + definitionASTDocument + ` #> $3
+		FROM tenant.job_definitions
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`
+	var subtree []byte
+	if err := r.db.QueryRow(query, jobDefID, tenantID, pq.Array(path)).Scan(&subtree); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("job definition not found")
+		}
+		return nil, err
+	}
+	if subtree == nil {
+		return nil, nil
+	}
+	return json.RawMessage(subtree), nil
+}
+
+func (r *jobRepository) GetDefinitionASTTables(tenantID, jobDefID string, tables []string) (json.RawMessage, error) {
+	query := `
+		WITH def AS (
+			SELECT ` + definitionASTDocument + ` #> $3 AS tables
+			FROM tenant.job_definitions
+			WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		)
+		SELECT COALESCE((
+			SELECT jsonb_agg(t.entry ORDER BY t.pos)
+			FROM ` + astTableEntries + `
+			WHERE n.name = ANY($4)
+		), '[]'::jsonb)
+		FROM def
+	`
+	var entries []byte
+	if err := r.db.QueryRow(query, jobDefID, tenantID, pq.Array(models.ASTTablesPath), pq.Array(tables)).Scan(&entries); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("job definition not found")
+		}
+		return nil, err
+	}
+	return json.RawMessage(entries), nil
+}
+
+func (r *jobRepository) ListDefinitionASTTables(tenantID, jobDefID string) ([]string, error) {
+	query := `
+		WITH def AS (
+			SELECT ` + definitionASTDocument + ` #> $3 AS tables
+			FROM tenant.job_definitions
+			WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		)
+		SELECT COALESCE((
+			SELECT array_agg(n.name ORDER BY t.pos)
+			FROM ` + astTableEntries + `
+			WHERE n.name IS NOT NULL
+		), '{}')
+		FROM def
+	`
+	var names []string
+	if err := r.db.QueryRow(query, jobDefID, tenantID, pq.Array(models.ASTTablesPath)).Scan(pq.Array(&names)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("job definition not found")
+		}
+		return nil, err
+	}
+	return names, nil
+}
+
 func (r *jobRepository) DeleteDefinition(tenantID, jobDefID string) error {
 	query := `
 		UPDATE tenant.job_definitions
@@ -710,19 +1489,33 @@ func (r *jobRepository) DeleteDefinition(tenantID, jobDefID string) error {
 		log.Printf("No job definition found with ID %s", jobDefID)
 		return errors.New("job definition not found")
 	}
+
+	// Inline DSN connections belong to this definition alone.
+	cleanup := `
+		UPDATE tenant.connections c
+		SET deleted_at = now(), updated_at = now()
+		FROM tenant.job_definitions jd
+		WHERE jd.id = $1 AND jd.tenant_id = $2
+		  AND c.ephemeral AND c.deleted_at IS NULL
+		  AND c.id IN (jd.source_connection_id, jd.destination_connection_id);
+	`
+	if _, err := r.db.Exec(cleanup, jobDefID, tenantID); err != nil {
+		log.Printf("Error removing inline connections for job definition %s: %v", jobDefID, err)
+	}
 	return nil
 }
 
 func (r *jobRepository) UpdateExecution(
-	tenantID, execID, status, errorMessage, logs string,
+	tenantID, execID string, status models.ExecutionStatus, errorMessage, logs string,
 ) (int64, error) {
 	var (
-		query string
-		args  []interface{}
+		query  string
+		args   []interface{}
+		chunks []models.ExecutionLogChunk
 	)
 
 	switch status {
-	case "running":
+	case models.ExecutionStatusRunning:
 		query = `
             UPDATE tenant.job_executions
                SET status          = $1,
@@ -734,7 +1527,7 @@ func (r *jobRepository) UpdateExecution(
         `
 		args = []interface{}{status, execID, tenantID}
 
-	case "succeeded", "failed":
+	case models.ExecutionStatusSucceeded, models.ExecutionStatusFailed, models.ExecutionStatusSkipped, models.ExecutionStatusCancelled:
 		query = `
             UPDATE tenant.job_executions
                SET status             = $1,
@@ -744,84 +1537,65 @@ func (r *jobRepository) UpdateExecution(
                    logs               = NULLIF($3, '')
              WHERE id = $4 AND tenant_id = $5
         `
+		logs = models.StripANSI(logs)
+		chunks = models.ChunkLogs(logs)
 		args = []interface{}{status, errorMessage, logs, execID, tenantID}
 
 	default:
 		return 0, fmt.Errorf("invalid status %q", status)
 	}
 
-	res, err := r.db.Exec(query, args...)
+	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
 	}
-	return res.RowsAffected()
-}
+	defer tx.Rollback()
 
-func (r *jobRepository) ListExecutions(tenantID string, limit, offset int) ([]models.JobExecution, error) {
-	const query = `
-        SELECT
-            id,
-            tenant_id,
-            job_definition_id,
-            status,
-            created_at,
-            updated_at,
-            run_started_at,
-            run_completed_at,
-            error_message,
-            logs,
-            records_processed,
-            bytes_transferred
-        FROM tenant.job_executions
-        WHERE tenant_id = $1
-        ORDER BY created_at DESC
-        LIMIT $2
-        OFFSET $3
-    `
-	rows, err := r.db.Query(query, tenantID, limit, offset)
+	res, err := tx.Exec(query, args...)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-
-	executions := make([]models.JobExecution, 0, limit)
-	for rows.Next() {
-		var e models.JobExecution
-		var runStarted sql.NullTime
-		var runCompleted sql.NullTime
-		var errMsg sql.NullString
-		var logs sql.NullString
-
-		if err := rows.Scan(
-			&e.ID,
-			&e.TenantID,
-			&e.JobDefinitionID,
-			&e.Status,
-			&e.CreatedAt,
-			&e.UpdatedAt,
-			&runStarted,
-			&runCompleted,
-			&errMsg,
-			&logs,
-			&e.RecordsProcessed,
-			&e.BytesTransferred,
-		); err != nil {
-			return nil, err
+	updated, err := res.RowsAffected()
+	if err != nil || updated == 0 {
+		return updated, err
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM tenant.job_execution_log_chunks WHERE execution_id = $1 AND tenant_id = $2`,
+		execID, tenantID,
+	); err != nil {
+		return 0, err
+	}
+	const insertChunk = `
+		INSERT INTO tenant.job_execution_log_chunks (execution_id, tenant_id, seq, level, line_count, content)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, chunk := range chunks {
+		if _, err := tx.Exec(insertChunk, execID, tenantID, chunk.Seq, chunk.Level, chunk.Lines, chunk.Content); err != nil {
+			return 0, err
 		}
+	}
+	return updated, tx.Commit()
+}
 
-		if runStarted.Valid {
-			e.RunStartedAt = &runStarted.Time
-		}
-		if runCompleted.Valid {
-			e.RunCompletedAt = &runCompleted.Time
-		}
-		if errMsg.Valid {
-			e.ErrorMessage = &errMsg.String
-		}
-		if logs.Valid {
-			e.Logs = &logs.String
-		}
+func (r *jobRepository) ListExecutions(tenantID, triggeredBy string, limit, offset int) ([]models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1 AND ($4 = '' OR triggered_by = $4)
+		ORDER BY created_at DESC
+		LIMIT $2
+		OFFSET $3
+	`
+	rows, err := r.db.Query(query, tenantID, limit, offset, triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	executions := make([]models.JobExecution, 0, limit)
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
 		executions = append(executions, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -830,29 +1604,42 @@ func (r *jobRepository) ListExecutions(tenantID string, limit, offset int) ([]mo
 	return executions, nil
 }
 
-func (r *jobRepository) ListExecutionStats(tenantID string, days int) (models.ExecutionStat, error) {
+// ListExecutionStats aggregates the tenant's executions, bucketing per-day counts by
+// calendar day in tz. An empty tz uses the tenant's configured timezone.
+func (r *jobRepository) ListExecutionStats(tenantID string, days int, tz string) (models.ExecutionStat, error) {
+	if tz == "" {
+		if err := r.db.QueryRow(`SELECT timezone FROM tenant.tenants WHERE id = $1`, tenantID).Scan(&tz); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return models.ExecutionStat{}, fmt.Errorf("ListExecutionStats timezone lookup error: %w", err)
+			}
+			tz = "UTC"
+		}
+	}
+
 	const query = `
 		WITH days AS (
 			SELECT generate_series(
-				(current_date - ($1 - 1) * INTERVAL '1 day'),
-				current_date,
+				(now() AT TIME ZONE $3)::date - ($1 - 1),
+				(now() AT TIME ZONE $3)::date,
 				'1 day'::INTERVAL
-			) AS day
+			)::date AS day
 		)
 		SELECT
 			days.day,
 			COALESCE(SUM((je.status = 'succeeded')::int), 0)   AS succeeded,
 			COALESCE(SUM((je.status = 'failed')::int), 0)      AS failed,
 			COALESCE(SUM((je.status = 'running')::int), 0)     AS running,
-			COALESCE(SUM((je.status = 'pending')::int), 0)     AS pending
+			COALESCE(SUM((je.status = 'paused')::int), 0)      AS paused,
+			COALESCE(SUM((je.status = 'pending')::int), 0)     AS pending,
+			COALESCE(SUM((je.status = 'cancelled')::int), 0)   AS cancelled
 		FROM days
 		LEFT JOIN tenant.job_executions je
-		ON je.created_at::DATE = days.day AND je.tenant_id = $2
+		ON (je.created_at AT TIME ZONE $3)::date = days.day AND je.tenant_id = $2
 		GROUP BY days.day
 		ORDER BY days.day;
 	`
 
-	rows, err := r.db.Query(query, days, tenantID)
+	rows, err := r.db.Query(query, days, tenantID, tz)
 	if err != nil {
 		return models.ExecutionStat{}, fmt.Errorf("ListExecutionStats query error: %w", err)
 	}
@@ -861,25 +1648,39 @@ func (r *jobRepository) ListExecutionStats(tenantID string, days int) (models.Ex
 	var perDay []models.ExecutionStatDay
 	for rows.Next() {
 		var stat models.ExecutionStatDay
-		if err := rows.Scan(&stat.Day, &stat.Succeeded, &stat.Failed, &stat.Running, &stat.Pending); err != nil {
+		if err := rows.Scan(&stat.Day, &stat.Succeeded, &stat.Failed, &stat.Running, &stat.Paused, &stat.Pending, &stat.Cancelled); err != nil {
 			return models.ExecutionStat{}, fmt.Errorf("failed to scan execution stat: %w", err)
 		}
 		perDay = append(perDay, stat)
 	}
 
+	// Settled months come from the rollups, the rest from the executions.
 	const totalQuery = `
+		WITH settled AS (
+			SELECT COALESCE(SUM(executions), 0) AS total,
+			       COALESCE(SUM(succeeded), 0)  AS succeeded,
+			       COALESCE(SUM(failed), 0)     AS failed
+			FROM tenant.execution_monthly_rollups
+			WHERE tenant_id = $1 AND month < $2::date
+		), recent AS (
+			SELECT COUNT(*) AS total,
+			       COALESCE(SUM((status = 'succeeded')::int), 0) AS succeeded,
+			       COALESCE(SUM((status = 'failed')::int), 0)    AS failed
+			FROM tenant.job_executions
+			WHERE tenant_id = $1 AND created_at >= ($2::date)::timestamp AT TIME ZONE 'UTC'
+		)
 		SELECT
-			COALESCE(COUNT(*), 0) AS total,
-			COALESCE(SUM((status = 'succeeded')::int), 0) AS succeeded,
-			COALESCE(SUM((status = 'failed')::int), 0)    AS failed,
-			COALESCE(SUM((status = 'running')::int), 0)   AS running
-		FROM tenant.job_executions
-		WHERE tenant_id = $1;
+			settled.total + recent.total,
+			settled.succeeded + recent.succeeded,
+			settled.failed + recent.failed,
+			(SELECT COUNT(*) FROM tenant.job_executions WHERE tenant_id = $1 AND status = 'running'),
+			(SELECT COUNT(*) FROM tenant.job_executions WHERE tenant_id = $1 AND status = 'paused')
+		FROM settled, recent;
 	`
 
 	var stats models.ExecutionStat
-	row := r.db.QueryRow(totalQuery, tenantID)
-	if err := row.Scan(&stats.Total, &stats.Succeeded, &stats.Failed, &stats.Running); err != nil {
+	row := r.db.QueryRow(totalQuery, tenantID, rollupCutoff(time.Now()).Format(monthDateLayout))
+	if err := row.Scan(&stats.Total, &stats.Succeeded, &stats.Failed, &stats.Running, &stats.Paused); err != nil {
 		return models.ExecutionStat{}, fmt.Errorf("GetExecutionStats total scan error: %w", err)
 	}
 
@@ -899,33 +1700,272 @@ func (r *jobRepository) ListExecutionStats(tenantID string, days int) (models.Ex
 	} else {
 		stats.SuccessRate = 0.0 // Avoid division by zero
 	}
+	// Trigger counts cover the same window as per_day.
+	const triggerQuery = `
+		SELECT COALESCE(triggered_by, 'unknown'), COUNT(*)
+		FROM tenant.job_executions
+		WHERE tenant_id = $1 AND (created_at AT TIME ZONE $3)::date > (now() AT TIME ZONE $3)::date - $2
+		GROUP BY 1;
+	`
+	triggerRows, err := r.db.Query(triggerQuery, tenantID, days, tz)
+	if err != nil {
+		return models.ExecutionStat{}, fmt.Errorf("ListExecutionStats trigger query error: %w", err)
+	}
+	defer triggerRows.Close()
+	stats.PerTrigger = map[string]int{}
+	for triggerRows.Next() {
+		var (
+			trigger string
+			count   int
+		)
+		if err := triggerRows.Scan(&trigger, &count); err != nil {
+			return models.ExecutionStat{}, fmt.Errorf("failed to scan trigger stat: %w", err)
+		}
+		stats.PerTrigger[trigger] = count
+	}
+	if err := triggerRows.Err(); err != nil {
+		return models.ExecutionStat{}, fmt.Errorf("ListExecutionStats trigger rows error: %w", err)
+	}
+
 	stats.PerDay = perDay
 	stats.TotalDefinitions = totalDefinitions
+	stats.Timezone = tz
 
 	return stats, nil
 }
 
-func (r *jobRepository) GetExecution(tenantID, execID string) (models.JobExecution, error) {
-	query := `
-		SELECT id, tenant_id, job_definition_id, status, created_at, updated_at, run_started_at, run_completed_at, error_message, logs, records_processed, bytes_transferred
+const monthDateLayout = "2006-01-02"
+
+// rollupCutoff is the first month whose rollups may still change: executions of the
+// previous month can still be finishing early in a month. Months before it are
+// settled.
+func rollupCutoff(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+func (r *jobRepository) RefreshMonthlyRollups() (int64, error) {
+	const query = `
+		INSERT INTO tenant.execution_monthly_rollups (
+			tenant_id, job_definition_id, month, executions, succeeded, failed,
+			records_processed, bytes_transferred, duration_seconds, timed_runs)
+		SELECT
+			tenant_id,
+			job_definition_id,
+			date_trunc('month', created_at AT TIME ZONE 'UTC')::date,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COALESCE(SUM(records_processed), 0),
+			COALESCE(SUM(bytes_transferred), 0),
+			COALESCE(SUM(EXTRACT(EPOCH FROM (run_completed_at - run_started_at))), 0),
+			COUNT(run_completed_at - run_started_at)
 		FROM tenant.job_executions
+		WHERE tenant_id IS NOT NULL
+		  AND created_at >= ($1::date)::timestamp AT TIME ZONE 'UTC'
+		GROUP BY 1, 2, 3
+		ON CONFLICT (tenant_id, job_definition_id, month) DO UPDATE SET
+			executions = EXCLUDED.executions,
+			succeeded = EXCLUDED.succeeded,
+			failed = EXCLUDED.failed,
+			records_processed = EXCLUDED.records_processed,
+			bytes_transferred = EXCLUDED.bytes_transferred,
+			duration_seconds = EXCLUDED.duration_seconds,
+			timed_runs = EXCLUDED.timed_runs,
+			refreshed_at = now();
+	`
+	res, err := r.db.Exec(query, rollupCutoff(time.Now()).Format(monthDateLayout))
+	if err != nil {
+		return 0, fmt.Errorf("RefreshMonthlyRollups query error: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *jobRepository) ListMonthlyStats(tenantID, jobDefID string, from, to time.Time) ([]models.ExecutionMonthStat, error) {
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+
+	const query = `
+		SELECT month, SUM(executions), SUM(succeeded), SUM(failed), SUM(records_processed),
+		       SUM(bytes_transferred), SUM(duration_seconds), SUM(timed_runs), 'rollup'
+		FROM tenant.execution_monthly_rollups
+		WHERE tenant_id = $1
+		  AND ($2 = '' OR job_definition_id::text = $2)
+		  AND month >= $3::date AND month < LEAST($4::date, $5::date)
+		GROUP BY month
+		UNION ALL
+		SELECT
+			date_trunc('month', created_at AT TIME ZONE 'UTC')::date,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COALESCE(SUM(records_processed), 0),
+			COALESCE(SUM(bytes_transferred), 0),
+			COALESCE(SUM(EXTRACT(EPOCH FROM (run_completed_at - run_started_at))), 0),
+			COUNT(run_completed_at - run_started_at),
+			'raw'
+		FROM tenant.job_executions
+		WHERE tenant_id = $1
+		  AND ($2 = '' OR job_definition_id::text = $2)
+		  AND created_at >= (GREATEST($3::date, $5::date))::timestamp AT TIME ZONE 'UTC'
+		  AND created_at < ($4::date)::timestamp AT TIME ZONE 'UTC'
+		GROUP BY 1
+		ORDER BY 1;
+	`
+	rows, err := r.db.Query(query, tenantID, jobDefID,
+		from.Format(monthDateLayout), end.Format(monthDateLayout), rollupCutoff(time.Now()).Format(monthDateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("ListMonthlyStats query error: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.ExecutionMonthStat
+	for rows.Next() {
+		var (
+			stat      models.ExecutionMonthStat
+			duration  float64
+			timedRuns int64
+		)
+		if err := rows.Scan(
+			&stat.Month,
+			&stat.Executions,
+			&stat.Succeeded,
+			&stat.Failed,
+			&stat.RecordsProcessed,
+			&stat.BytesTransferred,
+			&duration,
+			&timedRuns,
+			&stat.Source,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly stat: %w", err)
+		}
+		if stat.Executions > 0 {
+			stat.SuccessRate = float64(stat.Succeeded) / float64(stat.Executions) * 100.0
+		}
+		if timedRuns > 0 {
+			avg := duration / float64(timedRuns)
+			stat.AvgDurationSeconds = &avg
+		}
+		stats = append(stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListExecutionSeries buckets executions created in [from, to) per definition.
+func (r *jobRepository) ListExecutionSeries(tenantID string, from, to time.Time, bucket time.Duration) ([]models.ExecutionSeriesPoint, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds <= 0 {
+		bucketSeconds = 60
+	}
+
+	const query = `
+		SELECT
+			to_timestamp(floor(EXTRACT(EPOCH FROM je.created_at) / $4) * $4) AS bucket,
+			je.job_definition_id,
+			COALESCE(jd.name, je.job_definition_id::text) AS job_definition_name,
+			COUNT(*) AS total,
+			COALESCE(SUM((je.status = 'succeeded')::int), 0) AS succeeded,
+			COALESCE(SUM((je.status = 'failed')::int), 0) AS failed,
+			AVG(EXTRACT(EPOCH FROM (je.run_completed_at - je.run_started_at))) AS avg_duration_seconds,
+			COUNT(je.run_completed_at - je.run_started_at) AS timed_runs,
+			COALESCE(SUM(je.bytes_transferred), 0) AS bytes_transferred
+		FROM tenant.job_executions je
+		LEFT JOIN tenant.job_definitions jd ON jd.id = je.job_definition_id
+		WHERE je.tenant_id = $1
+		  AND je.created_at >= $2
+		  AND je.created_at < $3
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 3;
+	`
+	rows, err := r.db.Query(query, tenantID, from, to, bucketSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("ListExecutionSeries query error: %w", err)
+	}
+	defer rows.Close()
+
+	var points []models.ExecutionSeriesPoint
+	for rows.Next() {
+		var (
+			point       models.ExecutionSeriesPoint
+			avgDuration sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&point.Bucket,
+			&point.JobDefinitionID,
+			&point.JobDefinitionName,
+			&point.Total,
+			&point.Succeeded,
+			&point.Failed,
+			&avgDuration,
+			&point.TimedRuns,
+			&point.BytesTransferred,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan execution series point: %w", err)
+		}
+		if avgDuration.Valid {
+			value := avgDuration.Float64
+			point.AvgDurationSeconds = &value
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func (r *jobRepository) ListCalendarExecutions(tenantID string, from, to time.Time, limit int) ([]models.CalendarEvent, error) {
+	const query = `
+		SELECT
+			je.id,
+			je.job_definition_id,
+			COALESCE(jd.name, je.job_definition_id::text),
+			je.status,
+			COALESCE(je.run_started_at, je.created_at) AS at,
+			je.run_completed_at
+		FROM tenant.job_executions je
+		LEFT JOIN tenant.job_definitions jd ON jd.id = je.job_definition_id
+		WHERE je.tenant_id = $1
+		  AND COALESCE(je.run_started_at, je.created_at) >= $2
+		  AND COALESCE(je.run_started_at, je.created_at) < $3
+		ORDER BY at, je.id
+		LIMIT $4;
+	`
+	rows, err := r.db.Query(query, tenantID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ListCalendarExecutions query error: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.CalendarEvent
+	for rows.Next() {
+		event := models.CalendarEvent{Kind: models.CalendarEventExecution}
+		if err := rows.Scan(
+			&event.ExecutionID,
+			&event.JobDefinitionID,
+			&event.JobDefinitionName,
+			&event.Status,
+			&event.At,
+			&event.EndedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar execution: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *jobRepository) GetExecution(tenantID, execID string) (models.JobExecution, error) {
+	query := executionSelectColumns + `
 		WHERE id = $1 AND tenant_id = $2;
 	`
-	var exec models.JobExecution
-	err := r.db.QueryRow(query, execID, tenantID).Scan(
-		&exec.ID,
-		&exec.TenantID,
-		&exec.JobDefinitionID,
-		&exec.Status,
-		&exec.CreatedAt,
-		&exec.UpdatedAt,
-		&exec.RunStartedAt,
-		&exec.RunCompletedAt,
-		&exec.ErrorMessage,
-		&exec.Logs,
-		&exec.RecordsProcessed,
-		&exec.BytesTransferred,
-	)
+	exec, err := scanExecution(r.db.QueryRow(query, execID, tenantID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return exec, errors.New("execution not found")
@@ -935,14 +1975,161 @@ func (r *jobRepository) GetExecution(tenantID, execID string) (models.JobExecuti
 	return exec, nil
 }
 
-func (r *jobRepository) SetExecutionComplete(tenantID, execID string, status string, recordsProcessed int64, bytesTransferred int64) error {
+func (r *jobRepository) SetExecutionComplete(tenantID, execID string, status models.ExecutionStatus, recordsProcessed int64, bytesTransferred int64) error {
 	query := `
 		UPDATE tenant.job_executions
 		SET status = $1, run_completed_at = NOW(), records_processed = $2, bytes_transferred = $3
 		WHERE id = $4 AND tenant_id = $5;
 	`
-	_, err := r.db.Exec(query, status, recordsProcessed, bytesTransferred, execID, tenantID)
-	return err
+	res, err := r.db.Exec(query, status, recordsProcessed, bytesTransferred, execID, tenantID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobRepository) SetExecutionPaused(tenantID, execID string, paused bool) (bool, error) {
+	query := `
+		UPDATE tenant.job_executions
+		SET status = 'paused', paused_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND status = 'running';
+	`
+	if !paused {
+		query = `
+			UPDATE tenant.job_executions
+			SET status = 'running', paused_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND tenant_id = $2 AND status = 'paused';
+		`
+	}
+	res, err := r.db.Exec(query, execID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *jobRepository) FailActiveExecution(tenantID, execID, errorMessage string) (bool, error) {
+	query := `
+		UPDATE tenant.job_executions
+		SET status = 'failed', run_completed_at = NOW(), updated_at = NOW(), error_message = NULLIF($1, '')
+		WHERE id = $2 AND tenant_id = $3 AND status IN ('pending', 'running', 'paused');
+	`
+	res, err := r.db.Exec(query, errorMessage, execID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *jobRepository) CancelPendingExecution(tenantID, execID, errorMessage string) (bool, error) {
+	query := `
+		UPDATE tenant.job_executions
+		SET status = 'cancelled', run_completed_at = NOW(), updated_at = NOW(), error_message = NULLIF($1, '')
+		WHERE id = $2 AND tenant_id = $3 AND status = 'pending';
+	`
+	res, err := r.db.Exec(query, errorMessage, execID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *jobRepository) ListActiveExecutions(updatedBefore time.Time, limit int) ([]models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE status IN ('pending', 'running', 'paused') AND updated_at < $1
+		/* cross-tenant: the reconciler checks every tenant's executions */
+		ORDER BY updated_at
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, updatedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []models.JobExecution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+func (r *jobRepository) ListConnectionActiveExecutions(tenantID, connectionID string) ([]models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1
+		  AND status IN ('pending', 'running', 'paused')
+		  AND job_definition_id IN (
+			SELECT id FROM tenant.job_definitions
+			WHERE tenant_id = $1 AND (source_connection_id = $2 OR destination_connection_id = $2)
+		  )
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(query, tenantID, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []models.JobExecution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+func (r *jobRepository) ListTenantActiveExecutions(tenantID string) ([]models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1
+		  AND status IN ('pending', 'running', 'paused')
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []models.JobExecution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+func (r *jobRepository) AverageDefinitionDurations(tenantID string) (map[string]float64, error) {
+	metrics, err := r.fetchDefinitionStats(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	averages := make(map[string]float64, len(metrics))
+	for defID, metric := range metrics {
+		if metric.avgDurationSeconds != nil {
+			averages[defID] = *metric.avgDurationSeconds
+		}
+	}
+	return averages, nil
 }
 
 // Retrieves all job definitions along with their execution stats.
@@ -971,3 +2158,1442 @@ func (r *jobRepository) ListJobDefinitionsWithStats(tenantID string) ([]models.J
 
 	return stats, nil
 }
+
+// CreateExecutionSnapshot stores the execution's environment snapshot. The first
+// snapshot written wins; retried activities never overwrite it.
+func (r *jobRepository) CreateExecutionSnapshot(snapshot models.ExecutionSnapshot) error {
+	source, err := json.Marshal(snapshot.SourceConnection)
+	if err != nil {
+		return fmt.Errorf("marshal source fingerprint: %w", err)
+	}
+	destination, err := json.Marshal(snapshot.DestinationConnection)
+	if err != nil {
+		return fmt.Errorf("marshal destination fingerprint: %w", err)
+	}
+	params := snapshot.Parameters
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	parameters, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal parameters: %w", err)
+	}
+
+	astHash, err := r.putJSONBlob(snapshot.AST)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO tenant.execution_snapshots (
+			execution_id,
+			tenant_id,
+			job_definition_id,
+			ast_hash,
+			engine_image,
+			engine_image_digest,
+			source_connection,
+			destination_connection,
+			parameters,
+			container_cpu_limit,
+			container_memory_limit,
+			api_version
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (execution_id) DO NOTHING
+	`
+	_, err = r.db.Exec(
+		query,
+		snapshot.ExecutionID,
+		snapshot.TenantID,
+		snapshot.JobDefinitionID,
+		astHash,
+		snapshot.EngineImage,
+		snapshot.EngineImageDigest,
+		source,
+		destination,
+		parameters,
+		snapshot.ContainerCPULimit,
+		snapshot.ContainerMemoryLimit,
+		snapshot.APIVersion,
+	)
+	return err
+}
+
+func (r *jobRepository) SetExecutionSnapshotImageDigest(tenantID, execID, digest string) error {
+	_, err := r.db.Exec(`
+		UPDATE tenant.execution_snapshots
+		SET engine_image_digest = $3
+		WHERE execution_id = $1 AND tenant_id = $2 AND engine_image_digest IS NULL
+	`, execID, tenantID, digest)
+	return err
+}
+
+func (r *jobRepository) GetExecutionSnapshot(tenantID, execID string) (models.ExecutionSnapshot, error) {
+	const query = `
+		SELECT
+			execution_id,
+			tenant_id,
+			job_definition_id,
+			ast_hash,
+			COALESCE(convert_to(ast::text, 'UTF8'), tenant.blob_content(ast_hash)),
+			engine_image,
+			engine_image_digest,
+			source_connection,
+			destination_connection,
+			parameters,
+			container_cpu_limit,
+			container_memory_limit,
+			api_version,
+			created_at
+		FROM tenant.execution_snapshots
+		WHERE execution_id = $1 AND tenant_id = $2
+	`
+	var (
+		snapshot    models.ExecutionSnapshot
+		ast         []byte
+		digest      sql.NullString
+		source      []byte
+		destination []byte
+		parameters  []byte
+	)
+	err := r.db.QueryRow(query, execID, tenantID).Scan(
+		&snapshot.ExecutionID,
+		&snapshot.TenantID,
+		&snapshot.JobDefinitionID,
+		&snapshot.ASTHash,
+		&ast,
+		&snapshot.EngineImage,
+		&digest,
+		&source,
+		&destination,
+		&parameters,
+		&snapshot.ContainerCPULimit,
+		&snapshot.ContainerMemoryLimit,
+		&snapshot.APIVersion,
+		&snapshot.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return snapshot, errors.New("execution snapshot not found")
+		}
+		return snapshot, err
+	}
+
+	snapshot.AST = json.RawMessage(ast)
+	if digest.Valid {
+		snapshot.EngineImageDigest = &digest.String
+	}
+	if err := json.Unmarshal(source, &snapshot.SourceConnection); err != nil {
+		return snapshot, fmt.Errorf("decode source fingerprint: %w", err)
+	}
+	if err := json.Unmarshal(destination, &snapshot.DestinationConnection); err != nil {
+		return snapshot, fmt.Errorf("decode destination fingerprint: %w", err)
+	}
+	if len(parameters) > 0 {
+		if err := json.Unmarshal(parameters, &snapshot.Parameters); err != nil {
+			return snapshot, fmt.Errorf("decode parameters: %w", err)
+		}
+	}
+	return snapshot, nil
+}
+
+// SaveExecutionArtifact stores an artifact, replacing any earlier artifact of the
+// same kind for the execution.
+func (r *jobRepository) SaveExecutionArtifact(artifact models.ExecutionArtifact) (models.ExecutionArtifact, error) {
+	if artifact.ScanStatus == "" {
+		artifact.ScanStatus = models.ArtifactScanUnscanned
+	}
+	contentHash, err := r.putJSONBlob(artifact.Content)
+	if err != nil {
+		return artifact, err
+	}
+	const query = `
+		INSERT INTO tenant.execution_artifacts (execution_id, tenant_id, kind, content_hash, storage_region, storage_location, scan_status, scan_detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (execution_id, kind) DO UPDATE
+		SET content = NULL,
+		    content_hash = EXCLUDED.content_hash,
+		    storage_region = EXCLUDED.storage_region,
+		    storage_location = EXCLUDED.storage_location,
+		    scan_status = EXCLUDED.scan_status,
+		    scan_detail = EXCLUDED.scan_detail,
+		    created_at = now()
+		RETURNING id, created_at
+	`
+	err = r.db.QueryRow(
+		query,
+		artifact.ExecutionID,
+		artifact.TenantID,
+		artifact.Kind,
+		contentHash,
+		artifact.StorageRegion,
+		artifact.StorageLocation,
+		artifact.ScanStatus,
+		artifact.ScanDetail,
+	).Scan(&artifact.ID, &artifact.CreatedAt)
+	return artifact, err
+}
+
+func (r *jobRepository) GetExecutionArtifact(tenantID, execID, kind string) (models.ExecutionArtifact, error) {
+	const query = `
+		SELECT id, tenant_id, execution_id, kind,
+		       COALESCE(tenant.blob_content(content_hash), convert_to(content::text, 'UTF8')),
+		       storage_region, storage_location, scan_status, scan_detail, created_at
+		FROM tenant.execution_artifacts
+		WHERE execution_id = $1 AND tenant_id = $2 AND kind = $3
+	`
+	artifact, err := scanExecutionArtifact(r.db.QueryRow(query, execID, tenantID, kind))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return artifact, errors.New("execution artifact not found")
+		}
+		return artifact, err
+	}
+	return artifact, nil
+}
+
+func (r *jobRepository) ListExecutionArtifacts(tenantID, execID string) ([]models.ExecutionArtifact, error) {
+	const query = `
+		SELECT id, tenant_id, execution_id, kind,
+		       COALESCE(tenant.blob_content(content_hash), convert_to(content::text, 'UTF8')),
+		       storage_region, storage_location, scan_status, scan_detail, created_at
+		FROM tenant.execution_artifacts
+		WHERE execution_id = $1 AND tenant_id = $2
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(query, execID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := []models.ExecutionArtifact{}
+	for rows.Next() {
+		artifact, err := scanExecutionArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, rows.Err()
+}
+
+func scanExecutionArtifact(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.ExecutionArtifact, error) {
+	var (
+		artifact models.ExecutionArtifact
+		content  []byte
+	)
+	if err := scanner.Scan(
+		&artifact.ID,
+		&artifact.TenantID,
+		&artifact.ExecutionID,
+		&artifact.Kind,
+		&content,
+		&artifact.StorageRegion,
+		&artifact.StorageLocation,
+		&artifact.ScanStatus,
+		&artifact.ScanDetail,
+		&artifact.CreatedAt,
+	); err != nil {
+		return artifact, err
+	}
+	artifact.Content = json.RawMessage(content)
+	return artifact, nil
+}
+
+// SaveExecutionCheckpoints upserts the engine's latest per-table progress for an
+// execution. Each table keeps only its most recent checkpoint.
+func (r *jobRepository) SaveExecutionCheckpoints(tenantID, execID string, checkpoints []models.ExecutionCheckpoint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(
+		`SELECT 1 FROM tenant.job_executions WHERE id = $1 AND tenant_id = $2`,
+		execID, tenantID,
+	).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("execution not found")
+		}
+		return err
+	}
+
+	const query = `
+		INSERT INTO tenant.execution_checkpoints (execution_id, tenant_id, table_name, last_key, rows_processed, completed)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (execution_id, table_name) DO UPDATE
+		SET last_key = EXCLUDED.last_key,
+		    rows_processed = EXCLUDED.rows_processed,
+		    completed = EXCLUDED.completed,
+		    updated_at = now()
+	`
+	for _, cp := range checkpoints {
+		var lastKey interface{}
+		if len(cp.LastKey) > 0 {
+			lastKey = []byte(cp.LastKey)
+		}
+		if _, err := tx.Exec(query, execID, tenantID, cp.Table, lastKey, cp.RowsProcessed, cp.Completed); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *jobRepository) ListExecutionCheckpoints(tenantID, execID string) ([]models.ExecutionCheckpoint, error) {
+	const query = `
+		SELECT execution_id, table_name, last_key, rows_processed, completed, updated_at
+		FROM tenant.execution_checkpoints
+		WHERE execution_id = $1 AND tenant_id = $2
+		ORDER BY table_name
+	`
+	rows, err := r.db.Query(query, execID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []models.ExecutionCheckpoint{}
+	for rows.Next() {
+		var (
+			cp      models.ExecutionCheckpoint
+			lastKey []byte
+		)
+		if err := rows.Scan(&cp.ExecutionID, &cp.Table, &lastKey, &cp.RowsProcessed, &cp.Completed, &cp.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if len(lastKey) > 0 {
+			cp.LastKey = json.RawMessage(lastKey)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// ResumeExecutionFrom links execID to the execution it resumes and seeds it with
+// that execution's checkpoints, so a further interruption still resumes from the
+// furthest point reached. It returns the seeded checkpoints.
+func (r *jobRepository) ResumeExecutionFrom(tenantID, execID, fromExecID string) ([]models.ExecutionCheckpoint, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE tenant.job_executions
+		SET resumed_from_execution_id = $3
+		WHERE id = $1 AND tenant_id = $2
+	`, execID, tenantID, fromExecID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, errors.New("execution not found")
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO tenant.execution_checkpoints (execution_id, tenant_id, table_name, last_key, rows_processed, completed)
+		SELECT $1, tenant_id, table_name, last_key, rows_processed, completed
+		FROM tenant.execution_checkpoints
+		WHERE execution_id = $3 AND tenant_id = $2
+		ON CONFLICT (execution_id, table_name) DO NOTHING
+	`, execID, tenantID, fromExecID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.ListExecutionCheckpoints(tenantID, execID)
+}
+
+// SaveExecutionTables upserts the engine's per-table results for an execution.
+func (r *jobRepository) SaveExecutionTables(tenantID, execID string, tables []models.JobExecutionTable) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(
+		`SELECT 1 FROM tenant.job_executions WHERE id = $1 AND tenant_id = $2`,
+		execID, tenantID,
+	).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("execution not found")
+		}
+		return err
+	}
+
+	const query = `
+		INSERT INTO tenant.job_execution_tables (execution_id, tenant_id, table_name, status, rows_copied, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (execution_id, table_name) DO UPDATE
+		SET status = EXCLUDED.status,
+		    rows_copied = EXCLUDED.rows_copied,
+		    error = EXCLUDED.error,
+		    updated_at = now()
+	`
+	for _, t := range tables {
+		if _, err := tx.Exec(query, execID, tenantID, t.Table, t.Status, t.RowsCopied, t.Error); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *jobRepository) ListExecutionTables(tenantID, execID string) ([]models.JobExecutionTable, error) {
+	const query = `
+		SELECT execution_id, table_name, status, rows_copied, error, updated_at
+		FROM tenant.job_execution_tables
+		WHERE execution_id = $1 AND tenant_id = $2
+		ORDER BY table_name
+	`
+	rows, err := r.db.Query(query, execID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []models.JobExecutionTable{}
+	for rows.Next() {
+		var t models.JobExecutionTable
+		if err := rows.Scan(&t.ExecutionID, &t.Table, &t.Status, &t.RowsCopied, &t.Error, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+func (r *jobRepository) ListExecutionLogChunks(tenantID, execID string) ([]models.ExecutionLogChunk, error) {
+	const query = `
+		SELECT seq, level, line_count, content
+		FROM tenant.job_execution_log_chunks
+		WHERE execution_id = $1 AND tenant_id = $2
+		ORDER BY seq
+	`
+	rows, err := r.db.Query(query, execID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []models.ExecutionLogChunk{}
+	for rows.Next() {
+		var c models.ExecutionLogChunk
+		if err := rows.Scan(&c.Seq, &c.Level, &c.Lines, &c.Content); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// DemoteReadyDefinition moves a READY definition back to DRAFT. It reports false when
+// the definition was no longer READY, e.g. because it was edited in the meantime.
+func (r *jobRepository) DemoteReadyDefinition(tenantID, jobDefID string) (bool, error) {
+	const query = `
+		UPDATE tenant.job_definitions
+		SET status = $3, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND status = $4 AND deleted_at IS NULL
+	`
+	res, err := r.db.Exec(query, jobDefID, tenantID, models.DefinitionStatusDraft, models.DefinitionStatusReady)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ArchiveDefinition moves a definition to ARCHIVED, remembering its status for
+// UnarchiveDefinition. It reports false when the definition was already archived.
+func (r *jobRepository) ArchiveDefinition(tenantID, jobDefID string) (bool, error) {
+	const query = `
+		UPDATE tenant.job_definitions
+		SET archived_from_status = status, status = $3, archived_at = now(), updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND status <> $3 AND deleted_at IS NULL
+	`
+	res, err := r.db.Exec(query, jobDefID, tenantID, models.DefinitionStatusArchived)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UnarchiveDefinition restores an archived definition to the status it had when it
+// was archived. It reports false when the definition was not archived.
+func (r *jobRepository) UnarchiveDefinition(tenantID, jobDefID string) (bool, error) {
+	const query = `
+		UPDATE tenant.job_definitions
+		SET status = COALESCE(archived_from_status, $4), archived_from_status = NULL, archived_at = NULL, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND status = $3 AND deleted_at IS NULL
+	`
+	res, err := r.db.Exec(query, jobDefID, tenantID, models.DefinitionStatusArchived, models.DefinitionStatusDraft)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ListSchemaCheckDefinitions returns the READY engine definitions due for a schema
+// drift check. Checks of an earlier version of a definition do not count.
+func (r *jobRepository) ListSchemaCheckDefinitions(checkedBefore time.Time, limit int) ([]models.JobDefinition, error) {
+	query := jobDefinitionSelectColumns + `
+		JOIN tenant.tenants t ON t.id = jd.tenant_id
+		/* cross-tenant: schema drift checks serve every tenant */
+		WHERE jd.status = $1 AND jd.job_type = $2 AND jd.deleted_at IS NULL
+		  AND t.validation_freshness <> $3 AND t.suspended_at IS NULL
+		  AND (sv.checked_at IS NULL OR sv.checked_at < $4)
+		ORDER BY sv.checked_at NULLS FIRST, jd.created_at
+		LIMIT $5
+	`
+	rows, err := r.db.Query(query, models.DefinitionStatusReady, models.JobTypeEngine, models.ValidationFreshnessOff, checkedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var defs []models.JobDefinition
+	for rows.Next() {
+		def, err := scanJobDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+// RecordDefinitionSchemaCheck records a schema drift check. A check of a definition
+// that was edited or left READY since it was loaded records nothing.
+func (r *jobRepository) RecordDefinitionSchemaCheck(tenantID, jobDefID string, updatedAt time.Time, schemaHash string) (bool, error) {
+	const query = `
+		INSERT INTO tenant.job_definition_schema_checks AS c
+			(job_definition_id, tenant_id, definition_updated_at, schema_hash, checked_at)
+		SELECT id, tenant_id, updated_at, $4, now()
+		FROM tenant.job_definitions
+		WHERE id = $1 AND tenant_id = $2 AND updated_at = $3 AND status = $5 AND deleted_at IS NULL
+		ON CONFLICT (job_definition_id) DO UPDATE
+		SET schema_hash = CASE WHEN c.definition_updated_at = EXCLUDED.definition_updated_at
+				THEN c.schema_hash ELSE EXCLUDED.schema_hash END,
+			stale = c.definition_updated_at = EXCLUDED.definition_updated_at
+				AND (c.stale OR c.schema_hash <> EXCLUDED.schema_hash),
+			stale_since = CASE
+				WHEN c.definition_updated_at <> EXCLUDED.definition_updated_at THEN NULL
+				WHEN c.stale THEN c.stale_since
+				WHEN c.schema_hash <> EXCLUDED.schema_hash THEN now()
+			END,
+			definition_updated_at = EXCLUDED.definition_updated_at,
+			checked_at = now()
+		RETURNING COALESCE(c.stale AND c.stale_since = now(), FALSE)
+	`
+	var flagged bool
+	err := r.db.QueryRow(query, jobDefID, tenantID, updatedAt, schemaHash, models.DefinitionStatusReady).Scan(&flagged)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return flagged, err
+}
+
+// ResetDefinitionSchemaCheck deletes the definition's schema check.
+func (r *jobRepository) ResetDefinitionSchemaCheck(tenantID, jobDefID string) error {
+	const query = `
+		DELETE FROM tenant.job_definition_schema_checks
+		WHERE job_definition_id = $1 AND tenant_id = $2
+	`
+	_, err := r.db.Exec(query, jobDefID, tenantID)
+	return err
+}
+
+// EnqueueDefinitionRevalidations queues every READY definition that uses the
+// connection as source or destination. Already queued definitions are re-stamped so
+// a pending check sees the latest change. It returns the number of definitions queued.
+func (r *jobRepository) EnqueueDefinitionRevalidations(tenantID, connectionID string) (int64, error) {
+	const query = `
+		INSERT INTO tenant.definition_revalidations (job_definition_id, tenant_id, connection_id)
+		SELECT id, tenant_id, $2
+		FROM tenant.job_definitions
+		WHERE tenant_id = $1 AND status = $3 AND deleted_at IS NULL
+		  AND (source_connection_id = $2 OR destination_connection_id = $2)
+		ON CONFLICT (job_definition_id) DO UPDATE
+		SET connection_id = EXCLUDED.connection_id, enqueued_at = now(), attempts = 0, last_error = NULL
+	`
+	res, err := r.db.Exec(query, tenantID, connectionID, models.DefinitionStatusReady)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListDefinitionRevalidations returns the oldest pending revalidations across all tenants.
+func (r *jobRepository) ListDefinitionRevalidations(limit int) ([]models.DefinitionRevalidation, error) {
+	const query = `
+		/* cross-tenant: revalidation serves every tenant */
+		SELECT job_definition_id, tenant_id, connection_id, enqueued_at, attempts, last_error
+		FROM tenant.definition_revalidations
+		ORDER BY enqueued_at
+		LIMIT $1
+	`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []models.DefinitionRevalidation
+	for rows.Next() {
+		var rev models.DefinitionRevalidation
+		if err := rows.Scan(&rev.JobDefinitionID, &rev.TenantID, &rev.ConnectionID, &rev.EnqueuedAt, &rev.Attempts, &rev.LastError); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+// CompleteDefinitionRevalidation removes a processed entry unless it was re-enqueued
+// while the check was running.
+func (r *jobRepository) CompleteDefinitionRevalidation(rev models.DefinitionRevalidation) error {
+	const query = `
+		/* cross-tenant: revalidation serves every tenant */
+		DELETE FROM tenant.definition_revalidations
+		WHERE job_definition_id = $1 AND enqueued_at = $2
+	`
+	_, err := r.db.Exec(query, rev.JobDefinitionID, rev.EnqueuedAt)
+	return err
+}
+
+// RecordDefinitionRevalidationError notes a failed attempt so the entry is retried.
+func (r *jobRepository) RecordDefinitionRevalidationError(jobDefID, message string) error {
+	const query = `
+		/* cross-tenant: revalidation serves every tenant */
+		UPDATE tenant.definition_revalidations
+		SET attempts = attempts + 1, last_error = $2
+		WHERE job_definition_id = $1
+	`
+	_, err := r.db.Exec(query, jobDefID, message)
+	return err
+}
+
+// SetDefinitionSecrets encrypts and upserts the given secret values.
+func (r *jobRepository) SetDefinitionSecrets(tenantID, jobDefID string, secrets map[string]string) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const query = `
+		INSERT INTO tenant.definition_secrets (job_definition_id, tenant_id, name, value)
+		SELECT jd.id, jd.tenant_id, $3, $4
+		FROM tenant.job_definitions jd
+		WHERE jd.id = $1 AND jd.tenant_id = $2 AND jd.deleted_at IS NULL
+		ON CONFLICT (job_definition_id, name) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = now()
+	`
+	for name, value := range secrets {
+		enc, err := utils.EncryptSecret(value)
+		if err != nil {
+			return fmt.Errorf("encrypt secret %q: %w", name, err)
+		}
+		res, err := tx.Exec(query, jobDefID, tenantID, name, enc)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("job definition not found")
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *jobRepository) ListDefinitionSecrets(tenantID, jobDefID string) ([]models.DefinitionSecret, error) {
+	const query = `
+		SELECT job_definition_id, name, created_at, updated_at
+		FROM tenant.definition_secrets
+		WHERE job_definition_id = $1 AND tenant_id = $2
+		ORDER BY name
+	`
+	rows, err := r.db.Query(query, jobDefID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	secrets := []models.DefinitionSecret{}
+	for rows.Next() {
+		var secret models.DefinitionSecret
+		if err := rows.Scan(&secret.JobDefinitionID, &secret.Name, &secret.CreatedAt, &secret.UpdatedAt); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+func (r *jobRepository) DeleteDefinitionSecret(tenantID, jobDefID, name string) error {
+	const query = `
+		DELETE FROM tenant.definition_secrets
+		WHERE job_definition_id = $1 AND tenant_id = $2 AND name = $3
+	`
+	res, err := r.db.Exec(query, jobDefID, tenantID, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("secret not found")
+	}
+	return nil
+}
+
+// GetDefinitionSecretValues returns the decrypted secret values of a definition. It is
+// meant for execution preparation only; API responses must never include them.
+func (r *jobRepository) GetDefinitionSecretValues(tenantID, jobDefID string) (map[string]string, error) {
+	const query = `
+		SELECT name, value
+		FROM tenant.definition_secrets
+		WHERE job_definition_id = $1 AND tenant_id = $2
+	`
+	rows, err := r.db.Query(query, jobDefID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var (
+			name string
+			enc  []byte
+		)
+		if err := rows.Scan(&name, &enc); err != nil {
+			return nil, err
+		}
+		plain, err := utils.DecryptSecret(enc)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt secret %q: %w", name, err)
+		}
+		values[name] = plain
+	}
+	return values, rows.Err()
+}
+
+const executionNoteSelectColumns = `
+	SELECT n.id, n.execution_id, n.tenant_id, n.author_id, COALESCE(u.email, ''), n.body, n.created_at, n.updated_at
+	FROM tenant.execution_notes n
+	LEFT JOIN tenant.users u ON u.id = n.author_id
+`
+
+func scanExecutionNote(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.ExecutionNote, error) {
+	var note models.ExecutionNote
+	err := scanner.Scan(
+		&note.ID,
+		&note.ExecutionID,
+		&note.TenantID,
+		&note.AuthorID,
+		&note.AuthorEmail,
+		&note.Body,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+	)
+	return note, err
+}
+
+func (r *jobRepository) CreateExecutionNote(note models.ExecutionNote) (models.ExecutionNote, error) {
+	const query = `
+		INSERT INTO tenant.execution_notes (execution_id, tenant_id, author_id, body)
+		SELECT e.id, e.tenant_id, $3, $4
+		FROM tenant.job_executions e
+		WHERE e.id = $1 AND e.tenant_id = $2
+		RETURNING id
+	`
+	var id string
+	if err := r.db.QueryRow(query, note.ExecutionID, note.TenantID, note.AuthorID, note.Body).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return note, errors.New("execution not found")
+		}
+		return note, err
+	}
+	return scanExecutionNote(r.db.QueryRow(executionNoteSelectColumns+`WHERE n.id = $1 AND n.tenant_id = $2`, id, note.TenantID))
+}
+
+func (r *jobRepository) ListExecutionNotes(tenantID, execID string) ([]models.ExecutionNote, error) {
+	query := executionNoteSelectColumns + `
+		WHERE n.execution_id = $1 AND n.tenant_id = $2
+		ORDER BY n.created_at
+	`
+	return r.queryExecutionNotes(query, execID, tenantID)
+}
+
+// SearchExecutionNotes full-text searches a tenant's notes, best matches first.
+func (r *jobRepository) SearchExecutionNotes(tenantID, query string, limit int) ([]models.ExecutionNote, error) {
+	q := executionNoteSelectColumns + `
+		WHERE n.tenant_id = $1 AND n.search_vector @@ websearch_to_tsquery('simple', $2)
+		ORDER BY ts_rank(n.search_vector, websearch_to_tsquery('simple', $2)) DESC, n.created_at DESC
+		LIMIT $3
+	`
+	return r.queryExecutionNotes(q, tenantID, query, limit)
+}
+
+func (r *jobRepository) DeleteExecutionNote(tenantID, execID, noteID string) error {
+	const query = `
+		DELETE FROM tenant.execution_notes
+		WHERE id = $1 AND execution_id = $2 AND tenant_id = $3
+	`
+	res, err := r.db.Exec(query, noteID, execID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("execution note not found")
+	}
+	return nil
+}
+
+func (r *jobRepository) queryExecutionNotes(query string, args ...interface{}) ([]models.ExecutionNote, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.ExecutionNote{}
+	for rows.Next() {
+		note, err := scanExecutionNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// GetExecutionBaseline computes the definition's baseline from its last runs
+// succeeded executions, leaving out excludeExecID (the run being compared).
+func (r *jobRepository) GetExecutionBaseline(tenantID, jobDefID, excludeExecID string, runs int) (models.ExecutionBaseline, error) {
+	const query = `
+		WITH recent AS (
+			SELECT EXTRACT(EPOCH FROM (run_completed_at - run_started_at))::float8 AS duration,
+			       records_processed
+			FROM tenant.job_executions
+			WHERE tenant_id = $1 AND job_definition_id = $2 AND id::text <> $3
+			  AND status = 'succeeded'
+			  AND run_started_at IS NOT NULL AND run_completed_at > run_started_at
+			ORDER BY run_completed_at DESC
+			LIMIT $4
+		)
+		SELECT COUNT(*),
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY duration), 0),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY records_processed / duration)
+		           FILTER (WHERE records_processed > 0)
+		FROM recent
+	`
+	var (
+		baseline   models.ExecutionBaseline
+		throughput sql.NullFloat64
+	)
+	if err := r.db.QueryRow(query, tenantID, jobDefID, excludeExecID, runs).Scan(&baseline.Runs, &baseline.MedianDurationSeconds, &throughput); err != nil {
+		return models.ExecutionBaseline{}, err
+	}
+	if throughput.Valid {
+		baseline.MedianThroughput = &throughput.Float64
+	}
+	return baseline, nil
+}
+
+// SaveExecutionRegressions stores detected regressions. Saving the same execution
+// and metric again keeps the first record.
+func (r *jobRepository) SaveExecutionRegressions(regressions []models.ExecutionRegression) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const query = `
+		INSERT INTO tenant.execution_regressions
+			(tenant_id, job_definition_id, execution_id, metric, baseline_value, observed_value, deviation, baseline_runs, engine_image_digest)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (execution_id, metric) DO NOTHING
+	`
+	for _, reg := range regressions {
+		if _, err := tx.Exec(query, reg.TenantID, reg.JobDefinitionID, reg.ExecutionID, reg.Metric,
+			reg.BaselineValue, reg.ObservedValue, reg.Deviation, reg.BaselineRuns, reg.EngineImageDigest); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListExecutionRegressions returns the definition's regressions, newest first.
+func (r *jobRepository) ListExecutionRegressions(tenantID, jobDefID string, limit int) ([]models.ExecutionRegression, error) {
+	const query = `
+		SELECT id, tenant_id, job_definition_id, execution_id, metric, baseline_value, observed_value,
+		       deviation, baseline_runs, engine_image_digest, created_at
+		FROM tenant.execution_regressions
+		WHERE tenant_id = $1 AND job_definition_id = $2
+		ORDER BY created_at DESC, metric
+		LIMIT $3
+	`
+	rows, err := r.db.Query(query, tenantID, jobDefID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	regressions := []models.ExecutionRegression{}
+	for rows.Next() {
+		var reg models.ExecutionRegression
+		if err := rows.Scan(&reg.ID, &reg.TenantID, &reg.JobDefinitionID, &reg.ExecutionID, &reg.Metric,
+			&reg.BaselineValue, &reg.ObservedValue, &reg.Deviation, &reg.BaselineRuns, &reg.EngineImageDigest, &reg.CreatedAt); err != nil {
+			return nil, err
+		}
+		regressions = append(regressions, reg)
+	}
+	return regressions, rows.Err()
+}
+
+// CountDataMovingRuns counts the definition's succeeded executions, other than
+// excludeExecID, that reported moving records or bytes.
+func (r *jobRepository) CountDataMovingRuns(tenantID, jobDefID, excludeExecID string) (int, error) {
+	const query = `
+		SELECT COUNT(*)
+		FROM tenant.job_executions
+		WHERE tenant_id = $1 AND job_definition_id = $2 AND id::text <> $3
+		  AND status = 'succeeded'
+		  AND (records_processed > 0 OR bytes_transferred > 0)
+	`
+	var count int
+	err := r.db.QueryRow(query, tenantID, jobDefID, excludeExecID).Scan(&count)
+	return count, err
+}
+
+func (r *jobRepository) MarkExecutionSuspect(tenantID, execID, reason string) error {
+	const query = `
+		UPDATE tenant.job_executions
+		SET suspect = TRUE, suspect_reason = $3, updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+	`
+	result, err := r.db.Exec(query, tenantID, execID, reason)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobRepository) ListDeadLetterExecutions(tenantID string, limit int) ([]models.JobExecution, error) {
+	query := executionSelectColumns + `
+		WHERE tenant_id = $1 AND status = 'failed' AND requeued_as_execution_id IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := make([]models.JobExecution, 0)
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+func (r *jobRepository) MarkExecutionRequeued(tenantID, execID, requeuedAsID string) (bool, error) {
+	const query = `
+		UPDATE tenant.job_executions
+		SET requeued_as_execution_id = $3, requeued_at = now(), updated_at = now()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'failed' AND requeued_as_execution_id IS NULL
+	`
+	result, err := r.db.Exec(query, tenantID, execID, requeuedAsID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *jobRepository) ClearExecutionRequeued(tenantID, execID, requeuedAsID string) error {
+	const query = `
+		UPDATE tenant.job_executions
+		SET requeued_as_execution_id = NULL, requeued_at = NULL, updated_at = now()
+		WHERE tenant_id = $1 AND id = $2 AND requeued_as_execution_id = $3
+	`
+	_, err := r.db.Exec(query, tenantID, execID, requeuedAsID)
+	return err
+}
+
+// RecordActivityAttempt stores the latest attempt number of a retried activity.
+// Attempts of executions that have no record yet are ignored.
+func (r *jobRepository) RecordActivityAttempt(tenantID, execID, activity string, attempt int32) error {
+	const query = `
+		UPDATE tenant.job_executions
+		SET activity_attempts = jsonb_set(activity_attempts, ARRAY[$3::text], to_jsonb($4::int)), updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+	`
+	_, err := r.db.Exec(query, tenantID, execID, activity, attempt)
+	return err
+}
+
+// RecordExecutionEvent appends an event to an execution's timeline. A zero
+// OccurredAt is stored as now.
+func (r *jobRepository) RecordExecutionEvent(tenantID, execID string, event models.ExecutionEvent) error {
+	var details interface{}
+	if len(event.Details) > 0 {
+		raw, err := json.Marshal(event.Details)
+		if err != nil {
+			return err
+		}
+		details = raw
+	}
+	var occurredAt interface{}
+	if !event.OccurredAt.IsZero() {
+		occurredAt = event.OccurredAt.UTC()
+	}
+	const query = `
+		INSERT INTO tenant.job_execution_events (execution_id, tenant_id, type, details, occurred_at)
+		SELECT id, tenant_id, $3, $4, COALESCE($5, now())
+		FROM tenant.job_executions
+		WHERE id = $1 AND tenant_id = $2
+	`
+	res, err := r.db.Exec(query, execID, tenantID, event.Type, details, occurredAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("execution not found")
+	}
+	return nil
+}
+
+func (r *jobRepository) ListExecutionEvents(tenantID, execID string) ([]models.ExecutionEvent, error) {
+	const query = `
+		SELECT id, execution_id, type, details, occurred_at
+		FROM tenant.job_execution_events
+		WHERE execution_id = $1 AND tenant_id = $2
+		ORDER BY occurred_at, id
+	`
+	rows, err := r.db.Query(query, execID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ExecutionEvent{}
+	for rows.Next() {
+		var e models.ExecutionEvent
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.ExecutionID, &e.Type, &details, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// RecordStartupStage stores when an execution completed a startup stage. A stage
+// completed again by a retried activity keeps the latest time.
+func (r *jobRepository) RecordStartupStage(tenantID, execID, stage string, at time.Time) error {
+	const query = `
+		UPDATE tenant.job_executions
+		SET startup_timings = jsonb_set(startup_timings, ARRAY[$3::text], to_jsonb($4::timestamptz)), updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+	`
+	_, err := r.db.Exec(query, tenantID, execID, stage, at.UTC())
+	return err
+}
+
+func (r *jobRepository) ListStartupTimings(tenantID string, since time.Time) ([]map[string]time.Time, error) {
+	const query = `
+		SELECT startup_timings
+		FROM tenant.job_executions
+		WHERE created_at >= $1
+		  AND ($2 = '' OR tenant_id::text = $2)
+		  AND startup_timings <> '{}'::jsonb
+	`
+	rows, err := r.db.Query(query, since, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var timings []map[string]time.Time
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var t map[string]time.Time
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, fmt.Errorf("decode startup timings: %w", err)
+		}
+		timings = append(timings, t)
+	}
+	return timings, rows.Err()
+}
+
+func (r *jobRepository) GetRunGrants(tenantID, jobDefID string) (models.RunGrants, error) {
+	const query = `
+		SELECT user_id, role
+		FROM tenant.job_run_grants
+		WHERE tenant_id = $1 AND job_definition_id = $2
+		ORDER BY role NULLS LAST, user_id
+	`
+	rows, err := r.db.Query(query, tenantID, jobDefID)
+	if err != nil {
+		return models.RunGrants{}, err
+	}
+	defer rows.Close()
+
+	grants := models.RunGrants{Users: []string{}, Roles: []models.UserRole{}}
+	for rows.Next() {
+		var userID, role sql.NullString
+		if err := rows.Scan(&userID, &role); err != nil {
+			return models.RunGrants{}, err
+		}
+		if userID.Valid {
+			grants.Users = append(grants.Users, userID.String)
+		} else {
+			grants.Roles = append(grants.Roles, models.UserRole(role.String))
+		}
+	}
+	return grants, rows.Err()
+}
+
+// SetRunGrants replaces the definition's run grants. Granted users must be active
+// members of the tenant.
+func (r *jobRepository) SetRunGrants(tenantID, jobDefID string, grants models.RunGrants, grantedBy string) (models.RunGrants, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.RunGrants{}, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(
+		`SELECT 1 FROM tenant.job_definitions WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		jobDefID, tenantID,
+	).Scan(&exists); err != nil {
+		return models.RunGrants{}, err
+	}
+	if _, err := tx.Exec(`DELETE FROM tenant.job_run_grants WHERE tenant_id = $1 AND job_definition_id = $2`, tenantID, jobDefID); err != nil {
+		return models.RunGrants{}, err
+	}
+
+	var createdBy interface{}
+	if grantedBy != "" {
+		createdBy = grantedBy
+	}
+	const grantUser = `
+		INSERT INTO tenant.job_run_grants (tenant_id, job_definition_id, user_id, created_by)
+		SELECT $1, $2, u.id, $4
+		FROM tenant.users u
+		WHERE u.id::text = $3 AND u.tenant_id = $1 AND u.deleted_at IS NULL
+		ON CONFLICT DO NOTHING
+	`
+	for _, userID := range grants.Users {
+		res, err := tx.Exec(grantUser, tenantID, jobDefID, userID, createdBy)
+		if err != nil {
+			return models.RunGrants{}, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return models.RunGrants{}, err
+		} else if n == 0 {
+			return models.RunGrants{}, fmt.Errorf("%w: %s", ErrRunGrantUserNotFound, userID)
+		}
+	}
+	const grantRole = `
+		INSERT INTO tenant.job_run_grants (tenant_id, job_definition_id, role, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	for _, role := range grants.Roles {
+		if _, err := tx.Exec(grantRole, tenantID, jobDefID, string(role), createdBy); err != nil {
+			return models.RunGrants{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.RunGrants{}, err
+	}
+	return r.GetRunGrants(tenantID, jobDefID)
+}
+
+const scheduleColumns = `s.job_definition_id, jd.name, s.tenant_id, s.cron_expression, s.timezone,
+	s.temporal_schedule_id, s.created_by, s.created_at, s.updated_at, s.paused_at, s.paused_for_connection_id`
+
+func scanSchedule(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.JobSchedule, error) {
+	var s models.JobSchedule
+	err := scanner.Scan(&s.JobDefinitionID, &s.JobDefinitionName, &s.TenantID, &s.CronExpression, &s.Timezone,
+		&s.ScheduleID, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt, &s.PausedAt, &s.PausedForConnectionID)
+	return s, err
+}
+
+func (r *jobRepository) GetSchedule(tenantID, jobDefID string) (models.JobSchedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM tenant.job_schedules s
+		JOIN tenant.job_definitions jd ON jd.id = s.job_definition_id
+		WHERE s.tenant_id = $1 AND s.job_definition_id = $2 AND jd.deleted_at IS NULL
+	`
+	return scanSchedule(r.db.QueryRow(query, tenantID, jobDefID))
+}
+
+func (r *jobRepository) ListSchedules(tenantID string) ([]models.JobSchedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM tenant.job_schedules s
+		JOIN tenant.job_definitions jd ON jd.id = s.job_definition_id
+		WHERE s.tenant_id = $1 AND jd.deleted_at IS NULL
+		ORDER BY jd.name
+	`
+	rows, err := r.db.Query(query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.JobSchedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *jobRepository) SaveSchedule(schedule models.JobSchedule) (models.JobSchedule, error) {
+	const query = `
+		INSERT INTO tenant.job_schedules (job_definition_id, tenant_id, cron_expression, timezone, temporal_schedule_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (job_definition_id) DO UPDATE
+		SET cron_expression = EXCLUDED.cron_expression,
+		    timezone = EXCLUDED.timezone,
+		    temporal_schedule_id = EXCLUDED.temporal_schedule_id,
+		    updated_at = now()
+	`
+	if _, err := r.db.Exec(query, schedule.JobDefinitionID, schedule.TenantID, schedule.CronExpression,
+		schedule.Timezone, schedule.ScheduleID, schedule.CreatedBy); err != nil {
+		return models.JobSchedule{}, err
+	}
+	return r.GetSchedule(schedule.TenantID, schedule.JobDefinitionID)
+}
+
+func (r *jobRepository) SetSchedulePaused(tenantID, jobDefID string, connectionID *string) error {
+	const query = `
+		UPDATE tenant.job_schedules
+		SET paused_at = CASE WHEN $3::uuid IS NULL THEN NULL ELSE now() END,
+		    paused_for_connection_id = $3,
+		    updated_at = now()
+		WHERE tenant_id = $1 AND job_definition_id = $2
+	`
+	res, err := r.db.Exec(query, tenantID, jobDefID, connectionID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobRepository) DeleteSchedule(tenantID, jobDefID string) error {
+	res, err := r.db.Exec(`DELETE FROM tenant.job_schedules WHERE tenant_id = $1 AND job_definition_id = $2`, tenantID, jobDefID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobRepository) CreateRunGroup(group models.RunGroup) (models.RunGroup, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return group, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO tenant.run_groups (tenant_id, name, parallelism, stop_on_failure, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at`,
+		group.TenantID, group.Name, group.Parallelism, group.StopOnFailure, group.CreatedBy,
+	).Scan(&group.ID, &group.Status, &group.CreatedAt)
+	if err != nil {
+		return group, err
+	}
+	for i, m := range group.Members {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant.run_group_members (group_id, position, job_definition_id, execution_id)
+			VALUES ($1, $2, $3, $4)`,
+			group.ID, m.Position, m.JobDefinitionID, m.ExecutionID); err != nil {
+			return group, err
+		}
+		group.Members[i].Status = models.RunGroupMemberPending
+	}
+	if err := tx.Commit(); err != nil {
+		return group, err
+	}
+	group.Summarize()
+	return group, nil
+}
+
+func (r *jobRepository) GetRunGroup(tenantID, groupID string) (models.RunGroup, error) {
+	var group models.RunGroup
+	err := r.db.QueryRow(`
+		SELECT id, tenant_id, name, parallelism, stop_on_failure, status, created_by, created_at, completed_at
+		FROM tenant.run_groups
+		WHERE tenant_id = $1 AND id = $2`, tenantID, groupID,
+	).Scan(&group.ID, &group.TenantID, &group.Name, &group.Parallelism, &group.StopOnFailure,
+		&group.Status, &group.CreatedBy, &group.CreatedAt, &group.CompletedAt)
+	if err != nil {
+		return group, err
+	}
+
+	// Members without an execution row have not started; the workflow creates it.
+	rows, err := r.db.Query(`
+		SELECT m.position, m.job_definition_id, COALESCE(d.name, ''), m.execution_id,
+		       COALESCE(e.status, m.status), e.error_message, e.records_processed, e.bytes_transferred,
+		       e.run_started_at, e.run_completed_at
+		FROM tenant.run_group_members m
+		LEFT JOIN tenant.job_definitions d ON d.id = m.job_definition_id
+		LEFT JOIN tenant.job_executions e ON e.id = m.execution_id AND e.tenant_id = $2
+		WHERE m.group_id = $1
+		ORDER BY m.position`, groupID, tenantID)
+	if err != nil {
+		return group, err
+	}
+	defer rows.Close()
+	group.Members = []models.RunGroupMember{}
+	for rows.Next() {
+		var m models.RunGroupMember
+		if err := rows.Scan(&m.Position, &m.JobDefinitionID, &m.JobDefinitionName, &m.ExecutionID,
+			&m.Status, &m.ErrorMessage, &m.RecordsProcessed, &m.BytesTransferred,
+			&m.StartedAt, &m.CompletedAt); err != nil {
+			return group, err
+		}
+		group.Members = append(group.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return group, err
+	}
+	group.Summarize()
+	return group, nil
+}
+
+func (r *jobRepository) SkipRunGroupMembers(tenantID, groupID string, executionIDs []string) error {
+	_, err := r.db.Exec(`
+		UPDATE tenant.run_group_members m
+		SET status = 'skipped'
+		FROM tenant.run_groups g
+		WHERE g.id = m.group_id AND g.tenant_id = $1 AND m.group_id = $2
+		  AND m.execution_id = ANY($3::uuid[])`,
+		tenantID, groupID, pq.Array(executionIDs))
+	return err
+}
+
+func (r *jobRepository) CompleteRunGroup(tenantID, groupID string, report models.RunGroupReport) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.Exec(`
+		UPDATE tenant.run_groups
+		SET status = $3, report = $4, completed_at = $5
+		WHERE tenant_id = $1 AND id = $2`,
+		tenantID, groupID, report.Status, raw, report.CompletedAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobRepository) GetRunGroupReport(tenantID, groupID string) (models.RunGroupReport, error) {
+	var (
+		report models.RunGroupReport
+		raw    []byte
+	)
+	err := r.db.QueryRow(`
+		SELECT report
+		FROM tenant.run_groups
+		WHERE tenant_id = $1 AND id = $2 AND report IS NOT NULL`, tenantID, groupID,
+	).Scan(&raw)
+	if err != nil {
+		return report, err
+	}
+	err = json.Unmarshal(raw, &report)
+	return report, err
+}
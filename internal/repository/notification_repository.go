@@ -6,14 +6,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stanstork/stratum-api/internal/models"
 )
 
 type NotificationRepository interface {
 	Create(ctx context.Context, params CreateNotificationParams) (models.Notification, error)
 	ListRecent(ctx context.Context, tenantID string, limit int) ([]models.Notification, error)
+	// ListGroups returns the most recently active notification groups, each
+	// collapsed into its latest notification.
+	ListGroups(ctx context.Context, tenantID string, limit int) ([]models.NotificationGroup, error)
 	MarkRead(ctx context.Context, tenantID, notificationID string) (models.Notification, error)
+	// MarkGroupRead marks every unread notification with the correlation key read
+	// and returns how many it marked; sql.ErrNoRows when the group has none.
+	MarkGroupRead(ctx context.Context, tenantID, correlationKey string) (int64, error)
+	ListVersion(ctx context.Context, tenantID string) (string, error)
 }
 
 type notificationRepository struct {
@@ -21,24 +30,26 @@ type notificationRepository struct {
 }
 
 type CreateNotificationParams struct {
-	TenantID *string
-	Event    models.NotificationEvent
-	Severity models.NotificationSeverity
-	Title    string
-	Message  string
-	Metadata map[string]interface{}
+	TenantID       *string
+	CorrelationKey string
+	Event          models.NotificationEvent
+	Severity       models.NotificationSeverity
+	Title          string
+	Message        string
+	Metadata       map[string]interface{}
 }
 
+const notificationColumns = `id, tenant_id, correlation_key, event_type, severity, title, message, metadata, created_at, read_at`
+
 func NewNotificationRepository(db *sql.DB) NotificationRepository {
 	return &notificationRepository{db: db}
 }
 
 func (r *notificationRepository) Create(ctx context.Context, params CreateNotificationParams) (models.Notification, error) {
 	const query = `
-		INSERT INTO tenant.notifications (tenant_id, event_type, severity, title, message, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, tenant_id, event_type, severity, title, message, metadata, created_at, read_at
-	`
+		INSERT INTO tenant.notifications (tenant_id, event_type, severity, title, message, metadata, correlation_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + notificationColumns
 
 	var tenantID interface{}
 	if params.TenantID != nil && strings.TrimSpace(*params.TenantID) != "" {
@@ -54,7 +65,8 @@ func (r *notificationRepository) Create(ctx context.Context, params CreateNotifi
 		metadata = bytes
 	}
 
-	row := r.db.QueryRowContext(ctx, query, tenantID, params.Event, params.Severity, params.Title, params.Message, metadata)
+	row := r.db.QueryRowContext(ctx, query, tenantID, params.Event, params.Severity, params.Title, params.Message, metadata,
+		nullIfEmpty(params.CorrelationKey))
 	return scanNotification(row)
 }
 
@@ -64,7 +76,7 @@ func (r *notificationRepository) ListRecent(ctx context.Context, tenantID string
 	}
 
 	const query = `
-		SELECT id, tenant_id, event_type, severity, title, message, metadata, created_at, read_at
+		SELECT ` + notificationColumns + `
 		FROM tenant.notifications
 		WHERE tenant_id IS NULL OR tenant_id = $1
 		ORDER BY created_at DESC
@@ -96,25 +108,134 @@ func (r *notificationRepository) MarkRead(ctx context.Context, tenantID, notific
 		UPDATE tenant.notifications
 		SET read_at = NOW()
 		WHERE id = $1 AND (tenant_id IS NULL OR tenant_id = $2)
-		RETURNING id, tenant_id, event_type, severity, title, message, metadata, created_at, read_at
-	`
+		RETURNING ` + notificationColumns
 	row := r.db.QueryRowContext(ctx, query, strings.TrimSpace(notificationID), strings.TrimSpace(tenantID))
 	return scanNotification(row)
 }
 
+func (r *notificationRepository) ListGroups(ctx context.Context, tenantID string, limit int) ([]models.NotificationGroup, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	// A notification without a correlation key is keyed by its own ID, so it
+	// forms a group of one.
+	const query = `
+		WITH visible AS (
+			SELECT ` + notificationColumns + `, COALESCE(correlation_key, id::text) AS group_key
+			FROM tenant.notifications
+			WHERE tenant_id IS NULL OR tenant_id = $1
+		),
+		groups AS (
+			SELECT
+				group_key,
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE read_at IS NULL) AS unread,
+				MIN(created_at) AS first_at,
+				(array_agg(event_type ORDER BY created_at DESC) FILTER (WHERE event_type = ANY($3)))[1] AS status_event
+			FROM visible
+			GROUP BY group_key
+		),
+		latest AS (
+			SELECT DISTINCT ON (v.group_key) v.*, g.total, g.unread, g.first_at, COALESCE(g.status_event, '') AS status_event
+			FROM visible v
+			JOIN groups g ON g.group_key = v.group_key
+			ORDER BY v.group_key, v.created_at DESC, v.id DESC
+		)
+		SELECT ` + notificationColumns + `, total, unread, first_at, status_event
+		FROM latest
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, strings.TrimSpace(tenantID), limit, pq.Array(models.ExecutionLifecycleEvents()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.NotificationGroup{}
+	for rows.Next() {
+		var (
+			group       models.NotificationGroup
+			statusEvent models.NotificationEvent
+		)
+		notif, err := scanNotification(rows, &group.Count, &group.UnreadCount, &group.FirstAt, &statusEvent)
+		if err != nil {
+			return nil, err
+		}
+		group.CorrelationKey = notif.CorrelationKey
+		group.Latest = notif
+		group.Status = models.StatusAfter(statusEvent)
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+func (r *notificationRepository) MarkGroupRead(ctx context.Context, tenantID, correlationKey string) (int64, error) {
+	const query = `
+		WITH members AS (
+			SELECT id, read_at
+			FROM tenant.notifications
+			WHERE correlation_key = $1 AND (tenant_id IS NULL OR tenant_id = $2)
+		),
+		marked AS (
+			UPDATE tenant.notifications n
+			SET read_at = NOW()
+			FROM members m
+			WHERE n.id = m.id AND m.read_at IS NULL
+			RETURNING n.id
+		)
+		SELECT (SELECT COUNT(*) FROM members), (SELECT COUNT(*) FROM marked)
+	`
+	var members, marked int64
+	if err := r.db.QueryRowContext(ctx, query, strings.TrimSpace(correlationKey), strings.TrimSpace(tenantID)).Scan(&members, &marked); err != nil {
+		return 0, err
+	}
+	if members == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return marked, nil
+}
+
+// ListVersion returns a cheap fingerprint of the notifications visible to a tenant.
+// Notifications are never updated except for read_at, so that is folded in as well.
+func (r *notificationRepository) ListVersion(ctx context.Context, tenantID string) (string, error) {
+	const query = `
+		SELECT
+			COUNT(*),
+			COALESCE(MAX(created_at), 'epoch'::timestamptz),
+			COALESCE(MAX(read_at), 'epoch'::timestamptz)
+		FROM tenant.notifications
+		WHERE tenant_id IS NULL OR tenant_id = $1
+	`
+	var (
+		count   int64
+		created time.Time
+		read    time.Time
+	)
+	if err := r.db.QueryRowContext(ctx, query, strings.TrimSpace(tenantID)).Scan(&count, &created, &read); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d-%d", count, created.UnixNano(), read.UnixNano()), nil
+}
+
+// scanNotification scans notificationColumns, followed by extra when a query
+// selects more.
 func scanNotification(scanner interface {
 	Scan(dest ...interface{}) error
-}) (models.Notification, error) {
+}, extra ...interface{}) (models.Notification, error) {
 	var (
-		notif       models.Notification
-		tenantID    sql.NullString
-		metadataRaw []byte
-		readAt      sql.NullTime
+		notif          models.Notification
+		tenantID       sql.NullString
+		correlationKey sql.NullString
+		metadataRaw    []byte
+		readAt         sql.NullTime
 	)
 
-	if err := scanner.Scan(
+	dest := []interface{}{
 		&notif.ID,
 		&tenantID,
+		&correlationKey,
 		&notif.EventType,
 		&notif.Severity,
 		&notif.Title,
@@ -122,10 +243,12 @@ func scanNotification(scanner interface {
 		&metadataRaw,
 		&notif.CreatedAt,
 		&readAt,
-	); err != nil {
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
 		return models.Notification{}, err
 	}
 
+	notif.CorrelationKey = correlationKey.String
 	if tenantID.Valid {
 		val := tenantID.String
 		notif.TenantID = &val
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/utils"
+)
+
+type RegistryRepository interface {
+	ListRegistryCredentials() ([]models.RegistryCredential, error)
+	// GetRegistryCredential returns the credential of a registry host with its
+	// decrypted password, or sql.ErrNoRows when the registry has none.
+	GetRegistryCredential(registry string) (models.RegistryCredential, string, error)
+	// SaveRegistryCredential creates or replaces the credential of cred.Registry.
+	// An empty password keeps the stored one.
+	SaveRegistryCredential(cred models.RegistryCredential, password string) (models.RegistryCredential, error)
+	// DeleteRegistryCredential returns sql.ErrNoRows when the credential does not exist.
+	DeleteRegistryCredential(id string) (models.RegistryCredential, error)
+}
+
+type registryRepository struct {
+	db *sql.DB
+}
+
+func NewRegistryRepository(db *sql.DB) RegistryRepository {
+	return &registryRepository{db: db}
+}
+
+const registryCredentialColumns = `id, registry, auth_type, username, helper, password_enc IS NOT NULL, created_by, created_at, updated_at`
+
+func scanRegistryCredential(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.RegistryCredential, error) {
+	var cred models.RegistryCredential
+	err := scanner.Scan(
+		&cred.ID,
+		&cred.Registry,
+		&cred.AuthType,
+		&cred.Username,
+		&cred.Helper,
+		&cred.HasPassword,
+		&cred.CreatedBy,
+		&cred.CreatedAt,
+		&cred.UpdatedAt,
+	)
+	return cred, err
+}
+
+func (r *registryRepository) ListRegistryCredentials() ([]models.RegistryCredential, error) {
+	rows, err := r.db.Query(`SELECT ` + registryCredentialColumns + ` FROM tenant.registry_credentials ORDER BY registry`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	creds := []models.RegistryCredential{}
+	for rows.Next() {
+		cred, err := scanRegistryCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+func (r *registryRepository) GetRegistryCredential(registry string) (models.RegistryCredential, string, error) {
+	var enc []byte
+	row := r.db.QueryRow(`
+		SELECT `+registryCredentialColumns+`, password_enc
+		FROM tenant.registry_credentials
+		WHERE registry = $1`, registry)
+	var cred models.RegistryCredential
+	if err := row.Scan(
+		&cred.ID,
+		&cred.Registry,
+		&cred.AuthType,
+		&cred.Username,
+		&cred.Helper,
+		&cred.HasPassword,
+		&cred.CreatedBy,
+		&cred.CreatedAt,
+		&cred.UpdatedAt,
+		&enc,
+	); err != nil {
+		return cred, "", err
+	}
+	if enc == nil {
+		return cred, "", nil
+	}
+	password, err := utils.DecryptSecret(enc)
+	if err != nil {
+		return cred, "", err
+	}
+	return cred, password, nil
+}
+
+func (r *registryRepository) SaveRegistryCredential(cred models.RegistryCredential, password string) (models.RegistryCredential, error) {
+	var enc []byte
+	if password != "" {
+		sealed, err := utils.EncryptSecret(password)
+		if err != nil {
+			return cred, err
+		}
+		enc = sealed
+	}
+	return scanRegistryCredential(r.db.QueryRow(`
+		INSERT INTO tenant.registry_credentials (registry, auth_type, username, password_enc, helper, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (registry) DO UPDATE
+		SET auth_type = EXCLUDED.auth_type,
+		    username = EXCLUDED.username,
+		    password_enc = CASE
+		        WHEN EXCLUDED.auth_type <> 'basic' THEN NULL
+		        ELSE COALESCE(EXCLUDED.password_enc, tenant.registry_credentials.password_enc)
+		    END,
+		    helper = EXCLUDED.helper,
+		    updated_at = now()
+		RETURNING `+registryCredentialColumns,
+		cred.Registry, cred.AuthType, cred.Username, enc, cred.Helper, cred.CreatedBy))
+}
+
+func (r *registryRepository) DeleteRegistryCredential(id string) (models.RegistryCredential, error) {
+	return scanRegistryCredential(r.db.QueryRow(`
+		DELETE FROM tenant.registry_credentials
+		WHERE id = $1
+		RETURNING `+registryCredentialColumns, id))
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type SCIMTokenRepository interface {
+	// SetSCIMToken stores the tenant's SCIM token, replacing any previous one.
+	SetSCIMToken(token models.SCIMToken) (models.SCIMToken, error)
+	GetSCIMToken(tenantID string) (models.SCIMToken, error)
+	DeleteSCIMToken(tenantID string) error
+	// AuthenticateSCIMToken resolves the token of a tenant that is not suspended by
+	// hash and records that it was used.
+	AuthenticateSCIMToken(tokenHash string) (models.SCIMToken, error)
+}
+
+type scimTokenRepository struct {
+	db *sql.DB
+}
+
+func NewSCIMTokenRepository(db *sql.DB) SCIMTokenRepository {
+	return &scimTokenRepository{db: db}
+}
+
+const scimTokenColumns = `tenant_id, token_prefix, token_hash, created_by, created_at, last_used_at`
+
+func scanSCIMToken(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.SCIMToken, error) {
+	var (
+		token     models.SCIMToken
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&token.TenantID,
+		&token.TokenPrefix,
+		&token.TokenHash,
+		&createdBy,
+		&token.CreatedAt,
+		&token.LastUsedAt,
+	); err != nil {
+		return models.SCIMToken{}, err
+	}
+	if createdBy.Valid {
+		token.CreatedBy = &createdBy.String
+	}
+	return token, nil
+}
+
+func (r *scimTokenRepository) SetSCIMToken(token models.SCIMToken) (models.SCIMToken, error) {
+	const query = `
+		INSERT INTO tenant.scim_tokens (tenant_id, token_prefix, token_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET token_prefix = EXCLUDED.token_prefix,
+		    token_hash = EXCLUDED.token_hash,
+		    created_by = EXCLUDED.created_by,
+		    created_at = now(),
+		    last_used_at = NULL
+		RETURNING ` + scimTokenColumns
+	var createdBy interface{}
+	if token.CreatedBy != nil && *token.CreatedBy != "" {
+		createdBy = *token.CreatedBy
+	}
+	return scanSCIMToken(r.db.QueryRow(query, token.TenantID, token.TokenPrefix, token.TokenHash, createdBy))
+}
+
+func (r *scimTokenRepository) GetSCIMToken(tenantID string) (models.SCIMToken, error) {
+	return scanSCIMToken(r.db.QueryRow(`SELECT `+scimTokenColumns+` FROM tenant.scim_tokens WHERE tenant_id = $1`, tenantID))
+}
+
+func (r *scimTokenRepository) DeleteSCIMToken(tenantID string) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.scim_tokens WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *scimTokenRepository) AuthenticateSCIMToken(tokenHash string) (models.SCIMToken, error) {
+	const query = `
+		/* cross-tenant: the token identifies the tenant */
+		UPDATE tenant.scim_tokens
+		SET last_used_at = now()
+		WHERE token_hash = $1
+		  AND tenant_id IN (SELECT id FROM tenant.tenants WHERE suspended_at IS NULL)
+		RETURNING ` + scimTokenColumns
+	return scanSCIMToken(r.db.QueryRow(query, tokenHash))
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type SensorRepository interface {
+	// ListSensors returns the definition's sensors. It returns sql.ErrNoRows when
+	// the definition is not the tenant's.
+	ListSensors(tenantID, jobDefID string) ([]models.JobSensor, error)
+	GetSensor(tenantID, jobDefID, sensorID string) (models.JobSensor, error)
+	// CreateSensor attaches a sensor to a definition of the sensor's tenant. It
+	// returns sql.ErrNoRows when the definition does not exist.
+	CreateSensor(sensor models.JobSensor) (models.JobSensor, error)
+	UpdateSensor(sensor models.JobSensor) (models.JobSensor, error)
+	DeleteSensor(tenantID, jobDefID, sensorID string) error
+
+	RecordSensorEvaluation(eval models.SensorEvaluation) error
+	// LastPassedSensorValue returns the value observed when the sensor last passed
+	// for an execution other than excludeExecID, or nil when it never did.
+	LastPassedSensorValue(tenantID, sensorID, excludeExecID string) (*int64, error)
+	// ListSensorEvaluations returns the definition's sensor evaluations, newest
+	// first. A non-empty sensorID or execID narrows the list. It returns
+	// sql.ErrNoRows when the definition is not the tenant's.
+	ListSensorEvaluations(tenantID, jobDefID, sensorID, execID string, limit int) ([]models.SensorEvaluation, error)
+}
+
+type sensorRepository struct {
+	db *sql.DB
+}
+
+func NewSensorRepository(db *sql.DB) SensorRepository {
+	return &sensorRepository{db: db}
+}
+
+const sensorColumns = `id, tenant_id, job_definition_id, name, kind, config, poke_interval_seconds, timeout_seconds,
+	on_timeout, enabled, created_at, updated_at`
+
+const sensorEvaluationColumns = `id, tenant_id, sensor_id, job_definition_id, execution_id, passed, observed_value,
+	detail, evaluated_at`
+
+func scanSensor(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.JobSensor, error) {
+	var (
+		s      models.JobSensor
+		config []byte
+	)
+	if err := scanner.Scan(
+		&s.ID,
+		&s.TenantID,
+		&s.JobDefinitionID,
+		&s.Name,
+		&s.Kind,
+		&config,
+		&s.PokeIntervalSeconds,
+		&s.TimeoutSeconds,
+		&s.OnTimeout,
+		&s.Enabled,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	); err != nil {
+		return s, err
+	}
+	s.Config = json.RawMessage(config)
+	return s, nil
+}
+
+// checkDefinition returns sql.ErrNoRows unless the definition is the tenant's.
+func (r *sensorRepository) checkDefinition(tenantID, jobDefID string) error {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM tenant.job_definitions WHERE tenant_id = $1 AND id = $2)`,
+		tenantID, jobDefID).Scan(&exists)
+	if err == nil && !exists {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+func (r *sensorRepository) ListSensors(tenantID, jobDefID string) ([]models.JobSensor, error) {
+	if err := r.checkDefinition(tenantID, jobDefID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + sensorColumns + `
+		FROM tenant.job_sensors
+		WHERE tenant_id = $1 AND job_definition_id = $2
+		ORDER BY name`
+	rows, err := r.db.Query(query, tenantID, jobDefID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sensors := []models.JobSensor{}
+	for rows.Next() {
+		s, err := scanSensor(rows)
+		if err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, rows.Err()
+}
+
+func (r *sensorRepository) GetSensor(tenantID, jobDefID, sensorID string) (models.JobSensor, error) {
+	query := `
+		SELECT ` + sensorColumns + `
+		FROM tenant.job_sensors
+		WHERE tenant_id = $1 AND job_definition_id = $2 AND id = $3`
+	return scanSensor(r.db.QueryRow(query, tenantID, jobDefID, sensorID))
+}
+
+func (r *sensorRepository) CreateSensor(sensor models.JobSensor) (models.JobSensor, error) {
+	query := `
+		INSERT INTO tenant.job_sensors
+			(tenant_id, job_definition_id, name, kind, config, poke_interval_seconds, timeout_seconds, on_timeout, enabled)
+		SELECT jd.tenant_id, jd.id, $3, $4, $5, $6, $7, $8, $9
+		FROM tenant.job_definitions jd
+		WHERE jd.tenant_id = $1 AND jd.id = $2
+		RETURNING ` + sensorColumns
+	return scanSensor(r.db.QueryRow(query, sensor.TenantID, sensor.JobDefinitionID, sensor.Name, sensor.Kind,
+		[]byte(sensor.Config), sensor.PokeIntervalSeconds, sensor.TimeoutSeconds, sensor.OnTimeout, sensor.Enabled))
+}
+
+func (r *sensorRepository) UpdateSensor(sensor models.JobSensor) (models.JobSensor, error) {
+	query := `
+		UPDATE tenant.job_sensors
+		SET name = $4, kind = $5, config = $6, poke_interval_seconds = $7, timeout_seconds = $8,
+		    on_timeout = $9, enabled = $10, updated_at = now()
+		WHERE tenant_id = $1 AND job_definition_id = $2 AND id = $3
+		RETURNING ` + sensorColumns
+	return scanSensor(r.db.QueryRow(query, sensor.TenantID, sensor.JobDefinitionID, sensor.ID, sensor.Name, sensor.Kind,
+		[]byte(sensor.Config), sensor.PokeIntervalSeconds, sensor.TimeoutSeconds, sensor.OnTimeout, sensor.Enabled))
+}
+
+func (r *sensorRepository) DeleteSensor(tenantID, jobDefID, sensorID string) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.job_sensors WHERE tenant_id = $1 AND job_definition_id = $2 AND id = $3`,
+		tenantID, jobDefID, sensorID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sensorRepository) RecordSensorEvaluation(eval models.SensorEvaluation) error {
+	_, err := r.db.Exec(`
+		INSERT INTO tenant.sensor_evaluations
+			(tenant_id, sensor_id, job_definition_id, execution_id, passed, observed_value, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		eval.TenantID, eval.SensorID, eval.JobDefinitionID, eval.ExecutionID, eval.Passed, eval.ObservedValue, eval.Detail)
+	return err
+}
+
+func (r *sensorRepository) LastPassedSensorValue(tenantID, sensorID, excludeExecID string) (*int64, error) {
+	var value sql.NullInt64
+	err := r.db.QueryRow(`
+		SELECT observed_value
+		FROM tenant.sensor_evaluations
+		WHERE tenant_id = $1 AND sensor_id = $2 AND execution_id::text <> $3 AND passed
+		ORDER BY evaluated_at DESC
+		LIMIT 1`, tenantID, sensorID, excludeExecID).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !value.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value.Int64, nil
+}
+
+func (r *sensorRepository) ListSensorEvaluations(tenantID, jobDefID, sensorID, execID string, limit int) ([]models.SensorEvaluation, error) {
+	if err := r.checkDefinition(tenantID, jobDefID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + sensorEvaluationColumns + `
+		FROM tenant.sensor_evaluations
+		WHERE tenant_id = $1 AND job_definition_id = $2
+		  AND ($3 = '' OR sensor_id::text = $3)
+		  AND ($4 = '' OR execution_id::text = $4)
+		ORDER BY evaluated_at DESC
+		LIMIT $5`
+	rows, err := r.db.Query(query, tenantID, jobDefID, sensorID, execID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	evals := []models.SensorEvaluation{}
+	for rows.Next() {
+		var (
+			e     models.SensorEvaluation
+			value sql.NullInt64
+		)
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.SensorID, &e.JobDefinitionID, &e.ExecutionID, &e.Passed,
+			&value, &e.Detail, &e.EvaluatedAt); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			e.ObservedValue = &value.Int64
+		}
+		evals = append(evals, e)
+	}
+	return evals, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type TemplateRepository interface {
+	ListTemplates(tenantID, kind string) ([]models.JobTemplate, error)
+	GetTemplate(tenantID, templateID string) (models.JobTemplate, error)
+	CreateTemplate(template models.JobTemplate) (models.JobTemplate, error)
+	UpdateTemplate(template models.JobTemplate) (models.JobTemplate, error)
+	DeleteTemplate(template models.JobTemplate) error
+}
+
+type templateRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+const templateColumns = `id, tenant_id, name, description, kind, ast, created_by, created_at, updated_at`
+
+func scanTemplate(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.JobTemplate, error) {
+	var (
+		t         models.JobTemplate
+		tenantID  sql.NullString
+		ast       []byte
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&t.ID,
+		&tenantID,
+		&t.Name,
+		&t.Description,
+		&t.Kind,
+		&ast,
+		&createdBy,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	); err != nil {
+		return t, err
+	}
+	if tenantID.Valid {
+		t.TenantID = &tenantID.String
+	}
+	t.Global = t.TenantID == nil
+	if createdBy.Valid {
+		t.CreatedBy = &createdBy.String
+	}
+	t.AST = json.RawMessage(ast)
+	placeholders, err := models.TemplatePlaceholders(t.AST)
+	if err != nil {
+		return t, err
+	}
+	t.Placeholders = placeholders
+	return t, nil
+}
+
+// templateTenant is the tenant_id value of a template: NULL for global templates.
+func templateTenant(t models.JobTemplate) interface{} {
+	if t.TenantID == nil {
+		return nil
+	}
+	return *t.TenantID
+}
+
+// ListTemplates returns the tenant's templates followed by the global ones, each
+// ordered by name. An empty kind returns every kind.
+func (r *templateRepository) ListTemplates(tenantID, kind string) ([]models.JobTemplate, error) {
+	query := `
+		SELECT ` + templateColumns + `
+		FROM tenant.job_templates
+		WHERE (tenant_id = $1 OR tenant_id IS NULL) AND ($2 = '' OR kind = $2)
+		ORDER BY tenant_id NULLS LAST, name`
+	rows, err := r.db.Query(query, tenantID, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.JobTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// GetTemplate returns one of the tenant's templates or a global template.
+func (r *templateRepository) GetTemplate(tenantID, templateID string) (models.JobTemplate, error) {
+	query := `
+		SELECT ` + templateColumns + `
+		FROM tenant.job_templates
+		WHERE id = $2 AND (tenant_id = $1 OR tenant_id IS NULL)`
+	return scanTemplate(r.db.QueryRow(query, tenantID, templateID))
+}
+
+func (r *templateRepository) CreateTemplate(template models.JobTemplate) (models.JobTemplate, error) {
+	query := `
+		INSERT INTO tenant.job_templates (tenant_id, name, description, kind, ast, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + templateColumns
+	var createdBy interface{}
+	if template.CreatedBy != nil {
+		createdBy = *template.CreatedBy
+	}
+	return scanTemplate(r.db.QueryRow(query, templateTenant(template), template.Name, template.Description,
+		template.Kind, []byte(template.AST), createdBy))
+}
+
+// UpdateTemplate replaces the template's name, description, kind and AST. The
+// template keeps its owner: a tenant template is never turned global or back.
+func (r *templateRepository) UpdateTemplate(template models.JobTemplate) (models.JobTemplate, error) {
+	query := `
+		UPDATE tenant.job_templates
+		SET name = $3, description = $4, kind = $5, ast = $6, updated_at = now()
+		WHERE id = $1 AND tenant_id IS NOT DISTINCT FROM $2
+		RETURNING ` + templateColumns
+	return scanTemplate(r.db.QueryRow(query, template.ID, templateTenant(template), template.Name,
+		template.Description, template.Kind, []byte(template.AST)))
+}
+
+func (r *templateRepository) DeleteTemplate(template models.JobTemplate) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.job_templates WHERE id = $1 AND tenant_id IS NOT DISTINCT FROM $2`,
+		template.ID, templateTenant(template))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
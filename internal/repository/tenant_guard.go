@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// TenantGuardDriverName is the database/sql driver the API opens its database
+// with. It is the Postgres driver, except that it refuses statements that read or
+// write a tenant table without a tenant predicate, so a lookup by a guessed ID can
+// never reach another tenant's rows. Migrations connect with the plain driver.
+const TenantGuardDriverName = "postgres-tenant-guard"
+
+// CrossTenantMarker opts a statement out of the tenant guard. It opens an SQL
+// comment giving the reason, as in "/* cross-tenant: workers poll every tenant */".
+const CrossTenantMarker = "/* cross-tenant:"
+
+// ErrMissingTenantPredicate is returned for statements refused by the tenant guard.
+var ErrMissingTenantPredicate = errors.New("statement on a tenant table has no tenant predicate")
+
+// tenantTables are the tables with a tenant_id column.
+var tenantTables = []string{
+	"access_logs", "announcements", "api_keys", "audit_events", "blackout_windows",
+	"connections", "definition_revalidations", "definition_secrets", "domain_join_requests",
+	"email_domains", "execution_artifacts", "execution_checkpoints", "execution_monthly_rollups",
+	"execution_notes", "execution_regressions", "execution_snapshots", "invites",
+	"job_definition_schema_checks", "job_definitions", "job_execution_events",
+	"job_execution_log_chunks", "job_execution_tables", "job_executions", "job_run_grants",
+	"job_schedules", "job_sensors", "job_templates", "notifications", "run_groups",
+	"saved_views", "scim_tokens", "sensor_evaluations", "tenant_execution_limits",
+	"tenant_worker_configs", "users", "webhook_deliveries", "webhook_subscriptions",
+}
+
+var (
+	tenantTablePattern = regexp.MustCompile(`\btenant\.(` + strings.Join(tenantTables, "|") + `)\b`)
+	// tenantPredicate matches tenant_id compared with a value or another tenant_id.
+	tenantPredicate = regexp.MustCompile(`(?i)\btenant_id(::\w+)?\s*(=|<>|!=|IN\s*\(|IS\s)|=\s*[\w.]*tenant_id\b`)
+	insertStatement = regexp.MustCompile(`(?is)^\s*(WITH\s+\w+\s+AS\s*\(\s*)?INSERT\b`)
+)
+
+// CheckTenantPredicate returns ErrMissingTenantPredicate when query touches a tenant
+// table without comparing tenant_id, or, for inserts, without setting it. It is a
+// textual check meant to catch lookups by ID alone; statements that must span
+// tenants carry CrossTenantMarker.
+func CheckTenantPredicate(query string) error {
+	table := tenantTablePattern.FindString(query)
+	if table == "" || strings.Contains(query, CrossTenantMarker) {
+		return nil
+	}
+	if insertStatement.MatchString(query) {
+		if strings.Contains(query, "tenant_id") {
+			return nil
+		}
+	} else if tenantPredicate.MatchString(query) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingTenantPredicate, table)
+}
+
+func init() {
+	sql.Register(TenantGuardDriverName, tenantGuardDriver{})
+}
+
+type tenantGuardDriver struct{}
+
+func (tenantGuardDriver) Open(name string) (driver.Conn, error) {
+	conn, err := pq.Driver{}.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	pqConn, ok := conn.(postgresConn)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("tenant guard: unexpected postgres connection type")
+	}
+	return guardedConn{pqConn}, nil
+}
+
+// postgresConn is what database/sql uses of a lib/pq connection.
+type postgresConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// guardedConn checks every statement before handing it to the connection, in
+// and outside transactions alike.
+type guardedConn struct {
+	postgresConn
+}
+
+func (c guardedConn) Prepare(query string) (driver.Stmt, error) {
+	if err := CheckTenantPredicate(query); err != nil {
+		return nil, err
+	}
+	return c.postgresConn.Prepare(query)
+}
+
+func (c guardedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := CheckTenantPredicate(query); err != nil {
+		return nil, err
+	}
+	return c.postgresConn.PrepareContext(ctx, query)
+}
+
+func (c guardedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := CheckTenantPredicate(query); err != nil {
+		return nil, err
+	}
+	return c.postgresConn.ExecContext(ctx, query, args)
+}
+
+func (c guardedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := CheckTenantPredicate(query); err != nil {
+		return nil, err
+	}
+	return c.postgresConn.QueryContext(ctx, query, args)
+}
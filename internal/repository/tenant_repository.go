@@ -2,41 +2,457 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stanstork/stratum-api/internal/models"
 )
 
 type TenantRepository interface {
 	CreateTenant(name string) (models.Tenant, error)
 	GetTenantByID(id string) (models.Tenant, error)
+	UpdateTenant(id string, update TenantUpdate) (models.Tenant, error)
+	ScheduleTenantDeletion(id string, purgeAfter time.Time) (models.Tenant, error)
+	RestoreTenant(id string) (models.Tenant, error)
+	PurgeTenant(id string) (bool, error)
+
+	// GetWorkerConfig returns the tenant's worker configuration; tenants without one
+	// get an empty configuration.
+	GetWorkerConfig(tenantID string) (models.TenantWorkerConfig, error)
+	SaveWorkerConfig(cfg models.TenantWorkerConfig) (models.TenantWorkerConfig, error)
+
+	// GetExecutionLimits returns the tenant's execution start limits; tenants
+	// without any are unlimited.
+	GetExecutionLimits(tenantID string) (models.TenantExecutionLimits, error)
+	SaveExecutionLimits(limits models.TenantExecutionLimits) (models.TenantExecutionLimits, error)
+	// ListExecutionStarts returns when the tenant's executions since since were
+	// created, oldest first. Runs awaiting approval and skipped runs never started
+	// and are left out.
+	ListExecutionStarts(tenantID string, since time.Time) ([]time.Time, error)
+
+	ListBlackoutWindows(tenantID string) ([]models.BlackoutWindow, error)
+	CreateBlackoutWindow(window models.BlackoutWindow) (models.BlackoutWindow, error)
+	// DeleteBlackoutWindow returns sql.ErrNoRows when the tenant has no such window.
+	DeleteBlackoutWindow(tenantID, windowID string) error
+
+	// ListTenantUsage returns the usage since monthStart of every tenant that is
+	// not scheduled for deletion.
+	ListTenantUsage(monthStart time.Time) ([]models.TenantUsage, error)
+}
+
+// TenantUpdate carries the tenant settings to change; nil fields are left untouched.
+type TenantUpdate struct {
+	RequireVerifiedEmail *bool
+	Timezone             *string
+	Locale               *string
+	// DataRegion sets the storage region; an empty string resets it to the default.
+	DataRegion *string
+	// BlockCrossEnvironmentJobs toggles the cross-environment job policy.
+	BlockCrossEnvironmentJobs *bool
+	// RequireArtifactScan toggles mandatory scanning of execution artifacts.
+	RequireArtifactScan *bool
+	// RequireRunApproval toggles the two-person approval of runs.
+	RequireRunApproval *bool
+	// ValidationFreshness sets how definitions with source schema drift are treated.
+	ValidationFreshness *models.ValidationFreshness
 }
 
 type tenantRepository struct {
 	db *sql.DB
 }
 
+const tenantColumns = `id, name, require_verified_email, timezone, locale, data_region, block_cross_environment_jobs, require_artifact_scan, require_run_approval, validation_freshness, suspended_at, purge_after, created_at, updated_at`
+
 func NewTenantRepository(db *sql.DB) TenantRepository {
 	return &tenantRepository{db: db}
 }
 
+func scanTenant(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.Tenant, error) {
+	var tenant models.Tenant
+	err := scanner.Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.RequireVerifiedEmail,
+		&tenant.Timezone,
+		&tenant.Locale,
+		&tenant.DataRegion,
+		&tenant.BlockCrossEnvironmentJobs,
+		&tenant.RequireArtifactScan,
+		&tenant.RequireRunApproval,
+		&tenant.ValidationFreshness,
+		&tenant.SuspendedAt,
+		&tenant.PurgeAfter,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	return tenant, err
+}
+
 func (r *tenantRepository) CreateTenant(name string) (models.Tenant, error) {
-	const query = `
+	query := `
 		INSERT INTO tenant.tenants (name)
 		VALUES ($1)
-		RETURNING id, name, created_at, updated_at;
+		RETURNING ` + tenantColumns + `;
 	`
-	var tenant models.Tenant
-	err := r.db.QueryRow(query, name).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
-	return tenant, err
+	return scanTenant(r.db.QueryRow(query, name))
 }
 
 func (r *tenantRepository) GetTenantByID(id string) (models.Tenant, error) {
-	const query = `
-		SELECT id, name, created_at, updated_at
+	query := `
+		SELECT ` + tenantColumns + `
 		FROM tenant.tenants
 		WHERE id = $1;
 	`
-	var tenant models.Tenant
-	err := r.db.QueryRow(query, id).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
-	return tenant, err
+	return scanTenant(r.db.QueryRow(query, id))
+}
+
+func (r *tenantRepository) UpdateTenant(id string, update TenantUpdate) (models.Tenant, error) {
+	setClauses := make([]string, 0, 3)
+	args := make([]interface{}, 0, 4)
+	idx := 1
+
+	if update.RequireVerifiedEmail != nil {
+		setClauses = append(setClauses, fmt.Sprintf("require_verified_email = $%d", idx))
+		args = append(args, *update.RequireVerifiedEmail)
+		idx++
+	}
+	if update.Timezone != nil {
+		setClauses = append(setClauses, fmt.Sprintf("timezone = $%d", idx))
+		args = append(args, *update.Timezone)
+		idx++
+	}
+	if update.Locale != nil {
+		setClauses = append(setClauses, fmt.Sprintf("locale = $%d", idx))
+		args = append(args, *update.Locale)
+		idx++
+	}
+
+	if update.DataRegion != nil {
+		setClauses = append(setClauses, fmt.Sprintf("data_region = $%d", idx))
+		args = append(args, nullIfEmpty(*update.DataRegion))
+		idx++
+	}
+	if update.BlockCrossEnvironmentJobs != nil {
+		setClauses = append(setClauses, fmt.Sprintf("block_cross_environment_jobs = $%d", idx))
+		args = append(args, *update.BlockCrossEnvironmentJobs)
+		idx++
+	}
+	if update.RequireArtifactScan != nil {
+		setClauses = append(setClauses, fmt.Sprintf("require_artifact_scan = $%d", idx))
+		args = append(args, *update.RequireArtifactScan)
+		idx++
+	}
+	if update.RequireRunApproval != nil {
+		setClauses = append(setClauses, fmt.Sprintf("require_run_approval = $%d", idx))
+		args = append(args, *update.RequireRunApproval)
+		idx++
+	}
+	if update.ValidationFreshness != nil {
+		setClauses = append(setClauses, fmt.Sprintf("validation_freshness = $%d", idx))
+		args = append(args, *update.ValidationFreshness)
+		idx++
+	}
+
+	if len(setClauses) == 0 {
+		return r.GetTenantByID(id)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE tenant.tenants
+		SET %s, updated_at = now()
+		WHERE id = $%d
+		RETURNING %s;
+	`, strings.Join(setClauses, ", "), idx, tenantColumns)
+	args = append(args, id)
+
+	return scanTenant(r.db.QueryRow(query, args...))
+}
+
+// ScheduleTenantDeletion suspends an active tenant and schedules its purge. Tenants
+// that are missing or already suspended are reported as sql.ErrNoRows.
+func (r *tenantRepository) ScheduleTenantDeletion(id string, purgeAfter time.Time) (models.Tenant, error) {
+	query := `
+		UPDATE tenant.tenants
+		SET suspended_at = now(), purge_after = $2, updated_at = now()
+		WHERE id = $1 AND suspended_at IS NULL
+		RETURNING ` + tenantColumns + `;
+	`
+	return scanTenant(r.db.QueryRow(query, id, purgeAfter))
+}
+
+// RestoreTenant lifts a pending deletion. Tenants that are not suspended or whose
+// grace period has ended are reported as sql.ErrNoRows.
+func (r *tenantRepository) RestoreTenant(id string) (models.Tenant, error) {
+	query := `
+		UPDATE tenant.tenants
+		SET suspended_at = NULL, purge_after = NULL, updated_at = now()
+		WHERE id = $1 AND suspended_at IS NOT NULL AND purge_after > now()
+		RETURNING ` + tenantColumns + `;
+	`
+	return scanTenant(r.db.QueryRow(query, id))
+}
+
+// PurgeTenant permanently deletes a suspended tenant whose grace period has ended,
+// cascading to all of its data. It reports whether the tenant was deleted.
+func (r *tenantRepository) PurgeTenant(id string) (bool, error) {
+	const query = `
+		DELETE FROM tenant.tenants
+		WHERE id = $1 AND suspended_at IS NOT NULL AND purge_after <= now()`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+const workerConfigColumns = `tenant_id, cpu_limit, memory_limit, engine_image, allowed_engine_images, max_execution_seconds, updated_by, updated_at`
+
+func scanWorkerConfig(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.TenantWorkerConfig, error) {
+	var (
+		cfg       models.TenantWorkerConfig
+		images    pq.StringArray
+		updatedAt time.Time
+	)
+	if err := scanner.Scan(
+		&cfg.TenantID,
+		&cfg.CPULimit,
+		&cfg.MemoryLimit,
+		&cfg.EngineImage,
+		&images,
+		&cfg.MaxExecutionSeconds,
+		&cfg.UpdatedBy,
+		&updatedAt,
+	); err != nil {
+		return models.TenantWorkerConfig{}, err
+	}
+	cfg.AllowedEngineImages = []string(images)
+	cfg.UpdatedAt = &updatedAt
+	return cfg, nil
+}
+
+func (r *tenantRepository) GetWorkerConfig(tenantID string) (models.TenantWorkerConfig, error) {
+	query := `
+		SELECT ` + workerConfigColumns + `
+		FROM tenant.tenant_worker_configs
+		WHERE tenant_id = $1;
+	`
+	cfg, err := scanWorkerConfig(r.db.QueryRow(query, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TenantWorkerConfig{TenantID: tenantID, AllowedEngineImages: []string{}}, nil
+	}
+	return cfg, err
+}
+
+func (r *tenantRepository) SaveWorkerConfig(cfg models.TenantWorkerConfig) (models.TenantWorkerConfig, error) {
+	query := `
+		INSERT INTO tenant.tenant_worker_configs
+			(tenant_id, cpu_limit, memory_limit, engine_image, allowed_engine_images, max_execution_seconds, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET cpu_limit = EXCLUDED.cpu_limit,
+		    memory_limit = EXCLUDED.memory_limit,
+		    engine_image = EXCLUDED.engine_image,
+		    allowed_engine_images = EXCLUDED.allowed_engine_images,
+		    max_execution_seconds = EXCLUDED.max_execution_seconds,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+		RETURNING ` + workerConfigColumns + `;
+	`
+	return scanWorkerConfig(r.db.QueryRow(query,
+		cfg.TenantID,
+		cfg.CPULimit,
+		cfg.MemoryLimit,
+		cfg.EngineImage,
+		pq.Array(cfg.AllowedEngineImages),
+		cfg.MaxExecutionSeconds,
+		cfg.UpdatedBy,
+	))
+}
+
+const executionLimitsColumns = `tenant_id, plan, starts_per_hour, burst, updated_by, updated_at`
+
+func scanExecutionLimits(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.TenantExecutionLimits, error) {
+	var (
+		limits    models.TenantExecutionLimits
+		updatedAt time.Time
+	)
+	if err := scanner.Scan(
+		&limits.TenantID,
+		&limits.Plan,
+		&limits.StartsPerHour,
+		&limits.Burst,
+		&limits.UpdatedBy,
+		&updatedAt,
+	); err != nil {
+		return models.TenantExecutionLimits{}, err
+	}
+	limits.UpdatedAt = &updatedAt
+	return limits, nil
+}
+
+func (r *tenantRepository) GetExecutionLimits(tenantID string) (models.TenantExecutionLimits, error) {
+	query := `
+		SELECT ` + executionLimitsColumns + `
+		FROM tenant.tenant_execution_limits
+		WHERE tenant_id = $1;
+	`
+	limits, err := scanExecutionLimits(r.db.QueryRow(query, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TenantExecutionLimits{TenantID: tenantID}, nil
+	}
+	return limits, err
+}
+
+func (r *tenantRepository) SaveExecutionLimits(limits models.TenantExecutionLimits) (models.TenantExecutionLimits, error) {
+	query := `
+		INSERT INTO tenant.tenant_execution_limits (tenant_id, plan, starts_per_hour, burst, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET plan = EXCLUDED.plan,
+		    starts_per_hour = EXCLUDED.starts_per_hour,
+		    burst = EXCLUDED.burst,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+		RETURNING ` + executionLimitsColumns + `;
+	`
+	return scanExecutionLimits(r.db.QueryRow(query,
+		limits.TenantID,
+		limits.Plan,
+		limits.StartsPerHour,
+		limits.Burst,
+		limits.UpdatedBy,
+	))
+}
+
+func (r *tenantRepository) ListExecutionStarts(tenantID string, since time.Time) ([]time.Time, error) {
+	rows, err := r.db.Query(`
+		SELECT created_at
+		FROM tenant.job_executions
+		WHERE tenant_id = $1 AND created_at > $2 AND status NOT IN ('awaiting_approval', 'skipped')
+		ORDER BY created_at`, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var starts []time.Time
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return nil, err
+		}
+		starts = append(starts, at)
+	}
+	return starts, rows.Err()
+}
+
+const blackoutWindowColumns = `id, tenant_id, name, days, start_time, end_time, timezone, created_by, created_at`
+
+func scanBlackoutWindow(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.BlackoutWindow, error) {
+	var window models.BlackoutWindow
+	err := scanner.Scan(
+		&window.ID,
+		&window.TenantID,
+		&window.Name,
+		pq.Array(&window.Days),
+		&window.StartTime,
+		&window.EndTime,
+		&window.Timezone,
+		&window.CreatedBy,
+		&window.CreatedAt,
+	)
+	return window, err
+}
+
+func (r *tenantRepository) ListBlackoutWindows(tenantID string) ([]models.BlackoutWindow, error) {
+	rows, err := r.db.Query(`
+		SELECT `+blackoutWindowColumns+`
+		FROM tenant.blackout_windows
+		WHERE tenant_id = $1
+		ORDER BY created_at`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	windows := []models.BlackoutWindow{}
+	for rows.Next() {
+		window, err := scanBlackoutWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, rows.Err()
+}
+
+func (r *tenantRepository) CreateBlackoutWindow(window models.BlackoutWindow) (models.BlackoutWindow, error) {
+	return scanBlackoutWindow(r.db.QueryRow(`
+		INSERT INTO tenant.blackout_windows (tenant_id, name, days, start_time, end_time, timezone, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+blackoutWindowColumns,
+		window.TenantID, window.Name, pq.Array(window.Days), window.StartTime, window.EndTime, window.Timezone, window.CreatedBy))
+}
+
+func (r *tenantRepository) DeleteBlackoutWindow(tenantID, windowID string) error {
+	res, err := r.db.Exec(`DELETE FROM tenant.blackout_windows WHERE tenant_id = $1 AND id = $2`, tenantID, windowID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *tenantRepository) ListTenantUsage(monthStart time.Time) ([]models.TenantUsage, error) {
+	rows, err := r.db.Query(`
+		SELECT t.id, t.name,
+		       COALESCE(e.runs, 0), COALESCE(e.bytes, 0), COALESCE(u.active, 0)
+		FROM tenant.tenants t
+		LEFT JOIN (
+			SELECT tenant_id, COUNT(*) AS runs, SUM(bytes_transferred) AS bytes
+			FROM tenant.job_executions
+			WHERE created_at >= $1
+			GROUP BY tenant_id
+		) e ON e.tenant_id = t.id
+		LEFT JOIN (
+			SELECT tenant_id, COUNT(*) AS active
+			FROM tenant.users
+			WHERE is_active AND deleted_at IS NULL
+			GROUP BY tenant_id
+		) u ON u.tenant_id = t.id
+		WHERE t.suspended_at IS NULL
+		ORDER BY t.id`, monthStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []models.TenantUsage
+	for rows.Next() {
+		var u models.TenantUsage
+		if err := rows.Scan(&u.TenantID, &u.TenantName, &u.ExecutionsThisMonth, &u.BytesThisMonth, &u.ActiveUsers); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
 }
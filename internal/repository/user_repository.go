@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/stanstork/stratum-api/internal/models"
@@ -12,12 +13,24 @@ import (
 
 type UserRepository interface {
 	CreateUser(tenantID, email, password, firstName, lastName string, roles []models.UserRole) (models.User, error)
+	CreateInactiveUser(tenantID, email, password, firstName, lastName string, roles []models.UserRole) (models.User, error)
 	AuthenticateUser(email, password string) (models.User, error)
 	ListUsersByTenant(tenantID string) ([]models.User, error)
 	GetUserByEmail(email string) (models.User, error)
 	GetUserByID(userID string) (models.User, error)
+	GetUserIncludingDeleted(userID string) (models.User, error)
 	UpdateUserRoles(userID string, roles []models.UserRole) (models.User, error)
+	// UpdateUserProfile sets a user's name and whether it may sign in.
+	UpdateUserProfile(tenantID, userID, firstName, lastName string, active bool) (models.User, error)
 	DeleteUser(userID string) error
+	UpdateUserEmail(userID, email string) (models.User, error)
+	UpdatePassword(userID, password string) error
+	MarkEmailVerified(userID string) error
+	CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error
+	ConsumeEmailVerification(tokenHash string) (models.User, error)
+	PruneEmailVerifications(before time.Time) (int64, error)
+	EraseUser(tenantID, userID string) error
+	ListErasableUsers(deletedBefore time.Time, limit int) ([]string, error)
 }
 
 type userRepository struct {
@@ -29,6 +42,16 @@ func NewUserRepository(db *sql.DB) UserRepository {
 }
 
 func (u *userRepository) CreateUser(tenantID string, email string, password string, firstName string, lastName string, roles []models.UserRole) (models.User, error) {
+	return u.createUser(tenantID, email, password, firstName, lastName, roles, true)
+}
+
+// CreateInactiveUser creates a user who cannot log in until activated, e.g. one
+// joining through an email domain that still needs verification or approval.
+func (u *userRepository) CreateInactiveUser(tenantID string, email string, password string, firstName string, lastName string, roles []models.UserRole) (models.User, error) {
+	return u.createUser(tenantID, email, password, firstName, lastName, roles, false)
+}
+
+func (u *userRepository) createUser(tenantID string, email string, password string, firstName string, lastName string, roles []models.UserRole, active bool) (models.User, error) {
 	if len(roles) == 0 {
 		roles = []models.UserRole{models.RoleViewer}
 	}
@@ -51,7 +74,7 @@ func (u *userRepository) CreateUser(tenantID string, email string, password stri
 		FirstName:    firstName,
 		LastName:     lastName,
 		PasswordHash: string(hash),
-		IsActive:     true,
+		IsActive:     active,
 		Roles:        normalized,
 	}
 
@@ -72,7 +95,8 @@ func (u *userRepository) AuthenticateUser(email string, password string) (models
 	var roles pq.StringArray
 
 	query := `
-		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles
+		/* cross-tenant: sign-in resolves the user's tenant */
+		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at
 		FROM tenant.users
 		WHERE email = $1 AND deleted_at IS NULL`
 	err := u.db.QueryRow(query, email).Scan(
@@ -84,6 +108,7 @@ func (u *userRepository) AuthenticateUser(email string, password string) (models
 		&user.PasswordHash,
 		&user.IsActive,
 		&roles,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -114,7 +139,8 @@ func (u *userRepository) GetUserByEmail(email string) (models.User, error) {
 	var roles pq.StringArray
 
 	const query = `
-		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles
+		/* cross-tenant: email addresses are unique across tenants */
+		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at
 		FROM tenant.users
 		WHERE email = $1 AND deleted_at IS NULL`
 
@@ -127,6 +153,7 @@ func (u *userRepository) GetUserByEmail(email string) (models.User, error) {
 		&user.PasswordHash,
 		&user.IsActive,
 		&roles,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		return models.User{}, err
@@ -141,15 +168,25 @@ func (u *userRepository) GetUserByEmail(email string) (models.User, error) {
 }
 
 func (u *userRepository) GetUserByID(userID string) (models.User, error) {
+	return u.getUserByID(userID, false)
+}
+
+// GetUserIncludingDeleted also returns soft-deleted users that have not been erased.
+func (u *userRepository) GetUserIncludingDeleted(userID string) (models.User, error) {
+	return u.getUserByID(userID, true)
+}
+
+func (u *userRepository) getUserByID(userID string, includeDeleted bool) (models.User, error) {
 	var user models.User
 	var roles pq.StringArray
 
 	const query = `
-		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles
+		/* cross-tenant: user IDs are unique across tenants */
+		SELECT id, tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at
 		FROM tenant.users
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = $1 AND erased_at IS NULL AND ($2 OR deleted_at IS NULL)`
 
-	err := u.db.QueryRow(query, userID).Scan(
+	err := u.db.QueryRow(query, userID, includeDeleted).Scan(
 		&user.ID,
 		&user.TenantID,
 		&user.Email,
@@ -158,6 +195,7 @@ func (u *userRepository) GetUserByID(userID string) (models.User, error) {
 		&user.PasswordHash,
 		&user.IsActive,
 		&roles,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		return models.User{}, err
@@ -182,10 +220,11 @@ func (u *userRepository) UpdateUserRoles(userID string, roles []models.UserRole)
 	}
 
 	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
 		UPDATE tenant.users
 		SET roles = $2, updated_at = now()
 		WHERE id = $1 AND deleted_at IS NULL
-		RETURNING id, tenant_id, email, first_name, last_name, password_hash, is_active, roles
+		RETURNING id, tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at
 	`
 
 	var user models.User
@@ -199,6 +238,7 @@ func (u *userRepository) UpdateUserRoles(userID string, roles []models.UserRole)
 		&user.PasswordHash,
 		&user.IsActive,
 		&updatedRoles,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		return models.User{}, err
@@ -212,8 +252,37 @@ func (u *userRepository) UpdateUserRoles(userID string, roles []models.UserRole)
 	return user, nil
 }
 
+func (u *userRepository) UpdateUserProfile(tenantID, userID, firstName, lastName string, active bool) (models.User, error) {
+	const query = `
+		UPDATE tenant.users
+		SET first_name = $3, last_name = $4, is_active = $5, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		RETURNING id, tenant_id, email, first_name, last_name, password_hash, is_active, roles, email_verified_at
+	`
+
+	var user models.User
+	var roles pq.StringArray
+	err := u.db.QueryRow(query, userID, tenantID, strings.TrimSpace(firstName), strings.TrimSpace(lastName), active).Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.FirstName,
+		&user.LastName,
+		&user.PasswordHash,
+		&user.IsActive,
+		&roles,
+		&user.EmailVerifiedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Roles = models.EnsureDefaultRole(toUserRoleSlice(roles))
+	return user, nil
+}
+
 func (u *userRepository) DeleteUser(userID string) error {
 	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
 		UPDATE tenant.users
 		SET is_active = FALSE, deleted_at = now(), updated_at = now()
 		WHERE id = $1 AND deleted_at IS NULL`
@@ -236,7 +305,7 @@ func (u *userRepository) DeleteUser(userID string) error {
 
 func (u *userRepository) ListUsersByTenant(tenantID string) ([]models.User, error) {
 	const query = `
-		SELECT id, tenant_id, email, first_name, last_name, is_active, roles
+		SELECT id, tenant_id, email, first_name, last_name, is_active, roles, email_verified_at
 		FROM tenant.users
 		WHERE tenant_id = $1 AND deleted_at IS NULL
 		ORDER BY email`
@@ -252,7 +321,7 @@ func (u *userRepository) ListUsersByTenant(tenantID string) ([]models.User, erro
 		var user models.User
 		var roles pq.StringArray
 
-		if err := rows.Scan(&user.ID, &user.TenantID, &user.Email, &user.FirstName, &user.LastName, &user.IsActive, &roles); err != nil {
+		if err := rows.Scan(&user.ID, &user.TenantID, &user.Email, &user.FirstName, &user.LastName, &user.IsActive, &roles, &user.EmailVerifiedAt); err != nil {
 			return nil, err
 		}
 
@@ -272,6 +341,231 @@ func (u *userRepository) ListUsersByTenant(tenantID string) ([]models.User, erro
 	return users, nil
 }
 
+// UpdateUserEmail changes a user's email address and clears its verification state.
+func (u *userRepository) UpdateUserEmail(userID, email string) (models.User, error) {
+	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
+		UPDATE tenant.users
+		SET email = $2, email_verified_at = NULL, updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := u.db.Exec(query, userID, email)
+	if err != nil {
+		return models.User{}, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return models.User{}, err
+	}
+	if rows == 0 {
+		return models.User{}, sql.ErrNoRows
+	}
+	return u.GetUserByID(userID)
+}
+
+func (u *userRepository) UpdatePassword(userID, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
+		UPDATE tenant.users
+		SET password_hash = $2, updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := u.db.Exec(query, userID, string(hash))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (u *userRepository) MarkEmailVerified(userID string) error {
+	const query = `
+		/* cross-tenant: user IDs are unique across tenants */
+		UPDATE tenant.users
+		SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := u.db.Exec(query, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (u *userRepository) CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error {
+	const query = `
+		INSERT INTO tenant.email_verifications (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := u.db.Exec(query, userID, email, tokenHash, expiresAt)
+	return err
+}
+
+// ConsumeEmailVerification redeems an unexpired token and marks the user's email as
+// verified, provided the address has not changed since the token was issued.
+func (u *userRepository) ConsumeEmailVerification(tokenHash string) (models.User, error) {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	const consume = `
+		UPDATE tenant.email_verifications
+		SET consumed_at = now()
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > now()
+		RETURNING user_id, email`
+
+	var userID, email string
+	if err := tx.QueryRow(consume, tokenHash).Scan(&userID, &email); err != nil {
+		return models.User{}, err
+	}
+
+	const verify = `
+		/* cross-tenant: user IDs are unique across tenants */
+		UPDATE tenant.users
+		SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL`
+
+	result, err := tx.Exec(verify, userID, email)
+	if err != nil {
+		return models.User{}, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return models.User{}, err
+	}
+	if rows == 0 {
+		return models.User{}, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+	return u.GetUserByID(userID)
+}
+
+// PruneEmailVerifications deletes verification tokens that expired or were consumed
+// before the given time.
+func (u *userRepository) PruneEmailVerifications(before time.Time) (int64, error) {
+	const query = `
+		DELETE FROM tenant.email_verifications
+		WHERE expires_at < $1 OR consumed_at < $1`
+
+	result, err := u.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// EraseUser anonymises a user for GDPR erasure. The row stays as a tombstone so the
+// IDs referencing it remain valid, but name, email and password are overwritten, and
+// the email is scrubbed from the tenant's invites, notifications, execution notes,
+// audit event details and webhook delivery payloads.
+// An empty tenantID erases the user in any tenant. Unknown or already erased users
+// are reported as sql.ErrNoRows.
+func (u *userRepository) EraseUser(tenantID, userID string) error {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userTenantID, email string
+	err = tx.QueryRow(`
+		SELECT tenant_id, email
+		FROM tenant.users
+		WHERE id = $1 AND erased_at IS NULL AND ($2 = '' OR tenant_id::text = $2)
+		FOR UPDATE`, userID, tenantID).Scan(&userTenantID, &email)
+	if err != nil {
+		return err
+	}
+	tombstone := models.ErasedEmail(userID)
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE tenant.users
+		  SET email = $2, first_name = '', last_name = '', password_hash = '',
+		      is_active = FALSE, email_verified_at = NULL,
+		      deleted_at = COALESCE(deleted_at, now()), erased_at = now(), updated_at = now()
+		  WHERE id = $1 AND tenant_id = $3`, []interface{}{userID, tombstone, userTenantID}},
+		{`DELETE FROM tenant.email_verifications WHERE user_id = $1`, []interface{}{userID}},
+		{`UPDATE tenant.invites
+		  SET email = $3, updated_at = now()
+		  WHERE tenant_id = $1 AND lower(email) = lower($2)`, []interface{}{userTenantID, email, tombstone}},
+		{`UPDATE tenant.notifications
+		  SET title = replace(title, $2, $3),
+		      message = replace(message, $2, $3),
+		      metadata = replace(metadata::text, $2, $3)::jsonb
+		  WHERE tenant_id = $1
+		    AND (strpos(title, $2) > 0 OR strpos(message, $2) > 0 OR strpos(COALESCE(metadata::text, ''), $2) > 0)`,
+			[]interface{}{userTenantID, email, tombstone}},
+		{`UPDATE tenant.execution_notes
+		  SET body = replace(body, $2, $3), updated_at = now()
+		  WHERE tenant_id = $1 AND strpos(body, $2) > 0`, []interface{}{userTenantID, email, tombstone}},
+		{`UPDATE tenant.audit_events
+		  SET details = replace(details::text, $2, $3)::jsonb
+		  WHERE (tenant_id = $1 OR tenant_id IS NULL) AND strpos(COALESCE(details::text, ''), $2) > 0`,
+			[]interface{}{userTenantID, email, tombstone}},
+		{`UPDATE tenant.webhook_deliveries
+		  SET payload = replace(payload::text, $2, $3)::jsonb
+		  WHERE tenant_id = $1 AND strpos(payload::text, $2) > 0`, []interface{}{userTenantID, email, tombstone}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListErasableUsers returns the IDs of users soft-deleted before deletedBefore that
+// have not been erased yet, oldest first.
+func (u *userRepository) ListErasableUsers(deletedBefore time.Time, limit int) ([]string, error) {
+	const query = `
+		/* cross-tenant: erasure sweeps every tenant */
+		SELECT id
+		FROM tenant.users
+		WHERE deleted_at < $1 AND erased_at IS NULL
+		ORDER BY deleted_at
+		LIMIT $2`
+	rows, err := u.db.Query(query, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func toStringSlice(roles []models.UserRole) []string {
 	result := make([]string, 0, len(roles))
 	for _, role := range roles {
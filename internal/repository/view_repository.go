@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type ViewRepository interface {
+	// ListViews returns the user's views of list followed by the tenant's shared
+	// ones, each ordered by name. An empty list returns the views of every list.
+	ListViews(tenantID, userID, list string) ([]models.SavedView, error)
+	// GetView returns one of the user's views or a view shared in the tenant.
+	GetView(tenantID, userID, viewID string) (models.SavedView, error)
+	CreateView(view models.SavedView) (models.SavedView, error)
+	// UpdateView replaces the view's name, filters and sort on behalf of userID. A
+	// view keeps its list and owner.
+	UpdateView(userID string, view models.SavedView) (models.SavedView, error)
+	DeleteView(view models.SavedView) error
+	// SetDefaultView makes viewID the view the user opens list with; a nil viewID
+	// clears the preference. It returns sql.ErrNoRows when the view is not visible
+	// to the user or belongs to another list.
+	SetDefaultView(tenantID, userID, list string, viewID *string) error
+}
+
+type viewRepository struct {
+	db *sql.DB
+}
+
+func NewViewRepository(db *sql.DB) ViewRepository {
+	return &viewRepository{db: db}
+}
+
+const viewColumns = `v.id, v.tenant_id, v.owner_id, v.list, v.name, v.filters, v.sort, v.created_by, v.created_at, v.updated_at,
+	EXISTS (SELECT 1 FROM tenant.default_views d WHERE d.view_id = v.id AND d.user_id = $2)`
+
+func scanView(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.SavedView, error) {
+	var (
+		v         models.SavedView
+		ownerID   sql.NullString
+		filters   []byte
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&v.ID,
+		&v.TenantID,
+		&ownerID,
+		&v.List,
+		&v.Name,
+		&filters,
+		&v.Sort,
+		&createdBy,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+		&v.IsDefault,
+	); err != nil {
+		return v, err
+	}
+	if ownerID.Valid {
+		v.OwnerID = &ownerID.String
+	}
+	v.Shared = v.OwnerID == nil
+	if createdBy.Valid {
+		v.CreatedBy = &createdBy.String
+	}
+	v.Filters = json.RawMessage(filters)
+	return v, nil
+}
+
+// viewOwner is the owner_id value of a view: NULL for shared views.
+func viewOwner(v models.SavedView) interface{} {
+	if v.OwnerID == nil {
+		return nil
+	}
+	return *v.OwnerID
+}
+
+func (r *viewRepository) ListViews(tenantID, userID, list string) ([]models.SavedView, error) {
+	query := `
+		SELECT ` + viewColumns + `
+		FROM tenant.saved_views v
+		WHERE v.tenant_id = $1 AND (v.owner_id = $2 OR v.owner_id IS NULL) AND ($3 = '' OR v.list = $3)
+		ORDER BY v.owner_id NULLS LAST, v.list, v.name`
+	rows, err := r.db.Query(query, tenantID, userID, list)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []models.SavedView{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+func (r *viewRepository) GetView(tenantID, userID, viewID string) (models.SavedView, error) {
+	query := `
+		SELECT ` + viewColumns + `
+		FROM tenant.saved_views v
+		WHERE v.tenant_id = $1 AND (v.owner_id = $2 OR v.owner_id IS NULL) AND v.id = $3`
+	return scanView(r.db.QueryRow(query, tenantID, userID, viewID))
+}
+
+func (r *viewRepository) CreateView(view models.SavedView) (models.SavedView, error) {
+	var createdBy interface{}
+	if view.CreatedBy != nil {
+		createdBy = *view.CreatedBy
+	}
+	query := `
+		WITH v AS (
+			INSERT INTO tenant.saved_views (tenant_id, owner_id, list, name, filters, sort, created_by)
+			VALUES ($1, $3, $4, $5, $6, $7, $8)
+			RETURNING *
+		)
+		SELECT ` + viewColumns + ` FROM v`
+	return scanView(r.db.QueryRow(query, view.TenantID, createdBy, viewOwner(view), view.List, view.Name,
+		[]byte(view.Filters), view.Sort, createdBy))
+}
+
+func (r *viewRepository) UpdateView(userID string, view models.SavedView) (models.SavedView, error) {
+	query := `
+		WITH v AS (
+			UPDATE tenant.saved_views
+			SET name = $5, filters = $6, sort = $7, updated_at = now()
+			WHERE tenant_id = $1 AND id = $3 AND owner_id IS NOT DISTINCT FROM $4
+			RETURNING *
+		)
+		SELECT ` + viewColumns + ` FROM v`
+	return scanView(r.db.QueryRow(query, view.TenantID, userID, view.ID, viewOwner(view), view.Name,
+		[]byte(view.Filters), view.Sort))
+}
+
+func (r *viewRepository) DeleteView(view models.SavedView) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.saved_views WHERE tenant_id = $1 AND id = $2 AND owner_id IS NOT DISTINCT FROM $3`,
+		view.TenantID, view.ID, viewOwner(view))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *viewRepository) SetDefaultView(tenantID, userID, list string, viewID *string) error {
+	if viewID == nil {
+		_, err := r.db.Exec(`DELETE FROM tenant.default_views WHERE user_id = $1 AND list = $2`, userID, list)
+		return err
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO tenant.default_views (user_id, list, view_id)
+		SELECT $2, v.list, v.id
+		FROM tenant.saved_views v
+		WHERE v.tenant_id = $1 AND (v.owner_id = $2 OR v.owner_id IS NULL) AND v.list = $3 AND v.id = $4
+		ON CONFLICT (user_id, list) DO UPDATE SET view_id = EXCLUDED.view_id`,
+		tenantID, userID, list, *viewID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
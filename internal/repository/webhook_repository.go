@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type WebhookRepository interface {
+	ListSubscriptions(tenantID, category string) ([]models.WebhookSubscription, error)
+	GetSubscription(tenantID, subscriptionID string) (models.WebhookSubscription, error)
+	// CreateSubscription stores a subscription with its encrypted signing secret.
+	CreateSubscription(sub models.WebhookSubscription, secret []byte) (models.WebhookSubscription, error)
+	UpdateSubscription(sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	DeleteSubscription(tenantID, subscriptionID string) error
+
+	// EnqueueEvent queues evt for every active subscription of the tenant that
+	// covers its category and type, returning the number of deliveries queued. Each
+	// delivery carries the payload the subscription's template renders; one whose
+	// template fails to render is recorded as failed.
+	EnqueueEvent(evt models.WebhookEvent) (int64, error)
+	// EnqueueDelivery queues payload for one subscription regardless of the events
+	// it covers.
+	EnqueueDelivery(sub models.WebhookSubscription, eventType string, payload json.RawMessage) (models.WebhookDelivery, error)
+	// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt is
+	// due, counting the attempt and hiding them from other claims for lease.
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]PendingWebhookDelivery, error)
+	MarkDelivered(deliveryID string, responseStatus int) error
+	// MarkAttemptFailed records a failed attempt. A nil retryAt fails the delivery
+	// for good.
+	MarkAttemptFailed(deliveryID string, responseStatus *int, errMsg string, retryAt *time.Time) error
+	ListDeliveries(tenantID, subscriptionID string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// PendingWebhookDelivery is a claimed delivery with the endpoint it goes to.
+type PendingWebhookDelivery struct {
+	models.WebhookDelivery
+	URL string
+	// Secret is the subscription's encrypted signing secret.
+	Secret []byte
+	// Active is false when the subscription was disabled after the event was queued.
+	Active bool
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookSubscriptionColumns = `id, tenant_id, category, url, events, active, payload_template, created_by,
+	created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, tenant_id, event_type, payload, status, attempts, next_attempt_at,
+	last_error, response_status, created_at, delivered_at`
+
+func scanWebhookSubscription(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.WebhookSubscription, error) {
+	var (
+		s         models.WebhookSubscription
+		tmpl      []byte
+		createdBy sql.NullString
+	)
+	if err := scanner.Scan(
+		&s.ID,
+		&s.TenantID,
+		&s.Category,
+		&s.URL,
+		pq.Array(&s.Events),
+		&s.Active,
+		&tmpl,
+		&createdBy,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	); err != nil {
+		return s, err
+	}
+	if len(tmpl) > 0 {
+		var t models.WebhookPayloadTemplate
+		if err := json.Unmarshal(tmpl, &t); err != nil {
+			return s, err
+		}
+		s.PayloadTemplate = &t
+	}
+	if createdBy.Valid {
+		s.CreatedBy = &createdBy.String
+	}
+	if s.Events == nil {
+		s.Events = []string{}
+	}
+	return s, nil
+}
+
+func scanWebhookDelivery(scanner interface {
+	Scan(dest ...interface{}) error
+}, extra ...interface{}) (models.WebhookDelivery, error) {
+	var (
+		d              models.WebhookDelivery
+		payload        []byte
+		lastError      sql.NullString
+		responseStatus sql.NullInt64
+		deliveredAt    sql.NullTime
+	)
+	dest := []interface{}{
+		&d.ID,
+		&d.SubscriptionID,
+		&d.TenantID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&lastError,
+		&responseStatus,
+		&d.CreatedAt,
+		&deliveredAt,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return d, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if lastError.Valid {
+		d.LastError = &lastError.String
+	}
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		d.ResponseStatus = &status
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(tenantID, category string) ([]models.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookSubscriptionColumns + `
+		FROM tenant.webhook_subscriptions
+		WHERE tenant_id = $1 AND category = $2
+		ORDER BY created_at`
+	rows, err := r.db.Query(query, tenantID, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		s, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *webhookRepository) GetSubscription(tenantID, subscriptionID string) (models.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookSubscriptionColumns + `
+		FROM tenant.webhook_subscriptions
+		WHERE tenant_id = $1 AND id = $2`
+	return scanWebhookSubscription(r.db.QueryRow(query, tenantID, subscriptionID))
+}
+
+func (r *webhookRepository) CreateSubscription(sub models.WebhookSubscription, secret []byte) (models.WebhookSubscription, error) {
+	tmpl, err := payloadTemplateValue(sub.PayloadTemplate)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	query := `
+		INSERT INTO tenant.webhook_subscriptions (tenant_id, category, url, secret, events, active, payload_template, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + webhookSubscriptionColumns
+	return scanWebhookSubscription(r.db.QueryRow(query, sub.TenantID, sub.Category, sub.URL, secret,
+		pq.Array(sub.Events), sub.Active, tmpl, sub.CreatedBy))
+}
+
+// UpdateSubscription replaces the subscription's URL, events, active flag and
+// payload template. The signing secret never changes.
+func (r *webhookRepository) UpdateSubscription(sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	tmpl, err := payloadTemplateValue(sub.PayloadTemplate)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	query := `
+		UPDATE tenant.webhook_subscriptions
+		SET url = $3, events = $4, active = $5, payload_template = $6, updated_at = now()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING ` + webhookSubscriptionColumns
+	return scanWebhookSubscription(r.db.QueryRow(query, sub.TenantID, sub.ID, sub.URL, pq.Array(sub.Events), sub.Active, tmpl))
+}
+
+// payloadTemplateValue encodes a payload template for its JSONB column.
+func payloadTemplateValue(t *models.WebhookPayloadTemplate) (interface{}, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+func (r *webhookRepository) DeleteSubscription(tenantID, subscriptionID string) error {
+	result, err := r.db.Exec(`DELETE FROM tenant.webhook_subscriptions WHERE tenant_id = $1 AND id = $2`,
+		tenantID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *webhookRepository) EnqueueEvent(evt models.WebhookEvent) (int64, error) {
+	query := `
+		SELECT ` + webhookSubscriptionColumns + `
+		FROM tenant.webhook_subscriptions
+		WHERE tenant_id = $1 AND category = $2 AND active
+		  AND (cardinality(events) = 0 OR $3 = ANY(events))`
+	rows, err := r.db.Query(query, evt.TenantID, evt.Category, evt.Type)
+	if err != nil {
+		return 0, err
+	}
+	var subs []models.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		subs = append(subs, sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(subs) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, sub := range subs {
+		status, lastError := models.WebhookDeliveryPending, sql.NullString{}
+		payload, err := models.RenderWebhookPayload(sub, evt)
+		if err != nil {
+			// The event is kept so the failure can be inspected and the template fixed.
+			status, lastError = models.WebhookDeliveryFailed, sql.NullString{String: "render payload: " + err.Error(), Valid: true}
+			if payload, err = json.Marshal(evt); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO tenant.webhook_deliveries (subscription_id, tenant_id, event_type, payload, status, last_error)
+			VALUES ($1, $2, $3, $4, $5, $6)`, sub.ID, sub.TenantID, evt.Type, []byte(payload), status, lastError); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(subs)), nil
+}
+
+func (r *webhookRepository) EnqueueDelivery(sub models.WebhookSubscription, eventType string, payload json.RawMessage) (models.WebhookDelivery, error) {
+	query := `
+		INSERT INTO tenant.webhook_deliveries (subscription_id, tenant_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + webhookDeliveryColumns
+	return scanWebhookDelivery(r.db.QueryRow(query, sub.ID, sub.TenantID, eventType, []byte(payload)))
+}
+
+func (r *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]PendingWebhookDelivery, error) {
+	query := `
+		/* cross-tenant: webhook delivery serves every tenant */
+		WITH due AS (
+			SELECT id
+			FROM tenant.webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE tenant.webhook_deliveries d
+		SET attempts = d.attempts + 1,
+		    next_attempt_at = now() + make_interval(secs => $2)
+		FROM due, tenant.webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.tenant_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.last_error, d.response_status, d.created_at, d.delivered_at,
+			s.url, s.secret, s.active`
+	rows, err := r.db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []PendingWebhookDelivery
+	for rows.Next() {
+		var p PendingWebhookDelivery
+		d, err := scanWebhookDelivery(rows, &p.URL, &p.Secret, &p.Active)
+		if err != nil {
+			return nil, err
+		}
+		p.WebhookDelivery = d
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+func (r *webhookRepository) MarkDelivered(deliveryID string, responseStatus int) error {
+	_, err := r.db.Exec(`
+		/* cross-tenant: webhook delivery serves every tenant */
+		UPDATE tenant.webhook_deliveries
+		SET status = 'delivered', response_status = $2, last_error = NULL, delivered_at = now()
+		WHERE id = $1`, deliveryID, responseStatus)
+	return err
+}
+
+func (r *webhookRepository) MarkAttemptFailed(deliveryID string, responseStatus *int, errMsg string, retryAt *time.Time) error {
+	status := models.WebhookDeliveryPending
+	var next interface{}
+	if retryAt != nil {
+		next = *retryAt
+	} else {
+		status = models.WebhookDeliveryFailed
+	}
+	_, err := r.db.Exec(`
+		/* cross-tenant: webhook delivery serves every tenant */
+		UPDATE tenant.webhook_deliveries
+		SET status = $2, response_status = $3, last_error = $4, next_attempt_at = COALESCE($5, next_attempt_at)
+		WHERE id = $1`, deliveryID, status, responseStatus, errMsg, next)
+	return err
+}
+
+// ListDeliveries returns the subscription's most recent deliveries, newest first.
+func (r *webhookRepository) ListDeliveries(tenantID, subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM tenant.webhook_deliveries
+		WHERE tenant_id = $1 AND subscription_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3`
+	rows, err := r.db.Query(query, tenantID, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,128 @@
+package revalidation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// FreshnessChecker compares the source schema of READY definitions with the one
+// they were validated against, for tenants that check validation freshness. The
+// first check after a definition becomes READY records the baseline; a later
+// mismatch flags the definition stale and notifies the tenant once.
+type FreshnessChecker struct {
+	jobs          repository.JobRepository
+	conns         repository.ConnectionRepository
+	tenants       repository.TenantRepository
+	hosts         *engine.HostPool
+	containerName string
+	notifier      notification.Service
+	interval      time.Duration
+	batchSize     int
+	timeout       time.Duration
+	logger        zerolog.Logger
+}
+
+// NewFreshnessChecker checks each definition at most once per interval.
+func NewFreshnessChecker(jobs repository.JobRepository, conns repository.ConnectionRepository, tenants repository.TenantRepository, hosts *engine.HostPool, containerName string, notifier notification.Service, interval time.Duration, batchSize int, logger zerolog.Logger) *FreshnessChecker {
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	return &FreshnessChecker{
+		jobs:          jobs,
+		conns:         conns,
+		tenants:       tenants,
+		hosts:         hosts,
+		containerName: containerName,
+		notifier:      notifier,
+		interval:      interval,
+		batchSize:     batchSize,
+		timeout:       2 * time.Minute,
+		logger:        logger.With().Str("component", "freshness_checker").Logger(),
+	}
+}
+
+// RunOnce checks one batch of definitions that are due. A definition whose schema
+// cannot be read is logged and tried again on a later pass.
+func (c *FreshnessChecker) RunOnce(ctx context.Context) error {
+	defs, err := c.jobs.ListSchemaCheckDefinitions(time.Now().Add(-c.interval), c.batchSize)
+	if err != nil {
+		return fmt.Errorf("list definitions to check: %w", err)
+	}
+	for _, def := range defs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := c.check(ctx, def); err != nil {
+			c.logger.Warn().Err(err).Str("job_definition_id", def.ID).Msg("schema freshness check failed")
+		}
+	}
+	return nil
+}
+
+func (c *FreshnessChecker) check(ctx context.Context, def models.JobDefinition) error {
+	src, err := c.conns.Get(def.TenantID, def.SourceConnectionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("load source connection: %w", err)
+	}
+	if src == nil {
+		// Revalidation reports a missing connection.
+		return nil
+	}
+	if src.PromptsForCredentials() {
+		// Without a stored password the schema cannot be read; record the check so
+		// the definition is not retried on every pass.
+		_, err := c.jobs.RecordDefinitionSchemaCheck(def.TenantID, def.ID, def.UpdatedAt, "")
+		return err
+	}
+
+	hash, err := c.schemaHash(ctx, def.TenantID, *src)
+	if err != nil {
+		return err
+	}
+	flagged, err := c.jobs.RecordDefinitionSchemaCheck(def.TenantID, def.ID, def.UpdatedAt, hash)
+	if err != nil {
+		return fmt.Errorf("record schema check: %w", err)
+	}
+	if !flagged {
+		return nil
+	}
+
+	tenant, err := c.tenants.GetTenantByID(def.TenantID)
+	if err != nil {
+		return fmt.Errorf("load tenant: %w", err)
+	}
+	blocked := tenant.ValidationFreshness == models.ValidationFreshnessBlock
+	c.logger.Warn().Str("job_definition_id", def.ID).Bool("blocked", blocked).Msg("source schema drifted since validation")
+	if c.notifier != nil {
+		if err := c.notifier.NotifyValidationStale(ctx, def.TenantID, def.ID, def.Name, blocked); err != nil {
+			c.logger.Warn().Err(err).Str("job_definition_id", def.ID).Msg("failed to publish stale validation notification")
+		}
+	}
+	return nil
+}
+
+// schemaHash reads the source schema through the engine and fingerprints it.
+func (c *FreshnessChecker) schemaHash(ctx context.Context, tenantID string, src models.Connection) (string, error) {
+	host, release, err := c.hosts.Acquire(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("engine host: %w", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	metadata, err := host.Engine(c.containerName).SaveSourceMetadata(ctx, src)
+	if err != nil {
+		return "", fmt.Errorf("read source schema: %w", err)
+	}
+	return models.SourceSchemaHash(metadata)
+}
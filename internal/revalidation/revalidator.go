@@ -0,0 +1,208 @@
+package revalidation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// maxAttempts bounds retries of revalidations that fail for infrastructure reasons
+// (database or engine unavailable) rather than because the definition is invalid.
+const maxAttempts = 5
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+var dataFormatMap = map[string]string{
+	"pg":         "Postgres",
+	"postgresql": "Postgres",
+	"postgres":   "Postgres",
+	"mysql":      "MySql",
+}
+
+// Revalidator drains the definition revalidation queue, re-running the engine's
+// validation against the current connections of each queued READY definition.
+type Revalidator struct {
+	jobs            repository.JobRepository
+	conns           repository.ConnectionRepository
+	hosts           *engine.HostPool
+	containerName   string
+	notifier        notification.Service
+	demoteOnFailure bool
+	batchSize       int
+	timeout         time.Duration
+	logger          zerolog.Logger
+}
+
+func New(jobs repository.JobRepository, conns repository.ConnectionRepository, hosts *engine.HostPool, containerName string, notifier notification.Service, demoteOnFailure bool, batchSize int, logger zerolog.Logger) *Revalidator {
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	return &Revalidator{
+		jobs:            jobs,
+		conns:           conns,
+		hosts:           hosts,
+		containerName:   containerName,
+		notifier:        notifier,
+		demoteOnFailure: demoteOnFailure,
+		batchSize:       batchSize,
+		timeout:         2 * time.Minute,
+		logger:          logger.With().Str("component", "revalidator").Logger(),
+	}
+}
+
+// RunOnce processes one batch of queued revalidations.
+func (v *Revalidator) RunOnce(ctx context.Context) error {
+	revs, err := v.jobs.ListDefinitionRevalidations(v.batchSize)
+	if err != nil {
+		return fmt.Errorf("list revalidations: %w", err)
+	}
+	for _, rev := range revs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := v.process(ctx, rev); err != nil {
+			v.logger.Warn().Err(err).Str("job_definition_id", rev.JobDefinitionID).Msg("revalidation attempt failed")
+			if rev.Attempts+1 >= maxAttempts {
+				v.logger.Error().Str("job_definition_id", rev.JobDefinitionID).Msg("giving up on revalidation")
+				_ = v.jobs.CompleteDefinitionRevalidation(rev)
+				continue
+			}
+			if err := v.jobs.RecordDefinitionRevalidationError(rev.JobDefinitionID, err.Error()); err != nil {
+				v.logger.Warn().Err(err).Str("job_definition_id", rev.JobDefinitionID).Msg("failed to record revalidation error")
+			}
+		}
+	}
+	return nil
+}
+
+// process validates a single definition. A returned error means the check could
+// not be performed and should be retried; validation failures are handled here.
+func (v *Revalidator) process(ctx context.Context, rev models.DefinitionRevalidation) error {
+	def, err := v.jobs.GetJobDefinitionByID(rev.TenantID, rev.JobDefinitionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return v.jobs.CompleteDefinitionRevalidation(rev)
+		}
+		return err
+	}
+	if !strings.EqualFold(string(def.Status), string(models.DefinitionStatusReady)) {
+		// Edited or demoted since it was queued; the next READY transition validates it.
+		return v.jobs.CompleteDefinitionRevalidation(rev)
+	}
+
+	errs, err := v.validate(ctx, def)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		v.logger.Info().Str("job_definition_id", def.ID).Msg("definition revalidated")
+		// Validated against the changed connection, the definition's schema
+		// baseline starts over.
+		if err := v.jobs.ResetDefinitionSchemaCheck(def.TenantID, def.ID); err != nil {
+			return fmt.Errorf("reset schema check: %w", err)
+		}
+		return v.jobs.CompleteDefinitionRevalidation(rev)
+	}
+
+	demoted := false
+	if v.demoteOnFailure {
+		demoted, err = v.jobs.DemoteReadyDefinition(def.TenantID, def.ID)
+		if err != nil {
+			return fmt.Errorf("demote definition: %w", err)
+		}
+	}
+	v.logger.Warn().Str("job_definition_id", def.ID).Strs("errors", errs).Bool("demoted", demoted).Msg("definition failed revalidation")
+	if v.notifier != nil {
+		if err := v.notifier.NotifyValidationFailed(ctx, def.TenantID, def.ID, def.Name, errs, demoted); err != nil {
+			v.logger.Warn().Err(err).Str("job_definition_id", def.ID).Msg("failed to publish revalidation notification")
+		}
+	}
+	return v.jobs.CompleteDefinitionRevalidation(rev)
+}
+
+// validate returns the validation errors for def. Missing connections count as
+// validation errors, as does a non-zero engine exit; failing to reach the engine
+// is returned as an error so the entry is retried.
+func (v *Revalidator) validate(ctx context.Context, def models.JobDefinition) ([]string, error) {
+	var errs []string
+	src, err := v.conns.Get(def.TenantID, def.SourceConnectionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("load source connection: %w", err)
+	}
+	if src == nil {
+		errs = append(errs, "source connection is missing")
+	}
+	dst, err := v.conns.Get(def.TenantID, def.DestinationConnectionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("load destination connection: %w", err)
+	}
+	if dst == nil {
+		errs = append(errs, "destination connection is missing")
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+	if src.PromptsForCredentials() || dst.PromptsForCredentials() {
+		// Without a stored password there is nothing to dry-run against.
+		v.logger.Info().Str("job_definition_id", def.ID).Msg("skipping revalidation of definition with prompt-mode connections")
+		return nil, nil
+	}
+
+	var ast map[string]interface{}
+	if err := json.Unmarshal(def.AST, &ast); err != nil || ast == nil {
+		return []string{"ast is empty or invalid"}, nil
+	}
+	secrets, err := v.jobs.GetDefinitionSecretValues(def.TenantID, def.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load definition secrets: %w", err)
+	}
+	resolved, err := models.ResolveASTSecrets(ast, secrets)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	ast = resolved.(map[string]interface{})
+	srcConnStr, err := src.GenerateConnString()
+	if err != nil {
+		return []string{"source connection: " + err.Error()}, nil
+	}
+	dstConnStr, err := dst.GenerateConnString()
+	if err != nil {
+		return []string{"destination connection: " + err.Error()}, nil
+	}
+	ast["connections"] = map[string]interface{}{
+		"source": map[string]interface{}{"conn_type": "Source", "format": dataFormatMap[src.DataFormat], "conn_str": srcConnStr},
+		"dest":   map[string]interface{}{"conn_type": "Dest", "format": dataFormatMap[dst.DataFormat], "conn_str": dstConnStr},
+	}
+	cfg, err := json.Marshal(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	host, release, err := v.hosts.Acquire(ctx, def.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("engine host: %w", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+	if _, err := host.Engine(v.containerName).DryRun(ctx, cfg); err != nil {
+		var exitErr *engine.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("dry-run: %w", err)
+		}
+		return []string{strings.TrimSpace(ansiEscape.ReplaceAllString(exitErr.Output, ""))}, nil
+	}
+	return nil, nil
+}
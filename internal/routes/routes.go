@@ -6,9 +6,39 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/stanstork/stratum-api/internal/authz"
 	"github.com/stanstork/stratum-api/internal/handlers"
+	"github.com/stanstork/stratum-api/internal/middleware"
 	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/version"
 )
 
+// deprecatedEndpoints lists routes scheduled for change, keyed by "METHOD /path"
+// relative to the API prefix. Matching responses carry Deprecation/Sunset headers.
+var deprecatedEndpoints = map[string]middleware.Deprecation{}
+
+type handlerSet struct {
+	auth         *handlers.AuthHandler
+	job          *handlers.JobHandler
+	conn         *handlers.ConnectionHandler
+	meta         *handlers.MetadataHandler
+	report       *handlers.ReportHandler
+	tenant       *handlers.TenantHandler
+	invite       *handlers.InviteHandler
+	notification *handlers.NotificationHandler
+	apiKey       *handlers.APIKeyHandler
+	grafana      *handlers.GrafanaHandler
+	admin        *handlers.AdminHandler
+	domain       *handlers.DomainHandler
+	compliance   *handlers.ComplianceHandler
+	template     *handlers.TemplateHandler
+	webhook      *handlers.WebhookHandler
+	announcement *handlers.AnnouncementHandler
+	sensor       *handlers.SensorHandler
+	view         *handlers.ViewHandler
+	setup        *handlers.SetupHandler
+	metrics      *handlers.MetricsHandler
+	scim         *handlers.SCIMHandler
+}
+
 // RegisterRoutes sets up the API routes
 func NewRouter(auth *handlers.AuthHandler,
 	job *handlers.JobHandler,
@@ -17,127 +47,529 @@ func NewRouter(auth *handlers.AuthHandler,
 	report *handlers.ReportHandler,
 	tenant *handlers.TenantHandler,
 	invite *handlers.InviteHandler,
-	notification *handlers.NotificationHandler) *mux.Router {
+	notification *handlers.NotificationHandler,
+	apiKey *handlers.APIKeyHandler,
+	grafana *handlers.GrafanaHandler,
+	admin *handlers.AdminHandler,
+	domain *handlers.DomainHandler,
+	compliance *handlers.ComplianceHandler,
+	template *handlers.TemplateHandler,
+	webhook *handlers.WebhookHandler,
+	announcement *handlers.AnnouncementHandler,
+	sensor *handlers.SensorHandler,
+	view *handlers.ViewHandler,
+	setup *handlers.SetupHandler,
+	metrics *handlers.MetricsHandler,
+	scim *handlers.SCIMHandler) *mux.Router {
+
+	h := handlerSet{
+		auth:         auth,
+		job:          job,
+		conn:         conn,
+		meta:         meta,
+		report:       report,
+		tenant:       tenant,
+		invite:       invite,
+		notification: notification,
+		apiKey:       apiKey,
+		grafana:      grafana,
+		admin:        admin,
+		domain:       domain,
+		compliance:   compliance,
+		template:     template,
+		webhook:      webhook,
+		announcement: announcement,
+		sensor:       sensor,
+		view:         view,
+		setup:        setup,
+		metrics:      metrics,
+		scim:         scim,
+	}
 
 	router := mux.NewRouter().StrictSlash(true)
 
 	// Health check route
 	router.HandleFunc("/health", handlers.HealthCheck).Methods(http.MethodGet)
 
+	// Per-tenant usage gauges for Prometheus, authenticated by the scrape token
+	router.HandleFunc("/metrics/tenants", h.metrics.TenantUsage).Methods(http.MethodGet)
+
+	// SCIM 2.0 provisioning for identity providers, authenticated by the tenant's
+	// SCIM token. The paths follow the SCIM spec rather than the API's conventions.
+	provisioning := router.PathPrefix("/scim/v2").Subrouter()
+	provisioning.Use(h.scim.Middleware)
+	provisioning.HandleFunc("/ServiceProviderConfig", h.scim.ServiceProviderConfig).Methods(http.MethodGet)
+	provisioning.HandleFunc("/Users", h.scim.ListUsers).Methods(http.MethodGet)
+	provisioning.HandleFunc("/Users", h.scim.CreateUser).Methods(http.MethodPost)
+	provisioning.HandleFunc("/Users/{userID}", h.scim.GetUser).Methods(http.MethodGet)
+	provisioning.HandleFunc("/Users/{userID}", h.scim.ReplaceUser).Methods(http.MethodPut)
+	provisioning.HandleFunc("/Users/{userID}", h.scim.PatchUser).Methods(http.MethodPatch)
+	provisioning.HandleFunc("/Users/{userID}", h.scim.DeleteUser).Methods(http.MethodDelete)
+	provisioning.HandleFunc("/Groups", h.scim.ListGroups).Methods(http.MethodGet)
+	provisioning.HandleFunc("/Groups/{groupID}", h.scim.GetGroup).Methods(http.MethodGet)
+	provisioning.HandleFunc("/Groups/{groupID}", h.scim.ReplaceGroup).Methods(http.MethodPut)
+	provisioning.HandleFunc("/Groups/{groupID}", h.scim.PatchGroup).Methods(http.MethodPatch)
+
+	// Versioned API. Must be registered before the unversioned alias, whose prefix
+	// also matches /api/v1.
+	v1Prefix := "/api/" + version.CurrentContract
+	v1 := router.PathPrefix(v1Prefix).Subrouter()
+	v1.Use(middleware.NegotiateVersion(version.CurrentContract))
+	v1.Use(middleware.DeprecatedEndpoints(v1Prefix, deprecatedEndpoints))
+	h.register(v1)
+
+	// Unversioned alias kept while clients migrate; every response carries
+	// Deprecation/Sunset headers pointing at the /api/v1 equivalent.
+	legacy := router.PathPrefix("/api").Subrouter()
+	legacy.Use(middleware.NegotiateVersion(""))
+	legacy.Use(middleware.DeprecatedAlias("/api", v1Prefix, version.LegacyAliasDeprecatedAt, version.LegacyAliasSunset))
+	legacy.Use(middleware.DeprecatedEndpoints("/api", deprecatedEndpoints))
+	h.register(legacy)
+
+	return router
+}
+
+// register mounts every API endpoint on base, which is either the versioned or the
+// unversioned API prefix.
+func (h handlerSet) register(base *mux.Router) {
+	base.HandleFunc("/version", handlers.VersionInfo(deprecatedEndpoints)).Methods(http.MethodGet)
+
+	// First-run setup of a self-hosted instance; locked once it completes.
+	base.HandleFunc("/setup", h.setup.Status).Methods(http.MethodGet)
+	base.HandleFunc("/setup", h.setup.Setup).Methods(http.MethodPost)
+	base.HandleFunc("/setup/checks", h.setup.Checks).Methods(http.MethodPost)
+
 	// Public auth endpoints
-	router.HandleFunc("/api/signup", auth.SignUp).Methods(http.MethodPost)
-	router.HandleFunc("/api/login", auth.Login).Methods(http.MethodPost)
+	base.HandleFunc("/signup", h.auth.SignUp).Methods(http.MethodPost)
+	base.HandleFunc("/signup/domain", h.auth.LookupSignupDomain).Methods(http.MethodGet)
+	base.HandleFunc("/login", h.auth.Login).Methods(http.MethodPost)
+	base.HandleFunc("/verify-email", h.auth.VerifyEmail).Methods(http.MethodGet)
+	base.HandleFunc("/verify-email/resend", h.auth.ResendVerification).Methods(http.MethodPost)
+	base.HandleFunc("/meta/password-policy", h.auth.PasswordPolicy).Methods(http.MethodGet)
+	base.HandleFunc("/meta/enums", handlers.Enums).Methods(http.MethodGet)
 
 	// Public invite workflows
-	router.HandleFunc("/api/invites/{token}", invite.PreviewInvite).Methods(http.MethodGet)
-	router.HandleFunc("/api/invites/{token}/accept", invite.AcceptInvite).Methods(http.MethodPost)
+	base.HandleFunc("/invites/{token}", h.invite.PreviewInvite).Methods(http.MethodGet)
+	base.HandleFunc("/invites/{token}/accept", h.invite.AcceptInvite).Methods(http.MethodPost)
+
+	// Grafana JSON datasource, authenticated by tenant API key
+	graf := base.PathPrefix("/grafana").Subrouter()
+	graf.Use(h.apiKey.Middleware)
+	graf.HandleFunc("/", h.grafana.TestDatasource).Methods(http.MethodGet)
+	graf.HandleFunc("/search", h.grafana.Search).Methods(http.MethodPost)
+	graf.HandleFunc("/query", h.grafana.Query).Methods(http.MethodPost)
 
 	// Protected routes with tenant ID in context
-	api := router.PathPrefix("/api").Subrouter()
-	api.Use(auth.JWTMiddleware)
+	api := base.NewRoute().Subrouter()
+	api.Use(h.auth.JWTMiddleware)
+	api.Use(h.announcement.MaintenanceGuard)
+
+	api.HandleFunc("/announcements", h.announcement.Active).Methods(http.MethodGet)
 
 	api.Handle("/tenants",
-		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(tenant.CreateTenant)),
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.tenant.CreateTenant)),
+	).Methods(http.MethodPost)
+	// Platform administration
+	api.Handle("/admin/engine/pull",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.PullEngineImage)),
+	).Methods(http.MethodPost)
+	api.Handle("/admin/engine/compatibility",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.EngineCompatibility)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/engine/compatibility",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.RecordEngineCompatibility)),
+	).Methods(http.MethodPost)
+	api.Handle("/admin/worker/status",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.WorkerStatus)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/registries",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.ListRegistryCredentials)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/registries",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.SaveRegistryCredential)),
+	).Methods(http.MethodPut)
+	api.Handle("/admin/registries/{registryID}",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.DeleteRegistryCredential)),
+	).Methods(http.MethodDelete)
+	api.Handle("/admin/docker/hosts",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.DockerHosts)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/compliance/export",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.compliance.Export)),
+	).Methods(http.MethodPost)
+	api.Handle("/admin/announcements",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.List)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/announcements",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.Create)),
 	).Methods(http.MethodPost)
+	api.Handle("/admin/announcements/{announcementID}",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.Update)),
+	).Methods(http.MethodPut)
+	api.Handle("/admin/announcements/{announcementID}",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.Delete)),
+	).Methods(http.MethodDelete)
+	api.Handle("/admin/maintenance",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.GetMaintenance)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/maintenance",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.announcement.SetMaintenance)),
+	).Methods(http.MethodPut)
+	api.Handle("/admin/latency",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.admin.Latency)),
+	).Methods(http.MethodGet)
+	api.Handle("/admin/executions/startup-latency",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.job.StartupLatency)),
+	).Methods(http.MethodGet)
+
+	api.Handle("/tenants/{tenantID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.UpdateTenant)),
+	).Methods(http.MethodPatch)
+	api.Handle("/tenants/{tenantID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.DeleteTenant)),
+	).Methods(http.MethodDelete)
+	api.Handle("/tenants/{tenantID}/restore",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.tenant.RestoreTenant)),
+	).Methods(http.MethodPost)
+	api.Handle("/tenants/{tenantID}/worker-config",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.tenant.GetWorkerConfig)),
+	).Methods(http.MethodGet)
+	api.Handle("/tenants/{tenantID}/worker-config",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.tenant.UpdateWorkerConfig)),
+	).Methods(http.MethodPut)
+	api.Handle("/tenants/{tenantID}/limits",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.GetExecutionLimits)),
+	).Methods(http.MethodGet)
+	api.Handle("/tenants/{tenantID}/limits",
+		authz.RequireRoleHandler(models.RoleSuperAdmin, http.HandlerFunc(h.tenant.UpdateExecutionLimits)),
+	).Methods(http.MethodPut)
+	api.Handle("/tenants/{tenantID}/blackout-windows",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.ListBlackoutWindows)),
+	).Methods(http.MethodGet)
+	api.Handle("/tenants/{tenantID}/blackout-windows",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.CreateBlackoutWindow)),
+	).Methods(http.MethodPost)
+	api.Handle("/tenants/{tenantID}/blackout-windows/{windowID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.DeleteBlackoutWindow)),
+	).Methods(http.MethodDelete)
 	api.Handle("/tenants/{tenantID}/users",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(tenant.ListUsers)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.ListUsers)),
 	).Methods(http.MethodGet)
 	api.Handle("/tenants/{tenantID}/users",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(tenant.AddUser)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.AddUser)),
 	).Methods(http.MethodPost)
 	api.Handle("/tenants/{tenantID}/invites",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(invite.CreateInvite)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.CreateInvite)),
 	).Methods(http.MethodPost)
 	api.Handle("/users/invites",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(invite.CreateCurrentTenantInvite)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.CreateCurrentTenantInvite)),
+	).Methods(http.MethodPost)
+	api.Handle("/users/invites/bulk",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.CreateBulkInvites)),
 	).Methods(http.MethodPost)
 	api.Handle("/users",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(tenant.ListCurrentTenantUsers)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.ListCurrentTenantUsers)),
 	).Methods(http.MethodGet)
+	api.HandleFunc("/users/me/email", h.auth.ChangeEmail).Methods(http.MethodPut)
+	api.HandleFunc("/users/me/password", h.auth.ChangePassword).Methods(http.MethodPut)
 	api.Handle("/users/{userID}/roles",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(tenant.UpdateUserRoles)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.UpdateUserRoles)),
 	).Methods(http.MethodPut)
 	api.Handle("/users/{userID}",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(tenant.DeleteUser)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.tenant.DeleteUser)),
 	).Methods(http.MethodDelete)
 	api.Handle("/users/invites",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(invite.ListCurrentInvites)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.ListCurrentInvites)),
 	).Methods(http.MethodGet)
 	api.Handle("/users/invites/{inviteID}",
-		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(invite.CancelCurrentInvite)),
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.CancelCurrentInvite)),
+	).Methods(http.MethodDelete)
+	api.Handle("/users/invites/{inviteID}/resend",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.invite.ResendCurrentInvite)),
+	).Methods(http.MethodPost)
+
+	// Email domain auto-join
+	api.Handle("/domains",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.List)),
+	).Methods(http.MethodGet)
+	api.Handle("/domains",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.Create)),
+	).Methods(http.MethodPost)
+	api.Handle("/domains/join-requests",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.ListJoinRequests)),
+	).Methods(http.MethodGet)
+	api.Handle("/domains/join-requests/{requestID}/approve",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.ApproveJoinRequest)),
+	).Methods(http.MethodPost)
+	api.Handle("/domains/join-requests/{requestID}/reject",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.RejectJoinRequest)),
+	).Methods(http.MethodPost)
+	api.Handle("/domains/{domainID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.Update)),
+	).Methods(http.MethodPatch)
+	api.Handle("/domains/{domainID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.Delete)),
+	).Methods(http.MethodDelete)
+	api.Handle("/domains/{domainID}/verify",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.domain.Verify)),
+	).Methods(http.MethodPost)
+
+	// The tenant's SCIM token; rotating issues one and replaces any previous token
+	api.Handle("/scim/token",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.scim.GetToken)),
+	).Methods(http.MethodGet)
+	api.Handle("/scim/token",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.scim.RotateToken)),
+	).Methods(http.MethodPost)
+	api.Handle("/scim/token",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.scim.RevokeToken)),
+	).Methods(http.MethodDelete)
+
+	api.Handle("/api-keys",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.apiKey.List)),
+	).Methods(http.MethodGet)
+	api.Handle("/api-keys",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.apiKey.Create)),
+	).Methods(http.MethodPost)
+	api.Handle("/api-keys/{keyID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.apiKey.Revoke)),
+	).Methods(http.MethodDelete)
+
+	// Webhook subscriptions, managed per event category
+	api.Handle("/webhooks/{category}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.List)),
+	).Methods(http.MethodGet)
+	api.Handle("/webhooks/{category}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.Create)),
+	).Methods(http.MethodPost)
+	api.Handle("/webhooks/{category}/{webhookID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.Update)),
+	).Methods(http.MethodPatch)
+	api.Handle("/webhooks/{category}/{webhookID}",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.Delete)),
 	).Methods(http.MethodDelete)
+	api.Handle("/webhooks/{category}/{webhookID}/deliveries",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.ListDeliveries)),
+	).Methods(http.MethodGet)
+	api.Handle("/webhooks/{category}/{webhookID}/test",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.webhook.Test)),
+	).Methods(http.MethodPost)
+
+	// Job templates
+	api.HandleFunc("/templates", h.template.List).Methods(http.MethodGet)
+	api.Handle("/templates",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.template.Create)),
+	).Methods(http.MethodPost)
+	api.HandleFunc("/templates/{templateID}", h.template.Get).Methods(http.MethodGet)
+	api.Handle("/templates/{templateID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.template.Update)),
+	).Methods(http.MethodPut)
+	api.Handle("/templates/{templateID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.template.Delete)),
+	).Methods(http.MethodDelete)
+	api.HandleFunc("/templates/{templateID}/render", h.template.Render).Methods(http.MethodPost)
+
+	// Saved views of the executions and jobs lists
+	api.HandleFunc("/views", h.view.List).Methods(http.MethodGet)
+	api.HandleFunc("/views", h.view.Create).Methods(http.MethodPost)
+	api.HandleFunc("/views/defaults/{list}", h.view.SetDefault).Methods(http.MethodPut)
+	api.HandleFunc("/views/{viewID}", h.view.Get).Methods(http.MethodGet)
+	api.HandleFunc("/views/{viewID}", h.view.Update).Methods(http.MethodPut)
+	api.HandleFunc("/views/{viewID}", h.view.Delete).Methods(http.MethodDelete)
 
 	// Base "/jobs" routes
 	api.Handle("/jobs/draft",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.CreateDraft)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CreateDraft)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/from-template/{templateID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CreateFromTemplate)),
 	).Methods(http.MethodPost)
 	api.Handle("/jobs",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.CreateJob)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CreateJob)),
 	).Methods(http.MethodPost)
-	api.HandleFunc("/jobs", job.ListJobs).Methods(http.MethodGet)
+	api.HandleFunc("/jobs", h.job.ListJobs).Methods(http.MethodGet)
 	api.Handle("/jobs/{jobID}",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.AutosaveJob)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.AutosaveJob)),
 	).Methods(http.MethodPatch)
 
 	// Specific sub-paths of "/jobs/..." MUST come BEFORE dynamic "/jobs/{jobID}"
 
 	// Most specific "/jobs/executions/..." route first
-	api.HandleFunc("/jobs/executions/stats", job.GetExecutionStats).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/stats", h.job.GetExecutionStats).Methods(http.MethodGet)
+	api.Handle("/jobs/executions/cancel-pending",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CancelPendingExecutions)),
+	).Methods(http.MethodPost)
 
 	// Parent "/jobs/executions" route next
-	api.HandleFunc("/jobs/executions", job.ListExecutions).Methods(http.MethodGet)
-	api.HandleFunc("/jobs/executions/{execID}", job.GetExecution).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions", h.job.ListExecutions).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}", h.job.GetExecution).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/snapshot", h.job.GetExecutionSnapshot).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/artifacts", h.job.ListExecutionArtifacts).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/queue", h.job.GetExecutionQueue).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/progress", h.job.GetExecutionProgress).Methods(http.MethodGet)
+	api.Handle("/jobs/executions/{execID}/sample-diff",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.SampleDiff)),
+	).Methods(http.MethodPost)
+	api.HandleFunc("/jobs/executions/{execID}/notes", h.job.ListExecutionNotes).Methods(http.MethodGet)
+	api.Handle("/jobs/executions/{execID}/notes",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CreateExecutionNote)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/notes/{noteID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.DeleteExecutionNote)),
+	).Methods(http.MethodDelete)
+	api.HandleFunc("/execution-notes", h.job.SearchExecutionNotes).Methods(http.MethodGet)
+	// Failed executions awaiting a requeue
+	api.HandleFunc("/executions/dead-letter", h.job.ListDeadLetterExecutions).Methods(http.MethodGet)
+	api.Handle("/executions/requeue",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.RequeueExecutions)),
+	).Methods(http.MethodPost)
+	// Short execution control paths; same handlers as /jobs/executions/{execID}/...
+	api.Handle("/executions/{execID}/pause",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.PauseExecution)),
+	).Methods(http.MethodPost)
+	api.Handle("/executions/{execID}/resume",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ResumeExecution)),
+	).Methods(http.MethodPost)
+	api.HandleFunc("/executions/{execID}/rerun", h.job.RerunExecution).Methods(http.MethodPost)
+	api.HandleFunc("/executions/{execID}/rerun-failed", h.job.RerunFailedTables).Methods(http.MethodPost)
+	api.Handle("/executions/{execID}/rollback",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.RollbackExecution)),
+	).Methods(http.MethodPost)
+	api.Handle("/executions/{execID}/approve",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.job.ApproveExecution)),
+	).Methods(http.MethodPost)
+	api.HandleFunc("/executions/{execID}/queue", h.job.GetExecutionQueue).Methods(http.MethodGet)
+	api.HandleFunc("/executions/{execID}/progress", h.job.GetExecutionProgress).Methods(http.MethodGet)
+	api.HandleFunc("/executions/{execID}/tables", h.job.ListExecutionTables).Methods(http.MethodGet)
+	api.HandleFunc("/executions/{execID}/logs", h.job.GetExecutionLogs).Methods(http.MethodGet)
+	api.HandleFunc("/executions/{execID}/events", h.job.ListExecutionEvents).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/checkpoints", h.job.ListExecutionCheckpoints).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/tables", h.job.ListExecutionTables).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/logs", h.job.GetExecutionLogs).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/executions/{execID}/events", h.job.ListExecutionEvents).Methods(http.MethodGet)
+	api.Handle("/jobs/executions/{execID}/checkpoints",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ReportCheckpoints)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/pause",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.PauseExecution)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/resume",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ResumeExecution)),
+	).Methods(http.MethodPost)
+	api.HandleFunc("/jobs/executions/{execID}/rerun", h.job.RerunExecution).Methods(http.MethodPost)
+	api.HandleFunc("/jobs/executions/{execID}/rerun-failed", h.job.RerunFailedTables).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/rollback",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.RollbackExecution)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/approve",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.job.ApproveExecution)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/executions/{execID}/cancel",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.CancelExecution)),
+	).Methods(http.MethodPost)
 	api.Handle("/jobs/executions/{execID}/complete",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.SetExecutionComplete)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.SetExecutionComplete)),
 	).Methods(http.MethodPost)
 
-	api.HandleFunc("/jobs/stats", job.ListJobDefinitionsWithStats).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/stats", h.job.ListJobDefinitionsWithStats).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/calendar", h.job.GetCalendar).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/run-group", h.job.RunGroup).Methods(http.MethodPost)
+	api.HandleFunc("/jobs/run-groups/{groupID}", h.job.GetRunGroup).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/run-groups/{groupID}/report", h.job.GetRunGroupReport).Methods(http.MethodGet)
 	api.Handle("/jobs/{jobID}/validate",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.ValidateJobDefinition)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ValidateJobDefinition)),
 	).Methods(http.MethodPost)
 	api.Handle("/jobs/{jobID}/ready",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.MarkDefinitionReady)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.MarkDefinitionReady)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/{jobID}/archive",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ArchiveJob)),
 	).Methods(http.MethodPost)
-	api.Handle("/jobs/{jobID}/run",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.RunJob)),
+	api.Handle("/jobs/{jobID}/unarchive",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.UnarchiveJob)),
 	).Methods(http.MethodPost)
-	api.HandleFunc("/jobs/{jobID}/status", job.GetJobStatus).Methods(http.MethodGet)
+	// Viewers may run definitions that grant them; RunJob checks the grants.
+	api.HandleFunc("/jobs/{jobID}/run", h.job.RunJob).Methods(http.MethodPost)
+	api.Handle("/jobs/{jobID}/permissions",
+		authz.RequireRoleHandler(models.RoleAdmin, http.HandlerFunc(h.job.SetRunPermissions)),
+	).Methods(http.MethodPut)
+	api.HandleFunc("/jobs/{jobID}/schedule", h.job.GetSchedule).Methods(http.MethodGet)
+	api.Handle("/jobs/{jobID}/schedule",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.SetSchedule)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/{jobID}/schedule",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.DeleteSchedule)),
+	).Methods(http.MethodDelete)
+	api.HandleFunc("/jobs/{jobID}/status", h.job.GetJobStatus).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/ast/tables", h.job.ListASTTables).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/stats/monthly", h.job.GetDefinitionMonthlyStats).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/regressions", h.job.ListRegressions).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/sensors/evaluations", h.sensor.ListEvaluations).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/sensors", h.sensor.List).Methods(http.MethodGet)
+	api.Handle("/jobs/{jobID}/sensors",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.sensor.Create)),
+	).Methods(http.MethodPost)
+	api.Handle("/jobs/{jobID}/sensors/{sensorID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.sensor.Update)),
+	).Methods(http.MethodPatch)
+	api.Handle("/jobs/{jobID}/sensors/{sensorID}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.sensor.Delete)),
+	).Methods(http.MethodDelete)
 	api.Handle("/jobs/{jobID}",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(job.DelteJob)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.DelteJob)),
 	).Methods(http.MethodDelete)
-	api.HandleFunc("/jobs/{jobID}", job.GetJobDefinition).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}", h.job.GetJobDefinition).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{jobID}/secrets", h.job.ListDefinitionSecrets).Methods(http.MethodGet)
+	api.Handle("/jobs/{jobID}/secrets/{name}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.SetDefinitionSecret)),
+	).Methods(http.MethodPut)
+	api.Handle("/jobs/{jobID}/secrets/{name}",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.DeleteDefinitionSecret)),
+	).Methods(http.MethodDelete)
+
+	// Bulk import of connections, definitions and schedules from another system
+	api.Handle("/import",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.job.ImportTenant)),
+	).Methods(http.MethodPost)
 
 	// Connection management routes
 	api.Handle("/connections/test",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(conn.TestConnection)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.TestConnection)),
+	).Methods(http.MethodPost)
+	api.Handle("/connections/import",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.Import)),
 	).Methods(http.MethodPost)
 	api.Handle("/connections/{id}/test",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(conn.TestConnectionByID)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.TestConnectionByID)),
+	).Methods(http.MethodPost)
+	api.Handle("/connections/{id}/benchmark",
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.Benchmark)),
 	).Methods(http.MethodPost)
-	api.HandleFunc("/connections", conn.List).Methods(http.MethodGet)
+	api.HandleFunc("/connections", h.conn.List).Methods(http.MethodGet)
 	api.Handle("/connections",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(conn.Create)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.Create)),
 	).Methods(http.MethodPost)
-	api.HandleFunc("/connections/{id}", conn.Get).Methods(http.MethodGet)
+	api.HandleFunc("/connections/{id}", h.conn.Get).Methods(http.MethodGet)
 	api.Handle("/connections/{id}",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(conn.Update)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.Update)),
 	).Methods(http.MethodPut)
 	api.Handle("/connections/{id}",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(conn.Delete)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.conn.Delete)),
 	).Methods(http.MethodDelete)
+	api.HandleFunc("/connections/{id}/impact", h.conn.Impact).Methods(http.MethodGet)
 
 	// Metadata routes
 	api.Handle("/connections/{id}/metadata",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(meta.GetSourceMetadata)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.meta.GetSourceMetadata)),
 	).Methods(http.MethodGet)
 
 	// Report routes
 	api.Handle("/reports/dry-run/{definition_id}",
-		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(report.DryRunReport)),
+		authz.RequireRoleHandler(models.RoleEditor, http.HandlerFunc(h.report.DryRunReport)),
 	).Methods(http.MethodPost)
 
-	api.HandleFunc("/notifications", notification.List).Methods(http.MethodGet)
-	api.HandleFunc("/notifications/{notificationID}/read", notification.MarkRead).Methods(http.MethodPost)
+	api.HandleFunc("/notifications", h.notification.List).Methods(http.MethodGet)
+	api.HandleFunc("/notifications/{notificationID}/read", h.notification.MarkRead).Methods(http.MethodPost)
+	api.HandleFunc("/notifications/groups/{correlationKey}/read", h.notification.MarkGroupRead).Methods(http.MethodPost)
 
-	return router
 }
@@ -0,0 +1,90 @@
+package scanning
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks content is streamed to clamd in; it must
+// stay below clamd's StreamMaxLength.
+const clamdChunkSize = 64 * 1024
+
+// ClamAV scans content with a clamd daemon over its INSTREAM protocol.
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV returns a scanner for the clamd daemon at address: "host:port" for TCP
+// or a path for a unix socket.
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &ClamAV{network: network, address: address, timeout: timeout}
+}
+
+func (c *ClamAV) Scan(ctx context.Context, content []byte) (Verdict, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Verdict{}, fmt.Errorf("write to clamd: %w", err)
+	}
+	var size [4]byte
+	for len(content) > 0 {
+		chunk := content
+		if len(chunk) > clamdChunkSize {
+			chunk = chunk[:clamdChunkSize]
+		}
+		binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+		if _, err := conn.Write(size[:]); err != nil {
+			return Verdict{}, fmt.Errorf("write to clamd: %w", err)
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return Verdict{}, fmt.Errorf("write to clamd: %w", err)
+		}
+		content = content[len(chunk):]
+	}
+	// A zero-length chunk ends the stream.
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return Verdict{}, fmt.Errorf("write to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil {
+		return Verdict{}, fmt.Errorf("read clamd reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads a reply such as "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND".
+func parseClamdReply(reply string) (Verdict, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return Verdict{Clean: true}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return Verdict{}, fmt.Errorf("clamd: %s", result)
+	}
+}
@@ -0,0 +1,57 @@
+package scanning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPScanner sends content to an external scanning service. The service receives
+// the content as the body of a POST and answers 200 with
+// {"clean": bool, "signature": string}.
+type HTTPScanner struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPScanner returns a scanner for the service at url. A non-empty token is
+// sent as a bearer token.
+func NewHTTPScanner(url, token string, timeout time.Duration) *HTTPScanner {
+	return &HTTPScanner{url: url, token: token, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *HTTPScanner) Scan(ctx context.Context, content []byte) (Verdict, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(content))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Verdict{}, fmt.Errorf("scanning service returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var out struct {
+		Clean     *bool  `json:"clean"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Verdict{}, fmt.Errorf("decode scanning service reply: %w", err)
+	}
+	if out.Clean == nil {
+		return Verdict{}, fmt.Errorf("scanning service reply has no verdict")
+	}
+	return Verdict{Clean: *out.Clean, Signature: out.Signature}, nil
+}
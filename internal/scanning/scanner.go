@@ -0,0 +1,88 @@
+// Package scanning checks artifacts produced by engine containers for malware
+// before users can download them.
+package scanning
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/config"
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+// Scanning backends.
+const (
+	BackendClamAV = "clamav"
+	BackendHTTP   = "http"
+)
+
+// Verdict is the outcome of scanning one artifact.
+type Verdict struct {
+	Clean bool
+	// Signature names what was found in content that is not clean.
+	Signature string
+}
+
+// Scanner checks content for malware. An error means the content could not be
+// scanned, not that it is infected.
+type Scanner interface {
+	Scan(ctx context.Context, content []byte) (Verdict, error)
+}
+
+// New builds the scanner configured by cfg. It returns nil when no backend is
+// configured.
+func New(cfg config.ScanningConfig) (Scanner, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case BackendClamAV:
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("scanning backend %s needs an address", BackendClamAV)
+		}
+		return NewClamAV(cfg.Address, timeout), nil
+	case BackendHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("scanning backend %s needs a url", BackendHTTP)
+		}
+		return NewHTTPScanner(cfg.URL, cfg.Token, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown scanning backend %q", cfg.Backend)
+	}
+}
+
+// Apply scans an artifact about to be stored and records the outcome on it.
+// Infected artifacts are quarantined. When the tenant requires scanning, artifacts
+// that cannot be scanned, or that arrive while no scanner is configured, are
+// quarantined too; otherwise they are stored unscanned. The returned error is the
+// scanner's, for callers to log; the artifact can be stored either way.
+func Apply(ctx context.Context, scanner Scanner, required bool, artifact *models.ExecutionArtifact) error {
+	if scanner == nil {
+		artifact.ScanStatus = models.ArtifactScanUnscanned
+		if required {
+			artifact.ScanStatus = models.ArtifactScanQuarantined
+			artifact.ScanDetail = "scanning is required but no scanner is configured"
+		}
+		return nil
+	}
+	verdict, err := scanner.Scan(ctx, artifact.Content)
+	if err != nil {
+		artifact.ScanStatus = models.ArtifactScanUnscanned
+		if required {
+			artifact.ScanStatus = models.ArtifactScanQuarantined
+		}
+		artifact.ScanDetail = "scan failed: " + err.Error()
+		return err
+	}
+	if !verdict.Clean {
+		artifact.ScanStatus = models.ArtifactScanQuarantined
+		artifact.ScanDetail = "infected: " + verdict.Signature
+		return nil
+	}
+	artifact.ScanStatus = models.ArtifactScanClean
+	return nil
+}
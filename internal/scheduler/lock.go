@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+)
+
+// Locker hands out cluster-wide, non-blocking locks identified by name.
+type Locker interface {
+	// TryLock attempts to take the named lock. When acquired is true the caller owns
+	// the lock until it calls unlock.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
+
+type postgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker returns a Locker backed by Postgres session-level advisory locks.
+// Each held lock pins one pooled connection, and the lock is released automatically
+// by Postgres if that connection dies.
+func NewPostgresLocker(db *sql.DB) Locker {
+	return &postgresLocker{db: db}
+}
+
+func (l *postgresLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve lock connection: %w", err)
+	}
+
+	key := advisoryKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock %q: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Use a fresh context so cancellation of the task does not leak the lock.
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// advisoryKey maps a lock name onto the bigint keyspace used by pg advisory locks.
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("stratum:scheduler:" + name))
+	return int64(h.Sum64())
+}
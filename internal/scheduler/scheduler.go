@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Task is a periodic unit of work that must run at most once per interval across
+// all API replicas.
+type Task struct {
+	Name     string
+	Interval time.Duration
+	// Timeout bounds a single run. Defaults to the task interval.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Scheduler runs registered tasks on every replica but lets only one replica execute
+// each due run: the run is guarded by an advisory lock and recorded in
+// tenant.scheduler_runs, so a replica that wins the lock right after another one
+// finished sees the recent run and skips.
+type Scheduler struct {
+	db     *sql.DB
+	locker Locker
+	holder string
+	logger zerolog.Logger
+
+	mu    sync.Mutex
+	tasks []Task
+	wg    sync.WaitGroup
+}
+
+func New(db *sql.DB, locker Locker, logger zerolog.Logger) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:     db,
+		locker: locker,
+		holder: fmt.Sprintf("%s/%s", host, uuid.NewString()[:8]),
+		logger: logger.With().Str("component", "scheduler").Logger(),
+	}
+}
+
+// Register adds a task. Tasks registered after Start are not picked up.
+func (s *Scheduler) Register(task Task) {
+	if task.Timeout <= 0 {
+		task.Timeout = task.Interval
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = append(s.tasks, task)
+}
+
+// Start launches one loop per registered task. Loops exit when ctx is cancelled;
+// use Wait to block until in-flight runs have returned.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	tasks := append([]Task(nil), s.tasks...)
+	s.mu.Unlock()
+
+	for _, task := range tasks {
+		s.wg.Add(1)
+		go func(task Task) {
+			defer s.wg.Done()
+			s.loop(ctx, task)
+		}(task)
+	}
+	s.logger.Info().Int("tasks", len(tasks)).Str("holder", s.holder).Msg("scheduler started")
+}
+
+// Wait blocks until all task loops have stopped.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, task Task) {
+	// Check often enough that a replica picks up the task soon after it becomes due,
+	// without hammering the database for long intervals.
+	poll := task.Interval / 4
+	if poll < time.Second {
+		poll = time.Second
+	}
+	if poll > time.Minute {
+		poll = time.Minute
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		s.runIfDue(ctx, task)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runIfDue(ctx context.Context, task Task) {
+	unlock, acquired, err := s.locker.TryLock(ctx, task.Name)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Str("task", task.Name).Msg("failed to acquire task lock")
+		}
+		return
+	}
+	if !acquired {
+		return
+	}
+	defer unlock()
+
+	due, err := s.claim(ctx, task)
+	if err != nil {
+		s.logger.Error().Err(err).Str("task", task.Name).Msg("failed to claim task run")
+		return
+	}
+	if !due {
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	runErr := task.Run(runCtx)
+	logEvent := s.logger.Debug()
+	if runErr != nil {
+		logEvent = s.logger.Error().Err(runErr)
+	}
+	logEvent.Str("task", task.Name).Dur("duration", time.Since(started)).Msg("scheduled task finished")
+
+	if err := s.finish(task, runErr); err != nil {
+		s.logger.Error().Err(err).Str("task", task.Name).Msg("failed to record task run")
+	}
+}
+
+// claim records a new run for the task if its interval has elapsed since the last
+// run on any replica. It must be called while holding the task lock.
+func (s *Scheduler) claim(ctx context.Context, task Task) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant.scheduler_runs (task_name, last_started_at, holder)
+		VALUES ($1, now(), $2)
+		ON CONFLICT (task_name) DO UPDATE
+		SET last_started_at = now(), last_finished_at = NULL, last_error = NULL, holder = EXCLUDED.holder
+		WHERE tenant.scheduler_runs.last_started_at <= now() - make_interval(secs => $3)
+	`, task.Name, s.holder, task.Interval.Seconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *Scheduler) finish(task Task, runErr error) error {
+	var errMsg sql.NullString
+	if runErr != nil {
+		errMsg = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+	_, err := s.db.Exec(`
+		UPDATE tenant.scheduler_runs
+		SET last_finished_at = now(), last_error = $2
+		WHERE task_name = $1 AND holder = $3
+	`, task.Name, errMsg, s.holder)
+	return err
+}
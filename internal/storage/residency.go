@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/config"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// ErrUnknownRegion is returned for regions that have no storage configured.
+var ErrUnknownRegion = errors.New("unknown storage region")
+
+// CrossRegionError reports an attempt to read or write tenant data from a deployment
+// serving a different region than the one the data is pinned to.
+type CrossRegionError struct {
+	Region        string
+	ServingRegion string
+	// URL is the deployment serving Region, if known.
+	URL string
+}
+
+func (e *CrossRegionError) Error() string {
+	return fmt.Sprintf("data is stored in region %q but this deployment serves %q", e.Region, e.ServingRegion)
+}
+
+// Location is a resolved storage target for one region.
+type Location struct {
+	Region   string
+	Bucket   string
+	Endpoint string
+}
+
+// URI returns the object URI for key inside the location's bucket.
+func (l Location) URI(key string) string {
+	if l.Bucket == "" {
+		return ""
+	}
+	return fmt.Sprintf("s3://%s/%s", l.Bucket, strings.TrimPrefix(key, "/"))
+}
+
+// Residency resolves where a tenant's artifacts and logs live and guards against
+// moving them across regions.
+type Residency struct {
+	cfg     config.StorageConfig
+	tenants repository.TenantRepository
+}
+
+func NewResidency(cfg config.StorageConfig, tenants repository.TenantRepository) *Residency {
+	return &Residency{cfg: cfg, tenants: tenants}
+}
+
+// Regions lists the configured region names, sorted.
+func (r *Residency) Regions() []string {
+	names := make([]string, 0, len(r.cfg.Regions))
+	for name := range r.cfg.Regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateRegion checks that region has storage configured. An empty region selects
+// the default and is always valid.
+func (r *Residency) ValidateRegion(region string) error {
+	if region == "" {
+		return nil
+	}
+	if _, ok := r.cfg.Regions[region]; !ok {
+		return fmt.Errorf("%w %q (configured: %s)", ErrUnknownRegion, region, strings.Join(r.Regions(), ", "))
+	}
+	return nil
+}
+
+// Location resolves region, falling back to the default region when empty.
+func (r *Residency) Location(region string) (Location, error) {
+	if region == "" {
+		region = r.cfg.DefaultRegion
+	}
+	if region == "" {
+		return Location{}, nil
+	}
+	rc, ok := r.cfg.Regions[region]
+	if !ok {
+		return Location{}, fmt.Errorf("%w %q", ErrUnknownRegion, region)
+	}
+	return Location{Region: region, Bucket: rc.Bucket, Endpoint: rc.Endpoint}, nil
+}
+
+// TenantLocation resolves the storage location for a tenant's data and verifies
+// that this deployment may access it.
+func (r *Residency) TenantLocation(tenantID string) (Location, error) {
+	tenant, err := r.tenants.GetTenantByID(tenantID)
+	if err != nil {
+		return Location{}, err
+	}
+	region := ""
+	if tenant.DataRegion != nil {
+		region = *tenant.DataRegion
+	}
+	loc, err := r.Location(region)
+	if err != nil {
+		return Location{}, err
+	}
+	return loc, r.CheckAccess(loc.Region)
+}
+
+// CheckAccess returns a *CrossRegionError when data pinned to region must not be
+// handled by this deployment. Deployments without a serving region are unrestricted.
+func (r *Residency) CheckAccess(region string) error {
+	serving := r.cfg.ServingRegion
+	if serving == "" || region == "" || region == serving {
+		return nil
+	}
+	return &CrossRegionError{Region: region, ServingRegion: serving, URL: r.cfg.Regions[region].URL}
+}
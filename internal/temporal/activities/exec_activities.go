@@ -3,43 +3,77 @@ package activities
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"go.temporal.io/sdk/activity"
+	sdktemporal "go.temporal.io/sdk/temporal"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/blobstore"
+	"github.com/stanstork/stratum-api/internal/engine"
 	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/notification"
 	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/scanning"
+	"github.com/stanstork/stratum-api/internal/storage"
 	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/utils"
+	"github.com/stanstork/stratum-api/internal/version"
 )
 
 type Activities struct {
-	JobRepo           repository.JobRepository
-	ConnRepo          repository.ConnectionRepository
-	DockerClient      *client.Client
+	JobRepo    repository.JobRepository
+	ConnRepo   repository.ConnectionRepository
+	TenantRepo repository.TenantRepository
+	SensorRepo repository.SensorRepository
+	Hosts      *engine.HostPool
+	// Kubernetes launches engine runs as Kubernetes Jobs; nil runs them as
+	// containers on Hosts.
+	Kubernetes *engine.KubernetesRunner
+	// Registries authenticates engine image pulls from private registries.
+	Registries        *engine.RegistryAuth
+	Credentials       *temporal.CredentialVault
 	EngineImage       string
 	JWTSigningKey     []byte
-	TempDir           string
+	Blobs             blobstore.Store
 	ContainerCPULimit int64
 	ContainerMemLimit int64
 	Notifier          notification.Service
+	Residency         *storage.Residency
+	// Scanner checks artifacts for malware before they are stored; nil when no
+	// scanner is configured.
+	Scanner scanning.Scanner
+	// Regression compares succeeded executions against their definition's baseline.
+	Regression RegressionPolicy
 }
 
+// RegressionPolicy configures regression detection. A run is compared once its
+// definition has MinRuns succeeded executions; the baseline covers the last
+// BaselineRuns of them. Threshold is the relative slowdown that is flagged.
+type RegressionPolicy struct {
+	Threshold    float64
+	BaselineRuns int
+	MinRuns      int
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
 var dataFormatMap = map[string]string{
 	"pg":         "Postgres",
 	"postgresql": "Postgres",
@@ -47,15 +81,65 @@ var dataFormatMap = map[string]string{
 	"mysql":      "MySql",
 }
 
-func (a *Activities) CreateExecutionActivity(ctx context.Context, tenantID, jobDefID, executionID string) error {
+func (a *Activities) CreateExecutionActivity(ctx context.Context, tenantID, jobDefID, executionID, triggeredBy string, triggerContext map[string]string, allowConcurrent bool) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Creating job execution record in database", "tenantID", tenantID, "jobDefID", jobDefID, "executionID", executionID)
+	logger.Info("Creating job execution record in database", "tenantID", tenantID, "jobDefID", jobDefID, "executionID", executionID, "triggeredBy", triggeredBy)
+
+	// API runs are checked against blackout windows, start limits and validation
+	// freshness before their workflow starts; scheduled runs start on their own and
+	// are checked here.
+	var skipped, skipType string
+	if triggeredBy == models.TriggerSchedule {
+		now := time.Now()
+		windows, err := a.TenantRepo.ListBlackoutWindows(tenantID)
+		if err != nil {
+			return errors.Wrap(err, "failed to check blackout windows")
+		}
+		if active, until := models.ActiveBlackout(windows, now); active != nil {
+			skipped = fmt.Sprintf("Skipped, blackout window %q until %s", active.Name, until.UTC().Format(time.RFC3339))
+			skipType = temporal.ErrTypeBlackout
+		} else {
+			allowed, resetAt, err := a.checkStartLimit(tenantID, now)
+			if err != nil {
+				return errors.Wrap(err, "failed to check execution start limit")
+			}
+			if !allowed {
+				skipped = "Skipped, execution start limit reached until " + resetAt.UTC().Format(time.RFC3339)
+				skipType = temporal.ErrTypeStartLimited
+			}
+		}
+		if skipped == "" {
+			blocked, err := a.staleValidationBlocked(tenantID, jobDefID)
+			if err != nil {
+				return errors.Wrap(err, "failed to check validation freshness")
+			}
+			if blocked {
+				skipped = "Skipped, the source schema changed since the job definition was validated"
+				skipType = temporal.ErrTypeStaleValidation
+			}
+		}
+	}
 
-	exec, err := a.JobRepo.CreateExecution(tenantID, jobDefID, executionID)
+	workflowID := activity.GetInfo(ctx).WorkflowExecution.ID
+	exec, err := a.JobRepo.CreateExecution(tenantID, jobDefID, executionID, workflowID, triggeredBy, triggerContext, allowConcurrent)
+	if errors.Is(err, repository.ErrExecutionActive) {
+		logger.Warn("Definition already has an active execution", "jobDefID", jobDefID, "error", err)
+		return sdktemporal.NewNonRetryableApplicationError(err.Error(), temporal.ErrTypeExecutionActive, err)
+	}
 	if err != nil {
 		logger.Error("Failed to create execution record in database", "error", err)
 		return err
 	}
+	if skipped != "" {
+		logger.Warn("Scheduled run skipped", "tenantID", tenantID, "reason", skipped)
+		if _, err := a.JobRepo.UpdateExecution(tenantID, executionID, models.ExecutionStatusSkipped, skipped, ""); err != nil {
+			return errors.Wrap(err, "failed to record skipped execution")
+		}
+		return sdktemporal.NewNonRetryableApplicationError(skipped, skipType, nil)
+	}
+	a.recordStartupStage(ctx, tenantID, executionID, models.StartupStageWorkflowStarted, exec.CreatedAt)
+	a.recordEvent(ctx, tenantID, executionID, models.ExecutionEventCreated, exec.CreatedAt,
+		map[string]interface{}{"triggered_by": triggeredBy, "workflow_id": workflowID})
 
 	if a.Notifier != nil {
 		def, defErr := a.JobRepo.GetJobDefinitionByID(tenantID, jobDefID)
@@ -69,7 +153,26 @@ func (a *Activities) CreateExecutionActivity(ctx context.Context, tenantID, jobD
 	return nil
 }
 
-func (a *Activities) UpdateJobStatusActivity(ctx context.Context, tenantID, executionID, status, message, logs string) error {
+// checkStartLimit reports whether the tenant may start another execution at now
+// and, when it may not, when it may again.
+func (a *Activities) checkStartLimit(tenantID string, now time.Time) (bool, time.Time, error) {
+	limits, err := a.TenantRepo.GetExecutionLimits(tenantID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	limit := limits.Effective()
+	if limit.Unlimited() {
+		return true, time.Time{}, nil
+	}
+	starts, err := a.TenantRepo.ListExecutionStarts(tenantID, now.Add(-models.ExecutionStartRateWindow))
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	allowed, resetAt := limit.Check(starts, now)
+	return allowed, resetAt, nil
+}
+
+func (a *Activities) UpdateJobStatusActivity(ctx context.Context, tenantID, executionID string, status models.ExecutionStatus, message, logs string) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Updating job status", "tenantID", tenantID, "executionID", executionID, "status", status)
 	_, err := a.JobRepo.UpdateExecution(tenantID, executionID, status, message, logs)
@@ -77,145 +180,319 @@ func (a *Activities) UpdateJobStatusActivity(ctx context.Context, tenantID, exec
 		logger.Error("Failed to update job status", "error", err)
 		return err
 	}
+	if status == models.ExecutionStatusRunning {
+		a.recordEvent(ctx, tenantID, executionID, models.ExecutionEventQueued, time.Now(), nil)
+	} else {
+		a.recordCompleted(ctx, tenantID, executionID, status, message)
+	}
 
 	a.emitStatusNotification(ctx, tenantID, executionID, status, message)
 	return err
 }
 
+// FinalizeExecutionActivity fails the execution if the workflow ends while it is
+// still pending, running or paused. Executions the workflow already settled are left alone.
+func (a *Activities) FinalizeExecutionActivity(ctx context.Context, tenantID, executionID, message string) error {
+	failed, err := a.JobRepo.FailActiveExecution(tenantID, executionID, message)
+	if err != nil {
+		return errors.Wrap(err, "failed to finalize execution")
+	}
+	if failed {
+		activity.GetLogger(ctx).Warn("Execution left unsettled by its workflow marked failed", "tenantID", tenantID, "executionID", executionID, "reason", message)
+		a.recordCompleted(ctx, tenantID, executionID, models.ExecutionStatusFailed, message)
+		a.emitStatusNotification(ctx, tenantID, executionID, models.ExecutionStatusFailed, message)
+	}
+	return nil
+}
+
 func (a *Activities) PrepareExecutionActivity(ctx context.Context, params temporal.ExecutionParams) (*temporal.PrepareActivityResult, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Preparing execution", "tenantID", params.TenantID, "executionID", params.ExecutionID)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
 
 	def, err := a.JobRepo.GetJobDefinitionByID(params.TenantID, params.JobDefinitionID)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch job definition")
+		return nil, lookupError(err, "failed to fetch job definition")
+	}
+
+	// Settled before the config is stored, so a disallowed image leaves nothing behind.
+	settings, err := a.resolveRunSettings(params.TenantID)
+	if err != nil {
+		return nil, err
 	}
+	settings.MaxDuration = runMaxDuration(settings.MaxDuration, params.TimeoutSeconds)
 
 	source_conn, err := a.ConnRepo.Get(params.TenantID, def.SourceConnectionID)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch source connection")
+		return nil, lookupError(err, "failed to fetch source connection")
 	}
 
 	dest_conn, err := a.ConnRepo.Get(params.TenantID, def.DestinationConnectionID)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch destination connection")
+		return nil, lookupError(err, "failed to fetch destination connection")
+	}
+	if msg := models.ReadOnlyDestination(*dest_conn); msg != "" {
+		return nil, invalidDefinition(errors.New(msg), "refusing to write to the destination")
 	}
 
+	if err := a.injectRunCredentials(params.ExecutionID, source_conn, dest_conn); err != nil {
+		return nil, err
+	}
+
+	runAST, err := a.runAST(params, def)
+	if err != nil {
+		return nil, err
+	}
 	var ast map[string]interface{}
-	if err := json.Unmarshal(def.AST, &ast); err != nil {
-		return nil, errors.Wrap(err, "failed to parse AST from job definition")
+	if err := json.Unmarshal(runAST, &ast); err != nil {
+		return nil, invalidDefinition(err, "failed to parse AST from job definition")
+	}
+
+	// Secret references are only ever resolved here, right before the engine gets the config.
+	secrets, err := a.JobRepo.GetDefinitionSecretValues(params.TenantID, params.JobDefinitionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load definition secrets")
+	}
+	resolved, err := models.ResolveASTSecrets(ast, secrets)
+	if err != nil {
+		return nil, invalidDefinition(err, "failed to resolve definition secrets")
 	}
+	ast = resolved.(map[string]interface{})
 
 	source_conn_str, err := source_conn.GenerateConnString()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate source connection string")
+		return nil, invalidDefinition(err, "failed to generate source connection string")
 	}
 
 	dest_conn_str, err := dest_conn.GenerateConnString()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate destination connection string")
+		return nil, invalidDefinition(err, "failed to generate destination connection string")
 	}
 
 	ast["connections"] = map[string]interface{}{
 		"source": map[string]interface{}{"conn_type": "Source", "format": dataFormatMap[def.SourceConnection.DataFormat], "conn_str": source_conn_str},
 		"dest":   map[string]interface{}{"conn_type": "Dest", "format": dataFormatMap[def.DestinationConnection.DataFormat], "conn_str": dest_conn_str},
 	}
+	// Per-table overrides stay in the AST's write_modes.
+	ast["write_mode"] = def.EffectiveWriteMode()
+
+	if params.ResumeFromExecutionID != "" {
+		checkpoints, err := a.JobRepo.ResumeExecutionFrom(params.TenantID, params.ExecutionID, params.ResumeFromExecutionID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load resume checkpoints")
+		}
+		ast["resume"] = map[string]interface{}{
+			"from_execution_id": params.ResumeFromExecutionID,
+			"checkpoints":       checkpoints,
+		}
+		logger.Info("Resuming from checkpoints", "fromExecutionID", params.ResumeFromExecutionID, "tables", len(checkpoints))
+	}
 
 	astBytes, err := json.Marshal(ast)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal AST to JSON")
 	}
 
-	tmpFileName := filepath.Join(a.TempDir, fmt.Sprintf("migration-%s-%s.json", params.JobDefinitionID, uuid.NewString()))
-	if err := os.WriteFile(tmpFileName, astBytes, 0644); err != nil {
-		return nil, errors.Wrapf(err, "failed to write AST to temporary file %s", tmpFileName)
+	// The config carries connection passwords, so it is stored encrypted.
+	sealed, err := utils.EncryptSecret(string(astBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt execution config")
 	}
-	logger.Info("Wrote AST to temporary file", "file", tmpFileName)
+	configRef, err := blobstore.PutBytes(ctx, a.Blobs, sealed)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store execution config")
+	}
+	logger.Info("Stored execution config", "blob", configRef.Hash, "size", configRef.Size)
 
 	authToken, err := generateJobToken(params.ExecutionID, params.TenantID, a.JWTSigningKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate job auth token")
 	}
 
+	// Kubernetes runs need no Docker host, but cannot join the Docker networks some
+	// connections are reached through.
+	var host *engine.DockerHost
+	networks := models.DockerNetworks(source_conn, dest_conn)
+	if a.Kubernetes == nil {
+		host, err = a.Hosts.Pick(ctx, params.TenantID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to pick a docker host")
+		}
+		logger.Info("Selected docker host", "host", host.Name)
+
+		if err := host.CheckNetworks(ctx, networks...); err != nil {
+			return nil, networkError(err)
+		}
+	} else if len(networks) > 0 {
+		return nil, sdktemporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("executions run as Kubernetes Jobs cannot join Docker networks (%s)", strings.Join(networks, ", ")),
+			temporal.ErrTypeRunnerUnsupported, nil)
+	}
+
+	if source_conn.ReadOnly {
+		if err := a.checkReadOnlySource(ctx, host, source_conn, source_conn_str); err != nil {
+			return nil, err
+		}
+	}
+
 	hostIP, err := getOutboundIP()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get host IP for callback URL")
 	}
-	hostCallbackURL := fmt.Sprintf("http://%s:8080/api/jobs/executions/%s/complete", hostIP, params.ExecutionID)
+	hostCallbackURL := fmt.Sprintf("http://%s:8080/api/v1/jobs/executions/%s/complete", hostIP, params.ExecutionID)
+	checkpointCallbackURL := fmt.Sprintf("http://%s:8080/api/v1/jobs/executions/%s/checkpoints", hostIP, params.ExecutionID)
+
+	// The config now carries any just-in-time passwords; nothing else needs them.
+	if a.Credentials != nil {
+		a.Credentials.Discard(params.ExecutionID)
+	}
+	a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStagePrepared, time.Now())
 
 	return &temporal.PrepareActivityResult{
-		ASTFilePath:     tmpFileName,
-		AuthToken:       authToken,
-		HostCallbackURL: hostCallbackURL,
-		TenantID:        params.TenantID,
-		ExecutionID:     params.ExecutionID,
+		ConfigBlob:            configRef.Hash,
+		AuthToken:             authToken,
+		HostCallbackURL:       hostCallbackURL,
+		CheckpointCallbackURL: checkpointCallbackURL,
+		TenantID:              params.TenantID,
+		ExecutionID:           params.ExecutionID,
+		DockerHost:            dockerHostName(host),
+		Networks:              networks,
+		EngineImage:           settings.Image,
+		CPULimit:              settings.CPULimit,
+		MemoryLimit:           settings.MemoryLimit,
+		MaxDuration:           settings.MaxDuration,
+		RetryPolicy:           def.RetryPolicy,
+		Verification:          def.Verification,
 	}, nil
 }
 
 func (a *Activities) RunExecutionContainerActivity(ctx context.Context, params temporal.PrepareActivityResult) (*temporal.RunContainerResult, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Starting Docker container for execution", "ExecutionID", params.ExecutionID)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
+	if a.Kubernetes != nil {
+		return a.runExecutionJob(ctx, params)
+	}
+	logger.Info("Starting Docker container for execution", "ExecutionID", params.ExecutionID, "host", params.DockerHost)
+
+	host, err := a.dockerHost(params.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+	release := host.Hold()
+	defer release()
+	docker := host.Client
+
+	config, err := a.loadExecutionConfig(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution config: %w", err)
+	}
+
+	image, cpuLimit, memLimit := params.EngineImage, params.CPULimit, params.MemoryLimit
+	if image == "" {
+		image, cpuLimit, memLimit = a.EngineImage, a.ContainerCPULimit, a.ContainerMemLimit
+	}
 
 	// Pull the engine image if not present
-	if _, err := a.DockerClient.ImageInspect(ctx, a.EngineImage); err != nil {
-		logger.Info("Image not found locally, pulling...", "image", a.EngineImage)
-		activity.RecordHeartbeat(ctx, "pulling-image")
-		reader, pullErr := a.DockerClient.ImagePull(ctx, a.EngineImage, image.PullOptions{})
-		if pullErr != nil {
-			return nil, fmt.Errorf("failed to pull image: %w", pullErr)
-		}
-		io.Copy(io.Discard, reader)
-		reader.Close()
+	if _, err := a.ensureEngineImage(ctx, docker, image); err != nil {
+		return nil, err
+	}
+
+	env, resumed, err := a.executionEnv(ctx, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create container
-	resp, err := a.DockerClient.ContainerCreate(ctx,
+	resp, err := docker.ContainerCreate(ctx,
 		&container.Config{
-			Image: a.EngineImage,
+			Image: image,
 			Cmd:   []string{"migrate", "--config", "/app/config.json", "--from-ast"},
-			Env: []string{
-				fmt.Sprintf("REPORT_CALLBACK_URL=%s", params.HostCallbackURL),
-				fmt.Sprintf("AUTH_TOKEN=%s", params.AuthToken),
+			Env:   env,
+			Labels: map[string]string{
+				"stratum.tenant_id":    params.TenantID,
+				"stratum.execution_id": params.ExecutionID,
 			},
 		},
 		&container.HostConfig{
-			Mounts: []mount.Mount{{Type: mount.TypeBind, Source: params.ASTFilePath, Target: "/app/config.json"}},
 			Resources: container.Resources{
-				CPUShares: a.ContainerCPULimit,
-				Memory:    a.ContainerMemLimit,
+				CPUShares: cpuLimit,
+				Memory:    memLimit,
 			},
 			AutoRemove: true,
-		}, nil, nil, "")
+		}, engine.EndpointsConfig(params.Networks), nil, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
 	containerID := resp.ID
 	logger.Info("Container created", "containerID", containerID)
+	a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventContainerCreated, time.Now(),
+		map[string]interface{}{"container_id": containerID})
+
+	// The config is copied in rather than bind-mounted so remote daemons, which cannot
+	// see this worker's filesystem, get it too.
+	if err := engine.NewDockerRunner(docker).CopyTo(ctx, containerID, "/app", config, "config.json"); err != nil {
+		docker.ContainerRemove(context.Background(), containerID, container.RemoveOptions{Force: true})
+		return nil, fmt.Errorf("failed to copy config into container: %w", err)
+	}
 
 	// Start container
-	if err := a.DockerClient.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+	if err := docker.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
+	a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageContainerStarted, time.Now())
+	a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventStarted, time.Now(),
+		map[string]interface{}{"container_id": containerID, "attempt": activity.GetInfo(ctx).Attempt, "resumed_tables": resumed})
+
+	// The tenant's maximum execution duration bounds the run from here on.
+	runCtx := ctx
+	if params.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, params.MaxDuration)
+		defer cancel()
+	}
+	overran := func() bool {
+		return runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	}
+
+	// Heartbeats are how a cancellation requested by the workflow reaches this
+	// activity; without them it would only notice once the container exits.
+	stopHeartbeat := heartbeatWhileRunning(ctx, "container-running")
+	defer stopHeartbeat()
 
 	// Stream logs
-	logReader, err := a.DockerClient.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	logReader, err := docker.ContainerLogs(runCtx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get container logs: %w", err)
 	}
 	defer logReader.Close()
 
 	var stdoutBuf, stderrBuf bytes.Buffer
-	if _, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, logReader); err != nil {
+	var firstLog sync.Once
+	onFirstLog := func() {
+		a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageFirstLog, time.Now())
+	}
+	stdout := &firstWriteWriter{w: &stdoutBuf, once: &firstLog, onFirst: onFirstLog}
+	stderr := &firstWriteWriter{w: &stderrBuf, once: &firstLog, onFirst: onFirstLog}
+	if _, err := stdcopy.StdCopy(stdout, stderr, logReader); err != nil {
+		if overran() {
+			return nil, stopOverrunContainer(ctx, docker, containerID, params.MaxDuration)
+		}
+		if ctx.Err() != nil {
+			return nil, stopCancelledContainer(ctx, docker, containerID)
+		}
 		return nil, fmt.Errorf("failed to demux container logs: %w", err)
 	}
-	mergedLogs := stdoutBuf.String() + stderrBuf.String()
+	mergedLogs := redactConnectionSecrets(config, stdoutBuf.String()+stderrBuf.String())
 
 	// Wait for container to finish
 	activity.RecordHeartbeat(ctx, "waiting-for-container")
-	waitResp, errCh := a.DockerClient.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	waitResp, errCh := docker.ContainerWait(runCtx, containerID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
+		if overran() {
+			return nil, stopOverrunContainer(ctx, docker, containerID, params.MaxDuration)
+		}
 		return nil, fmt.Errorf("container wait error: %w", err)
 	case status := <-waitResp:
 		logger.Info("Container finished.", "ContainerID", containerID, "ExitCode", status.StatusCode)
@@ -225,28 +502,149 @@ func (a *Activities) RunExecutionContainerActivity(ctx context.Context, params t
 			TenantID:    params.TenantID,
 			ExecutionID: params.ExecutionID,
 		}, nil
-	case <-ctx.Done():
-		// If the activity is cancelled, we should try to stop the container.
-		logger.Warn("Activity context cancelled, stopping container", "ContainerID", containerID)
-		// Use a background context for the stop command.
-		stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		a.DockerClient.ContainerStop(stopCtx, containerID, container.StopOptions{})
-		return nil, ctx.Err()
+	case <-runCtx.Done():
+		if overran() {
+			return nil, stopOverrunContainer(ctx, docker, containerID, params.MaxDuration)
+		}
+		return nil, stopCancelledContainer(ctx, docker, containerID)
 	}
 }
 
+// executionEnv is the engine's environment for a run. From the second attempt on,
+// it carries a fresh auth token and the checkpoints earlier attempts reported;
+// resumed is the number of tables they cover.
+func (a *Activities) executionEnv(ctx context.Context, params temporal.PrepareActivityResult) (env []string, resumed int, err error) {
+	logger := activity.GetLogger(ctx)
+	env = []string{
+		fmt.Sprintf("REPORT_CALLBACK_URL=%s", params.HostCallbackURL),
+		fmt.Sprintf("CHECKPOINT_CALLBACK_URL=%s", params.CheckpointCallbackURL),
+		fmt.Sprintf("CONTROL_FILE=%s/%s", engineControlDir, engineControlFile),
+	}
+	authToken := params.AuthToken
+	if activity.GetInfo(ctx).Attempt > 1 {
+		// The token minted at prepare time may have expired by now, and the engine
+		// picks up from the checkpoints the failed attempt reported instead of
+		// starting over.
+		if authToken, err = generateJobToken(params.ExecutionID, params.TenantID, a.JWTSigningKey); err != nil {
+			return nil, 0, fmt.Errorf("failed to generate job auth token: %w", err)
+		}
+		checkpoints, err := a.JobRepo.ListExecutionCheckpoints(params.TenantID, params.ExecutionID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to load execution checkpoints: %w", err)
+		}
+		if len(checkpoints) > 0 {
+			encoded, err := json.Marshal(checkpoints)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to encode execution checkpoints: %w", err)
+			}
+			env = append(env, fmt.Sprintf("RESUME_CHECKPOINTS=%s", encoded))
+			resumed = len(checkpoints)
+			logger.Info("Resuming from the previous attempt's checkpoints", "tables", resumed)
+		}
+	}
+	env = append(env, fmt.Sprintf("AUTH_TOKEN=%s", authToken))
+	return env, resumed, nil
+}
+
+// RecordExecutionSnapshotActivity captures the immutable environment snapshot for an
+// execution: the exact AST, engine image digest, connection fingerprints and limits.
+func (a *Activities) RecordExecutionSnapshotActivity(ctx context.Context, params temporal.ExecutionParams, dockerHost string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Recording execution snapshot", "tenantID", params.TenantID, "executionID", params.ExecutionID)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
+
+	def, err := a.JobRepo.GetJobDefinitionByID(params.TenantID, params.JobDefinitionID)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch job definition")
+	}
+	sourceConn, err := a.ConnRepo.Get(params.TenantID, def.SourceConnectionID)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch source connection")
+	}
+	destConn, err := a.ConnRepo.Get(params.TenantID, def.DestinationConnectionID)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch destination connection")
+	}
+
+	settings, err := a.resolveRunSettings(params.TenantID)
+	if err != nil {
+		return err
+	}
+	// Kubernetes pulls the image when the run's pod starts; runExecutionJob records
+	// the digest then.
+	var digest string
+	if a.Kubernetes == nil {
+		host, err := a.dockerHost(dockerHost)
+		if err != nil {
+			return err
+		}
+		if digest, err = a.ensureEngineImage(ctx, host.Client, settings.Image); err != nil {
+			return err
+		}
+		a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageImagePulled, time.Now())
+		a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventImagePulled, time.Now(),
+			map[string]interface{}{"image": settings.Image, "digest": digest, "docker_host": host.Name})
+	}
+
+	runAST, err := a.runAST(params, def)
+	if err != nil {
+		return err
+	}
+	astSum := sha256.Sum256(runAST)
+	snapshot := models.ExecutionSnapshot{
+		ExecutionID:           params.ExecutionID,
+		TenantID:              params.TenantID,
+		JobDefinitionID:       params.JobDefinitionID,
+		ASTHash:               hex.EncodeToString(astSum[:]),
+		AST:                   runAST,
+		EngineImage:           settings.Image,
+		SourceConnection:      sourceConn.Fingerprint(),
+		DestinationConnection: destConn.Fingerprint(),
+		Parameters: map[string]interface{}{
+			"tenant_id":         params.TenantID,
+			"execution_id":      params.ExecutionID,
+			"job_definition_id": params.JobDefinitionID,
+		},
+		ContainerCPULimit:    settings.CPULimit,
+		ContainerMemoryLimit: settings.MemoryLimit,
+		APIVersion:           version.APIVersion,
+	}
+	if params.ResumeFromExecutionID != "" {
+		snapshot.Parameters["resume_from_execution_id"] = params.ResumeFromExecutionID
+	}
+	if params.ReplayOfExecutionID != "" {
+		snapshot.Parameters["replay_of_execution_id"] = params.ReplayOfExecutionID
+	}
+	if len(params.Overrides) > 0 {
+		snapshot.Parameters["overrides"] = params.Overrides
+	}
+	if len(params.OnlyTables) > 0 {
+		snapshot.Parameters["only_tables"] = params.OnlyTables
+	}
+	if digest != "" {
+		snapshot.EngineImageDigest = &digest
+	}
+
+	if err := a.JobRepo.CreateExecutionSnapshot(snapshot); err != nil {
+		return errors.Wrap(err, "failed to store execution snapshot")
+	}
+	return nil
+}
+
 func (a *Activities) HandleCompletionActivity(ctx context.Context, result temporal.RunContainerResult) error {
 	logger := activity.GetLogger(ctx)
+	a.recordAttempt(ctx, result.TenantID, result.ExecutionID)
 
 	if result.ExitCode != 0 {
 		msg := fmt.Sprintf("Container exited with non-zero code %d", result.ExitCode)
 		logger.Error(msg, "ExecutionID", result.ExecutionID)
-		return a.UpdateJobStatusActivity(ctx, result.TenantID, result.ExecutionID, "failed", msg, result.Logs)
+		return a.UpdateJobStatusActivity(ctx, result.TenantID, result.ExecutionID, models.ExecutionStatusFailed, msg, result.Logs)
 	}
 
-	logger.Info("Container succeeded. Waiting for engine report...", "ExecutionID", result.ExecutionID)
-	time.Sleep(5 * time.Second) // Give the engine's API call a few seconds to arrive.
+	if !result.Reported {
+		logger.Info("Container succeeded. Waiting for engine report...", "ExecutionID", result.ExecutionID)
+		time.Sleep(5 * time.Second) // Give the engine's API call a few seconds to arrive.
+	}
 
 	exec, err := a.JobRepo.GetExecution(result.TenantID, result.ExecutionID)
 	if err != nil {
@@ -254,16 +652,548 @@ func (a *Activities) HandleCompletionActivity(ctx context.Context, result tempor
 		return errors.Wrap(err, "failed to re-fetch execution after run")
 	}
 
-	if exec.Status == "running" {
+	if exec.Status == models.ExecutionStatusRunning || exec.Status == models.ExecutionStatusPaused {
 		// The callback didn't update the status in time.
 		logger.Warn("Engine report did not arrive in time. Marking as succeeded without metrics.", "ExecutionID", result.ExecutionID)
-		return a.UpdateJobStatusActivity(ctx, result.TenantID, result.ExecutionID, "succeeded", "", result.Logs)
+		if err := a.UpdateJobStatusActivity(ctx, result.TenantID, result.ExecutionID, models.ExecutionStatusSucceeded, "", result.Logs); err != nil {
+			return err
+		}
+		a.checkSucceededExecution(ctx, result.TenantID, result.ExecutionID)
+		return nil
 	}
 
 	// The callback updated the status. We just need to save the logs.
 	logger.Info("Engine report received. Final status set by engine.", "ExecutionID", result.ExecutionID, "Status", exec.Status)
-	_, err = a.JobRepo.UpdateExecution(result.TenantID, result.ExecutionID, exec.Status, "", result.Logs)
-	return err
+	if _, err = a.JobRepo.UpdateExecution(result.TenantID, result.ExecutionID, exec.Status, "", result.Logs); err != nil {
+		return err
+	}
+	a.recordCompleted(ctx, result.TenantID, result.ExecutionID, exec.Status, "")
+	if exec.Status == models.ExecutionStatusSucceeded {
+		a.checkSucceededExecution(ctx, result.TenantID, result.ExecutionID)
+	}
+	return nil
+}
+
+// checkSucceededExecution runs the post-completion sanity checks of a succeeded
+// execution.
+func (a *Activities) checkSucceededExecution(ctx context.Context, tenantID, executionID string) {
+	a.flagZeroProgress(ctx, tenantID, executionID)
+	a.detectRegressions(ctx, tenantID, executionID)
+}
+
+// flagZeroProgress marks a succeeded execution suspect when it reported moving no
+// records or bytes although earlier runs of its definition moved data, which usually
+// means a silently misconfigured engine. Failures are logged and never fail the run.
+func (a *Activities) flagZeroProgress(ctx context.Context, tenantID, executionID string) {
+	logger := activity.GetLogger(ctx)
+
+	exec, def, err := a.loadExecutionDetails(tenantID, executionID)
+	if err != nil {
+		logger.Warn("Unable to load execution for zero-progress check", "error", err)
+		return
+	}
+	if !exec.MovedNoData() {
+		return
+	}
+	previous, err := a.JobRepo.CountDataMovingRuns(tenantID, exec.JobDefinitionID, executionID)
+	if err != nil {
+		logger.Warn("Unable to count earlier data-moving runs", "error", err)
+		return
+	}
+	if previous == 0 {
+		return
+	}
+
+	reason := fmt.Sprintf("it transferred no records or bytes while %d earlier runs moved data", previous)
+	if err := a.JobRepo.MarkExecutionSuspect(tenantID, executionID, reason); err != nil {
+		logger.Warn("Failed to mark execution suspect", "error", err)
+		return
+	}
+	logger.Warn("Execution moved no data", "ExecutionID", executionID, "EarlierRuns", previous)
+	if a.Notifier != nil {
+		if notifyErr := a.Notifier.NotifyExecutionSuspect(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, reason); notifyErr != nil {
+			logger.Warn("Failed to publish suspect execution notification", "error", notifyErr)
+		}
+	}
+}
+
+// detectRegressions compares a succeeded execution against its definition's
+// baseline, records any regression and raises a warning notification. Failures are
+// logged only; they never fail the execution.
+func (a *Activities) detectRegressions(ctx context.Context, tenantID, executionID string) {
+	if a.Regression.Threshold <= 0 {
+		return
+	}
+	logger := activity.GetLogger(ctx)
+
+	exec, def, err := a.loadExecutionDetails(tenantID, executionID)
+	if err != nil {
+		logger.Warn("Unable to load execution for regression check", "error", err)
+		return
+	}
+	baseline, err := a.JobRepo.GetExecutionBaseline(tenantID, exec.JobDefinitionID, executionID, a.Regression.BaselineRuns)
+	if err != nil {
+		logger.Warn("Unable to compute execution baseline", "error", err)
+		return
+	}
+	if baseline.Runs < a.Regression.MinRuns {
+		return
+	}
+	regressions := models.DetectRegressions(baseline, exec, a.Regression.Threshold)
+	if len(regressions) == 0 {
+		return
+	}
+
+	if snapshot, err := a.JobRepo.GetExecutionSnapshot(tenantID, executionID); err == nil {
+		for i := range regressions {
+			regressions[i].EngineImageDigest = snapshot.EngineImageDigest
+		}
+	}
+	if err := a.JobRepo.SaveExecutionRegressions(regressions); err != nil {
+		logger.Warn("Failed to store execution regressions", "error", err)
+		return
+	}
+	logger.Warn("Execution regressed against baseline", "ExecutionID", executionID, "Regressions", len(regressions))
+	if a.Notifier != nil {
+		if notifyErr := a.Notifier.NotifyExecutionRegressed(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, regressions); notifyErr != nil {
+			logger.Warn("Failed to publish execution regression notification", "error", notifyErr)
+		}
+	}
+}
+
+// CapturePartialStateActivity asks the engine for destination row counts after a
+// failed run and stores them as the execution's partial state report. Capture
+// failures are recorded in the report rather than failing the workflow.
+func (a *Activities) CapturePartialStateActivity(ctx context.Context, params temporal.PrepareActivityResult) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Capturing destination partial state", "ExecutionID", params.ExecutionID)
+
+	report := models.PartialStateReport{}
+	host, hostErr := a.dockerHost(params.DockerHost)
+	config, err := a.loadExecutionConfig(ctx, params)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to read execution config: %v", err)
+	} else if hostErr != nil {
+		report.Error = hostErr.Error()
+	} else if raw, err := host.Engine(a.EngineImage).DestinationState(ctx, config); err != nil {
+		report.Error = redactConnectionSecrets(config, ansiEscape.ReplaceAllString(err.Error(), ""))
+	} else if err := json.Unmarshal(raw, &report); err != nil {
+		report.Error = fmt.Sprintf("failed to parse engine report: %v", err)
+	}
+	if report.CapturedAt.IsZero() {
+		report.CapturedAt = time.Now().UTC()
+	}
+	if report.Error != "" {
+		logger.Warn("Partial state capture incomplete", "ExecutionID", params.ExecutionID, "error", report.Error)
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal partial state report")
+	}
+	artifact := models.ExecutionArtifact{
+		TenantID:    params.TenantID,
+		ExecutionID: params.ExecutionID,
+		Kind:        models.ArtifactKindPartialState,
+		Content:     content,
+	}
+	if err := a.placeArtifact(&artifact); err != nil {
+		return err
+	}
+	if err := a.scanArtifact(ctx, &artifact); err != nil {
+		return err
+	}
+	_, err = a.JobRepo.SaveExecutionArtifact(artifact)
+	if err != nil {
+		return errors.Wrap(err, "failed to store partial state report")
+	}
+	return nil
+}
+
+// VerifyExecutionActivity has the engine compare the source and destination of a
+// succeeded run and stores the report as the execution's verification artifact. A
+// comparison the engine could not make is recorded in the report rather than
+// returned, so the workflow applies the definition's mode to it like a mismatch.
+// Runs that did not succeed are not verified and return nil.
+func (a *Activities) VerifyExecutionActivity(ctx context.Context, params temporal.PrepareActivityResult) (*models.VerificationReport, error) {
+	logger := activity.GetLogger(ctx)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
+
+	exec, err := a.JobRepo.GetExecution(params.TenantID, params.ExecutionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch execution for verification")
+	}
+	if exec.Status != models.ExecutionStatusSucceeded {
+		logger.Info("Skipping verification of unsuccessful execution", "ExecutionID", params.ExecutionID, "Status", exec.Status)
+		return nil, nil
+	}
+	logger.Info("Verifying migrated data", "ExecutionID", params.ExecutionID, "Mode", params.Verification)
+
+	host, err := a.dockerHost(params.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+	if err := host.JoinNetworks(ctx, a.EngineImage, params.Networks...); err != nil {
+		return nil, networkError(err)
+	}
+	config, err := a.loadExecutionConfig(ctx, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read execution config")
+	}
+
+	stopHeartbeat := heartbeatWhileRunning(ctx, "verifying")
+	raw, verifyErr := host.Engine(a.EngineImage).Verify(ctx, config)
+	stopHeartbeat()
+
+	report := models.VerificationReport{}
+	if verifyErr != nil {
+		report.Error = redactConnectionSecrets(config, ansiEscape.ReplaceAllString(verifyErr.Error(), ""))
+	} else if err := json.Unmarshal(raw, &report); err != nil {
+		report.Error = fmt.Sprintf("failed to parse engine report: %v", err)
+	}
+	report.Mode = params.Verification
+	if report.CapturedAt.IsZero() {
+		report.CapturedAt = time.Now().UTC()
+	}
+	report.Evaluate()
+	if problem := report.Problem(); problem != "" {
+		logger.Warn("Execution did not verify", "ExecutionID", params.ExecutionID, "problem", problem)
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal verification report")
+	}
+	artifact := models.ExecutionArtifact{
+		TenantID:    params.TenantID,
+		ExecutionID: params.ExecutionID,
+		Kind:        models.ArtifactKindVerification,
+		Content:     content,
+	}
+	if err := a.placeArtifact(&artifact); err != nil {
+		return nil, err
+	}
+	if err := a.scanArtifact(ctx, &artifact); err != nil {
+		return nil, err
+	}
+	if _, err := a.JobRepo.SaveExecutionArtifact(artifact); err != nil {
+		return nil, errors.Wrap(err, "failed to store verification report")
+	}
+	return &report, nil
+}
+
+// FlagUnverifiedExecutionActivity marks a succeeded execution whose verification
+// found a problem suspect, for definitions that only warn about it.
+func (a *Activities) FlagUnverifiedExecutionActivity(ctx context.Context, tenantID, executionID, problem string) error {
+	if err := a.JobRepo.MarkExecutionSuspect(tenantID, executionID, problem); err != nil {
+		return errors.Wrap(err, "failed to mark execution suspect")
+	}
+	if a.Notifier == nil {
+		return nil
+	}
+	exec, def, err := a.loadExecutionDetails(tenantID, executionID)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Unable to load execution for suspect notification", "error", err)
+		return nil
+	}
+	if err := a.Notifier.NotifyExecutionSuspect(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, problem); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to publish suspect execution notification", "error", err)
+	}
+	return nil
+}
+
+// recordAttempt notes on the execution that the current activity is being retried,
+// so the attempt count shows up on the execution and its notifications.
+func (a *Activities) recordAttempt(ctx context.Context, tenantID, executionID string) {
+	info := activity.GetInfo(ctx)
+	if info.Attempt <= 1 {
+		return
+	}
+	activity.GetLogger(ctx).Warn("Retrying activity", "Activity", info.ActivityType.Name, "Attempt", info.Attempt)
+	if err := a.JobRepo.RecordActivityAttempt(tenantID, executionID, info.ActivityType.Name, info.Attempt); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to record activity attempt", "error", err)
+	}
+}
+
+// staleValidationBlocked reports whether the definition is flagged with source
+// schema drift and its tenant refuses to run such definitions.
+func (a *Activities) staleValidationBlocked(tenantID, jobDefID string) (bool, error) {
+	tenant, err := a.TenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		return false, err
+	}
+	if tenant.ValidationFreshness != models.ValidationFreshnessBlock {
+		return false, nil
+	}
+	def, err := a.JobRepo.GetJobDefinitionByID(tenantID, jobDefID)
+	if err != nil {
+		return false, err
+	}
+	return def.StaleValidation, nil
+}
+
+// recordStartupStage notes on the execution when it completed a startup stage. A
+// failure to record is logged and does not affect the run.
+func (a *Activities) recordStartupStage(ctx context.Context, tenantID, executionID, stage string, at time.Time) {
+	if err := a.JobRepo.RecordStartupStage(tenantID, executionID, stage, at); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to record startup stage", "stage", stage, "error", err)
+	}
+}
+
+// recordEvent appends an event to the execution's timeline. A failure to record is
+// logged and does not affect the run.
+func (a *Activities) recordEvent(ctx context.Context, tenantID, executionID, eventType string, at time.Time, details map[string]interface{}) {
+	event := models.ExecutionEvent{Type: eventType, Details: details, OccurredAt: at}
+	if err := a.JobRepo.RecordExecutionEvent(tenantID, executionID, event); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to record execution event", "event", eventType, "error", err)
+	}
+}
+
+// recordCompleted records the completed event with the status the run ended in.
+func (a *Activities) recordCompleted(ctx context.Context, tenantID, executionID string, status models.ExecutionStatus, message string) {
+	details := map[string]interface{}{"status": status}
+	if message != "" {
+		details["message"] = message
+	}
+	a.recordEvent(ctx, tenantID, executionID, models.ExecutionEventCompleted, time.Now(), details)
+}
+
+// firstWriteWriter calls onFirst once, before the first output written to any of
+// the writers sharing its once.
+type firstWriteWriter struct {
+	w       io.Writer
+	once    *sync.Once
+	onFirst func()
+}
+
+func (f *firstWriteWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		f.once.Do(f.onFirst)
+	}
+	return f.w.Write(p)
+}
+
+// runAST returns the AST the execution runs: the definition's, or for a replay the
+// one the replayed execution snapshotted, with the run's overrides merged in and
+// its table list cut down to OnlyTables, if set. A replayed execution without a
+// snapshot cannot be fixed by retrying.
+func (a *Activities) runAST(params temporal.ExecutionParams, def models.JobDefinition) (json.RawMessage, error) {
+	ast := def.AST
+	if params.ReplayOfExecutionID != "" {
+		snapshot, err := a.JobRepo.GetExecutionSnapshot(params.TenantID, params.ReplayOfExecutionID)
+		if err != nil {
+			return nil, lookupError(err, "failed to load the replayed execution's snapshot")
+		}
+		ast = snapshot.AST
+	}
+	merged, err := models.ApplyRunOverrides(ast, params.Overrides)
+	if err != nil {
+		return nil, invalidDefinition(err, "failed to apply run overrides")
+	}
+	if len(params.OnlyTables) == 0 {
+		return merged, nil
+	}
+	filtered, kept, err := models.FilterASTTables(merged, params.OnlyTables)
+	if err != nil {
+		return nil, invalidDefinition(err, "failed to select the tables to run")
+	}
+	if len(kept) == 0 {
+		return nil, invalidDefinition(errors.New("none of "+strings.Join(params.OnlyTables, ", ")+" are in the AST"), "failed to select the tables to run")
+	}
+	return filtered, nil
+}
+
+// invalidDefinition marks err as a definition problem that retrying cannot fix.
+func invalidDefinition(err error, msg string) error {
+	return sdktemporal.NewApplicationErrorWithCause(msg+": "+err.Error(), temporal.ErrTypeInvalidDefinition, err)
+}
+
+// checkReadOnlySource is the pre-flight check of a read-only source: the engine must
+// find that its user holds no write privileges. Failing to run the check is
+// retried; a user that can write fails the run. On a Docker host the engine
+// container joins the source's Docker network first so its host resolves; the
+// Kubernetes runner runs the check as a Job of its own.
+func (a *Activities) checkReadOnlySource(ctx context.Context, host *engine.DockerHost, conn *models.Connection, dsn string) error {
+	var privileges []string
+	var err error
+	if a.Kubernetes != nil {
+		privileges, err = a.kubernetesWritePrivileges(ctx, conn, dsn)
+	} else {
+		if err := host.JoinNetworks(ctx, a.EngineImage, models.DockerNetworks(conn)...); err != nil {
+			return networkError(err)
+		}
+		privileges, err = host.Engine(a.EngineImage).WritePrivileges(ctx, conn.DataFormat, dsn)
+	}
+	if err != nil {
+		return errors.Wrap(errors.New(models.ScrubDSN(err.Error(), dsn)), "failed to check source privileges")
+	}
+	if len(privileges) > 0 {
+		msg := fmt.Sprintf("source connection %q is read-only but its user can write: %s", conn.Name, strings.Join(privileges, ", "))
+		return sdktemporal.NewApplicationError(msg, temporal.ErrTypeSourceWritable)
+	}
+	return nil
+}
+
+// dockerHostName names the host a run was prepared on; Kubernetes runs have none.
+func dockerHostName(host *engine.DockerHost) string {
+	if host == nil {
+		return ""
+	}
+	return host.Name
+}
+
+// networkError fails the run when one of its connections' Docker networks does
+// not exist on the chosen host; other failures to check the networks are retried.
+func networkError(err error) error {
+	if errors.Is(err, engine.ErrNetworkNotFound) {
+		return sdktemporal.NewApplicationErrorWithCause(err.Error(), temporal.ErrTypeDockerNetworkNotFound, err)
+	}
+	return errors.Wrap(err, "failed to check docker networks")
+}
+
+// lookupError reports a definition or connection that no longer exists as an
+// invalid definition; other lookup failures stay retryable.
+func lookupError(err error, msg string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return invalidDefinition(err, msg)
+	}
+	return errors.Wrap(err, msg)
+}
+
+// injectRunCredentials fills in the passwords of prompt-mode connections from the
+// credentials supplied when the run was started. They only exist in the vault of the
+// process that accepted the run, so a missing entry cannot be fixed by retrying.
+func (a *Activities) injectRunCredentials(executionID string, conns ...*models.Connection) error {
+	var creds temporal.RunCredentials
+	for _, conn := range conns {
+		if !conn.PromptsForCredentials() {
+			continue
+		}
+		if creds == nil {
+			var ok bool
+			if a.Credentials != nil {
+				creds, ok = a.Credentials.Get(executionID)
+			}
+			if !ok {
+				return sdktemporal.NewNonRetryableApplicationError(
+					"credentials for connection "+conn.Name+" were not supplied or have expired; start the run again",
+					temporal.ErrTypeCredentialsUnavailable, nil)
+			}
+		}
+		password, ok := creds[conn.ID]
+		if !ok {
+			return sdktemporal.NewNonRetryableApplicationError(
+				"credentials for connection "+conn.Name+" were not supplied", temporal.ErrTypeCredentialsUnavailable, nil)
+		}
+		conn.Password = password
+	}
+	return nil
+}
+
+// redactConnectionSecrets masks the connection passwords of an engine config in
+// text, so container output echoing a connection string never reaches the logs or
+// the database.
+func redactConnectionSecrets(config []byte, text string) string {
+	var cfg struct {
+		Connections map[string]struct {
+			ConnStr string `json:"conn_str"`
+		} `json:"connections"`
+	}
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return text
+	}
+	for _, conn := range cfg.Connections {
+		u, err := url.Parse(conn.ConnStr)
+		if err != nil || u.User == nil {
+			continue
+		}
+		password, ok := u.User.Password()
+		if !ok || password == "" {
+			continue
+		}
+		for _, form := range []string{password, url.QueryEscape(password), url.PathEscape(password)} {
+			text = strings.ReplaceAll(text, form, "****")
+		}
+	}
+	return text
+}
+
+// dockerHost resolves the host an execution was assigned to. A host removed from the
+// configuration mid-execution cannot be recovered by retrying.
+func (a *Activities) dockerHost(name string) (*engine.DockerHost, error) {
+	host, err := a.Hosts.Host(name)
+	if err != nil {
+		return nil, sdktemporal.NewNonRetryableApplicationError(err.Error(), temporal.ErrTypeUnknownDockerHost, err)
+	}
+	return host, nil
+}
+
+// placeArtifact records the tenant's storage location on artifact. Artifacts of
+// tenants pinned to another region are refused rather than stored here.
+func (a *Activities) placeArtifact(artifact *models.ExecutionArtifact) error {
+	if a.Residency == nil {
+		return nil
+	}
+	loc, err := a.Residency.TenantLocation(artifact.TenantID)
+	if err != nil {
+		var crossErr *storage.CrossRegionError
+		if errors.As(err, &crossErr) {
+			return sdktemporal.NewNonRetryableApplicationError(err.Error(), temporal.ErrTypeCrossRegion, err)
+		}
+		return errors.Wrap(err, "failed to resolve artifact storage location")
+	}
+	artifact.StorageRegion = loc.Region
+	artifact.StorageLocation = loc.URI(fmt.Sprintf("tenants/%s/executions/%s/%s.json", artifact.TenantID, artifact.ExecutionID, artifact.Kind))
+	return nil
+}
+
+// scanArtifact malware scans artifact before it is stored, quarantining it when it
+// fails. Scanner outages are logged; whether they quarantine the artifact depends
+// on the tenant's require_artifact_scan setting.
+func (a *Activities) scanArtifact(ctx context.Context, artifact *models.ExecutionArtifact) error {
+	tenant, err := a.TenantRepo.GetTenantByID(artifact.TenantID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load tenant scanning settings")
+	}
+	logger := activity.GetLogger(ctx)
+	if err := scanning.Apply(ctx, a.Scanner, tenant.RequireArtifactScan, artifact); err != nil {
+		logger.Warn("Artifact scan failed", "ExecutionID", artifact.ExecutionID, "Kind", artifact.Kind, "error", err)
+	}
+	if artifact.Quarantined() {
+		logger.Warn("Artifact quarantined", "ExecutionID", artifact.ExecutionID, "Kind", artifact.Kind, "detail", artifact.ScanDetail)
+	}
+	return nil
+}
+
+// DeleteExecutionConfigActivity removes an execution's config from the blob store
+// once the execution no longer needs it. Each config is encrypted with its own
+// nonce, so its blob is never shared.
+func (a *Activities) DeleteExecutionConfigActivity(ctx context.Context, hash string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Deleting execution config", "blob", hash)
+	if err := a.Blobs.Delete(ctx, hash); err != nil {
+		logger.Error("Failed to delete execution config", "blob", hash, "error", err)
+		return err
+	}
+	return nil
+}
+
+// loadExecutionConfig streams the prepared engine config out of the blob store and
+// decrypts it. Executions prepared before the blob store read it from the worker's
+// disk.
+func (a *Activities) loadExecutionConfig(ctx context.Context, params temporal.PrepareActivityResult) ([]byte, error) {
+	if params.ConfigBlob == "" {
+		return os.ReadFile(params.ASTFilePath)
+	}
+	sealed, err := blobstore.ReadAll(ctx, a.Blobs, params.ConfigBlob)
+	if err != nil {
+		return nil, err
+	}
+	config, err := utils.DecryptSecret(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(config), nil
 }
 
 func (a *Activities) CleanupActivity(ctx context.Context, filePath string) error {
@@ -277,15 +1207,15 @@ func (a *Activities) CleanupActivity(ctx context.Context, filePath string) error
 	return nil
 }
 
-func (a *Activities) emitStatusNotification(ctx context.Context, tenantID, executionID, status, message string) {
+func (a *Activities) emitStatusNotification(ctx context.Context, tenantID, executionID string, status models.ExecutionStatus, message string) {
 	if a.Notifier == nil {
 		return
 	}
 
 	logger := activity.GetLogger(ctx)
 
-	switch strings.ToLower(strings.TrimSpace(status)) {
-	case "failed":
+	switch models.ExecutionStatus(strings.ToLower(strings.TrimSpace(string(status)))) {
+	case models.ExecutionStatusFailed:
 		exec, def, err := a.loadExecutionDetails(tenantID, executionID)
 		if err != nil {
 			logger.Warn("Unable to load execution for failure notification", "error", err)
@@ -295,10 +1225,10 @@ func (a *Activities) emitStatusNotification(ctx context.Context, tenantID, execu
 		if reason == "" && exec.ErrorMessage != nil {
 			reason = strings.TrimSpace(*exec.ErrorMessage)
 		}
-		if notifyErr := a.Notifier.NotifyExecutionFailed(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, reason); notifyErr != nil {
+		if notifyErr := a.Notifier.NotifyExecutionFailed(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, reason, exec.ActivityAttempts); notifyErr != nil {
 			logger.Warn("Failed to publish execution failed notification", "error", notifyErr)
 		}
-	case "succeeded":
+	case models.ExecutionStatusSucceeded:
 		exec, def, err := a.loadExecutionDetails(tenantID, executionID)
 		if err != nil {
 			logger.Warn("Unable to load execution for success notification", "error", err)
@@ -311,7 +1241,7 @@ func (a *Activities) emitStatusNotification(ctx context.Context, tenantID, execu
 		if exec.BytesTransferred != nil {
 			bytesTransferred = *exec.BytesTransferred
 		}
-		if notifyErr := a.Notifier.NotifyExecutionSucceeded(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, recordsProcessed, bytesTransferred); notifyErr != nil {
+		if notifyErr := a.Notifier.NotifyExecutionSucceeded(ctx, tenantID, exec.JobDefinitionID, executionID, def.Name, recordsProcessed, bytesTransferred, exec.ActivityAttempts); notifyErr != nil {
 			logger.Warn("Failed to publish execution success notification", "error", notifyErr)
 		}
 	}
@@ -329,6 +1259,136 @@ func (a *Activities) loadExecutionDetails(tenantID, executionID string) (models.
 	return exec, def, nil
 }
 
+// ensureEngineImage pulls the engine image when it is missing on the host and returns its
+// content digest (falling back to the local image ID when no repo digest exists).
+func (a *Activities) ensureEngineImage(ctx context.Context, docker *client.Client, engineImage string) (string, error) {
+	logger := activity.GetLogger(ctx)
+
+	inspect, err := docker.ImageInspect(ctx, engineImage)
+	if err != nil {
+		logger.Info("Image not found locally, pulling...", "image", engineImage)
+		activity.RecordHeartbeat(ctx, "pulling-image")
+		opts, err := a.Registries.PullOptions(ctx, engineImage)
+		if err != nil {
+			return "", fmt.Errorf("failed to pull image: %w", err)
+		}
+		reader, pullErr := docker.ImagePull(ctx, engineImage, opts)
+		if pullErr != nil {
+			return "", fmt.Errorf("failed to pull image: %w", pullErr)
+		}
+		io.Copy(io.Discard, reader)
+		reader.Close()
+
+		inspect, err = docker.ImageInspect(ctx, engineImage)
+		if err != nil {
+			return "", fmt.Errorf("failed to inspect image after pull: %w", err)
+		}
+	}
+
+	if len(inspect.RepoDigests) > 0 {
+		return inspect.RepoDigests[0], nil
+	}
+	return inspect.ID, nil
+}
+
+// runSettings are the engine image and container limits of one tenant's runs.
+type runSettings struct {
+	Image       string
+	CPULimit    int64
+	MemoryLimit int64
+	MaxDuration time.Duration
+}
+
+// resolveRunSettings applies the tenant's worker configuration over the worker's
+// defaults. An image the tenant may not use fails the run.
+func (a *Activities) resolveRunSettings(tenantID string) (runSettings, error) {
+	settings := runSettings{Image: a.EngineImage, CPULimit: a.ContainerCPULimit, MemoryLimit: a.ContainerMemLimit}
+	if a.TenantRepo == nil {
+		return settings, nil
+	}
+	cfg, err := a.TenantRepo.GetWorkerConfig(tenantID)
+	if err != nil {
+		return settings, errors.Wrap(err, "failed to load tenant worker config")
+	}
+	if cfg.EngineImage != nil {
+		settings.Image = *cfg.EngineImage
+	}
+	if !cfg.AllowsImage(settings.Image) {
+		return settings, sdktemporal.NewApplicationError(
+			fmt.Sprintf("engine image %s is not allowed for this tenant", settings.Image), temporal.ErrTypeEngineImageNotAllowed)
+	}
+	if cfg.CPULimit != nil {
+		settings.CPULimit = *cfg.CPULimit
+	}
+	if cfg.MemoryLimit != nil {
+		settings.MemoryLimit = *cfg.MemoryLimit
+	}
+	settings.MaxDuration = cfg.MaxExecutionDuration()
+	return settings, nil
+}
+
+// runMaxDuration applies a run's timeout to the tenant's maximum execution
+// duration: the shorter one wins, and either applies alone. Zero means no limit.
+func runMaxDuration(tenantMax time.Duration, timeoutSeconds int) time.Duration {
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	if timeout <= 0 || (tenantMax > 0 && tenantMax < timeout) {
+		return tenantMax
+	}
+	return timeout
+}
+
+// stopOverrunContainer stops a container that outlived its maximum duration and
+// returns the error failing the run.
+func stopOverrunContainer(ctx context.Context, docker *client.Client, containerID string, limit time.Duration) error {
+	activity.GetLogger(ctx).Warn("Execution exceeded its maximum duration, stopping container", "ContainerID", containerID, "limit", limit)
+	stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	docker.ContainerStop(stopCtx, containerID, container.StopOptions{})
+	return overrunError(limit)
+}
+
+// overrunError fails a run that outlived its maximum duration.
+func overrunError(limit time.Duration) error {
+	return sdktemporal.NewApplicationError(
+		fmt.Sprintf("execution exceeded its maximum duration of %s", limit), temporal.ErrTypeExecutionTimeLimit)
+}
+
+// heartbeatInterval keeps long container runs well inside the workflow's 30s
+// heartbeat timeout.
+const heartbeatInterval = 10 * time.Second
+
+// stopCancelledContainer stops the container of a run whose activity was cancelled
+// and returns the cancellation.
+func stopCancelledContainer(ctx context.Context, docker *client.Client, containerID string) error {
+	activity.GetLogger(ctx).Warn("Activity context cancelled, stopping container", "ContainerID", containerID)
+	// The activity context is done; the stop gets its own.
+	stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	docker.ContainerStop(stopCtx, containerID, container.StopOptions{})
+	return ctx.Err()
+}
+
+// heartbeatWhileRunning records a heartbeat with details every heartbeatInterval
+// until the returned stop function is called or ctx is done.
+func heartbeatWhileRunning(ctx context.Context, details string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx, details)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
 func generateJobToken(execID string, tenantID string, signingKey []byte) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": execID,
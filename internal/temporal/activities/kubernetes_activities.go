@@ -0,0 +1,214 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/sdk/activity"
+)
+
+const (
+	// kubeCleanupTimeout bounds deleting a run's Job once the activity is done with it.
+	kubeCleanupTimeout = 30 * time.Second
+	// kubeCheckTimeout bounds a pre-flight check Job, including its image pull.
+	kubeCheckTimeout = 5 * time.Minute
+)
+
+// runExecutionJob is RunExecutionContainerActivity for the Kubernetes runner: the
+// engine runs as a Job, whose pod gets the config through a secret owned by the
+// Job. The Job is deleted when the run ends, which also stops a pod that is still
+// running after a cancellation or an overrun.
+func (a *Activities) runExecutionJob(ctx context.Context, params temporal.PrepareActivityResult) (*temporal.RunContainerResult, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Starting Kubernetes job for execution", "ExecutionID", params.ExecutionID, "namespace", a.Kubernetes.Namespace)
+
+	config, err := a.loadExecutionConfig(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution config: %w", err)
+	}
+
+	image, cpuLimit, memLimit := params.EngineImage, params.CPULimit, params.MemoryLimit
+	if image == "" {
+		image, cpuLimit, memLimit = a.EngineImage, a.ContainerCPULimit, a.ContainerMemLimit
+	}
+
+	env, resumed, err := a.executionEnv(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every attempt gets its own Job, so a retry never finds the last one's.
+	attempt := activity.GetInfo(ctx).Attempt
+	name := fmt.Sprintf("stratum-run-%s-%d", params.ExecutionID, attempt)
+	err = a.Kubernetes.Launch(ctx, engine.KubernetesJob{
+		Name:      name,
+		Image:     image,
+		Args:      []string{"migrate", "--config", "/app/config.json", "--from-ast"},
+		Env:       env,
+		Files:     map[string][]byte{"config.json": config},
+		MountPath: "/app",
+		Labels: map[string]string{
+			"stratum.tenant_id":    params.TenantID,
+			"stratum.execution_id": params.ExecutionID,
+		},
+		CPUMillis:   cpuLimit,
+		MemoryBytes: memLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes job: %w", err)
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), kubeCleanupTimeout)
+		defer cancel()
+		if err := a.Kubernetes.Delete(cleanupCtx, name); err != nil {
+			logger.Warn("Failed to delete kubernetes job", "job", name, "error", err)
+		}
+	}()
+	logger.Info("Kubernetes job created", "job", name, "namespace", a.Kubernetes.Namespace)
+	a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventContainerCreated, time.Now(),
+		map[string]interface{}{"kubernetes_job": name, "namespace": a.Kubernetes.Namespace})
+
+	// The tenant's maximum execution duration bounds the run from here on.
+	runCtx := ctx
+	if params.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, params.MaxDuration)
+		defer cancel()
+	}
+	stopped := func(err error) error {
+		if runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			logger.Warn("Execution exceeded its maximum duration, deleting job", "job", name, "limit", params.MaxDuration)
+			return overrunError(params.MaxDuration)
+		}
+		if ctx.Err() != nil {
+			logger.Warn("Activity context cancelled, deleting job", "job", name)
+			return ctx.Err()
+		}
+		return err
+	}
+
+	stopHeartbeat := heartbeatWhileRunning(ctx, "container-running")
+	defer stopHeartbeat()
+
+	pod, err := a.Kubernetes.WaitForPod(runCtx, name)
+	if err != nil {
+		return nil, stopped(fmt.Errorf("kubernetes job %s did not start: %w", name, err))
+	}
+	// The snapshot was written before the pod pulled its image.
+	if pod.ImageID != "" {
+		if err := a.JobRepo.SetExecutionSnapshotImageDigest(params.TenantID, params.ExecutionID, pod.ImageID); err != nil {
+			logger.Warn("Failed to record engine image digest", "ExecutionID", params.ExecutionID, "error", err)
+		}
+	}
+	now := time.Now()
+	a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageImagePulled, now)
+	a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventImagePulled, now,
+		map[string]interface{}{"image": image, "digest": pod.ImageID, "kubernetes_job": name})
+	a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageContainerStarted, now)
+	a.recordEvent(ctx, params.TenantID, params.ExecutionID, models.ExecutionEventStarted, now,
+		map[string]interface{}{"kubernetes_job": name, "pod": pod.Name, "attempt": attempt, "resumed_tables": resumed})
+
+	logReader, err := a.Kubernetes.Logs(runCtx, pod.Name)
+	if err != nil {
+		return nil, stopped(fmt.Errorf("failed to get pod logs: %w", err))
+	}
+	defer logReader.Close()
+
+	// Kubernetes interleaves stdout and stderr into one stream.
+	var logBuf bytes.Buffer
+	var firstLog sync.Once
+	logs := &firstWriteWriter{w: &logBuf, once: &firstLog, onFirst: func() {
+		a.recordStartupStage(ctx, params.TenantID, params.ExecutionID, models.StartupStageFirstLog, time.Now())
+	}}
+	if _, err := io.Copy(logs, logReader); err != nil {
+		return nil, stopped(fmt.Errorf("failed to read pod logs: %w", err))
+	}
+
+	activity.RecordHeartbeat(ctx, "waiting-for-container")
+	exitCode, err := a.Kubernetes.Wait(runCtx, pod.Name)
+	if err != nil {
+		return nil, stopped(fmt.Errorf("kubernetes job wait error: %w", err))
+	}
+	logger.Info("Kubernetes job finished.", "job", name, "pod", pod.Name, "ExitCode", exitCode)
+	return &temporal.RunContainerResult{
+		ExitCode:    exitCode,
+		Logs:        redactConnectionSecrets(config, logBuf.String()),
+		TenantID:    params.TenantID,
+		ExecutionID: params.ExecutionID,
+	}, nil
+}
+
+// kubernetesWritePrivileges runs the engine's privilege check of a source as a Job,
+// since the Kubernetes runner has no long-lived engine container to exec into. The
+// DSN reaches the pod through the Job's secret and is expanded into its args there.
+func (a *Activities) kubernetesWritePrivileges(ctx context.Context, conn *models.Connection, dsn string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubeCheckTimeout)
+	defer cancel()
+
+	name := "stratum-check-" + uuid.NewString()
+	err := a.Kubernetes.Launch(ctx, engine.KubernetesJob{
+		Name:  name,
+		Image: a.EngineImage,
+		Args:  []string{"source", "privileges", "--format", conn.DataFormat, "--conn-str", "$(SOURCE_CONN_STR)", "--writes"},
+		Env:   []string{"SOURCE_CONN_STR=" + dsn},
+		Labels: map[string]string{
+			"stratum.tenant_id":     conn.TenantID,
+			"stratum.connection_id": conn.ID,
+		},
+		CPUMillis:   a.ContainerCPULimit,
+		MemoryBytes: a.ContainerMemLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create privilege check job: %w", err)
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), kubeCleanupTimeout)
+		defer cancel()
+		if err := a.Kubernetes.Delete(cleanupCtx, name); err != nil {
+			activity.GetLogger(ctx).Warn("Failed to delete kubernetes job", "job", name, "error", err)
+		}
+	}()
+
+	pod, err := a.Kubernetes.WaitForPod(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("privilege check job %s did not start: %w", name, err)
+	}
+	exitCode, err := a.Kubernetes.Wait(ctx, pod.Name)
+	if err != nil {
+		return nil, fmt.Errorf("privilege check job wait error: %w", err)
+	}
+	logReader, err := a.Kubernetes.Logs(ctx, pod.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod logs: %w", err)
+	}
+	defer logReader.Close()
+	raw, err := io.ReadAll(logReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod logs: %w", err)
+	}
+	output := strings.TrimSpace(string(raw))
+	if exitCode != 0 {
+		return nil, &engine.ExitError{Op: "privilege check", ExitCode: int(exitCode), Output: output}
+	}
+
+	// Kubernetes interleaves stderr into the log; the engine prints the privileges last.
+	last := output
+	if i := strings.LastIndexByte(output, '\n'); i >= 0 {
+		last = strings.TrimSpace(output[i+1:])
+	}
+	var privileges []string
+	if err := json.Unmarshal([]byte(last), &privileges); err != nil {
+		return nil, fmt.Errorf("unexpected privilege check output %q", last)
+	}
+	return privileges, nil
+}
@@ -0,0 +1,83 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/sdk/activity"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+// The engine polls its control file, named in CONTROL_FILE, for operator commands.
+const (
+	engineControlDir  = "/app"
+	engineControlFile = "control"
+)
+
+// Engine control commands.
+const (
+	engineControlPause  = "pause"
+	engineControlResume = "resume"
+)
+
+// SetExecutionPausedActivity tells the execution's engine container to checkpoint
+// and idle (paused) or to carry on, and records the new status. A container that is
+// not up yet fails the attempt so it is retried.
+func (a *Activities) SetExecutionPausedActivity(ctx context.Context, params temporal.PrepareActivityResult, paused bool) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Setting execution pause state", "ExecutionID", params.ExecutionID, "paused", paused)
+
+	if a.Kubernetes != nil {
+		return sdktemporal.NewNonRetryableApplicationError(
+			"executions run as Kubernetes Jobs cannot be paused", temporal.ErrTypeRunnerUnsupported, nil)
+	}
+	host, err := a.dockerHost(params.DockerHost)
+	if err != nil {
+		return err
+	}
+	containers, err := host.Client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "stratum.execution_id="+params.ExecutionID)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to find execution container")
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("no running container for execution %s", params.ExecutionID)
+	}
+
+	command := engineControlResume
+	if paused {
+		command = engineControlPause
+	}
+	runner := engine.NewDockerRunner(host.Client)
+	if err := runner.CopyTo(ctx, containers[0].ID, engineControlDir, []byte(command+"\n"), engineControlFile); err != nil {
+		return errors.Wrap(err, "failed to write engine control file")
+	}
+
+	changed, err := a.JobRepo.SetExecutionPaused(params.TenantID, params.ExecutionID, paused)
+	if err != nil {
+		return errors.Wrap(err, "failed to record pause state")
+	}
+	if !changed || a.Notifier == nil {
+		return nil
+	}
+	exec, def, err := a.loadExecutionDetails(params.TenantID, params.ExecutionID)
+	if err != nil {
+		logger.Warn("Unable to load execution for pause notification", "error", err)
+		return nil
+	}
+	if paused {
+		err = a.Notifier.NotifyExecutionPaused(ctx, params.TenantID, exec.JobDefinitionID, params.ExecutionID, def.Name)
+	} else {
+		err = a.Notifier.NotifyExecutionResumed(ctx, params.TenantID, exec.JobDefinitionID, params.ExecutionID, def.Name)
+	}
+	if err != nil {
+		logger.Warn("Failed to publish pause notification", "error", err)
+	}
+	return nil
+}
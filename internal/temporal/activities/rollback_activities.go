@@ -0,0 +1,76 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/sdk/activity"
+)
+
+// RollbackExecutionActivity has the engine undo what the execution rolledBackID
+// wrote to its destination, using the config prepared for the rollback run, and
+// stores the report as the rollback execution's rollback artifact. A rollback the
+// engine could not run is recorded in the report rather than returned, so it is
+// not repeated against a destination in an unknown state.
+func (a *Activities) RollbackExecutionActivity(ctx context.Context, params temporal.PrepareActivityResult, rolledBackID string) (*models.RollbackReport, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Rolling back execution", "ExecutionID", params.ExecutionID, "RolledBackExecutionID", rolledBackID)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
+
+	host, err := a.dockerHost(params.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+	if err := host.JoinNetworks(ctx, a.EngineImage, params.Networks...); err != nil {
+		return nil, networkError(err)
+	}
+	config, err := a.loadExecutionConfig(ctx, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read execution config")
+	}
+
+	stopHeartbeat := heartbeatWhileRunning(ctx, "rolling back")
+	raw, rollbackErr := host.Engine(a.EngineImage).Rollback(ctx, config, rolledBackID)
+	stopHeartbeat()
+
+	report := models.RollbackReport{}
+	if rollbackErr != nil {
+		report.Error = redactConnectionSecrets(config, ansiEscape.ReplaceAllString(rollbackErr.Error(), ""))
+	} else if err := json.Unmarshal(raw, &report); err != nil {
+		report.Error = fmt.Sprintf("failed to parse engine report: %v", err)
+	}
+	report.RolledBackExecutionID = rolledBackID
+	if report.CapturedAt.IsZero() {
+		report.CapturedAt = time.Now().UTC()
+	}
+	report.Evaluate()
+	if problem := report.Problem(); problem != "" {
+		logger.Warn("Rollback incomplete", "ExecutionID", params.ExecutionID, "problem", problem)
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal rollback report")
+	}
+	artifact := models.ExecutionArtifact{
+		TenantID:    params.TenantID,
+		ExecutionID: params.ExecutionID,
+		Kind:        models.ArtifactKindRollback,
+		Content:     content,
+	}
+	if err := a.placeArtifact(&artifact); err != nil {
+		return nil, err
+	}
+	if err := a.scanArtifact(ctx, &artifact); err != nil {
+		return nil, err
+	}
+	if _, err := a.JobRepo.SaveExecutionArtifact(artifact); err != nil {
+		return nil, errors.Wrap(err, "failed to store rollback report")
+	}
+	return &report, nil
+}
@@ -0,0 +1,56 @@
+package activities
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/models"
+	"go.temporal.io/sdk/activity"
+)
+
+// ExecutionStatusActivity returns the recorded status of an execution. A run whose
+// workflow never recorded it counts as failed.
+func (a *Activities) ExecutionStatusActivity(ctx context.Context, tenantID, executionID string) (models.ExecutionStatus, error) {
+	exec, err := a.JobRepo.GetExecution(tenantID, executionID)
+	if err != nil {
+		// The repository reports a missing execution as "execution not found".
+		if strings.Contains(err.Error(), "not found") {
+			return models.ExecutionStatusFailed, nil
+		}
+		return "", errors.Wrap(err, "failed to load execution")
+	}
+	return exec.Status, nil
+}
+
+// SkipRunGroupMembersActivity records the members of a run group that were not
+// started after an earlier member failed.
+func (a *Activities) SkipRunGroupMembersActivity(ctx context.Context, tenantID, groupID string, executionIDs []string) error {
+	if err := a.JobRepo.SkipRunGroupMembers(tenantID, groupID, executionIDs); err != nil {
+		return errors.Wrap(err, "failed to skip run group members")
+	}
+	return nil
+}
+
+// CompleteRunGroupActivity records the consolidated report of a run group whose
+// members all finished and notifies the tenant.
+func (a *Activities) CompleteRunGroupActivity(ctx context.Context, tenantID, groupID string) error {
+	logger := activity.GetLogger(ctx)
+	group, err := a.JobRepo.GetRunGroup(tenantID, groupID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load run group")
+	}
+	report := models.NewRunGroupReport(group, time.Now().UTC())
+	if err := a.JobRepo.CompleteRunGroup(tenantID, groupID, report); err != nil {
+		return errors.Wrap(err, "failed to record run group report")
+	}
+	logger.Info("Run group completed", "GroupID", groupID, "Status", report.Status)
+
+	if a.Notifier != nil {
+		if err := a.Notifier.NotifyRunGroupCompleted(ctx, tenantID, report); err != nil {
+			logger.Warn("Failed to publish run group notification", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+)
+
+// sensorHTTPTimeout bounds a single HTTP sensor probe.
+const sensorHTTPTimeout = 30 * time.Second
+
+var sensorHTTPClient = &http.Client{Timeout: sensorHTTPTimeout}
+
+// LoadSensorsActivity returns the enabled sensors of a definition.
+func (a *Activities) LoadSensorsActivity(ctx context.Context, tenantID, jobDefID string) ([]models.JobSensor, error) {
+	if a.SensorRepo == nil {
+		return nil, nil
+	}
+	sensors, err := a.SensorRepo.ListSensors(tenantID, jobDefID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make([]models.JobSensor, 0, len(sensors))
+	for _, s := range sensors {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+	return enabled, nil
+}
+
+// EvaluateSensorActivity probes a sensor once on behalf of an execution and records
+// the evaluation. A probe that cannot reach its target has not passed; only
+// failures to record the evaluation fail the activity.
+func (a *Activities) EvaluateSensorActivity(ctx context.Context, tenantID, executionID string, sensor models.JobSensor) (*temporal.SensorCheckResult, error) {
+	logger := activity.GetLogger(ctx)
+
+	var (
+		passed bool
+		value  *int64
+		detail string
+	)
+	switch sensor.Kind {
+	case models.SensorKindRowCount:
+		passed, value, detail = a.probeRowCount(ctx, tenantID, executionID, sensor)
+	case models.SensorKindFile:
+		passed, value, detail = probeFile(sensor)
+	case models.SensorKindHTTP:
+		passed, value, detail = probeHTTP(ctx, sensor)
+	default:
+		detail = "unknown sensor kind " + sensor.Kind
+	}
+	logger.Info("Sensor evaluated", "sensor", sensor.Name, "passed", passed, "detail", detail)
+
+	err := a.SensorRepo.RecordSensorEvaluation(models.SensorEvaluation{
+		TenantID:        tenantID,
+		SensorID:        sensor.ID,
+		JobDefinitionID: sensor.JobDefinitionID,
+		ExecutionID:     executionID,
+		Passed:          passed,
+		ObservedValue:   value,
+		Detail:          detail,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("record sensor evaluation: %w", err)
+	}
+	return &temporal.SensorCheckResult{Passed: passed, Detail: detail}, nil
+}
+
+func (a *Activities) probeRowCount(ctx context.Context, tenantID, executionID string, sensor models.JobSensor) (bool, *int64, string) {
+	var cfg models.RowCountSensorConfig
+	if err := json.Unmarshal(sensor.Config, &cfg); err != nil {
+		return false, nil, "invalid config: " + err.Error()
+	}
+	conn, err := a.ConnRepo.Get(tenantID, cfg.ConnectionID)
+	if err != nil {
+		return false, nil, "failed to load connection: " + err.Error()
+	}
+	if err := a.injectRunCredentials(executionID, conn); err != nil {
+		return false, nil, err.Error()
+	}
+	dsn, err := conn.GenerateConnString()
+	if err != nil {
+		return false, nil, "failed to build connection string: " + err.Error()
+	}
+	host, release, err := a.Hosts.Acquire(ctx, tenantID)
+	if err != nil {
+		return false, nil, "no engine host available: " + err.Error()
+	}
+	defer release()
+
+	count, err := host.Engine(a.EngineImage).RowCount(ctx, conn.DataFormat, dsn, cfg.Table)
+	if err != nil {
+		msg := ansiEscape.ReplaceAllString(err.Error(), "")
+		if conn.Password != "" {
+			msg = strings.ReplaceAll(msg, conn.Password, "****")
+		}
+		return false, nil, "row count failed: " + msg
+	}
+	if count < cfg.MinRows {
+		return false, &count, fmt.Sprintf("%s has %d rows, waiting for at least %d", cfg.Table, count, cfg.MinRows)
+	}
+	if cfg.Changed {
+		last, err := a.SensorRepo.LastPassedSensorValue(tenantID, sensor.ID, executionID)
+		if err != nil {
+			return false, &count, "failed to load previous row count: " + err.Error()
+		}
+		if last != nil && *last == count {
+			return false, &count, fmt.Sprintf("%s still has %d rows, waiting for a change", cfg.Table, count)
+		}
+	}
+	return true, &count, fmt.Sprintf("%s has %d rows", cfg.Table, count)
+}
+
+func probeFile(sensor models.JobSensor) (bool, *int64, string) {
+	var cfg models.FileSensorConfig
+	if err := json.Unmarshal(sensor.Config, &cfg); err != nil {
+		return false, nil, "invalid config: " + err.Error()
+	}
+	matches, err := filepath.Glob(cfg.Path)
+	if err != nil {
+		return false, nil, "invalid path pattern: " + err.Error()
+	}
+	var largest *int64
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		size := info.Size()
+		if largest == nil || size > *largest {
+			largest = &size
+		}
+		if size >= cfg.MinSizeBytes {
+			return true, &size, fmt.Sprintf("%s is %d bytes", match, size)
+		}
+	}
+	if largest != nil {
+		return false, largest, fmt.Sprintf("no file matching %s has %d bytes yet", cfg.Path, cfg.MinSizeBytes)
+	}
+	return false, nil, "no file matches " + cfg.Path
+}
+
+func probeHTTP(ctx context.Context, sensor models.JobSensor) (bool, *int64, string) {
+	var cfg models.HTTPSensorConfig
+	if err := json.Unmarshal(sensor.Config, &cfg); err != nil {
+		return false, nil, "invalid config: " + err.Error()
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	expected := cfg.ExpectedStatus
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cfg.URL, nil)
+	if err != nil {
+		return false, nil, "invalid request: " + err.Error()
+	}
+	resp, err := sensorHTTPClient.Do(req)
+	if err != nil {
+		return false, nil, "request failed: " + err.Error()
+	}
+	resp.Body.Close()
+
+	status := int64(resp.StatusCode)
+	if resp.StatusCode != expected {
+		return false, &status, fmt.Sprintf("%s answered %d, waiting for %d", cfg.URL, resp.StatusCode, expected)
+	}
+	return true, &status, fmt.Sprintf("%s answered %d", cfg.URL, resp.StatusCode)
+}
@@ -0,0 +1,318 @@
+package activities
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"go.temporal.io/sdk/activity"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+// sqlScriptStats is what a SQL script run moved.
+type sqlScriptStats struct {
+	Rows  int64
+	Bytes int64
+}
+
+// RunSQLScriptActivity executes a sql_script definition directly against its
+// connections, without the engine, and stores the run's final status and metrics.
+// All changes are made in one destination transaction, so a run that fails or hits
+// its limits changes nothing.
+func (a *Activities) RunSQLScriptActivity(ctx context.Context, params temporal.ExecutionParams) (*temporal.RunContainerResult, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Running SQL script", "tenantID", params.TenantID, "executionID", params.ExecutionID)
+	a.recordAttempt(ctx, params.TenantID, params.ExecutionID)
+
+	result := &temporal.RunContainerResult{
+		TenantID:    params.TenantID,
+		ExecutionID: params.ExecutionID,
+		Reported:    true,
+	}
+
+	// An attempt retried after its predecessor committed must not run the script again.
+	exec, err := a.JobRepo.GetExecution(params.TenantID, params.ExecutionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch execution")
+	}
+	if exec.Status != models.ExecutionStatusRunning {
+		if exec.Logs != nil {
+			result.Logs = *exec.Logs
+		}
+		return result, nil
+	}
+
+	def, err := a.JobRepo.GetJobDefinitionByID(params.TenantID, params.JobDefinitionID)
+	if err != nil {
+		return nil, lookupError(err, "failed to fetch job definition")
+	}
+	spec, err := models.ParseSQLScript(def.AST)
+	if err != nil {
+		return nil, invalidDefinition(err, "invalid sql script")
+	}
+
+	dest, err := a.ConnRepo.Get(params.TenantID, def.DestinationConnectionID)
+	if err != nil {
+		return nil, lookupError(err, "failed to fetch destination connection")
+	}
+	conns := []*models.Connection{dest}
+	var source *models.Connection
+	if spec.NeedsSource() {
+		source, err = a.ConnRepo.Get(params.TenantID, def.SourceConnectionID)
+		if err != nil {
+			return nil, lookupError(err, "failed to fetch source connection")
+		}
+		conns = append(conns, source)
+	}
+	if err := a.injectRunCredentials(params.ExecutionID, conns...); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, spec.Timeout())
+	defer cancel()
+	started := time.Now()
+	var stats sqlScriptStats
+	if spec.Mode == models.SQLScriptModeCopy {
+		stats, err = copySQLTable(runCtx, source, dest, spec)
+	} else {
+		stats, err = runSQLStatements(runCtx, dest, spec)
+	}
+	if err != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			err = sqlScriptFailed(fmt.Errorf("timed out after %s", spec.Timeout()))
+		}
+		return nil, redactConnectionError(err, conns...)
+	}
+
+	if spec.Mode == models.SQLScriptModeCopy {
+		result.Logs = fmt.Sprintf("Copied %d rows from %s to %s in %s.\n",
+			stats.Rows, spec.SourceTable, spec.DestinationTable, time.Since(started).Round(time.Millisecond))
+	} else {
+		result.Logs = fmt.Sprintf("Script affected %d rows in %s.\n", stats.Rows, time.Since(started).Round(time.Millisecond))
+	}
+	if err := a.JobRepo.SetExecutionComplete(params.TenantID, params.ExecutionID, models.ExecutionStatusSucceeded, stats.Rows, stats.Bytes); err != nil {
+		return nil, errors.Wrap(err, "failed to record sql script result")
+	}
+	a.emitStatusNotification(ctx, params.TenantID, params.ExecutionID, models.ExecutionStatusSucceeded, "")
+	return result, nil
+}
+
+// runSQLStatements runs the script's statements on the destination one at a time,
+// in one transaction. lib/pq reports only the last statement's rows for a script
+// sent as a whole, so each statement's rows are added up and checked against
+// MaxRows as soon as it has run.
+func runSQLStatements(ctx context.Context, conn *models.Connection, spec models.SQLScriptSpec) (sqlScriptStats, error) {
+	var stats sqlScriptStats
+	statements, err := models.SQLScriptStatements(spec.Script)
+	if err != nil {
+		return stats, invalidDefinition(err, "invalid sql script")
+	}
+	db, err := openNativePostgres(ctx, conn)
+	if err != nil {
+		return stats, err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+	if err := setStatementTimeout(ctx, tx, spec.Timeout()); err != nil {
+		return stats, err
+	}
+
+	for i, stmt := range statements {
+		res, err := tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return sqlScriptStats{}, sqlScriptFailed(fmt.Errorf("statement %d: %w", i+1, err))
+		}
+		rows, _ := res.RowsAffected()
+		stats.Rows += rows
+		if stats.Rows > spec.MaxRows {
+			return sqlScriptStats{}, sqlScriptFailed(fmt.Errorf("script affected %d rows by statement %d, more than max_rows %d", stats.Rows, i+1, spec.MaxRows))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return sqlScriptStats{}, sqlScriptFailed(err)
+	}
+	return stats, nil
+}
+
+// copySQLTable copies the source table into the destination table with COPY, in
+// one destination transaction. A source holding more than MaxRows rows fails the
+// run rather than being copied in part.
+func copySQLTable(ctx context.Context, src, dst *models.Connection, spec models.SQLScriptSpec) (sqlScriptStats, error) {
+	var stats sqlScriptStats
+	srcDB, err := openNativePostgres(ctx, src)
+	if err != nil {
+		return stats, err
+	}
+	defer srcDB.Close()
+	dstDB, err := openNativePostgres(ctx, dst)
+	if err != nil {
+		return stats, err
+	}
+	defer dstDB.Close()
+
+	rows, err := srcDB.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT %d", quoteTableName(spec.SourceTable), spec.MaxRows+1))
+	if err != nil {
+		return stats, sqlScriptFailed(err)
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return stats, sqlScriptFailed(err)
+	}
+	cols := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		cols[i] = ct.Name()
+	}
+
+	tx, err := dstDB.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+	if err := setStatementTimeout(ctx, tx, spec.Timeout()); err != nil {
+		return stats, err
+	}
+	if spec.Truncate {
+		if _, err := tx.ExecContext(ctx, "TRUNCATE TABLE "+quoteTableName(spec.DestinationTable)); err != nil {
+			return stats, sqlScriptFailed(err)
+		}
+	}
+
+	copyQuery := pq.CopyIn(spec.DestinationTable, cols...)
+	if schema, table := splitTableName(spec.DestinationTable); schema != "" {
+		copyQuery = pq.CopyInSchema(schema, table, cols...)
+	}
+	stmt, err := tx.PrepareContext(ctx, copyQuery)
+	if err != nil {
+		return stats, sqlScriptFailed(err)
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if stats.Rows >= spec.MaxRows {
+			return sqlScriptStats{}, sqlScriptFailed(fmt.Errorf("%s has more than max_rows %d rows", spec.SourceTable, spec.MaxRows))
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return sqlScriptStats{}, sqlScriptFailed(err)
+		}
+		for i, v := range values {
+			// lib/pq returns text as []byte, which COPY would write as bytea.
+			if b, ok := v.([]byte); ok {
+				stats.Bytes += int64(len(b))
+				if colTypes[i].DatabaseTypeName() != "BYTEA" {
+					values[i] = string(b)
+				}
+			} else if s, ok := v.(string); ok {
+				stats.Bytes += int64(len(s))
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return sqlScriptStats{}, sqlScriptFailed(err)
+		}
+		stats.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return sqlScriptStats{}, sqlScriptFailed(err)
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return sqlScriptStats{}, sqlScriptFailed(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return sqlScriptStats{}, sqlScriptFailed(err)
+	}
+	return stats, nil
+}
+
+// openNativePostgres connects to a PostgreSQL connection with lib/pq. Connection
+// failures stay retryable; other data formats are not supported by SQL scripts.
+func openNativePostgres(ctx context.Context, conn *models.Connection) (*sql.DB, error) {
+	switch conn.DataFormat {
+	case "pg", "postgresql", "postgres":
+	default:
+		return nil, invalidDefinition(fmt.Errorf("%s is a %s connection", conn.Name, conn.DataFormat),
+			"sql script jobs support PostgreSQL connections only")
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(conn.Username, conn.Password),
+		Host:   net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port)),
+		Path:   "/" + conn.DBName,
+	}
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, invalidDefinition(err, "invalid connection "+conn.Name)
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to connect to "+conn.Name)
+	}
+	return db, nil
+}
+
+// setStatementTimeout makes the server cancel statements that outlive the run,
+// in case the client-side cancellation does not reach it.
+func setStatementTimeout(ctx context.Context, tx *sql.Tx, timeout time.Duration) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		return errors.Wrap(err, "failed to set statement timeout")
+	}
+	return nil
+}
+
+func sqlScriptFailed(err error) error {
+	return sdktemporal.NewApplicationErrorWithCause(err.Error(), temporal.ErrTypeSQLScriptFailed, err)
+}
+
+// redactConnectionError masks the connections' passwords in err's message.
+func redactConnectionError(err error, conns ...*models.Connection) error {
+	msg := err.Error()
+	redacted := msg
+	for _, conn := range conns {
+		if conn.Password != "" {
+			redacted = strings.ReplaceAll(redacted, conn.Password, "****")
+		}
+	}
+	if redacted == msg {
+		return err
+	}
+	var appErr *sdktemporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return sdktemporal.NewApplicationError(redacted, appErr.Type())
+	}
+	return errors.New(redacted)
+}
+
+// splitTableName splits an optionally schema-qualified table name.
+func splitTableName(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func quoteTableName(name string) string {
+	schema, table := splitTableName(name)
+	if schema == "" {
+		return pq.QuoteIdentifier(table)
+	}
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+}
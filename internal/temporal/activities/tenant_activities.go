@@ -0,0 +1,24 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.temporal.io/sdk/activity"
+)
+
+// PurgeTenantActivity permanently deletes a tenant whose deletion grace period has
+// ended. A tenant that was restored in the meantime is left untouched.
+func (a *Activities) PurgeTenantActivity(ctx context.Context, tenantID string) error {
+	logger := activity.GetLogger(ctx)
+	purged, err := a.TenantRepo.PurgeTenant(tenantID)
+	if err != nil {
+		return errors.Wrap(err, "failed to purge tenant")
+	}
+	if purged {
+		logger.Info("Purged deleted tenant", "tenantID", tenantID)
+	} else {
+		logger.Info("Tenant no longer scheduled for purge", "tenantID", tenantID)
+	}
+	return nil
+}
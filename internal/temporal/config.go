@@ -1,31 +1,177 @@
 package temporal
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
 
 // TaskQueueName is the name of the Temporal task queue used for Stratum migration workflows.
 const TaskQueueName = "STRATUM_MIGRATION"
 
+// PriorityTaskQueueName is the task queue of high-priority executions. Its worker
+// runs a reserved number of slots that bulk runs on TaskQueueName cannot take.
+const PriorityTaskQueueName = "STRATUM_MIGRATION_PRIORITY"
+
+// TaskQueueFor returns the task queue executions of the given priority run on.
+func TaskQueueFor(priority string) string {
+	if priority == models.PriorityHigh {
+		return PriorityTaskQueueName
+	}
+	return TaskQueueName
+}
+
 // ExecWorkflowIDPrefix is the prefix used for Stratum migration workflow IDs.
 const ExecWorkflowIDPrefix = "stratum-migration-"
 
+// ExecutionWorkflowID returns the ID of the workflow running an execution. Scheduled
+// runs get theirs from Temporal and record it; other runs may not have one recorded.
+func ExecutionWorkflowID(executionID string, recorded *string) string {
+	if recorded != nil && *recorded != "" {
+		return *recorded
+	}
+	return ExecWorkflowIDPrefix + executionID
+}
+
+// JobScheduleIDPrefix is the prefix of the Temporal Schedule that runs a job
+// definition on its cron expression.
+const JobScheduleIDPrefix = "stratum-schedule-"
+
+// RunGroupWorkflowIDPrefix is the prefix of the workflow that runs a run group.
+const RunGroupWorkflowIDPrefix = "stratum-run-group-"
+
+// TenantPurgeWorkflowIDPrefix is the prefix of the workflow that purges a deleted tenant.
+const TenantPurgeWorkflowIDPrefix = "stratum-tenant-purge-"
+
+// Signals accepted by the execution workflow while its engine container runs.
+const (
+	// PauseSignalName asks the engine to checkpoint and idle.
+	PauseSignalName = "pause-execution"
+	// ResumeSignalName lets a paused engine carry on.
+	ResumeSignalName = "resume-execution"
+	// CancelSignalName aborts the run: the engine container is stopped, or the
+	// workflow stops at its next step when the container has not started yet.
+	CancelSignalName = "cancel-execution"
+)
+
+// ProgressQueryName is the query ExecutionWorkflow answers with its current step
+// and percent complete, as a models.ExecutionProgress.
+const ProgressQueryName = "execution-progress"
+
 // DefaultActivityTimeout is the default timeout duration for Temporal activities in Stratum migration workflows.
 const DefaultActivityTimeout = 5 * time.Minute
 
+// ContainerStopGrace is how long past its time limit the container activity may
+// take to stop the engine container and report the overrun.
+const ContainerStopGrace = 2 * time.Minute
+
+// VerificationActivityTimeout is the start-to-close timeout of the post-migration
+// verification; it leaves the engine's own limit room to report.
+const VerificationActivityTimeout = 35 * time.Minute
+
+// RollbackActivityTimeout is the start-to-close timeout of a rollback; it leaves
+// the engine's own limit room to report.
+const RollbackActivityTimeout = 35 * time.Minute
+
+// ContainerActivityTimeout returns the start-to-close timeout of the activity that
+// runs an engine container limited to maxDuration. Runs without a limit get the
+// longest duration a tenant may allow; heartbeats still catch a lost worker.
+func ContainerActivityTimeout(maxDuration time.Duration) time.Duration {
+	if maxDuration <= 0 {
+		return models.MaxMaxExecutionDuration
+	}
+	return maxDuration + ContainerStopGrace
+}
+
 // ExecutionParams defines the input for Stratum migration workflows.
 type ExecutionParams struct {
 	TenantID        string
 	ExecutionID     string
 	JobDefinitionID string
+	// JobType selects how the run executes; sql_script runs skip the engine.
+	JobType string
+	// ResumeFromExecutionID, when set, continues from that execution's checkpoints.
+	ResumeFromExecutionID string
+	// ReplayOfExecutionID, when set, runs the AST snapshotted by that execution
+	// instead of the definition's current one.
+	ReplayOfExecutionID string
+	// OnlyTables, when set, cuts the AST's table list down to these tables, as for
+	// a re-run of the tables an earlier execution failed on.
+	OnlyTables []string
+	// RollbackOfExecutionID, when set, makes the run a rollback of that execution,
+	// run by RollbackWorkflow instead of ExecutionWorkflow. ReplayOfExecutionID
+	// names the same execution, so the rollback sees the AST it ran.
+	RollbackOfExecutionID string
+	// SkipSensors starts the run without waiting for the definition's sensors.
+	SkipSensors bool
+	// AllowConcurrent starts the run even while the definition has another pending,
+	// running or paused execution.
+	AllowConcurrent bool
+	// Overrides is a JSON object merged into the AST for this run only (see
+	// models.ApplyRunOverrides). The definition itself is left unchanged.
+	Overrides json.RawMessage
+	// TimeoutSeconds limits how long this run's engine container may take. It can
+	// only shorten the tenant's maximum execution duration; zero keeps it.
+	TimeoutSeconds int
+	// Priority is models.PriorityHigh for runs on PriorityTaskQueueName; empty is
+	// normal priority. The workflow's activities run on the same queue.
+	Priority string
+	// TriggeredBy records what started the run (models.Trigger*); TriggerContext
+	// identifies the user, API key, schedule or execution behind it.
+	TriggeredBy    string
+	TriggerContext map[string]string
+}
+
+// RunGroupParams defines the input of the run group workflow. Members are started
+// as child execution workflows in order, at most Parallelism at a time; each
+// carries the execution ID recorded for it when the group was created.
+type RunGroupParams struct {
+	TenantID      string
+	GroupID       string
+	Members       []ExecutionParams
+	Parallelism   int
+	StopOnFailure bool
 }
 
 // PrepareActivityResult holds the results from the PrepareMigrationActivity.
 // This data is passed to the next activity in the workflow.
 type PrepareActivityResult struct {
-	ASTFilePath     string
-	AuthToken       string
-	HostCallbackURL string
-	TenantID        string
-	ExecutionID     string
+	// ConfigBlob is the hash of the encrypted engine config in the blob store.
+	ConfigBlob string
+	// ASTFilePath is where executions prepared before the blob store wrote their
+	// config on the worker.
+	ASTFilePath           string
+	AuthToken             string
+	HostCallbackURL       string
+	CheckpointCallbackURL string
+	TenantID              string
+	ExecutionID           string
+	// DockerHost names the Docker host chosen for this execution; every later
+	// activity talks to the same daemon.
+	DockerHost string
+	// Networks are the Docker networks of the run's connections; the engine
+	// container joins them so hosts on them resolve.
+	Networks []string
+	// EngineImage and the container limits come from the tenant's worker
+	// configuration. Results prepared before it existed leave them empty, which
+	// means the worker's defaults.
+	EngineImage string
+	CPULimit    int64
+	MemoryLimit int64
+	// MaxDuration stops the engine run after this long; zero means no limit.
+	MaxDuration time.Duration
+	// RetryPolicy is the definition's override of ContainerRetryPolicy; nil keeps it.
+	RetryPolicy *models.RetryPolicy
+	// Verification is the definition's post-migration verification mode; empty
+	// means off.
+	Verification string
+}
+
+// SensorCheckResult is the outcome of one probe of a sensor.
+type SensorCheckResult struct {
+	Passed bool
+	Detail string
 }
 
 // RunContainerResult holds the results from running the Docker container.
@@ -34,4 +180,7 @@ type RunContainerResult struct {
 	Logs        string
 	TenantID    string
 	ExecutionID string
+	// Reported is set by runs that stored their final status and metrics
+	// themselves, so completion need not wait for an engine report.
+	Reported bool
 }
@@ -0,0 +1,70 @@
+package temporal
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCredentialTTL bounds how long supplied run credentials wait for the prepare
+// activity before they are dropped.
+const DefaultCredentialTTL = 15 * time.Minute
+
+// RunCredentials maps connection IDs to the passwords supplied for one run.
+type RunCredentials map[string]string
+
+// CredentialVault holds just-in-time connection passwords in memory between the API
+// accepting a run and the prepare activity building its config. Credentials are
+// never written to the database or to workflow history, so they only reach an
+// activity running in the process that accepted the run.
+type CredentialVault struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]vaultEntry
+}
+
+type vaultEntry struct {
+	creds   RunCredentials
+	expires time.Time
+}
+
+func NewCredentialVault(ttl time.Duration) *CredentialVault {
+	if ttl <= 0 {
+		ttl = DefaultCredentialTTL
+	}
+	return &CredentialVault{ttl: ttl, entries: make(map[string]vaultEntry)}
+}
+
+// Put stores the credentials for an execution, replacing any earlier ones.
+func (v *CredentialVault) Put(executionID string, creds RunCredentials) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.evictExpired(time.Now())
+	v.entries[executionID] = vaultEntry{creds: creds, expires: time.Now().Add(v.ttl)}
+}
+
+// Get returns the credentials for an execution. They stay in the vault so a retried
+// prepare activity finds them again.
+func (v *CredentialVault) Get(executionID string) (RunCredentials, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.evictExpired(time.Now())
+	entry, ok := v.entries[executionID]
+	return entry.creds, ok
+}
+
+// Discard drops the credentials for an execution once its config is built, or when
+// the run never started.
+func (v *CredentialVault) Discard(executionID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.entries, executionID)
+}
+
+func (v *CredentialVault) evictExpired(now time.Time) {
+	for id, entry := range v.entries {
+		if now.After(entry.expires) {
+			delete(v.entries, id)
+		}
+	}
+}
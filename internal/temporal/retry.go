@@ -0,0 +1,191 @@
+package temporal
+
+import (
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+// Application error types raised by the execution activities. Types listed in a
+// retry policy's NonRetryableErrorTypes fail the activity on the first attempt.
+const (
+	// ErrTypeInvalidDefinition covers definitions the engine cannot run as stored:
+	// a malformed AST, unresolved secrets or missing connections.
+	ErrTypeInvalidDefinition = "InvalidDefinition"
+	// ErrTypeCredentialsUnavailable means just-in-time credentials are not in this
+	// worker's vault, which no retry can fix.
+	ErrTypeCredentialsUnavailable = "CredentialsUnavailable"
+	// ErrTypeUnknownDockerHost means the execution's Docker host left the configuration.
+	ErrTypeUnknownDockerHost = "UnknownDockerHost"
+	// ErrTypeCrossRegion means the tenant's data may not be stored in this region.
+	ErrTypeCrossRegion = "CrossRegion"
+	// ErrTypeSensorTimeout fails a run whose sensors did not pass in time.
+	ErrTypeSensorTimeout = "SensorTimeout"
+	// ErrTypeSQLScriptFailed means a SQL script run was rejected by the database or
+	// exceeded its limits; its transaction was rolled back.
+	ErrTypeSQLScriptFailed = "SQLScriptFailed"
+	// ErrTypeEngineImageNotAllowed means the tenant's worker configuration does not
+	// allow the engine image the run would use.
+	ErrTypeEngineImageNotAllowed = "EngineImageNotAllowed"
+	// ErrTypeExecutionTimeLimit stops a run that outlived the tenant's maximum
+	// execution duration.
+	ErrTypeExecutionTimeLimit = "ExecutionTimeLimit"
+	// ErrTypeExecutionActive rejects a run of a definition that already has a
+	// pending, running or paused execution.
+	ErrTypeExecutionActive = "ExecutionActive"
+	// ErrTypeSourceWritable refuses a run whose read-only source connection logs in
+	// as a user that can write to it.
+	ErrTypeSourceWritable = "SourceWritable"
+	// ErrTypeStartLimited skips a scheduled run of a tenant that reached its
+	// execution start limit.
+	ErrTypeStartLimited = "StartLimited"
+	// ErrTypeBlackout skips a scheduled run that falls into one of the tenant's
+	// blackout windows.
+	ErrTypeBlackout = "Blackout"
+	// ErrTypeStaleValidation skips a scheduled run of a definition whose source
+	// schema changed since it was validated, when its tenant blocks such runs.
+	ErrTypeStaleValidation = "StaleValidation"
+	// ErrTypeDockerNetworkNotFound means a connection's Docker network does not
+	// exist on the execution's Docker host.
+	ErrTypeDockerNetworkNotFound = "DockerNetworkNotFound"
+	// ErrTypeVerificationFailed fails a run whose destination did not match its
+	// source, when its definition fails such runs.
+	ErrTypeVerificationFailed = "VerificationFailed"
+	// ErrTypeRollbackFailed fails a rollback the engine could not complete.
+	ErrTypeRollbackFailed = "RollbackFailed"
+	// ErrTypeRunnerUnsupported means the configured engine runner cannot do what was
+	// asked, such as pausing a run launched as a Kubernetes Job.
+	ErrTypeRunnerUnsupported = "RunnerUnsupported"
+)
+
+// nonRetryableExecutionErrors are failed on the first attempt by every execution
+// activity.
+var nonRetryableExecutionErrors = []string{
+	ErrTypeInvalidDefinition,
+	ErrTypeCredentialsUnavailable,
+	ErrTypeUnknownDockerHost,
+	ErrTypeCrossRegion,
+	ErrTypeSQLScriptFailed,
+	ErrTypeEngineImageNotAllowed,
+	ErrTypeExecutionTimeLimit,
+	ErrTypeExecutionActive,
+	ErrTypeSourceWritable,
+	ErrTypeStartLimited,
+	ErrTypeBlackout,
+	ErrTypeStaleValidation,
+	ErrTypeDockerNetworkNotFound,
+	ErrTypeRunnerUnsupported,
+}
+
+// Retry policies of the execution workflow's activities.
+var (
+	// DatabaseRetryPolicy suits the short bookkeeping activities that only talk to
+	// the database: quick retries that ride out a failover.
+	DatabaseRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        30 * time.Second,
+		MaximumAttempts:        10,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// PrepareRetryPolicy gives up quickly: most preparation failures are definition
+	// problems, which are non-retryable, and the rest are transient lookups.
+	PrepareRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        2 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        time.Minute,
+		MaximumAttempts:        5,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// ImagePullRetryPolicy backs off slowly for activities that may pull the engine
+	// image, so a registry outage or slow Docker daemon is waited out.
+	ImagePullRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        10 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        5 * time.Minute,
+		MaximumAttempts:        8,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// ContainerRetryPolicy retries the engine run itself. It also pulls the image,
+	// hence the long backoff, but a run that got as far as starting its container is
+	// only repeated a few times.
+	ContainerRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        10 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        5 * time.Minute,
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// SQLScriptRetryPolicy retries a SQL script run that could not reach its
+	// databases. Every attempt runs in a single transaction, so a retry never
+	// repeats committed work.
+	SQLScriptRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        5 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        time.Minute,
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// VerificationRetryPolicy retries the post-migration comparison a few times. It
+	// reads every migrated table, so attempts are spaced out; a comparison that keeps
+	// failing is treated like a mismatch.
+	VerificationRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        30 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        5 * time.Minute,
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// RollbackRetryPolicy retries a rollback that could not reach the engine. The
+	// engine skips objects that are already rolled back, so a retry is safe.
+	RollbackRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        30 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        5 * time.Minute,
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+
+	// BestEffortRetryPolicy is for activities whose failure never changes the
+	// execution's outcome, such as partial state capture and cleanup.
+	BestEffortRetryPolicy = &sdktemporal.RetryPolicy{
+		InitialInterval:        5 * time.Second,
+		BackoffCoefficient:     2,
+		MaximumInterval:        time.Minute,
+		MaximumAttempts:        3,
+		NonRetryableErrorTypes: nonRetryableExecutionErrors,
+	}
+)
+
+// ContainerRetryPolicyFor returns ContainerRetryPolicy with the fields a definition's
+// retry policy sets replaced. Non-retryable errors stay non-retryable whatever the
+// policy says.
+func ContainerRetryPolicyFor(policy *models.RetryPolicy) *sdktemporal.RetryPolicy {
+	if policy == nil || policy.IsZero() {
+		return ContainerRetryPolicy
+	}
+	merged := *ContainerRetryPolicy
+	if policy.MaxAttempts > 0 {
+		merged.MaximumAttempts = int32(policy.MaxAttempts)
+	}
+	if policy.InitialIntervalSeconds > 0 {
+		merged.InitialInterval = policy.InitialInterval()
+	}
+	if policy.BackoffCoefficient > 0 {
+		merged.BackoffCoefficient = policy.BackoffCoefficient
+	}
+	if policy.MaxIntervalSeconds > 0 {
+		merged.MaximumInterval = policy.MaxInterval()
+	}
+	if merged.MaximumInterval < merged.InitialInterval {
+		merged.MaximumInterval = merged.InitialInterval
+	}
+	return &merged
+}
@@ -4,17 +4,30 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/temporal"
 	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	sdktemporal "go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
-func ExecutionWorkflow(ctx workflow.Context, params temporal.ExecutionParams) error {
-	ao := workflow.ActivityOptions{
+// withRetryPolicy returns ctx with the execution activity options and policy.
+func withRetryPolicy(ctx workflow.Context, policy *sdktemporal.RetryPolicy) workflow.Context {
+	return workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: temporal.DefaultActivityTimeout,
 		HeartbeatTimeout:    30 * time.Second, // Activities can report progress.
+		RetryPolicy:         policy,
+	})
+}
+
+func ExecutionWorkflow(ctx workflow.Context, params temporal.ExecutionParams) (err error) {
+	ctx = withRetryPolicy(ctx, temporal.DatabaseRetryPolicy)
+
+	// Runs started by a schedule all share the same input; the run ID gives each of
+	// them its own execution.
+	if params.ExecutionID == "" {
+		params.ExecutionID = workflow.GetInfo(ctx).WorkflowExecution.RunID
 	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Starting execution workflow", "TenantID", params.TenantID, "ExecutionID", params.ExecutionID)
@@ -23,62 +36,299 @@ func ExecutionWorkflow(ctx workflow.Context, params temporal.ExecutionParams) er
 	// The actual implementation is on the worker; this is just a proxy.
 	var a *activities.Activities
 
-	var preparedResult temporal.PrepareActivityResult
+	progress := models.ExecutionProgress{}
+	progress.EnterStep(models.ProgressStepPending, workflow.Now(ctx))
+	err = workflow.SetQueryHandler(ctx, temporal.ProgressQueryName, func() (models.ExecutionProgress, error) {
+		return progress, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		progress.EnterStep(models.ProgressStepComplete, workflow.Now(ctx))
+	}()
+
+	// Whatever ends the workflow early, the execution must not stay pending or
+	// running. Steps that fail normally record their own message first.
 	defer func() {
-		// If the preparation activity created a temp file, we schedule its cleanup.
-		if preparedResult.ASTFilePath != "" {
-			// Using a new context for cleanup to ensure it runs even if the workflow is cancelled.
-			cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
-			err := workflow.ExecuteActivity(cleanupCtx, a.CleanupActivity, preparedResult.ASTFilePath).Get(cleanupCtx, nil)
-			if err != nil {
-				logger.Error("Failed to cleanup temporary AST file.", "path", preparedResult.ASTFilePath, "error", err)
-			}
+		if err != nil {
+			finalizeExecution(ctx, a, params, err)
 		}
 	}()
 
+	var preparedResult temporal.PrepareActivityResult
+	defer func() {
+		deleteExecutionConfig(ctx, a, preparedResult)
+	}()
+
 	// Step 0: Create job execution record
-	err := workflow.ExecuteActivity(ctx, a.CreateExecutionActivity, params.TenantID, params.JobDefinitionID, params.ExecutionID).Get(ctx, nil)
+	err = workflow.ExecuteActivity(ctx, a.CreateExecutionActivity, params.TenantID, params.JobDefinitionID, params.ExecutionID,
+		params.TriggeredBy, params.TriggerContext, params.AllowConcurrent).Get(ctx, nil)
 	if err != nil {
 		logger.Error("Failed to create job execution record.", "error", err)
 		return err
 	}
 
+	// Wait for the definition's sensors unless the run was started without them.
+	if !params.SkipSensors {
+		proceed, err := waitForSensors(ctx, a, params)
+		if err != nil {
+			logger.Error("Sensors did not pass.", "error", err)
+			return err
+		}
+		if !proceed {
+			return nil
+		}
+	}
+
+	if cancelRequested(ctx) {
+		return markCancelled(ctx, a, params)
+	}
+
 	// Step 1: Update job status to 'running'.
-	err = workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, "running", "", "").Get(ctx, nil)
+	err = workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusRunning, "", "").Get(ctx, nil)
 	if err != nil {
 		logger.Error("Failed to update job status to running.", "error", err)
 		return err
 	}
 
+	// SQL script definitions run on the worker instead of the engine.
+	if params.JobType == models.JobTypeSQLScript {
+		progress.EnterStep(models.ProgressStepRun, workflow.Now(ctx))
+		return runSQLScript(ctx, a, params)
+	}
+
+	if cancelRequested(ctx) {
+		return markCancelled(ctx, a, params)
+	}
+
 	// Step 2: Prepare the execution environment
-	err = workflow.ExecuteActivity(ctx, a.PrepareExecutionActivity, params).Get(ctx, &preparedResult)
+	progress.EnterStep(models.ProgressStepPrepare, workflow.Now(ctx))
+	prepareCtx := withRetryPolicy(ctx, temporal.PrepareRetryPolicy)
+	err = workflow.ExecuteActivity(prepareCtx, a.PrepareExecutionActivity, params).Get(prepareCtx, &preparedResult)
 	if err != nil {
 		msg := fmt.Sprintf("Failed to prepare execution: %v", err)
-		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, "failed", msg, "").Get(ctx, nil)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
 		logger.Error("Execution preparation failed.", "error", err)
 		return err
 	}
 
-	// Step 3: Run the execution container
+	// Step 3: Record the immutable environment snapshot for audits
+	// The snapshot records the engine image digest and pulls the image if needed.
+	progress.EnterStep(models.ProgressStepPull, workflow.Now(ctx))
+	snapshotCtx := withRetryPolicy(ctx, temporal.ImagePullRetryPolicy)
+	err = workflow.ExecuteActivity(snapshotCtx, a.RecordExecutionSnapshotActivity, params, preparedResult.DockerHost).Get(snapshotCtx, nil)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to record execution snapshot: %v", err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		logger.Error("Execution snapshot recording failed.", "error", err)
+		return err
+	}
+
+	if cancelRequested(ctx) {
+		return markCancelled(ctx, a, params)
+	}
+
+	// Step 4: Run the execution container, relaying pause, resume and cancel
+	// requests to it
+	progress.EnterStep(models.ProgressStepRun, workflow.Now(ctx))
 	var containerResult temporal.RunContainerResult
-	err = workflow.ExecuteActivity(ctx, a.RunExecutionContainerActivity, preparedResult).Get(ctx, &containerResult)
+	containerCtx := withRetryPolicy(ctx, temporal.ContainerRetryPolicyFor(preparedResult.RetryPolicy))
+	containerCtx = workflow.WithStartToCloseTimeout(containerCtx, temporal.ContainerActivityTimeout(preparedResult.MaxDuration))
+	// A cancelled run waits for the activity to stop its container.
+	containerCtx = workflow.WithWaitForCancellation(containerCtx, true)
+	containerCtx, stopContainer := workflow.WithCancel(containerCtx)
+	containerFuture := workflow.ExecuteActivity(containerCtx, a.RunExecutionContainerActivity, preparedResult)
+	cancelled := relayControlSignals(ctx, a, preparedResult, containerFuture, stopContainer)
+	err = containerFuture.Get(ctx, &containerResult)
+	if err != nil && cancelled {
+		capturePartialState(ctx, a, preparedResult)
+		return markCancelled(ctx, a, params)
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Failed to run execution container: %v", err)
-		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, "failed", msg, "").Get(ctx, nil)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
 		logger.Error("Execution container execution failed.", "error", err)
+		capturePartialState(ctx, a, preparedResult)
 		return err
 	}
 
-	// Step 4: Handle the completion logic
+	// Step 5: Handle the completion logic
 	err = workflow.ExecuteActivity(ctx, a.HandleCompletionActivity, containerResult).Get(ctx, nil)
 	if err != nil {
 		// The completion handler itself failed, which is a critical error.
 		msg := fmt.Sprintf("Failed during post-execution processing: %v", err)
-		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, "failed", msg, containerResult.Logs).Get(ctx, nil)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, containerResult.Logs).Get(ctx, nil)
 		logger.Error("Execution completion handling failed.", "error", err)
 		return err
 	}
 
+	// Step 6: On a failed run, record how much data reached the destination
+	if containerResult.ExitCode != 0 {
+		capturePartialState(ctx, a, preparedResult)
+	}
+
+	// Step 7: Compare a successful run's destination with its source, if the
+	// definition asks for it
+	if containerResult.ExitCode == 0 && preparedResult.Verification != "" && preparedResult.Verification != models.VerificationOff {
+		if err := verifyExecution(ctx, a, preparedResult, containerResult.Logs); err != nil {
+			return err
+		}
+	}
+
 	logger.Info("Execution workflow completed successfully.", "ExecutionID", params.ExecutionID)
 	return nil
 }
+
+// relayControlSignals forwards pause and resume signals to the engine container
+// until the container activity completes, and cancels the activity through stop on
+// a cancel signal. Repeated signals for the current state are ignored. It reports
+// whether the run was cancelled.
+func relayControlSignals(ctx workflow.Context, a *activities.Activities, prepared temporal.PrepareActivityResult, container workflow.Future, stop workflow.CancelFunc) bool {
+	logger := workflow.GetLogger(ctx)
+	pauseCh := workflow.GetSignalChannel(ctx, temporal.PauseSignalName)
+	resumeCh := workflow.GetSignalChannel(ctx, temporal.ResumeSignalName)
+	cancelCh := workflow.GetSignalChannel(ctx, temporal.CancelSignalName)
+	controlCtx := withRetryPolicy(ctx, temporal.DatabaseRetryPolicy)
+
+	paused, cancelled, done := false, false, false
+	setPaused := func(want bool) {
+		if want == paused {
+			return
+		}
+		err := workflow.ExecuteActivity(controlCtx, a.SetExecutionPausedActivity, prepared, want).Get(controlCtx, nil)
+		if err != nil {
+			logger.Error("Failed to change execution pause state.", "ExecutionID", prepared.ExecutionID, "paused", want, "error", err)
+			return
+		}
+		paused = want
+	}
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddFuture(container, func(workflow.Future) { done = true })
+	selector.AddReceive(pauseCh, func(c workflow.ReceiveChannel, _ bool) {
+		c.Receive(ctx, nil)
+		setPaused(true)
+	})
+	selector.AddReceive(resumeCh, func(c workflow.ReceiveChannel, _ bool) {
+		c.Receive(ctx, nil)
+		setPaused(false)
+	})
+	selector.AddReceive(cancelCh, func(c workflow.ReceiveChannel, _ bool) {
+		c.Receive(ctx, nil)
+		if !cancelled {
+			logger.Info("Cancelling execution container.", "ExecutionID", prepared.ExecutionID)
+			cancelled = true
+			stop()
+		}
+	})
+	for !done {
+		selector.Select(ctx)
+	}
+	return cancelled
+}
+
+// cancelRequested reports, without blocking, whether a cancel signal has arrived.
+func cancelRequested(ctx workflow.Context) bool {
+	return workflow.GetSignalChannel(ctx, temporal.CancelSignalName).ReceiveAsync(nil)
+}
+
+// markCancelled settles a run that was cancelled on request.
+func markCancelled(ctx workflow.Context, a *activities.Activities, params temporal.ExecutionParams) error {
+	workflow.GetLogger(ctx).Info("Execution cancelled.", "ExecutionID", params.ExecutionID)
+	return workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID,
+		models.ExecutionStatusCancelled, "Execution was cancelled on request.", "").Get(ctx, nil)
+}
+
+// finalizeExecution fails the execution if the workflow ended with err while the
+// execution was still pending, running or paused.
+func finalizeExecution(ctx workflow.Context, a *activities.Activities, params temporal.ExecutionParams, err error) {
+	finalCtx, _ := workflow.NewDisconnectedContext(ctx)
+	finalCtx = withRetryPolicy(finalCtx, temporal.DatabaseRetryPolicy)
+	ferr := workflow.ExecuteActivity(finalCtx, a.FinalizeExecutionActivity, params.TenantID, params.ExecutionID, terminalMessage(err)).Get(finalCtx, nil)
+	if ferr != nil {
+		workflow.GetLogger(ctx).Error("Failed to finalize execution.", "ExecutionID", params.ExecutionID, "error", ferr)
+	}
+}
+
+// deleteExecutionConfig drops the prepared config, which holds credentials, from
+// the blob store. It runs on a new context so it also runs when the workflow is
+// cancelled.
+func deleteExecutionConfig(ctx workflow.Context, a *activities.Activities, prepared temporal.PrepareActivityResult) {
+	logger := workflow.GetLogger(ctx)
+	if prepared.ConfigBlob != "" {
+		cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
+		cleanupCtx = withRetryPolicy(cleanupCtx, temporal.BestEffortRetryPolicy)
+		err := workflow.ExecuteActivity(cleanupCtx, a.DeleteExecutionConfigActivity, prepared.ConfigBlob).Get(cleanupCtx, nil)
+		if err != nil {
+			logger.Error("Failed to delete execution config.", "blob", prepared.ConfigBlob, "error", err)
+		}
+	}
+	// Executions prepared before the blob store wrote a temp file instead.
+	if prepared.ASTFilePath != "" {
+		cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
+		cleanupCtx = withRetryPolicy(cleanupCtx, temporal.BestEffortRetryPolicy)
+		err := workflow.ExecuteActivity(cleanupCtx, a.CleanupActivity, prepared.ASTFilePath).Get(cleanupCtx, nil)
+		if err != nil {
+			logger.Error("Failed to cleanup temporary AST file.", "path", prepared.ASTFilePath, "error", err)
+		}
+	}
+}
+
+// terminalMessage describes why the workflow ended with err.
+func terminalMessage(err error) string {
+	switch {
+	case sdktemporal.IsCanceledError(err):
+		return "Execution was cancelled."
+	case sdktemporal.IsTimeoutError(err):
+		return fmt.Sprintf("Execution timed out: %v", err)
+	default:
+		return fmt.Sprintf("Execution workflow failed: %v", err)
+	}
+}
+
+// capturePartialState records the destination row counts after a failed run. It is
+// best effort: a failed capture never changes the workflow outcome.
+func capturePartialState(ctx workflow.Context, a *activities.Activities, prepared temporal.PrepareActivityResult) {
+	ctx = withRetryPolicy(ctx, temporal.BestEffortRetryPolicy)
+	err := workflow.ExecuteActivity(ctx, a.CapturePartialStateActivity, prepared).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to capture partial destination state.", "ExecutionID", prepared.ExecutionID, "error", err)
+	}
+}
+
+// verifyExecution compares the destination of a succeeded run with its source and
+// applies the definition's verification mode to a problem: warn flags the execution
+// suspect, fail fails it. A comparison that could not be made counts as a problem.
+func verifyExecution(ctx workflow.Context, a *activities.Activities, prepared temporal.PrepareActivityResult, logs string) error {
+	logger := workflow.GetLogger(ctx)
+	verifyCtx := withRetryPolicy(ctx, temporal.VerificationRetryPolicy)
+	verifyCtx = workflow.WithStartToCloseTimeout(verifyCtx, temporal.VerificationActivityTimeout)
+
+	var problem string
+	var report *models.VerificationReport
+	if err := workflow.ExecuteActivity(verifyCtx, a.VerifyExecutionActivity, prepared).Get(verifyCtx, &report); err != nil {
+		problem = fmt.Sprintf("verification could not run: %v", err)
+	} else if report == nil {
+		return nil
+	} else {
+		problem = report.Problem()
+	}
+	if problem == "" {
+		logger.Info("Execution verified.", "ExecutionID", prepared.ExecutionID)
+		return nil
+	}
+
+	if prepared.Verification != models.VerificationFail {
+		logger.Warn("Execution did not verify.", "ExecutionID", prepared.ExecutionID, "problem", problem)
+		err := workflow.ExecuteActivity(ctx, a.FlagUnverifiedExecutionActivity, prepared.TenantID, prepared.ExecutionID, problem).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to flag unverified execution.", "ExecutionID", prepared.ExecutionID, "error", err)
+		}
+		return nil
+	}
+	msg := "Post-migration verification failed: " + problem
+	workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, prepared.TenantID, prepared.ExecutionID, models.ExecutionStatusFailed, msg, logs).Get(ctx, nil)
+	logger.Error("Execution failed verification.", "ExecutionID", prepared.ExecutionID, "problem", problem)
+	return sdktemporal.NewNonRetryableApplicationError(msg, temporal.ErrTypeVerificationFailed, nil)
+}
@@ -0,0 +1,83 @@
+package workflows
+
+import (
+	"fmt"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	sdktemporal "go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// RollbackWorkflow undoes what the execution params.RollbackOfExecutionID wrote to
+// its destination. The rollback is an execution of its own, with its own record,
+// status and notifications: it is prepared like a replay of the rolled back
+// execution, then the engine drops the destination objects that execution created
+// and restores the ones it replaced.
+func RollbackWorkflow(ctx workflow.Context, params temporal.ExecutionParams) (err error) {
+	ctx = withRetryPolicy(ctx, temporal.DatabaseRetryPolicy)
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting rollback workflow", "TenantID", params.TenantID, "ExecutionID", params.ExecutionID, "RolledBackExecutionID", params.RollbackOfExecutionID)
+
+	var a *activities.Activities
+
+	// As for migrations, the execution must not stay pending or running.
+	defer func() {
+		if err != nil {
+			finalizeExecution(ctx, a, params, err)
+		}
+	}()
+
+	var prepared temporal.PrepareActivityResult
+	defer func() {
+		deleteExecutionConfig(ctx, a, prepared)
+	}()
+
+	err = workflow.ExecuteActivity(ctx, a.CreateExecutionActivity, params.TenantID, params.JobDefinitionID, params.ExecutionID,
+		params.TriggeredBy, params.TriggerContext, params.AllowConcurrent).Get(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to create rollback execution record.", "error", err)
+		return err
+	}
+
+	err = workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusRunning, "", "").Get(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to update rollback status to running.", "error", err)
+		return err
+	}
+
+	prepareCtx := withRetryPolicy(ctx, temporal.PrepareRetryPolicy)
+	err = workflow.ExecuteActivity(prepareCtx, a.PrepareExecutionActivity, params).Get(prepareCtx, &prepared)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to prepare rollback: %v", err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		logger.Error("Rollback preparation failed.", "error", err)
+		return err
+	}
+
+	rollbackCtx := withRetryPolicy(ctx, temporal.RollbackRetryPolicy)
+	rollbackCtx = workflow.WithStartToCloseTimeout(rollbackCtx, temporal.RollbackActivityTimeout)
+	var report models.RollbackReport
+	err = workflow.ExecuteActivity(rollbackCtx, a.RollbackExecutionActivity, prepared, params.RollbackOfExecutionID).Get(rollbackCtx, &report)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to roll back execution %s: %v", params.RollbackOfExecutionID, err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		logger.Error("Rollback failed.", "error", err)
+		return err
+	}
+	if problem := report.Problem(); problem != "" {
+		msg := fmt.Sprintf("Rollback of execution %s incomplete: %s", params.RollbackOfExecutionID, problem)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		logger.Error("Rollback incomplete.", "ExecutionID", params.ExecutionID, "problem", problem)
+		return sdktemporal.NewNonRetryableApplicationError(msg, temporal.ErrTypeRollbackFailed, nil)
+	}
+
+	err = workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusSucceeded, "", "").Get(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to update rollback status to succeeded.", "error", err)
+		return err
+	}
+	logger.Info("Rollback workflow completed successfully.", "ExecutionID", params.ExecutionID)
+	return nil
+}
@@ -0,0 +1,81 @@
+package workflows
+
+import (
+	"fmt"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+// RunGroupWorkflow runs the members of a run group as child execution workflows,
+// at most params.Parallelism at a time. With StopOnFailure, members not started yet
+// are skipped once a member did not succeed. When every member has finished, the
+// group's report is recorded and the tenant notified.
+func RunGroupWorkflow(ctx workflow.Context, params temporal.RunGroupParams) error {
+	ctx = withRetryPolicy(ctx, temporal.DatabaseRetryPolicy)
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting run group workflow", "TenantID", params.TenantID, "GroupID", params.GroupID, "Members", len(params.Members))
+
+	var a *activities.Activities
+	parallelism := max(params.Parallelism, 1)
+	selector := workflow.NewSelector(ctx)
+	running, next, failed := 0, 0, false
+
+	start := func(member temporal.ExecutionParams) {
+		// Members keep the workflow ID of a standalone run, so they are paused and
+		// cancelled like any other execution. They outlive a terminated group.
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID:        temporal.ExecWorkflowIDPrefix + member.ExecutionID,
+			TaskQueue:         temporal.TaskQueueFor(member.Priority),
+			ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+		})
+		child := workflow.ExecuteChildWorkflow(childCtx, ExecutionWorkflow, member)
+		running++
+		selector.AddFuture(child, func(f workflow.Future) {
+			running--
+			// The execution workflow also ends without error when the engine run
+			// failed; the recorded status is what counts.
+			var status models.ExecutionStatus
+			if err := f.Get(ctx, nil); err != nil {
+				logger.Warn("Run group member workflow failed.", "ExecutionID", member.ExecutionID, "error", err)
+				failed = true
+				return
+			}
+			if err := workflow.ExecuteActivity(ctx, a.ExecutionStatusActivity, member.TenantID, member.ExecutionID).Get(ctx, &status); err != nil {
+				logger.Error("Failed to load run group member status.", "ExecutionID", member.ExecutionID, "error", err)
+				failed = true
+				return
+			}
+			if status != models.ExecutionStatusSucceeded {
+				failed = true
+			}
+		})
+	}
+
+	for {
+		for running < parallelism && next < len(params.Members) && !(failed && params.StopOnFailure) {
+			start(params.Members[next])
+			next++
+		}
+		if running == 0 {
+			break
+		}
+		selector.Select(ctx)
+	}
+
+	if next < len(params.Members) {
+		skipped := make([]string, 0, len(params.Members)-next)
+		for _, member := range params.Members[next:] {
+			skipped = append(skipped, member.ExecutionID)
+		}
+		logger.Info("Skipping run group members after a failure.", "GroupID", params.GroupID, "Skipped", len(skipped))
+		if err := workflow.ExecuteActivity(ctx, a.SkipRunGroupMembersActivity, params.TenantID, params.GroupID, skipped).Get(ctx, nil); err != nil {
+			return fmt.Errorf("skip run group members: %w", err)
+		}
+	}
+
+	return workflow.ExecuteActivity(ctx, a.CompleteRunGroupActivity, params.TenantID, params.GroupID).Get(ctx, nil)
+}
@@ -0,0 +1,113 @@
+package workflows
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	sdktemporal "go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// waitForSensors probes the definition's sensors until each has passed or timed
+// out. It reports whether the execution should go ahead. A sensor that times out
+// skips the execution, fails it, or is ignored, as its on_timeout says; failing
+// wins over skipping. Each sensor is probed on its own poke interval.
+func waitForSensors(ctx workflow.Context, a *activities.Activities, params temporal.ExecutionParams) (bool, error) {
+	logger := workflow.GetLogger(ctx)
+
+	var sensors []models.JobSensor
+	if err := workflow.ExecuteActivity(ctx, a.LoadSensorsActivity, params.TenantID, params.JobDefinitionID).Get(ctx, &sensors); err != nil {
+		msg := fmt.Sprintf("Failed to load sensors: %v", err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		return false, err
+	}
+	if len(sensors) == 0 {
+		return true, nil
+	}
+
+	started := workflow.Now(ctx)
+	waiting := make([]pendingSensor, 0, len(sensors))
+	for _, sensor := range sensors {
+		waiting = append(waiting, pendingSensor{sensor: sensor, deadline: started.Add(sensor.Timeout()), nextAt: started})
+	}
+	var skipped, failed []string
+	for len(waiting) > 0 {
+		now := workflow.Now(ctx)
+		still := waiting[:0]
+		for _, p := range waiting {
+			if now.Before(p.nextAt) {
+				still = append(still, p)
+				continue
+			}
+			var result temporal.SensorCheckResult
+			err := workflow.ExecuteActivity(ctx, a.EvaluateSensorActivity, params.TenantID, params.ExecutionID, p.sensor).Get(ctx, &result)
+			if err != nil {
+				msg := fmt.Sprintf("Failed to evaluate sensor %s: %v", p.sensor.Name, err)
+				workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+				return false, err
+			}
+			if result.Passed {
+				continue
+			}
+			if checked := workflow.Now(ctx); checked.Before(p.deadline) {
+				p.nextAt = checked.Add(p.sensor.PokeInterval())
+				if p.nextAt.After(p.deadline) {
+					p.nextAt = p.deadline
+				}
+				still = append(still, p)
+				continue
+			}
+			label := fmt.Sprintf("%s (%s)", p.sensor.Name, result.Detail)
+			switch p.sensor.OnTimeout {
+			case models.SensorTimeoutSkip:
+				skipped = append(skipped, label)
+			case models.SensorTimeoutRun:
+				logger.Warn("Sensor timed out, running anyway.", "sensor", p.sensor.Name, "detail", result.Detail)
+			default:
+				failed = append(failed, label)
+			}
+		}
+		waiting = still
+		if len(waiting) == 0 {
+			break
+		}
+		next := waiting[0].nextAt
+		for _, p := range waiting[1:] {
+			if p.nextAt.Before(next) {
+				next = p.nextAt
+			}
+		}
+		if wait := next.Sub(workflow.Now(ctx)); wait > 0 {
+			if err := workflow.Sleep(ctx, wait); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		msg := "Sensors timed out: " + strings.Join(failed, "; ")
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		return false, sdktemporal.NewNonRetryableApplicationError(msg, temporal.ErrTypeSensorTimeout, nil)
+	}
+	if len(skipped) > 0 {
+		msg := "Skipped, sensors timed out: " + strings.Join(skipped, "; ")
+		if err := workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusSkipped, msg, "").Get(ctx, nil); err != nil {
+			return false, err
+		}
+		logger.Info("Execution skipped.", "ExecutionID", params.ExecutionID, "reason", msg)
+		return false, nil
+	}
+	return true, nil
+}
+
+// pendingSensor is a sensor that has not passed yet, with when it is probed next
+// and when it times out.
+type pendingSensor struct {
+	sensor   models.JobSensor
+	nextAt   time.Time
+	deadline time.Time
+}
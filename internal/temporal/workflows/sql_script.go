@@ -0,0 +1,43 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	"go.temporal.io/sdk/workflow"
+)
+
+// runSQLScript executes a sql_script definition in place of the engine steps. The
+// run shares the execution record, notifications and completion checks of engine
+// runs.
+func runSQLScript(ctx workflow.Context, a *activities.Activities, params temporal.ExecutionParams) error {
+	logger := workflow.GetLogger(ctx)
+
+	scriptCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		// The script enforces its own timeout; this only bounds a lost worker.
+		StartToCloseTimeout: models.MaxSQLScriptTimeout + time.Minute,
+		RetryPolicy:         temporal.SQLScriptRetryPolicy,
+	})
+	var result temporal.RunContainerResult
+	err := workflow.ExecuteActivity(scriptCtx, a.RunSQLScriptActivity, params).Get(scriptCtx, &result)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to run SQL script: %v", err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, "").Get(ctx, nil)
+		logger.Error("SQL script run failed.", "error", err)
+		return err
+	}
+
+	err = workflow.ExecuteActivity(ctx, a.HandleCompletionActivity, result).Get(ctx, nil)
+	if err != nil {
+		msg := fmt.Sprintf("Failed during post-execution processing: %v", err)
+		workflow.ExecuteActivity(ctx, a.UpdateJobStatusActivity, params.TenantID, params.ExecutionID, models.ExecutionStatusFailed, msg, result.Logs).Get(ctx, nil)
+		logger.Error("Execution completion handling failed.", "error", err)
+		return err
+	}
+
+	logger.Info("SQL script workflow completed successfully.", "ExecutionID", params.ExecutionID)
+	return nil
+}
@@ -0,0 +1,30 @@
+package workflows
+
+import (
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/temporal/activities"
+	"go.temporal.io/sdk/workflow"
+)
+
+// TenantPurgeWorkflow waits out a deleted tenant's grace period and then purges its
+// data. Restoring the tenant cancels the workflow; the purge activity also re-checks
+// that the tenant is still scheduled, so a missed cancellation is harmless.
+func TenantPurgeWorkflow(ctx workflow.Context, tenantID string, purgeAfter time.Time) error {
+	logger := workflow.GetLogger(ctx)
+
+	if wait := purgeAfter.Sub(workflow.Now(ctx)); wait > 0 {
+		logger.Info("Waiting for tenant deletion grace period", "TenantID", tenantID, "PurgeAfter", purgeAfter)
+		if err := workflow.Sleep(ctx, wait); err != nil {
+			logger.Info("Tenant purge cancelled", "TenantID", tenantID)
+			return err
+		}
+	}
+
+	ao := workflow.ActivityOptions{StartToCloseTimeout: temporal.DefaultActivityTimeout}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	var a *activities.Activities
+	return workflow.ExecuteActivity(ctx, a.PurgeTenantActivity, tenantID).Get(ctx, nil)
+}
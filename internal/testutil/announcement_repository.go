@@ -0,0 +1,171 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type announcementRepository struct {
+	s *Store
+}
+
+// maintenanceCompleteFor is how long the notice posted when maintenance ends stays up.
+const maintenanceCompleteFor = time.Hour
+
+func (r *announcementRepository) ListAnnouncements(tenantID string) ([]models.Announcement, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	announcements := []models.Announcement{}
+	for _, a := range r.s.announcements {
+		if tenantID == "" || (a.TenantID != nil && *a.TenantID == tenantID) {
+			announcements = append(announcements, a)
+		}
+	}
+	sort.Slice(announcements, func(i, j int) bool { return announcements[i].StartsAt.After(announcements[j].StartsAt) })
+	return announcements, nil
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case models.AnnouncementCritical:
+		return 0
+	case models.AnnouncementWarning:
+		return 1
+	}
+	return 2
+}
+
+func (r *announcementRepository) ActiveAnnouncements(tenantID string) ([]models.Announcement, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	announcements := []models.Announcement{}
+	for _, a := range r.s.announcements {
+		if a.TenantID != nil && *a.TenantID != tenantID {
+			continue
+		}
+		if a.StartsAt.After(now) || (a.EndsAt != nil && !a.EndsAt.After(now)) {
+			continue
+		}
+		announcements = append(announcements, a)
+	}
+	sort.Slice(announcements, func(i, j int) bool {
+		a, b := announcements[i], announcements[j]
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		return a.StartsAt.After(b.StartsAt)
+	})
+	return announcements, nil
+}
+
+func (r *announcementRepository) GetAnnouncement(announcementID string) (models.Announcement, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, ok := r.s.announcements[announcementID]
+	if !ok {
+		return models.Announcement{}, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+func (r *announcementRepository) CreateAnnouncement(a models.Announcement) (models.Announcement, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.createAnnouncement(a), nil
+}
+
+// createAnnouncement stores an announcement with the repository's defaults. Callers
+// hold s.mu.
+func (s *Store) createAnnouncement(a models.Announcement) models.Announcement {
+	if a.StartsAt.IsZero() {
+		a.StartsAt = time.Now()
+	}
+	if a.Source == "" {
+		a.Source = models.AnnouncementSourceManual
+	}
+	now := s.now()
+	a.ID, a.CreatedAt, a.UpdatedAt, a.Global = newID(), now, now, a.TenantID == nil
+	s.announcements[a.ID] = a
+	return a
+}
+
+func (r *announcementRepository) UpdateAnnouncement(a models.Announcement) (models.Announcement, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.announcements[a.ID]
+	if !ok {
+		return models.Announcement{}, sql.ErrNoRows
+	}
+	current.Message, current.Severity, current.StartsAt, current.EndsAt = a.Message, a.Severity, a.StartsAt, a.EndsAt
+	current.UpdatedAt = r.s.now()
+	r.s.announcements[a.ID] = current
+	return current, nil
+}
+
+func (r *announcementRepository) DeleteAnnouncement(announcementID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.announcements[announcementID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.s.announcements, announcementID)
+	return nil
+}
+
+func (r *announcementRepository) GetMaintenanceMode() (models.MaintenanceMode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.maintenance, nil
+}
+
+func (r *announcementRepository) SetMaintenanceMode(enabled bool, message, completeMessage string, updatedBy *string) (models.MaintenanceMode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current := r.s.maintenance
+	announcementID := current.AnnouncementID
+	switch {
+	case enabled && current.Enabled && announcementID != nil:
+		// Already in maintenance: only the message changes.
+		if a, ok := r.s.announcements[*announcementID]; ok {
+			a.Message, a.UpdatedAt = message, r.s.now()
+			r.s.announcements[a.ID] = a
+		}
+	case enabled:
+		a := r.s.createAnnouncement(models.Announcement{
+			Message:   message,
+			Severity:  models.AnnouncementCritical,
+			Source:    models.AnnouncementSourceMaintenance,
+			CreatedBy: updatedBy,
+		})
+		announcementID = &a.ID
+	case !enabled && current.Enabled:
+		if announcementID != nil {
+			if a, ok := r.s.announcements[*announcementID]; ok && (a.EndsAt == nil || a.EndsAt.After(time.Now())) {
+				endsAt := time.Now()
+				a.EndsAt, a.UpdatedAt = &endsAt, r.s.now()
+				r.s.announcements[a.ID] = a
+			}
+		}
+		endsAt := time.Now().Add(maintenanceCompleteFor)
+		r.s.createAnnouncement(models.Announcement{
+			Message:   completeMessage,
+			Severity:  models.AnnouncementInfo,
+			EndsAt:    &endsAt,
+			Source:    models.AnnouncementSourceMaintenance,
+			CreatedBy: updatedBy,
+		})
+		announcementID = nil
+	}
+	r.s.maintenance = models.MaintenanceMode{
+		Enabled:        enabled,
+		Message:        message,
+		AnnouncementID: announcementID,
+		UpdatedBy:      updatedBy,
+		UpdatedAt:      r.s.now(),
+	}
+	return r.s.maintenance, nil
+}
@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type apiKeyRepository struct {
+	s *Store
+}
+
+func (r *apiKeyRepository) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if key.CreatedBy != nil && *key.CreatedBy == "" {
+		key.CreatedBy = nil
+	}
+	key.ID, key.CreatedAt = newID(), r.s.now()
+	key.LastUsedAt, key.RevokedAt = nil, nil
+	r.s.apiKeys[key.ID] = key
+	return key, nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(tenantID string) ([]models.APIKey, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var keys []models.APIKey
+	for _, key := range r.s.apiKeys {
+		if key.TenantID == tenantID && key.RevokedAt == nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(tenantID, keyID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key, ok := r.s.apiKeys[keyID]
+	if !ok || key.TenantID != tenantID || key.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	key.RevokedAt = &now
+	r.s.apiKeys[keyID] = key
+	return nil
+}
+
+func (r *apiKeyRepository) AuthenticateAPIKey(keyHash string) (models.APIKey, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, key := range r.s.apiKeys {
+		if key.KeyHash != keyHash || key.RevokedAt != nil {
+			continue
+		}
+		if tenant, ok := r.s.tenants[key.TenantID]; !ok || tenant.SuspendedAt != nil {
+			continue
+		}
+		now := r.s.now()
+		key.LastUsedAt = &now
+		r.s.apiKeys[id] = key
+		return key, nil
+	}
+	return models.APIKey{}, sql.ErrNoRows
+}
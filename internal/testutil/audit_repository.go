@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type auditRepository struct {
+	s *Store
+}
+
+func (r *auditRepository) RecordEvent(evt models.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	evt.ID, evt.CreatedAt = newID(), r.s.now()
+	r.s.auditEvents = append(r.s.auditEvents, evt)
+	return nil
+}
+
+func (r *auditRepository) RecordAccessLogs(entries []models.AccessLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.accessLogs = append(r.s.accessLogs, entries...)
+	return nil
+}
+
+func (r *auditRepository) EachAuditEvent(tenantID string, from, to time.Time, actions []string, fn func(json.RawMessage) error) error {
+	r.s.mu.Lock()
+	var rows []interface{}
+	for _, evt := range r.s.auditEvents {
+		if !inTenant(evt.TenantID, tenantID) || !inWindow(evt.CreatedAt, from, to) || !hasAction(actions, evt.Action) {
+			continue
+		}
+		rows = append(rows, evt)
+	}
+	r.s.mu.Unlock()
+	return eachJSON(rows, fn)
+}
+
+func (r *auditRepository) EachAccessLog(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error {
+	r.s.mu.Lock()
+	var rows []interface{}
+	for _, entry := range r.s.accessLogs {
+		if inTenant(entry.TenantID, tenantID) && inWindow(entry.CreatedAt, from, to) {
+			rows = append(rows, entry)
+		}
+	}
+	r.s.mu.Unlock()
+	return eachJSON(rows, fn)
+}
+
+func (r *auditRepository) EachExecutionSnapshot(tenantID string, from, to time.Time, fn func(json.RawMessage) error) error {
+	r.s.mu.Lock()
+	var rows []interface{}
+	for _, snapshot := range r.s.snapshots {
+		if (tenantID == "" || snapshot.TenantID == tenantID) && inWindow(snapshot.CreatedAt, from, to) {
+			rows = append(rows, snapshot)
+		}
+	}
+	r.s.mu.Unlock()
+	return eachJSON(rows, fn)
+}
+
+func inTenant(rowTenantID *string, tenantID string) bool {
+	return tenantID == "" || (rowTenantID != nil && *rowTenantID == tenantID)
+}
+
+func inWindow(at, from, to time.Time) bool {
+	return !at.Before(from) && at.Before(to)
+}
+
+func hasAction(actions []string, action string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// eachJSON calls fn with every row encoded as JSON, stopping at the first error.
+func eachJSON(rows []interface{}, fn func(json.RawMessage) error) error {
+	for _, row := range rows {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
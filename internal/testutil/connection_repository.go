@@ -0,0 +1,182 @@
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type connectionRepository struct {
+	s *Store
+}
+
+func (r *connectionRepository) List(tenantID string, filter repository.ConnectionFilter) ([]*models.Connection, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var conns []*models.Connection
+	for id, conn := range r.s.connections {
+		if conn.TenantID != tenantID || conn.Ephemeral || r.s.deletedConnections[id] {
+			continue
+		}
+		if filter.Environment != "" && conn.Environment != filter.Environment {
+			continue
+		}
+		if !hasTags(conn.Tags, filter.Tags) {
+			continue
+		}
+		c := cloneConnection(conn)
+		conns = append(conns, &c)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Name < conns[j].Name })
+	return conns, nil
+}
+
+func hasTags(tags, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *connectionRepository) Get(tenantID, id string) (*models.Connection, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	conn, ok := r.s.connections[id]
+	if !ok || conn.TenantID != tenantID || r.s.deletedConnections[id] {
+		return nil, sql.ErrNoRows
+	}
+	c := cloneConnection(conn)
+	return &c, nil
+}
+
+func (r *connectionRepository) Create(conn *models.Connection) (*models.Connection, error) {
+	normalizeCredentialMode(conn)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	conn.ID, conn.CreatedAt, conn.UpdatedAt = newID(), now, now
+	if conn.Status == "" {
+		conn.Status = models.ConnectionStatusUntested
+	}
+	r.s.connections[conn.ID] = cloneConnection(*conn)
+	return conn, nil
+}
+
+func (r *connectionRepository) Update(conn *models.Connection) (*models.Connection, error) {
+	normalizeCredentialMode(conn)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.connections[conn.ID]
+	if !ok || current.TenantID != conn.TenantID || current.Ephemeral || r.s.deletedConnections[conn.ID] {
+		return conn, sql.ErrNoRows
+	}
+	conn.CreatedAt, conn.UpdatedAt = current.CreatedAt, r.s.now()
+	conn.Benchmark, conn.ServerVersion = current.Benchmark, current.ServerVersion
+	r.s.connections[conn.ID] = cloneConnection(*conn)
+	return conn, nil
+}
+
+// normalizeCredentialMode mirrors the repository: the credential mode defaults to
+// stored and prompt-mode connections never keep a password.
+func normalizeCredentialMode(conn *models.Connection) {
+	if conn.CredentialMode == "" {
+		conn.CredentialMode = models.CredentialModeStored
+	}
+	if conn.PromptsForCredentials() {
+		conn.Password = ""
+	}
+	if conn.Tags == nil {
+		conn.Tags = []string{}
+	}
+}
+
+func (r *connectionRepository) Delete(tenantID, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	conn, ok := r.s.connections[id]
+	if !ok || conn.TenantID != tenantID || conn.Ephemeral || r.s.deletedConnections[id] {
+		return sql.ErrNoRows
+	}
+	conn.UpdatedAt = r.s.now()
+	r.s.connections[id] = conn
+	r.s.deletedConnections[id] = true
+	return nil
+}
+
+func (r *connectionRepository) DeleteEphemeral(tenantID, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if conn, ok := r.s.connections[id]; ok && conn.TenantID == tenantID && conn.Ephemeral {
+		conn.UpdatedAt = r.s.now()
+		r.s.connections[id] = conn
+		r.s.deletedConnections[id] = true
+	}
+	return nil
+}
+
+func (r *connectionRepository) SaveBenchmark(tenantID, id string, benchmark models.ConnectionBenchmark) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	conn, ok := r.s.connections[id]
+	if !ok || conn.TenantID != tenantID || r.s.deletedConnections[id] {
+		return sql.ErrNoRows
+	}
+	conn.Benchmark = &benchmark
+	r.s.connections[id] = conn
+	return nil
+}
+
+func (r *connectionRepository) SaveServerVersion(tenantID, id, version string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	conn, ok := r.s.connections[id]
+	if !ok || conn.TenantID != tenantID || r.s.deletedConnections[id] {
+		return sql.ErrNoRows
+	}
+	conn.ServerVersion = version
+	r.s.connections[id] = conn
+	return nil
+}
+
+func (r *connectionRepository) ListVersion(tenantID string) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count, updated int64
+	for id, conn := range r.s.connections {
+		if conn.TenantID != tenantID || conn.Ephemeral {
+			continue
+		}
+		if !r.s.deletedConnections[id] {
+			count++
+		}
+		if ts := conn.UpdatedAt.UnixNano(); ts > updated {
+			updated = ts
+		}
+	}
+	return fmt.Sprintf("%d-%d", count, updated), nil
+}
+
+func cloneConnection(conn models.Connection) models.Connection {
+	conn.Tags = append([]string{}, conn.Tags...)
+	if conn.Benchmark != nil {
+		benchmark := *conn.Benchmark
+		conn.Benchmark = &benchmark
+	}
+	if conn.Options.AllowNativePasswords != nil {
+		allow := *conn.Options.AllowNativePasswords
+		conn.Options.AllowNativePasswords = &allow
+	}
+	return conn
+}
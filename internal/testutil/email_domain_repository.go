@@ -0,0 +1,165 @@
+package testutil
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type emailDomainRepository struct {
+	s *Store
+}
+
+func (r *emailDomainRepository) CreateDomain(domain models.EmailDomain) (models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.domains {
+		if existing.TenantID == domain.TenantID && existing.Domain == domain.Domain {
+			return models.EmailDomain{}, errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	now := r.s.now()
+	domain.ID, domain.CreatedAt, domain.UpdatedAt, domain.VerifiedAt = newID(), now, now, nil
+	r.s.domains[domain.ID] = domain
+	return domain, nil
+}
+
+func (r *emailDomainRepository) ListDomains(tenantID string) ([]models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	domains := []models.EmailDomain{}
+	for _, domain := range r.s.domains {
+		if domain.TenantID == tenantID {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains, nil
+}
+
+func (r *emailDomainRepository) GetDomain(tenantID, domainID string) (models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	domain, ok := r.s.domains[domainID]
+	if !ok || domain.TenantID != tenantID {
+		return models.EmailDomain{}, sql.ErrNoRows
+	}
+	return domain, nil
+}
+
+func (r *emailDomainRepository) MarkDomainVerified(tenantID, domainID string) (models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	domain, ok := r.s.domains[domainID]
+	if !ok || domain.TenantID != tenantID {
+		return models.EmailDomain{}, sql.ErrNoRows
+	}
+	for id, other := range r.s.domains {
+		if id != domainID && other.Domain == domain.Domain && other.VerifiedAt != nil {
+			return models.EmailDomain{}, errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	now := r.s.now()
+	if domain.VerifiedAt == nil {
+		domain.VerifiedAt = &now
+	}
+	domain.UpdatedAt = now
+	r.s.domains[domainID] = domain
+	return domain, nil
+}
+
+func (r *emailDomainRepository) SetDomainApproval(tenantID, domainID string, requireApproval bool) (models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	domain, ok := r.s.domains[domainID]
+	if !ok || domain.TenantID != tenantID {
+		return models.EmailDomain{}, sql.ErrNoRows
+	}
+	domain.RequireApproval, domain.UpdatedAt = requireApproval, r.s.now()
+	r.s.domains[domainID] = domain
+	return domain, nil
+}
+
+func (r *emailDomainRepository) DeleteDomain(tenantID, domainID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	domain, ok := r.s.domains[domainID]
+	if !ok || domain.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	delete(r.s.domains, domainID)
+	return nil
+}
+
+func (r *emailDomainRepository) FindVerifiedDomain(name string) (models.EmailDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, domain := range r.s.domains {
+		if domain.Domain != name || domain.VerifiedAt == nil {
+			continue
+		}
+		if tenant, ok := r.s.tenants[domain.TenantID]; ok && tenant.SuspendedAt == nil {
+			return domain, nil
+		}
+	}
+	return models.EmailDomain{}, sql.ErrNoRows
+}
+
+func (r *emailDomainRepository) CreateJoinRequest(req models.DomainJoinRequest) (models.DomainJoinRequest, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	req.ID, req.CreatedAt = newID(), r.s.now()
+	r.s.joinRequests[req.ID] = req
+	return req, nil
+}
+
+// withEmail fills in the requesting user's email. Callers hold s.mu.
+func (s *Store) withEmail(req models.DomainJoinRequest) models.DomainJoinRequest {
+	req.Email = s.users[req.UserID].Email
+	return req
+}
+
+func (r *emailDomainRepository) ListJoinRequests(tenantID, status string) ([]models.DomainJoinRequest, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	requests := []models.DomainJoinRequest{}
+	for _, req := range r.s.joinRequests {
+		if req.TenantID == tenantID && (status == "" || req.Status == status) {
+			requests = append(requests, r.s.withEmail(req))
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.After(requests[j].CreatedAt) })
+	return requests, nil
+}
+
+func (r *emailDomainRepository) DecideJoinRequest(tenantID, requestID, status, decidedBy string) (models.DomainJoinRequest, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	req, ok := r.s.joinRequests[requestID]
+	if !ok || req.TenantID != tenantID || req.Status != "pending" {
+		return models.DomainJoinRequest{}, sql.ErrNoRows
+	}
+	now := r.s.now()
+	req.Status, req.DecidedBy, req.DecidedAt = status, &decidedBy, &now
+	r.s.joinRequests[requestID] = req
+	return r.s.withEmail(req), nil
+}
+
+func (r *emailDomainRepository) ActivateDomainMember(userID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok || user.IsActive || user.EmailVerifiedAt == nil {
+		return false, nil
+	}
+	for _, req := range r.s.joinRequests {
+		if req.UserID == userID && req.Status == "approved" {
+			user.IsActive = true
+			r.s.users[userID] = user
+			return true, nil
+		}
+	}
+	return false, nil
+}
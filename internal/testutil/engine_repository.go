@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type engineRepository struct {
+	s *Store
+}
+
+func (r *engineRepository) RecordEngineCapabilities(caps models.EngineCapabilities, current bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.engineVersions[caps.Version] = r.s.now()
+	r.s.engineFormats[caps.Version] = append([]models.FormatPair(nil), caps.Pairs...)
+	if current {
+		r.s.currentEngine = caps.Version
+	}
+	return nil
+}
+
+func (r *engineRepository) GetCompatibilityMatrix() (models.CompatibilityMatrix, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	matrix := models.CompatibilityMatrix{Entries: []models.EngineFormatSupport{}}
+	if at, ok := r.s.engineVersions[r.s.currentEngine]; ok {
+		matrix.CurrentVersion, matrix.DiscoveredAt = r.s.currentEngine, &at
+	}
+	for version := range r.s.engineVersions {
+		for _, pair := range r.s.engineFormats[version] {
+			matrix.Entries = append(matrix.Entries, models.EngineFormatSupport{
+				EngineVersion:     version,
+				SourceFormat:      pair.Source,
+				DestinationFormat: pair.Destination,
+			})
+		}
+	}
+	sort.Slice(matrix.Entries, func(i, j int) bool {
+		a, b := matrix.Entries[i], matrix.Entries[j]
+		if a.SourceFormat != b.SourceFormat {
+			return a.SourceFormat < b.SourceFormat
+		}
+		if a.DestinationFormat != b.DestinationFormat {
+			return a.DestinationFormat < b.DestinationFormat
+		}
+		return a.EngineVersion < b.EngineVersion
+	})
+	return matrix, nil
+}
@@ -0,0 +1,236 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/config"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/handlers"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/notification"
+	"github.com/stanstork/stratum-api/internal/passwords"
+	"github.com/stanstork/stratum-api/internal/routes"
+	"github.com/stanstork/stratum-api/internal/storage"
+	"github.com/stanstork/stratum-api/internal/temporal"
+	"github.com/stanstork/stratum-api/internal/webhook"
+)
+
+const harnessJWTSecret = "testutil-jwt-secret"
+
+// Harness serves the API router the way cmd/server wires it, with every repository
+// backed by Store, Temporal replaced by FakeTemporal and outgoing email captured by
+// Mailer. Docker-backed endpoints (connection tests, metadata, engine runs) are not
+// usable: the host pool is built from the environment but never reached.
+type Harness struct {
+	Config   *config.Config
+	Store    *Store
+	Temporal *FakeTemporal
+	Mailer   *Mailer
+	Router   *mux.Router
+
+	t testing.TB
+}
+
+// NewHarness builds a harness on an empty store. It sets the encryption keys the
+// handlers read from the environment for the duration of the test.
+func NewHarness(t testing.TB) *Harness {
+	t.Helper()
+	t.Setenv("STRATUM_ENC_KEY", randomKey(t))
+	t.Setenv("STRATUM_SECRETS_KEY", randomKey(t))
+
+	cfg := &config.Config{
+		JWTSecret: harnessJWTSecret,
+		Email: config.EmailConfig{
+			InviteURLTemplate: "http://localhost/invites/%s",
+			VerifyURLTemplate: "http://localhost/verify-email?token=%s",
+			InviteMaxAttempts: 3,
+		},
+		Tenants: config.TenantsConfig{DeletionGracePeriod: 30 * 24 * time.Hour},
+	}
+	logger := zerolog.Nop()
+
+	store := NewStore()
+	fakeTemporal := NewFakeTemporal()
+	mailer := &Mailer{}
+
+	hosts, err := engine.NewHostPool(config.DockerConfig{}, logger)
+	if err != nil {
+		t.Fatalf("build docker host pool: %v", err)
+	}
+
+	jobs := store.Jobs()
+	conns := store.Connections()
+	users := store.Users()
+	tenants := store.Tenants()
+	audit := store.Audit()
+	webhooks := store.Webhooks()
+	residency := storage.NewResidency(cfg.Storage, tenants)
+	policy := passwords.NewPolicy(cfg.Users.PasswordPolicy, logger)
+	notifications := notification.NewService(store.Notifications(), logger, webhook.NewExecutionNotifier(webhooks, jobs, logger))
+	delivery := notification.NewInviteDelivery(store.Invites(), tenants, mailer, cfg.Email.InviteURLTemplate, cfg.Email.InviteMaxAttempts, logger)
+	image := cfg.Worker.EngineImage
+
+	router := routes.NewRouter(
+		handlers.NewAuthHandlerWithRepositories(users, tenants, store.EmailDomains(), cfg, mailer, policy, logger),
+		handlers.NewJobHandler(jobs, conns, tenants, store.Templates(), audit, store.Engines(), fakeTemporal, notifications, residency, hosts, temporal.NewCredentialVault(temporal.DefaultCredentialTTL), nil, image, logger),
+		handlers.NewConnectionHandler(conns, jobs, fakeTemporal, hosts, image, logger),
+		handlers.NewMetadataHandler(conns, hosts, image, logger),
+		handlers.NewReportHandler(conns, jobs, hosts, image, logger),
+		handlers.NewTenantHandler(tenants, users, audit, webhooks, residency, fakeTemporal, cfg.Tenants.DeletionGracePeriod, policy, logger),
+		handlers.NewInviteHandler(store.Invites(), tenants, users, audit, webhooks, delivery, policy, logger),
+		handlers.NewNotificationHandler(notifications, logger),
+		handlers.NewAPIKeyHandler(store.APIKeys(), audit, logger),
+		handlers.NewGrafanaHandler(jobs, logger),
+		handlers.NewAdminHandler(context.Background(), nil, hosts, store.Registries(), store.Engines(), nil, image, nil, logger),
+		handlers.NewDomainHandler(store.EmailDomains(), users, audit, logger),
+		handlers.NewComplianceHandler(audit, nil, logger),
+		handlers.NewTemplateHandler(store.Templates(), conns, logger),
+		handlers.NewWebhookHandler(webhooks, audit, logger),
+		handlers.NewAnnouncementHandler(store.Announcements(), logger),
+		handlers.NewSensorHandler(store.Sensors(), conns, logger),
+		handlers.NewViewHandler(store.Views(), logger),
+		// No Docker daemon is reachable in tests, so setup skips the engine check.
+		handlers.NewSetupHandler(store.Instance(), audit, nil, nil, fakeTemporal, policy, logger),
+		handlers.NewMetricsHandler(tenants, cfg.Metrics, logger),
+		handlers.NewSCIMHandler(store.SCIMTokens(), users, audit, webhooks, policy, logger),
+	)
+
+	return &Harness{
+		Config:   cfg,
+		Store:    store,
+		Temporal: fakeTemporal,
+		Mailer:   mailer,
+		Router:   router,
+		t:        t,
+	}
+}
+
+func randomKey(t testing.TB) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// SeedInitialData adds what the initial migrations seed into a fresh database: the
+// default tenant with its admin and super-admin.
+func (h *Harness) SeedInitialData() models.Tenant {
+	h.t.Helper()
+	tenant, err := h.Store.Tenants().CreateTenant(models.SeedTenantName)
+	if err != nil {
+		h.t.Fatalf("create seed tenant: %v", err)
+	}
+	roles := [][]models.UserRole{{models.RoleAdmin}, {models.RoleSuperAdmin}}
+	for i, email := range models.SeedUserEmails {
+		if _, err := h.Store.Users().CreateUser(tenant.ID, email, "Qwerty123!", "", "", roles[i]); err != nil {
+			h.t.Fatalf("create seed user %s: %v", email, err)
+		}
+	}
+	return tenant
+}
+
+// SeedTenant creates a tenant and a user in it holding roles, and returns both
+// along with a token for the user.
+func (h *Harness) SeedTenant(name, email string, roles ...models.UserRole) (models.Tenant, models.User, string) {
+	h.t.Helper()
+	tenant, err := h.Store.Tenants().CreateTenant(name)
+	if err != nil {
+		h.t.Fatalf("create tenant: %v", err)
+	}
+	user, err := h.Store.Users().CreateUser(tenant.ID, email, "Password123!", "Test", "User", roles)
+	if err != nil {
+		h.t.Fatalf("create user: %v", err)
+	}
+	return tenant, user, h.Token(tenant.ID, user.ID, user.Roles...)
+}
+
+// Token signs a bearer token for the user the way Login does.
+func (h *Harness) Token(tenantID, userID string, roles ...models.UserRole) string {
+	h.t.Helper()
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	claims := jwt.MapClaims{
+		"sub":   userID,
+		"tid":   tenantID,
+		"role":  string(models.HighestRole(roles)),
+		"roles": names,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.Config.JWTSecret))
+	if err != nil {
+		h.t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+// JobToken signs a token like the one an engine container is started with for
+// the execution.
+func (h *Harness) JobToken(tenantID, executionID string) string {
+	h.t.Helper()
+	claims := jwt.MapClaims{
+		"sub": executionID,
+		"tid": tenantID,
+		"aud": "job-worker",
+		"iss": "job-orchestrator",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.Config.JWTSecret))
+	if err != nil {
+		h.t.Fatalf("sign job token: %v", err)
+	}
+	return signed
+}
+
+// Do serves a request through the router. A non-nil body is encoded as JSON, and a
+// non-empty token is sent as a bearer token.
+func (h *Harness) Do(method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("encode request body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+	return rec
+}
+
+// Decode unmarshals a JSON response body into v, failing the test when the status
+// is not want.
+func (h *Harness) Decode(rec *httptest.ResponseRecorder, want int, v interface{}) {
+	h.t.Helper()
+	if rec.Code != want {
+		h.t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		h.t.Fatalf("decode response: %v; body: %s", err, rec.Body.String())
+	}
+}
@@ -0,0 +1,97 @@
+package testutil
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type instanceRepository struct {
+	s *Store
+}
+
+func (r *instanceRepository) GetInstance() (models.Instance, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.instance == nil {
+		return models.Instance{}, sql.ErrNoRows
+	}
+	return *r.s.instance, nil
+}
+
+func (r *instanceRepository) SetupRequired() (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.instance == nil && r.s.onlySeedData(), nil
+}
+
+// onlySeedData mirrors the check that the database holds nothing but the tenant
+// and admins the initial migrations seed. The caller holds s.mu.
+func (s *Store) onlySeedData() bool {
+	for _, tenant := range s.tenants {
+		if tenant.Name != models.SeedTenantName {
+			return false
+		}
+	}
+	for _, user := range s.users {
+		if !slices.Contains(models.SeedUserEmails, user.Email) {
+			return false
+		}
+	}
+	return len(s.connections) == 0 && len(s.definitions) == 0
+}
+
+func (r *instanceRepository) Bootstrap(input repository.BootstrapInput) (models.Instance, models.Tenant, models.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.MinCost)
+	if err != nil {
+		return models.Instance{}, models.Tenant{}, models.User{}, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.instance != nil || !r.s.onlySeedData() {
+		return models.Instance{}, models.Tenant{}, models.User{}, repository.ErrSetupLocked
+	}
+	for id := range r.s.tenants {
+		delete(r.s.tenants, id)
+	}
+	for id := range r.s.users {
+		delete(r.s.users, id)
+	}
+	now := r.s.now()
+	tenant := models.Tenant{
+		ID:                  newID(),
+		Name:                input.TenantName,
+		Timezone:            "UTC",
+		Locale:              "en-US",
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		ValidationFreshness: models.ValidationFreshnessOff,
+	}
+	// The super-admin chose their own address during setup, so it counts as verified.
+	user := models.User{
+		ID:              newID(),
+		TenantID:        tenant.ID,
+		Email:           input.Email,
+		FirstName:       strings.TrimSpace(input.FirstName),
+		LastName:        strings.TrimSpace(input.LastName),
+		PasswordHash:    string(hash),
+		IsActive:        true,
+		Roles:           models.EnsureDefaultRole(models.NormalizeRoles([]models.UserRole{models.RoleSuperAdmin})),
+		EmailVerifiedAt: &now,
+	}
+	inst := models.Instance{
+		Name:             input.InstanceName,
+		SetupTenantID:    &tenant.ID,
+		SetupUserID:      &user.ID,
+		SetupCompletedAt: now,
+	}
+	r.s.tenants[tenant.ID] = tenant
+	r.s.users[user.ID] = user
+	r.s.instance = &inst
+	return inst, tenant, user, nil
+}
@@ -0,0 +1,196 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type inviteRepository struct {
+	s *Store
+}
+
+func (r *inviteRepository) CreateInvite(invite models.Invite) (models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.createInvite(invite), nil
+}
+
+func (r *inviteRepository) CreateInvites(invites []models.Invite) ([]models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	created := make([]models.Invite, 0, len(invites))
+	for _, invite := range invites {
+		created = append(created, r.s.createInvite(invite))
+	}
+	return created, nil
+}
+
+// createInvite stores a new invite. Callers hold s.mu.
+func (s *Store) createInvite(invite models.Invite) models.Invite {
+	now := s.now()
+	invite.ID, invite.CreatedAt, invite.UpdatedAt = newID(), now, now
+	invite.AcceptedAt, invite.DeliveredAt, invite.DeliveryError = nil, nil, nil
+	invite.DeliveryStatus, invite.DeliveryAttempts = "pending", 0
+	if invite.CreatedBy != nil && *invite.CreatedBy == "" {
+		invite.CreatedBy = nil
+	}
+	s.invites[invite.ID] = invite
+	return invite
+}
+
+// liveInvite returns an invite that was not cancelled. Callers hold s.mu.
+func (s *Store) liveInvite(inviteID string) (models.Invite, bool) {
+	invite, ok := s.invites[inviteID]
+	if !ok || s.cancelled[inviteID] {
+		return models.Invite{}, false
+	}
+	return invite, true
+}
+
+// pendingInvite returns an invite that is neither accepted nor cancelled. Callers
+// hold s.mu.
+func (s *Store) pendingInvite(inviteID, tenantID string) (models.Invite, bool) {
+	invite, ok := s.liveInvite(inviteID)
+	if !ok || invite.AcceptedAt != nil || (tenantID != "" && invite.TenantID != tenantID) {
+		return models.Invite{}, false
+	}
+	return invite, true
+}
+
+func (r *inviteRepository) GetInviteByTokenHash(tokenHash string) (models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, invite := range r.s.invites {
+		if invite.TokenHash == tokenHash && !r.s.cancelled[id] {
+			return invite, nil
+		}
+	}
+	return models.Invite{}, sql.ErrNoRows
+}
+
+func (r *inviteRepository) GetInvite(inviteID, tenantID string) (models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.liveInvite(inviteID)
+	if !ok || invite.TenantID != tenantID {
+		return models.Invite{}, sql.ErrNoRows
+	}
+	return invite, nil
+}
+
+func (r *inviteRepository) MarkInviteAccepted(inviteID string) (models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.pendingInvite(inviteID, "")
+	if !ok {
+		return models.Invite{}, sql.ErrNoRows
+	}
+	now := r.s.now()
+	invite.AcceptedAt, invite.UpdatedAt = &now, now
+	r.s.invites[inviteID] = invite
+	return invite, nil
+}
+
+func (r *inviteRepository) ListInvitesByTenant(tenantID string) ([]models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var invites []models.Invite
+	for id, invite := range r.s.invites {
+		if invite.TenantID == tenantID && !r.s.cancelled[id] {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.After(invites[j].CreatedAt) })
+	return invites, nil
+}
+
+func (r *inviteRepository) CancelInvite(inviteID, tenantID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.pendingInvite(inviteID, tenantID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	invite.UpdatedAt, invite.DeliveryToken = r.s.now(), nil
+	if invite.DeliveryStatus == "pending" {
+		invite.DeliveryStatus = "failed"
+	}
+	r.s.invites[inviteID] = invite
+	r.s.cancelled[inviteID] = true
+	return nil
+}
+
+func (r *inviteRepository) ClaimDueInviteDeliveries(limit int, lease time.Duration) ([]models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	var due []models.Invite
+	for id := range r.s.invites {
+		invite, ok := r.s.pendingInvite(id, "")
+		if !ok || invite.DeliveryStatus != "pending" || invite.NextDeliveryAt == nil || invite.NextDeliveryAt.After(now) {
+			continue
+		}
+		due = append(due, invite)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextDeliveryAt.Before(*due[j].NextDeliveryAt) })
+	if limit >= 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		next := now.Add(lease)
+		due[i].NextDeliveryAt = &next
+		r.s.invites[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *inviteRepository) MarkInviteDelivered(inviteID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.invites[inviteID]
+	if !ok {
+		return nil
+	}
+	now := r.s.now()
+	invite.DeliveryStatus, invite.DeliveryError = "sent", nil
+	invite.DeliveryAttempts++
+	invite.DeliveredAt, invite.NextDeliveryAt, invite.DeliveryToken, invite.UpdatedAt = &now, nil, nil, now
+	r.s.invites[inviteID] = invite
+	return nil
+}
+
+func (r *inviteRepository) MarkInviteDeliveryFailed(inviteID, errMsg string, retryAt *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.invites[inviteID]
+	if !ok {
+		return nil
+	}
+	invite.DeliveryAttempts++
+	invite.DeliveryError, invite.UpdatedAt = &errMsg, r.s.now()
+	if retryAt != nil {
+		next := *retryAt
+		invite.NextDeliveryAt = &next
+	} else {
+		invite.DeliveryStatus, invite.NextDeliveryAt, invite.DeliveryToken = "failed", nil, nil
+	}
+	r.s.invites[inviteID] = invite
+	return nil
+}
+
+func (r *inviteRepository) RequeueInviteDelivery(inviteID, tenantID, tokenHash string, deliveryToken []byte, nextAt time.Time) (models.Invite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	invite, ok := r.s.pendingInvite(inviteID, tenantID)
+	if !ok {
+		return models.Invite{}, sql.ErrNoRows
+	}
+	invite.TokenHash, invite.DeliveryToken, invite.NextDeliveryAt = tokenHash, deliveryToken, &nextAt
+	invite.DeliveryStatus, invite.DeliveryAttempts, invite.DeliveryError = "pending", 0, nil
+	invite.UpdatedAt = r.s.now()
+	r.s.invites[inviteID] = invite
+	return invite, nil
+}
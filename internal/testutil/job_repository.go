@@ -0,0 +1,1797 @@
+package testutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type jobRepository struct {
+	s *Store
+}
+
+func normalizeDefinitionStatus(status models.DefinitionStatus) (models.DefinitionStatus, error) {
+	status = models.DefinitionStatus(strings.ToUpper(strings.TrimSpace(string(status))))
+	if status == "" {
+		return models.DefinitionStatusReady, nil
+	}
+	if models.IsValidDefinitionStatus(status) {
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid job definition status %q", status)
+}
+
+// checkConnection mirrors the repository's tenant check of a referenced connection.
+// Callers hold s.mu.
+func (s *Store) checkConnection(tenantID, connectionID string) error {
+	if strings.TrimSpace(connectionID) == "" {
+		return nil
+	}
+	conn, ok := s.connections[connectionID]
+	if !ok || conn.TenantID != tenantID || s.deletedConnections[connectionID] {
+		return fmt.Errorf("connection %s not found for tenant %s", connectionID, tenantID)
+	}
+	return nil
+}
+
+// liveDefinition returns a definition with its connections joined in, the way the
+// repository loads it. Callers hold s.mu.
+func (s *Store) liveDefinition(tenantID, jobDefID string) (models.JobDefinition, bool) {
+	def, ok := s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || s.deletedDefinitions[jobDefID] {
+		return models.JobDefinition{}, false
+	}
+	def.SourceConnection = s.joinedConnection(def.SourceConnectionID)
+	def.DestinationConnection = s.joinedConnection(def.DestinationConnectionID)
+	def.RetryPolicy = cloneRetryPolicy(def.RetryPolicy)
+	if check, ok := s.schemaChecks[jobDefID]; ok && check.DefinitionUpdatedAt.Equal(def.UpdatedAt) {
+		checkedAt := check.CheckedAt
+		def.StaleValidation, def.StaleSince, def.SchemaCheckedAt = check.Stale, check.StaleSince, &checkedAt
+	}
+	def.Warnings = append(def.EnvironmentWarnings(), def.StaleValidationWarnings()...)
+	return def, true
+}
+
+// cloneRetryPolicy copies a policy the way the repository stores it: a zero policy
+// is kept as NULL.
+func cloneRetryPolicy(policy *models.RetryPolicy) *models.RetryPolicy {
+	if policy == nil || policy.IsZero() {
+		return nil
+	}
+	p := *policy
+	return &p
+}
+
+func (s *Store) joinedConnection(id string) models.Connection {
+	conn, ok := s.connections[id]
+	if !ok || s.deletedConnections[id] {
+		return models.Connection{}
+	}
+	conn = cloneConnection(conn)
+	conn.Password = ""
+	return conn
+}
+
+func (r *jobRepository) CrateDefinition(def models.JobDefinition) (models.JobDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.s.checkConnection(def.TenantID, def.SourceConnectionID); err != nil {
+		return def, err
+	}
+	if err := r.s.checkConnection(def.TenantID, def.DestinationConnectionID); err != nil {
+		return def, err
+	}
+	status, err := normalizeDefinitionStatus(def.Status)
+	if err != nil {
+		return def, err
+	}
+	def.Status = status
+	if def.JobType == "" {
+		def.JobType = models.JobTypeEngine
+	}
+	writeMode, err := models.NormalizeWriteMode(def.WriteMode)
+	if err != nil {
+		return def, err
+	}
+	def.WriteMode = writeMode
+	verification, err := models.NormalizeVerification(def.Verification)
+	if err != nil {
+		return def, err
+	}
+	def.Verification = verification
+	now := r.s.now()
+	def.ID, def.CreatedAt, def.UpdatedAt = newID(), now, now
+	def.SourceConnection, def.DestinationConnection = models.Connection{}, models.Connection{}
+	def.RetryPolicy = cloneRetryPolicy(def.RetryPolicy)
+	r.s.definitions[def.ID] = def
+	stored, _ := r.s.liveDefinition(def.TenantID, def.ID)
+	return stored, nil
+}
+
+func (r *jobRepository) ImportTenant(tenantID string, bundle repository.TenantImport) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	for _, conn := range bundle.Connections {
+		normalizeCredentialMode(&conn)
+		conn.TenantID, conn.CreatedAt, conn.UpdatedAt = tenantID, now, now
+		if conn.Status == "" {
+			conn.Status = models.ConnectionStatusUntested
+		}
+		r.s.connections[conn.ID] = cloneConnection(conn)
+	}
+	for _, def := range bundle.Definitions {
+		status, err := normalizeDefinitionStatus(def.Status)
+		if err != nil {
+			return err
+		}
+		def.TenantID, def.Status, def.CreatedAt, def.UpdatedAt = tenantID, status, now, now
+		def.SourceConnection, def.DestinationConnection = models.Connection{}, models.Connection{}
+		def.RetryPolicy = cloneRetryPolicy(def.RetryPolicy)
+		r.s.definitions[def.ID] = def
+	}
+	for jobDefID, secrets := range bundle.Secrets {
+		stored := make(map[string]definitionSecret, len(secrets))
+		for name, value := range secrets {
+			stored[name] = definitionSecret{
+				value:            value,
+				DefinitionSecret: models.DefinitionSecret{JobDefinitionID: jobDefID, Name: name, CreatedAt: now, UpdatedAt: now},
+			}
+		}
+		r.s.secrets[jobDefID] = stored
+	}
+	for _, schedule := range bundle.Schedules {
+		schedule.TenantID, schedule.CreatedAt, schedule.UpdatedAt = tenantID, now, now
+		r.s.schedules[schedule.JobDefinitionID] = schedule
+	}
+	return nil
+}
+
+func (r *jobRepository) GetJobDefinitionByID(tenantID, jobDefID string) (models.JobDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return models.JobDefinition{}, errors.New("job definition not found")
+	}
+	return def, nil
+}
+
+func (r *jobRepository) GetDefinitionASTPath(tenantID, jobDefID string, path []string) (json.RawMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return nil, errors.New("job definition not found")
+	}
+	return astSubtree(def.AST, path), nil
+}
+
+func (r *jobRepository) GetDefinitionASTTables(tenantID, jobDefID string, tables []string) (json.RawMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return nil, errors.New("job definition not found")
+	}
+	var entries []json.RawMessage
+	_ = json.Unmarshal(astSubtree(def.AST, models.ASTTablesPath), &entries)
+	selected := []json.RawMessage{}
+	for _, entry := range entries {
+		name := models.ASTTableName(entry)
+		for _, table := range tables {
+			if name != "" && name == table {
+				selected = append(selected, entry)
+				break
+			}
+		}
+	}
+	return json.Marshal(selected)
+}
+
+func (r *jobRepository) ListDefinitionASTTables(tenantID, jobDefID string) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return nil, errors.New("job definition not found")
+	}
+	var entries []json.RawMessage
+	_ = json.Unmarshal(astSubtree(def.AST, models.ASTTablesPath), &entries)
+	names := []string{}
+	for _, entry := range entries {
+		if name := models.ASTTableName(entry); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// astSubtree follows path through the AST the way Postgres' #> does, returning nil
+// when it leads nowhere.
+func astSubtree(ast json.RawMessage, path []string) json.RawMessage {
+	node := ast
+	for _, key := range path {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(node, &object); err == nil {
+			if node = object[key]; node == nil {
+				return nil
+			}
+			continue
+		}
+		var array []json.RawMessage
+		idx, err := strconv.Atoi(key)
+		if json.Unmarshal(node, &array) != nil || err != nil || idx < 0 || idx >= len(array) {
+			return nil
+		}
+		node = array[idx]
+	}
+	if len(node) == 0 || string(node) == "null" {
+		return nil
+	}
+	return node
+}
+
+func (r *jobRepository) ListDefinitions(tenantID string) ([]models.JobDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.listDefinitions(tenantID), nil
+}
+
+// listDefinitions returns the tenant's live definitions, newest first. Callers hold s.mu.
+func (s *Store) listDefinitions(tenantID string) []models.JobDefinition {
+	var defs []models.JobDefinition
+	for id := range s.definitions {
+		if def, ok := s.liveDefinition(tenantID, id); ok {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].CreatedAt.After(defs[j].CreatedAt) })
+	return defs
+}
+
+func (r *jobRepository) UpdateDefinition(tenantID, jobDefID string, update repository.DefinitionUpdate) (models.JobDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if update.SourceConnectionID != nil {
+		*update.SourceConnectionID = strings.TrimSpace(*update.SourceConnectionID)
+		if err := r.s.checkConnection(tenantID, *update.SourceConnectionID); err != nil {
+			return models.JobDefinition{}, err
+		}
+	}
+	if update.DestinationConnectionID != nil {
+		*update.DestinationConnectionID = strings.TrimSpace(*update.DestinationConnectionID)
+		if err := r.s.checkConnection(tenantID, *update.DestinationConnectionID); err != nil {
+			return models.JobDefinition{}, err
+		}
+	}
+	var status models.DefinitionStatus
+	if update.Status != nil {
+		var err error
+		if status, err = normalizeDefinitionStatus(*update.Status); err != nil {
+			return models.JobDefinition{}, err
+		}
+	}
+	var writeMode string
+	if update.WriteMode != nil {
+		var err error
+		if writeMode, err = models.NormalizeWriteMode(*update.WriteMode); err != nil {
+			return models.JobDefinition{}, err
+		}
+	}
+	var verification string
+	if update.Verification != nil {
+		var err error
+		if verification, err = models.NormalizeVerification(*update.Verification); err != nil {
+			return models.JobDefinition{}, err
+		}
+	}
+
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || r.s.deletedDefinitions[jobDefID] {
+		return models.JobDefinition{}, errors.New("job definition not found")
+	}
+	changed := false
+	if update.Name != nil {
+		def.Name, changed = *update.Name, true
+	}
+	if update.Description != nil {
+		def.Description, changed = *update.Description, true
+	}
+	if update.AST != nil {
+		def.AST, changed = *update.AST, true
+	}
+	if update.SourceConnectionID != nil {
+		def.SourceConnectionID, changed = *update.SourceConnectionID, true
+	}
+	if update.DestinationConnectionID != nil {
+		def.DestinationConnectionID, changed = *update.DestinationConnectionID, true
+	}
+	if update.Status != nil {
+		def.Status, changed = status, true
+	}
+	if update.ProgressSnapshot != nil {
+		def.ProgressSnapshot, changed = *update.ProgressSnapshot, true
+	}
+	if update.RetryPolicy != nil {
+		def.RetryPolicy, changed = cloneRetryPolicy(update.RetryPolicy), true
+	}
+	if update.WriteMode != nil {
+		def.WriteMode, changed = writeMode, true
+	}
+	if update.Verification != nil {
+		def.Verification, changed = verification, true
+	}
+	if changed {
+		def.UpdatedAt = r.s.now()
+		r.s.definitions[jobDefID] = def
+	}
+	stored, _ := r.s.liveDefinition(tenantID, jobDefID)
+	return stored, nil
+}
+
+func (r *jobRepository) DeleteDefinition(tenantID, jobDefID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || r.s.deletedDefinitions[jobDefID] {
+		return errors.New("job definition not found")
+	}
+	def.UpdatedAt = r.s.now()
+	r.s.definitions[jobDefID] = def
+	r.s.deletedDefinitions[jobDefID] = true
+	// Inline DSN connections belong to this definition alone.
+	for _, id := range []string{def.SourceConnectionID, def.DestinationConnectionID} {
+		if conn, ok := r.s.connections[id]; ok && conn.Ephemeral {
+			r.s.deletedConnections[id] = true
+		}
+	}
+	return nil
+}
+
+func (r *jobRepository) ListJobDefinitionsWithStats(tenantID string) ([]models.JobDefinitionStat, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	defs := r.s.listDefinitions(tenantID)
+	stats := make([]models.JobDefinitionStat, 0, len(defs))
+	for _, def := range defs {
+		stat := models.JobDefinitionStat{JobDefinition: def}
+		var last *models.JobExecution
+		for _, exec := range r.s.executions {
+			if exec.JobDefinitionID != def.ID {
+				continue
+			}
+			stat.TotalRuns++
+			if exec.BytesTransferred != nil {
+				stat.TotalBytesTransferred += *exec.BytesTransferred
+			}
+			if last == nil || exec.CreatedAt.After(last.CreatedAt) {
+				e := exec
+				last = &e
+			}
+		}
+		if last != nil {
+			status := string(last.Status)
+			stat.LastRunStatus = &status
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+func (r *jobRepository) DefinitionsVersion(tenantID string) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count, defUpdated, connUpdated int64
+	for id, def := range r.s.definitions {
+		if def.TenantID != tenantID {
+			continue
+		}
+		if !r.s.deletedDefinitions[id] {
+			count++
+		}
+		if ts := def.UpdatedAt.UnixNano(); ts > defUpdated {
+			defUpdated = ts
+		}
+	}
+	for _, conn := range r.s.connections {
+		if ts := conn.UpdatedAt.UnixNano(); conn.TenantID == tenantID && ts > connUpdated {
+			connUpdated = ts
+		}
+	}
+	var checked int64
+	for _, check := range r.s.schemaChecks {
+		if ts := check.CheckedAt.UnixNano(); check.TenantID == tenantID && ts > checked {
+			checked = ts
+		}
+	}
+	return fmt.Sprintf("%d-%d-%d-%d", count, defUpdated, connUpdated, checked), nil
+}
+
+func (r *jobRepository) DemoteReadyDefinition(tenantID, jobDefID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || r.s.deletedDefinitions[jobDefID] || def.Status != models.DefinitionStatusReady {
+		return false, nil
+	}
+	def.Status, def.UpdatedAt = models.DefinitionStatusDraft, r.s.now()
+	r.s.definitions[jobDefID] = def
+	return true, nil
+}
+
+func (r *jobRepository) ArchiveDefinition(tenantID, jobDefID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || r.s.deletedDefinitions[jobDefID] || def.Status == models.DefinitionStatusArchived {
+		return false, nil
+	}
+	now := r.s.now()
+	r.s.archivedFrom[jobDefID] = def.Status
+	def.Status, def.ArchivedAt, def.UpdatedAt = models.DefinitionStatusArchived, &now, now
+	r.s.definitions[jobDefID] = def
+	return true, nil
+}
+
+func (r *jobRepository) UnarchiveDefinition(tenantID, jobDefID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID || r.s.deletedDefinitions[jobDefID] || def.Status != models.DefinitionStatusArchived {
+		return false, nil
+	}
+	status, ok := r.s.archivedFrom[jobDefID]
+	if !ok {
+		status = models.DefinitionStatusDraft
+	}
+	delete(r.s.archivedFrom, jobDefID)
+	def.Status, def.ArchivedAt, def.UpdatedAt = status, nil, r.s.now()
+	r.s.definitions[jobDefID] = def
+	return true, nil
+}
+
+func (r *jobRepository) GetRunGrants(tenantID, jobDefID string) (models.RunGrants, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	grants := models.RunGrants{Users: []string{}, Roles: []models.UserRole{}}
+	if def, ok := r.s.definitions[jobDefID]; ok && def.TenantID == tenantID {
+		stored := r.s.runGrants[jobDefID]
+		grants.Users = append(grants.Users, stored.Users...)
+		grants.Roles = append(grants.Roles, stored.Roles...)
+	}
+	return grants, nil
+}
+
+func (r *jobRepository) SetRunGrants(tenantID, jobDefID string, grants models.RunGrants, grantedBy string) (models.RunGrants, error) {
+	r.s.mu.Lock()
+	if def, ok := r.s.definitions[jobDefID]; !ok || def.TenantID != tenantID {
+		r.s.mu.Unlock()
+		return models.RunGrants{}, sql.ErrNoRows
+	}
+	stored := models.RunGrants{}
+	for _, userID := range grants.Users {
+		if user, ok := r.s.liveUser(userID); !ok || user.TenantID != tenantID {
+			r.s.mu.Unlock()
+			return models.RunGrants{}, fmt.Errorf("%w: %s", repository.ErrRunGrantUserNotFound, userID)
+		}
+		stored.Users = append(stored.Users, userID)
+	}
+	stored.Roles = append(stored.Roles, grants.Roles...)
+	sort.Strings(stored.Users)
+	sort.Slice(stored.Roles, func(i, j int) bool { return stored.Roles[i] < stored.Roles[j] })
+	r.s.runGrants[jobDefID] = stored
+	r.s.mu.Unlock()
+	return r.GetRunGrants(tenantID, jobDefID)
+}
+
+func (r *jobRepository) GetSchedule(tenantID, jobDefID string) (models.JobSchedule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.schedule(tenantID, jobDefID)
+}
+
+// schedule returns a live definition's schedule. Callers hold s.mu.
+func (s *Store) schedule(tenantID, jobDefID string) (models.JobSchedule, error) {
+	schedule, ok := s.schedules[jobDefID]
+	def, live := s.liveDefinition(tenantID, jobDefID)
+	if !ok || !live || schedule.TenantID != tenantID {
+		return models.JobSchedule{}, sql.ErrNoRows
+	}
+	schedule.JobDefinitionName = def.Name
+	return schedule, nil
+}
+
+func (r *jobRepository) ListSchedules(tenantID string) ([]models.JobSchedule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var schedules []models.JobSchedule
+	for id := range r.s.schedules {
+		if schedule, err := r.s.schedule(tenantID, id); err == nil {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].JobDefinitionName < schedules[j].JobDefinitionName })
+	return schedules, nil
+}
+
+func (r *jobRepository) SaveSchedule(schedule models.JobSchedule) (models.JobSchedule, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	if current, ok := r.s.schedules[schedule.JobDefinitionID]; ok {
+		schedule.CreatedBy, schedule.CreatedAt = current.CreatedBy, current.CreatedAt
+		schedule.PausedAt, schedule.PausedForConnectionID = current.PausedAt, current.PausedForConnectionID
+	} else {
+		schedule.CreatedAt = now
+	}
+	schedule.UpdatedAt = now
+	schedule.NextRuns = nil
+	r.s.schedules[schedule.JobDefinitionID] = schedule
+	return r.s.schedule(schedule.TenantID, schedule.JobDefinitionID)
+}
+
+func (r *jobRepository) SetSchedulePaused(tenantID, jobDefID string, connectionID *string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	schedule, ok := r.s.schedules[jobDefID]
+	if !ok || schedule.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	schedule.PausedAt, schedule.PausedForConnectionID, schedule.UpdatedAt = nil, nil, now
+	if connectionID != nil {
+		id := *connectionID
+		schedule.PausedAt, schedule.PausedForConnectionID = &now, &id
+	}
+	r.s.schedules[jobDefID] = schedule
+	return nil
+}
+
+func (r *jobRepository) DeleteSchedule(tenantID, jobDefID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	schedule, ok := r.s.schedules[jobDefID]
+	if !ok || schedule.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	delete(r.s.schedules, jobDefID)
+	return nil
+}
+
+func (r *jobRepository) SetDefinitionSecrets(tenantID, jobDefID string, secrets map[string]string) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.liveDefinition(tenantID, jobDefID); !ok {
+		return errors.New("job definition not found")
+	}
+	stored := r.s.secrets[jobDefID]
+	if stored == nil {
+		stored = make(map[string]definitionSecret)
+		r.s.secrets[jobDefID] = stored
+	}
+	now := r.s.now()
+	for name, value := range secrets {
+		secret, ok := stored[name]
+		if !ok {
+			secret.DefinitionSecret = models.DefinitionSecret{JobDefinitionID: jobDefID, Name: name, CreatedAt: now}
+		}
+		secret.value, secret.UpdatedAt = value, now
+		stored[name] = secret
+	}
+	return nil
+}
+
+func (r *jobRepository) ListDefinitionSecrets(tenantID, jobDefID string) ([]models.DefinitionSecret, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	secrets := []models.DefinitionSecret{}
+	if def, ok := r.s.definitions[jobDefID]; ok && def.TenantID == tenantID {
+		for _, secret := range r.s.secrets[jobDefID] {
+			secrets = append(secrets, secret.DefinitionSecret)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+func (r *jobRepository) DeleteDefinitionSecret(tenantID, jobDefID, name string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.definitions[jobDefID]
+	if !ok || def.TenantID != tenantID {
+		return errors.New("secret not found")
+	}
+	if _, ok := r.s.secrets[jobDefID][name]; !ok {
+		return errors.New("secret not found")
+	}
+	delete(r.s.secrets[jobDefID], name)
+	return nil
+}
+
+func (r *jobRepository) GetDefinitionSecretValues(tenantID, jobDefID string) (map[string]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	values := map[string]string{}
+	if def, ok := r.s.definitions[jobDefID]; ok && def.TenantID == tenantID {
+		for name, secret := range r.s.secrets[jobDefID] {
+			values[name] = secret.value
+		}
+	}
+	return values, nil
+}
+
+func (r *jobRepository) EnqueueDefinitionRevalidations(tenantID, connectionID string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var queued int64
+	for id, def := range r.s.definitions {
+		if def.TenantID != tenantID || def.Status != models.DefinitionStatusReady || r.s.deletedDefinitions[id] {
+			continue
+		}
+		if def.SourceConnectionID != connectionID && def.DestinationConnectionID != connectionID {
+			continue
+		}
+		connID := connectionID
+		r.s.revalidations[id] = models.DefinitionRevalidation{
+			JobDefinitionID: id,
+			TenantID:        tenantID,
+			ConnectionID:    &connID,
+			EnqueuedAt:      r.s.now(),
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+func (r *jobRepository) ListDefinitionRevalidations(limit int) ([]models.DefinitionRevalidation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var revs []models.DefinitionRevalidation
+	for _, rev := range r.s.revalidations {
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i].EnqueuedAt.Before(revs[j].EnqueuedAt) })
+	if limit > 0 && len(revs) > limit {
+		revs = revs[:limit]
+	}
+	return revs, nil
+}
+
+func (r *jobRepository) CompleteDefinitionRevalidation(rev models.DefinitionRevalidation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if current, ok := r.s.revalidations[rev.JobDefinitionID]; ok && current.EnqueuedAt.Equal(rev.EnqueuedAt) {
+		delete(r.s.revalidations, rev.JobDefinitionID)
+	}
+	return nil
+}
+
+func (r *jobRepository) RecordDefinitionRevalidationError(jobDefID, message string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if rev, ok := r.s.revalidations[jobDefID]; ok {
+		rev.Attempts++
+		rev.LastError = &message
+		r.s.revalidations[jobDefID] = rev
+	}
+	return nil
+}
+
+func (r *jobRepository) ListSchemaCheckDefinitions(checkedBefore time.Time, limit int) ([]models.JobDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var defs []models.JobDefinition
+	for id, stored := range r.s.definitions {
+		tenant := r.s.tenants[stored.TenantID]
+		if tenant.ValidationFreshness == models.ValidationFreshnessOff || tenant.ValidationFreshness == "" || tenant.SuspendedAt != nil {
+			continue
+		}
+		def, ok := r.s.liveDefinition(stored.TenantID, id)
+		if !ok || def.Status != models.DefinitionStatusReady || def.JobType != models.JobTypeEngine {
+			continue
+		}
+		if def.SchemaCheckedAt != nil && !def.SchemaCheckedAt.Before(checkedBefore) {
+			continue
+		}
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		a, b := defs[i].SchemaCheckedAt, defs[j].SchemaCheckedAt
+		if (a == nil) != (b == nil) {
+			return a == nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return defs[i].CreatedAt.Before(defs[j].CreatedAt)
+	})
+	if len(defs) > limit {
+		defs = defs[:limit]
+	}
+	return defs, nil
+}
+
+func (r *jobRepository) RecordDefinitionSchemaCheck(tenantID, jobDefID string, updatedAt time.Time, schemaHash string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok || !def.UpdatedAt.Equal(updatedAt) || def.Status != models.DefinitionStatusReady {
+		return false, nil
+	}
+	now := r.s.now()
+	check, ok := r.s.schemaChecks[jobDefID]
+	if !ok || !check.DefinitionUpdatedAt.Equal(updatedAt) {
+		check = models.DefinitionSchemaCheck{JobDefinitionID: jobDefID, TenantID: tenantID, DefinitionUpdatedAt: updatedAt, SchemaHash: schemaHash}
+	}
+	flagged := !check.Stale && check.SchemaHash != schemaHash
+	if flagged {
+		check.Stale, check.StaleSince = true, &now
+	}
+	check.CheckedAt = now
+	r.s.schemaChecks[jobDefID] = check
+	return flagged, nil
+}
+
+func (r *jobRepository) ResetDefinitionSchemaCheck(tenantID, jobDefID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if check, ok := r.s.schemaChecks[jobDefID]; ok && check.TenantID == tenantID {
+		delete(r.s.schemaChecks, jobDefID)
+	}
+	return nil
+}
+
+func (r *jobRepository) CreateExecution(tenantID, jobDefID, executionID, workflowID, triggeredBy string, triggerContext map[string]string, allowConcurrent bool) (models.JobExecution, error) {
+	exec := models.JobExecution{
+		ID:              executionID,
+		TenantID:        tenantID,
+		JobDefinitionID: jobDefID,
+		Status:          models.ExecutionStatusPending,
+		TriggerContext:  triggerContext,
+	}
+	if triggeredBy != "" {
+		exec.TriggeredBy = &triggeredBy
+	}
+	if triggerType, userID := models.ExecutionTrigger(triggeredBy, triggerContext); triggerType != "" {
+		exec.TriggerType = &triggerType
+		if userID != "" {
+			exec.TriggeredByUserID = &userID
+		}
+	}
+	if workflowID != "" {
+		exec.WorkflowID = &workflowID
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return exec, sql.ErrNoRows
+	}
+	if def.Status != models.DefinitionStatusReady {
+		return exec, fmt.Errorf("%w: current status %s", repository.ErrJobDefinitionNotReady, def.Status)
+	}
+	existing, exists := r.s.executions[executionID]
+	approved := exists && existing.TenantID == tenantID && existing.Status == models.ExecutionStatusAwaitingApproval && existing.ApprovedBy != nil
+	if exists && !approved {
+		return exec, fmt.Errorf("execution %s already exists", executionID)
+	}
+	if active, ok := r.s.activeExecution(tenantID, jobDefID); ok && !allowConcurrent {
+		return exec, fmt.Errorf("%w: execution %s", repository.ErrExecutionActive, active.ID)
+	}
+	now := r.s.now()
+	if approved {
+		existing.Status, existing.WorkflowID, existing.UpdatedAt = models.ExecutionStatusPending, exec.WorkflowID, now
+		r.s.executions[executionID] = existing
+		return existing, nil
+	}
+	exec.CreatedAt, exec.UpdatedAt = now, now
+	r.s.executions[executionID] = exec
+	return exec, nil
+}
+
+func (r *jobRepository) CreateAwaitingExecution(tenantID, jobDefID, executionID, triggeredBy string, triggerContext map[string]string, request json.RawMessage) (models.JobExecution, error) {
+	exec := models.JobExecution{
+		ID:              executionID,
+		TenantID:        tenantID,
+		JobDefinitionID: jobDefID,
+		Status:          models.ExecutionStatusAwaitingApproval,
+		TriggerContext:  triggerContext,
+	}
+	if triggeredBy != "" {
+		exec.TriggeredBy = &triggeredBy
+	}
+	if triggerType, userID := models.ExecutionTrigger(triggeredBy, triggerContext); triggerType != "" {
+		exec.TriggerType = &triggerType
+		if userID != "" {
+			exec.TriggeredByUserID = &userID
+		}
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	def, ok := r.s.liveDefinition(tenantID, jobDefID)
+	if !ok {
+		return exec, sql.ErrNoRows
+	}
+	if def.Status != models.DefinitionStatusReady {
+		return exec, fmt.Errorf("%w: current status %s", repository.ErrJobDefinitionNotReady, def.Status)
+	}
+	if _, exists := r.s.executions[executionID]; exists {
+		return exec, fmt.Errorf("execution %s already exists", executionID)
+	}
+	now := r.s.now()
+	exec.CreatedAt, exec.UpdatedAt = now, now
+	r.s.executions[executionID] = exec
+	r.s.approvalRequests[executionID] = request
+	return exec, nil
+}
+
+func (r *jobRepository) ClaimExecutionApproval(tenantID, execID, approvedBy string) (json.RawMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || exec.Status != models.ExecutionStatusAwaitingApproval || exec.ApprovedBy != nil {
+		return nil, sql.ErrNoRows
+	}
+	now := r.s.now()
+	exec.ApprovedBy, exec.ApprovedAt, exec.UpdatedAt = &approvedBy, &now, now
+	r.s.executions[execID] = exec
+	return r.s.approvalRequests[execID], nil
+}
+
+func (r *jobRepository) ReleaseExecutionApproval(tenantID, execID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || exec.Status != models.ExecutionStatusAwaitingApproval {
+		return nil
+	}
+	exec.ApprovedBy, exec.ApprovedAt, exec.UpdatedAt = nil, nil, r.s.now()
+	r.s.executions[execID] = exec
+	return nil
+}
+
+// execution returns one of the tenant's executions. Callers hold s.mu.
+func (s *Store) execution(tenantID, execID string) (models.JobExecution, bool) {
+	exec, ok := s.executions[execID]
+	if !ok || exec.TenantID != tenantID {
+		return models.JobExecution{}, false
+	}
+	return exec, true
+}
+
+func (r *jobRepository) GetLastExecution(tenantID, jobDefID string) (models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var (
+		last  models.JobExecution
+		found bool
+	)
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || exec.JobDefinitionID != jobDefID {
+			continue
+		}
+		if !found || exec.CreatedAt.After(last.CreatedAt) {
+			last, found = exec, true
+		}
+	}
+	if !found {
+		return last, errors.New("job execution not found")
+	}
+	return last, nil
+}
+
+func (r *jobRepository) UpdateExecution(tenantID, execID string, status models.ExecutionStatus, errorMessage, logs string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	switch status {
+	case models.ExecutionStatusRunning, models.ExecutionStatusSucceeded, models.ExecutionStatusFailed, models.ExecutionStatusSkipped, models.ExecutionStatusCancelled:
+	default:
+		return 0, fmt.Errorf("invalid status %q", status)
+	}
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return 0, nil
+	}
+	now := r.s.now()
+	exec.Status, exec.UpdatedAt = status, now
+	delete(r.s.logChunks, execID)
+	if status == models.ExecutionStatusRunning {
+		exec.RunStartedAt, exec.ErrorMessage, exec.Logs = &now, nil, nil
+	} else {
+		logs = models.StripANSI(logs)
+		exec.RunCompletedAt, exec.ErrorMessage, exec.Logs = &now, nilIfEmpty(errorMessage), nilIfEmpty(logs)
+		r.s.logChunks[execID] = models.ChunkLogs(logs)
+	}
+	r.s.executions[execID] = exec
+	return 1, nil
+}
+
+func nilIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (r *jobRepository) ListExecutions(tenantID, triggeredBy string, limit, offset int) ([]models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	executions := []models.JobExecution{}
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID {
+			continue
+		}
+		if triggeredBy != "" && (exec.TriggeredBy == nil || *exec.TriggeredBy != triggeredBy) {
+			continue
+		}
+		executions = append(executions, exec)
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].CreatedAt.After(executions[j].CreatedAt) })
+	return page(executions, limit, offset), nil
+}
+
+func page(executions []models.JobExecution, limit, offset int) []models.JobExecution {
+	if offset >= len(executions) {
+		return []models.JobExecution{}
+	}
+	executions = executions[offset:]
+	if limit >= 0 && len(executions) > limit {
+		executions = executions[:limit]
+	}
+	return executions
+}
+
+// ListExecutionStats only fills the totals; the per-day, per-trigger and monthly
+// breakdowns are left empty.
+func (r *jobRepository) ListExecutionStats(tenantID string, days int, tz string) (models.ExecutionStat, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if tz == "" {
+		tz = "UTC"
+		if tenant, ok := r.s.tenants[tenantID]; ok {
+			tz = tenant.Timezone
+		}
+	}
+	stat := models.ExecutionStat{
+		Timezone:         tz,
+		PerDay:           []models.ExecutionStatDay{},
+		PerTrigger:       map[string]int{},
+		TotalDefinitions: len(r.s.listDefinitions(tenantID)),
+	}
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID {
+			continue
+		}
+		stat.Total++
+		switch exec.Status {
+		case models.ExecutionStatusSucceeded:
+			stat.Succeeded++
+		case models.ExecutionStatusFailed:
+			stat.Failed++
+		case models.ExecutionStatusRunning:
+			stat.Running++
+		case models.ExecutionStatusPaused:
+			stat.Paused++
+		}
+	}
+	if stat.Total > 0 {
+		stat.SuccessRate = float64(stat.Succeeded) / float64(stat.Total)
+	}
+	return stat, nil
+}
+
+// ListExecutionSeries is not modelled by the fake and returns no points.
+func (r *jobRepository) ListExecutionSeries(tenantID string, from, to time.Time, bucket time.Duration) ([]models.ExecutionSeriesPoint, error) {
+	return []models.ExecutionSeriesPoint{}, nil
+}
+
+// ListMonthlyStats is not modelled by the fake and returns no months.
+func (r *jobRepository) ListMonthlyStats(tenantID, jobDefID string, from, to time.Time) ([]models.ExecutionMonthStat, error) {
+	return []models.ExecutionMonthStat{}, nil
+}
+
+func (r *jobRepository) RefreshMonthlyRollups() (int64, error) {
+	return 0, nil
+}
+
+func (r *jobRepository) ListCalendarExecutions(tenantID string, from, to time.Time, limit int) ([]models.CalendarEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var events []models.CalendarEvent
+	for _, exec := range r.s.executions {
+		at := exec.CreatedAt
+		if exec.RunStartedAt != nil {
+			at = *exec.RunStartedAt
+		}
+		if exec.TenantID != tenantID || at.Before(from) || !at.Before(to) {
+			continue
+		}
+		name := exec.JobDefinitionID
+		if def, ok := r.s.definitions[exec.JobDefinitionID]; ok {
+			name = def.Name
+		}
+		events = append(events, models.CalendarEvent{
+			Kind:              models.CalendarEventExecution,
+			JobDefinitionID:   exec.JobDefinitionID,
+			JobDefinitionName: name,
+			ExecutionID:       exec.ID,
+			Status:            string(exec.Status),
+			At:                at,
+			EndedAt:           exec.RunCompletedAt,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	if limit >= 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *jobRepository) GetExecution(tenantID, execID string) (models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return exec, errors.New("execution not found")
+	}
+	return exec, nil
+}
+
+func (r *jobRepository) SetExecutionComplete(tenantID, execID string, status models.ExecutionStatus, recordsProcessed int64, bytesTransferred int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	exec.Status, exec.RunCompletedAt = status, &now
+	exec.RecordsProcessed, exec.BytesTransferred = &recordsProcessed, &bytesTransferred
+	r.s.executions[execID] = exec
+	return nil
+}
+
+func (r *jobRepository) SetExecutionPaused(tenantID, execID string, paused bool) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return false, nil
+	}
+	now := r.s.now()
+	switch {
+	case paused && exec.Status == models.ExecutionStatusRunning:
+		exec.Status, exec.PausedAt = "paused", &now
+	case !paused && exec.Status == models.ExecutionStatusPaused:
+		exec.Status, exec.PausedAt = "running", nil
+	default:
+		return false, nil
+	}
+	exec.UpdatedAt = now
+	r.s.executions[execID] = exec
+	return true, nil
+}
+
+func (r *jobRepository) FailActiveExecution(tenantID, execID, errorMessage string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || !exec.Status.Active() {
+		return false, nil
+	}
+	now := r.s.now()
+	exec.Status, exec.RunCompletedAt, exec.UpdatedAt = "failed", &now, now
+	exec.ErrorMessage = nilIfEmpty(errorMessage)
+	r.s.executions[execID] = exec
+	return true, nil
+}
+
+func (r *jobRepository) CancelPendingExecution(tenantID, execID, errorMessage string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || exec.Status != models.ExecutionStatusPending {
+		return false, nil
+	}
+	now := r.s.now()
+	exec.Status, exec.RunCompletedAt, exec.UpdatedAt = models.ExecutionStatusCancelled, &now, now
+	exec.ErrorMessage = nilIfEmpty(errorMessage)
+	r.s.executions[execID] = exec
+	return true, nil
+}
+
+func (r *jobRepository) ListActiveExecutions(updatedBefore time.Time, limit int) ([]models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var executions []models.JobExecution
+	for _, exec := range r.s.executions {
+		if exec.Status.Active() && exec.UpdatedAt.Before(updatedBefore) {
+			executions = append(executions, exec)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].UpdatedAt.Before(executions[j].UpdatedAt) })
+	if limit >= 0 && len(executions) > limit {
+		executions = executions[:limit]
+	}
+	return executions, nil
+}
+
+func (r *jobRepository) GetActiveDefinitionExecution(tenantID, jobDefID string) (models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.activeExecution(tenantID, jobDefID)
+	if !ok {
+		return models.JobExecution{}, sql.ErrNoRows
+	}
+	return exec, nil
+}
+
+// activeExecution returns the oldest pending, running or paused execution of a
+// definition. Callers hold s.mu.
+func (s *Store) activeExecution(tenantID, jobDefID string) (models.JobExecution, bool) {
+	var (
+		oldest models.JobExecution
+		found  bool
+	)
+	for _, exec := range s.executions {
+		if exec.TenantID != tenantID || exec.JobDefinitionID != jobDefID || !exec.Status.Active() {
+			continue
+		}
+		if !found || exec.CreatedAt.Before(oldest.CreatedAt) {
+			oldest, found = exec, true
+		}
+	}
+	return oldest, found
+}
+
+func (r *jobRepository) GetAwaitingApprovalExecution(tenantID, jobDefID string) (models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var (
+		oldest models.JobExecution
+		found  bool
+	)
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || exec.JobDefinitionID != jobDefID || exec.Status != models.ExecutionStatusAwaitingApproval {
+			continue
+		}
+		if !found || exec.CreatedAt.Before(oldest.CreatedAt) {
+			oldest, found = exec, true
+		}
+	}
+	if !found {
+		return models.JobExecution{}, sql.ErrNoRows
+	}
+	return oldest, nil
+}
+
+func (r *jobRepository) ListConnectionActiveExecutions(tenantID, connectionID string) ([]models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var executions []models.JobExecution
+	for _, exec := range r.s.executions {
+		def, ok := r.s.definitions[exec.JobDefinitionID]
+		if exec.TenantID != tenantID || !exec.Status.Active() || !ok {
+			continue
+		}
+		if def.SourceConnectionID == connectionID || def.DestinationConnectionID == connectionID {
+			executions = append(executions, exec)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].CreatedAt.Before(executions[j].CreatedAt) })
+	return executions, nil
+}
+
+func (r *jobRepository) ListTenantActiveExecutions(tenantID string) ([]models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var executions []models.JobExecution
+	for _, exec := range r.s.executions {
+		if exec.TenantID == tenantID && exec.Status.Active() {
+			executions = append(executions, exec)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].CreatedAt.Before(executions[j].CreatedAt) })
+	return executions, nil
+}
+
+func (r *jobRepository) AverageDefinitionDurations(tenantID string) (map[string]float64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || exec.RunStartedAt == nil || exec.RunCompletedAt == nil {
+			continue
+		}
+		sums[exec.JobDefinitionID] += exec.RunCompletedAt.Sub(*exec.RunStartedAt).Seconds()
+		counts[exec.JobDefinitionID]++
+	}
+	averages := make(map[string]float64, len(sums))
+	for defID, sum := range sums {
+		averages[defID] = sum / float64(counts[defID])
+	}
+	return averages, nil
+}
+
+func (r *jobRepository) CreateExecutionSnapshot(snapshot models.ExecutionSnapshot) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, exists := r.s.snapshots[snapshot.ExecutionID]; exists {
+		return nil
+	}
+	if snapshot.Parameters == nil {
+		snapshot.Parameters = map[string]interface{}{}
+	}
+	snapshot.CreatedAt = r.s.now()
+	r.s.snapshots[snapshot.ExecutionID] = snapshot
+	return nil
+}
+
+func (r *jobRepository) SetExecutionSnapshotImageDigest(tenantID, execID, digest string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	snapshot, ok := r.s.snapshots[execID]
+	if ok && snapshot.TenantID == tenantID && snapshot.EngineImageDigest == nil {
+		snapshot.EngineImageDigest = &digest
+		r.s.snapshots[execID] = snapshot
+	}
+	return nil
+}
+
+func (r *jobRepository) GetExecutionSnapshot(tenantID, execID string) (models.ExecutionSnapshot, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	snapshot, ok := r.s.snapshots[execID]
+	if !ok || snapshot.TenantID != tenantID {
+		return models.ExecutionSnapshot{}, errors.New("execution snapshot not found")
+	}
+	return snapshot, nil
+}
+
+func (r *jobRepository) SaveExecutionArtifact(artifact models.ExecutionArtifact) (models.ExecutionArtifact, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	artifact.CreatedAt = r.s.now()
+	if artifact.ScanStatus == "" {
+		artifact.ScanStatus = models.ArtifactScanUnscanned
+	}
+	for i, existing := range r.s.artifacts {
+		if existing.ExecutionID == artifact.ExecutionID && existing.Kind == artifact.Kind {
+			artifact.ID = existing.ID
+			r.s.artifacts[i] = artifact
+			return artifact, nil
+		}
+	}
+	artifact.ID = newID()
+	r.s.artifacts = append(r.s.artifacts, artifact)
+	return artifact, nil
+}
+
+func (r *jobRepository) GetExecutionArtifact(tenantID, execID, kind string) (models.ExecutionArtifact, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, artifact := range r.s.artifacts {
+		if artifact.TenantID == tenantID && artifact.ExecutionID == execID && artifact.Kind == kind {
+			return artifact, nil
+		}
+	}
+	return models.ExecutionArtifact{}, errors.New("execution artifact not found")
+}
+
+func (r *jobRepository) ListExecutionArtifacts(tenantID, execID string) ([]models.ExecutionArtifact, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	artifacts := []models.ExecutionArtifact{}
+	for _, artifact := range r.s.artifacts {
+		if artifact.TenantID == tenantID && artifact.ExecutionID == execID {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].CreatedAt.Before(artifacts[j].CreatedAt) })
+	return artifacts, nil
+}
+
+func (r *jobRepository) SaveExecutionCheckpoints(tenantID, execID string, checkpoints []models.ExecutionCheckpoint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return errors.New("execution not found")
+	}
+	for _, cp := range checkpoints {
+		r.s.saveCheckpoint(execID, cp, true)
+	}
+	return nil
+}
+
+// saveCheckpoint inserts a table's checkpoint, replacing an existing one only when
+// overwrite is set. Callers hold s.mu.
+func (s *Store) saveCheckpoint(execID string, cp models.ExecutionCheckpoint, overwrite bool) {
+	cp.ExecutionID, cp.UpdatedAt = execID, s.now()
+	stored := s.checkpoints[execID]
+	for i, existing := range stored {
+		if existing.Table == cp.Table {
+			if overwrite {
+				stored[i] = cp
+			}
+			return
+		}
+	}
+	s.checkpoints[execID] = append(stored, cp)
+}
+
+func (r *jobRepository) ListExecutionCheckpoints(tenantID, execID string) ([]models.ExecutionCheckpoint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.listCheckpoints(tenantID, execID), nil
+}
+
+func (s *Store) listCheckpoints(tenantID, execID string) []models.ExecutionCheckpoint {
+	checkpoints := []models.ExecutionCheckpoint{}
+	if _, ok := s.execution(tenantID, execID); ok {
+		checkpoints = append(checkpoints, s.checkpoints[execID]...)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Table < checkpoints[j].Table })
+	return checkpoints
+}
+
+func (r *jobRepository) ResumeExecutionFrom(tenantID, execID, fromExecID string) ([]models.ExecutionCheckpoint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return nil, errors.New("execution not found")
+	}
+	exec.ResumedFromExecutionID = &fromExecID
+	r.s.executions[execID] = exec
+	for _, cp := range r.s.listCheckpoints(tenantID, fromExecID) {
+		r.s.saveCheckpoint(execID, cp, false)
+	}
+	return r.s.listCheckpoints(tenantID, execID), nil
+}
+
+func (r *jobRepository) SaveExecutionTables(tenantID, execID string, tables []models.JobExecutionTable) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return errors.New("execution not found")
+	}
+	now := r.s.now()
+	for _, t := range tables {
+		t.ExecutionID, t.UpdatedAt = execID, now
+		existing := r.s.executionTables[execID]
+		replaced := false
+		for i := range existing {
+			if existing[i].Table == t.Table {
+				existing[i], replaced = t, true
+			}
+		}
+		if !replaced {
+			existing = append(existing, t)
+		}
+		r.s.executionTables[execID] = existing
+	}
+	return nil
+}
+
+func (r *jobRepository) ListExecutionTables(tenantID, execID string) ([]models.JobExecutionTable, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tables := []models.JobExecutionTable{}
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return tables, nil
+	}
+	tables = append(tables, r.s.executionTables[execID]...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Table < tables[j].Table })
+	return tables, nil
+}
+
+func (r *jobRepository) ListExecutionLogChunks(tenantID, execID string) ([]models.ExecutionLogChunk, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	chunks := []models.ExecutionLogChunk{}
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return chunks, nil
+	}
+	return append(chunks, r.s.logChunks[execID]...), nil
+}
+
+func (r *jobRepository) CreateExecutionNote(note models.ExecutionNote) (models.ExecutionNote, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.execution(note.TenantID, note.ExecutionID); !ok {
+		return note, errors.New("execution not found")
+	}
+	now := r.s.now()
+	note.ID, note.CreatedAt, note.UpdatedAt = newID(), now, now
+	r.s.notes = append(r.s.notes, note)
+	return r.s.withAuthor(note), nil
+}
+
+// withAuthor fills in the note author's email. Callers hold s.mu.
+func (s *Store) withAuthor(note models.ExecutionNote) models.ExecutionNote {
+	note.AuthorEmail = ""
+	if note.AuthorID != nil {
+		if user, ok := s.users[*note.AuthorID]; ok {
+			note.AuthorEmail = user.Email
+		}
+	}
+	return note
+}
+
+func (r *jobRepository) ListExecutionNotes(tenantID, execID string) ([]models.ExecutionNote, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	notes := []models.ExecutionNote{}
+	for _, note := range r.s.notes {
+		if note.TenantID == tenantID && note.ExecutionID == execID {
+			notes = append(notes, r.s.withAuthor(note))
+		}
+	}
+	return notes, nil
+}
+
+// SearchExecutionNotes matches notes containing every word of the query, ignoring
+// case, newest first.
+func (r *jobRepository) SearchExecutionNotes(tenantID, query string, limit int) ([]models.ExecutionNote, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	words := strings.Fields(strings.ToLower(query))
+	notes := []models.ExecutionNote{}
+	for i := len(r.s.notes) - 1; i >= 0; i-- {
+		note := r.s.notes[i]
+		if note.TenantID != tenantID {
+			continue
+		}
+		body := strings.ToLower(note.Body)
+		matches := len(words) > 0
+		for _, word := range words {
+			if !strings.Contains(body, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			notes = append(notes, r.s.withAuthor(note))
+		}
+		if limit >= 0 && len(notes) == limit {
+			break
+		}
+	}
+	return notes, nil
+}
+
+func (r *jobRepository) DeleteExecutionNote(tenantID, execID, noteID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, note := range r.s.notes {
+		if note.ID == noteID && note.ExecutionID == execID && note.TenantID == tenantID {
+			r.s.notes = append(r.s.notes[:i], r.s.notes[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("execution note not found")
+}
+
+func (r *jobRepository) GetExecutionBaseline(tenantID, jobDefID, excludeExecID string, runs int) (models.ExecutionBaseline, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var recent []models.JobExecution
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || exec.JobDefinitionID != jobDefID || exec.ID == excludeExecID || exec.Status != models.ExecutionStatusSucceeded {
+			continue
+		}
+		if exec.RunStartedAt == nil || exec.RunCompletedAt == nil || !exec.RunCompletedAt.After(*exec.RunStartedAt) {
+			continue
+		}
+		recent = append(recent, exec)
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].RunCompletedAt.After(*recent[j].RunCompletedAt) })
+	if len(recent) > runs {
+		recent = recent[:runs]
+	}
+
+	var durations, throughputs []float64
+	for _, exec := range recent {
+		duration := exec.RunCompletedAt.Sub(*exec.RunStartedAt).Seconds()
+		durations = append(durations, duration)
+		if exec.RecordsProcessed != nil && *exec.RecordsProcessed > 0 {
+			throughputs = append(throughputs, float64(*exec.RecordsProcessed)/duration)
+		}
+	}
+	baseline := models.ExecutionBaseline{Runs: len(recent), MedianDurationSeconds: median(durations)}
+	if len(throughputs) > 0 {
+		throughput := median(throughputs)
+		baseline.MedianThroughput = &throughput
+	}
+	return baseline, nil
+}
+
+// median interpolates between the middle values like percentile_cont(0.5).
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	return (values[mid-1] + values[mid]) / 2
+}
+
+func (r *jobRepository) SaveExecutionRegressions(regressions []models.ExecutionRegression) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+next:
+	for _, reg := range regressions {
+		for _, existing := range r.s.regressions {
+			if existing.ExecutionID == reg.ExecutionID && existing.Metric == reg.Metric {
+				continue next
+			}
+		}
+		reg.ID, reg.CreatedAt = newID(), r.s.now()
+		r.s.regressions = append(r.s.regressions, reg)
+	}
+	return nil
+}
+
+func (r *jobRepository) ListExecutionRegressions(tenantID, jobDefID string, limit int) ([]models.ExecutionRegression, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	regressions := []models.ExecutionRegression{}
+	for i := len(r.s.regressions) - 1; i >= 0 && (limit < 0 || len(regressions) < limit); i-- {
+		if reg := r.s.regressions[i]; reg.TenantID == tenantID && reg.JobDefinitionID == jobDefID {
+			regressions = append(regressions, reg)
+		}
+	}
+	return regressions, nil
+}
+
+func (r *jobRepository) CountDataMovingRuns(tenantID, jobDefID, excludeExecID string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	count := 0
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || exec.JobDefinitionID != jobDefID || exec.ID == excludeExecID || exec.Status != models.ExecutionStatusSucceeded {
+			continue
+		}
+		if (exec.RecordsProcessed != nil && *exec.RecordsProcessed > 0) || (exec.BytesTransferred != nil && *exec.BytesTransferred > 0) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *jobRepository) MarkExecutionSuspect(tenantID, execID, reason string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	exec.Suspect, exec.SuspectReason, exec.UpdatedAt = true, &reason, r.s.now()
+	r.s.executions[execID] = exec
+	return nil
+}
+
+func (r *jobRepository) ListDeadLetterExecutions(tenantID string, limit int) ([]models.JobExecution, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	executions := []models.JobExecution{}
+	for _, exec := range r.s.executions {
+		if exec.TenantID == tenantID && exec.Status == models.ExecutionStatusFailed && exec.RequeuedAsExecutionID == nil {
+			executions = append(executions, exec)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].CreatedAt.After(executions[j].CreatedAt) })
+	return page(executions, limit, 0), nil
+}
+
+func (r *jobRepository) MarkExecutionRequeued(tenantID, execID, requeuedAsID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || exec.Status != models.ExecutionStatusFailed || exec.RequeuedAsExecutionID != nil {
+		return false, nil
+	}
+	now := r.s.now()
+	exec.RequeuedAsExecutionID, exec.RequeuedAt, exec.UpdatedAt = &requeuedAsID, &now, now
+	r.s.executions[execID] = exec
+	return true, nil
+}
+
+func (r *jobRepository) ClearExecutionRequeued(tenantID, execID, requeuedAsID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok || exec.RequeuedAsExecutionID == nil || *exec.RequeuedAsExecutionID != requeuedAsID {
+		return nil
+	}
+	exec.RequeuedAsExecutionID, exec.RequeuedAt, exec.UpdatedAt = nil, nil, r.s.now()
+	r.s.executions[execID] = exec
+	return nil
+}
+
+func (r *jobRepository) RecordActivityAttempt(tenantID, execID, activity string, attempt int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return nil
+	}
+	attempts := map[string]int32{activity: attempt}
+	for name, n := range exec.ActivityAttempts {
+		if name != activity {
+			attempts[name] = n
+		}
+	}
+	exec.ActivityAttempts, exec.UpdatedAt = attempts, r.s.now()
+	r.s.executions[execID] = exec
+	return nil
+}
+
+func (r *jobRepository) RecordExecutionEvent(tenantID, execID string, event models.ExecutionEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return errors.New("execution not found")
+	}
+	now := r.s.now()
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = now
+	}
+	event.ID, event.ExecutionID, event.OccurredAt = newID(), execID, event.OccurredAt.UTC()
+	r.s.executionEvents[execID] = append(r.s.executionEvents[execID], event)
+	return nil
+}
+
+func (r *jobRepository) ListExecutionEvents(tenantID, execID string) ([]models.ExecutionEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	events := []models.ExecutionEvent{}
+	if _, ok := r.s.execution(tenantID, execID); !ok {
+		return events, nil
+	}
+	events = append(events, r.s.executionEvents[execID]...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	return events, nil
+}
+
+func (r *jobRepository) RecordStartupStage(tenantID, execID, stage string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exec, ok := r.s.execution(tenantID, execID)
+	if !ok {
+		return nil
+	}
+	timings := map[string]time.Time{stage: at.UTC()}
+	for name, t := range exec.StartupTimings {
+		if name != stage {
+			timings[name] = t
+		}
+	}
+	exec.StartupTimings, exec.UpdatedAt = timings, r.s.now()
+	r.s.executions[execID] = exec
+	return nil
+}
+
+func (r *jobRepository) ListStartupTimings(tenantID string, since time.Time) ([]map[string]time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var timings []map[string]time.Time
+	for _, exec := range r.s.executions {
+		if (tenantID != "" && exec.TenantID != tenantID) || exec.CreatedAt.Before(since) || len(exec.StartupTimings) == 0 {
+			continue
+		}
+		t := make(map[string]time.Time, len(exec.StartupTimings))
+		for stage, at := range exec.StartupTimings {
+			t[stage] = at
+		}
+		timings = append(timings, t)
+	}
+	return timings, nil
+}
+
+func (r *jobRepository) CreateRunGroup(group models.RunGroup) (models.RunGroup, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	group.ID = newID()
+	group.Status = models.RunGroupStatusRunning
+	group.CreatedAt = r.s.now()
+	members := make([]models.RunGroupMember, len(group.Members))
+	for i, m := range group.Members {
+		m.Status = models.RunGroupMemberPending
+		members[i] = m
+	}
+	group.Members = members
+	r.s.runGroups[group.ID] = group
+	return r.s.runGroup(group), nil
+}
+
+func (r *jobRepository) GetRunGroup(tenantID, groupID string) (models.RunGroup, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	group, ok := r.s.runGroups[groupID]
+	if !ok || group.TenantID != tenantID {
+		return models.RunGroup{}, sql.ErrNoRows
+	}
+	return r.s.runGroup(group), nil
+}
+
+func (r *jobRepository) SkipRunGroupMembers(tenantID, groupID string, executionIDs []string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	group, ok := r.s.runGroups[groupID]
+	if !ok || group.TenantID != tenantID {
+		return nil
+	}
+	skip := make(map[string]bool, len(executionIDs))
+	for _, id := range executionIDs {
+		skip[id] = true
+	}
+	for i, m := range group.Members {
+		if skip[m.ExecutionID] {
+			group.Members[i].Status = models.RunGroupMemberSkipped
+		}
+	}
+	return nil
+}
+
+func (r *jobRepository) CompleteRunGroup(tenantID, groupID string, report models.RunGroupReport) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	group, ok := r.s.runGroups[groupID]
+	if !ok || group.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	completedAt := report.CompletedAt
+	group.Status, group.CompletedAt = report.Status, &completedAt
+	r.s.runGroups[groupID] = group
+	r.s.runGroupReports[groupID] = report
+	return nil
+}
+
+func (r *jobRepository) GetRunGroupReport(tenantID, groupID string) (models.RunGroupReport, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	report, ok := r.s.runGroupReports[groupID]
+	if !ok || r.s.runGroups[groupID].TenantID != tenantID {
+		return models.RunGroupReport{}, sql.ErrNoRows
+	}
+	return report, nil
+}
+
+// runGroup returns a copy of group with the status of its started members taken
+// from their executions. The caller holds s.mu.
+func (s *Store) runGroup(group models.RunGroup) models.RunGroup {
+	members := make([]models.RunGroupMember, len(group.Members))
+	for i, m := range group.Members {
+		if def, ok := s.definitions[m.JobDefinitionID]; ok {
+			m.JobDefinitionName = def.Name
+		}
+		if exec, ok := s.execution(group.TenantID, m.ExecutionID); ok {
+			m.Status = string(exec.Status)
+			m.ErrorMessage, m.RecordsProcessed, m.BytesTransferred = exec.ErrorMessage, exec.RecordsProcessed, exec.BytesTransferred
+			m.StartedAt, m.CompletedAt = exec.RunStartedAt, exec.RunCompletedAt
+		}
+		members[i] = m
+	}
+	group.Members = members
+	group.Summarize()
+	return group
+}
@@ -0,0 +1,60 @@
+package testutil
+
+import "sync"
+
+// SentInvite is an invite email sent through Mailer.
+type SentInvite struct {
+	Email      string
+	TenantName string
+	URL        string
+}
+
+// SentVerification is a verification email sent through Mailer.
+type SentVerification struct {
+	Email string
+	URL   string
+}
+
+// Mailer records the invite and verification emails it is asked to send. Err, when
+// set, fails every send.
+type Mailer struct {
+	Err error
+
+	mu            sync.Mutex
+	invites       []SentInvite
+	verifications []SentVerification
+}
+
+func (m *Mailer) SendInvite(recipientEmail, tenantName, inviteURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.invites = append(m.invites, SentInvite{Email: recipientEmail, TenantName: tenantName, URL: inviteURL})
+	return nil
+}
+
+func (m *Mailer) SendVerification(recipientEmail, verifyURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.verifications = append(m.verifications, SentVerification{Email: recipientEmail, URL: verifyURL})
+	return nil
+}
+
+// Invites returns the invite emails sent so far, oldest first.
+func (m *Mailer) Invites() []SentInvite {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SentInvite(nil), m.invites...)
+}
+
+// Verifications returns the verification emails sent so far, oldest first.
+func (m *Mailer) Verifications() []SentVerification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SentVerification(nil), m.verifications...)
+}
@@ -0,0 +1,158 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type notificationRepository struct {
+	s *Store
+}
+
+func (r *notificationRepository) Create(ctx context.Context, params repository.CreateNotificationParams) (models.Notification, error) {
+	notif := models.Notification{
+		CorrelationKey: params.CorrelationKey,
+		EventType:      params.Event,
+		Severity:       params.Severity,
+		Title:          params.Title,
+		Message:        params.Message,
+	}
+	if params.TenantID != nil && strings.TrimSpace(*params.TenantID) != "" {
+		tenantID := strings.TrimSpace(*params.TenantID)
+		notif.TenantID = &tenantID
+	}
+	if len(params.Metadata) > 0 {
+		raw, err := json.Marshal(params.Metadata)
+		if err != nil {
+			return models.Notification{}, fmt.Errorf("marshal metadata: %w", err)
+		}
+		notif.Metadata = raw
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	notif.ID, notif.CreatedAt = newID(), r.s.now()
+	r.s.notifications = append(r.s.notifications, notif)
+	return notif, nil
+}
+
+func visibleTo(notif models.Notification, tenantID string) bool {
+	return notif.TenantID == nil || *notif.TenantID == strings.TrimSpace(tenantID)
+}
+
+func (r *notificationRepository) ListRecent(ctx context.Context, tenantID string, limit int) ([]models.Notification, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var notifications []models.Notification
+	for i := len(r.s.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		if notif := r.s.notifications[i]; visibleTo(notif, tenantID) {
+			notifications = append(notifications, notif)
+		}
+	}
+	return notifications, nil
+}
+
+// ListGroups mirrors the repository's grouping: notifications are scanned newest
+// first, so the first one seen of each group is its latest.
+func (r *notificationRepository) ListGroups(ctx context.Context, tenantID string, limit int) ([]models.NotificationGroup, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	groups := []models.NotificationGroup{}
+	index := map[string]int{}
+	for i := len(r.s.notifications) - 1; i >= 0; i-- {
+		notif := r.s.notifications[i]
+		if !visibleTo(notif, tenantID) {
+			continue
+		}
+		key := notif.CorrelationKey
+		if key == "" {
+			key = notif.ID
+		}
+		at, ok := index[key]
+		if !ok {
+			at = len(groups)
+			index[key] = at
+			groups = append(groups, models.NotificationGroup{CorrelationKey: notif.CorrelationKey, Latest: notif})
+		}
+		group := &groups[at]
+		group.Count++
+		if notif.ReadAt == nil {
+			group.UnreadCount++
+		}
+		group.FirstAt = notif.CreatedAt
+		if group.Status == "" {
+			group.Status = models.StatusAfter(notif.EventType)
+		}
+	}
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+func (r *notificationRepository) MarkGroupRead(ctx context.Context, tenantID, correlationKey string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var members, marked int64
+	for i, notif := range r.s.notifications {
+		if notif.CorrelationKey != strings.TrimSpace(correlationKey) || !visibleTo(notif, tenantID) {
+			continue
+		}
+		members++
+		if notif.ReadAt == nil {
+			now := r.s.now()
+			notif.ReadAt = &now
+			r.s.notifications[i] = notif
+			marked++
+		}
+	}
+	if members == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return marked, nil
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, tenantID, notificationID string) (models.Notification, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, notif := range r.s.notifications {
+		if notif.ID == strings.TrimSpace(notificationID) && visibleTo(notif, tenantID) {
+			now := r.s.now()
+			notif.ReadAt = &now
+			r.s.notifications[i] = notif
+			return notif, nil
+		}
+	}
+	return models.Notification{}, sql.ErrNoRows
+}
+
+func (r *notificationRepository) ListVersion(ctx context.Context, tenantID string) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count, created, read int64
+	for _, notif := range r.s.notifications {
+		if !visibleTo(notif, tenantID) {
+			continue
+		}
+		count++
+		if ts := notif.CreatedAt.UnixNano(); ts > created {
+			created = ts
+		}
+		if notif.ReadAt != nil && notif.ReadAt.UnixNano() > read {
+			read = notif.ReadAt.UnixNano()
+		}
+	}
+	return fmt.Sprintf("%d-%d-%d", count, created, read), nil
+}
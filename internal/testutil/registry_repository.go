@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type registryRepository struct {
+	s *Store
+}
+
+func (r *registryRepository) ListRegistryCredentials() ([]models.RegistryCredential, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	creds := []models.RegistryCredential{}
+	for _, cred := range r.s.registries {
+		creds = append(creds, cred)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Registry < creds[j].Registry })
+	return creds, nil
+}
+
+func (r *registryRepository) GetRegistryCredential(registry string) (models.RegistryCredential, string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cred, ok := r.s.registries[registry]
+	if !ok {
+		return cred, "", sql.ErrNoRows
+	}
+	return cred, r.s.registryPasswords[registry], nil
+}
+
+func (r *registryRepository) SaveRegistryCredential(cred models.RegistryCredential, password string) (models.RegistryCredential, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	if existing, ok := r.s.registries[cred.Registry]; ok {
+		cred.ID, cred.CreatedBy, cred.CreatedAt = existing.ID, existing.CreatedBy, existing.CreatedAt
+	} else {
+		cred.ID, cred.CreatedAt = newID(), now
+	}
+	cred.UpdatedAt = now
+	switch {
+	case cred.AuthType != models.RegistryAuthBasic:
+		delete(r.s.registryPasswords, cred.Registry)
+	case password != "":
+		r.s.registryPasswords[cred.Registry] = password
+	}
+	_, cred.HasPassword = r.s.registryPasswords[cred.Registry]
+	r.s.registries[cred.Registry] = cred
+	return cred, nil
+}
+
+func (r *registryRepository) DeleteRegistryCredential(id string) (models.RegistryCredential, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for registry, cred := range r.s.registries {
+		if cred.ID == id {
+			delete(r.s.registries, registry)
+			delete(r.s.registryPasswords, registry)
+			return cred, nil
+		}
+	}
+	return models.RegistryCredential{}, sql.ErrNoRows
+}
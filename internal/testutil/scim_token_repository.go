@@ -0,0 +1,60 @@
+package testutil
+
+import (
+	"database/sql"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type scimTokenRepository struct {
+	s *Store
+}
+
+func (r *scimTokenRepository) SetSCIMToken(token models.SCIMToken) (models.SCIMToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if token.CreatedBy != nil && *token.CreatedBy == "" {
+		token.CreatedBy = nil
+	}
+	token.CreatedAt, token.LastUsedAt = r.s.now(), nil
+	r.s.scimTokens[token.TenantID] = token
+	return token, nil
+}
+
+func (r *scimTokenRepository) GetSCIMToken(tenantID string) (models.SCIMToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	token, ok := r.s.scimTokens[tenantID]
+	if !ok {
+		return models.SCIMToken{}, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (r *scimTokenRepository) DeleteSCIMToken(tenantID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.scimTokens[tenantID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.s.scimTokens, tenantID)
+	return nil
+}
+
+func (r *scimTokenRepository) AuthenticateSCIMToken(tokenHash string) (models.SCIMToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for tenantID, token := range r.s.scimTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		if tenant, ok := r.s.tenants[tenantID]; !ok || tenant.SuspendedAt != nil {
+			continue
+		}
+		now := r.s.now()
+		token.LastUsedAt = &now
+		r.s.scimTokens[tenantID] = token
+		return token, nil
+	}
+	return models.SCIMToken{}, sql.ErrNoRows
+}
@@ -0,0 +1,112 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type sensorRepository struct {
+	s *Store
+}
+
+func (r *sensorRepository) ListSensors(tenantID, jobDefID string) ([]models.JobSensor, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if def, ok := r.s.definitions[jobDefID]; !ok || def.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+	sensors := []models.JobSensor{}
+	for _, sensor := range r.s.sensors {
+		if sensor.TenantID == tenantID && sensor.JobDefinitionID == jobDefID {
+			sensors = append(sensors, sensor)
+		}
+	}
+	sort.Slice(sensors, func(i, j int) bool { return sensors[i].Name < sensors[j].Name })
+	return sensors, nil
+}
+
+func (r *sensorRepository) GetSensor(tenantID, jobDefID, sensorID string) (models.JobSensor, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sensor, ok := r.s.sensors[sensorID]
+	if !ok || sensor.TenantID != tenantID || sensor.JobDefinitionID != jobDefID {
+		return models.JobSensor{}, sql.ErrNoRows
+	}
+	return sensor, nil
+}
+
+func (r *sensorRepository) CreateSensor(sensor models.JobSensor) (models.JobSensor, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if def, ok := r.s.definitions[sensor.JobDefinitionID]; !ok || def.TenantID != sensor.TenantID {
+		return models.JobSensor{}, sql.ErrNoRows
+	}
+	now := r.s.now()
+	sensor.ID, sensor.CreatedAt, sensor.UpdatedAt = newID(), now, now
+	r.s.sensors[sensor.ID] = sensor
+	return sensor, nil
+}
+
+func (r *sensorRepository) UpdateSensor(sensor models.JobSensor) (models.JobSensor, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.sensors[sensor.ID]
+	if !ok || current.TenantID != sensor.TenantID || current.JobDefinitionID != sensor.JobDefinitionID {
+		return models.JobSensor{}, sql.ErrNoRows
+	}
+	sensor.CreatedAt, sensor.UpdatedAt = current.CreatedAt, r.s.now()
+	r.s.sensors[sensor.ID] = sensor
+	return sensor, nil
+}
+
+func (r *sensorRepository) DeleteSensor(tenantID, jobDefID, sensorID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sensor, ok := r.s.sensors[sensorID]
+	if !ok || sensor.TenantID != tenantID || sensor.JobDefinitionID != jobDefID {
+		return sql.ErrNoRows
+	}
+	delete(r.s.sensors, sensorID)
+	return nil
+}
+
+func (r *sensorRepository) RecordSensorEvaluation(eval models.SensorEvaluation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	eval.ID, eval.EvaluatedAt = newID(), r.s.now()
+	r.s.evaluations = append(r.s.evaluations, eval)
+	return nil
+}
+
+func (r *sensorRepository) LastPassedSensorValue(tenantID, sensorID, excludeExecID string) (*int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i := len(r.s.evaluations) - 1; i >= 0; i-- {
+		eval := r.s.evaluations[i]
+		if eval.TenantID == tenantID && eval.SensorID == sensorID && eval.ExecutionID != excludeExecID && eval.Passed {
+			return eval.ObservedValue, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *sensorRepository) ListSensorEvaluations(tenantID, jobDefID, sensorID, execID string, limit int) ([]models.SensorEvaluation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if def, ok := r.s.definitions[jobDefID]; !ok || def.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+	evals := []models.SensorEvaluation{}
+	for i := len(r.s.evaluations) - 1; i >= 0 && (limit < 0 || len(evals) < limit); i-- {
+		eval := r.s.evaluations[i]
+		if eval.TenantID != tenantID || eval.JobDefinitionID != jobDefID {
+			continue
+		}
+		if (sensorID == "" || eval.SensorID == sensorID) && (execID == "" || eval.ExecutionID == execID) {
+			evals = append(evals, eval)
+		}
+	}
+	return evals, nil
+}
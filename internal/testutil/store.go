@@ -0,0 +1,211 @@
+// Package testutil provides in-memory fakes of the repositories, the Temporal
+// client and the mailers, and an HTTP harness that serves the API router on top of
+// them for handler tests.
+package testutil
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// Store holds the state behind every fake repository. Repositories taken from the
+// same store see each other's rows, the way repositories sharing a database do.
+type Store struct {
+	mu    sync.Mutex
+	clock time.Time
+
+	tenants         map[string]models.Tenant
+	workerConfigs   map[string]models.TenantWorkerConfig
+	executionLimits map[string]models.TenantExecutionLimits
+	blackoutWindows map[string]models.BlackoutWindow
+	users           map[string]models.User
+	deletedUsers    map[string]time.Time
+	erasedUsers     map[string]bool
+	verifications   map[string]emailVerification
+
+	connections        map[string]models.Connection
+	deletedConnections map[string]bool
+	definitions        map[string]models.JobDefinition
+	deletedDefinitions map[string]bool
+	archivedFrom       map[string]models.DefinitionStatus
+	runGrants          map[string]models.RunGrants
+	schedules          map[string]models.JobSchedule
+	secrets            map[string]map[string]definitionSecret
+	revalidations      map[string]models.DefinitionRevalidation
+	schemaChecks       map[string]models.DefinitionSchemaCheck
+	executions         map[string]models.JobExecution
+	// approvalRequests holds the workflow input of runs awaiting approval.
+	approvalRequests map[string]json.RawMessage
+	snapshots        map[string]models.ExecutionSnapshot
+	artifacts        []models.ExecutionArtifact
+	checkpoints      map[string][]models.ExecutionCheckpoint
+	executionTables  map[string][]models.JobExecutionTable
+	logChunks        map[string][]models.ExecutionLogChunk
+	executionEvents  map[string][]models.ExecutionEvent
+	notes            []models.ExecutionNote
+	regressions      []models.ExecutionRegression
+	// runGroups holds run groups with their members' pending or skipped status;
+	// runGroupReports the reports of finished ones.
+	runGroups       map[string]models.RunGroup
+	runGroupReports map[string]models.RunGroupReport
+
+	invites        map[string]models.Invite
+	cancelled      map[string]bool
+	apiKeys        map[string]models.APIKey
+	scimTokens     map[string]models.SCIMToken
+	auditEvents    []models.AuditEvent
+	accessLogs     []models.AccessLog
+	domains        map[string]models.EmailDomain
+	joinRequests   map[string]models.DomainJoinRequest
+	notifications  []models.Notification
+	sensors        map[string]models.JobSensor
+	evaluations    []models.SensorEvaluation
+	templates      map[string]models.JobTemplate
+	views          map[string]models.SavedView
+	defaultViews   map[string]string
+	subscriptions  map[string]models.WebhookSubscription
+	webhookSecrets map[string][]byte
+	webhookEvents  []models.WebhookEvent
+	deliveries     map[string]models.WebhookDelivery
+	announcements  map[string]models.Announcement
+	maintenance    models.MaintenanceMode
+	instance       *models.Instance
+	// registries holds registry credentials by registry host, with their
+	// plaintext passwords in registryPasswords.
+	registries        map[string]models.RegistryCredential
+	registryPasswords map[string]string
+	// engineVersions holds when each engine version was recorded, engineFormats
+	// the format pairs it migrates and currentEngine the running engine's version.
+	engineVersions map[string]time.Time
+	engineFormats  map[string][]models.FormatPair
+	currentEngine  string
+}
+
+type emailVerification struct {
+	userID    string
+	email     string
+	expiresAt time.Time
+}
+
+type definitionSecret struct {
+	value string
+	models.DefinitionSecret
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		clock:              time.Now().UTC().Truncate(time.Second),
+		tenants:            make(map[string]models.Tenant),
+		workerConfigs:      make(map[string]models.TenantWorkerConfig),
+		executionLimits:    make(map[string]models.TenantExecutionLimits),
+		blackoutWindows:    make(map[string]models.BlackoutWindow),
+		users:              make(map[string]models.User),
+		deletedUsers:       make(map[string]time.Time),
+		erasedUsers:        make(map[string]bool),
+		verifications:      make(map[string]emailVerification),
+		connections:        make(map[string]models.Connection),
+		deletedConnections: make(map[string]bool),
+		definitions:        make(map[string]models.JobDefinition),
+		deletedDefinitions: make(map[string]bool),
+		archivedFrom:       make(map[string]models.DefinitionStatus),
+		runGrants:          make(map[string]models.RunGrants),
+		schedules:          make(map[string]models.JobSchedule),
+		secrets:            make(map[string]map[string]definitionSecret),
+		revalidations:      make(map[string]models.DefinitionRevalidation),
+		schemaChecks:       make(map[string]models.DefinitionSchemaCheck),
+		executions:         make(map[string]models.JobExecution),
+		runGroups:          make(map[string]models.RunGroup),
+		runGroupReports:    make(map[string]models.RunGroupReport),
+		approvalRequests:   make(map[string]json.RawMessage),
+		snapshots:          make(map[string]models.ExecutionSnapshot),
+		checkpoints:        make(map[string][]models.ExecutionCheckpoint),
+		executionTables:    make(map[string][]models.JobExecutionTable),
+		logChunks:          make(map[string][]models.ExecutionLogChunk),
+		executionEvents:    make(map[string][]models.ExecutionEvent),
+		invites:            make(map[string]models.Invite),
+		cancelled:          make(map[string]bool),
+		apiKeys:            make(map[string]models.APIKey),
+		scimTokens:         make(map[string]models.SCIMToken),
+		domains:            make(map[string]models.EmailDomain),
+		joinRequests:       make(map[string]models.DomainJoinRequest),
+		sensors:            make(map[string]models.JobSensor),
+		templates:          make(map[string]models.JobTemplate),
+		views:              make(map[string]models.SavedView),
+		defaultViews:       make(map[string]string),
+		subscriptions:      make(map[string]models.WebhookSubscription),
+		webhookSecrets:     make(map[string][]byte),
+		deliveries:         make(map[string]models.WebhookDelivery),
+		announcements:      make(map[string]models.Announcement),
+		registries:         make(map[string]models.RegistryCredential),
+		registryPasswords:  make(map[string]string),
+		engineVersions:     make(map[string]time.Time),
+		engineFormats:      make(map[string][]models.FormatPair),
+	}
+}
+
+// now advances the store's clock by a millisecond, so rows written one after
+// another sort in the order they were written. Callers hold s.mu.
+func (s *Store) now() time.Time {
+	s.clock = s.clock.Add(time.Millisecond)
+	return s.clock
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+func (s *Store) Jobs() repository.JobRepository                 { return &jobRepository{s} }
+func (s *Store) Connections() repository.ConnectionRepository   { return &connectionRepository{s} }
+func (s *Store) Users() repository.UserRepository               { return &userRepository{s} }
+func (s *Store) Tenants() repository.TenantRepository           { return &tenantRepository{s} }
+func (s *Store) Invites() repository.InviteRepository           { return &inviteRepository{s} }
+func (s *Store) APIKeys() repository.APIKeyRepository           { return &apiKeyRepository{s} }
+func (s *Store) Audit() repository.AuditRepository              { return &auditRepository{s} }
+func (s *Store) EmailDomains() repository.EmailDomainRepository { return &emailDomainRepository{s} }
+func (s *Store) Instance() repository.InstanceRepository        { return &instanceRepository{s} }
+func (s *Store) Notifications() repository.NotificationRepository {
+	return &notificationRepository{s}
+}
+func (s *Store) Sensors() repository.SensorRepository             { return &sensorRepository{s} }
+func (s *Store) Templates() repository.TemplateRepository         { return &templateRepository{s} }
+func (s *Store) Views() repository.ViewRepository                 { return &viewRepository{s} }
+func (s *Store) Webhooks() repository.WebhookRepository           { return &webhookRepository{s} }
+func (s *Store) Announcements() repository.AnnouncementRepository { return &announcementRepository{s} }
+func (s *Store) Registries() repository.RegistryRepository        { return &registryRepository{s} }
+func (s *Store) SCIMTokens() repository.SCIMTokenRepository       { return &scimTokenRepository{s} }
+func (s *Store) Engines() repository.EngineRepository             { return &engineRepository{s} }
+
+// AuditEvents returns the audit events recorded so far, oldest first.
+func (s *Store) AuditEvents() []models.AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.AuditEvent(nil), s.auditEvents...)
+}
+
+// EphemeralConnections returns the tenant's inline DSN connections that are not
+// deleted.
+func (s *Store) EphemeralConnections(tenantID string) []models.Connection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var conns []models.Connection
+	for id, conn := range s.connections {
+		if conn.TenantID == tenantID && conn.Ephemeral && !s.deletedConnections[id] {
+			conns = append(conns, cloneConnection(conn))
+		}
+	}
+	return conns
+}
+
+// WebhookEvents returns every webhook event queued so far, oldest first, whether or
+// not a subscription covered it.
+func (s *Store) WebhookEvents() []models.WebhookEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.WebhookEvent(nil), s.webhookEvents...)
+}
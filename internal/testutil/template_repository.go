@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type templateRepository struct {
+	s *Store
+}
+
+func sameOwner(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// loadTemplate derives the fields the repository computes when reading a template.
+func loadTemplate(t models.JobTemplate) (models.JobTemplate, error) {
+	t.Global = t.TenantID == nil
+	placeholders, err := models.TemplatePlaceholders(t.AST)
+	if err != nil {
+		return t, err
+	}
+	t.Placeholders = placeholders
+	return t, nil
+}
+
+func (r *templateRepository) ListTemplates(tenantID, kind string) ([]models.JobTemplate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	templates := []models.JobTemplate{}
+	for _, t := range r.s.templates {
+		if (t.TenantID != nil && *t.TenantID != tenantID) || (kind != "" && t.Kind != kind) {
+			continue
+		}
+		loaded, err := loadTemplate(t)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, loaded)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Global != templates[j].Global {
+			return !templates[i].Global
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+func (r *templateRepository) GetTemplate(tenantID, templateID string) (models.JobTemplate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.templates[templateID]
+	if !ok || (t.TenantID != nil && *t.TenantID != tenantID) {
+		return models.JobTemplate{}, sql.ErrNoRows
+	}
+	return loadTemplate(t)
+}
+
+func (r *templateRepository) CreateTemplate(template models.JobTemplate) (models.JobTemplate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	template.ID, template.CreatedAt, template.UpdatedAt = newID(), now, now
+	r.s.templates[template.ID] = template
+	return loadTemplate(template)
+}
+
+func (r *templateRepository) UpdateTemplate(template models.JobTemplate) (models.JobTemplate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.templates[template.ID]
+	if !ok || !sameOwner(current.TenantID, template.TenantID) {
+		return models.JobTemplate{}, sql.ErrNoRows
+	}
+	current.Name, current.Description, current.Kind, current.AST = template.Name, template.Description, template.Kind, template.AST
+	current.UpdatedAt = r.s.now()
+	r.s.templates[template.ID] = current
+	return loadTemplate(current)
+}
+
+func (r *templateRepository) DeleteTemplate(template models.JobTemplate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.templates[template.ID]
+	if !ok || !sameOwner(current.TenantID, template.TenantID) {
+		return sql.ErrNoRows
+	}
+	delete(r.s.templates, template.ID)
+	return nil
+}
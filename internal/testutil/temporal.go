@@ -0,0 +1,215 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"go.temporal.io/api/serviceerror"
+	tc "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+// StartedWorkflow is a workflow started through FakeTemporal.
+type StartedWorkflow struct {
+	Options tc.StartWorkflowOptions
+	Args    []interface{}
+}
+
+// SentSignal is a signal sent through FakeTemporal.
+type SentSignal struct {
+	WorkflowID string
+	Name       string
+	Arg        interface{}
+}
+
+// FakeTemporal records the workflows the API starts, signals, cancels and terminates, and the
+// state of the schedules it manages, without running anything. Client methods it does not override panic, so a test touching them
+// fails loudly instead of passing against a no-op.
+type FakeTemporal struct {
+	tc.Client
+
+	mu         sync.Mutex
+	started    []StartedWorkflow
+	signals    []SentSignal
+	cancelled  []string
+	terminated []string
+	// schedules maps the ID of every schedule the API touched to whether it is paused.
+	schedules map[string]bool
+	// queries holds the answer of each workflow to each query type, by workflow ID.
+	queries map[string]map[string]interface{}
+}
+
+// NewFakeTemporal returns a client that has recorded nothing yet.
+func NewFakeTemporal() *FakeTemporal {
+	return &FakeTemporal{schedules: make(map[string]bool), queries: make(map[string]map[string]interface{})}
+}
+
+func (f *FakeTemporal) ExecuteWorkflow(ctx context.Context, options tc.StartWorkflowOptions, workflow interface{}, args ...interface{}) (tc.WorkflowRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, StartedWorkflow{Options: options, Args: args})
+	return &workflowRun{id: options.ID, runID: newID()}, nil
+}
+
+func (f *FakeTemporal) SignalWorkflow(ctx context.Context, workflowID, runID, signalName string, arg interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signals = append(f.signals, SentSignal{WorkflowID: workflowID, Name: signalName, Arg: arg})
+	return nil
+}
+
+func (f *FakeTemporal) CancelWorkflow(ctx context.Context, workflowID, runID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancelled = append(f.cancelled, workflowID)
+	return nil
+}
+
+func (f *FakeTemporal) TerminateWorkflow(ctx context.Context, workflowID, runID, reason string, details ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, workflowID)
+	return nil
+}
+
+// QueryWorkflow answers with the result set through SetQueryResult, and like a
+// server without the workflow otherwise.
+func (f *FakeTemporal) QueryWorkflow(ctx context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result, ok := f.queries[workflowID][queryType]
+	if !ok {
+		return nil, serviceerror.NewNotFound("workflow not found: " + workflowID)
+	}
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return encodedValue(payload), nil
+}
+
+func (f *FakeTemporal) CheckHealth(ctx context.Context, request *tc.CheckHealthRequest) (*tc.CheckHealthResponse, error) {
+	return &tc.CheckHealthResponse{}, nil
+}
+
+func (f *FakeTemporal) ScheduleClient() tc.ScheduleClient {
+	return &scheduleClient{f: f}
+}
+
+// SchedulePaused reports whether the schedule is paused, and whether the API ever
+// created or touched it.
+func (f *FakeTemporal) SchedulePaused(scheduleID string) (paused, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	paused, ok = f.schedules[scheduleID]
+	return paused, ok
+}
+
+// Started returns the workflows started so far, oldest first.
+func (f *FakeTemporal) Started() []StartedWorkflow {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]StartedWorkflow(nil), f.started...)
+}
+
+// Signals returns the signals sent so far, oldest first.
+func (f *FakeTemporal) Signals() []SentSignal {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SentSignal(nil), f.signals...)
+}
+
+// Cancelled returns the IDs of the workflows cancelled so far, oldest first.
+func (f *FakeTemporal) Cancelled() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.cancelled...)
+}
+
+// Terminated returns the IDs of the workflows terminated so far, oldest first.
+func (f *FakeTemporal) Terminated() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.terminated...)
+}
+
+// SetQueryResult makes the workflow answer queryType with result.
+func (f *FakeTemporal) SetQueryResult(workflowID, queryType string, result interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.queries[workflowID] == nil {
+		f.queries[workflowID] = make(map[string]interface{})
+	}
+	f.queries[workflowID][queryType] = result
+}
+
+// encodedValue is a query result, JSON-encoded like the default data converter does.
+type encodedValue []byte
+
+func (v encodedValue) HasValue() bool { return len(v) > 0 }
+
+func (v encodedValue) Get(valuePtr interface{}) error { return json.Unmarshal(v, valuePtr) }
+
+type workflowRun struct {
+	tc.WorkflowRun
+	id    string
+	runID string
+}
+
+func (r *workflowRun) GetID() string    { return r.id }
+func (r *workflowRun) GetRunID() string { return r.runID }
+
+type scheduleClient struct {
+	tc.ScheduleClient
+	f *FakeTemporal
+}
+
+func (c *scheduleClient) Create(ctx context.Context, options tc.ScheduleOptions) (tc.ScheduleHandle, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if _, exists := c.f.schedules[options.ID]; exists {
+		return nil, sdktemporal.ErrScheduleAlreadyRunning
+	}
+	c.f.schedules[options.ID] = options.Paused
+	return &scheduleHandle{f: c.f, id: options.ID}, nil
+}
+
+func (c *scheduleClient) GetHandle(ctx context.Context, scheduleID string) tc.ScheduleHandle {
+	return &scheduleHandle{f: c.f, id: scheduleID}
+}
+
+type scheduleHandle struct {
+	tc.ScheduleHandle
+	f  *FakeTemporal
+	id string
+}
+
+func (h *scheduleHandle) GetID() string { return h.id }
+
+func (h *scheduleHandle) Update(ctx context.Context, options tc.ScheduleUpdateOptions) error {
+	return nil
+}
+
+func (h *scheduleHandle) Delete(ctx context.Context) error {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	delete(h.f.schedules, h.id)
+	return nil
+}
+
+func (h *scheduleHandle) Pause(ctx context.Context, options tc.SchedulePauseOptions) error {
+	return h.setPaused(true)
+}
+
+func (h *scheduleHandle) Unpause(ctx context.Context, options tc.ScheduleUnpauseOptions) error {
+	return h.setPaused(false)
+}
+
+func (h *scheduleHandle) setPaused(paused bool) error {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	h.f.schedules[h.id] = paused
+	return nil
+}
@@ -0,0 +1,233 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type tenantRepository struct {
+	s *Store
+}
+
+func (r *tenantRepository) CreateTenant(name string) (models.Tenant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	tenant := models.Tenant{
+		ID:                  newID(),
+		Name:                name,
+		Timezone:            "UTC",
+		Locale:              "en-US",
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		ValidationFreshness: models.ValidationFreshnessOff,
+	}
+	r.s.tenants[tenant.ID] = tenant
+	return tenant, nil
+}
+
+func (r *tenantRepository) GetTenantByID(id string) (models.Tenant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tenant, ok := r.s.tenants[id]
+	if !ok {
+		return models.Tenant{}, sql.ErrNoRows
+	}
+	return tenant, nil
+}
+
+func (r *tenantRepository) UpdateTenant(id string, update repository.TenantUpdate) (models.Tenant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tenant, ok := r.s.tenants[id]
+	if !ok {
+		return models.Tenant{}, sql.ErrNoRows
+	}
+	if update.RequireVerifiedEmail != nil {
+		tenant.RequireVerifiedEmail = *update.RequireVerifiedEmail
+	}
+	if update.Timezone != nil {
+		tenant.Timezone = *update.Timezone
+	}
+	if update.Locale != nil {
+		tenant.Locale = *update.Locale
+	}
+	if update.DataRegion != nil {
+		tenant.DataRegion = nil
+		if *update.DataRegion != "" {
+			region := *update.DataRegion
+			tenant.DataRegion = &region
+		}
+	}
+	if update.BlockCrossEnvironmentJobs != nil {
+		tenant.BlockCrossEnvironmentJobs = *update.BlockCrossEnvironmentJobs
+	}
+	if update.RequireArtifactScan != nil {
+		tenant.RequireArtifactScan = *update.RequireArtifactScan
+	}
+	if update.RequireRunApproval != nil {
+		tenant.RequireRunApproval = *update.RequireRunApproval
+	}
+	if update.ValidationFreshness != nil {
+		tenant.ValidationFreshness = *update.ValidationFreshness
+	}
+	tenant.UpdatedAt = r.s.now()
+	r.s.tenants[id] = tenant
+	return tenant, nil
+}
+
+func (r *tenantRepository) ScheduleTenantDeletion(id string, purgeAfter time.Time) (models.Tenant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tenant, ok := r.s.tenants[id]
+	if !ok || tenant.SuspendedAt != nil {
+		return models.Tenant{}, sql.ErrNoRows
+	}
+	now := r.s.now()
+	tenant.SuspendedAt, tenant.PurgeAfter, tenant.UpdatedAt = &now, &purgeAfter, now
+	r.s.tenants[id] = tenant
+	return tenant, nil
+}
+
+func (r *tenantRepository) RestoreTenant(id string) (models.Tenant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tenant, ok := r.s.tenants[id]
+	if !ok || tenant.SuspendedAt == nil || tenant.PurgeAfter == nil || !tenant.PurgeAfter.After(time.Now()) {
+		return models.Tenant{}, sql.ErrNoRows
+	}
+	tenant.SuspendedAt, tenant.PurgeAfter, tenant.UpdatedAt = nil, nil, r.s.now()
+	r.s.tenants[id] = tenant
+	return tenant, nil
+}
+
+func (r *tenantRepository) PurgeTenant(id string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tenant, ok := r.s.tenants[id]
+	if !ok || tenant.SuspendedAt == nil || tenant.PurgeAfter == nil || tenant.PurgeAfter.After(time.Now()) {
+		return false, nil
+	}
+	delete(r.s.tenants, id)
+	return true, nil
+}
+
+func (r *tenantRepository) GetWorkerConfig(tenantID string) (models.TenantWorkerConfig, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if cfg, ok := r.s.workerConfigs[tenantID]; ok {
+		return cfg, nil
+	}
+	return models.TenantWorkerConfig{TenantID: tenantID}, nil
+}
+
+func (r *tenantRepository) SaveWorkerConfig(cfg models.TenantWorkerConfig) (models.TenantWorkerConfig, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	cfg.UpdatedAt = &now
+	r.s.workerConfigs[cfg.TenantID] = cfg
+	return cfg, nil
+}
+
+func (r *tenantRepository) GetExecutionLimits(tenantID string) (models.TenantExecutionLimits, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if limits, ok := r.s.executionLimits[tenantID]; ok {
+		return limits, nil
+	}
+	return models.TenantExecutionLimits{TenantID: tenantID}, nil
+}
+
+func (r *tenantRepository) SaveExecutionLimits(limits models.TenantExecutionLimits) (models.TenantExecutionLimits, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	limits.UpdatedAt = &now
+	r.s.executionLimits[limits.TenantID] = limits
+	return limits, nil
+}
+
+func (r *tenantRepository) ListExecutionStarts(tenantID string, since time.Time) ([]time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var starts []time.Time
+	for _, exec := range r.s.executions {
+		if exec.TenantID != tenantID || !exec.CreatedAt.After(since) ||
+			exec.Status == models.ExecutionStatusAwaitingApproval || exec.Status == models.ExecutionStatusSkipped {
+			continue
+		}
+		starts = append(starts, exec.CreatedAt)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts, nil
+}
+
+func (r *tenantRepository) ListBlackoutWindows(tenantID string) ([]models.BlackoutWindow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	windows := []models.BlackoutWindow{}
+	for _, window := range r.s.blackoutWindows {
+		if window.TenantID == tenantID {
+			windows = append(windows, window)
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].CreatedAt.Before(windows[j].CreatedAt) })
+	return windows, nil
+}
+
+func (r *tenantRepository) CreateBlackoutWindow(window models.BlackoutWindow) (models.BlackoutWindow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	window.ID = newID()
+	window.CreatedAt = r.s.now()
+	r.s.blackoutWindows[window.ID] = window
+	return window, nil
+}
+
+func (r *tenantRepository) DeleteBlackoutWindow(tenantID, windowID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	window, ok := r.s.blackoutWindows[windowID]
+	if !ok || window.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	delete(r.s.blackoutWindows, windowID)
+	return nil
+}
+
+func (r *tenantRepository) ListTenantUsage(monthStart time.Time) ([]models.TenantUsage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var usage []models.TenantUsage
+	for _, tenant := range r.s.tenants {
+		if tenant.SuspendedAt != nil {
+			continue
+		}
+		u := models.TenantUsage{TenantID: tenant.ID, TenantName: tenant.Name}
+		for _, exec := range r.s.executions {
+			if exec.TenantID != tenant.ID || exec.CreatedAt.Before(monthStart) {
+				continue
+			}
+			u.ExecutionsThisMonth++
+			if exec.BytesTransferred != nil {
+				u.BytesThisMonth += *exec.BytesTransferred
+			}
+		}
+		for id, user := range r.s.users {
+			if _, deleted := r.s.deletedUsers[id]; deleted {
+				continue
+			}
+			if user.TenantID == tenant.ID && user.IsActive {
+				u.ActiveUsers++
+			}
+		}
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].TenantName < usage[j].TenantName })
+	return usage, nil
+}
@@ -0,0 +1,339 @@
+package testutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type userRepository struct {
+	s *Store
+}
+
+func (r *userRepository) CreateUser(tenantID, email, password, firstName, lastName string, roles []models.UserRole) (models.User, error) {
+	return r.createUser(tenantID, email, password, firstName, lastName, roles, true)
+}
+
+func (r *userRepository) CreateInactiveUser(tenantID, email, password, firstName, lastName string, roles []models.UserRole) (models.User, error) {
+	return r.createUser(tenantID, email, password, firstName, lastName, roles, false)
+}
+
+func (r *userRepository) createUser(tenantID, email, password, firstName, lastName string, roles []models.UserRole, active bool) (models.User, error) {
+	if len(roles) == 0 {
+		roles = []models.UserRole{models.RoleViewer}
+	}
+	if !models.IsValidRoleList(roles) {
+		return models.User{}, errors.New("invalid roles")
+	}
+	// The lowest cost keeps tests fast; the hash is still checked like a real one.
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.tenants[tenantID]; !ok {
+		return models.User{}, errors.New("tenant does not exist")
+	}
+	if _, ok := r.s.userByEmail(email); ok {
+		return models.User{}, errors.New("user with this email already exists")
+	}
+	user := models.User{
+		ID:           newID(),
+		TenantID:     tenantID,
+		Email:        email,
+		FirstName:    strings.TrimSpace(firstName),
+		LastName:     strings.TrimSpace(lastName),
+		PasswordHash: string(hash),
+		IsActive:     active,
+		Roles:        models.EnsureDefaultRole(models.NormalizeRoles(roles)),
+	}
+	r.s.users[user.ID] = user
+	r.s.now()
+	return user, nil
+}
+
+// userByEmail finds a live user by email. Callers hold s.mu.
+func (s *Store) userByEmail(email string) (models.User, bool) {
+	for id, user := range s.users {
+		if _, deleted := s.deletedUsers[id]; !deleted && user.Email == email {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// liveUser returns a user that is not deleted. Callers hold s.mu.
+func (s *Store) liveUser(userID string) (models.User, bool) {
+	user, ok := s.users[userID]
+	if !ok {
+		return models.User{}, false
+	}
+	if _, deleted := s.deletedUsers[userID]; deleted {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (r *userRepository) AuthenticateUser(email, password string) (models.User, error) {
+	r.s.mu.Lock()
+	user, ok := r.s.userByEmail(email)
+	r.s.mu.Unlock()
+	if !ok {
+		return models.User{}, errors.New("invalid credentials")
+	}
+	if !user.IsActive {
+		return models.User{}, errors.New("user is inactive")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return models.User{}, errors.New("invalid credentials")
+	}
+	return user, nil
+}
+
+func (r *userRepository) ListUsersByTenant(tenantID string) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var users []models.User
+	for id, user := range r.s.users {
+		if _, deleted := r.s.deletedUsers[id]; !deleted && user.TenantID == tenantID {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (r *userRepository) GetUserByEmail(email string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.userByEmail(email)
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetUserByID(userID string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetUserIncludingDeleted(userID string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.users[userID]
+	if !ok || r.s.erasedUsers[userID] {
+		return models.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *userRepository) UpdateUserRoles(userID string, roles []models.UserRole) (models.User, error) {
+	if len(roles) == 0 {
+		return models.User{}, errors.New("roles cannot be empty")
+	}
+	if !models.IsValidRoleList(roles) {
+		return models.User{}, errors.New("invalid roles")
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	user.Roles = models.EnsureDefaultRole(models.NormalizeRoles(roles))
+	r.s.users[userID] = user
+	return user, nil
+}
+
+func (r *userRepository) UpdateUserProfile(tenantID, userID, firstName, lastName string, active bool) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok || user.TenantID != tenantID {
+		return models.User{}, sql.ErrNoRows
+	}
+	user.FirstName, user.LastName, user.IsActive = strings.TrimSpace(firstName), strings.TrimSpace(lastName), active
+	r.s.users[userID] = user
+	return user, nil
+}
+
+func (r *userRepository) DeleteUser(userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.IsActive = false
+	r.s.users[userID] = user
+	r.s.deletedUsers[userID] = r.s.now()
+	return nil
+}
+
+func (r *userRepository) UpdateUserEmail(userID, email string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	if other, taken := r.s.userByEmail(email); taken && other.ID != userID {
+		return models.User{}, errors.New("user with this email already exists")
+	}
+	user.Email, user.EmailVerifiedAt = email, nil
+	r.s.users[userID] = user
+	return user, nil
+}
+
+func (r *userRepository) UpdatePassword(userID, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.PasswordHash = string(hash)
+	r.s.users[userID] = user
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.liveUser(userID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if user.EmailVerifiedAt == nil {
+		now := r.s.now()
+		user.EmailVerifiedAt = &now
+	}
+	r.s.users[userID] = user
+	return nil
+}
+
+func (r *userRepository) CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.verifications[tokenHash] = emailVerification{userID: userID, email: email, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *userRepository) ConsumeEmailVerification(tokenHash string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	v, ok := r.s.verifications[tokenHash]
+	if !ok || !v.expiresAt.After(time.Now()) {
+		return models.User{}, sql.ErrNoRows
+	}
+	delete(r.s.verifications, tokenHash)
+	user, ok := r.s.liveUser(v.userID)
+	if !ok || user.Email != v.email {
+		return models.User{}, sql.ErrNoRows
+	}
+	if user.EmailVerifiedAt == nil {
+		now := r.s.now()
+		user.EmailVerifiedAt = &now
+	}
+	r.s.users[user.ID] = user
+	return user, nil
+}
+
+func (r *userRepository) PruneEmailVerifications(before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var pruned int64
+	for hash, v := range r.s.verifications {
+		if v.expiresAt.Before(before) {
+			delete(r.s.verifications, hash)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (r *userRepository) EraseUser(tenantID, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.users[userID]
+	if !ok || r.s.erasedUsers[userID] || (tenantID != "" && user.TenantID != tenantID) {
+		return sql.ErrNoRows
+	}
+	tombstone := models.ErasedEmail(userID)
+	for id, invite := range r.s.invites {
+		if invite.TenantID == user.TenantID && strings.EqualFold(invite.Email, user.Email) {
+			invite.Email = tombstone
+			r.s.invites[id] = invite
+		}
+	}
+	for i, event := range r.s.auditEvents {
+		if event.TenantID == nil || *event.TenantID == user.TenantID {
+			r.s.auditEvents[i].Details = replaceInJSON(event.Details, user.Email, tombstone)
+		}
+	}
+	for id, d := range r.s.deliveries {
+		if d.TenantID == user.TenantID {
+			d.Payload = json.RawMessage(strings.ReplaceAll(string(d.Payload), user.Email, tombstone))
+			r.s.deliveries[id] = d
+		}
+	}
+	user.Email, user.FirstName, user.LastName, user.PasswordHash = tombstone, "", "", ""
+	user.IsActive, user.EmailVerifiedAt = false, nil
+	r.s.users[userID] = user
+	if _, deleted := r.s.deletedUsers[userID]; !deleted {
+		r.s.deletedUsers[userID] = r.s.now()
+	}
+	r.s.erasedUsers[userID] = true
+	return nil
+}
+
+func (r *userRepository) ListErasableUsers(deletedBefore time.Time, limit int) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []string
+	for id, deletedAt := range r.s.deletedUsers {
+		if deletedAt.Before(deletedBefore) && !r.s.erasedUsers[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return r.s.deletedUsers[ids[i]].Before(r.s.deletedUsers[ids[j]]) })
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// replaceInJSON replaces old with new in the JSON encoding of v, as EraseUser does
+// with replace() on JSONB columns.
+func replaceInJSON(v map[string]interface{}, old, new string) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var replaced map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.ReplaceAll(string(raw), old, new)), &replaced); err != nil {
+		return v
+	}
+	return replaced
+}
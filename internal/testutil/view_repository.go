@@ -0,0 +1,116 @@
+package testutil
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/stanstork/stratum-api/internal/models"
+)
+
+type viewRepository struct {
+	s *Store
+}
+
+func defaultViewKey(userID, list string) string {
+	return userID + "/" + list
+}
+
+// visibleView returns a view the user owns or that is shared in the tenant, marked
+// with whether it is the user's default. Callers hold s.mu.
+func (s *Store) visibleView(tenantID, userID, viewID string) (models.SavedView, bool) {
+	v, ok := s.views[viewID]
+	if !ok || v.TenantID != tenantID || (v.OwnerID != nil && *v.OwnerID != userID) {
+		return models.SavedView{}, false
+	}
+	v.Shared = v.OwnerID == nil
+	v.IsDefault = s.defaultViews[defaultViewKey(userID, v.List)] == v.ID
+	return v, true
+}
+
+func (r *viewRepository) ListViews(tenantID, userID, list string) ([]models.SavedView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	views := []models.SavedView{}
+	for id := range r.s.views {
+		if v, ok := r.s.visibleView(tenantID, userID, id); ok && (list == "" || v.List == list) {
+			views = append(views, v)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		a, b := views[i], views[j]
+		if a.Shared != b.Shared {
+			return !a.Shared
+		}
+		if a.List != b.List {
+			return a.List < b.List
+		}
+		return a.Name < b.Name
+	})
+	return views, nil
+}
+
+func (r *viewRepository) GetView(tenantID, userID, viewID string) (models.SavedView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	v, ok := r.s.visibleView(tenantID, userID, viewID)
+	if !ok {
+		return models.SavedView{}, sql.ErrNoRows
+	}
+	return v, nil
+}
+
+func (r *viewRepository) CreateView(view models.SavedView) (models.SavedView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	view.ID, view.CreatedAt, view.UpdatedAt = newID(), now, now
+	view.Shared, view.IsDefault = view.OwnerID == nil, false
+	r.s.views[view.ID] = view
+	return view, nil
+}
+
+func (r *viewRepository) UpdateView(userID string, view models.SavedView) (models.SavedView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.views[view.ID]
+	if !ok || current.TenantID != view.TenantID || !sameOwner(current.OwnerID, view.OwnerID) {
+		return models.SavedView{}, sql.ErrNoRows
+	}
+	current.Name, current.Filters, current.Sort = view.Name, view.Filters, view.Sort
+	current.UpdatedAt = r.s.now()
+	r.s.views[view.ID] = current
+	current.Shared = current.OwnerID == nil
+	current.IsDefault = r.s.defaultViews[defaultViewKey(userID, current.List)] == current.ID
+	return current, nil
+}
+
+func (r *viewRepository) DeleteView(view models.SavedView) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.views[view.ID]
+	if !ok || current.TenantID != view.TenantID || !sameOwner(current.OwnerID, view.OwnerID) {
+		return sql.ErrNoRows
+	}
+	delete(r.s.views, view.ID)
+	for key, id := range r.s.defaultViews {
+		if id == view.ID {
+			delete(r.s.defaultViews, key)
+		}
+	}
+	return nil
+}
+
+func (r *viewRepository) SetDefaultView(tenantID, userID, list string, viewID *string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if viewID == nil {
+		delete(r.s.defaultViews, defaultViewKey(userID, list))
+		return nil
+	}
+	v, ok := r.s.visibleView(tenantID, userID, *viewID)
+	if !ok || v.List != list {
+		return sql.ErrNoRows
+	}
+	r.s.defaultViews[defaultViewKey(userID, list)] = v.ID
+	return nil
+}
@@ -0,0 +1,214 @@
+package testutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+type webhookRepository struct {
+	s *Store
+}
+
+func (r *webhookRepository) ListSubscriptions(tenantID, category string) ([]models.WebhookSubscription, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	subs := []models.WebhookSubscription{}
+	for _, sub := range r.s.subscriptions {
+		if sub.TenantID == tenantID && sub.Category == category {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs, nil
+}
+
+func (r *webhookRepository) GetSubscription(tenantID, subscriptionID string) (models.WebhookSubscription, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sub, ok := r.s.subscriptions[subscriptionID]
+	if !ok || sub.TenantID != tenantID {
+		return models.WebhookSubscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (r *webhookRepository) CreateSubscription(sub models.WebhookSubscription, secret []byte) (models.WebhookSubscription, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	sub.ID, sub.CreatedAt, sub.UpdatedAt = newID(), now, now
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+	r.s.subscriptions[sub.ID] = sub
+	r.s.webhookSecrets[sub.ID] = secret
+	return sub, nil
+}
+
+func (r *webhookRepository) UpdateSubscription(sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.subscriptions[sub.ID]
+	if !ok || current.TenantID != sub.TenantID {
+		return models.WebhookSubscription{}, sql.ErrNoRows
+	}
+	current.URL, current.Events, current.Active, current.PayloadTemplate = sub.URL, sub.Events, sub.Active, sub.PayloadTemplate
+	if current.Events == nil {
+		current.Events = []string{}
+	}
+	current.UpdatedAt = r.s.now()
+	r.s.subscriptions[sub.ID] = current
+	return current, nil
+}
+
+func (r *webhookRepository) DeleteSubscription(tenantID, subscriptionID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sub, ok := r.s.subscriptions[subscriptionID]
+	if !ok || sub.TenantID != tenantID {
+		return sql.ErrNoRows
+	}
+	delete(r.s.subscriptions, subscriptionID)
+	delete(r.s.webhookSecrets, subscriptionID)
+	for id, d := range r.s.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			delete(r.s.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (r *webhookRepository) EnqueueEvent(evt models.WebhookEvent) (int64, error) {
+	raw, err := json.Marshal(evt)
+	if err != nil {
+		return 0, err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.webhookEvents = append(r.s.webhookEvents, evt)
+	var queued int64
+	for _, sub := range r.s.subscriptions {
+		if sub.TenantID != evt.TenantID || sub.Category != evt.Category || !sub.Active {
+			continue
+		}
+		if len(sub.Events) > 0 && !hasAction(sub.Events, evt.Type) {
+			continue
+		}
+		d := r.newDelivery(sub, evt.Type, raw)
+		if payload, err := models.RenderWebhookPayload(sub, evt); err != nil {
+			msg := "render payload: " + err.Error()
+			d.Status, d.LastError = models.WebhookDeliveryFailed, &msg
+		} else {
+			d.Payload = payload
+		}
+		r.s.deliveries[d.ID] = d
+		queued++
+	}
+	return queued, nil
+}
+
+func (r *webhookRepository) EnqueueDelivery(sub models.WebhookSubscription, eventType string, payload json.RawMessage) (models.WebhookDelivery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	d := r.newDelivery(sub, eventType, payload)
+	r.s.deliveries[d.ID] = d
+	return d, nil
+}
+
+func (r *webhookRepository) newDelivery(sub models.WebhookSubscription, eventType string, payload json.RawMessage) models.WebhookDelivery {
+	now := r.s.now()
+	return models.WebhookDelivery{
+		ID:             newID(),
+		SubscriptionID: sub.ID,
+		TenantID:       sub.TenantID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+}
+
+func (r *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]repository.PendingWebhookDelivery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	var due []models.WebhookDelivery
+	for _, d := range r.s.deliveries {
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if limit >= 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	var pending []repository.PendingWebhookDelivery
+	for _, d := range due {
+		sub, ok := r.s.subscriptions[d.SubscriptionID]
+		if !ok {
+			continue
+		}
+		d.Attempts++
+		d.NextAttemptAt = now.Add(lease)
+		r.s.deliveries[d.ID] = d
+		pending = append(pending, repository.PendingWebhookDelivery{
+			WebhookDelivery: d,
+			URL:             sub.URL,
+			Secret:          r.s.webhookSecrets[sub.ID],
+			Active:          sub.Active,
+		})
+	}
+	return pending, nil
+}
+
+func (r *webhookRepository) MarkDelivered(deliveryID string, responseStatus int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	d, ok := r.s.deliveries[deliveryID]
+	if !ok {
+		return nil
+	}
+	now := r.s.now()
+	d.Status, d.ResponseStatus, d.LastError, d.DeliveredAt = models.WebhookDeliveryDelivered, &responseStatus, nil, &now
+	r.s.deliveries[deliveryID] = d
+	return nil
+}
+
+func (r *webhookRepository) MarkAttemptFailed(deliveryID string, responseStatus *int, errMsg string, retryAt *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	d, ok := r.s.deliveries[deliveryID]
+	if !ok {
+		return nil
+	}
+	d.Status, d.ResponseStatus, d.LastError = models.WebhookDeliveryPending, responseStatus, &errMsg
+	if retryAt != nil {
+		d.NextAttemptAt = *retryAt
+	} else {
+		d.Status = models.WebhookDeliveryFailed
+	}
+	r.s.deliveries[deliveryID] = d
+	return nil
+}
+
+func (r *webhookRepository) ListDeliveries(tenantID, subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for _, d := range r.s.deliveries {
+		if d.TenantID == tenantID && d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if limit >= 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
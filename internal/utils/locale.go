@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var localeTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// localeTimeLayouts holds the timestamp layout used for each supported locale. Lookups
+// fall back from the full tag to its language, then to ISO 8601.
+var localeTimeLayouts = map[string]string{
+	"en-us": "Jan 2, 2006 3:04 PM MST",
+	"en":    "2 Jan 2006 15:04 MST",
+	"de":    "02.01.2006 15:04 MST",
+	"fr":    "02/01/2006 15:04 MST",
+	"es":    "02/01/2006 15:04 MST",
+	"it":    "02/01/2006 15:04 MST",
+	"nl":    "02-01-2006 15:04 MST",
+	"pl":    "02.01.2006 15:04 MST",
+	"uk":    "02.01.2006 15:04 MST",
+	"ja":    "2006/01/02 15:04 MST",
+	"zh":    "2006-01-02 15:04 MST",
+}
+
+const defaultTimeLayout = "2006-01-02 15:04:05 MST"
+
+// ValidateTimezone checks that tz is a known IANA time zone name.
+func ValidateTimezone(tz string) error {
+	if strings.TrimSpace(tz) == "" {
+		return fmt.Errorf("timezone is required")
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("unknown timezone %q", tz)
+	}
+	return nil
+}
+
+// ValidateLocale checks that locale looks like a BCP 47 language tag, e.g. "en-GB".
+func ValidateLocale(locale string) error {
+	if !localeTag.MatchString(locale) {
+		return fmt.Errorf("invalid locale %q", locale)
+	}
+	return nil
+}
+
+// FormatLocalTime renders t in the given time zone using the locale's conventions.
+// Unknown zones fall back to UTC and unknown locales to ISO 8601.
+func FormatLocalTime(t time.Time, tz, locale string) string {
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "" {
+		loc = time.UTC
+	}
+
+	key := strings.ToLower(locale)
+	layout, ok := localeTimeLayouts[key]
+	if !ok {
+		if lang, _, found := strings.Cut(key, "-"); found {
+			layout, ok = localeTimeLayouts[lang]
+		}
+	}
+	if !ok {
+		layout = defaultTimeLayout
+	}
+	return t.In(loc).Format(layout)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+)
+
+// secretsKey loads the 32-byte key for AST secrets from STRATUM_SECRETS_KEY. It is
+// deliberately separate from the connection password key so either can be rotated
+// or scoped independently.
+func secretsKey() ([]byte, error) {
+	b64 := os.Getenv("STRATUM_SECRETS_KEY")
+	if b64 == "" {
+		return nil, fmt.Errorf("secrets encryption key not set")
+	}
+	key, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 secrets key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets encryption key must be 32 bytes")
+	}
+	return key, nil
+}
+
+func EncryptSecret(plain string) ([]byte, error) {
+	key, err := secretsKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plain), nil), nil
+}
+
+func DecryptSecret(data []byte) (string, error) {
+	key, err := secretsKey()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,36 @@
+package version
+
+import "time"
+
+// APIVersion identifies the running API build. It is overridden at build time with
+// -ldflags "-X github.com/stanstork/stratum-api/internal/version.APIVersion=<tag>".
+var APIVersion = "dev"
+
+// CurrentContract is the newest versioned API contract, served under /api/<contract>.
+const CurrentContract = "v1"
+
+// SupportedContracts lists every API contract clients may request.
+var SupportedContracts = []string{CurrentContract}
+
+// Unversioned /api aliases are kept while clients migrate to /api/v1.
+var (
+	LegacyAliasDeprecatedAt = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	LegacyAliasSunset       = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Testing a connection with a raw DSN is replaced by the structured payload, which
+// keeps the password out of the URL-shaped string clients and proxies log.
+var (
+	RawDSNTestDeprecatedAt = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	RawDSNTestSunset       = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// IsSupported reports whether the given contract name is served.
+func IsSupported(contract string) bool {
+	for _, c := range SupportedContracts {
+		if c == contract {
+			return true
+		}
+	}
+	return false
+}
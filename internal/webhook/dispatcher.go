@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/repository"
+	"github.com/stanstork/stratum-api/internal/utils"
+)
+
+// Headers sent with every delivery. The signature header has the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">", keyed with the
+// subscription's secret, so receivers can verify the sender and reject replays.
+const (
+	HeaderSignature = "X-Stratum-Signature"
+	HeaderEvent     = "X-Stratum-Event"
+	HeaderDelivery  = "X-Stratum-Delivery"
+)
+
+const (
+	initialBackoff = 30 * time.Second
+	maxBackoff     = time.Hour
+	// maxErrorBody bounds how much of a failed response is kept as the delivery error.
+	maxErrorBody = 512
+)
+
+// Dispatcher drains the webhook outbox, posting each due delivery to its
+// subscription and rescheduling failures with exponential backoff.
+type Dispatcher struct {
+	repo        repository.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	batchSize   int
+	logger      zerolog.Logger
+}
+
+func NewDispatcher(repo repository.WebhookRepository, timeout time.Duration, maxAttempts, batchSize int, logger zerolog.Logger) *Dispatcher {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	return &Dispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		batchSize:   batchSize,
+		logger:      logger.With().Str("component", "webhook_dispatcher").Logger(),
+	}
+}
+
+// Sign returns the signature header value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RunOnce sends one batch of due deliveries.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	// Claimed deliveries stay hidden for longer than a batch can take, so a crash
+	// mid-batch only delays them.
+	lease := d.client.Timeout*time.Duration(d.batchSize) + time.Minute
+	pending, err := d.repo.ClaimDueDeliveries(d.batchSize, lease)
+	if err != nil {
+		return fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		d.deliver(ctx, p)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, p repository.PendingWebhookDelivery) {
+	log := d.logger.With().Str("delivery_id", p.ID).Str("subscription_id", p.SubscriptionID).Logger()
+	if !p.Active {
+		d.fail(log, p, nil, "subscription is disabled", false)
+		return
+	}
+	secret, err := utils.DecryptSecret(p.Secret)
+	if err != nil {
+		d.fail(log, p, nil, "decrypt signing secret: "+err.Error(), true)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		d.fail(log, p, nil, err.Error(), false)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Stratum-Webhooks/1")
+	req.Header.Set(HeaderEvent, p.EventType)
+	req.Header.Set(HeaderDelivery, p.ID)
+	req.Header.Set(HeaderSignature, Sign(secret, time.Now(), p.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.fail(log, p, nil, err.Error(), true)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := d.repo.MarkDelivered(p.ID, resp.StatusCode); err != nil {
+			log.Error().Err(err).Msg("failed to mark webhook delivered")
+		}
+		return
+	}
+	status := resp.StatusCode
+	msg := fmt.Sprintf("endpoint responded %d", status)
+	if len(body) > 0 {
+		msg += ": " + string(body)
+	}
+	d.fail(log, p, &status, msg, true)
+}
+
+// fail records a failed attempt, rescheduling it unless it may not be retried or
+// the delivery has used up its attempts.
+func (d *Dispatcher) fail(log zerolog.Logger, p repository.PendingWebhookDelivery, status *int, msg string, retryable bool) {
+	var retryAt *time.Time
+	if retryable && p.Attempts < d.maxAttempts {
+		backoff := initialBackoff << uint(p.Attempts-1)
+		if backoff <= 0 || backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		next := time.Now().Add(backoff)
+		retryAt = &next
+	}
+	if retryAt == nil {
+		log.Warn().Int("attempts", p.Attempts).Str("error", msg).Msg("giving up on webhook delivery")
+	} else {
+		log.Debug().Int("attempts", p.Attempts).Str("error", msg).Msg("webhook delivery failed")
+	}
+	if err := d.repo.MarkAttemptFailed(p.ID, status, msg, retryAt); err != nil {
+		log.Error().Err(err).Msg("failed to record webhook delivery failure")
+	}
+}
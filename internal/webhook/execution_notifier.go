@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stanstork/stratum-api/internal/models"
+	"github.com/stanstork/stratum-api/internal/repository"
+)
+
+// ExecutionNotifier turns execution result notifications into execution webhook
+// events. It plugs into the notification service, so every path that reports a
+// finished execution also reaches the tenant's execution webhooks.
+type ExecutionNotifier struct {
+	webhooks repository.WebhookRepository
+	jobs     repository.JobRepository
+	logger   zerolog.Logger
+}
+
+func NewExecutionNotifier(webhooks repository.WebhookRepository, jobs repository.JobRepository, logger zerolog.Logger) *ExecutionNotifier {
+	return &ExecutionNotifier{
+		webhooks: webhooks,
+		jobs:     jobs,
+		logger:   logger.With().Str("notifier", "execution_webhook").Logger(),
+	}
+}
+
+var executionWebhookEvents = map[models.NotificationEvent]string{
+	models.NotificationEventExecutionSucceeded: models.WebhookEventExecutionSucceeded,
+	models.NotificationEventExecutionFailed:    models.WebhookEventExecutionFailed,
+}
+
+func (n *ExecutionNotifier) Notify(_ context.Context, notif models.Notification) error {
+	event, ok := executionWebhookEvents[notif.EventType]
+	if !ok || notif.TenantID == nil {
+		return nil
+	}
+	var meta struct {
+		ExecutionID   string `json:"execution_id"`
+		JobDefinition string `json:"job_definition"`
+	}
+	if err := json.Unmarshal(notif.Metadata, &meta); err != nil || meta.ExecutionID == "" {
+		return fmt.Errorf("notification %s does not identify an execution", notif.ID)
+	}
+	exec, err := n.jobs.GetExecution(*notif.TenantID, meta.ExecutionID)
+	if err != nil {
+		return fmt.Errorf("load execution %s: %w", meta.ExecutionID, err)
+	}
+	_, err = n.webhooks.EnqueueEvent(models.WebhookEvent{
+		ID:         uuid.NewString(),
+		Category:   models.WebhookCategoryExecution,
+		Type:       event,
+		TenantID:   exec.TenantID,
+		OccurredAt: time.Now().UTC(),
+		Data:       models.ExecutionWebhookData(exec, meta.JobDefinition),
+	})
+	return err
+}
+
+func (n *ExecutionNotifier) String() string {
+	return "ExecutionNotifier"
+}
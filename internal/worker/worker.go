@@ -14,13 +14,14 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/stanstork/stratum-api/internal/engine"
+	"github.com/stanstork/stratum-api/internal/models"
 	"github.com/stanstork/stratum-api/internal/repository"
 )
 
@@ -39,8 +40,9 @@ type WorkerConfig struct {
 	EngineImage          string
 	JWTSigningKey        []byte
 	TempDir              string
-	ContainerCPULimit    int64 // CPU limit in millicores (e.g., 1000 millicores = 1 CPU core)
-	ContainerMemoryLimit int64 // Memory limit in bytes (e.g., 512 * 1024 * 1024 for 512MB)
+	ContainerCPULimit    int64                // CPU limit in millicores (e.g., 1000 millicores = 1 CPU core)
+	ContainerMemoryLimit int64                // Memory limit in bytes (e.g., 512 * 1024 * 1024 for 512MB)
+	Registries           *engine.RegistryAuth // Credentials for pulling the engine image from private registries
 }
 
 type Worker struct {
@@ -85,6 +87,7 @@ func (w *Worker) processNextPendingJob(ctx context.Context) error {
 
 	var execID, jobDefID, tenantID string
 	query := `
+		/* cross-tenant: the worker runs every tenant's executions */
 		SELECT id, tenant_id, job_definition_id
 		FROM tenant.job_executions
 		WHERE status = 'pending'
@@ -119,7 +122,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	log.Printf("Running job execution %s for job definition %s", execID, jobDefID)
 
 	// Update execution status to running
-	if _, err := w.cfg.JobRepo.UpdateExecution(tenantID, execID, "running", "", ""); err != nil {
+	if _, err := w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusRunning, "", ""); err != nil {
 		log.Printf("UpdateExecution execID=%s error: %v", execID, err)
 		return errors.Wrap(err, "failed to update execution status to running")
 	}
@@ -127,19 +130,19 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	// Fetch job definition
 	def, err := w.cfg.JobRepo.GetJobDefinitionByID(tenantID, jobDefID)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to fetch job definition: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to fetch job definition: %v", err), "")
 		return errors.Wrap(err, "failed to fetch job definition")
 	}
 
 	source_conn, err := w.cfg.ConnRepo.Get(tenantID, def.SourceConnectionID)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to fetch source connection: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to fetch source connection: %v", err), "")
 		return errors.Wrap(err, "failed to fetch source connection")
 	}
 
 	dest_conn, err := w.cfg.ConnRepo.Get(tenantID, def.DestinationConnectionID)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to fetch destination connection: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to fetch destination connection: %v", err), "")
 		return errors.Wrap(err, "failed to fetch destination connection")
 	}
 
@@ -149,7 +152,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	// Parse the AST and ensure it has the necessary connections
 	var ast map[string]interface{}
 	if err := json.Unmarshal(def.AST, &ast); err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to parse AST: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to parse AST: %v", err), "")
 		return errors.Wrap(err, "failed to parse AST from job definition")
 	}
 	if ast == nil {
@@ -158,12 +161,12 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 
 	source_conn_str, err := source_conn.GenerateConnString()
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to generate source connection string: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to generate source connection string: %v", err), "")
 		return errors.Wrap(err, "failed to generate source connection string")
 	}
 	dest_conn_str, err := dest_conn.GenerateConnString()
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to generate destination connection string: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to generate destination connection string: %v", err), "")
 		return errors.Wrap(err, "failed to generate destination connection string")
 	}
 
@@ -180,15 +183,17 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 		},
 	}
 
+	ast["write_mode"] = def.EffectiveWriteMode()
+
 	log.Printf("AST for job definition %s: %+v", jobDefID, ast)
 
 	astBytes, err := json.Marshal(ast)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to marshal AST: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to marshal AST: %v", err), "")
 		return errors.Wrap(err, "failed to marshal AST to JSON")
 	}
 	if err := os.WriteFile(tmpFileName, astBytes, 0644); err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to write AST to file: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to write AST to file: %v", err), "")
 		return errors.Wrapf(err, "failed to write AST to temporary file %s", tmpFileName)
 	}
 	log.Printf("AST written to temporary file: %s", tmpFileName)
@@ -202,7 +207,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	authToken, err := generateJobToken(execID, def.TenantID, w.cfg.JWTSigningKey)
 	if err != nil {
 		// Update execution status to failed
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", "Failed to generate auth token", "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, "Failed to generate auth token", "")
 		return errors.Wrap(err, "failed to generate auth token for container")
 	}
 
@@ -213,7 +218,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 		hostIP = "localhost" // Fallback might not work from container
 	}
 
-	hostCallbackURL := fmt.Sprintf("http://%s:8080/api/jobs/executions/%s/complete", hostIP, execID)
+	hostCallbackURL := fmt.Sprintf("http://%s:8080/api/v1/jobs/executions/%s/complete", hostIP, execID)
 
 	// Environment variables
 	envVars := []string{
@@ -255,9 +260,14 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 		log.Printf("Image %s not found locally, pulling...", imageName)
 
 		// Pull the image
-		reader, err := w.cli.ImagePull(ctx, w.cfg.EngineImage, image.PullOptions{})
+		opts, err := w.cfg.Registries.PullOptions(ctx, w.cfg.EngineImage)
+		if err != nil {
+			w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to pull image: %v", err), "")
+			return fmt.Errorf("failed to pull image: %w", err)
+		}
+		reader, err := w.cli.ImagePull(ctx, w.cfg.EngineImage, opts)
 		if err != nil {
-			w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to pull image: %v", err), "")
+			w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to pull image: %v", err), "")
 			return fmt.Errorf("failed to pull image: %w", err)
 		}
 
@@ -271,12 +281,12 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 		ctx,
 		containerConfig,
 		hostConfig,
-		nil, // NetworkingConfig
+		engine.EndpointsConfig(models.DockerNetworks(source_conn, dest_conn)),
 		nil, // Platform
 		"",  // Container name (empty means Docker will assign a random name)
 	)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to create container: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to create container: %v", err), "")
 		return fmt.Errorf("failed to create container: %w", err)
 	}
 
@@ -285,7 +295,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 
 	// Start the container
 	if err := w.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to start container: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to start container: %v", err), "")
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
@@ -298,7 +308,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	}
 	logReader, err := w.cli.ContainerLogs(ctx, containerID, logOpts)
 	if err != nil {
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Failed to get container logs: %v", err), "")
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Failed to get container logs: %v", err), "")
 		return fmt.Errorf("failed to get container logs: %w", err)
 	}
 	defer logReader.Close()
@@ -311,7 +321,7 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	// stdcopy will consume the multiplexed stream and write “pure” output
 	if _, err := stdcopy.StdCopy(stdoutBuf, stderrBuf, logReader); err != nil {
 		w.cfg.JobRepo.UpdateExecution(tenantID, execID,
-			"failed",
+			models.ExecutionStatusFailed,
 			fmt.Sprintf("Failed to demux container logs: %v", err),
 			"",
 		)
@@ -326,12 +336,12 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 	waitResp, errCh := w.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
-		w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Container wait error: %v", err), mergedLogs)
+		w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Container wait error: %v", err), mergedLogs)
 		return fmt.Errorf("container wait error: %w", err)
 	case status := <-waitResp:
 		exitCode := status.StatusCode
 		if exitCode != 0 {
-			w.cfg.JobRepo.UpdateExecution(tenantID, execID, "failed", fmt.Sprintf("Container exited with code %d", exitCode), mergedLogs)
+			w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusFailed, fmt.Sprintf("Container exited with code %d", exitCode), mergedLogs)
 			log.Printf("Container %s exited with code %d", containerID, exitCode)
 			return fmt.Errorf("container exited with code %d", exitCode)
 		}
@@ -350,10 +360,10 @@ func (w *Worker) run(ctx context.Context, tenantID, execID, jobDefID string) err
 		}
 
 		// Check if the status is still "running".
-		if exec.Status == "running" {
+		if exec.Status == models.ExecutionStatusRunning {
 			// The callback did not arrive in time. The worker takes responsibility.
 			log.Printf("Engine report for %s did not arrive in time. Marking as succeeded without metrics.", execID)
-			w.cfg.JobRepo.UpdateExecution(tenantID, execID, "succeeded", "", mergedLogs)
+			w.cfg.JobRepo.UpdateExecution(tenantID, execID, models.ExecutionStatusSucceeded, "", mergedLogs)
 		} else {
 			// The callback was successful and updated the status.
 			log.Printf("Execution %s status was successfully set to '%s' by engine report.", execID, exec.Status)